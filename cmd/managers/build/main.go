@@ -17,9 +17,12 @@ limitations under the License.
 package main
 
 import (
+	"crypto/rand"
 	"flag"
+	"fmt"
 	"net/http"
 	"os"
+	"time"
 
 	"k8s.io/apimachinery/pkg/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
@@ -31,6 +34,8 @@ import (
 	kpackbuildv1alpha1 "github.com/projectriff/system/pkg/apis/thirdparty/kpack/build/v1alpha1"
 	"github.com/projectriff/system/pkg/controllers"
 	buildcontrollers "github.com/projectriff/system/pkg/controllers/build"
+	"github.com/projectriff/system/pkg/sourceupload"
+	"github.com/projectriff/system/pkg/tracker"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -38,6 +43,8 @@ var (
 	scheme    = runtime.NewScheme()
 	setupLog  = ctrl.Log.WithName("setup")
 	namespace = os.Getenv("SYSTEM_NAMESPACE")
+
+	syncPeriod = 10 * time.Hour
 )
 
 func init() {
@@ -51,10 +58,24 @@ func main() {
 	var metricsAddr string
 	var probesAddr string
 	var enableLeaderElection bool
+	var sourceUploadAddr string
+	var sourceUploadTLSAddr string
+	var sourceUploadCertDir string
+	var sourceUploadDir string
+	var sourceUploadURL string
+	var sourceUploadTTL time.Duration
 	flag.StringVar(&metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probesAddr, "probes-addr", ":8081", "The address health probes bind to.")
 	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false,
 		"Enable leader election for controller manager. Enabling this will ensure there is only one active controller manager.")
+	flag.StringVar(&sourceUploadAddr, "source-upload-addr", ":8082", "The address uploaded source archives are served on over HTTP. Set to empty to disable source uploads.")
+	flag.StringVar(&sourceUploadTLSAddr, "source-upload-tls-addr", ":8443", "The address the source upload endpoint binds to over TLS.")
+	flag.StringVar(&sourceUploadCertDir, "source-upload-cert-dir", "/tmp/k8s-webhook-server/serving-certs",
+		"The directory containing the tls.crt and tls.key of the source upload endpoint, shared with the webhook server.")
+	flag.StringVar(&sourceUploadDir, "source-upload-dir", "/var/lib/riff/source", "The directory uploaded source archives are stored in.")
+	flag.StringVar(&sourceUploadURL, "source-upload-url", fmt.Sprintf("http://riff-build-source-upload.%s.svc.cluster.local", namespace),
+		"The URL builds fetch uploaded source archives from.")
+	flag.DurationVar(&sourceUploadTTL, "source-upload-ttl", 24*time.Hour, "How long uploaded source archives that are no longer referenced are kept.")
 	flag.Parse()
 
 	ctrl.SetLogger(zap.Logger(true))
@@ -65,6 +86,7 @@ func main() {
 		HealthProbeBindAddress: probesAddr,
 		LeaderElection:         enableLeaderElection,
 		LeaderElectionID:       "controller-leader-election-helper-build",
+		SyncPeriod:             &syncPeriod,
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
@@ -78,7 +100,10 @@ func main() {
 			Recorder:  mgr.GetEventRecorderFor("Application"),
 			Log:       ctrl.Log.WithName("controllers").WithName("Application"),
 			Scheme:    mgr.GetScheme(),
+			Tracker:   tracker.New(syncPeriod, ctrl.Log.WithName("controllers").WithName("Application").WithName("tracker")),
 		},
+		namespace,
+		rand.Reader,
 	).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Application")
 		os.Exit(1)
@@ -88,10 +113,11 @@ func main() {
 		os.Exit(1)
 	}
 	if err = (&buildcontrollers.ContainerReconciler{
-		Client:   mgr.GetClient(),
-		Recorder: mgr.GetEventRecorderFor("Container"),
-		Log:      ctrl.Log.WithName("controllers").WithName("Container"),
-		Scheme:   mgr.GetScheme(),
+		Client:    mgr.GetClient(),
+		Recorder:  mgr.GetEventRecorderFor("Container"),
+		Log:       ctrl.Log.WithName("controllers").WithName("Container"),
+		Scheme:    mgr.GetScheme(),
+		Namespace: namespace,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Container")
 		os.Exit(1)
@@ -107,7 +133,10 @@ func main() {
 			Recorder:  mgr.GetEventRecorderFor("Function"),
 			Log:       ctrl.Log.WithName("controllers").WithName("Function"),
 			Scheme:    mgr.GetScheme(),
+			Tracker:   tracker.New(syncPeriod, ctrl.Log.WithName("controllers").WithName("Function").WithName("tracker")),
 		},
+		namespace,
+		rand.Reader,
 	).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Function")
 		os.Exit(1)
@@ -116,6 +145,23 @@ func main() {
 		setupLog.Error(err, "unable to create webhook", "webhook", "Function")
 		os.Exit(1)
 	}
+	if err = buildcontrollers.ImagePromotionReconciler(
+		controllers.Config{
+			Client:    mgr.GetClient(),
+			APIReader: mgr.GetAPIReader(),
+			Recorder:  mgr.GetEventRecorderFor("ImagePromotion"),
+			Log:       ctrl.Log.WithName("controllers").WithName("ImagePromotion"),
+			Scheme:    mgr.GetScheme(),
+			Tracker:   tracker.New(syncPeriod, ctrl.Log.WithName("controllers").WithName("ImagePromotion").WithName("tracker")),
+		},
+	).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ImagePromotion")
+		os.Exit(1)
+	}
+	if err = ctrl.NewWebhookManagedBy(mgr).For(&buildv1alpha1.ImagePromotion{}).Complete(); err != nil {
+		setupLog.Error(err, "unable to create webhook", "webhook", "ImagePromotion")
+		os.Exit(1)
+	}
 	if err = (&buildcontrollers.CredentialReconciler{
 		Client:   mgr.GetClient(),
 		Recorder: mgr.GetEventRecorderFor("Credential"),
@@ -124,6 +170,14 @@ func main() {
 		setupLog.Error(err, "unable to create controller", "controller", "Credential")
 		os.Exit(1)
 	}
+	if err = (&buildcontrollers.CredentialCheckReconciler{
+		Client:   mgr.GetClient(),
+		Recorder: mgr.GetEventRecorderFor("CredentialCheck"),
+		Log:      ctrl.Log.WithName("controllers").WithName("CredentialCheck"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "CredentialCheck")
+		os.Exit(1)
+	}
 	if err = (&buildcontrollers.ClusterBuilderReconciler{
 		Client:    mgr.GetClient(),
 		Recorder:  mgr.GetEventRecorderFor("ClusterBuilder"),
@@ -135,6 +189,23 @@ func main() {
 	}
 	// +kubebuilder:scaffold:builder
 
+	if sourceUploadAddr != "" {
+		if err := mgr.Add(&sourceupload.Server{
+			Client:     mgr.GetClient(),
+			Log:        ctrl.Log.WithName("sourceupload"),
+			Addr:       sourceUploadAddr,
+			UploadAddr: sourceUploadTLSAddr,
+			CertDir:    sourceUploadCertDir,
+			Dir:        sourceUploadDir,
+			URL:        sourceUploadURL,
+			TTL:        sourceUploadTTL,
+			Authorizer: &sourceupload.ReviewAuthorizer{Client: mgr.GetClient()},
+		}); err != nil {
+			setupLog.Error(err, "unable to create source upload server")
+			os.Exit(1)
+		}
+	}
+
 	if err := mgr.AddHealthzCheck("default", func(_ *http.Request) error { return nil }); err != nil {
 		setupLog.Error(err, "unable to create health check")
 		os.Exit(1)
@@ -23,13 +23,18 @@ import (
 	"time"
 
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/discovery"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	buildv1alpha1 "github.com/projectriff/system/pkg/apis/build/v1alpha1"
 	corev1alpha1 "github.com/projectriff/system/pkg/apis/core/v1alpha1"
+	streamingv1alpha1 "github.com/projectriff/system/pkg/apis/streaming/v1alpha1"
+	gatewayv1 "github.com/projectriff/system/pkg/apis/thirdparty/gateway/v1"
+	networkingv1 "github.com/projectriff/system/pkg/apis/thirdparty/kubernetes/networking/v1"
 	"github.com/projectriff/system/pkg/controllers"
 	corecontrollers "github.com/projectriff/system/pkg/controllers/core"
 	"github.com/projectriff/system/pkg/tracker"
@@ -37,8 +42,10 @@ import (
 )
 
 var (
-	scheme     = runtime.NewScheme()
-	setupLog   = ctrl.Log.WithName("setup")
+	scheme    = runtime.NewScheme()
+	setupLog  = ctrl.Log.WithName("setup")
+	namespace = os.Getenv("SYSTEM_NAMESPACE")
+
 	syncPeriod = 10 * time.Hour
 )
 
@@ -47,6 +54,9 @@ func init() {
 
 	_ = corev1alpha1.AddToScheme(scheme)
 	_ = buildv1alpha1.AddToScheme(scheme)
+	_ = networkingv1.AddToScheme(scheme)
+	_ = gatewayv1.AddToScheme(scheme)
+	_ = streamingv1alpha1.AddToScheme(scheme)
 	// +kubebuilder:scaffold:scheme
 }
 
@@ -75,6 +85,38 @@ func main() {
 		os.Exit(1)
 	}
 
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(mgr.GetConfig())
+	if err != nil {
+		setupLog.Error(err, "unable to create discovery client")
+		os.Exit(1)
+	}
+	ingressVersion, err := corecontrollers.DiscoverIngressVersion(discoveryClient)
+	if err != nil {
+		setupLog.Error(err, "unable to discover ingress api")
+		os.Exit(1)
+	}
+	setupLog.Info("using ingress api", "groupVersion", ingressVersion.String())
+	httpRouteAvailable, err := corecontrollers.DiscoverHTTPRouteAvailable(discoveryClient)
+	if err != nil {
+		setupLog.Error(err, "unable to discover httproute api")
+		os.Exit(1)
+	}
+	setupLog.Info("discovered httproute api", "available", httpRouteAvailable)
+	streamAvailable, err := corecontrollers.DiscoverStreamAvailable(discoveryClient)
+	if err != nil {
+		setupLog.Error(err, "unable to discover stream api")
+		os.Exit(1)
+	}
+	setupLog.Info("discovered stream api", "available", streamAvailable)
+	namespaceNameLabel, err := corecontrollers.DiscoverNamespaceNameLabel(discoveryClient)
+	if err != nil {
+		setupLog.Error(err, "unable to discover server version")
+		os.Exit(1)
+	}
+	if !namespaceNameLabel {
+		setupLog.Info("namespaces are not labeled with their name, NetworkPolicies for External deployers require the ingress namespace to be labeled kubernetes.io/metadata.name=<namespace>")
+	}
+
 	if err = corecontrollers.DeployerReconciler(
 		controllers.Config{
 			Client:    mgr.GetClient(),
@@ -84,6 +126,11 @@ func main() {
 			Scheme:    mgr.GetScheme(),
 			Tracker:   tracker.New(syncPeriod, ctrl.Log.WithName("controllers").WithName("Deployer").WithName("tracker")),
 		},
+		namespace,
+		ingressVersion,
+		httpRouteAvailable,
+		streamAvailable,
+		time.Now,
 	).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Deployer")
 		os.Exit(1)
@@ -92,6 +139,12 @@ func main() {
 		setupLog.Error(err, "unable to create webhook", "webhook", "Deployer")
 		os.Exit(1)
 	}
+	mgr.GetWebhookServer().Register(corecontrollers.DeployerIngressValidatorPath, &webhook.Admission{
+		Handler: &corecontrollers.DeployerIngressValidator{
+			Client:    mgr.GetClient(),
+			Namespace: namespace,
+		},
+	})
 	// +kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("default", func(_ *http.Request) error { return nil }); err != nil {
@@ -31,4 +31,19 @@ func (s *ApplicationSpec) Default() {
 	if s.Image == "" {
 		s.Image = "_"
 	}
+	if s.ServiceAccountName == "" {
+		s.ServiceAccountName = DefaultServiceAccountName
+	}
+	if s.Builder != nil {
+		s.Builder.Default()
+	}
+	if s.Dockerfile != nil {
+		s.Dockerfile.Default()
+	}
+}
+
+func (d *DockerfileBuild) Default() {
+	if d.Path == "" {
+		d.Path = "Dockerfile"
+	}
 }
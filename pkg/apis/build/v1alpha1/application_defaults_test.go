@@ -32,7 +32,37 @@ func TestApplicationDefault(t *testing.T) {
 		in:   &Application{},
 		want: &Application{
 			Spec: ApplicationSpec{
-				Image: "_",
+				Image:              "_",
+				ServiceAccountName: "riff-build",
+			},
+		},
+	}, {
+		name: "service account",
+		in: &Application{
+			Spec: ApplicationSpec{
+				ServiceAccountName: "my-builds",
+			},
+		},
+		want: &Application{
+			Spec: ApplicationSpec{
+				Image:              "_",
+				ServiceAccountName: "my-builds",
+			},
+		},
+	}, {
+		name: "dockerfile",
+		in: &Application{
+			Spec: ApplicationSpec{
+				Dockerfile: &DockerfileBuild{},
+			},
+		},
+		want: &Application{
+			Spec: ApplicationSpec{
+				Image:              "_",
+				ServiceAccountName: "riff-build",
+				Dockerfile: &DockerfileBuild{
+					Path: "Dockerfile",
+				},
 			},
 		},
 	}}
@@ -17,6 +17,7 @@ limitations under the License.
 package v1alpha1
 
 import (
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 
 	"github.com/projectriff/system/pkg/apis"
@@ -24,14 +25,20 @@ import (
 )
 
 const (
-	ApplicationConditionReady                              = apis.ConditionReady
-	ApplicationConditionKpackImageReady apis.ConditionType = "KpackImageReady"
-	ApplicationConditionImageResolved   apis.ConditionType = "ImageResolved"
+	ApplicationConditionReady                                   = apis.ConditionReady
+	ApplicationConditionKpackImageReady      apis.ConditionType = "KpackImageReady"
+	ApplicationConditionImageResolved        apis.ConditionType = "ImageResolved"
+	ApplicationConditionCredentialsReady     apis.ConditionType = "CredentialsReady"
+	ApplicationConditionUpToDate             apis.ConditionType = "UpToDate"
+	ApplicationConditionDockerfileBuildReady apis.ConditionType = "DockerfileBuildReady"
 )
 
+// applicationCondSet gates readiness. CredentialsReady and UpToDate are
+// informational: they are only set when they apply, and neither changes Ready.
 var applicationCondSet = apis.NewLivingConditionSet(
 	ApplicationConditionKpackImageReady,
 	ApplicationConditionImageResolved,
+	ApplicationConditionDockerfileBuildReady,
 )
 
 func (as *ApplicationStatus) GetObservedGeneration() int64 {
@@ -54,12 +61,42 @@ func (as *ApplicationStatus) InitializeConditions() {
 	applicationCondSet.Manage(as).InitializeConditions()
 }
 
+// MarkBuilderNotFound reports that the selected builder does not exist or is
+// not available for builds.
+func (as *ApplicationStatus) MarkBuilderNotFound(message string) {
+	applicationCondSet.Manage(as).MarkFalse(ApplicationConditionKpackImageReady, "BuilderNotFound", message)
+}
+
+// MarkBuildEnvInvalid reports that a build environment variable references a
+// value that is not available.
+func (as *ApplicationStatus) MarkBuildEnvInvalid(message string) {
+	applicationCondSet.Manage(as).MarkFalse(ApplicationConditionKpackImageReady, "BuildEnvInvalid", message)
+}
+
 func (as *ApplicationStatus) MarkBuildNotUsed() {
 	as.KpackImageRef = nil
 	as.BuildCacheRef = nil
 	applicationCondSet.Manage(as).MarkTrue(ApplicationConditionKpackImageReady)
 }
 
+// MarkCredentialsReady reports that a working credential is bound for the
+// registry of the target image.
+func (as *ApplicationStatus) MarkCredentialsReady() {
+	applicationCondSet.Manage(as).MarkTrue(ApplicationConditionCredentialsReady)
+}
+
+// MarkCredentialsInvalid reports that credentials are bound for the registry
+// of the target image, but none of them are working.
+func (as *ApplicationStatus) MarkCredentialsInvalid(message string) {
+	applicationCondSet.Manage(as).MarkFalse(ApplicationConditionCredentialsReady, "CredentialsInvalid", message)
+}
+
+// MarkCredentialsNotBound clears the credentials condition when no credentials
+// are bound for the registry of the target image.
+func (as *ApplicationStatus) MarkCredentialsNotBound() {
+	applicationCondSet.Manage(as).ClearCondition(ApplicationConditionCredentialsReady)
+}
+
 func (as *ApplicationStatus) MarkImageDefaultPrefixMissing(message string) {
 	applicationCondSet.Manage(as).MarkFalse(ApplicationConditionImageResolved, "DefaultImagePrefixMissing", message)
 }
@@ -72,6 +109,77 @@ func (as *ApplicationStatus) MarkImageResolved() {
 	applicationCondSet.Manage(as).MarkTrue(ApplicationConditionImageResolved)
 }
 
+// MarkUpToDate reports that the latest image was built with the current
+// builder image.
+func (as *ApplicationStatus) MarkUpToDate() {
+	applicationCondSet.Manage(as).MarkTrue(ApplicationConditionUpToDate)
+}
+
+// MarkBuilderOutdated reports that the latest image was built with a builder
+// image that has since changed.
+func (as *ApplicationStatus) MarkBuilderOutdated(message string) {
+	applicationCondSet.Manage(as).MarkFalse(ApplicationConditionUpToDate, "BuilderOutdated", message)
+}
+
+// MarkRebuildRunning reports that a build with the current builder image is
+// running.
+func (as *ApplicationStatus) MarkRebuildRunning(message string) {
+	applicationCondSet.Manage(as).MarkUnknown(ApplicationConditionUpToDate, "RebuildRunning", message)
+}
+
+// MarkUpToDateNotApplicable clears the up to date condition when there is no
+// built image to compare.
+func (as *ApplicationStatus) MarkUpToDateNotApplicable() {
+	applicationCondSet.Manage(as).ClearCondition(ApplicationConditionUpToDate)
+}
+
+// MarkDockerfileBuilderNotConfigured reports that the images used to run
+// Dockerfile builds are not configured.
+func (as *ApplicationStatus) MarkDockerfileBuilderNotConfigured(message string) {
+	applicationCondSet.Manage(as).MarkFalse(ApplicationConditionDockerfileBuildReady, "BuilderNotConfigured", message)
+}
+
+// MarkDockerfileCredentialsUnavailable reports that the registry credentials
+// for Dockerfile builds could not be provided to the build.
+func (as *ApplicationStatus) MarkDockerfileCredentialsUnavailable(message string) {
+	applicationCondSet.Manage(as).MarkFalse(ApplicationConditionDockerfileBuildReady, "CredentialsUnavailable", message)
+}
+
+// MarkDockerfileBuildEnvInvalid reports that a build environment variable
+// for a Dockerfile build references a value that is not available.
+func (as *ApplicationStatus) MarkDockerfileBuildEnvInvalid(message string) {
+	applicationCondSet.Manage(as).MarkFalse(ApplicationConditionDockerfileBuildReady, "BuildEnvInvalid", message)
+}
+
+// MarkDockerfileSourceResolving reports that the git revision of a Dockerfile
+// build is not yet resolved to a commit.
+func (as *ApplicationStatus) MarkDockerfileSourceResolving() {
+	as.DockerfileJobRef = nil
+	applicationCondSet.Manage(as).MarkUnknown(ApplicationConditionDockerfileBuildReady, "SourceResolving", "")
+}
+
+func (as *ApplicationStatus) MarkDockerfileBuildNotUsed() {
+	as.DockerfileJobRef = nil
+	applicationCondSet.Manage(as).MarkTrue(ApplicationConditionDockerfileBuildReady)
+}
+
+func (as *ApplicationStatus) PropagateDockerfileJobStatus(js *batchv1.JobStatus) {
+	for _, c := range js.Conditions {
+		if c.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch c.Type {
+		case batchv1.JobComplete:
+			applicationCondSet.Manage(as).MarkTrue(ApplicationConditionDockerfileBuildReady)
+			return
+		case batchv1.JobFailed:
+			applicationCondSet.Manage(as).MarkFalse(ApplicationConditionDockerfileBuildReady, "BuildFailed", c.Message)
+			return
+		}
+	}
+	applicationCondSet.Manage(as).MarkUnknown(ApplicationConditionDockerfileBuildReady, "Building", "")
+}
+
 func (as *ApplicationStatus) PropagateKpackImageStatus(is *kpackbuildv1alpha1.ImageStatus) {
 	sc := is.GetCondition(apis.ConditionReady)
 	if sc == nil {
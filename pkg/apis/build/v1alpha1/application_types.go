@@ -22,6 +22,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 
 	apis "github.com/projectriff/system/pkg/apis"
+	"github.com/projectriff/system/pkg/refs"
 )
 
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
@@ -49,6 +50,11 @@ type ApplicationSpec struct {
 	// intermediate build artifacts, like a maven cache, for future builds.
 	CacheSize *resource.Quantity `json:"cacheSize,omitempty"`
 
+	// Cache policy for the build cache, like resetting the cache after
+	// repeated failed builds. Requires a CacheSize.
+	// +optional
+	Cache *BuildCachePolicy `json:"cache,omitempty"`
+
 	// Source location. Required for on cluster builds.
 	Source *Source `json:"source,omitempty"`
 
@@ -62,6 +68,40 @@ type ApplicationSpec struct {
 	ImageTaggingStrategy ImageTaggingStrategy `json:"imageTaggingStrategy,omitempty"`
 	// +optional
 	Build ImageBuild `json:"build,omitempty"`
+
+	// Builder used to build images. Defaults to the `riff-application` ClusterBuilder.
+	// +optional
+	Builder *BuilderReference `json:"builder,omitempty"`
+
+	// ServiceAccountName is the name of the ServiceAccount whose bound
+	// credentials are used to push and pull images. Defaults to `riff-build`.
+	// +optional
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+
+	// RebuildPolicy controls whether images are rebuilt when the builder
+	// image changes. Either Never or BuilderUpdated, defaults to Never.
+	// +optional
+	RebuildPolicy RebuildPolicy `json:"rebuildPolicy,omitempty"`
+
+	// Dockerfile builds the image from a Dockerfile within the source rather
+	// than with buildpacks. Requires a git or blob source. Git sources are
+	// cloned with the git credentials of the ServiceAccount, like kpack does
+	// for buildpack builds. Literal build environment variables are passed to
+	// the build as build args. Variables referencing a Secret or ConfigMap are
+	// never passed as build args, which are recorded in the image, instead
+	// each value is a file named by the variable in `/kaniko/build-env` for
+	// the Dockerfile to read while building, like
+	// `RUN NPM_TOKEN=$(cat /kaniko/build-env/NPM_TOKEN) npm ci`.
+	// +optional
+	Dockerfile *DockerfileBuild `json:"dockerfile,omitempty"`
+}
+
+// DockerfileBuild configures an image built from a Dockerfile.
+type DockerfileBuild struct {
+	// Path to the Dockerfile, relative to the source directory. Defaults to
+	// `Dockerfile`.
+	// +optional
+	Path string `json:"path,omitempty"`
 }
 
 // ApplicationStatus defines the observed state of Application
@@ -71,6 +111,18 @@ type ApplicationStatus struct {
 
 	apis.Status `json:",inline"`
 	BuildStatus `json:",inline"`
+
+	// DockerfileJobRef references the Job building the image from a
+	// Dockerfile.
+	DockerfileJobRef *refs.TypedLocalObjectReference `json:"dockerfileJobRef,omitempty"`
+
+	// DockerfileSourceResolverRef references the kpack SourceResolver that
+	// resolves the git revision of a Dockerfile build to a commit.
+	DockerfileSourceResolverRef *refs.TypedLocalObjectReference `json:"dockerfileSourceResolverRef,omitempty"`
+
+	// DockerfileSourceRevision is the git commit the Dockerfile build uses. A
+	// new commit on the tracked branch or tag triggers a new build.
+	DockerfileSourceRevision string `json:"dockerfileSourceRevision,omitempty"`
 }
 
 // +kubebuilder:object:root=true
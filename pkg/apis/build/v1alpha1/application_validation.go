@@ -17,6 +17,9 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"path"
+	"strings"
+
 	"k8s.io/apimachinery/pkg/api/equality"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
@@ -62,13 +65,49 @@ func (s *ApplicationSpec) Validate() validation.FieldErrors {
 
 	errs := validation.FieldErrors{}
 
-	if s.Image == "" {
-		errs = errs.Also(validation.ErrMissingField("image"))
-	}
+	errs = errs.Also(validateImage(s.Image).ViaField("image"))
 
 	if s.Source != nil {
 		errs = errs.Also(s.Source.Validate().ViaField("source"))
 	}
 
+	if s.Builder != nil {
+		errs = errs.Also(s.Builder.Validate().ViaField("builder"))
+	}
+
+	errs = errs.Also(validateBuildEnv(s.Build.Env).ViaField("build"))
+
+	if s.Cache != nil {
+		errs = errs.Also(s.Cache.Validate().ViaField("cache"))
+		if s.CacheSize == nil {
+			errs = errs.Also(validation.ErrMissingField("cacheSize"))
+		}
+	}
+
+	errs = errs.Also(s.RebuildPolicy.Validate().ViaField("rebuildPolicy"))
+
+	if s.Dockerfile != nil {
+		errs = errs.Also(s.Dockerfile.Validate().ViaField("dockerfile"))
+		switch {
+		case s.Source == nil:
+			errs = errs.Also(validation.ErrMissingField("source"))
+		case s.Source.Registry != nil:
+			errs = errs.Also(validation.ErrDisallowedFields("source.registry", "dockerfile builds require a git or blob source"))
+		}
+		if s.Builder != nil {
+			errs = errs.Also(validation.ErrDisallowedFields("builder", "dockerfile builds do not use a builder"))
+		}
+	}
+
+	return errs
+}
+
+func (d *DockerfileBuild) Validate() validation.FieldErrors {
+	errs := validation.FieldErrors{}
+
+	if path.IsAbs(d.Path) || strings.HasPrefix(path.Clean(d.Path), "..") {
+		errs = errs.Also(validation.ErrInvalidValue(d.Path, "path"))
+	}
+
 	return errs
 }
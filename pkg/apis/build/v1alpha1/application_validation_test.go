@@ -20,6 +20,7 @@ import (
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/api/resource"
 
 	"github.com/projectriff/system/pkg/validation"
 )
@@ -58,6 +59,10 @@ func TestValidateApplication(t *testing.T) {
 }
 
 func TestValidateApplicationSpec(t *testing.T) {
+	cacheSize := resource.MustParse("1Gi")
+	resetAfterFailedBuilds := int32(3)
+	zero := int32(0)
+
 	for _, c := range []struct {
 		name     string
 		target   *ApplicationSpec
@@ -102,6 +107,172 @@ func TestValidateApplicationSpec(t *testing.T) {
 			Source: &Source{},
 		},
 		expected: validation.ErrMissingField("source"),
+	}, {
+		name: "valid builder",
+		target: &ApplicationSpec{
+			Image: "test-image",
+			Builder: &BuilderReference{
+				Kind: BuilderKind,
+				Name: "my-builder",
+			},
+		},
+		expected: validation.FieldErrors{},
+	}, {
+		name: "validates builder kind",
+		target: &ApplicationSpec{
+			Image: "test-image",
+			Builder: &BuilderReference{
+				Kind: "Bogus",
+				Name: "my-builder",
+			},
+		},
+		expected: validation.ErrInvalidValue("Bogus", "builder.kind"),
+	}, {
+		name: "validates builder name",
+		target: &ApplicationSpec{
+			Image: "test-image",
+			Builder: &BuilderReference{
+				Kind: ClusterBuilderKind,
+			},
+		},
+		expected: validation.ErrMissingField("builder.name"),
+	}, {
+		name: "valid rebuild policy",
+		target: &ApplicationSpec{
+			Image:         "test-image",
+			RebuildPolicy: RebuildPolicyBuilderUpdated,
+		},
+		expected: validation.FieldErrors{},
+	}, {
+		name: "invalid rebuild policy",
+		target: &ApplicationSpec{
+			Image:         "test-image",
+			RebuildPolicy: "Sometimes",
+		},
+		expected: validation.ErrInvalidValue("Sometimes", "rebuildPolicy"),
+	}, {
+		name: "valid cache policy",
+		target: &ApplicationSpec{
+			Image:     "test-image",
+			CacheSize: &cacheSize,
+			Cache: &BuildCachePolicy{
+				ResetAfterFailedBuilds: &resetAfterFailedBuilds,
+			},
+		},
+		expected: validation.FieldErrors{},
+	}, {
+		name: "invalid cache policy",
+		target: &ApplicationSpec{
+			Image:     "test-image",
+			CacheSize: &cacheSize,
+			Cache: &BuildCachePolicy{
+				ResetAfterFailedBuilds: &zero,
+			},
+		},
+		expected: validation.ErrInvalidValue(int32(0), "cache.resetAfterFailedBuilds"),
+	}, {
+		name: "cache policy without cache size",
+		target: &ApplicationSpec{
+			Image: "test-image",
+			Cache: &BuildCachePolicy{},
+		},
+		expected: validation.ErrMissingField("cacheSize"),
+	}, {
+		name: "default image",
+		target: &ApplicationSpec{
+			Image: "_",
+		},
+		expected: validation.FieldErrors{},
+	}, {
+		name: "default image prefix",
+		target: &ApplicationSpec{
+			Image: "_/test-image",
+		},
+		expected: validation.FieldErrors{},
+	}, {
+		name: "invalid default image prefix",
+		target: &ApplicationSpec{
+			Image: "_/Test Image",
+		},
+		expected: validation.ErrInvalidValue("_/Test Image", "image"),
+	}, {
+		name: "invalid image",
+		target: &ApplicationSpec{
+			Image: "example.com/Test Image",
+		},
+		expected: validation.ErrInvalidValue("example.com/Test Image", "image"),
+	}, {
+		name: "valid dockerfile",
+		target: &ApplicationSpec{
+			Image: "test-image",
+			Source: &Source{
+				Git: &Git{
+					URL:      "https://example.com/repo.git",
+					Revision: "master",
+				},
+			},
+			Dockerfile: &DockerfileBuild{
+				Path: "build/Dockerfile",
+			},
+		},
+		expected: validation.FieldErrors{},
+	}, {
+		name: "dockerfile requires source",
+		target: &ApplicationSpec{
+			Image: "test-image",
+			Dockerfile: &DockerfileBuild{
+				Path: "Dockerfile",
+			},
+		},
+		expected: validation.ErrMissingField("source"),
+	}, {
+		name: "dockerfile disallows registry source",
+		target: &ApplicationSpec{
+			Image: "test-image",
+			Source: &Source{
+				Registry: &Registry{
+					Image: "example.com/source",
+				},
+			},
+			Dockerfile: &DockerfileBuild{
+				Path: "Dockerfile",
+			},
+		},
+		expected: validation.ErrDisallowedFields("source.registry", "dockerfile builds require a git or blob source"),
+	}, {
+		name: "dockerfile disallows builder",
+		target: &ApplicationSpec{
+			Image: "test-image",
+			Source: &Source{
+				Git: &Git{
+					URL:      "https://example.com/repo.git",
+					Revision: "master",
+				},
+			},
+			Builder: &BuilderReference{
+				Kind: ClusterBuilderKind,
+				Name: "my-builder",
+			},
+			Dockerfile: &DockerfileBuild{
+				Path: "Dockerfile",
+			},
+		},
+		expected: validation.ErrDisallowedFields("builder", "dockerfile builds do not use a builder"),
+	}, {
+		name: "dockerfile path outside source",
+		target: &ApplicationSpec{
+			Image: "test-image",
+			Source: &Source{
+				Git: &Git{
+					URL:      "https://example.com/repo.git",
+					Revision: "master",
+				},
+			},
+			Dockerfile: &DockerfileBuild{
+				Path: "../Dockerfile",
+			},
+		},
+		expected: validation.ErrInvalidValue("../Dockerfile", "dockerfile.path"),
 	}} {
 		t.Run(c.name, func(t *testing.T) {
 			actual := c.target.Validate()
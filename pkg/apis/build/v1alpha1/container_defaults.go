@@ -31,4 +31,7 @@ func (s *ContainerSpec) Default() {
 	if s.Image == "" {
 		s.Image = "_"
 	}
+	if s.ServiceAccountName == "" {
+		s.ServiceAccountName = DefaultServiceAccountName
+	}
 }
@@ -32,7 +32,21 @@ func TestContainerDefault(t *testing.T) {
 		in:   &Container{},
 		want: &Container{
 			Spec: ContainerSpec{
-				Image: "_",
+				Image:              "_",
+				ServiceAccountName: "riff-build",
+			},
+		},
+	}, {
+		name: "service account",
+		in: &Container{
+			Spec: ContainerSpec{
+				ServiceAccountName: "my-builds",
+			},
+		},
+		want: &Container{
+			Spec: ContainerSpec{
+				Image:              "_",
+				ServiceAccountName: "my-builds",
 			},
 		},
 	}}
@@ -21,10 +21,14 @@ import (
 )
 
 const (
-	ContainerConditionReady                            = apis.ConditionReady
-	ContainerConditionImageResolved apis.ConditionType = "ImageResolved"
+	ContainerConditionReady                               = apis.ConditionReady
+	ContainerConditionImageResolved    apis.ConditionType = "ImageResolved"
+	ContainerConditionCredentialsReady apis.ConditionType = "CredentialsReady"
 )
 
+// containerCondSet gates readiness. CredentialsReady is informational: it is
+// only set while credentials are bound for the registry of the image and a
+// failing credential does not change Ready.
 var containerCondSet = apis.NewLivingConditionSet(
 	ContainerConditionImageResolved,
 )
@@ -49,6 +53,24 @@ func (cs *ContainerStatus) InitializeConditions() {
 	containerCondSet.Manage(cs).InitializeConditions()
 }
 
+// MarkCredentialsReady reports that a working credential is bound for the
+// registry of the target image.
+func (cs *ContainerStatus) MarkCredentialsReady() {
+	containerCondSet.Manage(cs).MarkTrue(ContainerConditionCredentialsReady)
+}
+
+// MarkCredentialsInvalid reports that credentials are bound for the registry
+// of the target image, but none of them are working.
+func (cs *ContainerStatus) MarkCredentialsInvalid(message string) {
+	containerCondSet.Manage(cs).MarkFalse(ContainerConditionCredentialsReady, "CredentialsInvalid", message)
+}
+
+// MarkCredentialsNotBound clears the credentials condition when no credentials
+// are bound for the registry of the target image.
+func (cs *ContainerStatus) MarkCredentialsNotBound() {
+	containerCondSet.Manage(cs).ClearCondition(ContainerConditionCredentialsReady)
+}
+
 func (cs *ContainerStatus) MarkImageDefaultPrefixMissing(message string) {
 	containerCondSet.Manage(cs).MarkFalse(ContainerConditionImageResolved, "DefaultImagePrefixMissing", message)
 }
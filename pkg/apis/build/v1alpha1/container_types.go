@@ -43,6 +43,38 @@ type ContainerSpec struct {
 	// to have the default image prefix applied, or be `_` to combine the default
 	// image prefix with the resource's name as a default value.
 	Image string `json:"image"`
+
+	// ServiceAccountName is the name of the ServiceAccount whose bound
+	// credentials are used to push and pull images. Defaults to `riff-build`.
+	// +optional
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+
+	// Platform selects the image for the platform when the image is an image
+	// index (a multi-arch manifest list). Without a platform, the digest of
+	// the index is used so each node pulls the image for its own platform.
+	// +optional
+	Platform *ImagePlatform `json:"platform,omitempty"`
+}
+
+// ImagePlatform identifies the platform of an image within an image index.
+type ImagePlatform struct {
+	// OS of the platform, like `linux`.
+	OS string `json:"os"`
+
+	// Architecture of the platform, like `amd64` or `arm64`.
+	Architecture string `json:"architecture"`
+
+	// Variant of the architecture, like `v7` for `arm`.
+	// +optional
+	Variant string `json:"variant,omitempty"`
+}
+
+// PlatformImage is the image for a platform within an image index.
+type PlatformImage struct {
+	ImagePlatform `json:",inline"`
+
+	// Image is the digest reference of the platform's image.
+	Image string `json:"image"`
 }
 
 // ContainerStatus defines the observed state of Container
@@ -52,6 +84,10 @@ type ContainerStatus struct {
 
 	apis.Status `json:",inline"`
 	BuildStatus `json:",inline"`
+
+	// Platforms are the images for each platform when the latest image is
+	// resolved from an image index.
+	Platforms []PlatformImage `json:"platforms,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -62,8 +62,23 @@ func (s *ContainerSpec) Validate() validation.FieldErrors {
 
 	errs := validation.FieldErrors{}
 
-	if s.Image == "" {
-		errs = errs.Also(validation.ErrMissingField("image"))
+	errs = errs.Also(validateImage(s.Image).ViaField("image"))
+
+	if s.Platform != nil {
+		errs = errs.Also(s.Platform.Validate().ViaField("platform"))
+	}
+
+	return errs
+}
+
+func (p *ImagePlatform) Validate() validation.FieldErrors {
+	errs := validation.FieldErrors{}
+
+	if p.OS == "" {
+		errs = errs.Also(validation.ErrMissingField("os"))
+	}
+	if p.Architecture == "" {
+		errs = errs.Also(validation.ErrMissingField("architecture"))
 	}
 
 	return errs
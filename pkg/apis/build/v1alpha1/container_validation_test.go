@@ -66,6 +66,51 @@ func TestValidateContainerSpec(t *testing.T) {
 			Image: "test-image",
 		},
 		expected: validation.FieldErrors{},
+	}, {
+		name: "default image",
+		target: &ContainerSpec{
+			Image: "_",
+		},
+		expected: validation.FieldErrors{},
+	}, {
+		name: "default image prefix",
+		target: &ContainerSpec{
+			Image: "_/test-image",
+		},
+		expected: validation.FieldErrors{},
+	}, {
+		name: "invalid default image prefix",
+		target: &ContainerSpec{
+			Image: "_/Test Image",
+		},
+		expected: validation.ErrInvalidValue("_/Test Image", "image"),
+	}, {
+		name: "invalid image",
+		target: &ContainerSpec{
+			Image: "example.com/Test Image",
+		},
+		expected: validation.ErrInvalidValue("example.com/Test Image", "image"),
+	}, {
+		name: "platform",
+		target: &ContainerSpec{
+			Image: "example.com/image",
+			Platform: &ImagePlatform{
+				OS:           "linux",
+				Architecture: "arm",
+				Variant:      "v7",
+			},
+		},
+		expected: validation.FieldErrors{},
+	}, {
+		name: "platform requires os and architecture",
+		target: &ContainerSpec{
+			Image:    "example.com/image",
+			Platform: &ImagePlatform{},
+		},
+		expected: validation.FieldErrors{}.Also(
+			validation.ErrMissingField("platform.os"),
+			validation.ErrMissingField("platform.architecture"),
+		),
 	}} {
 		t.Run(c.name, func(t *testing.T) {
 			actual := c.target.Validate()
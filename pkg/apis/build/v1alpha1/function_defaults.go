@@ -31,4 +31,10 @@ func (s *FunctionSpec) Default() {
 	if s.Image == "" {
 		s.Image = "_"
 	}
+	if s.ServiceAccountName == "" {
+		s.ServiceAccountName = DefaultServiceAccountName
+	}
+	if s.Builder != nil {
+		s.Builder.Default()
+	}
 }
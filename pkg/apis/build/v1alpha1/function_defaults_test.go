@@ -32,7 +32,21 @@ func TestFunctionDefault(t *testing.T) {
 		in:   &Function{},
 		want: &Function{
 			Spec: FunctionSpec{
-				Image: "_",
+				Image:              "_",
+				ServiceAccountName: "riff-build",
+			},
+		},
+	}, {
+		name: "service account",
+		in: &Function{
+			Spec: FunctionSpec{
+				ServiceAccountName: "my-builds",
+			},
+		},
+		want: &Function{
+			Spec: FunctionSpec{
+				Image:              "_",
+				ServiceAccountName: "my-builds",
 			},
 		},
 	}}
@@ -24,11 +24,15 @@ import (
 )
 
 const (
-	FunctionConditionReady                              = apis.ConditionReady
-	FunctionConditionKpackImageReady apis.ConditionType = "KpackImageReady"
-	FunctionConditionImageResolved   apis.ConditionType = "ImageResolved"
+	FunctionConditionReady                               = apis.ConditionReady
+	FunctionConditionKpackImageReady  apis.ConditionType = "KpackImageReady"
+	FunctionConditionImageResolved    apis.ConditionType = "ImageResolved"
+	FunctionConditionCredentialsReady apis.ConditionType = "CredentialsReady"
+	FunctionConditionUpToDate         apis.ConditionType = "UpToDate"
 )
 
+// functionCondSet gates readiness. CredentialsReady and UpToDate are
+// informational: they are only set when they apply, and neither changes Ready.
 var functionCondSet = apis.NewLivingConditionSet(
 	FunctionConditionKpackImageReady,
 	FunctionConditionImageResolved,
@@ -54,12 +58,42 @@ func (fs *FunctionStatus) InitializeConditions() {
 	functionCondSet.Manage(fs).InitializeConditions()
 }
 
+// MarkBuilderNotFound reports that the selected builder does not exist or is
+// not available for builds.
+func (fs *FunctionStatus) MarkBuilderNotFound(message string) {
+	functionCondSet.Manage(fs).MarkFalse(FunctionConditionKpackImageReady, "BuilderNotFound", message)
+}
+
+// MarkBuildEnvInvalid reports that a build environment variable references a
+// value that is not available.
+func (fs *FunctionStatus) MarkBuildEnvInvalid(message string) {
+	functionCondSet.Manage(fs).MarkFalse(FunctionConditionKpackImageReady, "BuildEnvInvalid", message)
+}
+
 func (fs *FunctionStatus) MarkBuildNotUsed() {
 	fs.KpackImageRef = nil
 	fs.BuildCacheRef = nil
 	functionCondSet.Manage(fs).MarkTrue(FunctionConditionKpackImageReady)
 }
 
+// MarkCredentialsReady reports that a working credential is bound for the
+// registry of the target image.
+func (fs *FunctionStatus) MarkCredentialsReady() {
+	functionCondSet.Manage(fs).MarkTrue(FunctionConditionCredentialsReady)
+}
+
+// MarkCredentialsInvalid reports that credentials are bound for the registry
+// of the target image, but none of them are working.
+func (fs *FunctionStatus) MarkCredentialsInvalid(message string) {
+	functionCondSet.Manage(fs).MarkFalse(FunctionConditionCredentialsReady, "CredentialsInvalid", message)
+}
+
+// MarkCredentialsNotBound clears the credentials condition when no credentials
+// are bound for the registry of the target image.
+func (fs *FunctionStatus) MarkCredentialsNotBound() {
+	functionCondSet.Manage(fs).ClearCondition(FunctionConditionCredentialsReady)
+}
+
 func (fs *FunctionStatus) MarkImageDefaultPrefixMissing(message string) {
 	functionCondSet.Manage(fs).MarkFalse(FunctionConditionImageResolved, "DefaultImagePrefixMissing", message)
 }
@@ -72,6 +106,30 @@ func (fs *FunctionStatus) MarkImageResolved() {
 	functionCondSet.Manage(fs).MarkTrue(FunctionConditionImageResolved)
 }
 
+// MarkUpToDate reports that the latest image was built with the current
+// builder image.
+func (fs *FunctionStatus) MarkUpToDate() {
+	functionCondSet.Manage(fs).MarkTrue(FunctionConditionUpToDate)
+}
+
+// MarkBuilderOutdated reports that the latest image was built with a builder
+// image that has since changed.
+func (fs *FunctionStatus) MarkBuilderOutdated(message string) {
+	functionCondSet.Manage(fs).MarkFalse(FunctionConditionUpToDate, "BuilderOutdated", message)
+}
+
+// MarkRebuildRunning reports that a build with the current builder image is
+// running.
+func (fs *FunctionStatus) MarkRebuildRunning(message string) {
+	functionCondSet.Manage(fs).MarkUnknown(FunctionConditionUpToDate, "RebuildRunning", message)
+}
+
+// MarkUpToDateNotApplicable clears the up to date condition when there is no
+// built image to compare.
+func (fs *FunctionStatus) MarkUpToDateNotApplicable() {
+	functionCondSet.Manage(fs).ClearCondition(FunctionConditionUpToDate)
+}
+
 func (fs *FunctionStatus) PropagateKpackImageStatus(is *kpackbuildv1alpha1.ImageStatus) {
 	sc := is.GetCondition(apis.ConditionReady)
 	if sc == nil {
@@ -48,6 +48,11 @@ type FunctionSpec struct {
 	// CacheSize of persistent volume to store resources between builds
 	CacheSize *resource.Quantity `json:"cacheSize,omitempty"`
 
+	// Cache policy for the build cache, like resetting the cache after
+	// repeated failed builds. Requires a CacheSize.
+	// +optional
+	Cache *BuildCachePolicy `json:"cache,omitempty"`
+
 	// Source location. Required for on cluster builds.
 	Source *Source `json:"source,omitempty"`
 
@@ -62,6 +67,20 @@ type FunctionSpec struct {
 	// +optional
 	Build ImageBuild `json:"build,omitempty"`
 
+	// Builder used to build images. Defaults to the `riff-function` ClusterBuilder.
+	// +optional
+	Builder *BuilderReference `json:"builder,omitempty"`
+
+	// ServiceAccountName is the name of the ServiceAccount whose bound
+	// credentials are used to push and pull images. Defaults to `riff-build`.
+	// +optional
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+
+	// RebuildPolicy controls whether images are rebuilt when the builder
+	// image changes. Either Never or BuilderUpdated, defaults to Never.
+	// +optional
+	RebuildPolicy RebuildPolicy `json:"rebuildPolicy,omitempty"`
+
 	// Artifact file containing the function within the build workspace.
 	Artifact string `json:"artifact,omitempty"`
 
@@ -80,6 +99,9 @@ type FunctionStatus struct {
 
 	apis.Status `json:",inline"`
 	BuildStatus `json:",inline"`
+
+	// Invoker detected from the buildpacks that built the latest image.
+	Invoker string `json:"invoker,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -62,13 +62,26 @@ func (s *FunctionSpec) Validate() validation.FieldErrors {
 
 	errs := validation.FieldErrors{}
 
-	if s.Image == "" {
-		errs = errs.Also(validation.ErrMissingField("image"))
-	}
+	errs = errs.Also(validateImage(s.Image).ViaField("image"))
 
 	if s.Source != nil {
 		errs = errs.Also(s.Source.Validate().ViaField("source"))
 	}
 
+	if s.Builder != nil {
+		errs = errs.Also(s.Builder.Validate().ViaField("builder"))
+	}
+
+	errs = errs.Also(validateBuildEnv(s.Build.Env).ViaField("build"))
+
+	if s.Cache != nil {
+		errs = errs.Also(s.Cache.Validate().ViaField("cache"))
+		if s.CacheSize == nil {
+			errs = errs.Also(validation.ErrMissingField("cacheSize"))
+		}
+	}
+
+	errs = errs.Also(s.RebuildPolicy.Validate().ViaField("rebuildPolicy"))
+
 	return errs
 }
@@ -20,6 +20,7 @@ import (
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/api/resource"
 
 	"github.com/projectriff/system/pkg/validation"
 )
@@ -58,6 +59,10 @@ func TestValidateFunction(t *testing.T) {
 }
 
 func TestValidateFunctionSpec(t *testing.T) {
+	cacheSize := resource.MustParse("1Gi")
+	resetAfterFailedBuilds := int32(3)
+	zero := int32(0)
+
 	for _, c := range []struct {
 		name     string
 		target   *FunctionSpec
@@ -102,6 +107,100 @@ func TestValidateFunctionSpec(t *testing.T) {
 			Source: &Source{},
 		},
 		expected: validation.ErrMissingField("source"),
+	}, {
+		name: "valid builder",
+		target: &FunctionSpec{
+			Image: "test-image",
+			Builder: &BuilderReference{
+				Kind: BuilderKind,
+				Name: "my-builder",
+			},
+		},
+		expected: validation.FieldErrors{},
+	}, {
+		name: "validates builder kind",
+		target: &FunctionSpec{
+			Image: "test-image",
+			Builder: &BuilderReference{
+				Kind: "Bogus",
+				Name: "my-builder",
+			},
+		},
+		expected: validation.ErrInvalidValue("Bogus", "builder.kind"),
+	}, {
+		name: "validates builder name",
+		target: &FunctionSpec{
+			Image: "test-image",
+			Builder: &BuilderReference{
+				Kind: ClusterBuilderKind,
+			},
+		},
+		expected: validation.ErrMissingField("builder.name"),
+	}, {
+		name: "valid rebuild policy",
+		target: &FunctionSpec{
+			Image:         "test-image",
+			RebuildPolicy: RebuildPolicyBuilderUpdated,
+		},
+		expected: validation.FieldErrors{},
+	}, {
+		name: "invalid rebuild policy",
+		target: &FunctionSpec{
+			Image:         "test-image",
+			RebuildPolicy: "Sometimes",
+		},
+		expected: validation.ErrInvalidValue("Sometimes", "rebuildPolicy"),
+	}, {
+		name: "valid cache policy",
+		target: &FunctionSpec{
+			Image:     "test-image",
+			CacheSize: &cacheSize,
+			Cache: &BuildCachePolicy{
+				ResetAfterFailedBuilds: &resetAfterFailedBuilds,
+			},
+		},
+		expected: validation.FieldErrors{},
+	}, {
+		name: "invalid cache policy",
+		target: &FunctionSpec{
+			Image:     "test-image",
+			CacheSize: &cacheSize,
+			Cache: &BuildCachePolicy{
+				ResetAfterFailedBuilds: &zero,
+			},
+		},
+		expected: validation.ErrInvalidValue(int32(0), "cache.resetAfterFailedBuilds"),
+	}, {
+		name: "cache policy without cache size",
+		target: &FunctionSpec{
+			Image: "test-image",
+			Cache: &BuildCachePolicy{},
+		},
+		expected: validation.ErrMissingField("cacheSize"),
+	}, {
+		name: "default image",
+		target: &FunctionSpec{
+			Image: "_",
+		},
+		expected: validation.FieldErrors{},
+	}, {
+		name: "default image prefix",
+		target: &FunctionSpec{
+			Image: "_/test-image",
+		},
+		expected: validation.FieldErrors{},
+	}, {
+		name: "invalid default image prefix",
+		target: &FunctionSpec{
+			Image: "_/Test Image",
+		},
+		expected: validation.ErrInvalidValue("_/Test Image", "image"),
+	}, {
+		name: "invalid image",
+		target: &FunctionSpec{
+			Image: "example.com/Test Image",
+		},
+		expected: validation.ErrInvalidValue("example.com/Test Image", "image"),
 	}} {
 		t.Run(c.name, func(t *testing.T) {
 			actual := c.target.Validate()
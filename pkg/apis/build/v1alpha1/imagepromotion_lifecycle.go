@@ -0,0 +1,82 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"github.com/projectriff/system/pkg/apis"
+)
+
+const (
+	ImagePromotionConditionReady                             = apis.ConditionReady
+	ImagePromotionConditionSourceResolved apis.ConditionType = "SourceResolved"
+	ImagePromotionConditionImagePromoted  apis.ConditionType = "ImagePromoted"
+)
+
+var imagePromotionCondSet = apis.NewLivingConditionSet(
+	ImagePromotionConditionSourceResolved,
+	ImagePromotionConditionImagePromoted,
+)
+
+func (ps *ImagePromotionStatus) GetObservedGeneration() int64 {
+	return ps.ObservedGeneration
+}
+
+func (ps *ImagePromotionStatus) IsReady() bool {
+	return imagePromotionCondSet.Manage(ps).IsHappy()
+}
+
+func (*ImagePromotionStatus) GetReadyConditionType() apis.ConditionType {
+	return ImagePromotionConditionReady
+}
+
+func (ps *ImagePromotionStatus) GetCondition(t apis.ConditionType) *apis.Condition {
+	return imagePromotionCondSet.Manage(ps).GetCondition(t)
+}
+
+func (ps *ImagePromotionStatus) InitializeConditions() {
+	imagePromotionCondSet.Manage(ps).InitializeConditions()
+}
+
+// MarkSourceNotFound reports that the referenced build resource does not
+// exist.
+func (ps *ImagePromotionStatus) MarkSourceNotFound(message string) {
+	imagePromotionCondSet.Manage(ps).MarkFalse(ImagePromotionConditionSourceResolved, "SourceNotFound", message)
+}
+
+// MarkSourceNotReady reports that the referenced build resource has not
+// produced an image yet.
+func (ps *ImagePromotionStatus) MarkSourceNotReady(message string) {
+	imagePromotionCondSet.Manage(ps).MarkUnknown(ImagePromotionConditionSourceResolved, "SourceNotReady", message)
+}
+
+func (ps *ImagePromotionStatus) MarkSourceInvalid(message string) {
+	imagePromotionCondSet.Manage(ps).MarkFalse(ImagePromotionConditionSourceResolved, "SourceInvalid", message)
+}
+
+func (ps *ImagePromotionStatus) MarkSourceResolved() {
+	imagePromotionCondSet.Manage(ps).MarkTrue(ImagePromotionConditionSourceResolved)
+}
+
+// MarkPromotionFailed reports that the image could not be copied to the
+// destination repository.
+func (ps *ImagePromotionStatus) MarkPromotionFailed(message string) {
+	imagePromotionCondSet.Manage(ps).MarkFalse(ImagePromotionConditionImagePromoted, "PromotionFailed", message)
+}
+
+func (ps *ImagePromotionStatus) MarkImagePromoted() {
+	imagePromotionCondSet.Manage(ps).MarkTrue(ImagePromotionConditionImagePromoted)
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	apis "github.com/projectriff/system/pkg/apis"
+)
+
+var (
+	ImagePromotionLabelKey = GroupVersion.Group + "/image-promotion"
+)
+
+var (
+	_ apis.Resource = (*ImagePromotion)(nil)
+)
+
+// ImagePromotionSpec defines the desired state of ImagePromotion
+type ImagePromotionSpec struct {
+	// Source of the image to promote.
+	Source ImagePromotionSource `json:"source"`
+
+	// Destination repository the image is copied to. When a tag is included,
+	// the tag is moved to the promoted image. Otherwise, the image is only
+	// addressable by its digest.
+	Destination string `json:"destination"`
+
+	// ServiceAccountName is the name of the ServiceAccount whose bound
+	// credentials are used to pull the source image and push the promoted
+	// image. Defaults to `riff-build`.
+	// +optional
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+}
+
+// ImagePromotionSource references the image to promote. Exactly one field
+// must be set.
+type ImagePromotionSource struct {
+	// ApplicationRef references an application in this namespace.
+	ApplicationRef string `json:"applicationRef,omitempty"`
+
+	// ContainerRef references a container in this namespace.
+	ContainerRef string `json:"containerRef,omitempty"`
+
+	// FunctionRef references a function in this namespace.
+	FunctionRef string `json:"functionRef,omitempty"`
+
+	// Image is a literal image reference, which must include a digest.
+	Image string `json:"image,omitempty"`
+}
+
+// ImagePromotionStatus defines the observed state of ImagePromotion
+type ImagePromotionStatus struct {
+	apis.Status `json:",inline"`
+
+	// SourceImage is the digest reference of the image being promoted.
+	SourceImage string `json:"sourceImage,omitempty"`
+
+	// LatestImage is the digest reference of the promoted image in the
+	// destination repository.
+	LatestImage string `json:"latestImage,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:categories="riff"
+// +kubebuilder:printcolumn:name="Latest Image",type=string,JSONPath=`.status.latestImage`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Reason",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].reason`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+// +genclient
+
+// ImagePromotion is the Schema for the imagepromotions API
+type ImagePromotion struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ImagePromotionSpec   `json:"spec,omitempty"`
+	Status ImagePromotionStatus `json:"status,omitempty"`
+}
+
+func (*ImagePromotion) GetGroupVersionKind() schema.GroupVersionKind {
+	return SchemeGroupVersion.WithKind("ImagePromotion")
+}
+
+func (p *ImagePromotion) GetStatus() apis.ResourceStatus {
+	return &p.Status
+}
+
+// +kubebuilder:object:root=true
+
+// ImagePromotionList contains a list of ImagePromotion
+type ImagePromotionList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ImagePromotion `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ImagePromotion{}, &ImagePromotionList{})
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"github.com/google/go-containerregistry/pkg/name"
+	"k8s.io/apimachinery/pkg/api/equality"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+
+	"github.com/projectriff/system/pkg/validation"
+)
+
+// +kubebuilder:webhook:path=/validate-build-projectriff-io-v1alpha1-imagepromotion,mutating=false,failurePolicy=fail,groups=build.projectriff.io,resources=imagepromotions,verbs=create;update,versions=v1alpha1,name=imagepromotions.build.projectriff.io
+
+var (
+	_ webhook.Validator         = &ImagePromotion{}
+	_ validation.FieldValidator = &ImagePromotion{}
+)
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *ImagePromotion) ValidateCreate() error {
+	return r.Validate().ToAggregate()
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *ImagePromotion) ValidateUpdate(old runtime.Object) error {
+	// TODO check for immutable fields
+	return r.Validate().ToAggregate()
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *ImagePromotion) ValidateDelete() error {
+	return nil
+}
+
+func (r *ImagePromotion) Validate() validation.FieldErrors {
+	errs := validation.FieldErrors{}
+
+	errs = errs.Also(r.Spec.Validate().ViaField("spec"))
+
+	return errs
+}
+
+func (s *ImagePromotionSpec) Validate() validation.FieldErrors {
+	if equality.Semantic.DeepEqual(s, &ImagePromotionSpec{}) {
+		return validation.ErrMissingField(validation.CurrentField)
+	}
+
+	errs := validation.FieldErrors{}
+
+	errs = errs.Also(s.Source.Validate().ViaField("source"))
+
+	if s.Destination == "" {
+		errs = errs.Also(validation.ErrMissingField("destination"))
+	} else if ref, err := name.ParseReference(s.Destination, name.WeakValidation); err != nil {
+		errs = errs.Also(validation.ErrInvalidValue(s.Destination, "destination"))
+	} else if _, ok := ref.(name.Digest); ok {
+		// the digest is determined by the source image
+		errs = errs.Also(validation.ErrInvalidValue(s.Destination, "destination"))
+	}
+
+	return errs
+}
+
+func (s *ImagePromotionSource) Validate() validation.FieldErrors {
+	if equality.Semantic.DeepEqual(s, &ImagePromotionSource{}) {
+		return validation.ErrMissingField(validation.CurrentField)
+	}
+
+	errs := validation.FieldErrors{}
+	used := []string{}
+	unused := []string{}
+
+	if s.ApplicationRef != "" {
+		used = append(used, "applicationRef")
+	} else {
+		unused = append(unused, "applicationRef")
+	}
+
+	if s.ContainerRef != "" {
+		used = append(used, "containerRef")
+	} else {
+		unused = append(unused, "containerRef")
+	}
+
+	if s.FunctionRef != "" {
+		used = append(used, "functionRef")
+	} else {
+		unused = append(unused, "functionRef")
+	}
+
+	if s.Image != "" {
+		used = append(used, "image")
+		if _, err := name.NewDigest(s.Image, name.WeakValidation); err != nil {
+			errs = errs.Also(validation.ErrInvalidValue(s.Image, "image"))
+		}
+	} else {
+		unused = append(unused, "image")
+	}
+
+	if len(used) == 0 {
+		errs = errs.Also(validation.ErrMissingOneOf(unused...))
+	} else if len(used) > 1 {
+		errs = errs.Also(validation.ErrMultipleOneOf(used...))
+	}
+
+	return errs
+}
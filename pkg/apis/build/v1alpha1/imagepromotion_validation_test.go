@@ -0,0 +1,180 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/projectriff/system/pkg/validation"
+)
+
+func TestValidateImagePromotion(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		target   *ImagePromotion
+		expected validation.FieldErrors
+	}{{
+		name:     "empty",
+		target:   &ImagePromotion{},
+		expected: validation.ErrMissingField("spec"),
+	}, {
+		name: "valid",
+		target: &ImagePromotion{
+			Spec: ImagePromotionSpec{
+				Source: ImagePromotionSource{
+					ApplicationRef: "my-application",
+				},
+				Destination: "example.com/prod/my-application",
+			},
+		},
+		expected: validation.FieldErrors{},
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			actual := c.target.Validate()
+			if diff := cmp.Diff(c.expected, actual); diff != "" {
+				t.Errorf("validateImagePromotion(%s) (-expected, +actual) = %v", c.name, diff)
+			}
+		})
+	}
+}
+
+func TestValidateImagePromotionSpec(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		target   *ImagePromotionSpec
+		expected validation.FieldErrors
+	}{{
+		name:     "empty",
+		target:   &ImagePromotionSpec{},
+		expected: validation.ErrMissingField(validation.CurrentField),
+	}, {
+		name: "valid",
+		target: &ImagePromotionSpec{
+			Source: ImagePromotionSource{
+				FunctionRef: "my-function",
+			},
+			Destination: "example.com/prod/my-function",
+		},
+		expected: validation.FieldErrors{},
+	}, {
+		name: "valid, tagged destination",
+		target: &ImagePromotionSpec{
+			Source: ImagePromotionSource{
+				FunctionRef: "my-function",
+			},
+			Destination: "example.com/prod/my-function:v1",
+		},
+		expected: validation.FieldErrors{},
+	}, {
+		name: "requires source",
+		target: &ImagePromotionSpec{
+			Destination: "example.com/prod/my-function",
+		},
+		expected: validation.ErrMissingField("source"),
+	}, {
+		name: "requires destination",
+		target: &ImagePromotionSpec{
+			Source: ImagePromotionSource{
+				FunctionRef: "my-function",
+			},
+		},
+		expected: validation.ErrMissingField("destination"),
+	}, {
+		name: "invalid destination",
+		target: &ImagePromotionSpec{
+			Source: ImagePromotionSource{
+				FunctionRef: "my-function",
+			},
+			Destination: "example.com/Prod",
+		},
+		expected: validation.ErrInvalidValue("example.com/Prod", "destination"),
+	}, {
+		name: "destination with digest",
+		target: &ImagePromotionSpec{
+			Source: ImagePromotionSource{
+				FunctionRef: "my-function",
+			},
+			Destination: "example.com/prod/my-function@sha256:cf8b4c69d5460f88530e1c80b8856a70801f31c50b191c8413043ba9b160a43e",
+		},
+		expected: validation.ErrInvalidValue("example.com/prod/my-function@sha256:cf8b4c69d5460f88530e1c80b8856a70801f31c50b191c8413043ba9b160a43e", "destination"),
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			actual := c.target.Validate()
+			if diff := cmp.Diff(c.expected, actual); diff != "" {
+				t.Errorf("validateImagePromotionSpec(%s) (-expected, +actual) = %v", c.name, diff)
+			}
+		})
+	}
+}
+
+func TestValidateImagePromotionSource(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		target   *ImagePromotionSource
+		expected validation.FieldErrors
+	}{{
+		name:     "empty",
+		target:   &ImagePromotionSource{},
+		expected: validation.ErrMissingField(validation.CurrentField),
+	}, {
+		name: "valid application",
+		target: &ImagePromotionSource{
+			ApplicationRef: "my-application",
+		},
+		expected: validation.FieldErrors{},
+	}, {
+		name: "valid container",
+		target: &ImagePromotionSource{
+			ContainerRef: "my-container",
+		},
+		expected: validation.FieldErrors{},
+	}, {
+		name: "valid function",
+		target: &ImagePromotionSource{
+			FunctionRef: "my-function",
+		},
+		expected: validation.FieldErrors{},
+	}, {
+		name: "valid image",
+		target: &ImagePromotionSource{
+			Image: "example.com/dev/my-image@sha256:cf8b4c69d5460f88530e1c80b8856a70801f31c50b191c8413043ba9b160a43e",
+		},
+		expected: validation.FieldErrors{},
+	}, {
+		name: "image without digest",
+		target: &ImagePromotionSource{
+			Image: "example.com/dev/my-image:latest",
+		},
+		expected: validation.ErrInvalidValue("example.com/dev/my-image:latest", "image"),
+	}, {
+		name: "multiple",
+		target: &ImagePromotionSource{
+			ApplicationRef: "my-application",
+			FunctionRef:    "my-function",
+		},
+		expected: validation.ErrMultipleOneOf("applicationRef", "functionRef"),
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			actual := c.target.Validate()
+			if diff := cmp.Diff(c.expected, actual); diff != "" {
+				t.Errorf("validateImagePromotionSource(%s) (-expected, +actual) = %v", c.name, diff)
+			}
+		})
+	}
+}
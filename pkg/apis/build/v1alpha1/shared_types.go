@@ -17,17 +17,55 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"bytes"
 	"fmt"
 	"strings"
+	"text/template"
+
+	"github.com/google/go-containerregistry/pkg/name"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
 
 	"github.com/projectriff/system/pkg/apis"
+	kpackbuildv1alpha1 "github.com/projectriff/system/pkg/apis/thirdparty/kpack/build/v1alpha1"
 	"github.com/projectriff/system/pkg/refs"
+	"github.com/projectriff/system/pkg/validation"
 )
 
+// DefaultServiceAccountName is the ServiceAccount used for builds that do not
+// specify a ServiceAccount.
+const DefaultServiceAccountName = "riff-build"
+
 var (
 	// credentials are not a CRD, but a Secret with this label
 	CredentialLabelKey       = GroupVersion.Group + "/credential"
 	CredentialsAnnotationKey = GroupVersion.Group + "/credentials"
+
+	// CredentialVerifiedAnnotationKey records when the result of verifying a
+	// credential against its registry last changed
+	CredentialVerifiedAnnotationKey = GroupVersion.Group + "/credential-verified"
+	// CredentialErrorAnnotationKey records the error from the most recent
+	// verification of a credential, absent when the credential is working
+	CredentialErrorAnnotationKey = GroupVersion.Group + "/credential-error"
+
+	// CredentialSelectorAnnotationKey opts a ServiceAccount into having
+	// credentials bound by riff. The value is a label selector restricting
+	// which credentials are bound, an empty value binds all credentials in the
+	// namespace. The default build ServiceAccount is always managed.
+	CredentialSelectorAnnotationKey = GroupVersion.Group + "/credential-selector"
+
+	// ClearBuildCacheAnnotationKey on an Application or Function requests
+	// that the build cache is cleared. The cache is cleared once for each new
+	// value, after any running build completes.
+	ClearBuildCacheAnnotationKey = GroupVersion.Group + "/clear-build-cache"
+
+	// SourceUploadAnnotationKey records the digest of the source archive most
+	// recently uploaded for an Application or Function
+	SourceUploadAnnotationKey = GroupVersion.Group + "/source-upload"
 )
 
 type BuildStatus struct {
@@ -35,6 +73,10 @@ type BuildStatus struct {
 	// for intermediate build resources.
 	BuildCacheRef *refs.TypedLocalObjectReference `json:"buildCacheRef,omitempty"`
 
+	// BuildCache describes the current build cache and when it was last
+	// reset.
+	BuildCache *BuildCacheStatus `json:"buildCache,omitempty"`
+
 	// KpackImageRef is a reference to the kpack Image backing this build.
 	KpackImageRef *refs.TypedLocalObjectReference `json:"kpackImageRef,omitempty"`
 
@@ -44,35 +86,388 @@ type BuildStatus struct {
 	// TargetImage is the resolved image repository where built images are
 	// pushed.
 	TargetImage string `json:"targetImage,omitempty"`
+
+	// BuilderRef is a reference to the kpack builder used for builds.
+	BuilderRef *refs.TypedLocalObjectReference `json:"builderRef,omitempty"`
+
+	// BuilderImage is the latest image of the builder used for builds.
+	BuilderImage string `json:"builderImage,omitempty"`
+
+	// Builds are the most recent builds of the kpack Image, newest first.
+	Builds []BuildSummary `json:"builds,omitempty"`
+
+	// BuildMetadata describes how the latest image was built.
+	BuildMetadata *BuildMetadata `json:"buildMetadata,omitempty"`
+}
+
+// BuildMetadata describes how an image was built, as recorded by the
+// buildpack lifecycle in the image's labels. Images not built by buildpacks
+// have no metadata beyond the image.
+type BuildMetadata struct {
+	// Image the metadata was read from.
+	Image string `json:"image"`
+
+	// Buildpacks that contributed to the image, with their versions.
+	Buildpacks kpackbuildv1alpha1.BuildpackMetadataList `json:"buildpacks,omitempty"`
+
+	// RunImage is the image the built layers are placed on.
+	RunImage string `json:"runImage,omitempty"`
+
+	// StackID identifies the stack of the build and run images.
+	StackID string `json:"stackId,omitempty"`
+
+	// BillOfMaterials lists the dependencies contributed by buildpacks.
+	BillOfMaterials []BillOfMaterialsEntry `json:"billOfMaterials,omitempty"`
+}
+
+// BillOfMaterialsEntry is a dependency contributed to an image by a buildpack
+type BillOfMaterialsEntry struct {
+	// Name of the dependency.
+	Name string `json:"name"`
+
+	// Version of the dependency, when known.
+	Version string `json:"version,omitempty"`
+
+	// Buildpack that contributed the dependency.
+	Buildpack string `json:"buildpack,omitempty"`
+}
+
+const (
+	// ClusterBuilderKind is the kind of a cluster scoped kpack builder
+	ClusterBuilderKind = "ClusterBuilder"
+	// BuilderKind is the kind of a namespace scoped kpack builder
+	BuilderKind = "Builder"
+)
+
+// BuilderReference selects the kpack builder used to build images
+type BuilderReference struct {
+	// Kind of the builder, either ClusterBuilder or Builder. Defaults to
+	// ClusterBuilder.
+	// +optional
+	Kind string `json:"kind,omitempty"`
+
+	// Name of the builder. A Builder must be in the same namespace as the
+	// build resource.
+	Name string `json:"name"`
+}
+
+func (r *BuilderReference) Default() {
+	if r.Kind == "" {
+		r.Kind = ClusterBuilderKind
+	}
+}
+
+func (r *BuilderReference) Validate() validation.FieldErrors {
+	errs := validation.FieldErrors{}
+
+	if r.Kind != ClusterBuilderKind && r.Kind != BuilderKind {
+		errs = errs.Also(validation.ErrInvalidValue(r.Kind, "kind"))
+	}
+	if r.Name == "" {
+		errs = errs.Also(validation.ErrMissingField("name"))
+	}
+
+	return errs
+}
+
+// RebuildPolicy controls whether images are rebuilt when the builder changes
+type RebuildPolicy string
+
+const (
+	// RebuildPolicyNever leaves rebuilds to kpack's own triggers
+	RebuildPolicyNever RebuildPolicy = "Never"
+	// RebuildPolicyBuilderUpdated forces a rebuild when the builder image
+	// changes, like when a new stack is published. kpack only rebuilds for
+	// changes to the Image spec, so the builder image is added to the build
+	// environment as RIFF_BUILDER_IMAGE. Buildpacks see the variable, it is
+	// set by riff and builds should not depend on it.
+	RebuildPolicyBuilderUpdated RebuildPolicy = "BuilderUpdated"
+)
+
+// Validate the rebuild policy, an empty policy is treated as Never
+func (p RebuildPolicy) Validate() validation.FieldErrors {
+	switch p {
+	case "", RebuildPolicyNever, RebuildPolicyBuilderUpdated:
+		return validation.FieldErrors{}
+	}
+	return validation.ErrInvalidValue(string(p), validation.CurrentField)
+}
+
+// BuildCachePolicy controls the lifecycle of the build cache
+type BuildCachePolicy struct {
+	// ResetAfterFailedBuilds clears the build cache after this many
+	// consecutive builds have failed with the cache. A corrupted cache is
+	// often the cause of repeated failures. Disabled when unset.
+	// +optional
+	ResetAfterFailedBuilds *int32 `json:"resetAfterFailedBuilds,omitempty"`
+}
+
+func (p *BuildCachePolicy) Validate() validation.FieldErrors {
+	errs := validation.FieldErrors{}
+
+	if p.ResetAfterFailedBuilds != nil && *p.ResetAfterFailedBuilds < 1 {
+		errs = errs.Also(validation.ErrInvalidValue(*p.ResetAfterFailedBuilds, "resetAfterFailedBuilds"))
+	}
+
+	return errs
+}
+
+// BuildCacheResetReason is why the build cache was reset
+type BuildCacheResetReason string
+
+const (
+	// BuildCacheResetRequested is a reset requested with the clear build
+	// cache annotation
+	BuildCacheResetRequested BuildCacheResetReason = "Requested"
+	// BuildCacheResetFailedBuilds is a reset after too many consecutive
+	// failed builds
+	BuildCacheResetFailedBuilds BuildCacheResetReason = "FailedBuilds"
+	// BuildCacheResetResized is a reset to shrink the cache, volumes can only
+	// be expanded in place
+	BuildCacheResetResized BuildCacheResetReason = "Resized"
+)
+
+// BuildCacheStatus describes the PersistentVolumeClaim used as the build
+// cache
+type BuildCacheStatus struct {
+	// Requested is the storage requested for the cache.
+	Requested *resource.Quantity `json:"requested,omitempty"`
+
+	// Capacity is the storage provisioned for the cache. Kubernetes does not
+	// report the storage used within a volume.
+	Capacity *resource.Quantity `json:"capacity,omitempty"`
+
+	// CreationTime is when the current cache was created.
+	CreationTime *metav1.Time `json:"creationTime,omitempty"`
+
+	// LastResetReason is why the cache was most recently reset, one of
+	// Requested, FailedBuilds or Resized.
+	LastResetReason BuildCacheResetReason `json:"lastResetReason,omitempty"`
+
+	// ClearRequest is the most recent value of the clear build cache
+	// annotation that was acted on.
+	ClearRequest string `json:"clearRequest,omitempty"`
+}
+
+// BuildOutcome is the result of a build
+type BuildOutcome string
+
+const (
+	BuildOutcomeRunning   BuildOutcome = "Running"
+	BuildOutcomeSucceeded BuildOutcome = "Succeeded"
+	BuildOutcomeFailed    BuildOutcome = "Failed"
+)
+
+// BuildSummary describes an individual kpack Build
+type BuildSummary struct {
+	// Name of the kpack Build.
+	Name string `json:"name"`
+
+	// BuildNumber is the sequence number of the build for the kpack Image.
+	BuildNumber int64 `json:"buildNumber"`
+
+	// Revision of the source that was built, when built from git.
+	Revision string `json:"revision,omitempty"`
+
+	// Reason the build was triggered, like CONFIG, COMMIT, BUILDPACK or STACK.
+	Reason string `json:"reason,omitempty"`
+
+	// StartTime is when the build was created.
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// CompletionTime is when the build finished, either successfully or not.
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+
+	// Outcome of the build, one of Running, Succeeded or Failed.
+	Outcome BuildOutcome `json:"outcome"`
+
+	// Message describing why the build failed.
+	Message string `json:"message,omitempty"`
+
+	// PodName is the name of the pod running the build, useful for logs.
+	PodName string `json:"podName,omitempty"`
+
+	// BuilderImage is the image of the builder used for the build.
+	BuilderImage string `json:"builderImage,omitempty"`
 }
 
 // +k8s:deepcopy-gen=false
 type ImageResource interface {
 	apis.Object
+	GetGroupVersionKind() schema.GroupVersionKind
 	GetImage() string
 }
 
-// ResolveDefaultImage applies the default image prefix as needed to an image.
+// ImageDefaults configure the image for resources that do not name a
+// repository explicitly.
+type ImageDefaults struct {
+	// Prefix is the registry and optional repository path prepended to
+	// default images.
+	Prefix string
+	// Template renders the image for resources whose image is '_'. The
+	// template may reference {{.Registry}}, {{.Namespace}}, {{.Kind}} and
+	// {{.Name}}. Defaults to `{{.Registry}}/{{.Name}}`.
+	Template string
+}
+
+// defaultImageTemplate matches the default image naming before templates
+// were configurable
+const defaultImageTemplate = "{{.Registry}}/{{.Name}}"
+
+// defaultImageRegistryPlaceholder stands in for the default image prefix when
+// validating images before the prefix is known
+const defaultImageRegistryPlaceholder = "registry.example.com"
+
+// ErrMissingDefaultPrefix is returned when a default image cannot be resolved
+// because the default image prefix is not configured.
+var ErrMissingDefaultPrefix = fmt.Errorf("missing default image prefix")
+
+// ResolveDefaultImage applies the image defaults as needed to an image.
 //
-// The default image prefix may apply to either a repository whose value is '_'
-// or a repository with a leading '_/'.
+// The defaults may apply to either a repository whose value is '_' or a
+// repository with a leading '_/'.
 //
 // For a leading '_/', the underscore is replaced with the default image prefix.
-// For a repository of '_', the default image prefix is combined with the name
-// of the build resource.
-func ResolveDefaultImage(resource ImageResource, defaultImagePrefix string) (string, error) {
-	if defaultImagePrefix == "" {
-		return "", fmt.Errorf("invalid default image prefix %q", defaultImagePrefix)
-	}
+// For a repository of '_', the image template is rendered with the default
+// image prefix as the registry and the namespace, lowercase kind and name of
+// the build resource.
+//
+// The resolved image must be a valid image reference.
+func ResolveDefaultImage(resource ImageResource, defaults ImageDefaults) (string, error) {
 	image := resource.GetImage()
-	if image == "_" {
-		// combine registry prefix and application name
-		image = fmt.Sprintf("%s/%s", defaultImagePrefix, resource.GetName())
-	} else if strings.HasPrefix(image, "_/") {
+	switch {
+	case image == "_":
+		tmpl := defaults.Template
+		if tmpl == "" {
+			if defaults.Prefix == "" {
+				return "", ErrMissingDefaultPrefix
+			}
+			tmpl = defaultImageTemplate
+		}
+		rendered, err := renderImageTemplate(tmpl, resource, defaults.Prefix)
+		if err != nil {
+			return "", err
+		}
+		image = rendered
+	case strings.HasPrefix(image, "_/"):
+		if defaults.Prefix == "" {
+			return "", ErrMissingDefaultPrefix
+		}
 		// add the prefix to the specified image name
-		image = strings.Replace(image, "_", defaultImagePrefix, 1)
-	} else {
+		image = strings.Replace(image, "_", defaults.Prefix, 1)
+	default:
 		return "", fmt.Errorf("unable to default registry")
 	}
+	if _, err := name.ParseReference(image); err != nil {
+		return "", fmt.Errorf("invalid default image %q: %v", image, err)
+	}
 	return image, nil
 }
+
+func renderImageTemplate(tmpl string, resource ImageResource, registry string) (string, error) {
+	t, err := template.New("image").Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("invalid default image template %q: %v", tmpl, err)
+	}
+	var image bytes.Buffer
+	err = t.Execute(&image, struct {
+		Registry  string
+		Namespace string
+		Kind      string
+		Name      string
+	}{
+		Registry:  registry,
+		Namespace: resource.GetNamespace(),
+		Kind:      strings.ToLower(resource.GetGroupVersionKind().Kind),
+		Name:      resource.GetName(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("invalid default image template %q: %v", tmpl, err)
+	}
+	return image.String(), nil
+}
+
+// validateImage checks that the image is a valid image reference. Images
+// using the default image prefix are checked with a placeholder registry.
+func validateImage(image string) validation.FieldErrors {
+	errs := validation.FieldErrors{}
+
+	if image == "" {
+		return errs.Also(validation.ErrMissingField(validation.CurrentField))
+	}
+	if image == "_" {
+		// resolved from the image template at runtime
+		return errs
+	}
+	ref := image
+	if strings.HasPrefix(image, "_/") {
+		ref = strings.Replace(image, "_", defaultImageRegistryPlaceholder, 1)
+	}
+	if _, err := name.ParseReference(ref); err != nil {
+		errs = errs.Also(validation.ErrInvalidValue(image, validation.CurrentField))
+	}
+
+	return errs
+}
+
+// validateBuildEnv checks that build environment variables are either literal
+// values or reference a key within a Secret or ConfigMap.
+func validateBuildEnv(env []corev1.EnvVar) validation.FieldErrors {
+	errs := validation.FieldErrors{}
+
+	names := sets.NewString()
+	for i, e := range env {
+		envErrs := validation.FieldErrors{}
+		if e.Name == "" {
+			envErrs = envErrs.Also(validation.ErrMissingField("name"))
+		} else if names.Has(e.Name) {
+			envErrs = envErrs.Also(validation.ErrDuplicateValue(e.Name, "name"))
+		}
+		names.Insert(e.Name)
+		if e.ValueFrom != nil {
+			envErrs = envErrs.Also(validateBuildEnvSource(e.ValueFrom).ViaField("valueFrom"))
+			if e.Value != "" {
+				envErrs = envErrs.Also(validation.ErrMultipleOneOf("value", "valueFrom"))
+			}
+		}
+		errs = errs.Also(envErrs.ViaFieldIndex("env", i))
+	}
+
+	return errs
+}
+
+func validateBuildEnvSource(source *corev1.EnvVarSource) validation.FieldErrors {
+	errs := validation.FieldErrors{}
+
+	if source.FieldRef != nil {
+		errs = errs.Also(validation.ErrDisallowedFields("fieldRef", "only secretKeyRef and configMapKeyRef are supported for builds"))
+	}
+	if source.ResourceFieldRef != nil {
+		errs = errs.Also(validation.ErrDisallowedFields("resourceFieldRef", "only secretKeyRef and configMapKeyRef are supported for builds"))
+	}
+	switch {
+	case source.SecretKeyRef != nil && source.ConfigMapKeyRef != nil:
+		errs = errs.Also(validation.ErrMultipleOneOf("secretKeyRef", "configMapKeyRef"))
+	case source.SecretKeyRef != nil:
+		errs = errs.Also(validateKeySelector(source.SecretKeyRef.Name, source.SecretKeyRef.Key).ViaField("secretKeyRef"))
+	case source.ConfigMapKeyRef != nil:
+		errs = errs.Also(validateKeySelector(source.ConfigMapKeyRef.Name, source.ConfigMapKeyRef.Key).ViaField("configMapKeyRef"))
+	case source.FieldRef == nil && source.ResourceFieldRef == nil:
+		errs = errs.Also(validation.ErrMissingOneOf("secretKeyRef", "configMapKeyRef"))
+	}
+
+	return errs
+}
+
+func validateKeySelector(name, key string) validation.FieldErrors {
+	errs := validation.FieldErrors{}
+
+	if name == "" {
+		errs = errs.Also(validation.ErrMissingField("name"))
+	}
+	if key == "" {
+		errs = errs.Also(validation.ErrMissingField("key"))
+	}
+
+	return errs
+}
@@ -0,0 +1,197 @@
+/*
+Copyright 2019 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/projectriff/system/pkg/validation"
+)
+
+func TestResolveDefaultImage(t *testing.T) {
+	for _, c := range []struct {
+		name        string
+		image       string
+		defaults    ImageDefaults
+		expected    string
+		expectedErr string
+	}{{
+		name:     "default image",
+		image:    "_",
+		defaults: ImageDefaults{Prefix: "example.com/repo"},
+		expected: "example.com/repo/my-app",
+	}, {
+		name:     "default image prefix",
+		image:    "_/other",
+		defaults: ImageDefaults{Prefix: "example.com/repo"},
+		expected: "example.com/repo/other",
+	}, {
+		name:     "template",
+		image:    "_",
+		defaults: ImageDefaults{Prefix: "example.com/repo", Template: "{{.Registry}}/{{.Namespace}}/{{.Kind}}-{{.Name}}"},
+		expected: "example.com/repo/my-namespace/application-my-app",
+	}, {
+		name:     "template without prefix",
+		image:    "_",
+		defaults: ImageDefaults{Template: "registry.example.com/{{.Namespace}}/{{.Name}}"},
+		expected: "registry.example.com/my-namespace/my-app",
+	}, {
+		name:        "template ignored for default image prefix",
+		image:       "_/other",
+		defaults:    ImageDefaults{Template: "registry.example.com/{{.Name}}"},
+		expectedErr: "missing default image prefix",
+	}, {
+		name:        "missing prefix",
+		image:       "_",
+		defaults:    ImageDefaults{},
+		expectedErr: "missing default image prefix",
+	}, {
+		name:        "invalid template",
+		image:       "_",
+		defaults:    ImageDefaults{Prefix: "example.com/repo", Template: "{{.Registry"},
+		expectedErr: `invalid default image template "{{.Registry": template: image:1: unclosed action`,
+	}, {
+		name:        "invalid reference",
+		image:       "_",
+		defaults:    ImageDefaults{Prefix: "example.com/Repo"},
+		expectedErr: `invalid default image "example.com/Repo/my-app": could not parse reference: example.com/Repo/my-app`,
+	}, {
+		name:        "not a default image",
+		image:       "example.com/repo/my-app",
+		defaults:    ImageDefaults{Prefix: "example.com/repo"},
+		expectedErr: "unable to default registry",
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			app := &Application{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "my-namespace",
+					Name:      "my-app",
+				},
+				Spec: ApplicationSpec{
+					Image: c.image,
+				},
+			}
+			actual, err := ResolveDefaultImage(app, c.defaults)
+			if c.expectedErr != "" {
+				if err == nil || err.Error() != c.expectedErr {
+					t.Errorf("ResolveDefaultImage() error = %v, expected %q", err, c.expectedErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResolveDefaultImage() unexpected error = %v", err)
+			}
+			if actual != c.expected {
+				t.Errorf("ResolveDefaultImage() = %q, expected %q", actual, c.expected)
+			}
+		})
+	}
+}
+
+func TestValidateBuildEnv(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		env      []corev1.EnvVar
+		expected validation.FieldErrors
+	}{{
+		name:     "empty",
+		env:      []corev1.EnvVar{},
+		expected: validation.FieldErrors{},
+	}, {
+		name: "literal value",
+		env: []corev1.EnvVar{
+			{Name: "BP_JAVA_VERSION", Value: "11"},
+		},
+		expected: validation.FieldErrors{},
+	}, {
+		name: "secret and configmap references",
+		env: []corev1.EnvVar{
+			{Name: "NPM_TOKEN", ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "npm"},
+					Key:                  "token",
+				},
+			}},
+			{Name: "MAVEN_MIRROR", ValueFrom: &corev1.EnvVarSource{
+				ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "maven"},
+					Key:                  "mirror",
+				},
+			}},
+		},
+		expected: validation.FieldErrors{},
+	}, {
+		name: "requires name",
+		env: []corev1.EnvVar{
+			{Value: "11"},
+		},
+		expected: validation.ErrMissingField("env[0].name"),
+	}, {
+		name: "duplicate name",
+		env: []corev1.EnvVar{
+			{Name: "BP_JAVA_VERSION", Value: "8"},
+			{Name: "BP_JAVA_VERSION", Value: "11"},
+		},
+		expected: validation.ErrDuplicateValue("BP_JAVA_VERSION", "env[1].name"),
+	}, {
+		name: "value and value from",
+		env: []corev1.EnvVar{
+			{Name: "NPM_TOKEN", Value: "token", ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "npm"},
+					Key:                  "token",
+				},
+			}},
+		},
+		expected: validation.ErrMultipleOneOf("value", "valueFrom").ViaFieldIndex("env", 0),
+	}, {
+		name: "empty value from",
+		env: []corev1.EnvVar{
+			{Name: "NPM_TOKEN", ValueFrom: &corev1.EnvVarSource{}},
+		},
+		expected: validation.ErrMissingOneOf("secretKeyRef", "configMapKeyRef").ViaField("valueFrom").ViaFieldIndex("env", 0),
+	}, {
+		name: "secret reference requires key",
+		env: []corev1.EnvVar{
+			{Name: "NPM_TOKEN", ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "npm"},
+				},
+			}},
+		},
+		expected: validation.ErrMissingField("env[0].valueFrom.secretKeyRef.key"),
+	}, {
+		name: "field reference",
+		env: []corev1.EnvVar{
+			{Name: "POD_NAME", ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.name"},
+			}},
+		},
+		expected: validation.ErrDisallowedFields("env[0].valueFrom.fieldRef", "only secretKeyRef and configMapKeyRef are supported for builds"),
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			actual := validateBuildEnv(c.env)
+			if diff := cmp.Diff(c.expected, actual); diff != "" {
+				t.Errorf("validateBuildEnv(%s) (-expected, +actual) = %v", c.name, diff)
+			}
+		})
+	}
+}
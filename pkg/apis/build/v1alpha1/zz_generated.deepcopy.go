@@ -93,6 +93,11 @@ func (in *ApplicationSpec) DeepCopyInto(out *ApplicationSpec) {
 		x := (*in).DeepCopy()
 		*out = &x
 	}
+	if in.Cache != nil {
+		in, out := &in.Cache, &out.Cache
+		*out = new(BuildCachePolicy)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Source != nil {
 		in, out := &in.Source, &out.Source
 		*out = new(buildv1alpha1.SourceConfig)
@@ -109,6 +114,16 @@ func (in *ApplicationSpec) DeepCopyInto(out *ApplicationSpec) {
 		**out = **in
 	}
 	in.Build.DeepCopyInto(&out.Build)
+	if in.Builder != nil {
+		in, out := &in.Builder, &out.Builder
+		*out = new(BuilderReference)
+		**out = **in
+	}
+	if in.Dockerfile != nil {
+		in, out := &in.Dockerfile, &out.Dockerfile
+		*out = new(DockerfileBuild)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApplicationSpec.
@@ -126,6 +141,14 @@ func (in *ApplicationStatus) DeepCopyInto(out *ApplicationStatus) {
 	*out = *in
 	in.Status.DeepCopyInto(&out.Status)
 	in.BuildStatus.DeepCopyInto(&out.BuildStatus)
+	if in.DockerfileJobRef != nil {
+		in, out := &in.DockerfileJobRef, &out.DockerfileJobRef
+		*out = (*in).DeepCopy()
+	}
+	if in.DockerfileSourceResolverRef != nil {
+		in, out := &in.DockerfileSourceResolverRef, &out.DockerfileSourceResolverRef
+		*out = (*in).DeepCopy()
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApplicationStatus.
@@ -138,6 +161,110 @@ func (in *ApplicationStatus) DeepCopy() *ApplicationStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BuildCachePolicy) DeepCopyInto(out *BuildCachePolicy) {
+	*out = *in
+	if in.ResetAfterFailedBuilds != nil {
+		in, out := &in.ResetAfterFailedBuilds, &out.ResetAfterFailedBuilds
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BuildCachePolicy.
+func (in *BuildCachePolicy) DeepCopy() *BuildCachePolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(BuildCachePolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BuildCacheStatus) DeepCopyInto(out *BuildCacheStatus) {
+	*out = *in
+	if in.Requested != nil {
+		in, out := &in.Requested, &out.Requested
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.Capacity != nil {
+		in, out := &in.Capacity, &out.Capacity
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.CreationTime != nil {
+		in, out := &in.CreationTime, &out.CreationTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BuildCacheStatus.
+func (in *BuildCacheStatus) DeepCopy() *BuildCacheStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(BuildCacheStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BuilderReference) DeepCopyInto(out *BuilderReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BuilderReference.
+func (in *BuilderReference) DeepCopy() *BuilderReference {
+	if in == nil {
+		return nil
+	}
+	out := new(BuilderReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BillOfMaterialsEntry) DeepCopyInto(out *BillOfMaterialsEntry) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BillOfMaterialsEntry.
+func (in *BillOfMaterialsEntry) DeepCopy() *BillOfMaterialsEntry {
+	if in == nil {
+		return nil
+	}
+	out := new(BillOfMaterialsEntry)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BuildMetadata) DeepCopyInto(out *BuildMetadata) {
+	*out = *in
+	if in.Buildpacks != nil {
+		in, out := &in.Buildpacks, &out.Buildpacks
+		*out = make(buildv1alpha1.BuildpackMetadataList, len(*in))
+		copy(*out, *in)
+	}
+	if in.BillOfMaterials != nil {
+		in, out := &in.BillOfMaterials, &out.BillOfMaterials
+		*out = make([]BillOfMaterialsEntry, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BuildMetadata.
+func (in *BuildMetadata) DeepCopy() *BuildMetadata {
+	if in == nil {
+		return nil
+	}
+	out := new(BuildMetadata)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BuildStatus) DeepCopyInto(out *BuildStatus) {
 	*out = *in
@@ -145,10 +272,31 @@ func (in *BuildStatus) DeepCopyInto(out *BuildStatus) {
 		in, out := &in.BuildCacheRef, &out.BuildCacheRef
 		*out = (*in).DeepCopy()
 	}
+	if in.BuildCache != nil {
+		in, out := &in.BuildCache, &out.BuildCache
+		*out = new(BuildCacheStatus)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.KpackImageRef != nil {
 		in, out := &in.KpackImageRef, &out.KpackImageRef
 		*out = (*in).DeepCopy()
 	}
+	if in.BuilderRef != nil {
+		in, out := &in.BuilderRef, &out.BuilderRef
+		*out = (*in).DeepCopy()
+	}
+	if in.Builds != nil {
+		in, out := &in.Builds, &out.Builds
+		*out = make([]BuildSummary, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.BuildMetadata != nil {
+		in, out := &in.BuildMetadata, &out.BuildMetadata
+		*out = new(BuildMetadata)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BuildStatus.
@@ -161,12 +309,35 @@ func (in *BuildStatus) DeepCopy() *BuildStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BuildSummary) DeepCopyInto(out *BuildSummary) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BuildSummary.
+func (in *BuildSummary) DeepCopy() *BuildSummary {
+	if in == nil {
+		return nil
+	}
+	out := new(BuildSummary)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Container) DeepCopyInto(out *Container) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
 	in.Status.DeepCopyInto(&out.Status)
 }
 
@@ -223,6 +394,11 @@ func (in *ContainerList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ContainerSpec) DeepCopyInto(out *ContainerSpec) {
 	*out = *in
+	if in.Platform != nil {
+		in, out := &in.Platform, &out.Platform
+		*out = new(ImagePlatform)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ContainerSpec.
@@ -240,6 +416,11 @@ func (in *ContainerStatus) DeepCopyInto(out *ContainerStatus) {
 	*out = *in
 	in.Status.DeepCopyInto(&out.Status)
 	in.BuildStatus.DeepCopyInto(&out.BuildStatus)
+	if in.Platforms != nil {
+		in, out := &in.Platforms, &out.Platforms
+		*out = make([]PlatformImage, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ContainerStatus.
@@ -252,6 +433,21 @@ func (in *ContainerStatus) DeepCopy() *ContainerStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DockerfileBuild) DeepCopyInto(out *DockerfileBuild) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DockerfileBuild.
+func (in *DockerfileBuild) DeepCopy() *DockerfileBuild {
+	if in == nil {
+		return nil
+	}
+	out := new(DockerfileBuild)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Function) DeepCopyInto(out *Function) {
 	*out = *in
@@ -319,6 +515,11 @@ func (in *FunctionSpec) DeepCopyInto(out *FunctionSpec) {
 		x := (*in).DeepCopy()
 		*out = &x
 	}
+	if in.Cache != nil {
+		in, out := &in.Cache, &out.Cache
+		*out = new(BuildCachePolicy)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Source != nil {
 		in, out := &in.Source, &out.Source
 		*out = new(buildv1alpha1.SourceConfig)
@@ -335,6 +536,11 @@ func (in *FunctionSpec) DeepCopyInto(out *FunctionSpec) {
 		**out = **in
 	}
 	in.Build.DeepCopyInto(&out.Build)
+	if in.Builder != nil {
+		in, out := &in.Builder, &out.Builder
+		*out = new(BuilderReference)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FunctionSpec.
@@ -363,3 +569,140 @@ func (in *FunctionStatus) DeepCopy() *FunctionStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImagePlatform) DeepCopyInto(out *ImagePlatform) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImagePlatform.
+func (in *ImagePlatform) DeepCopy() *ImagePlatform {
+	if in == nil {
+		return nil
+	}
+	out := new(ImagePlatform)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImagePromotion) DeepCopyInto(out *ImagePromotion) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImagePromotion.
+func (in *ImagePromotion) DeepCopy() *ImagePromotion {
+	if in == nil {
+		return nil
+	}
+	out := new(ImagePromotion)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ImagePromotion) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImagePromotionList) DeepCopyInto(out *ImagePromotionList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ImagePromotion, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImagePromotionList.
+func (in *ImagePromotionList) DeepCopy() *ImagePromotionList {
+	if in == nil {
+		return nil
+	}
+	out := new(ImagePromotionList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ImagePromotionList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImagePromotionSource) DeepCopyInto(out *ImagePromotionSource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImagePromotionSource.
+func (in *ImagePromotionSource) DeepCopy() *ImagePromotionSource {
+	if in == nil {
+		return nil
+	}
+	out := new(ImagePromotionSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImagePromotionSpec) DeepCopyInto(out *ImagePromotionSpec) {
+	*out = *in
+	out.Source = in.Source
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImagePromotionSpec.
+func (in *ImagePromotionSpec) DeepCopy() *ImagePromotionSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ImagePromotionSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImagePromotionStatus) DeepCopyInto(out *ImagePromotionStatus) {
+	*out = *in
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImagePromotionStatus.
+func (in *ImagePromotionStatus) DeepCopy() *ImagePromotionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ImagePromotionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PlatformImage) DeepCopyInto(out *PlatformImage) {
+	*out = *in
+	out.ImagePlatform = in.ImagePlatform
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlatformImage.
+func (in *PlatformImage) DeepCopy() *PlatformImage {
+	if in == nil {
+		return nil
+	}
+	out := new(PlatformImage)
+	in.DeepCopyInto(out)
+	return out
+}
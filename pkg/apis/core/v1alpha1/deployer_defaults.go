@@ -64,4 +64,50 @@ func (s *DeployerSpec) Default() {
 	if s.IngressPolicy == "" {
 		s.IngressPolicy = IngressPolicyClusterLocal
 	}
+
+	if s.Ingress != nil {
+		s.Ingress.Default()
+	}
+
+	if s.Rollout != nil {
+		s.Rollout.Default()
+	}
+
+	if s.Scale != nil {
+		s.Scale.Default()
+	}
+
+	for i := range s.Bindings {
+		s.Bindings[i].Default()
+	}
+}
+
+func (i *Ingress) Default() {
+	if i.Path == "" {
+		i.Path = "/"
+	}
+}
+
+func (s *Scale) Default() {
+	if s.Min == nil {
+		min := int32(1)
+		s.Min = &min
+	}
+	if s.Max == nil {
+		max := *s.Min
+		s.Max = &max
+	}
+}
+
+func (b *DeployerBinding) Default() {
+	if b.Ref.APIVersion == "" && b.Ref.Kind == "" {
+		b.Ref.APIVersion = "streaming.projectriff.io/v1alpha1"
+		b.Ref.Kind = "Stream"
+	}
+}
+
+func (r *Rollout) Default() {
+	if r.TrafficRouting == "" {
+		r.TrafficRouting = RolloutTrafficRoutingReplicas
+	}
 }
@@ -177,6 +177,274 @@ func TestDeployerSpecDefault(t *testing.T) {
 			},
 			IngressPolicy: IngressPolicyExternal,
 		},
+	}, {
+		name: "default rollout traffic routing",
+		in: &DeployerSpec{
+			Rollout: &Rollout{
+				Steps: []RolloutStep{{Weight: 50}},
+			},
+		},
+		want: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{},
+					Labels:      map[string]string{},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name: "handler",
+							Ports: []corev1.ContainerPort{
+								{Name: "http", Protocol: corev1.ProtocolTCP, ContainerPort: 8080},
+							},
+						},
+					},
+				},
+			},
+			IngressPolicy: IngressPolicyClusterLocal,
+			Rollout: &Rollout{
+				Steps:          []RolloutStep{{Weight: 50}},
+				TrafficRouting: RolloutTrafficRoutingReplicas,
+			},
+		},
+	}, {
+		name: "preserve rollout traffic routing",
+		in: &DeployerSpec{
+			IngressPolicy: IngressPolicyExternal,
+			Rollout: &Rollout{
+				Steps:          []RolloutStep{{Weight: 50}},
+				TrafficRouting: RolloutTrafficRoutingIngress,
+			},
+		},
+		want: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{},
+					Labels:      map[string]string{},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name: "handler",
+							Ports: []corev1.ContainerPort{
+								{Name: "http", Protocol: corev1.ProtocolTCP, ContainerPort: 8080},
+							},
+						},
+					},
+				},
+			},
+			IngressPolicy: IngressPolicyExternal,
+			Rollout: &Rollout{
+				Steps:          []RolloutStep{{Weight: 50}},
+				TrafficRouting: RolloutTrafficRoutingIngress,
+			},
+		},
+	}, {
+		name: "default ingress path",
+		in: &DeployerSpec{
+			IngressPolicy: IngressPolicyExternal,
+			Ingress:       &Ingress{},
+		},
+		want: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{},
+					Labels:      map[string]string{},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name: "handler",
+							Ports: []corev1.ContainerPort{
+								{Name: "http", Protocol: corev1.ProtocolTCP, ContainerPort: 8080},
+							},
+						},
+					},
+				},
+			},
+			IngressPolicy: IngressPolicyExternal,
+			Ingress:       &Ingress{Path: "/"},
+		},
+	}, {
+		name: "preserve ingress path",
+		in: &DeployerSpec{
+			IngressPolicy: IngressPolicyExternal,
+			Ingress:       &Ingress{Path: "/api"},
+		},
+		want: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{},
+					Labels:      map[string]string{},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name: "handler",
+							Ports: []corev1.ContainerPort{
+								{Name: "http", Protocol: corev1.ProtocolTCP, ContainerPort: 8080},
+							},
+						},
+					},
+				},
+			},
+			IngressPolicy: IngressPolicyExternal,
+			Ingress:       &Ingress{Path: "/api"},
+		},
+	}, {
+		name: "default scale",
+		in: &DeployerSpec{
+			Scale: &Scale{},
+		},
+		want: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{},
+					Labels:      map[string]string{},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name: "handler",
+							Ports: []corev1.ContainerPort{
+								{Name: "http", Protocol: corev1.ProtocolTCP, ContainerPort: 8080},
+							},
+						},
+					},
+				},
+			},
+			IngressPolicy: IngressPolicyClusterLocal,
+			Scale:         &Scale{Min: int32Ptr(1), Max: int32Ptr(1)},
+		},
+	}, {
+		name: "default scale max to min",
+		in: &DeployerSpec{
+			Scale: &Scale{Min: int32Ptr(3)},
+		},
+		want: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{},
+					Labels:      map[string]string{},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name: "handler",
+							Ports: []corev1.ContainerPort{
+								{Name: "http", Protocol: corev1.ProtocolTCP, ContainerPort: 8080},
+							},
+						},
+					},
+				},
+			},
+			IngressPolicy: IngressPolicyClusterLocal,
+			Scale:         &Scale{Min: int32Ptr(3), Max: int32Ptr(3)},
+		},
+	}, {
+		name: "preserve scale",
+		in: &DeployerSpec{
+			Scale: &Scale{Min: int32Ptr(2), Max: int32Ptr(5)},
+		},
+		want: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{},
+					Labels:      map[string]string{},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name: "handler",
+							Ports: []corev1.ContainerPort{
+								{Name: "http", Protocol: corev1.ProtocolTCP, ContainerPort: 8080},
+							},
+						},
+					},
+				},
+			},
+			IngressPolicy: IngressPolicyClusterLocal,
+			Scale:         &Scale{Min: int32Ptr(2), Max: int32Ptr(5)},
+		},
+	}, {
+		name: "default binding ref to stream",
+		in: &DeployerSpec{
+			Bindings: []DeployerBinding{
+				{Name: "my-binding", Ref: BindableReference{Name: "my-stream"}},
+			},
+		},
+		want: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{},
+					Labels:      map[string]string{},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name: "handler",
+							Ports: []corev1.ContainerPort{
+								{Name: "http", Protocol: corev1.ProtocolTCP, ContainerPort: 8080},
+							},
+						},
+					},
+				},
+			},
+			IngressPolicy: IngressPolicyClusterLocal,
+			Bindings: []DeployerBinding{
+				{
+					Name: "my-binding",
+					Ref: BindableReference{
+						APIVersion: "streaming.projectriff.io/v1alpha1",
+						Kind:       "Stream",
+						Name:       "my-stream",
+					},
+				},
+			},
+		},
+	}, {
+		name: "preserve binding ref",
+		in: &DeployerSpec{
+			Bindings: []DeployerBinding{
+				{
+					Name: "my-binding",
+					Ref: BindableReference{
+						APIVersion: "example.com/v1",
+						Kind:       "Database",
+						Name:       "my-database",
+					},
+				},
+			},
+		},
+		want: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{},
+					Labels:      map[string]string{},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name: "handler",
+							Ports: []corev1.ContainerPort{
+								{Name: "http", Protocol: corev1.ProtocolTCP, ContainerPort: 8080},
+							},
+						},
+					},
+				},
+			},
+			IngressPolicy: IngressPolicyClusterLocal,
+			Bindings: []DeployerBinding{
+				{
+					Name: "my-binding",
+					Ref: BindableReference{
+						APIVersion: "example.com/v1",
+						Kind:       "Database",
+						Name:       "my-database",
+					},
+				},
+			},
+		},
 	}}
 
 	for _, test := range tests {
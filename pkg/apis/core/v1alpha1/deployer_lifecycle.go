@@ -17,11 +17,15 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"time"
+
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	networkingv1beta1 "k8s.io/api/networking/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	apis "github.com/projectriff/system/pkg/apis"
+	gatewayv1 "github.com/projectriff/system/pkg/apis/thirdparty/gateway/v1"
 )
 
 const (
@@ -70,6 +74,11 @@ func (ds *DeployerStatus) PropagateDeploymentStatus(cds *appsv1.DeploymentStatus
 	if available == nil || progressing == nil {
 		return
 	}
+	if progressing.Status == corev1.ConditionFalse {
+		// the rollout failed, typically with ProgressDeadlineExceeded, even if pods from a previous rollout remain available
+		deployerCondSet.Manage(ds).MarkFalse(DeployerConditionDeploymentReady, progressing.Reason, progressing.Message)
+		return
+	}
 	if progressing.Status == corev1.ConditionTrue && available.Status == corev1.ConditionFalse {
 		// DeploymentAvailable is False while progressing, avoid reporting DeployerConditionReady as False
 		deployerCondSet.Manage(ds).MarkUnknown(DeployerConditionDeploymentReady, progressing.Reason, progressing.Message)
@@ -104,3 +113,98 @@ func (ds *DeployerStatus) PropagateIngressStatus(is *networkingv1beta1.IngressSt
 func (ds *DeployerStatus) MarkIngressNotRequired() {
 	deployerCondSet.Manage(ds).MarkTrue(DeployerConditionIngressReady)
 }
+
+func (ds *DeployerStatus) MarkIngressCertificateMissing(name string) {
+	deployerCondSet.Manage(ds).MarkFalse(DeployerConditionIngressReady, "CertificateMissing", "The certificate Secret %q was not found.", name)
+}
+
+func (ds *DeployerStatus) MarkIngressCertificatePending(name string) {
+	deployerCondSet.Manage(ds).MarkUnknown(DeployerConditionIngressReady, "CertificatePending", "Waiting for a certificate to be issued into Secret %q.", name)
+}
+
+func (ds *DeployerStatus) MarkIngressCertificateInvalid(name, message string) {
+	deployerCondSet.Manage(ds).MarkFalse(DeployerConditionIngressReady, "CertificateInvalid", "The certificate in Secret %q is invalid: %s", name, message)
+}
+
+func (ds *DeployerStatus) MarkIngressCertificateExpired(name string, notAfter time.Time) {
+	deployerCondSet.Manage(ds).MarkFalse(DeployerConditionIngressReady, "CertificateExpired", "The certificate in Secret %q expired at %s.", name, notAfter.UTC().Format(time.RFC3339))
+}
+
+// PropagateHTTPRouteStatus updates DeployerConditionIngressReady according
+// to the status reported by the gateway for the HTTPRoute.
+func (ds *DeployerStatus) PropagateHTTPRouteStatus(rs *gatewayv1.HTTPRouteStatus, gateway gatewayv1.ParentReference) {
+	for _, ps := range rs.Parents {
+		if !sameParentReference(ps.ParentRef, gateway) {
+			continue
+		}
+		var accepted, resolvedRefs *gatewayv1.Condition
+		for i := range ps.Conditions {
+			switch ps.Conditions[i].Type {
+			case gatewayv1.RouteConditionAccepted:
+				accepted = &ps.Conditions[i]
+			case gatewayv1.RouteConditionResolvedRefs:
+				resolvedRefs = &ps.Conditions[i]
+			}
+		}
+		if accepted == nil {
+			break
+		}
+		switch {
+		case accepted.Status == metav1.ConditionFalse:
+			deployerCondSet.Manage(ds).MarkFalse(DeployerConditionIngressReady, accepted.Reason, accepted.Message)
+		case resolvedRefs != nil && resolvedRefs.Status == metav1.ConditionFalse:
+			deployerCondSet.Manage(ds).MarkFalse(DeployerConditionIngressReady, resolvedRefs.Reason, resolvedRefs.Message)
+		case accepted.Status == metav1.ConditionTrue:
+			deployerCondSet.Manage(ds).MarkTrue(DeployerConditionIngressReady)
+		default:
+			deployerCondSet.Manage(ds).MarkUnknown(DeployerConditionIngressReady, accepted.Reason, accepted.Message)
+		}
+		return
+	}
+	deployerCondSet.Manage(ds).MarkUnknown(DeployerConditionIngressReady, "GatewayPending", "Waiting for Gateway %q to accept the route.", gateway.Name)
+}
+
+func (ds *DeployerStatus) MarkHTTPRouteNotAvailable() {
+	deployerCondSet.Manage(ds).MarkFalse(DeployerConditionIngressReady, "HTTPRouteNotAvailable", "The HTTPRoute API is not served by the cluster.")
+}
+
+func (ds *DeployerStatus) MarkIngressHostConflict(host, deployer string) {
+	deployerCondSet.Manage(ds).MarkFalse(DeployerConditionIngressReady, "HostConflict", "The host %q is already routed to Deployer %q.", host, deployer)
+}
+
+// DeployerHistoryLimit is the number of revisions retained in a deployer's
+// history
+const DeployerHistoryLimit = 10
+
+// RecordRevision moves the image to the head of the history, unless it is
+// already the most recent revision. A build generation of zero retains the
+// generation recorded when the image was previously deployed.
+func (ds *DeployerStatus) RecordRevision(image string, buildGeneration int64, now metav1.Time) {
+	if len(ds.History) != 0 && ds.History[0].Image == image {
+		return
+	}
+	history := []DeployerRevision{{Image: image, DeployedTime: now, BuildGeneration: buildGeneration}}
+	for _, revision := range ds.History {
+		if revision.Image == image {
+			if buildGeneration == 0 {
+				history[0].BuildGeneration = revision.BuildGeneration
+			}
+			continue
+		}
+		history = append(history, revision)
+	}
+	if len(history) > DeployerHistoryLimit {
+		history = history[:DeployerHistoryLimit]
+	}
+	ds.History = history
+}
+
+func sameParentReference(a, b gatewayv1.ParentReference) bool {
+	deref := func(s *string) string {
+		if s == nil {
+			return ""
+		}
+		return *s
+	}
+	return a.Name == b.Name && deref(a.Namespace) == deref(b.Namespace) && deref(a.SectionName) == deref(b.SectionName)
+}
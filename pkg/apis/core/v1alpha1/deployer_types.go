@@ -20,6 +20,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
 
 	"github.com/projectriff/system/pkg/apis"
 	"github.com/projectriff/system/pkg/refs"
@@ -30,6 +31,18 @@ import (
 
 var (
 	DeployerLabelKey = GroupVersion.Group + "/deployer"
+	// DeployerCanaryLabelKey identifies the canary workload of a deployer
+	// during a rollout
+	DeployerCanaryLabelKey = GroupVersion.Group + "/deployer-canary"
+	// DeployerTrackLabelKey separates the pods of the stable and canary
+	// Deployments of a deployer, the value is DeployerTrackStable or
+	// DeployerTrackCanary
+	DeployerTrackLabelKey = GroupVersion.Group + "/deployer-track"
+)
+
+const (
+	DeployerTrackStable = "stable"
+	DeployerTrackCanary = "canary"
 )
 
 var (
@@ -51,9 +64,70 @@ type DeployerSpec struct {
 	// +optional
 	Template *corev1.PodTemplateSpec `json:"template,omitempty"`
 
+	// Probes configure the health checks of the deployer's container. When
+	// no readiness probe is configured, readiness is checked by opening a TCP
+	// connection to the container port.
+	// +optional
+	Probes *Probes `json:"probes,omitempty"`
+
+	// MinReadySeconds is the minimum number of seconds a new pod must be
+	// ready, without any of its containers crashing, to be considered
+	// available. Defaults to 0.
+	// +optional
+	MinReadySeconds int32 `json:"minReadySeconds,omitempty"`
+
+	// ProgressDeadlineSeconds is the maximum number of seconds a rollout of
+	// the deployer's pods may take to progress before it is considered
+	// failed. Defaults to 600.
+	// +optional
+	ProgressDeadlineSeconds *int32 `json:"progressDeadlineSeconds,omitempty"`
+
 	// IngressPolicy defines whether the workload should be reachable from
 	// outside the cluster
 	IngressPolicy IngressPolicy `json:"ingressPolicy,omitempty"`
+
+	// Ingress customizes how the External ingress routes to the workload
+	// +optional
+	Ingress *Ingress `json:"ingress,omitempty"`
+
+	// TLS secures the External ingress with a certificate. When not set,
+	// the cluster-wide TLS settings apply, if any.
+	// +optional
+	TLS *TLS `json:"tls,omitempty"`
+
+	// Rollout defines how new images are rolled out. When not set, new
+	// images replace the running image immediately.
+	// +optional
+	Rollout *Rollout `json:"rollout,omitempty"`
+
+	// Scale bounds the number of replicas. When not set, the replica count
+	// of the Deployment is left unmanaged.
+	// +optional
+	Scale *Scale `json:"scale,omitempty"`
+
+	// Disruption limits voluntary disruptions of the deployer's pods, like
+	// node drains, with a PodDisruptionBudget. When not set, no budget is
+	// created.
+	// +optional
+	Disruption *Disruption `json:"disruption,omitempty"`
+
+	// NetworkPolicy restricts the pods that may connect to the deployer.
+	// When not set, no NetworkPolicy is created.
+	// +optional
+	NetworkPolicy *NetworkPolicy `json:"networkPolicy,omitempty"`
+
+	// Bindings project the binding metadata and secret of Streams, or other
+	// bindable resources, into the deployer's pods. Each binding is mounted
+	// in a directory named for the binding under $CNB_BINDINGS.
+	// +optional
+	Bindings []DeployerBinding `json:"bindings,omitempty"`
+
+	// PinnedImage overrides the image resolved from the build or template
+	// until it is cleared. Pinning an image from the deployer's history
+	// rolls back to that image. A cleared image keeps running until the
+	// build produces an image.
+	// +optional
+	PinnedImage string `json:"pinnedImage,omitempty"`
 }
 
 type Build struct {
@@ -65,6 +139,9 @@ type Build struct {
 
 	// FunctionRef references an application in this namespace.
 	FunctionRef string `json:"functionRef,omitempty"`
+
+	// ImagePromotionRef references an image promotion in this namespace.
+	ImagePromotionRef string `json:"imagePromotionRef,omitempty"`
 }
 
 // IngressPolicy describes whether the container should be exposed via
@@ -78,6 +155,258 @@ const (
 	IngressPolicyExternal     IngressPolicy = "External"
 )
 
+// Ingress customizes the routing of external traffic to the deployer
+type Ingress struct {
+	// Hosts are additional hostnames routed to the deployer. The host
+	// derived from the cluster's host template is always routed.
+	// +optional
+	Hosts []string `json:"hosts,omitempty"`
+
+	// Path prefix routed to the deployer on each host. Defaults to "/".
+	// +optional
+	Path string `json:"path,omitempty"`
+
+	// ClassName selects the ingress controller serving the ingress
+	// +optional
+	ClassName string `json:"className,omitempty"`
+
+	// Annotations are added to the Ingress, typically to configure the
+	// ingress controller
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// TLS references the certificate for the deployer's ingress
+type TLS struct {
+	// SecretRef references a Secret of type kubernetes.io/tls in this
+	// namespace
+	SecretRef string `json:"secretRef"`
+}
+
+// Probes configure the health checks of the deployer's container
+type Probes struct {
+	// Readiness determines whether the container receives traffic
+	// +optional
+	Readiness *Probe `json:"readiness,omitempty"`
+
+	// Liveness determines whether the container is restarted
+	// +optional
+	Liveness *Probe `json:"liveness,omitempty"`
+
+	// Startup delays the readiness and liveness probes until the container
+	// has started
+	// +optional
+	Startup *Probe `json:"startup,omitempty"`
+}
+
+// Probe checks the container port. An HTTP GET request is made to the path
+// when set, otherwise a TCP connection is opened.
+type Probe struct {
+	// Path of the HTTP GET request
+	// +optional
+	Path string `json:"path,omitempty"`
+
+	// InitialDelaySeconds after the container starts before the probe is
+	// run
+	// +optional
+	InitialDelaySeconds int32 `json:"initialDelaySeconds,omitempty"`
+
+	// PeriodSeconds between probes. Defaults to 10.
+	// +optional
+	PeriodSeconds int32 `json:"periodSeconds,omitempty"`
+
+	// TimeoutSeconds after which the probe fails. Defaults to 1.
+	// +optional
+	TimeoutSeconds int32 `json:"timeoutSeconds,omitempty"`
+
+	// SuccessThreshold is the number of consecutive successes for the probe
+	// to succeed after having failed. Must be 1 for liveness and startup
+	// probes. Defaults to 1.
+	// +optional
+	SuccessThreshold int32 `json:"successThreshold,omitempty"`
+
+	// FailureThreshold is the number of consecutive failures for the probe
+	// to fail after having succeeded. Defaults to 3.
+	// +optional
+	FailureThreshold int32 `json:"failureThreshold,omitempty"`
+}
+
+// Scale defines the number of replicas for the deployer. The replicas are
+// autoscaled by a HorizontalPodAutoscaler when max is greater than min.
+type Scale struct {
+	// Min replicas. Defaults to 1.
+	// +optional
+	Min *int32 `json:"min,omitempty"`
+
+	// Max replicas. Defaults to min.
+	// +optional
+	Max *int32 `json:"max,omitempty"`
+
+	// TargetCPUUtilization is the average CPU utilization, as a percentage
+	// of the requested CPU, targeted by the autoscaler. When neither target
+	// is set, the autoscaler targets 80% CPU utilization.
+	// +optional
+	TargetCPUUtilization *int32 `json:"targetCPUUtilization,omitempty"`
+
+	// TargetMemoryUtilization is the average memory utilization, as a
+	// percentage of the requested memory, targeted by the autoscaler.
+	// +optional
+	TargetMemoryUtilization *int32 `json:"targetMemoryUtilization,omitempty"`
+}
+
+// Disruption bounds the number of the deployer's pods that may be evicted
+// at once. Exactly one of minAvailable or maxUnavailable must be set.
+type Disruption struct {
+	// MinAvailable is the number, or percentage, of pods that must remain
+	// available during an eviction
+	// +optional
+	MinAvailable *intstr.IntOrString `json:"minAvailable,omitempty"`
+	// MaxUnavailable is the number, or percentage, of pods that may be
+	// unavailable during an eviction
+	// +optional
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+}
+
+// NetworkPolicy restricts ingress to the deployer's pods. Deployers with an
+// External ingress policy accept connections only from the ingress
+// controller's namespace, or the Gateway's namespace when routing with
+// HTTPRoutes. The namespace is selected by the kubernetes.io/metadata.name
+// label, set by Kubernetes 1.21 and later. Other deployers accept
+// connections from pods in their own namespace, and from namespaces matching
+// the namespace selector.
+type NetworkPolicy struct {
+	// NamespaceSelector selects additional namespaces that may connect to a
+	// deployer with a ClusterLocal ingress policy
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+}
+
+// DeployerBinding projects a bindable resource into the deployer's pods
+type DeployerBinding struct {
+	// Name of the directory the binding is mounted into
+	Name string `json:"name"`
+
+	// Ref references the bindable resource in this namespace
+	Ref BindableReference `json:"ref"`
+}
+
+// BindableReference references a resource exposing binding metadata and
+// secret references at status.binding, like a Stream
+type BindableReference struct {
+	// APIVersion of the referenced resource. Defaults to
+	// streaming.projectriff.io/v1alpha1.
+	// +optional
+	APIVersion string `json:"apiVersion,omitempty"`
+
+	// Kind of the referenced resource. Defaults to Stream.
+	// +optional
+	Kind string `json:"kind,omitempty"`
+
+	// Name of the referenced resource
+	Name string `json:"name"`
+}
+
+// Rollout progressively shifts traffic to a canary running a new image
+// before the image is promoted. The rollout is aborted if the canary fails
+// to become ready within the canary Deployment's progress deadline.
+type Rollout struct {
+	// Steps the canary progresses through before it is promoted
+	Steps []RolloutStep `json:"steps"`
+
+	// TrafficRouting selects how traffic is split between the stable and
+	// canary workloads. Defaults to Replicas.
+	// +optional
+	TrafficRouting RolloutTrafficRouting `json:"trafficRouting,omitempty"`
+}
+
+type RolloutStep struct {
+	// Weight is the percentage of traffic sent to the canary. A weight of
+	// 100 requires Ingress traffic routing.
+	Weight int32 `json:"weight"`
+
+	// Pause is the minimum time spent at this step. The canary must also be
+	// ready before moving to the next step.
+	// +optional
+	Pause *metav1.Duration `json:"pause,omitempty"`
+}
+
+// RolloutTrafficRouting describes how traffic is split between the stable
+// and canary workloads.
+type RolloutTrafficRouting string
+
+const (
+	// RolloutTrafficRoutingReplicas weights traffic by the ratio of canary
+	// to stable replicas behind the deployer's Service. Weights are
+	// approximate as replica counts are whole numbers, and at least one
+	// canary replica runs.
+	RolloutTrafficRoutingReplicas RolloutTrafficRouting = "Replicas"
+	// RolloutTrafficRoutingIngress weights traffic with canary annotations
+	// on a second Ingress, or with weighted backends when exposed by an
+	// HTTPRoute. Requires an External ingress policy and, for Ingresses, an
+	// ingress controller supporting nginx style canary annotations.
+	RolloutTrafficRoutingIngress RolloutTrafficRouting = "Ingress"
+)
+
+// RolloutPhase is the state of the most recent rollout. An aborted image is
+// not retried, a new image starts a new rollout.
+type RolloutPhase string
+
+const (
+	RolloutPhaseProgressing RolloutPhase = "Progressing"
+	RolloutPhasePromoted    RolloutPhase = "Promoted"
+	RolloutPhaseAborted     RolloutPhase = "Aborted"
+)
+
+type RolloutStatus struct {
+	// Phase of the most recent rollout
+	Phase RolloutPhase `json:"phase,omitempty"`
+
+	// Message is a human readable description of the rollout's progress
+	Message string `json:"message,omitempty"`
+
+	// StableImage is the image receiving traffic outside of the canary
+	StableImage string `json:"stableImage,omitempty"`
+
+	// CanaryImage is the image being rolled out, the promoted image until
+	// the stable Deployment runs it, or the image that was aborted
+	CanaryImage string `json:"canaryImage,omitempty"`
+
+	// Step is the index of the current rollout step
+	Step int32 `json:"step,omitempty"`
+
+	// Weight is the percentage of traffic sent to the canary
+	Weight int32 `json:"weight,omitempty"`
+
+	// StepStartTime is when the current step started
+	StepStartTime *metav1.Time `json:"stepStartTime,omitempty"`
+
+	CanaryDeploymentRef *refs.TypedLocalObjectReference `json:"canaryDeploymentRef,omitempty"`
+	CanaryServiceRef    *refs.TypedLocalObjectReference `json:"canaryServiceRef,omitempty"`
+	CanaryIngressRef    *refs.TypedLocalObjectReference `json:"canaryIngressRef,omitempty"`
+}
+
+// DeployerImageSource describes where the image of a deployer is resolved
+// from.
+type DeployerImageSource string
+
+const (
+	DeployerImageSourceBuild    DeployerImageSource = "Build"
+	DeployerImageSourceTemplate DeployerImageSource = "Template"
+	DeployerImageSourcePinned   DeployerImageSource = "Pinned"
+)
+
+// DeployerRevision is an image deployed by the deployer
+type DeployerRevision struct {
+	// Image deployed, by digest when resolved from a build
+	Image string `json:"image"`
+	// DeployedTime is when the image was most recently deployed
+	DeployedTime metav1.Time `json:"deployedTime"`
+	// BuildGeneration is the generation of the build resource that
+	// produced the image
+	// +optional
+	BuildGeneration int64 `json:"buildGeneration,omitempty"`
+}
+
 // DeployerStatus defines the observed state of Deployer
 type DeployerStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
@@ -88,21 +417,43 @@ type DeployerStatus struct {
 	// LatestImage is the most recent image resolved from the build
 	LatestImage string `json:"latestImage,omitempty"`
 
-	DeploymentRef *refs.TypedLocalObjectReference `json:"deploymentRef,omitempty"`
-	ServiceRef    *refs.TypedLocalObjectReference `json:"serviceRef,omitempty"`
-	IngressRef    *refs.TypedLocalObjectReference `json:"ingressRef,omitempty"`
+	// ImageSource is where the LatestImage was resolved from
+	ImageSource DeployerImageSource `json:"imageSource,omitempty"`
+
+	// History of the images deployed, most recent first. Only the most
+	// recent revisions are retained.
+	History []DeployerRevision `json:"history,omitempty"`
+
+	DeploymentRef              *refs.TypedLocalObjectReference `json:"deploymentRef,omitempty"`
+	ServiceRef                 *refs.TypedLocalObjectReference `json:"serviceRef,omitempty"`
+	IngressRef                 *refs.TypedLocalObjectReference `json:"ingressRef,omitempty"`
+	HTTPRouteRef               *refs.TypedLocalObjectReference `json:"httpRouteRef,omitempty"`
+	HorizontalPodAutoscalerRef *refs.TypedLocalObjectReference `json:"horizontalPodAutoscalerRef,omitempty"`
+	PodDisruptionBudgetRef     *refs.TypedLocalObjectReference `json:"podDisruptionBudgetRef,omitempty"`
+	NetworkPolicyRef           *refs.TypedLocalObjectReference `json:"networkPolicyRef,omitempty"`
+
+	// Replicas is the current number of replicas
+	Replicas int32 `json:"replicas,omitempty"`
+
+	// DesiredReplicas is the number of replicas most recently requested,
+	// either by the deployer or its autoscaler
+	DesiredReplicas int32 `json:"desiredReplicas,omitempty"`
 
 	// Address to target this deployer internally
 	Address *apis.Addressable `json:"address,omitempty"`
 
 	// URL to target this deployer publicly
 	URL string `json:"url,omitempty"`
+
+	// Rollout is the progress of rolling out new images
+	Rollout *RolloutStatus `json:"rollout,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
 // +kubebuilder:resource:categories="riff"
 // +kubebuilder:printcolumn:name="URL",type=string,JSONPath=`.status.url`
+// +kubebuilder:printcolumn:name="Source",type=string,JSONPath=`.status.imageSource`
 // +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
 // +kubebuilder:printcolumn:name="Reason",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].reason`
 // +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
@@ -18,16 +18,26 @@ package v1alpha1
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/google/go-cmp/cmp"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	apivalidation "k8s.io/apimachinery/pkg/util/validation"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	"github.com/projectriff/system/pkg/validation"
 )
 
+// defaultProgressDeadlineSeconds is the progress deadline of a Deployment
+// that does not set one
+const defaultProgressDeadlineSeconds int32 = 600
+
 // +kubebuilder:webhook:path=/validate-core-projectriff-io-v1alpha1-deployer,mutating=false,failurePolicy=fail,groups=core.projectriff.io,resources=deployers,verbs=create;update,versions=v1alpha1,name=deployers.core.projectriff.io
 
 var (
@@ -90,10 +100,277 @@ func (s *DeployerSpec) Validate() validation.FieldErrors {
 		errs = errs.Also(s.Build.Validate().ViaField("build"))
 	}
 
+	if s.Probes != nil {
+		errs = errs.Also(s.Probes.Validate().ViaField("probes"))
+		container := s.Template.Spec.Containers[0]
+		if s.Probes.Readiness != nil && container.ReadinessProbe != nil {
+			errs = errs.Also(validation.ErrMultipleOneOf("probes.readiness", "template.spec.containers[0].readinessProbe"))
+		}
+		if s.Probes.Liveness != nil && container.LivenessProbe != nil {
+			errs = errs.Also(validation.ErrMultipleOneOf("probes.liveness", "template.spec.containers[0].livenessProbe"))
+		}
+		if s.Probes.Startup != nil && container.StartupProbe != nil {
+			errs = errs.Also(validation.ErrMultipleOneOf("probes.startup", "template.spec.containers[0].startupProbe"))
+		}
+	}
+
+	if s.MinReadySeconds < 0 {
+		errs = errs.Also(validation.ErrInvalidValue(s.MinReadySeconds, "minReadySeconds"))
+	}
+	// the deadline must leave time for pods to become available
+	if s.ProgressDeadlineSeconds == nil {
+		if s.MinReadySeconds >= defaultProgressDeadlineSeconds {
+			errs = errs.Also(validation.ErrInvalidValue(s.MinReadySeconds, "minReadySeconds"))
+		}
+	} else if *s.ProgressDeadlineSeconds <= s.MinReadySeconds {
+		errs = errs.Also(validation.ErrInvalidValue(*s.ProgressDeadlineSeconds, "progressDeadlineSeconds"))
+	}
+
 	if s.IngressPolicy != "" && s.IngressPolicy != IngressPolicyClusterLocal && s.IngressPolicy != IngressPolicyExternal {
 		errs = errs.Also(validation.ErrInvalidValue(s.IngressPolicy, "ingressPolicy"))
 	}
 
+	if s.Ingress != nil {
+		errs = errs.Also(s.Ingress.Validate().ViaField("ingress"))
+	}
+
+	if s.TLS != nil {
+		errs = errs.Also(s.TLS.Validate().ViaField("tls"))
+	}
+
+	if s.Scale != nil {
+		errs = errs.Also(s.Scale.Validate().ViaField("scale"))
+	}
+
+	if s.Disruption != nil {
+		errs = errs.Also(s.Disruption.Validate().ViaField("disruption"))
+	}
+
+	if s.NetworkPolicy != nil {
+		errs = errs.Also(s.NetworkPolicy.Validate().ViaField("networkPolicy"))
+	}
+
+	bindings := map[string]int{}
+	for i, binding := range s.Bindings {
+		errs = errs.Also(binding.Validate().ViaFieldIndex("bindings", i))
+		if j, ok := bindings[binding.Name]; ok {
+			errs = errs.Also(validation.ErrDuplicateValue(binding.Name, fmt.Sprintf("bindings[%d].name", j), fmt.Sprintf("bindings[%d].name", i)))
+		}
+		bindings[binding.Name] = i
+	}
+
+	if s.Rollout != nil {
+		errs = errs.Also(s.Rollout.Validate().ViaField("rollout"))
+		if s.Rollout.TrafficRouting == RolloutTrafficRoutingIngress && s.IngressPolicy != IngressPolicyExternal {
+			errs = errs.Also(validation.ErrInvalidValue(s.Rollout.TrafficRouting, "rollout.trafficRouting"))
+		}
+	}
+
+	return errs
+}
+
+func (i *Ingress) Validate() validation.FieldErrors {
+	errs := validation.FieldErrors{}
+
+	hosts := map[string]int{}
+	for j, host := range i.Hosts {
+		if len(apivalidation.IsDNS1123Subdomain(host)) != 0 {
+			errs = errs.Also(validation.ErrInvalidArrayValue(host, "hosts", j))
+		}
+		if k, ok := hosts[host]; ok {
+			errs = errs.Also(validation.ErrDuplicateValue(host, fmt.Sprintf("hosts[%d]", k), fmt.Sprintf("hosts[%d]", j)))
+		}
+		hosts[host] = j
+	}
+	if i.Path != "" && !strings.HasPrefix(i.Path, "/") {
+		errs = errs.Also(validation.ErrInvalidValue(i.Path, "path"))
+	}
+	if i.ClassName != "" && len(apivalidation.IsDNS1123Subdomain(i.ClassName)) != 0 {
+		errs = errs.Also(validation.ErrInvalidValue(i.ClassName, "className"))
+	}
+
+	return errs
+}
+
+func (t *TLS) Validate() validation.FieldErrors {
+	errs := validation.FieldErrors{}
+
+	if t.SecretRef == "" {
+		errs = errs.Also(validation.ErrMissingField("secretRef"))
+	}
+
+	return errs
+}
+
+func (p *Probes) Validate() validation.FieldErrors {
+	errs := validation.FieldErrors{}
+
+	if p.Readiness != nil {
+		errs = errs.Also(p.Readiness.Validate().ViaField("readiness"))
+	}
+	if p.Liveness != nil {
+		errs = errs.Also(p.Liveness.Validate().ViaField("liveness"))
+		if p.Liveness.SuccessThreshold > 1 {
+			errs = errs.Also(validation.ErrInvalidValue(p.Liveness.SuccessThreshold, "liveness.successThreshold"))
+		}
+	}
+	if p.Startup != nil {
+		errs = errs.Also(p.Startup.Validate().ViaField("startup"))
+		if p.Startup.SuccessThreshold > 1 {
+			errs = errs.Also(validation.ErrInvalidValue(p.Startup.SuccessThreshold, "startup.successThreshold"))
+		}
+	}
+
+	return errs
+}
+
+func (p *Probe) Validate() validation.FieldErrors {
+	errs := validation.FieldErrors{}
+
+	if p.Path != "" && !strings.HasPrefix(p.Path, "/") {
+		errs = errs.Also(validation.ErrInvalidValue(p.Path, "path"))
+	}
+	if p.InitialDelaySeconds < 0 {
+		errs = errs.Also(validation.ErrInvalidValue(p.InitialDelaySeconds, "initialDelaySeconds"))
+	}
+	if p.PeriodSeconds < 0 {
+		errs = errs.Also(validation.ErrInvalidValue(p.PeriodSeconds, "periodSeconds"))
+	}
+	if p.TimeoutSeconds < 0 {
+		errs = errs.Also(validation.ErrInvalidValue(p.TimeoutSeconds, "timeoutSeconds"))
+	}
+	if p.SuccessThreshold < 0 {
+		errs = errs.Also(validation.ErrInvalidValue(p.SuccessThreshold, "successThreshold"))
+	}
+	if p.FailureThreshold < 0 {
+		errs = errs.Also(validation.ErrInvalidValue(p.FailureThreshold, "failureThreshold"))
+	}
+
+	return errs
+}
+
+func (s *Scale) Validate() validation.FieldErrors {
+	errs := validation.FieldErrors{}
+
+	if s.Min != nil && *s.Min < 0 {
+		errs = errs.Also(validation.ErrInvalidValue(*s.Min, "min"))
+	}
+	if s.Max != nil && *s.Max < 0 {
+		errs = errs.Also(validation.ErrInvalidValue(*s.Max, "max"))
+	}
+	if s.Min != nil && s.Max != nil && *s.Min > *s.Max {
+		errs = errs.Also(validation.ErrInvalidValue(*s.Max, "max"))
+	}
+	if s.Min != nil && s.Max != nil && *s.Min < *s.Max && *s.Min < 1 {
+		// autoscalers do not scale from zero
+		errs = errs.Also(validation.ErrInvalidValue(*s.Min, "min"))
+	}
+	if s.TargetCPUUtilization != nil && *s.TargetCPUUtilization < 1 {
+		errs = errs.Also(validation.ErrInvalidValue(*s.TargetCPUUtilization, "targetCPUUtilization"))
+	}
+	if s.TargetMemoryUtilization != nil && *s.TargetMemoryUtilization < 1 {
+		errs = errs.Also(validation.ErrInvalidValue(*s.TargetMemoryUtilization, "targetMemoryUtilization"))
+	}
+
+	return errs
+}
+
+func (d *Disruption) Validate() validation.FieldErrors {
+	errs := validation.FieldErrors{}
+
+	if d.MinAvailable == nil && d.MaxUnavailable == nil {
+		errs = errs.Also(validation.ErrMissingOneOf("minAvailable", "maxUnavailable"))
+	} else if d.MinAvailable != nil && d.MaxUnavailable != nil {
+		errs = errs.Also(validation.ErrMultipleOneOf("minAvailable", "maxUnavailable"))
+	}
+	if d.MinAvailable != nil && !isValidIntOrPercent(*d.MinAvailable) {
+		errs = errs.Also(validation.ErrInvalidValue(d.MinAvailable.String(), "minAvailable"))
+	}
+	if d.MaxUnavailable != nil && !isValidIntOrPercent(*d.MaxUnavailable) {
+		errs = errs.Also(validation.ErrInvalidValue(d.MaxUnavailable.String(), "maxUnavailable"))
+	}
+
+	return errs
+}
+
+// isValidIntOrPercent accepts non-negative integers and percentages from 0%
+// to 100%
+func isValidIntOrPercent(v intstr.IntOrString) bool {
+	if v.Type == intstr.Int {
+		return v.IntVal >= 0
+	}
+	if len(apivalidation.IsValidPercent(v.StrVal)) != 0 {
+		return false
+	}
+	percent, err := strconv.Atoi(strings.TrimSuffix(v.StrVal, "%"))
+	return err == nil && percent <= 100
+}
+
+func (n *NetworkPolicy) Validate() validation.FieldErrors {
+	errs := validation.FieldErrors{}
+
+	if n.NamespaceSelector != nil {
+		if _, err := metav1.LabelSelectorAsSelector(n.NamespaceSelector); err != nil {
+			errs = errs.Also(validation.ErrInvalidValue(err.Error(), "namespaceSelector"))
+		}
+	}
+
+	return errs
+}
+
+func (b *DeployerBinding) Validate() validation.FieldErrors {
+	errs := validation.FieldErrors{}
+
+	if b.Name == "" {
+		errs = errs.Also(validation.ErrMissingField("name"))
+	} else if len(apivalidation.IsDNS1123Label(b.Name)) != 0 {
+		errs = errs.Also(validation.ErrInvalidValue(b.Name, "name"))
+	}
+	if b.Ref.APIVersion == "" {
+		errs = errs.Also(validation.ErrMissingField("ref.apiVersion"))
+	} else if _, err := schema.ParseGroupVersion(b.Ref.APIVersion); err != nil {
+		errs = errs.Also(validation.ErrInvalidValue(b.Ref.APIVersion, "ref.apiVersion"))
+	}
+	if b.Ref.Kind == "" {
+		errs = errs.Also(validation.ErrMissingField("ref.kind"))
+	}
+	if b.Ref.Name == "" {
+		errs = errs.Also(validation.ErrMissingField("ref.name"))
+	}
+
+	return errs
+}
+
+func (r *Rollout) Validate() validation.FieldErrors {
+	errs := validation.FieldErrors{}
+
+	if len(r.Steps) == 0 {
+		errs = errs.Also(validation.ErrMissingField("steps"))
+	}
+	for i, step := range r.Steps {
+		errs = errs.Also(step.Validate().ViaFieldIndex("steps", i))
+		if step.Weight == 100 && r.TrafficRouting != RolloutTrafficRoutingIngress {
+			// stable replicas always receive a share of traffic
+			errs = errs.Also(validation.ErrInvalidValue(step.Weight, "weight").ViaFieldIndex("steps", i))
+		}
+	}
+	if r.TrafficRouting != "" && r.TrafficRouting != RolloutTrafficRoutingReplicas && r.TrafficRouting != RolloutTrafficRoutingIngress {
+		errs = errs.Also(validation.ErrInvalidValue(r.TrafficRouting, "trafficRouting"))
+	}
+
+	return errs
+}
+
+func (s *RolloutStep) Validate() validation.FieldErrors {
+	errs := validation.FieldErrors{}
+
+	if s.Weight < 1 || s.Weight > 100 {
+		errs = errs.Also(validation.ErrInvalidValue(s.Weight, "weight"))
+	}
+	if s.Pause != nil && s.Pause.Duration < 0 {
+		errs = errs.Also(validation.ErrInvalidValue(s.Pause.Duration.String(), "pause"))
+	}
+
 	return errs
 }
 
@@ -124,6 +401,12 @@ func (b *Build) Validate() validation.FieldErrors {
 		unused = append(unused, "functionRef")
 	}
 
+	if b.ImagePromotionRef != "" {
+		used = append(used, "imagePromotionRef")
+	} else {
+		unused = append(unused, "imagePromotionRef")
+	}
+
 	if len(used) == 0 {
 		errs = errs.Also(validation.ErrMissingOneOf(unused...))
 	} else if len(used) > 1 {
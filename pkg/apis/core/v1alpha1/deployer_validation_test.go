@@ -18,9 +18,12 @@ package v1alpha1
 
 import (
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 
 	"github.com/projectriff/system/pkg/validation"
 )
@@ -143,6 +146,642 @@ func TestValidateDeployerSpec(t *testing.T) {
 			IngressPolicy: "bogus",
 		},
 		expected: validation.ErrInvalidValue(IngressPolicy("bogus"), "ingressPolicy"),
+	}, {
+		name: "valid, rollout",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-image"},
+					},
+				},
+			},
+			Rollout: &Rollout{
+				Steps: []RolloutStep{
+					{Weight: 10, Pause: &metav1.Duration{Duration: time.Minute}},
+					{Weight: 50},
+				},
+				TrafficRouting: RolloutTrafficRoutingReplicas,
+			},
+		},
+		expected: validation.FieldErrors{},
+	}, {
+		name: "valid, rollout ingress traffic routing",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-image"},
+					},
+				},
+			},
+			IngressPolicy: IngressPolicyExternal,
+			Rollout: &Rollout{
+				Steps:          []RolloutStep{{Weight: 10}},
+				TrafficRouting: RolloutTrafficRoutingIngress,
+			},
+		},
+		expected: validation.FieldErrors{},
+	}, {
+		name: "invalid, rollout ingress traffic routing requires external ingress",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-image"},
+					},
+				},
+			},
+			IngressPolicy: IngressPolicyClusterLocal,
+			Rollout: &Rollout{
+				Steps:          []RolloutStep{{Weight: 10}},
+				TrafficRouting: RolloutTrafficRoutingIngress,
+			},
+		},
+		expected: validation.ErrInvalidValue(RolloutTrafficRoutingIngress, "rollout.trafficRouting"),
+	}, {
+		name: "invalid, rollout without steps",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-image"},
+					},
+				},
+			},
+			Rollout: &Rollout{},
+		},
+		expected: validation.ErrMissingField("rollout.steps"),
+	}, {
+		name: "invalid, rollout steps",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-image"},
+					},
+				},
+			},
+			Rollout: &Rollout{
+				Steps: []RolloutStep{
+					{Weight: 0},
+					{Weight: 101},
+					{Weight: 50, Pause: &metav1.Duration{Duration: -time.Minute}},
+				},
+			},
+		},
+		expected: validation.FieldErrors{}.Also(
+			validation.ErrInvalidValue(int32(0), "rollout.steps[0].weight"),
+			validation.ErrInvalidValue(int32(101), "rollout.steps[1].weight"),
+			validation.ErrInvalidValue("-1m0s", "rollout.steps[2].pause"),
+		),
+	}, {
+		name: "invalid, rollout traffic routing",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-image"},
+					},
+				},
+			},
+			Rollout: &Rollout{
+				Steps:          []RolloutStep{{Weight: 10}},
+				TrafficRouting: "bogus",
+			},
+		},
+		expected: validation.ErrInvalidValue(RolloutTrafficRouting("bogus"), "rollout.trafficRouting"),
+	}, {
+		name: "invalid, rollout full weight with replicas traffic routing",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-image"},
+					},
+				},
+			},
+			Rollout: &Rollout{
+				Steps:          []RolloutStep{{Weight: 100}},
+				TrafficRouting: RolloutTrafficRoutingReplicas,
+			},
+		},
+		expected: validation.ErrInvalidValue(int32(100), "rollout.steps[0].weight"),
+	}, {
+		name: "valid, ingress",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-image"},
+					},
+				},
+			},
+			IngressPolicy: IngressPolicyExternal,
+			Ingress: &Ingress{
+				Hosts:     []string{"www.example.com", "api.example.com"},
+				Path:      "/api",
+				ClassName: "nginx",
+				Annotations: map[string]string{
+					"nginx.ingress.kubernetes.io/proxy-body-size": "8m",
+				},
+			},
+		},
+		expected: validation.FieldErrors{},
+	}, {
+		name: "invalid, ingress hosts",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-image"},
+					},
+				},
+			},
+			IngressPolicy: IngressPolicyExternal,
+			Ingress: &Ingress{
+				Hosts: []string{"www.example.com", "Not A Host", "www.example.com"},
+			},
+		},
+		expected: validation.FieldErrors{}.Also(
+			validation.ErrInvalidArrayValue("Not A Host", "ingress.hosts", 1),
+			validation.ErrDuplicateValue("www.example.com", "ingress.hosts[0]", "ingress.hosts[2]"),
+		),
+	}, {
+		name: "invalid, ingress path",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-image"},
+					},
+				},
+			},
+			IngressPolicy: IngressPolicyExternal,
+			Ingress: &Ingress{
+				Path: "api",
+			},
+		},
+		expected: validation.ErrInvalidValue("api", "ingress.path"),
+	}, {
+		name: "invalid, ingress class name",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-image"},
+					},
+				},
+			},
+			IngressPolicy: IngressPolicyExternal,
+			Ingress: &Ingress{
+				ClassName: "Not A Class",
+			},
+		},
+		expected: validation.ErrInvalidValue("Not A Class", "ingress.className"),
+	}, {
+		name: "valid, tls",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-image"},
+					},
+				},
+			},
+			IngressPolicy: IngressPolicyExternal,
+			TLS:           &TLS{SecretRef: "my-certificate"},
+		},
+		expected: validation.FieldErrors{},
+	}, {
+		name: "invalid, tls without secret",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-image"},
+					},
+				},
+			},
+			IngressPolicy: IngressPolicyExternal,
+			TLS:           &TLS{},
+		},
+		expected: validation.ErrMissingField("tls.secretRef"),
+	}, {
+		name: "valid, fixed scale",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-image"},
+					},
+				},
+			},
+			Scale: &Scale{Min: int32Ptr(0), Max: int32Ptr(0)},
+		},
+		expected: validation.FieldErrors{},
+	}, {
+		name: "valid, autoscaled",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-image"},
+					},
+				},
+			},
+			Scale: &Scale{Min: int32Ptr(1), Max: int32Ptr(5), TargetCPUUtilization: int32Ptr(50), TargetMemoryUtilization: int32Ptr(70)},
+		},
+		expected: validation.FieldErrors{},
+	}, {
+		name: "invalid, negative scale",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-image"},
+					},
+				},
+			},
+			Scale: &Scale{Min: int32Ptr(-1), Max: int32Ptr(-1)},
+		},
+		expected: validation.FieldErrors{}.Also(
+			validation.ErrInvalidValue(int32(-1), "scale.min"),
+			validation.ErrInvalidValue(int32(-1), "scale.max"),
+		),
+	}, {
+		name: "invalid, max less than min",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-image"},
+					},
+				},
+			},
+			Scale: &Scale{Min: int32Ptr(3), Max: int32Ptr(2)},
+		},
+		expected: validation.ErrInvalidValue(int32(2), "scale.max"),
+	}, {
+		name: "invalid, autoscaled from zero",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-image"},
+					},
+				},
+			},
+			Scale: &Scale{Min: int32Ptr(0), Max: int32Ptr(2)},
+		},
+		expected: validation.ErrInvalidValue(int32(0), "scale.min"),
+	}, {
+		name: "invalid, scale targets",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-image"},
+					},
+				},
+			},
+			Scale: &Scale{Min: int32Ptr(1), Max: int32Ptr(2), TargetCPUUtilization: int32Ptr(0), TargetMemoryUtilization: int32Ptr(0)},
+		},
+		expected: validation.FieldErrors{}.Also(
+			validation.ErrInvalidValue(int32(0), "scale.targetCPUUtilization"),
+			validation.ErrInvalidValue(int32(0), "scale.targetMemoryUtilization"),
+		),
+	}, {
+		name: "valid, disruption min available",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-image"},
+					},
+				},
+			},
+			Disruption: &Disruption{MinAvailable: intOrStringPtr(intstr.FromInt(1))},
+		},
+		expected: validation.FieldErrors{},
+	}, {
+		name: "valid, disruption max unavailable",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-image"},
+					},
+				},
+			},
+			Disruption: &Disruption{MaxUnavailable: intOrStringPtr(intstr.FromString("25%"))},
+		},
+		expected: validation.FieldErrors{},
+	}, {
+		name: "invalid, disruption empty",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-image"},
+					},
+				},
+			},
+			Disruption: &Disruption{},
+		},
+		expected: validation.ErrMissingOneOf("minAvailable", "maxUnavailable").ViaField("disruption"),
+	}, {
+		name: "invalid, disruption both",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-image"},
+					},
+				},
+			},
+			Disruption: &Disruption{
+				MinAvailable:   intOrStringPtr(intstr.FromInt(1)),
+				MaxUnavailable: intOrStringPtr(intstr.FromInt(1)),
+			},
+		},
+		expected: validation.ErrMultipleOneOf("minAvailable", "maxUnavailable").ViaField("disruption"),
+	}, {
+		name: "invalid, disruption values",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-image"},
+					},
+				},
+			},
+			Disruption: &Disruption{MinAvailable: intOrStringPtr(intstr.FromInt(-1))},
+		},
+		expected: validation.ErrInvalidValue("-1", "disruption.minAvailable"),
+	}, {
+		name: "invalid, disruption percentage",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-image"},
+					},
+				},
+			},
+			Disruption: &Disruption{MaxUnavailable: intOrStringPtr(intstr.FromString("150%"))},
+		},
+		expected: validation.ErrInvalidValue("150%", "disruption.maxUnavailable"),
+	}, {
+		name: "valid, network policy",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-image"},
+					},
+				},
+			},
+			NetworkPolicy: &NetworkPolicy{
+				NamespaceSelector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{"team": "my-team"},
+				},
+			},
+		},
+		expected: validation.FieldErrors{},
+	}, {
+		name: "invalid, network policy namespace selector",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-image"},
+					},
+				},
+			},
+			NetworkPolicy: &NetworkPolicy{
+				NamespaceSelector: &metav1.LabelSelector{
+					MatchExpressions: []metav1.LabelSelectorRequirement{
+						{Key: "team", Operator: "Unknown"},
+					},
+				},
+			},
+		},
+		expected: validation.ErrInvalidValue(`"Unknown" is not a valid pod selector operator`, "networkPolicy.namespaceSelector"),
+	}, {
+		name: "valid, bindings",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-image"},
+					},
+				},
+			},
+			Bindings: []DeployerBinding{
+				{Name: "my-binding", Ref: BindableReference{APIVersion: "streaming.projectriff.io/v1alpha1", Kind: "Stream", Name: "my-stream"}},
+			},
+		},
+		expected: validation.FieldErrors{},
+	}, {
+		name: "invalid, binding name",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-image"},
+					},
+				},
+			},
+			Bindings: []DeployerBinding{
+				{Name: "My_Binding", Ref: BindableReference{APIVersion: "streaming.projectriff.io/v1alpha1", Kind: "Stream", Name: "my-stream"}},
+			},
+		},
+		expected: validation.ErrInvalidValue("My_Binding", "bindings[0].name"),
+	}, {
+		name: "invalid, binding duplicate name",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-image"},
+					},
+				},
+			},
+			Bindings: []DeployerBinding{
+				{Name: "my-binding", Ref: BindableReference{APIVersion: "streaming.projectriff.io/v1alpha1", Kind: "Stream", Name: "my-stream"}},
+				{Name: "my-binding", Ref: BindableReference{APIVersion: "streaming.projectriff.io/v1alpha1", Kind: "Stream", Name: "my-stream"}},
+			},
+		},
+		expected: validation.ErrDuplicateValue("my-binding", "bindings[0].name", "bindings[1].name"),
+	}, {
+		name: "invalid, binding empty",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-image"},
+					},
+				},
+			},
+			Bindings: []DeployerBinding{
+				{},
+			},
+		},
+		expected: validation.FieldErrors{}.Also(
+			validation.ErrMissingField("name"),
+			validation.ErrMissingField("ref.apiVersion"),
+			validation.ErrMissingField("ref.kind"),
+			validation.ErrMissingField("ref.name"),
+		).ViaFieldIndex("bindings", 0),
+	}, {
+		name: "invalid, binding ref api version",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-image"},
+					},
+				},
+			},
+			Bindings: []DeployerBinding{
+				{Name: "my-binding", Ref: BindableReference{APIVersion: "a/b/c", Kind: "Stream", Name: "my-stream"}},
+			},
+		},
+		expected: validation.ErrInvalidValue("a/b/c", "bindings[0].ref.apiVersion"),
+	}, {
+		name: "valid, probes",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-image"},
+					},
+				},
+			},
+			Probes: &Probes{
+				Readiness: &Probe{Path: "/ready", PeriodSeconds: 5, SuccessThreshold: 2},
+				Liveness:  &Probe{Path: "/healthz", FailureThreshold: 5},
+				Startup:   &Probe{InitialDelaySeconds: 10, TimeoutSeconds: 3},
+			},
+			MinReadySeconds:         10,
+			ProgressDeadlineSeconds: int32Ptr(120),
+		},
+		expected: validation.FieldErrors{},
+	}, {
+		name: "invalid, probe values",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-image"},
+					},
+				},
+			},
+			Probes: &Probes{
+				Readiness: &Probe{
+					Path:                "ready",
+					InitialDelaySeconds: -1,
+					PeriodSeconds:       -1,
+					TimeoutSeconds:      -1,
+					SuccessThreshold:    -1,
+					FailureThreshold:    -1,
+				},
+			},
+		},
+		expected: validation.FieldErrors{}.Also(
+			validation.ErrInvalidValue("ready", "path"),
+			validation.ErrInvalidValue(int32(-1), "initialDelaySeconds"),
+			validation.ErrInvalidValue(int32(-1), "periodSeconds"),
+			validation.ErrInvalidValue(int32(-1), "timeoutSeconds"),
+			validation.ErrInvalidValue(int32(-1), "successThreshold"),
+			validation.ErrInvalidValue(int32(-1), "failureThreshold"),
+		).ViaField("readiness").ViaField("probes"),
+	}, {
+		name: "invalid, liveness and startup success threshold",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-image"},
+					},
+				},
+			},
+			Probes: &Probes{
+				Liveness: &Probe{SuccessThreshold: 2},
+				Startup:  &Probe{SuccessThreshold: 2},
+			},
+		},
+		expected: validation.FieldErrors{}.Also(
+			validation.ErrInvalidValue(int32(2), "liveness.successThreshold"),
+			validation.ErrInvalidValue(int32(2), "startup.successThreshold"),
+		).ViaField("probes"),
+	}, {
+		name: "invalid, probes and template probes",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Image:          "my-image",
+							ReadinessProbe: &corev1.Probe{},
+							LivenessProbe:  &corev1.Probe{},
+							StartupProbe:   &corev1.Probe{},
+						},
+					},
+				},
+			},
+			Probes: &Probes{
+				Readiness: &Probe{},
+				Liveness:  &Probe{},
+				Startup:   &Probe{},
+			},
+		},
+		expected: validation.FieldErrors{}.Also(
+			validation.ErrMultipleOneOf("probes.readiness", "template.spec.containers[0].readinessProbe"),
+			validation.ErrMultipleOneOf("probes.liveness", "template.spec.containers[0].livenessProbe"),
+			validation.ErrMultipleOneOf("probes.startup", "template.spec.containers[0].startupProbe"),
+		),
+	}, {
+		name: "invalid, min ready seconds",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-image"},
+					},
+				},
+			},
+			MinReadySeconds: -1,
+		},
+		expected: validation.ErrInvalidValue(int32(-1), "minReadySeconds"),
+	}, {
+		name: "invalid, progress deadline within min ready seconds",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-image"},
+					},
+				},
+			},
+			MinReadySeconds:         60,
+			ProgressDeadlineSeconds: int32Ptr(60),
+		},
+		expected: validation.ErrInvalidValue(int32(60), "progressDeadlineSeconds"),
+	}, {
+		name: "invalid, default progress deadline within min ready seconds",
+		target: &DeployerSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Image: "my-image"},
+					},
+				},
+			},
+			MinReadySeconds: 600,
+		},
+		expected: validation.ErrInvalidValue(int32(600), "minReadySeconds"),
 	}} {
 		t.Run(c.name, func(t *testing.T) {
 			actual := c.target.Validate()
@@ -152,3 +791,11 @@ func TestValidateDeployerSpec(t *testing.T) {
 		})
 	}
 }
+
+func int32Ptr(i int32) *int32 {
+	return &i
+}
+
+func intOrStringPtr(i intstr.IntOrString) *intstr.IntOrString {
+	return &i
+}
@@ -18,6 +18,7 @@ package v1alpha1
 
 import (
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	apis "github.com/projectriff/system/pkg/apis"
 	servingv1 "github.com/projectriff/system/pkg/apis/thirdparty/knative/serving/v1"
@@ -90,3 +91,30 @@ func (ds *DeployerStatus) PropagateRouteStatus(rs *servingv1.RouteStatus) {
 func (ds *DeployerStatus) MarkRouteNotOwned(name string) {
 	deployerCondSet.Manage(ds).MarkFalse(DeployerConditionRouteReady, "NotOwned", "There is an existing Route %q that the Deployer does not own.", name)
 }
+
+// DeployerHistoryLimit is the number of revisions retained in a deployer's
+// history
+const DeployerHistoryLimit = 10
+
+// RecordRevision moves the image to the head of the history, unless it is
+// already the most recent revision. A build generation of zero retains the
+// generation recorded when the image was previously deployed.
+func (ds *DeployerStatus) RecordRevision(image string, buildGeneration int64, now metav1.Time) {
+	if len(ds.History) != 0 && ds.History[0].Image == image {
+		return
+	}
+	history := []DeployerRevision{{Image: image, DeployedTime: now, BuildGeneration: buildGeneration}}
+	for _, revision := range ds.History {
+		if revision.Image == image {
+			if buildGeneration == 0 {
+				history[0].BuildGeneration = revision.BuildGeneration
+			}
+			continue
+		}
+		history = append(history, revision)
+	}
+	if len(history) > DeployerHistoryLimit {
+		history = history[:DeployerHistoryLimit]
+	}
+	ds.History = history
+}
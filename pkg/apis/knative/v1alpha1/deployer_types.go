@@ -59,6 +59,13 @@ type DeployerSpec struct {
 	// IngressPolicy defines whether the workload should be reachable from
 	// outside the cluster
 	IngressPolicy IngressPolicy `json:"ingressPolicy,omitempty"`
+
+	// PinnedImage overrides the image resolved from the build or template
+	// until it is cleared. Pinning an image from the deployer's history
+	// rolls back to that image. A cleared image keeps running until the
+	// build produces an image.
+	// +optional
+	PinnedImage string `json:"pinnedImage,omitempty"`
 }
 
 // IngressPolicy describes whether the container should be exposed via
@@ -77,6 +84,28 @@ type Scale struct {
 	Max *int32 `json:"max,omitempty"`
 }
 
+// DeployerImageSource describes where the image of a deployer is resolved
+// from.
+type DeployerImageSource string
+
+const (
+	DeployerImageSourceBuild    DeployerImageSource = "Build"
+	DeployerImageSourceTemplate DeployerImageSource = "Template"
+	DeployerImageSourcePinned   DeployerImageSource = "Pinned"
+)
+
+// DeployerRevision is an image deployed by the deployer
+type DeployerRevision struct {
+	// Image deployed, by digest when resolved from a build
+	Image string `json:"image"`
+	// DeployedTime is when the image was most recently deployed
+	DeployedTime metav1.Time `json:"deployedTime"`
+	// BuildGeneration is the generation of the build resource that
+	// produced the image
+	// +optional
+	BuildGeneration int64 `json:"buildGeneration,omitempty"`
+}
+
 // DeployerStatus defines the observed state of Deployer
 type DeployerStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
@@ -87,6 +116,13 @@ type DeployerStatus struct {
 	// LatestImage is the most recent image resolved from the build
 	LatestImage string `json:"latestImage,omitempty"`
 
+	// ImageSource is where the LatestImage was resolved from
+	ImageSource DeployerImageSource `json:"imageSource,omitempty"`
+
+	// History of the images deployed, most recent first. Only the most
+	// recent revisions are retained.
+	History []DeployerRevision `json:"history,omitempty"`
+
 	// ConfigurationRef is a reference to the Knative Serving configuration
 	// backing this deployer.
 	ConfigurationRef *refs.TypedLocalObjectReference `json:"configurationRef,omitempty"`
@@ -106,6 +142,7 @@ type DeployerStatus struct {
 // +kubebuilder:subresource:status
 // +kubebuilder:resource:categories="riff"
 // +kubebuilder:printcolumn:name="URL",type=string,JSONPath=`.status.url`
+// +kubebuilder:printcolumn:name="Source",type=string,JSONPath=`.status.imageSource`
 // +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
 // +kubebuilder:printcolumn:name="Reason",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].reason`
 // +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
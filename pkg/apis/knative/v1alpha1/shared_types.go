@@ -25,4 +25,7 @@ type Build struct {
 
 	// FunctionRef references an application in this namespace.
 	FunctionRef string `json:"functionRef,omitempty"`
+
+	// ImagePromotionRef references an image promotion in this namespace.
+	ImagePromotionRef string `json:"imagePromotionRef,omitempty"`
 }
@@ -49,6 +49,12 @@ func (b *Build) Validate() validation.FieldErrors {
 		unused = append(unused, "functionRef")
 	}
 
+	if b.ImagePromotionRef != "" {
+		used = append(used, "imagePromotionRef")
+	} else {
+		unused = append(unused, "imagePromotionRef")
+	}
+
 	if len(used) == 0 {
 		errs = errs.Also(validation.ErrMissingOneOf(unused...))
 	} else if len(used) > 1 {
@@ -208,6 +208,22 @@ func (in *DeployerList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeployerRevision) DeepCopyInto(out *DeployerRevision) {
+	*out = *in
+	in.DeployedTime.DeepCopyInto(&out.DeployedTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeployerRevision.
+func (in *DeployerRevision) DeepCopy() *DeployerRevision {
+	if in == nil {
+		return nil
+	}
+	out := new(DeployerRevision)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DeployerSpec) DeepCopyInto(out *DeployerSpec) {
 	*out = *in
@@ -243,6 +259,13 @@ func (in *DeployerSpec) DeepCopy() *DeployerSpec {
 func (in *DeployerStatus) DeepCopyInto(out *DeployerStatus) {
 	*out = *in
 	in.Status.DeepCopyInto(&out.Status)
+	if in.History != nil {
+		in, out := &in.History, &out.History
+		*out = make([]DeployerRevision, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.ConfigurationRef != nil {
 		in, out := &in.ConfigurationRef, &out.ConfigurationRef
 		*out = (*in).DeepCopy()
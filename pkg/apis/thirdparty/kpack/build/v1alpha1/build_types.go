@@ -17,6 +17,8 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"strconv"
+
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
@@ -26,6 +28,16 @@ import (
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
 // NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
 
+const (
+	// BuildNumberLabel is the sequence number of a Build for its Image
+	BuildNumberLabel = "image.build.pivotal.io/buildNumber"
+	// ImageLabel is the name of the Image that created a Build
+	ImageLabel = "image.build.pivotal.io/image"
+	// BuildReasonAnnotation is the reason a Build was created, like CONFIG,
+	// COMMIT, BUILDPACK, STACK or TRIGGER
+	BuildReasonAnnotation = "image.build.pivotal.io/reason"
+)
+
 // BuildSpec is the spec for a Build resource.
 type BuildSpec struct {
 	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
@@ -77,6 +89,18 @@ type BuildList struct {
 	Items           []Build `json:"items"`
 }
 
+func (b *Build) BuildNumber() int64 {
+	number, err := strconv.ParseInt(b.Labels[BuildNumberLabel], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return number
+}
+
+func (b *Build) BuildReason() string {
+	return b.Annotations[BuildReasonAnnotation]
+}
+
 func init() {
 	SchemeBuilder.Register(&Build{}, &BuildList{})
 }
@@ -0,0 +1,44 @@
+package authn
+
+import (
+	"encoding/base64"
+	"encoding/json"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+type dockerConfig struct {
+	Auths map[string]dockerConfigAuth `json:"auths"`
+}
+
+type dockerConfigAuth struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Auth     string `json:"auth"`
+}
+
+// DockerConfigJSON renders the registry credentials within the secrets as a
+// docker config.json file, for tools that read credentials from disk rather
+// than from secrets. Only basic-auth secrets annotated with the registry are
+// included.
+func DockerConfigJSON(secrets []corev1.Secret) ([]byte, error) {
+	config := dockerConfig{
+		Auths: map[string]dockerConfigAuth{},
+	}
+	for _, secret := range secrets {
+		if secret.Annotations[DockerSecretAnnotation] == "" {
+			continue
+		}
+		if secret.Type != corev1.SecretTypeBasicAuth {
+			continue
+		}
+		username := string(secret.Data[corev1.BasicAuthUsernameKey])
+		password := string(secret.Data[corev1.BasicAuthPasswordKey])
+		config.Auths[secret.Annotations[DockerSecretAnnotation]] = dockerConfigAuth{
+			Username: username,
+			Password: password,
+			Auth:     base64.StdEncoding.EncodeToString([]byte(username + ":" + password)),
+		}
+	}
+	return json.Marshal(config)
+}
@@ -0,0 +1,76 @@
+package authn
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDockerConfigJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		secrets  []corev1.Secret
+		expected string
+	}{
+		{
+			name:     "empty",
+			secrets:  []corev1.Secret{},
+			expected: `{"auths":{}}`,
+		},
+		{
+			name: "gcr secret",
+			secrets: []corev1.Secret{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Annotations: map[string]string{
+							DockerSecretAnnotation: "https://gcr.io",
+						},
+					},
+					Data: map[string][]byte{
+						"username": []byte("gcr-user"),
+						"password": []byte("gcr-pass"),
+					},
+					Type: corev1.SecretTypeBasicAuth,
+				},
+			},
+			expected: `{"auths":{"https://gcr.io":{"username":"gcr-user","password":"gcr-pass","auth":"Z2NyLXVzZXI6Z2NyLXBhc3M="}}}`,
+		},
+		{
+			name: "skips unannotated and non basic-auth secrets",
+			secrets: []corev1.Secret{
+				{
+					Data: map[string][]byte{
+						"username": []byte("user"),
+						"password": []byte("pass"),
+					},
+					Type: corev1.SecretTypeBasicAuth,
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Annotations: map[string]string{
+							DockerSecretAnnotation: "https://gcr.io",
+						},
+					},
+					Data: map[string][]byte{
+						"ssh-privatekey": []byte("key"),
+					},
+					Type: corev1.SecretTypeSSHAuth,
+				},
+			},
+			expected: `{"auths":{}}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual, err := DockerConfigJSON(test.secrets)
+			if err != nil {
+				t.Fatalf("DockerConfigJSON() unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(test.expected, string(actual)); diff != "" {
+				t.Errorf("DockerConfigJSON() (-expected, +actual) = %v", diff)
+			}
+		})
+	}
+}
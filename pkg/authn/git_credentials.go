@@ -0,0 +1,120 @@
+package authn
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const GitSecretAnnotation = "build.pivotal.io/git"
+
+const (
+	// GitConfigKey holds the git config, git reads it from $HOME/.gitconfig
+	GitConfigKey = ".gitconfig"
+	// GitCredentialsKey holds the credentials of basic-auth secrets for the
+	// git credential store helper
+	GitCredentialsKey = ".git-credentials"
+	// GitSSHConfigKey holds the ssh config selecting the private key of
+	// ssh-auth secrets for each host
+	GitSSHConfigKey = ".git-ssh-config"
+	// GitKnownHostsKey holds the known hosts of ssh-auth secrets
+	GitKnownHostsKey = ".git-known-hosts"
+
+	gitSSHKeyPrefix = ".git-ssh-key-"
+	knownHostsKey   = "known_hosts"
+)
+
+// GitCredentials renders the git credentials within the secrets as files for
+// git, when the files are mounted in dir and dir is the $HOME of git. Like
+// kpack, basic-auth secrets annotated with the git server's URL are used for
+// https and ssh-auth secrets annotated with the git server's host are used
+// for ssh. The host key of an ssh server is verified when the secret includes
+// known_hosts, otherwise the first key seen is accepted. Secrets with an
+// annotation that is not a URL or host are skipped. Nil is returned when none
+// of the secrets are git credentials.
+func GitCredentials(secrets []corev1.Secret, dir string) map[string][]byte {
+	// in order by name, so the files are stable
+	secrets = append([]corev1.Secret{}, secrets...)
+	sort.Slice(secrets, func(i, j int) bool {
+		return secrets[i].Name < secrets[j].Name
+	})
+
+	credentials := []string{}
+	sshConfig := []string{}
+	knownHosts := []string{}
+	files := map[string][]byte{}
+	for _, secret := range secrets {
+		target := secret.Annotations[GitSecretAnnotation]
+		if target == "" {
+			continue
+		}
+		switch secret.Type {
+		case corev1.SecretTypeBasicAuth:
+			u, err := url.Parse(target)
+			if err != nil || u.Host == "" {
+				continue
+			}
+			u.User = url.UserPassword(string(secret.Data[corev1.BasicAuthUsernameKey]), string(secret.Data[corev1.BasicAuthPasswordKey]))
+			u.Path = ""
+			credentials = append(credentials, u.String())
+		case corev1.SecretTypeSSHAuth:
+			host := gitSSHHost(target)
+			if host == "" {
+				continue
+			}
+			key := gitSSHKeyPrefix + secret.Name
+			files[key] = secret.Data[corev1.SSHAuthPrivateKey]
+			sshConfig = append(sshConfig,
+				fmt.Sprintf("Host %s", host),
+				fmt.Sprintf("  IdentityFile %s", path.Join(dir, key)),
+				"  IdentitiesOnly yes",
+				fmt.Sprintf("  UserKnownHostsFile %s", path.Join(dir, GitKnownHostsKey)),
+			)
+			if hosts := strings.TrimSpace(string(secret.Data[knownHostsKey])); hosts != "" {
+				knownHosts = append(knownHosts, hosts)
+				sshConfig = append(sshConfig, "  StrictHostKeyChecking yes")
+			} else {
+				sshConfig = append(sshConfig, "  StrictHostKeyChecking accept-new")
+			}
+		}
+	}
+	if len(credentials) == 0 && len(sshConfig) == 0 {
+		return nil
+	}
+
+	gitConfig := []string{}
+	if len(credentials) != 0 {
+		files[GitCredentialsKey] = []byte(strings.Join(credentials, "\n") + "\n")
+		gitConfig = append(gitConfig,
+			"[credential]",
+			fmt.Sprintf("\thelper = store --file=%s", path.Join(dir, GitCredentialsKey)),
+		)
+	}
+	if len(sshConfig) != 0 {
+		files[GitSSHConfigKey] = []byte(strings.Join(sshConfig, "\n") + "\n")
+		files[GitKnownHostsKey] = []byte(strings.Join(append(knownHosts, ""), "\n"))
+		gitConfig = append(gitConfig,
+			"[core]",
+			fmt.Sprintf("\tsshCommand = ssh -F %s", path.Join(dir, GitSSHConfigKey)),
+		)
+	}
+	files[GitConfigKey] = []byte(strings.Join(gitConfig, "\n") + "\n")
+	return files
+}
+
+// gitSSHHost returns the host of an ssh git server, like `git@github.com`,
+// `github.com` or `ssh://git@github.com:22`
+func gitSSHHost(target string) string {
+	target = strings.TrimPrefix(target, "ssh://")
+	if i := strings.Index(target, "@"); i != -1 {
+		target = target[i+1:]
+	}
+	if i := strings.IndexAny(target, ":/"); i != -1 {
+		target = target[:i]
+	}
+	return target
+}
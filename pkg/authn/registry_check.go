@@ -0,0 +1,41 @@
+package authn
+
+import (
+	"fmt"
+	"net/http"
+
+	ggcrauthn "github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+)
+
+// VerifyRegistryAuth performs the registry auth handshake with the given
+// authenticator. The registry is pinged for its challenge, a token is
+// exchanged if required, and finally the /v2/ endpoint is requested with the
+// resulting credentials. An error is returned if the registry rejects the
+// credentials at any step.
+func VerifyRegistryAuth(registry name.Registry, auth ggcrauthn.Authenticator, t http.RoundTripper) error {
+	if t == nil {
+		t = http.DefaultTransport
+	}
+	rt, err := transport.New(registry, auth, t, []string{})
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("%s://%s/v2/", registry.Scheme(), registry.RegistryStr())
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := (&http.Client{Transport: rt}).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("registry %q rejected credentials: %s", registry.RegistryStr(), resp.Status)
+	}
+	return nil
+}
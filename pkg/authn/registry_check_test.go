@@ -0,0 +1,75 @@
+package authn
+
+import (
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	gauthn "github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+)
+
+func TestVerifyRegistryAuth(t *testing.T) {
+	reg := registry.New(registry.Logger(log.New(ioutil.Discard, "", 0)))
+	basicAuth := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if username, password, ok := r.BasicAuth(); !ok || username != "user" || password != "pass" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="test"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		reg.ServeHTTP(w, r)
+	})
+
+	tests := []struct {
+		name    string
+		handler http.Handler
+		auth    gauthn.Authenticator
+		wantErr bool
+	}{
+		{
+			name:    "anonymous registry",
+			handler: reg,
+			auth:    gauthn.Anonymous,
+		},
+		{
+			name:    "basic auth",
+			handler: basicAuth,
+			auth:    &gauthn.Basic{Username: "user", Password: "pass"},
+		},
+		{
+			name:    "basic auth, bad password",
+			handler: basicAuth,
+			auth:    &gauthn.Basic{Username: "user", Password: "wrong"},
+			wantErr: true,
+		},
+		{
+			name:    "basic auth, anonymous",
+			handler: basicAuth,
+			auth:    gauthn.Anonymous,
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewServer(test.handler)
+			defer server.Close()
+			u, err := url.Parse(server.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			registry, err := name.NewRegistry(u.Host)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = VerifyRegistryAuth(registry, test.auth, nil)
+			if (err != nil) != test.wantErr {
+				t.Errorf("VerifyRegistryAuth() error = %v, wantErr %v", err, test.wantErr)
+			}
+		})
+	}
+}
@@ -29,6 +29,7 @@ type BuildV1alpha1Interface interface {
 	ApplicationsGetter
 	ContainersGetter
 	FunctionsGetter
+	ImagePromotionsGetter
 }
 
 // BuildV1alpha1Client is used to interact with features provided by the build.projectriff.io group.
@@ -48,6 +49,10 @@ func (c *BuildV1alpha1Client) Functions(namespace string) FunctionInterface {
 	return newFunctions(c, namespace)
 }
 
+func (c *BuildV1alpha1Client) ImagePromotions(namespace string) ImagePromotionInterface {
+	return newImagePromotions(c, namespace)
+}
+
 // NewForConfig creates a new BuildV1alpha1Client for the given config.
 func NewForConfig(c *rest.Config) (*BuildV1alpha1Client, error) {
 	config := *c
@@ -40,6 +40,10 @@ func (c *FakeBuildV1alpha1) Functions(namespace string) v1alpha1.FunctionInterfa
 	return &FakeFunctions{c, namespace}
 }
 
+func (c *FakeBuildV1alpha1) ImagePromotions(namespace string) v1alpha1.ImagePromotionInterface {
+	return &FakeImagePromotions{c, namespace}
+}
+
 // RESTClient returns a RESTClient that is used to communicate
 // with API server by this client implementation.
 func (c *FakeBuildV1alpha1) RESTClient() rest.Interface {
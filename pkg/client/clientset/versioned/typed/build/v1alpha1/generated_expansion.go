@@ -22,3 +22,5 @@ type ApplicationExpansion interface{}
 type ContainerExpansion interface{}
 
 type FunctionExpansion interface{}
+
+type ImagePromotionExpansion interface{}
@@ -18,43 +18,81 @@ package build
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/json"
 	"fmt"
+	"io"
+	"path"
 
+	"github.com/google/go-containerregistry/pkg/name"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
+	"github.com/projectriff/system/pkg/apis"
 	buildv1alpha1 "github.com/projectriff/system/pkg/apis/build/v1alpha1"
 	kpackbuildv1alpha1 "github.com/projectriff/system/pkg/apis/thirdparty/kpack/build/v1alpha1"
+	"github.com/projectriff/system/pkg/authn"
 	"github.com/projectriff/system/pkg/controllers"
 	"github.com/projectriff/system/pkg/refs"
+	"github.com/projectriff/system/pkg/registries"
+	"github.com/projectriff/system/pkg/tracker"
 )
 
 // +kubebuilder:rbac:groups=build.projectriff.io,resources=applications,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=build.projectriff.io,resources=applications/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=build.pivotal.io,resources=images,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=build.pivotal.io,resources=builders,verbs=get;list;watch
+// +kubebuilder:rbac:groups=build.pivotal.io,resources=builds,verbs=get;list;watch
+// +kubebuilder:rbac:groups=build.pivotal.io,resources=sourceresolvers,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=serviceaccounts,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=persistentvolumeclaims,verbs=get;list;watch;delete
 // +kubebuilder:rbac:groups=core,resources=events,verbs=get;list;watch;create;update;patch;delete
 
-func ApplicationReconciler(c controllers.Config) *controllers.ParentReconciler {
+func ApplicationReconciler(c controllers.Config, namespace string, random io.Reader) *controllers.ParentReconciler {
 	c.Log = c.Log.WithName("Application")
 
 	return &controllers.ParentReconciler{
 		Type: &buildv1alpha1.Application{},
 		SubReconcilers: []controllers.SubReconciler{
-			ApplicationTargetImageReconciler(c),
+			ApplicationTargetImageReconciler(c, namespace),
+			ApplicationCredentialsReconciler(c),
+			ApplicationBuilderReconciler(c, namespace),
+			ApplicationBuildEnvReconciler(c, random),
+			ApplicationChildBuildEnvReconciler(c),
 			ApplicationChildImageReconciler(c),
+			ApplicationDockerfileCredentialsReconciler(c),
+			ApplicationDockerfileSourceResolverReconciler(c),
+			ApplicationStaleDockerfileJobReconciler(c),
+			ApplicationDockerfileJobReconciler(c),
+			ApplicationImageDigestReconciler(c),
+			ApplicationBuildMetadataReconciler(c),
+			ApplicationBuildHistoryReconciler(c),
+			ApplicationBuildCacheReconciler(c),
 		},
 
 		Config: c,
 	}
 }
 
-func ApplicationTargetImageReconciler(c controllers.Config) controllers.SubReconciler {
+func ApplicationTargetImageReconciler(c controllers.Config, namespace string) controllers.SubReconciler {
 	c.Log = c.Log.WithName("TargetImage")
 
 	return &controllers.SyncReconciler{
 		Sync: func(ctx context.Context, parent *buildv1alpha1.Application) error {
-			targetImage, err := resolveTargetImage(ctx, c.Client, parent)
+			// track registry rewrite rules for a new target image
+			registries.Track(c.Tracker, namespace, parent)
+			targetImage, err := resolveTargetImage(ctx, c.Client, namespace, parent)
 			if err != nil {
 				if err == errMissingDefaultPrefix {
 					parent.Status.MarkImageDefaultPrefixMissing(err.Error())
@@ -72,6 +110,289 @@ func ApplicationTargetImageReconciler(c controllers.Config) controllers.SubRecon
 	}
 }
 
+func ApplicationCredentialsReconciler(c controllers.Config) controllers.SubReconciler {
+	c.Log = c.Log.WithName("Credentials")
+
+	return &controllers.SyncReconciler{
+		Sync: func(ctx context.Context, parent *buildv1alpha1.Application) error {
+			health, err := resolveCredentialHealth(ctx, c.Client, parent.Namespace, parent.Spec.ServiceAccountName, parent.Status.TargetImage)
+			if err != nil {
+				return err
+			}
+			// track the service account for credential bindings
+			c.Tracker.Track(
+				tracker.NewKey(schema.GroupVersionKind{Version: "v1", Kind: "ServiceAccount"}, types.NamespacedName{Namespace: parent.Namespace, Name: parent.Spec.ServiceAccountName}),
+				types.NamespacedName{Namespace: parent.Namespace, Name: parent.Name},
+			)
+			for _, secret := range health.Secrets {
+				// track credentials for verification results
+				c.Tracker.Track(
+					tracker.NewKey(schema.GroupVersionKind{Version: "v1", Kind: "Secret"}, types.NamespacedName{Namespace: secret.Namespace, Name: secret.Name}),
+					types.NamespacedName{Namespace: parent.Namespace, Name: parent.Name},
+				)
+			}
+			switch {
+			case !health.Bound():
+				parent.Status.MarkCredentialsNotBound()
+			case !health.Working():
+				parent.Status.MarkCredentialsInvalid(health.Message)
+			default:
+				parent.Status.MarkCredentialsReady()
+			}
+			return nil
+		},
+
+		Config: c,
+		Setup: func(mgr controllers.Manager, bldr *controllers.Builder) error {
+			bldr.Watches(&source.Kind{Type: &corev1.ServiceAccount{}}, controllers.EnqueueTracked(&corev1.ServiceAccount{}, c.Tracker, c.Scheme))
+			bldr.Watches(&source.Kind{Type: &corev1.Secret{}}, controllers.EnqueueTracked(&corev1.Secret{}, c.Tracker, c.Scheme))
+			return nil
+		},
+	}
+}
+
+func ApplicationBuilderReconciler(c controllers.Config, namespace string) controllers.SubReconciler {
+	c.Log = c.Log.WithName("Builder")
+
+	return &controllers.SyncReconciler{
+		Sync: func(ctx context.Context, parent *buildv1alpha1.Application) error {
+			if parent.Spec.Source == nil {
+				// builds are not used
+				parent.Status.BuilderRef = nil
+				parent.Status.BuilderImage = ""
+				return nil
+			}
+			if parent.Spec.Dockerfile != nil {
+				builder, err := resolveDockerfileBuilder(ctx, c, namespace, parent)
+				if err != nil {
+					if err == errDockerfileBuilderNotConfigured {
+						parent.Status.MarkDockerfileBuilderNotConfigured(err.Error())
+					}
+					return err
+				}
+				controllers.StashValue(ctx, dockerfileBuilderStashKey, builder)
+				parent.Status.BuilderRef = nil
+				parent.Status.BuilderImage = builder.BuilderImage
+				return nil
+			}
+			builder := buildv1alpha1.BuilderReference{
+				Kind: buildv1alpha1.ClusterBuilderKind,
+				Name: "riff-application",
+			}
+			if parent.Spec.Builder != nil {
+				builder = *parent.Spec.Builder
+			}
+			image, err := resolveBuilderImage(ctx, c, namespace, parent, builder)
+			if err != nil {
+				if _, ok := err.(*builderNotFoundError); ok {
+					parent.Status.MarkBuilderNotFound(err.Error())
+				}
+				return err
+			}
+			parent.Status.BuilderRef = refs.NewTypedLocalObjectReference(builder.Name, schema.GroupKind{Group: kpackbuildv1alpha1.GroupVersion.Group, Kind: builder.Kind})
+			parent.Status.BuilderImage = image
+			return nil
+		},
+
+		Config: c,
+		Setup: func(mgr controllers.Manager, bldr *controllers.Builder) error {
+			bldr.Watches(&source.Kind{Type: &corev1.ConfigMap{}}, controllers.EnqueueTracked(&corev1.ConfigMap{}, c.Tracker, c.Scheme))
+			bldr.Watches(&source.Kind{Type: &kpackbuildv1alpha1.Builder{}}, controllers.EnqueueTracked(&kpackbuildv1alpha1.Builder{}, c.Tracker, c.Scheme))
+			return nil
+		},
+	}
+}
+
+func ApplicationBuildHistoryReconciler(c controllers.Config) controllers.SubReconciler {
+	c.Log = c.Log.WithName("BuildHistory")
+
+	return &controllers.SyncReconciler{
+		Sync: func(ctx context.Context, parent *buildv1alpha1.Application) error {
+			if parent.Status.KpackImageRef == nil {
+				parent.Status.Builds = nil
+				parent.Status.MarkUpToDateNotApplicable()
+				return nil
+			}
+			builds, err := resolveBuildHistory(ctx, c.Client, parent.Namespace, parent.Status.KpackImageRef.Name)
+			if err != nil {
+				return err
+			}
+			parent.Status.Builds = builds
+			reflectUpToDate(&parent.Status, builds, parent.Status.BuilderImage)
+			return nil
+		},
+
+		Config: c,
+		Setup: func(mgr controllers.Manager, bldr *controllers.Builder) error {
+			// kpack copies the labels of the Image, including the application label, onto each Build
+			bldr.Watches(&source.Kind{Type: &kpackbuildv1alpha1.Build{}}, enqueueByLabel(buildv1alpha1.ApplicationLabelKey))
+			return nil
+		},
+	}
+}
+
+func ApplicationBuildCacheReconciler(c controllers.Config) controllers.SubReconciler {
+	c.Log = c.Log.WithName("BuildCache")
+
+	return &controllers.SyncReconciler{
+		Sync: func(ctx context.Context, parent *buildv1alpha1.Application) error {
+			return reconcileBuildCache(ctx, c, parent, parent.Spec.CacheSize, parent.Spec.Cache, &parent.Status.BuildStatus)
+		},
+
+		Config: c,
+		Setup: func(mgr controllers.Manager, bldr *controllers.Builder) error {
+			bldr.Watches(&source.Kind{Type: &corev1.PersistentVolumeClaim{}}, controllers.EnqueueTracked(&corev1.PersistentVolumeClaim{}, c.Tracker, c.Scheme))
+			return nil
+		},
+	}
+}
+
+func ApplicationImageDigestReconciler(c controllers.Config) controllers.SubReconciler {
+	c.Log = c.Log.WithName("ImageDigest")
+
+	return &controllers.SyncReconciler{
+		Sync: func(ctx context.Context, parent *buildv1alpha1.Application) (ctrl.Result, error) {
+			if parent.Spec.Source != nil && parent.Spec.Dockerfile == nil {
+				// the latest image is reflected from the kpack image
+				return ctrl.Result{}, nil
+			}
+			if parent.Spec.Dockerfile != nil && !parent.Status.GetCondition(buildv1alpha1.ApplicationConditionDockerfileBuildReady).IsTrue() {
+				// keep the previous latest image until the build completes
+				return ctrl.Result{}, nil
+			}
+			ref, err := name.ParseReference(parent.Status.TargetImage)
+			if err != nil {
+				parent.Status.MarkImageInvalid(err.Error())
+				return ctrl.Result{}, err
+			}
+			latestImage, err := resolveImageDigest(ctx, c.Client, c.Log, parent.Namespace, parent.Spec.ServiceAccountName, ref)
+			if err != nil {
+				parent.Status.MarkImageInvalid(err.Error())
+				return ctrl.Result{}, err
+			}
+			parent.Status.LatestImage = latestImage
+			if parent.Spec.Dockerfile != nil {
+				// the tag only moves when the build completes
+				return ctrl.Result{}, nil
+			}
+			// poll for the tag to move
+			return ctrl.Result{RequeueAfter: imagePollingInterval}, nil
+		},
+
+		Config: c,
+	}
+}
+
+func ApplicationBuildMetadataReconciler(c controllers.Config) controllers.SubReconciler {
+	c.Log = c.Log.WithName("BuildMetadata")
+
+	return &controllers.SyncReconciler{
+		Sync: func(ctx context.Context, parent *buildv1alpha1.Application) error {
+			if parent.Status.LatestImage == "" {
+				parent.Status.BuildMetadata = nil
+				return nil
+			}
+			if parent.Status.BuildMetadata != nil && parent.Status.BuildMetadata.Image == parent.Status.LatestImage {
+				// images are immutable, the metadata is current
+				return nil
+			}
+			metadata, err := resolveBuildMetadata(ctx, c.Client, c.Log, parent.Namespace, parent.Spec.ServiceAccountName, parent.Status.LatestImage)
+			if err != nil {
+				return err
+			}
+			parent.Status.BuildMetadata = metadata
+			return nil
+		},
+
+		Config: c,
+	}
+}
+
+func ApplicationBuildEnvReconciler(c controllers.Config, random io.Reader) controllers.SubReconciler {
+	c.Log = c.Log.WithName("BuildEnv")
+
+	return &controllers.SyncReconciler{
+		Sync: func(ctx context.Context, parent *buildv1alpha1.Application) error {
+			if parent.Spec.Source == nil {
+				// builds are not used
+				return nil
+			}
+			values, err := resolveBuildEnv(ctx, c, parent, parent.Spec.Build.Env)
+			if err != nil {
+				if _, ok := err.(*buildEnvError); ok {
+					if parent.Spec.Dockerfile != nil {
+						parent.Status.MarkDockerfileBuildEnvInvalid(err.Error())
+					} else {
+						parent.Status.MarkBuildEnvInvalid(err.Error())
+					}
+				}
+				return err
+			}
+			controllers.StashValue(ctx, buildEnvStashKey, values)
+			if len(values) != 0 {
+				digestKey, err := resolveBuildEnvDigestKey(ctx, c.Client, parent, "application", random)
+				if err != nil {
+					return err
+				}
+				controllers.StashValue(ctx, buildEnvDigestKeyStashKey, digestKey)
+			}
+			return nil
+		},
+
+		Config: c,
+	}
+}
+
+func ApplicationChildBuildEnvReconciler(c controllers.Config) controllers.SubReconciler {
+	c.Log = c.Log.WithName("ChildBuildEnv")
+
+	return &controllers.ChildReconciler{
+		ParentType:    &buildv1alpha1.Application{},
+		ChildType:     &corev1.Secret{},
+		ChildListType: &corev1.SecretList{},
+
+		DesiredChild: func(ctx context.Context, parent *buildv1alpha1.Application) (*corev1.Secret, error) {
+			values, _ := controllers.RetrieveValue(ctx, buildEnvStashKey).(map[string][]byte)
+			labels := controllers.MergeMaps(parent.Labels, map[string]string{
+				buildv1alpha1.ApplicationLabelKey: parent.Name,
+			})
+			digestKey, _ := controllers.RetrieveValue(ctx, buildEnvDigestKeyStashKey).([]byte)
+			return buildEnvSecret(parent, "application", labels, values, digestKey), nil
+		},
+		ReflectChildStatusOnParent: func(parent *buildv1alpha1.Application, child *corev1.Secret, err error) {
+			if err != nil {
+				if apierrs.IsAlreadyExists(err) {
+					name := err.(apierrs.APIStatus).Status().Details.Name
+					message := fmt.Sprintf("build env secret %q already exists", name)
+					if parent.Spec.Dockerfile != nil {
+						parent.Status.MarkDockerfileBuildEnvInvalid(message)
+					} else {
+						parent.Status.MarkBuildEnvInvalid(message)
+					}
+				}
+			}
+		},
+		MergeBeforeUpdate: func(current, desired *corev1.Secret) {
+			current.Labels = desired.Labels
+			current.Data = desired.Data
+		},
+		SemanticEquals: func(a1, a2 *corev1.Secret) bool {
+			return equality.Semantic.DeepEqual(a1.Data, a2.Data) &&
+				equality.Semantic.DeepEqual(a1.Labels, a2.Labels)
+		},
+
+		Config:     c,
+		IndexField: ".metadata.applicationBuildEnvController",
+		Sanitize: func(child *corev1.Secret) interface{} {
+			// never log build env values
+			return child.Name
+		},
+		OurChild: func(parent *buildv1alpha1.Application, child *corev1.Secret) bool {
+			return child.Name == buildEnvSecretName(parent, "application")
+		},
+	}
+}
+
 func ApplicationChildImageReconciler(c controllers.Config) controllers.SubReconciler {
 	c.Log = c.Log.WithName("ChildImage")
 
@@ -80,8 +401,8 @@ func ApplicationChildImageReconciler(c controllers.Config) controllers.SubReconc
 		ChildType:     &kpackbuildv1alpha1.Image{},
 		ChildListType: &kpackbuildv1alpha1.ImageList{},
 
-		DesiredChild: func(parent *buildv1alpha1.Application) (*kpackbuildv1alpha1.Image, error) {
-			if parent.Spec.Source == nil {
+		DesiredChild: func(ctx context.Context, parent *buildv1alpha1.Application) (*kpackbuildv1alpha1.Image, error) {
+			if parent.Spec.Source == nil || parent.Spec.Dockerfile != nil {
 				return nil, nil
 			}
 
@@ -90,7 +411,6 @@ func ApplicationChildImageReconciler(c controllers.Config) controllers.SubReconc
 					Labels: controllers.MergeMaps(parent.Labels, map[string]string{
 						buildv1alpha1.ApplicationLabelKey: parent.Name,
 					}),
-					Annotations:  make(map[string]string),
 					GenerateName: fmt.Sprintf("%s-application-", parent.Name),
 					Namespace:    parent.Namespace,
 				},
@@ -98,26 +418,35 @@ func ApplicationChildImageReconciler(c controllers.Config) controllers.SubReconc
 					Tag: parent.Status.TargetImage,
 					Builder: kpackbuildv1alpha1.ImageBuilder{
 						TypeMeta: metav1.TypeMeta{
-							Kind: "ClusterBuilder",
+							Kind: parent.Status.BuilderRef.Kind,
 						},
-						Name: "riff-application",
+						Name: parent.Status.BuilderRef.Name,
 					},
-					ServiceAccount:           riffBuildServiceAccount,
+					ServiceAccount:           parent.Spec.ServiceAccountName,
 					Source:                   *parent.Spec.Source,
 					CacheSize:                parent.Spec.CacheSize,
 					FailedBuildHistoryLimit:  parent.Spec.FailedBuildHistoryLimit,
 					SuccessBuildHistoryLimit: parent.Spec.SuccessBuildHistoryLimit,
 					ImageTaggingStrategy:     parent.Spec.ImageTaggingStrategy,
-					Build:                    parent.Spec.Build,
+					Build:                    *parent.Spec.Build.DeepCopy(),
 				},
 			}
+			values, _ := controllers.RetrieveValue(ctx, buildEnvStashKey).(map[string][]byte)
+			if len(values) != 0 {
+				// the condition is reflected by the child build env reconciler
+				if err := checkSecretControlled(ctx, c.Client, parent, buildEnvSecretName(parent, "application")); err != nil {
+					return nil, err
+				}
+			}
+			digestKey, _ := controllers.RetrieveValue(ctx, buildEnvDigestKeyStashKey).([]byte)
+			image.Spec.Build.Env = managedBuildEnv(image.Spec.Build.Env, buildEnvSecretName(parent, "application"), values, digestKey)
+			image.Spec.Build.Env = rebuildBuildEnv(image.Spec.Build.Env, parent.Spec.RebuildPolicy, parent.Status.BuilderImage)
 
 			return image, nil
 		},
 		ReflectChildStatusOnParent: func(parent *buildv1alpha1.Application, child *kpackbuildv1alpha1.Image, err error) {
 			if child == nil {
-				// TODO resolve to a digest?
-				parent.Status.LatestImage = parent.Status.TargetImage
+				// the latest image is resolved by the image digest reconciler
 				parent.Status.MarkBuildNotUsed()
 			} else {
 				parent.Status.KpackImageRef = refs.NewTypedLocalObjectReferenceForObject(child, c.Scheme)
@@ -142,3 +471,487 @@ func ApplicationChildImageReconciler(c controllers.Config) controllers.SubReconc
 		},
 	}
 }
+
+func ApplicationDockerfileCredentialsReconciler(c controllers.Config) controllers.SubReconciler {
+	c.Log = c.Log.WithName("DockerfileCredentials")
+
+	return &controllers.ChildReconciler{
+		ParentType:    &buildv1alpha1.Application{},
+		ChildType:     &corev1.Secret{},
+		ChildListType: &corev1.SecretList{},
+
+		DesiredChild: func(ctx context.Context, parent *buildv1alpha1.Application) (*corev1.Secret, error) {
+			if parent.Spec.Source == nil || parent.Spec.Dockerfile == nil {
+				return nil, nil
+			}
+
+			var serviceAccount corev1.ServiceAccount
+			if err := c.Get(ctx, types.NamespacedName{Namespace: parent.Namespace, Name: parent.Spec.ServiceAccountName}, &serviceAccount); err != nil {
+				return nil, err
+			}
+			secrets, err := fetchSecrets(ctx, c.Client, c.Log, serviceAccount)
+			if err != nil {
+				return nil, err
+			}
+			dockerConfig, err := authn.DockerConfigJSON(secrets)
+			if err != nil {
+				return nil, err
+			}
+
+			child := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: controllers.MergeMaps(parent.Labels, map[string]string{
+						buildv1alpha1.ApplicationLabelKey: parent.Name,
+					}),
+					Name:      dockerfileCredentialsSecretName(parent),
+					Namespace: parent.Namespace,
+				},
+				Type: corev1.SecretTypeDockerConfigJson,
+				Data: map[string][]byte{
+					corev1.DockerConfigJsonKey: dockerConfig,
+				},
+			}
+			// git credentials for the source of the build, like kpack
+			for key, value := range authn.GitCredentials(secrets, dockerfileGitCredentialsDir) {
+				child.Data[key] = value
+			}
+
+			return child, nil
+		},
+		ReflectChildStatusOnParent: func(parent *buildv1alpha1.Application, child *corev1.Secret, err error) {
+			if err != nil {
+				if apierrs.IsAlreadyExists(err) {
+					name := err.(apierrs.APIStatus).Status().Details.Name
+					parent.Status.MarkDockerfileCredentialsUnavailable(fmt.Sprintf("credentials secret %q already exists", name))
+				}
+				return
+			}
+		},
+		MergeBeforeUpdate: func(current, desired *corev1.Secret) {
+			current.Labels = desired.Labels
+			current.Data = desired.Data
+		},
+		SemanticEquals: func(a1, a2 *corev1.Secret) bool {
+			return equality.Semantic.DeepEqual(a1.Data, a2.Data) &&
+				equality.Semantic.DeepEqual(a1.Labels, a2.Labels)
+		},
+
+		Config:     c,
+		IndexField: ".metadata.applicationDockerfileCredentialsController",
+		Sanitize: func(child *corev1.Secret) interface{} {
+			// never log credentials
+			return child.Name
+		},
+		OurChild: func(parent *buildv1alpha1.Application, child *corev1.Secret) bool {
+			return child.Name == dockerfileCredentialsSecretName(parent)
+		},
+	}
+}
+
+// ApplicationDockerfileSourceResolverReconciler resolves the git revision of a
+// Dockerfile build to a commit with a kpack SourceResolver. kpack polls the
+// tracked branch or tag, so a new commit triggers a new build like it does
+// for a kpack Image.
+func ApplicationDockerfileSourceResolverReconciler(c controllers.Config) controllers.SubReconciler {
+	c.Log = c.Log.WithName("DockerfileSourceResolver")
+
+	return &controllers.ChildReconciler{
+		ParentType:    &buildv1alpha1.Application{},
+		ChildType:     &kpackbuildv1alpha1.SourceResolver{},
+		ChildListType: &kpackbuildv1alpha1.SourceResolverList{},
+
+		DesiredChild: func(parent *buildv1alpha1.Application) (*kpackbuildv1alpha1.SourceResolver, error) {
+			if parent.Spec.Source == nil || parent.Spec.Source.Git == nil || parent.Spec.Dockerfile == nil {
+				return nil, nil
+			}
+
+			resolver := &kpackbuildv1alpha1.SourceResolver{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: controllers.MergeMaps(parent.Labels, map[string]string{
+						buildv1alpha1.ApplicationLabelKey: parent.Name,
+					}),
+					GenerateName: fmt.Sprintf("%s-dockerfile-source-", parent.Name),
+					Namespace:    parent.Namespace,
+				},
+				Spec: kpackbuildv1alpha1.SourceResolverSpec{
+					ServiceAccount: parent.Spec.ServiceAccountName,
+					Source:         *parent.Spec.Source.DeepCopy(),
+				},
+			}
+
+			return resolver, nil
+		},
+		ReflectChildStatusOnParent: func(parent *buildv1alpha1.Application, child *kpackbuildv1alpha1.SourceResolver, err error) {
+			if err != nil {
+				return
+			}
+			if child == nil {
+				parent.Status.DockerfileSourceResolverRef = nil
+				parent.Status.DockerfileSourceRevision = ""
+				return
+			}
+			parent.Status.DockerfileSourceResolverRef = refs.NewTypedLocalObjectReferenceForObject(child, c.Scheme)
+			if child.Status.ObservedGeneration != child.Generation {
+				// the resolved revision is for a previous source
+				parent.Status.DockerfileSourceRevision = ""
+				return
+			}
+			if child.Status.GetCondition(apis.ConditionReady).IsTrue() && child.Status.Source.Git != nil {
+				parent.Status.DockerfileSourceRevision = child.Status.Source.Git.Revision
+			}
+		},
+		MergeBeforeUpdate: func(current, desired *kpackbuildv1alpha1.SourceResolver) {
+			current.Labels = desired.Labels
+			current.Spec = desired.Spec
+		},
+		SemanticEquals: func(a1, a2 *kpackbuildv1alpha1.SourceResolver) bool {
+			return equality.Semantic.DeepEqual(a1.Spec, a2.Spec) &&
+				equality.Semantic.DeepEqual(a1.Labels, a2.Labels)
+		},
+
+		Config:     c,
+		IndexField: ".metadata.applicationDockerfileSourceResolverController",
+		Sanitize: func(child *kpackbuildv1alpha1.SourceResolver) interface{} {
+			return child.Spec
+		},
+	}
+}
+
+// ApplicationStaleDockerfileJobReconciler deletes Dockerfile build Jobs whose
+// inputs no longer match the Application. A Job's pod template is immutable,
+// so the replacement Job is created by the Dockerfile job reconciler.
+func ApplicationStaleDockerfileJobReconciler(c controllers.Config) controllers.SubReconciler {
+	c.Log = c.Log.WithName("StaleDockerfileJob")
+
+	return &controllers.SyncReconciler{
+		Sync: func(ctx context.Context, parent *buildv1alpha1.Application) error {
+			builder, ok := controllers.RetrieveValue(ctx, dockerfileBuilderStashKey).(dockerfileBuilder)
+			if !ok || parent.Spec.Source == nil || parent.Spec.Dockerfile == nil || !dockerfileSourceResolved(parent) {
+				// unwanted jobs are deleted by the dockerfile job reconciler
+				return nil
+			}
+			desired := dockerfileJob(parent, builder, dockerfileBuildEnv(ctx, parent))
+
+			var jobs batchv1.JobList
+			if err := c.List(ctx, &jobs, client.InNamespace(parent.Namespace), client.MatchingField(dockerfileJobIndexField, parent.Name)); err != nil {
+				return err
+			}
+			for i := range jobs.Items {
+				job := &jobs.Items[i]
+				if !metav1.IsControlledBy(job, parent) || job.Annotations[dockerfileBuildHashAnnotationKey] == desired.Annotations[dockerfileBuildHashAnnotationKey] {
+					continue
+				}
+				c.Log.Info("deleting stale job", "job", job.Name)
+				if err := c.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil {
+					c.Recorder.Eventf(parent, corev1.EventTypeWarning, "DeleteFailed",
+						"Failed to delete Job %q: %v", job.Name, err)
+					return err
+				}
+				c.Recorder.Eventf(parent, corev1.EventTypeNormal, "Deleted",
+					"Deleted Job %q", job.Name)
+			}
+			return nil
+		},
+
+		Config: c,
+	}
+}
+
+func ApplicationDockerfileJobReconciler(c controllers.Config) controllers.SubReconciler {
+	c.Log = c.Log.WithName("DockerfileJob")
+
+	return &controllers.ChildReconciler{
+		ParentType:    &buildv1alpha1.Application{},
+		ChildType:     &batchv1.Job{},
+		ChildListType: &batchv1.JobList{},
+
+		DesiredChild: func(ctx context.Context, parent *buildv1alpha1.Application) (*batchv1.Job, error) {
+			builder, ok := controllers.RetrieveValue(ctx, dockerfileBuilderStashKey).(dockerfileBuilder)
+			if !ok || parent.Spec.Source == nil || parent.Spec.Dockerfile == nil {
+				return nil, nil
+			}
+
+			// the condition is reflected by the dockerfile credentials reconciler
+			if err := checkSecretControlled(ctx, c.Client, parent, dockerfileCredentialsSecretName(parent)); err != nil {
+				return nil, err
+			}
+			if values, _ := controllers.RetrieveValue(ctx, buildEnvStashKey).(map[string][]byte); len(values) != 0 {
+				// the condition is reflected by the child build env reconciler
+				if err := checkSecretControlled(ctx, c.Client, parent, buildEnvSecretName(parent, "application")); err != nil {
+					return nil, err
+				}
+			}
+
+			if !dockerfileSourceResolved(parent) {
+				// build once the revision is resolved to a commit
+				return nil, nil
+			}
+
+			return dockerfileJob(parent, builder, dockerfileBuildEnv(ctx, parent)), nil
+		},
+		ReflectChildStatusOnParent: func(parent *buildv1alpha1.Application, child *batchv1.Job, err error) {
+			if err != nil {
+				return
+			}
+			if child == nil {
+				if parent.Spec.Source != nil && parent.Spec.Dockerfile != nil {
+					parent.Status.MarkDockerfileSourceResolving()
+				} else {
+					parent.Status.MarkDockerfileBuildNotUsed()
+				}
+				return
+			}
+			parent.Status.DockerfileJobRef = refs.NewTypedLocalObjectReferenceForObject(child, c.Scheme)
+			parent.Status.PropagateDockerfileJobStatus(&child.Status)
+		},
+		MergeBeforeUpdate: func(current, desired *batchv1.Job) {
+			// the job spec is immutable, jobs with stale specs are replaced
+			// by the stale dockerfile job reconciler
+			current.Labels = desired.Labels
+		},
+		SemanticEquals: func(a1, a2 *batchv1.Job) bool {
+			return equality.Semantic.DeepEqual(a1.Labels, a2.Labels)
+		},
+
+		Config:     c,
+		IndexField: dockerfileJobIndexField,
+		Sanitize: func(child *batchv1.Job) interface{} {
+			return child.Spec.Template.Spec.Containers
+		},
+	}
+}
+
+const (
+	dockerfileJobIndexField = ".metadata.applicationDockerfileJobController"
+
+	dockerfileBuilderStashKey controllers.StashKey = "dockerfile-builder"
+
+	// dockerfileWorkspace is the directory source is fetched into for
+	// Dockerfile builds
+	dockerfileWorkspace = "/workspace/source"
+	// dockerfileBuildEnvDir is the directory build environment values that
+	// reference a Secret or ConfigMap are mounted into for Dockerfile builds.
+	// Kaniko never snapshots its own directory, so the files are not copied
+	// into the image.
+	dockerfileBuildEnvDir = "/kaniko/build-env"
+	// dockerfileGitCredentialsDir is the $HOME of the source container for git
+	// sources, the git credentials of the Dockerfile credentials Secret are
+	// mounted into it
+	dockerfileGitCredentialsDir = "/var/run/riff/git"
+	// dockerfileSourceScript fetches the git or blob source into the workspace
+	dockerfileSourceScript = `set -e
+if [ -n "$GIT_URL" ]; then
+  git clone --quiet "$GIT_URL" ` + dockerfileWorkspace + `
+  cd ` + dockerfileWorkspace + `
+  git checkout --quiet "$GIT_REVISION"
+else
+  mkdir -p ` + dockerfileWorkspace + `
+  wget -q -O - "$BLOB_URL" | tar -xzf - -C ` + dockerfileWorkspace + `
+fi
+`
+)
+
+// dockerfileBuildHashAnnotationKey records a hash of the inputs for a
+// Dockerfile build Job, a Job with a different hash is stale
+var dockerfileBuildHashAnnotationKey = buildv1alpha1.GroupVersion.Group + "/dockerfile-build-hash"
+
+func dockerfileCredentialsSecretName(parent *buildv1alpha1.Application) string {
+	return fmt.Sprintf("%s-dockerfile-credentials", parent.Name)
+}
+
+// dockerfileSourceResolved returns true if the source of the Dockerfile build
+// is ready to fetch. Git revisions must first be resolved to a commit.
+func dockerfileSourceResolved(parent *buildv1alpha1.Application) bool {
+	return parent.Spec.Source.Git == nil || parent.Status.DockerfileSourceRevision != ""
+}
+
+// dockerfileBuildEnv is the environment of the Dockerfile builder, values
+// referencing a Secret or ConfigMap are read from the build env Secret.
+func dockerfileBuildEnv(ctx context.Context, parent *buildv1alpha1.Application) []corev1.EnvVar {
+	values, _ := controllers.RetrieveValue(ctx, buildEnvStashKey).(map[string][]byte)
+	digestKey, _ := controllers.RetrieveValue(ctx, buildEnvDigestKeyStashKey).([]byte)
+	return managedBuildEnv(parent.Spec.Build.Env, buildEnvSecretName(parent, "application"), values, digestKey)
+}
+
+// dockerfileJob is the Job that builds the Application's image from a
+// Dockerfile. The source is fetched into a shared volume by an init container
+// and then built and pushed to the target image by the builder. Git sources
+// are cloned with the git credentials of the ServiceAccount from the
+// Dockerfile credentials Secret. Literal build environment variables are
+// passed as build args. Build args are recorded in the pushed image's config
+// and history, so values referencing a Secret or ConfigMap are instead
+// mounted as files in dockerfileBuildEnvDir, named by the variable, for the
+// Dockerfile to read while building.
+func dockerfileJob(parent *buildv1alpha1.Application, builder dockerfileBuilder, env []corev1.EnvVar) *batchv1.Job {
+	source := parent.Spec.Source
+	sourceEnv := []corev1.EnvVar{}
+	sourceVolumeMounts := []corev1.VolumeMount{
+		{Name: "workspace", MountPath: path.Dir(dockerfileWorkspace)},
+	}
+	if source.Git != nil {
+		sourceEnv = append(sourceEnv,
+			corev1.EnvVar{Name: "GIT_URL", Value: source.Git.URL},
+			corev1.EnvVar{Name: "GIT_REVISION", Value: parent.Status.DockerfileSourceRevision},
+			// git reads its config and credentials from $HOME
+			corev1.EnvVar{Name: "HOME", Value: dockerfileGitCredentialsDir},
+		)
+		sourceVolumeMounts = append(sourceVolumeMounts, corev1.VolumeMount{Name: "git-credentials", MountPath: dockerfileGitCredentialsDir, ReadOnly: true})
+	}
+	if source.Blob != nil {
+		sourceEnv = append(sourceEnv, corev1.EnvVar{Name: "BLOB_URL", Value: source.Blob.URL})
+	}
+	contextDir := path.Join(dockerfileWorkspace, source.SubPath)
+	args := []string{
+		fmt.Sprintf("--context=dir://%s", contextDir),
+		fmt.Sprintf("--dockerfile=%s", path.Join(contextDir, parent.Spec.Dockerfile.Path)),
+		fmt.Sprintf("--destination=%s", parent.Status.TargetImage),
+	}
+	var buildEnv []corev1.EnvVar
+	var buildEnvFiles []corev1.KeyToPath
+	var buildEnvSecretName string
+	for _, e := range env {
+		switch {
+		case e.Name == buildEnvDigestName:
+			// only changes the job when a referenced value changes
+			buildEnv = append(buildEnv, e)
+		case e.ValueFrom == nil:
+			buildEnv = append(buildEnv, e)
+			args = append(args, fmt.Sprintf("--build-arg=%s=$(%s)", e.Name, e.Name))
+		case e.ValueFrom.SecretKeyRef != nil:
+			// the value is never exposed as a build arg or to the environment
+			buildEnvSecretName = e.ValueFrom.SecretKeyRef.Name
+			buildEnvFiles = append(buildEnvFiles, corev1.KeyToPath{Key: e.ValueFrom.SecretKeyRef.Key, Path: e.Name})
+		}
+	}
+	volumeMounts := []corev1.VolumeMount{
+		{Name: "workspace", MountPath: path.Dir(dockerfileWorkspace)},
+		{Name: "docker-config", MountPath: "/kaniko/.docker", ReadOnly: true},
+	}
+	volumes := []corev1.Volume{
+		{
+			Name: "workspace",
+			VolumeSource: corev1.VolumeSource{
+				EmptyDir: &corev1.EmptyDirVolumeSource{},
+			},
+		},
+		{
+			Name: "docker-config",
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: dockerfileCredentialsSecretName(parent),
+					Items: []corev1.KeyToPath{
+						{Key: corev1.DockerConfigJsonKey, Path: "config.json"},
+					},
+				},
+			},
+		},
+	}
+	if source.Git != nil {
+		// ssh refuses private keys readable by others
+		mode := int32(0400)
+		volumes = append(volumes, corev1.Volume{
+			Name: "git-credentials",
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName:  dockerfileCredentialsSecretName(parent),
+					DefaultMode: &mode,
+				},
+			},
+		})
+	}
+	if len(buildEnvFiles) != 0 {
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{Name: "build-env", MountPath: dockerfileBuildEnvDir, ReadOnly: true})
+		volumes = append(volumes, corev1.Volume{
+			Name: "build-env",
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: buildEnvSecretName,
+					Items:      buildEnvFiles,
+				},
+			},
+		})
+	}
+	backoffLimit := int32(0)
+
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels: controllers.MergeMaps(parent.Labels, map[string]string{
+				buildv1alpha1.ApplicationLabelKey: parent.Name,
+			}),
+			Annotations:  map[string]string{},
+			GenerateName: fmt.Sprintf("%s-dockerfile-", parent.Name),
+			Namespace:    parent.Namespace,
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						buildv1alpha1.ApplicationLabelKey: parent.Name,
+					},
+				},
+				Spec: corev1.PodSpec{
+					ServiceAccountName: parent.Spec.ServiceAccountName,
+					RestartPolicy:      corev1.RestartPolicyNever,
+					InitContainers: []corev1.Container{
+						{
+							Name:         "source",
+							Image:        builder.SourceImage,
+							Command:      []string{"/bin/sh", "-c", dockerfileSourceScript},
+							Env:          sourceEnv,
+							VolumeMounts: sourceVolumeMounts,
+						},
+					},
+					Containers: []corev1.Container{
+						{
+							Name:         "build",
+							Image:        builder.BuilderImage,
+							Args:         args,
+							Env:          buildEnv,
+							VolumeMounts: volumeMounts,
+						},
+					},
+					Volumes: volumes,
+				},
+			},
+		},
+	}
+
+	spec, _ := json.Marshal(job.Spec)
+	job.Annotations[dockerfileBuildHashAnnotationKey] = fmt.Sprintf("%x", sha256.Sum256(spec))
+
+	return job
+}
+
+// dockerfileBuilder are the images that run Dockerfile builds
+type dockerfileBuilder struct {
+	// BuilderImage builds and pushes the image, like kaniko's executor
+	BuilderImage string
+	// SourceImage fetches the source, it must provide sh, git, wget and tar,
+	// and ssh for git sources cloned over ssh
+	SourceImage string
+}
+
+var errDockerfileBuilderNotConfigured = fmt.Errorf("%q and %q must be set in the %s ConfigMap to build from a Dockerfile", dockerfileBuilderImageKey, dockerfileSourceImageKey, riffBuildServiceAccount)
+
+func resolveDockerfileBuilder(ctx context.Context, c controllers.Config, systemNamespace string, parent *buildv1alpha1.Application) (dockerfileBuilder, error) {
+	builder := dockerfileBuilder{}
+	key := types.NamespacedName{Namespace: systemNamespace, Name: riffBuildServiceAccount}
+	// track riff-build config for new builder images
+	c.Tracker.Track(
+		tracker.NewKey(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, key),
+		types.NamespacedName{Namespace: parent.Namespace, Name: parent.Name},
+	)
+	var riffBuildConfig corev1.ConfigMap
+	if err := c.Get(ctx, key, &riffBuildConfig); err != nil {
+		if !apierrs.IsNotFound(err) {
+			return builder, err
+		}
+	}
+	builder.BuilderImage = riffBuildConfig.Data[dockerfileBuilderImageKey]
+	builder.SourceImage = riffBuildConfig.Data[dockerfileSourceImageKey]
+	if builder.BuilderImage == "" || builder.SourceImage == "" {
+		return builder, errDockerfileBuilderNotConfigured
+	}
+	return builder, nil
+}
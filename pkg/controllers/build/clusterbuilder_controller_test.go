@@ -126,6 +126,39 @@ func TestClusterBuildersReconciler(t *testing.T) {
 				AddData("riff-application", testApplicationImage).
 				AddData("riff-function", testFunctionImage),
 		},
+	}, {
+		Name: "create builders configmap, custom builder",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			testApplicationBuilderReady,
+			testFunctionBuilderReady,
+			factories.KpackClusterBuilder().
+				NamespaceName("", "my-builder").
+				Image("example.com/my-builder").
+				StatusReady().
+				StatusLatestImage("example.com/my-builder@sha256:1a2b3c"),
+		},
+		ExpectCreates: []rtesting.Factory{
+			testBuilders.
+				AddData("riff-application", testApplicationImage).
+				AddData("riff-function", testFunctionImage).
+				AddData("my-builder", "example.com/my-builder@sha256:1a2b3c"),
+		},
+	}, {
+		Name: "create builders configmap, registry rewrites not applied",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			testApplicationBuilderReady,
+			testFunctionBuilderReady,
+			factories.ConfigMap().
+				NamespaceName(testNamespace, "riff-registry-rewrites").
+				AddData("rewrites", "index.docker.io/projectriff -> mirror.local/riff"),
+		},
+		ExpectCreates: []rtesting.Factory{
+			testBuilders.
+				AddData("riff-application", testApplicationImage).
+				AddData("riff-function", testFunctionImage),
+		},
 	}, {
 		Name: "create builders configmap, error",
 		Key:  testKey,
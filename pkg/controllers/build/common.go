@@ -18,42 +18,942 @@ package build
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/json"
 	"fmt"
+	"io"
+	"sort"
 	"strings"
+	"time"
 
+	"github.com/go-logr/logr"
+	gauthn "github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	imagetypes "github.com/google/go-containerregistry/pkg/v1/types"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
+	"github.com/projectriff/system/pkg/apis"
 	buildv1alpha1 "github.com/projectriff/system/pkg/apis/build/v1alpha1"
+	kpackbuildv1alpha1 "github.com/projectriff/system/pkg/apis/thirdparty/kpack/build/v1alpha1"
+	"github.com/projectriff/system/pkg/authn"
+	"github.com/projectriff/system/pkg/controllers"
+	"github.com/projectriff/system/pkg/registries"
+	"github.com/projectriff/system/pkg/tracker"
 )
 
-const riffBuildServiceAccount = "riff-build"
+const riffBuildServiceAccount = buildv1alpha1.DefaultServiceAccountName
 
-var errMissingDefaultPrefix = fmt.Errorf("missing default image prefix")
+var errMissingDefaultPrefix = buildv1alpha1.ErrMissingDefaultPrefix
+
+const (
+	// defaultImagePrefixKey in the riff-build ConfigMap is the registry and
+	// optional repository path for default images
+	defaultImagePrefixKey = "default-image-prefix"
+	// defaultImageTemplateKey in the riff-build ConfigMap is the template for
+	// the image of resources whose image is '_'
+	defaultImageTemplateKey = "default-image-template"
+	// dockerfileBuilderImageKey in the riff-build ConfigMap is the image that
+	// builds and pushes images from a Dockerfile
+	dockerfileBuilderImageKey = "dockerfile-builder-image"
+	// dockerfileSourceImageKey in the riff-build ConfigMap is the image that
+	// fetches source for Dockerfile builds
+	dockerfileSourceImageKey = "dockerfile-source-image"
+)
 
 // +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch
 
-func resolveTargetImage(ctx context.Context, client client.Client, build buildv1alpha1.ImageResource) (string, error) {
-	if !strings.HasPrefix(build.GetImage(), "_") {
-		return build.GetImage(), nil
+// resolveTargetImage resolves the image the resource is built to, applying
+// the default image and the cluster-wide registry rewrite rules.
+func resolveTargetImage(ctx context.Context, client client.Client, systemNamespace string, build buildv1alpha1.ImageResource) (string, error) {
+	image := build.GetImage()
+	if strings.HasPrefix(image, "_") {
+		defaults, err := resolveImageDefaults(ctx, client, systemNamespace, build.GetNamespace())
+		if err != nil {
+			return "", err
+		}
+		image, err = buildv1alpha1.ResolveDefaultImage(build, defaults)
+		if err != nil {
+			return "", err
+		}
 	}
 
-	var riffBuildConfig corev1.ConfigMap
-	if err := client.Get(ctx, types.NamespacedName{Namespace: build.GetNamespace(), Name: riffBuildServiceAccount}, &riffBuildConfig); err != nil {
-		if apierrs.IsNotFound(err) {
-			return "", errMissingDefaultPrefix
+	rewriter, err := registries.Load(ctx, client, systemNamespace)
+	if err != nil {
+		return "", err
+	}
+	return rewriter.Rewrite(image), nil
+}
+
+// resolveImageDefaults reads the image defaults from the riff-build ConfigMap
+// in the namespace. Values not set for the namespace fall back to the
+// riff-build ConfigMap in the system namespace, the cluster-wide default.
+func resolveImageDefaults(ctx context.Context, c client.Client, systemNamespace, namespace string) (buildv1alpha1.ImageDefaults, error) {
+	defaults := buildv1alpha1.ImageDefaults{}
+	namespaces := []string{namespace}
+	if systemNamespace != "" && systemNamespace != namespace {
+		namespaces = append(namespaces, systemNamespace)
+	}
+	for _, ns := range namespaces {
+		var riffBuildConfig corev1.ConfigMap
+		if err := c.Get(ctx, types.NamespacedName{Namespace: ns, Name: riffBuildServiceAccount}, &riffBuildConfig); err != nil {
+			if apierrs.IsNotFound(err) {
+				continue
+			}
+			return defaults, err
+		}
+		if defaults.Prefix == "" {
+			defaults.Prefix = riffBuildConfig.Data[defaultImagePrefixKey]
 		}
+		if defaults.Template == "" {
+			defaults.Template = riffBuildConfig.Data[defaultImageTemplateKey]
+		}
+	}
+	return defaults, nil
+}
+
+// imagePollingInterval is how often images that are not built are re-resolved
+// to detect a moved tag.
+var imagePollingInterval = 1 * time.Minute
+
+// resolveImageDigest resolves the image to an immutable digest reference,
+// authenticating with the credentials bound to the service account. An image
+// index resolves to the digest of the index.
+func resolveImageDigest(ctx context.Context, c client.Client, log logr.Logger, namespace, serviceAccountName string, ref name.Reference) (string, error) {
+	resolved, err := resolveImage(ctx, c, log, namespace, serviceAccountName, ref, nil)
+	if err != nil {
 		return "", err
 	}
-	defaultPrefix := riffBuildConfig.Data["default-image-prefix"]
-	if defaultPrefix == "" {
-		return "", errMissingDefaultPrefix
+	return resolved.Image, nil
+}
+
+// resolvedImage is an image resolved to an immutable digest reference
+type resolvedImage struct {
+	// Image is the digest reference of the image, or of the image index when
+	// a platform is not selected
+	Image string
+	// Platforms are the images within an image index
+	Platforms []buildv1alpha1.PlatformImage
+}
+
+// resolveImage resolves the image to an immutable digest reference,
+// authenticating with the credentials bound to the service account. When the
+// image is an image index, the image for each platform is resolved and the
+// image for the selected platform, if any, is used.
+func resolveImage(ctx context.Context, c client.Client, log logr.Logger, namespace, serviceAccountName string, ref name.Reference, platform *buildv1alpha1.ImagePlatform) (*resolvedImage, error) {
+	keychain, err := constructKeychain(ctx, c, log, namespace, serviceAccountName)
+	if err != nil {
+		return nil, err
+	}
+
+	auth, err := keychain.Resolve(ref.Context().Registry)
+	if err != nil {
+		log.Error(err, "unable to resolve auth for registry", "registry", ref.Context().RegistryStr())
+		return nil, err
+	}
+
+	desc, err := remote.Get(ref, remote.WithAuth(auth))
+	if err != nil {
+		log.Error(err, "failed to read image", "image", ref.String())
+		return nil, err
+	}
+
+	resolved := &resolvedImage{
+		Image: fmt.Sprintf("%s@%s", ref.Context().Name(), desc.Digest),
+	}
+	if !isImageIndex(desc.MediaType) {
+		return resolved, nil
+	}
+
+	index, err := desc.ImageIndex()
+	if err != nil {
+		log.Error(err, "failed to read image index", "image", ref.String())
+		return nil, err
+	}
+	manifest, err := index.IndexManifest()
+	if err != nil {
+		log.Error(err, "failed to read image index manifest", "image", ref.String())
+		return nil, err
+	}
+	for _, m := range manifest.Manifests {
+		if m.Platform == nil {
+			continue
+		}
+		resolved.Platforms = append(resolved.Platforms, buildv1alpha1.PlatformImage{
+			ImagePlatform: buildv1alpha1.ImagePlatform{
+				OS:           m.Platform.OS,
+				Architecture: m.Platform.Architecture,
+				Variant:      m.Platform.Variant,
+			},
+			Image: fmt.Sprintf("%s@%s", ref.Context().Name(), m.Digest),
+		})
+	}
+	if platform == nil {
+		return resolved, nil
+	}
+	for _, p := range resolved.Platforms {
+		if p.OS == platform.OS && p.Architecture == platform.Architecture && (platform.Variant == "" || p.Variant == platform.Variant) {
+			resolved.Image = p.Image
+			return resolved, nil
+		}
+	}
+	return nil, fmt.Errorf("image index %q has no image for platform %q", ref.String(), platformName(*platform))
+}
+
+// platformName formats the platform as os/architecture[/variant]
+func platformName(platform buildv1alpha1.ImagePlatform) string {
+	parts := []string{platform.OS, platform.Architecture}
+	if platform.Variant != "" {
+		parts = append(parts, platform.Variant)
+	}
+	return strings.Join(parts, "/")
+}
+
+const (
+	buildMetadataLabel     = "io.buildpacks.build.metadata"
+	lifecycleMetadataLabel = "io.buildpacks.lifecycle.metadata"
+	stackIDLabel           = "io.buildpacks.stack.id"
+)
+
+// lifecycleMetadata is the subset of the lifecycle metadata label used to
+// describe the image
+type lifecycleMetadata struct {
+	Buildpacks kpackbuildv1alpha1.BuildpackMetadataList `json:"buildpacks"`
+	RunImage   struct {
+		Reference string `json:"reference"`
+	} `json:"runImage"`
+	Stack struct {
+		RunImage struct {
+			Image string `json:"image"`
+		} `json:"runImage"`
+	} `json:"stack"`
+}
+
+// buildMetadata is the subset of the build metadata label used to describe
+// the image
+type buildMetadata struct {
+	BOM []struct {
+		Name      string `json:"name"`
+		Version   string `json:"version"`
+		Buildpack struct {
+			ID string `json:"id"`
+		} `json:"buildpack"`
+	} `json:"bom"`
+}
+
+// isImageIndex returns true if the media type is an OCI image index or a
+// docker manifest list.
+func isImageIndex(mediaType imagetypes.MediaType) bool {
+	switch mediaType {
+	case imagetypes.OCIImageIndex, imagetypes.DockerManifestList:
+		return true
+	}
+	return false
+}
+
+// imageIndexBuildImage resolves the image of an index the build metadata is
+// read from. Builds are made for linux/amd64, which is preferred, otherwise
+// the first image in the index is used.
+func imageIndexBuildImage(desc *remote.Descriptor) (v1.Image, error) {
+	index, err := desc.ImageIndex()
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := index.IndexManifest()
+	if err != nil {
+		return nil, err
+	}
+	var selected *v1.Descriptor
+	for i := range manifest.Manifests {
+		m := &manifest.Manifests[i]
+		if isImageIndex(m.MediaType) {
+			continue
+		}
+		if m.Platform != nil && m.Platform.OS == "linux" && m.Platform.Architecture == "amd64" {
+			selected = m
+			break
+		}
+		if selected == nil {
+			selected = m
+		}
+	}
+	if selected == nil {
+		return nil, fmt.Errorf("image index %s does not contain an image", desc.Digest)
+	}
+	return index.Image(selected.Digest)
+}
+
+// resolveBuildMetadata reads the metadata recorded by the buildpack lifecycle
+// from the labels of the image, authenticating with the credentials bound to
+// the service account.
+func resolveBuildMetadata(ctx context.Context, c client.Client, log logr.Logger, namespace, serviceAccountName, image string) (*buildv1alpha1.BuildMetadata, error) {
+	ref, err := name.ParseReference(image, name.WeakValidation)
+	if err != nil {
+		return nil, err
+	}
+	keychain, err := constructKeychain(ctx, c, log, namespace, serviceAccountName)
+	if err != nil {
+		return nil, err
+	}
+
+	desc, err := remote.Get(ref, remote.WithAuthFromKeychain(keychain))
+	if err != nil {
+		log.Error(err, "failed to read image", "image", ref.String())
+		return nil, err
+	}
+	var img v1.Image
+	if isImageIndex(desc.MediaType) {
+		img, err = imageIndexBuildImage(desc)
+	} else {
+		img, err = desc.Image()
+	}
+	if err != nil {
+		log.Error(err, "failed to read image", "image", ref.String())
+		return nil, err
+	}
+	config, err := img.ConfigFile()
+	if err != nil {
+		log.Error(err, "failed to read image config", "image", ref.String())
+		return nil, err
+	}
+
+	metadata := &buildv1alpha1.BuildMetadata{
+		Image: image,
+	}
+	labels := config.Config.Labels
+	metadata.StackID = labels[stackIDLabel]
+	if raw, ok := labels[lifecycleMetadataLabel]; ok {
+		var lm lifecycleMetadata
+		if err := json.Unmarshal([]byte(raw), &lm); err != nil {
+			return nil, fmt.Errorf("invalid %s label: %v", lifecycleMetadataLabel, err)
+		}
+		metadata.Buildpacks = lm.Buildpacks
+		metadata.RunImage = lm.RunImage.Reference
+		if metadata.RunImage == "" {
+			metadata.RunImage = lm.Stack.RunImage.Image
+		}
+	}
+	if raw, ok := labels[buildMetadataLabel]; ok {
+		var bm buildMetadata
+		if err := json.Unmarshal([]byte(raw), &bm); err != nil {
+			return nil, fmt.Errorf("invalid %s label: %v", buildMetadataLabel, err)
+		}
+		for _, entry := range bm.BOM {
+			metadata.BillOfMaterials = append(metadata.BillOfMaterials, buildv1alpha1.BillOfMaterialsEntry{
+				Name:      entry.Name,
+				Version:   entry.Version,
+				Buildpack: entry.Buildpack.ID,
+			})
+		}
+	}
+
+	return metadata, nil
+}
+
+func constructKeychain(ctx context.Context, c client.Client, log logr.Logger, namespace, serviceAccountName string) (gauthn.Keychain, error) {
+	var serviceAccount corev1.ServiceAccount
+	if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: serviceAccountName}, &serviceAccount); err != nil {
+		if apierrs.IsNotFound(err) {
+			log.Info("service account not found", "service-account", serviceAccountName)
+			return nil, err
+		} else {
+			log.Error(err, "failed to get service account", "service-account", serviceAccountName)
+			return nil, err
+		}
 	}
-	image, err := buildv1alpha1.ResolveDefaultImage(build, defaultPrefix)
+	secrets, err := fetchSecrets(ctx, c, log, serviceAccount)
 	if err != nil {
+		return nil, err
+	}
+
+	return gauthn.NewMultiKeychain(authn.NewSecretsKeychain(secrets), gauthn.DefaultKeychain), nil
+}
+
+func fetchSecrets(ctx context.Context, c client.Client, log logr.Logger, serviceAccount corev1.ServiceAccount) ([]corev1.Secret, error) {
+	var secrets []corev1.Secret
+	for _, secretRef := range serviceAccount.Secrets {
+		var secret corev1.Secret
+		if err := c.Get(ctx, types.NamespacedName{Namespace: serviceAccount.Namespace, Name: secretRef.Name}, &secret); err != nil {
+			if apierrs.IsNotFound(err) {
+				log.Info("secret not found", "secret", secretRef.Name)
+				continue
+			} else {
+				log.Error(err, "failed to get secret", "secret", secretRef.Name)
+				return nil, err
+			}
+		}
+		secrets = append(secrets, secret)
+	}
+	return secrets, nil
+}
+
+// credentialHealth summarizes the verification state of the credentials bound
+// to a registry.
+type credentialHealth struct {
+	// Secrets are the credentials bound to the registry
+	Secrets []corev1.Secret
+	// Message describes the failures when none of the credentials are working
+	Message string
+}
+
+// Bound returns true if at least one credential targets the registry.
+func (h *credentialHealth) Bound() bool {
+	return len(h.Secrets) != 0
+}
+
+// Working returns true if at least one credential for the registry has not
+// failed verification.
+func (h *credentialHealth) Working() bool {
+	return h.Message == ""
+}
+
+func resolveCredentialHealth(ctx context.Context, c client.Client, namespace, serviceAccountName, image string) (*credentialHealth, error) {
+	health := &credentialHealth{}
+	ref, err := name.ParseReference(image, name.WeakValidation)
+	if err != nil {
+		return nil, err
+	}
+
+	var serviceAccount corev1.ServiceAccount
+	if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: serviceAccountName}, &serviceAccount); err != nil {
+		if apierrs.IsNotFound(err) {
+			// no credentials are bound
+			return health, nil
+		}
+		return nil, err
+	}
+	boundSecrets := sets.NewString()
+	for _, secret := range serviceAccount.Secrets {
+		boundSecrets.Insert(secret.Name)
+	}
+
+	var secrets corev1.SecretList
+	if err := c.List(ctx, &secrets, client.InNamespace(namespace), MatchingLabels(buildv1alpha1.CredentialLabelKey)); err != nil {
+		return nil, err
+	}
+	failures := []string{}
+	for _, secret := range secrets.Items {
+		if !boundSecrets.Has(secret.Name) {
+			continue
+		}
+		registry := secret.Annotations[authn.DockerSecretAnnotation]
+		if registry == "" || normalizeRegistry(registry) != ref.Context().RegistryStr() {
+			continue
+		}
+		health.Secrets = append(health.Secrets, secret)
+		if msg, ok := secret.Annotations[buildv1alpha1.CredentialErrorAnnotationKey]; ok {
+			failures = append(failures, fmt.Sprintf("credential %q: %s", secret.Name, msg))
+		}
+	}
+	if health.Bound() && len(failures) == len(health.Secrets) {
+		health.Message = fmt.Sprintf("no working credential for registry %q; %s", ref.Context().RegistryStr(), strings.Join(failures, "; "))
+	}
+
+	return health, nil
+}
+
+// builderNotFoundError reports that a selected builder is not available.
+type builderNotFoundError struct {
+	builder buildv1alpha1.BuilderReference
+}
+
+func (e *builderNotFoundError) Error() string {
+	return fmt.Sprintf("%s %q not found", e.builder.Kind, e.builder.Name)
+}
+
+// resolveBuilderImage returns the latest image of the builder. ClusterBuilders
+// must be listed in the builders ConfigMap within the system namespace, while
+// Builders are found in the namespace of the build resource.
+func resolveBuilderImage(ctx context.Context, c controllers.Config, systemNamespace string, parent apis.Object, builder buildv1alpha1.BuilderReference) (string, error) {
+	parentKey := types.NamespacedName{Namespace: parent.GetNamespace(), Name: parent.GetName()}
+
+	if builder.Kind == buildv1alpha1.BuilderKind {
+		key := types.NamespacedName{Namespace: parent.GetNamespace(), Name: builder.Name}
+		// track builder for new images
+		c.Tracker.Track(tracker.NewKey(kpackbuildv1alpha1.GroupVersion.WithKind("Builder"), key), parentKey)
+		var b kpackbuildv1alpha1.Builder
+		if err := c.Get(ctx, key, &b); err != nil {
+			if apierrs.IsNotFound(err) {
+				return "", &builderNotFoundError{builder: builder}
+			}
+			return "", err
+		}
+		return b.Status.LatestImage, nil
+	}
+
+	key := types.NamespacedName{Namespace: systemNamespace, Name: buildersConfigMap}
+	// track builders config for new images
+	c.Tracker.Track(tracker.NewKey(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, key), parentKey)
+	var builders corev1.ConfigMap
+	if err := c.Get(ctx, key, &builders); err != nil {
+		if apierrs.IsNotFound(err) {
+			return "", &builderNotFoundError{builder: builder}
+		}
 		return "", err
 	}
+	image, ok := builders.Data[builder.Name]
+	if !ok {
+		return "", &builderNotFoundError{builder: builder}
+	}
 	return image, nil
 }
+
+// buildHistoryLimit is the number of recent builds reflected in status
+var buildHistoryLimit = 10
+
+func resolveBuildHistory(ctx context.Context, c client.Client, namespace, imageName string) ([]buildv1alpha1.BuildSummary, error) {
+	var builds kpackbuildv1alpha1.BuildList
+	if err := c.List(ctx, &builds, client.InNamespace(namespace), client.MatchingLabels{kpackbuildv1alpha1.ImageLabel: imageName}); err != nil {
+		return nil, err
+	}
+	items := builds.Items
+	// newest builds first
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].BuildNumber() > items[j].BuildNumber()
+	})
+	if len(items) > buildHistoryLimit {
+		items = items[:buildHistoryLimit]
+	}
+
+	var history []buildv1alpha1.BuildSummary
+	for i := range items {
+		history = append(history, summarizeBuild(&items[i]))
+	}
+	return history, nil
+}
+
+func summarizeBuild(build *kpackbuildv1alpha1.Build) buildv1alpha1.BuildSummary {
+	summary := buildv1alpha1.BuildSummary{
+		Name:         build.Name,
+		BuildNumber:  build.BuildNumber(),
+		Reason:       build.BuildReason(),
+		Outcome:      buildv1alpha1.BuildOutcomeRunning,
+		PodName:      build.Status.PodName,
+		BuilderImage: build.Spec.Builder.Image,
+	}
+	if build.Spec.Source.Git != nil {
+		summary.Revision = build.Spec.Source.Git.Revision
+	}
+	if !build.CreationTimestamp.IsZero() {
+		summary.StartTime = build.CreationTimestamp.DeepCopy()
+	}
+	if cond := build.Status.GetCondition(apis.ConditionSucceeded); cond != nil {
+		switch cond.Status {
+		case corev1.ConditionTrue:
+			summary.Outcome = buildv1alpha1.BuildOutcomeSucceeded
+			summary.CompletionTime = cond.LastTransitionTime.Inner.DeepCopy()
+		case corev1.ConditionFalse:
+			summary.Outcome = buildv1alpha1.BuildOutcomeFailed
+			summary.Message = cond.Message
+			summary.CompletionTime = cond.LastTransitionTime.Inner.DeepCopy()
+		}
+	}
+	return summary
+}
+
+// upToDateStatus is implemented by the status of resources that are built
+// with a builder
+type upToDateStatus interface {
+	MarkUpToDate()
+	MarkBuilderOutdated(message string)
+	MarkRebuildRunning(message string)
+	MarkUpToDateNotApplicable()
+}
+
+// reflectUpToDate compares the builder image used for the most recent
+// successful build with the current builder image.
+func reflectUpToDate(status upToDateStatus, builds []buildv1alpha1.BuildSummary, builderImage string) {
+	var latest *buildv1alpha1.BuildSummary
+	for i := range builds {
+		if builds[i].Outcome == buildv1alpha1.BuildOutcomeSucceeded {
+			latest = &builds[i]
+			break
+		}
+	}
+	switch {
+	case latest == nil || builderImage == "":
+		status.MarkUpToDateNotApplicable()
+	case latest.BuilderImage == builderImage:
+		status.MarkUpToDate()
+	case builds[0].Outcome == buildv1alpha1.BuildOutcomeRunning && builds[0].BuilderImage == builderImage:
+		status.MarkRebuildRunning(fmt.Sprintf("build %q is using builder image %q", builds[0].Name, builderImage))
+	default:
+		status.MarkBuilderOutdated(fmt.Sprintf("latest image was built with builder image %q, the builder image is now %q", latest.BuilderImage, builderImage))
+	}
+}
+
+// rebuildBuilderImageEnvName is the build environment variable holding the
+// builder image of resources with the BuilderUpdated rebuild policy. kpack
+// builds again when the build environment changes, so a new builder image
+// triggers a rebuild. kpack passes the environment to the buildpacks
+// unfiltered, so the variable is visible to the build like the other RIFF_*
+// variables set for functions.
+const rebuildBuilderImageEnvName = "RIFF_BUILDER_IMAGE"
+
+// rebuildBuildEnv adds the builder image to the build environment when the
+// policy rebuilds on builder updates.
+func rebuildBuildEnv(env []corev1.EnvVar, policy buildv1alpha1.RebuildPolicy, builderImage string) []corev1.EnvVar {
+	if policy != buildv1alpha1.RebuildPolicyBuilderUpdated || builderImage == "" {
+		return env
+	}
+	return append(env, corev1.EnvVar{Name: rebuildBuilderImageEnvName, Value: builderImage})
+}
+
+// reconcileBuildCache reflects the kpack build cache on the status and resets
+// the cache when requested with the clear build cache annotation, when it is
+// larger than the requested size or after consecutive failed builds. The
+// cache is only reset between builds, kpack recreates the deleted
+// PersistentVolumeClaim.
+func reconcileBuildCache(ctx context.Context, c controllers.Config, parent apis.Object, size *resource.Quantity, policy *buildv1alpha1.BuildCachePolicy, status *buildv1alpha1.BuildStatus) error {
+	if status.BuildCacheRef == nil {
+		status.BuildCache = nil
+		return nil
+	}
+	if status.BuildCache == nil {
+		status.BuildCache = &buildv1alpha1.BuildCacheStatus{}
+	}
+	cache := status.BuildCache
+
+	var pvc corev1.PersistentVolumeClaim
+	key := types.NamespacedName{Namespace: parent.GetNamespace(), Name: status.BuildCacheRef.Name}
+	// track the cache for recreation by kpack
+	c.Tracker.Track(
+		tracker.NewKey(schema.GroupVersionKind{Version: "v1", Kind: "PersistentVolumeClaim"}, key),
+		types.NamespacedName{Namespace: parent.GetNamespace(), Name: parent.GetName()},
+	)
+	if err := c.Get(ctx, key, &pvc); err != nil {
+		if !apierrs.IsNotFound(err) {
+			return err
+		}
+		pvc = corev1.PersistentVolumeClaim{}
+	}
+	if pvc.Name == "" || pvc.DeletionTimestamp != nil {
+		// kpack will create a new cache
+		cache.Requested = nil
+		cache.Capacity = nil
+		cache.CreationTime = nil
+		if equality.Semantic.DeepEqual(cache, &buildv1alpha1.BuildCacheStatus{}) {
+			status.BuildCache = nil
+		}
+		return nil
+	}
+	cache.Requested = storageQuantity(pvc.Spec.Resources.Requests)
+	cache.Capacity = storageQuantity(pvc.Status.Capacity)
+	cache.CreationTime = nil
+	if !pvc.CreationTimestamp.IsZero() {
+		cache.CreationTime = pvc.CreationTimestamp.DeepCopy()
+	}
+
+	clearRequest := parent.GetAnnotations()[buildv1alpha1.ClearBuildCacheAnnotationKey]
+	var reason buildv1alpha1.BuildCacheResetReason
+	switch {
+	case clearRequest != "" && clearRequest != cache.ClearRequest:
+		reason = buildv1alpha1.BuildCacheResetRequested
+	case size != nil && cache.Requested != nil && cache.Requested.Cmp(*size) > 0:
+		reason = buildv1alpha1.BuildCacheResetResized
+	case policy != nil && policy.ResetAfterFailedBuilds != nil && failedBuildsSince(status.Builds, pvc.CreationTimestamp) >= int(*policy.ResetAfterFailedBuilds):
+		reason = buildv1alpha1.BuildCacheResetFailedBuilds
+	default:
+		return nil
+	}
+	if len(status.Builds) != 0 && status.Builds[0].Outcome == buildv1alpha1.BuildOutcomeRunning {
+		// reset once the build completes
+		c.Log.Info("deferring build cache reset until the running build completes", "reason", reason)
+		return nil
+	}
+
+	c.Log.Info("resetting build cache", "cache", pvc.Name, "reason", reason)
+	if err := c.Delete(ctx, &pvc); err != nil && !apierrs.IsNotFound(err) {
+		c.Recorder.Eventf(parent, corev1.EventTypeWarning, "ResetFailed",
+			"Failed to reset build cache %q: %v", pvc.Name, err)
+		return err
+	}
+	c.Recorder.Eventf(parent, corev1.EventTypeNormal, "Reset",
+		"Reset build cache %q, reason %s", pvc.Name, reason)
+	cache.Requested = nil
+	cache.Capacity = nil
+	cache.CreationTime = nil
+	cache.LastResetReason = reason
+	if reason == buildv1alpha1.BuildCacheResetRequested {
+		cache.ClearRequest = clearRequest
+	}
+	return nil
+}
+
+// failedBuildsSince counts the consecutive most recent builds that failed
+// and were started after the time.
+func failedBuildsSince(builds []buildv1alpha1.BuildSummary, since metav1.Time) int {
+	failed := 0
+	for _, build := range builds {
+		if build.Outcome != buildv1alpha1.BuildOutcomeFailed || build.StartTime == nil || build.StartTime.Before(&since) {
+			break
+		}
+		failed++
+	}
+	return failed
+}
+
+func storageQuantity(resources corev1.ResourceList) *resource.Quantity {
+	quantity, ok := resources[corev1.ResourceStorage]
+	if !ok {
+		return nil
+	}
+	return &quantity
+}
+
+// buildEnvStashKey holds the resolved values of build environment variables
+// that reference a Secret or ConfigMap, keyed by the variable name.
+const buildEnvStashKey controllers.StashKey = "build-env"
+
+// buildEnvDigestKeyStashKey holds the key for the digest of the resolved
+// build environment values.
+const buildEnvDigestKeyStashKey controllers.StashKey = "build-env-digest-key"
+
+// buildEnvDigestName is the build environment variable whose value changes
+// when any referenced Secret or ConfigMap value changes, so kpack rebuilds
+// with the new values. Only the referenced keys are covered, other changes to
+// the objects do not trigger a rebuild. The digest is an HMAC-SHA256 of the
+// values keyed by a random key that is only stored in the build env Secret,
+// so the digest cannot be used to guess the values by anyone who is not
+// able to read the Secret.
+const buildEnvDigestName = "RIFF_BUILD_ENV_DIGEST"
+
+// buildEnvDigestKeyName is the key of the build env Secret holding the random
+// key for the build env digest. It may not be used as a build environment
+// variable referencing a Secret or ConfigMap.
+const buildEnvDigestKeyName = "RIFF_BUILD_ENV_DIGEST_KEY"
+
+// buildEnvDigestKeySize is the size in bytes of the key for the build env
+// digest
+const buildEnvDigestKeySize = 32
+
+// buildEnvError reports that a build environment variable references a value
+// that is not available.
+type buildEnvError struct {
+	name    string
+	message string
+}
+
+func (e *buildEnvError) Error() string {
+	return fmt.Sprintf("build env %q %s", e.name, e.message)
+}
+
+// resolveBuildEnv resolves the values of build environment variables that
+// reference a Secret or ConfigMap. The referenced objects are tracked so that
+// changed values are rebuilt. Missing optional references are omitted.
+func resolveBuildEnv(ctx context.Context, c controllers.Config, parent apis.Object, env []corev1.EnvVar) (map[string][]byte, error) {
+	parentKey := types.NamespacedName{Namespace: parent.GetNamespace(), Name: parent.GetName()}
+	values := map[string][]byte{}
+	for _, e := range env {
+		if e.ValueFrom == nil {
+			continue
+		}
+		if e.Name == buildEnvDigestKeyName && (e.ValueFrom.SecretKeyRef != nil || e.ValueFrom.ConfigMapKeyRef != nil) {
+			return nil, &buildEnvError{name: e.Name, message: "is reserved"}
+		}
+		switch {
+		case e.ValueFrom.SecretKeyRef != nil:
+			ref := e.ValueFrom.SecretKeyRef
+			optional := ref.Optional != nil && *ref.Optional
+			key := types.NamespacedName{Namespace: parent.GetNamespace(), Name: ref.Name}
+			// track secret for changed values
+			c.Tracker.Track(tracker.NewKey(schema.GroupVersionKind{Version: "v1", Kind: "Secret"}, key), parentKey)
+			var secret corev1.Secret
+			if err := c.Get(ctx, key, &secret); err != nil {
+				if !apierrs.IsNotFound(err) {
+					return nil, err
+				}
+				if optional {
+					continue
+				}
+				return nil, &buildEnvError{name: e.Name, message: fmt.Sprintf("references Secret %q which is not found", ref.Name)}
+			}
+			value, ok := secret.Data[ref.Key]
+			if !ok {
+				if optional {
+					continue
+				}
+				return nil, &buildEnvError{name: e.Name, message: fmt.Sprintf("references key %q which is not found in Secret %q", ref.Key, ref.Name)}
+			}
+			values[e.Name] = value
+		case e.ValueFrom.ConfigMapKeyRef != nil:
+			ref := e.ValueFrom.ConfigMapKeyRef
+			optional := ref.Optional != nil && *ref.Optional
+			key := types.NamespacedName{Namespace: parent.GetNamespace(), Name: ref.Name}
+			// track configmap for changed values
+			c.Tracker.Track(tracker.NewKey(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, key), parentKey)
+			var configMap corev1.ConfigMap
+			if err := c.Get(ctx, key, &configMap); err != nil {
+				if !apierrs.IsNotFound(err) {
+					return nil, err
+				}
+				if optional {
+					continue
+				}
+				return nil, &buildEnvError{name: e.Name, message: fmt.Sprintf("references ConfigMap %q which is not found", ref.Name)}
+			}
+			if value, ok := configMap.Data[ref.Key]; ok {
+				values[e.Name] = []byte(value)
+			} else if value, ok := configMap.BinaryData[ref.Key]; ok {
+				values[e.Name] = value
+			} else if !optional {
+				return nil, &buildEnvError{name: e.Name, message: fmt.Sprintf("references key %q which is not found in ConfigMap %q", ref.Key, ref.Name)}
+			}
+		}
+	}
+	return values, nil
+}
+
+// buildEnvSecretName is the name of the Secret holding the resolved build
+// environment values of a resource.
+func buildEnvSecretName(parent apis.Object, kind string) string {
+	return fmt.Sprintf("%s-%s-build-env", parent.GetName(), kind)
+}
+
+// resolveBuildEnvDigestKey returns the key for the build env digest held by
+// the build env Secret, or a new random key if the Secret does not hold one.
+func resolveBuildEnvDigestKey(ctx context.Context, c client.Client, parent apis.Object, kind string, random io.Reader) ([]byte, error) {
+	var secret corev1.Secret
+	if err := c.Get(ctx, types.NamespacedName{Namespace: parent.GetNamespace(), Name: buildEnvSecretName(parent, kind)}, &secret); err != nil {
+		if !apierrs.IsNotFound(err) {
+			return nil, err
+		}
+	} else if key := secret.Data[buildEnvDigestKeyName]; metav1.IsControlledBy(&secret, parent) && len(key) == buildEnvDigestKeySize {
+		return key, nil
+	}
+	key := make([]byte, buildEnvDigestKeySize)
+	if _, err := io.ReadFull(random, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// buildEnvSecret is the Secret holding the resolved build environment values
+// and the key for their digest, or nil if no values are referenced.
+func buildEnvSecret(parent apis.Object, kind string, labels map[string]string, values map[string][]byte, digestKey []byte) *corev1.Secret {
+	if len(values) == 0 {
+		return nil
+	}
+	data := map[string][]byte{
+		buildEnvDigestKeyName: digestKey,
+	}
+	for name, value := range values {
+		data[name] = value
+	}
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels:    labels,
+			Name:      buildEnvSecretName(parent, kind),
+			Namespace: parent.GetNamespace(),
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: data,
+	}
+}
+
+// managedBuildEnv rewrites build environment variables that reference a
+// Secret or ConfigMap to reference the resolved values in the build env
+// Secret, so values are never copied into the kpack Image or build Job. The
+// digest of the values is keyed by the digest key from the build env Secret.
+func managedBuildEnv(env []corev1.EnvVar, secretName string, values map[string][]byte, digestKey []byte) []corev1.EnvVar {
+	referenced := false
+	managedEnv := []corev1.EnvVar{}
+	for _, e := range env {
+		if e.ValueFrom == nil || (e.ValueFrom.SecretKeyRef == nil && e.ValueFrom.ConfigMapKeyRef == nil) {
+			managedEnv = append(managedEnv, e)
+			continue
+		}
+		referenced = true
+		if _, ok := values[e.Name]; !ok {
+			// missing optional value
+			continue
+		}
+		managedEnv = append(managedEnv, corev1.EnvVar{
+			Name: e.Name,
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: secretName},
+					Key:                  e.Name,
+				},
+			},
+		})
+	}
+	if !referenced {
+		return env
+	}
+	digest := hmac.New(sha256.New, digestKey)
+	for _, name := range sets.StringKeySet(values).List() {
+		digest.Write([]byte(name))
+		digest.Write([]byte{0})
+		digest.Write(values[name])
+		digest.Write([]byte{0})
+	}
+	return append(managedEnv, corev1.EnvVar{
+		Name:  buildEnvDigestName,
+		Value: fmt.Sprintf("hmac-sha256:%x", digest.Sum(nil)),
+	})
+}
+
+// checkSecretControlled returns an error if the named Secret exists but is not
+// controlled by the parent. A Secret that is not found is likely not yet in
+// the informer cache.
+func checkSecretControlled(ctx context.Context, c client.Client, parent apis.Object, name string) error {
+	var secret corev1.Secret
+	if err := c.Get(ctx, types.NamespacedName{Namespace: parent.GetNamespace(), Name: name}, &secret); err != nil {
+		if apierrs.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if !metav1.IsControlledBy(&secret, parent) {
+		return fmt.Errorf("secret %q is not controlled by %q", name, parent.GetName())
+	}
+	return nil
+}
+
+// enqueueByLabel enqueues the resource named by the value of the label, in the
+// same namespace as the watched resource.
+func enqueueByLabel(key string) handler.EventHandler {
+	return &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(func(a handler.MapObject) []reconcile.Request {
+			name, ok := a.Meta.GetLabels()[key]
+			if !ok {
+				return []reconcile.Request{}
+			}
+			return []reconcile.Request{
+				{
+					NamespacedName: types.NamespacedName{
+						Namespace: a.Meta.GetNamespace(),
+						Name:      name,
+					},
+				},
+			}
+		}),
+	}
+}
+
+// normalizeRegistry converts the registry from a credential's annotation into
+// the canonical registry name used by go-containerregistry.
+func normalizeRegistry(registry string) string {
+	registry = strings.TrimPrefix(registry, "http://")
+	registry = strings.TrimPrefix(registry, "https://")
+	if i := strings.Index(registry, "/"); i != -1 {
+		registry = registry[:i]
+	}
+	if reg, err := name.NewRegistry(registry, name.WeakValidation); err == nil {
+		return reg.RegistryStr()
+	}
+	return registry
+}
@@ -18,20 +18,14 @@ package build
 
 import (
 	"context"
-	"fmt"
-	"strings"
-	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/google/go-cmp/cmp"
-	gauthn "github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
-	"github.com/google/go-containerregistry/pkg/v1/remote"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	apierrs "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -39,19 +33,17 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	buildv1alpha1 "github.com/projectriff/system/pkg/apis/build/v1alpha1"
-	"github.com/projectriff/system/pkg/authn"
 )
 
 // ContainerReconciler reconciles a Container object
 type ContainerReconciler struct {
 	client.Client
-	Recorder record.EventRecorder
-	Log      logr.Logger
-	Scheme   *runtime.Scheme
+	Recorder  record.EventRecorder
+	Log       logr.Logger
+	Scheme    *runtime.Scheme
+	Namespace string
 }
 
-var containerPollingInterval = 1 * time.Minute
-
 // +kubebuilder:rbac:groups=build.projectriff.io,resources=containers,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=build.projectriff.io,resources=containers/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch
@@ -116,7 +108,21 @@ func (r *ContainerReconciler) reconcile(ctx context.Context, log logr.Logger, co
 	}
 	container.Status.TargetImage = targetImageRef.Name()
 
-	latestImage, err := r.resolveDigestReference(ctx, log, targetImageRef, container)
+	health, err := resolveCredentialHealth(ctx, r.Client, container.Namespace, container.Spec.ServiceAccountName, container.Status.TargetImage)
+	if err != nil {
+		log.Error(err, "unable to resolve credential health")
+		return ctrl.Result{}, err
+	}
+	switch {
+	case !health.Bound():
+		container.Status.MarkCredentialsNotBound()
+	case !health.Working():
+		container.Status.MarkCredentialsInvalid(health.Message)
+	default:
+		container.Status.MarkCredentialsReady()
+	}
+
+	resolved, err := resolveImage(ctx, r.Client, log, container.Namespace, container.Spec.ServiceAccountName, targetImageRef, container.Spec.Platform)
 	if err != nil {
 		container.Status.MarkImageInvalid(err.Error())
 		return ctrl.Result{}, err
@@ -124,23 +130,20 @@ func (r *ContainerReconciler) reconcile(ctx context.Context, log logr.Logger, co
 
 	container.Status.MarkImageResolved()
 
-	container.Status.LatestImage = latestImage
+	container.Status.LatestImage = resolved.Image
+	container.Status.Platforms = resolved.Platforms
 
 	container.Status.ObservedGeneration = container.Generation
 
 	return ctrl.Result{
-		RequeueAfter: containerPollingInterval,
+		RequeueAfter: imagePollingInterval,
 	}, nil
 }
 
 func (r *ContainerReconciler) resolveTargetImage(ctx context.Context, log logr.Logger, container *buildv1alpha1.Container) (name.Reference, error) {
-	image := container.Spec.Image
-	var err error
-	if strings.HasPrefix(container.Spec.Image, "_") {
-		image, err = r.interpolatePrefix(ctx, log, container)
-		if err != nil {
-			return nil, err
-		}
+	image, err := resolveTargetImage(ctx, r.Client, r.Namespace, container)
+	if err != nil {
+		return nil, err
 	}
 
 	ref, err := name.ParseReference(image)
@@ -151,89 +154,6 @@ func (r *ContainerReconciler) resolveTargetImage(ctx context.Context, log logr.L
 	return ref, nil
 }
 
-func (r *ContainerReconciler) interpolatePrefix(ctx context.Context, log logr.Logger, container *buildv1alpha1.Container) (string, error) {
-	var riffBuildConfig corev1.ConfigMap
-	if err := r.Get(ctx, types.NamespacedName{Namespace: container.Namespace, Name: riffBuildServiceAccount}, &riffBuildConfig); err != nil {
-		if apierrs.IsNotFound(err) {
-			return "", errMissingDefaultPrefix
-		}
-		return "", err
-	}
-	defaultPrefix := riffBuildConfig.Data["default-image-prefix"]
-	if defaultPrefix == "" {
-		return "", errMissingDefaultPrefix
-	}
-	image, err := buildv1alpha1.ResolveDefaultImage(container, defaultPrefix)
-	if err != nil {
-		return "", err
-	}
-	return image, nil
-}
-
-func (r *ContainerReconciler) resolveDigestReference(ctx context.Context, log logr.Logger, ref name.Reference, container *buildv1alpha1.Container) (string, error) {
-	keychain, err := r.constructKeychain(ctx, log, container)
-	if err != nil {
-		return "", err
-	}
-
-	auth, err := keychain.Resolve(ref.Context().Registry)
-	if err != nil {
-		log.Error(err, "unable to resolve auth for registry", "registry", ref.Context().RegistryStr())
-		return "", err
-	}
-
-	img, err := remote.Image(ref, remote.WithAuth(auth))
-	if err != nil {
-		log.Error(err, "failed to read image", "image", ref.String())
-		return "", err
-	}
-
-	digest, err := img.Digest()
-	if err != nil {
-		log.Error(err, "failed to get image digest", "image", ref.String())
-		return "", err
-	}
-
-	return fmt.Sprintf("%s@%s", ref.Context().Name(), digest), nil
-}
-
-func (r *ContainerReconciler) constructKeychain(ctx context.Context, log logr.Logger, container *buildv1alpha1.Container) (gauthn.Keychain, error) {
-	var serviceAccount corev1.ServiceAccount
-	if err := r.Get(ctx, types.NamespacedName{Namespace: container.Namespace, Name: riffBuildServiceAccount}, &serviceAccount); err != nil {
-		if apierrs.IsNotFound(err) {
-			log.Info("service account not found", "service-account", riffBuildServiceAccount)
-			return nil, err
-		} else {
-			log.Error(err, "failed to get service account", "service-account", riffBuildServiceAccount)
-			return nil, err
-		}
-	}
-	secrets, err := r.fetchSecrets(serviceAccount, ctx, log)
-	if err != nil {
-		return nil, err
-	}
-
-	return gauthn.NewMultiKeychain(authn.NewSecretsKeychain(secrets), gauthn.DefaultKeychain), nil
-}
-
-func (r *ContainerReconciler) fetchSecrets(serviceAccount corev1.ServiceAccount, ctx context.Context, log logr.Logger) ([]corev1.Secret, error) {
-	var secrets []corev1.Secret
-	for _, secretRef := range serviceAccount.Secrets {
-		var secret corev1.Secret
-		if err := r.Get(ctx, types.NamespacedName{Namespace: serviceAccount.Namespace, Name: secretRef.Name}, &secret); err != nil {
-			if apierrs.IsNotFound(err) {
-				log.Info("secret not found", "secret", secretRef.Name)
-				continue
-			} else {
-				log.Error(err, "failed to get secret", "secret", secretRef.Name)
-				return nil, err
-			}
-		}
-		secrets = append(secrets, secret)
-	}
-	return secrets, nil
-}
-
 func (r *ContainerReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&buildv1alpha1.Container{}).
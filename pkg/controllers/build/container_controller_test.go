@@ -17,14 +17,18 @@ limitations under the License.
 package build_test
 
 import (
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/go-logr/logr"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/tools/record"
+	controllerruntime "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
@@ -40,8 +44,24 @@ func TestContainerReconciler(t *testing.T) {
 	testNamespace := "test-namespace"
 	testName := "test-container"
 	testKey := types.NamespacedName{Namespace: testNamespace, Name: testName}
-	testImagePrefix := "example.com/repo"
-	testSha256 := "cf8b4c69d5460f88530e1c80b8856a70801f31c50b191c8413043ba9b160a43e"
+	testSystemNamespace := "system-namespace"
+
+	testRegistry := rtesting.NewRegistry()
+	defer testRegistry.Close()
+	testImagePrefix := testRegistry.Repository("repo")
+	testDigest := testRegistry.PushRandomImage(t, fmt.Sprintf("%s/%s", testImagePrefix, testName))
+	testTemplateDigest := testRegistry.PushRandomImage(t, fmt.Sprintf("%s/%s/container-%s", testImagePrefix, testNamespace, testName))
+	testIndexDigest, testPlatformDigests := testRegistry.PushImageIndex(t, fmt.Sprintf("%s/%s:multi-arch", testImagePrefix, testName),
+		v1.Platform{OS: "linux", Architecture: "amd64"},
+		v1.Platform{OS: "linux", Architecture: "arm", Variant: "v7"},
+	)
+	testPlatforms := []buildv1alpha1.PlatformImage{{
+		ImagePlatform: buildv1alpha1.ImagePlatform{OS: "linux", Architecture: "amd64"},
+		Image:         fmt.Sprintf("%s/%s@%s", testImagePrefix, testName, testPlatformDigests[0]),
+	}, {
+		ImagePlatform: buildv1alpha1.ImagePlatform{OS: "linux", Architecture: "arm", Variant: "v7"},
+		Image:         fmt.Sprintf("%s/%s@%s", testImagePrefix, testName, testPlatformDigests[1]),
+	}}
 
 	containerConditionImageResolved := factories.Condition().Type(buildv1alpha1.ContainerConditionImageResolved)
 	containerConditionReady := factories.Condition().Type(buildv1alpha1.ContainerConditionReady)
@@ -59,6 +79,8 @@ func TestContainerReconciler(t *testing.T) {
 	cmImagePrefix := factories.ConfigMap().
 		NamespaceName(testNamespace, "riff-build").
 		AddData("default-image-prefix", "")
+	cmClusterImagePrefix := factories.ConfigMap().
+		NamespaceName(testSystemNamespace, "riff-build")
 
 	serviceAccount := factories.ServiceAccount().
 		NamespaceName(testNamespace, "riff-build")
@@ -76,8 +98,6 @@ func TestContainerReconciler(t *testing.T) {
 				}),
 		},
 	}, {
-		// TODO mock image digest resolution
-		Skip: true,
 		Name: "resolve images digest",
 		Key:  testKey,
 		GivenObjects: []rtesting.Factory{
@@ -92,10 +112,105 @@ func TestContainerReconciler(t *testing.T) {
 			containerMinimal.
 				StatusConditions(
 					containerConditionImageResolved.True(),
-					containerConditionReady.Unknown(),
+					containerConditionReady.True(),
+				).
+				StatusTargetImage("%s/%s:latest", testImagePrefix, testName).
+				StatusLatestImage("%s/%s@%s", testImagePrefix, testName, testDigest),
+		},
+		ExpectedResult: controllerruntime.Result{RequeueAfter: 1 * time.Minute},
+	}, {
+		Name: "resolve images digest, registry rewrite",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			containerMinimal.
+				Image("gcr.io/example/%s", testName),
+			serviceAccount,
+			factories.ConfigMap().
+				NamespaceName(testSystemNamespace, "riff-registry-rewrites").
+				AddData("rewrites", fmt.Sprintf("gcr.io/example -> %s", testImagePrefix)),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(containerValid, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			containerMinimal.
+				StatusConditions(
+					containerConditionImageResolved.True(),
+					containerConditionReady.True(),
+				).
+				StatusTargetImage("%s/%s:latest", testImagePrefix, testName).
+				StatusLatestImage("%s/%s@%s", testImagePrefix, testName, testDigest),
+		},
+		ExpectedResult: controllerruntime.Result{RequeueAfter: 1 * time.Minute},
+	}, {
+		Name: "resolve image index digest",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			containerMinimal.
+				Image("%s/%s:multi-arch", testImagePrefix, testName),
+			serviceAccount,
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(containerValid, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			containerMinimal.
+				StatusConditions(
+					containerConditionImageResolved.True(),
+					containerConditionReady.True(),
+				).
+				StatusTargetImage("%s/%s:multi-arch", testImagePrefix, testName).
+				StatusLatestImage("%s/%s@%s", testImagePrefix, testName, testIndexDigest).
+				StatusPlatforms(testPlatforms...),
+		},
+		ExpectedResult: controllerruntime.Result{RequeueAfter: 1 * time.Minute},
+	}, {
+		Name: "resolve image index digest, platform",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			containerMinimal.
+				Image("%s/%s:multi-arch", testImagePrefix, testName).
+				Platform("linux", "arm", "v7"),
+			serviceAccount,
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(containerValid, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			containerMinimal.
+				StatusConditions(
+					containerConditionImageResolved.True(),
+					containerConditionReady.True(),
+				).
+				StatusTargetImage("%s/%s:multi-arch", testImagePrefix, testName).
+				StatusLatestImage("%s/%s@%s", testImagePrefix, testName, testPlatformDigests[1]).
+				StatusPlatforms(testPlatforms...),
+		},
+		ExpectedResult: controllerruntime.Result{RequeueAfter: 1 * time.Minute},
+	}, {
+		Name: "resolve image index digest, platform not found",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			containerMinimal.
+				Image("%s/%s:multi-arch", testImagePrefix, testName).
+				Platform("linux", "arm64", ""),
+			serviceAccount,
+		},
+		ShouldErr: true,
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(containerValid, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			containerMinimal.
+				StatusConditions(
+					containerConditionImageResolved.False().Reason("ImageInvalid", fmt.Sprintf(`image index "%s/%s:multi-arch" has no image for platform "linux/arm64"`, testImagePrefix, testName)),
+					containerConditionReady.False().Reason("ImageInvalid", fmt.Sprintf(`image index "%s/%s:multi-arch" has no image for platform "linux/arm64"`, testImagePrefix, testName)),
 				).
-				StatusTargetImage("%s/%s", testImagePrefix, testName).
-				StatusLatestImage("%s/%s@sha256:%s", testImagePrefix, testName, testSha256),
+				StatusTargetImage("%s/%s:multi-arch", testImagePrefix, testName),
 		},
 	}, {
 		Name: "container get error",
@@ -105,8 +220,6 @@ func TestContainerReconciler(t *testing.T) {
 		},
 		ShouldErr: true,
 	}, {
-		// TODO mock image digest resolution
-		Skip: true,
 		Name: "default image",
 		Key:  testKey,
 		GivenObjects: []rtesting.Factory{
@@ -123,10 +236,60 @@ func TestContainerReconciler(t *testing.T) {
 			containerMinimal.
 				StatusConditions(
 					containerConditionImageResolved.True(),
-					containerConditionReady.Unknown(),
+					containerConditionReady.True(),
+				).
+				StatusTargetImage("%s/%s:latest", testImagePrefix, testName).
+				StatusLatestImage("%s/%s@%s", testImagePrefix, testName, testDigest),
+		},
+		ExpectedResult: controllerruntime.Result{RequeueAfter: 1 * time.Minute},
+	}, {
+		Name: "default image, cluster default",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			cmClusterImagePrefix.
+				AddData("default-image-prefix", testImagePrefix),
+			containerMinimal,
+			serviceAccount,
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(containerValid, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			containerMinimal.
+				StatusConditions(
+					containerConditionImageResolved.True(),
+					containerConditionReady.True(),
+				).
+				StatusTargetImage("%s/%s:latest", testImagePrefix, testName).
+				StatusLatestImage("%s/%s@%s", testImagePrefix, testName, testDigest),
+		},
+		ExpectedResult: controllerruntime.Result{RequeueAfter: 1 * time.Minute},
+	}, {
+		Name: "default image, template",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			cmImagePrefix.
+				AddData("default-image-prefix", testImagePrefix),
+			cmClusterImagePrefix.
+				AddData("default-image-template", "{{.Registry}}/{{.Namespace}}/{{.Kind}}-{{.Name}}"),
+			containerMinimal,
+			serviceAccount,
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(containerValid, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			containerMinimal.
+				StatusConditions(
+					containerConditionImageResolved.True(),
+					containerConditionReady.True(),
 				).
-				StatusTargetImage("%s/%s", testImagePrefix, testName),
+				StatusTargetImage("%s/%s/container-%s:latest", testImagePrefix, testNamespace, testName).
+				StatusLatestImage("%s/%s/container-%s@%s", testImagePrefix, testNamespace, testName, testTemplateDigest),
 		},
+		ExpectedResult: controllerruntime.Result{RequeueAfter: 1 * time.Minute},
 	}, {
 		Name: "default image, missing",
 		Key:  testKey,
@@ -187,8 +350,6 @@ func TestContainerReconciler(t *testing.T) {
 				),
 		},
 	}, {
-		// TODO mock image digest resolution
-		Skip: true,
 		Name: "container status update error",
 		Key:  testKey,
 		WithReactors: []rtesting.ReactionFunc{
@@ -200,25 +361,27 @@ func TestContainerReconciler(t *testing.T) {
 		},
 		ShouldErr: true,
 		ExpectEvents: []rtesting.Event{
-			rtesting.NewEvent(containerValid, scheme, corev1.EventTypeNormal, "StatusUpdated",
-				`Updated status`),
+			rtesting.NewEvent(containerValid, scheme, corev1.EventTypeWarning, "StatusUpdateFailed",
+				`Failed to update status: inducing failure for update Container`),
 		},
 		ExpectStatusUpdates: []rtesting.Factory{
 			containerMinimal.
 				StatusConditions(
 					containerConditionImageResolved.True(),
-					containerConditionReady.Unknown(),
+					containerConditionReady.True(),
 				).
-				StatusTargetImage("%s/%s", testImagePrefix, testName),
+				StatusTargetImage("%s/%s:latest", testImagePrefix, testName).
+				StatusLatestImage("%s/%s@%s", testImagePrefix, testName, testDigest),
 		},
 	}}
 
 	table.Test(t, scheme, func(t *testing.T, row *rtesting.Testcase, client client.Client, apiReader client.Reader, tracker tracker.Tracker, recorder record.EventRecorder, log logr.Logger) reconcile.Reconciler {
 		return &build.ContainerReconciler{
-			Client:   client,
-			Recorder: recorder,
-			Scheme:   scheme,
-			Log:      log,
+			Client:    client,
+			Recorder:  recorder,
+			Scheme:    scheme,
+			Log:       log,
+			Namespace: testSystemNamespace,
 		}
 	})
 }
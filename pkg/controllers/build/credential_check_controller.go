@@ -0,0 +1,198 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package build
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	gauthn "github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	buildv1alpha1 "github.com/projectriff/system/pkg/apis/build/v1alpha1"
+	"github.com/projectriff/system/pkg/authn"
+)
+
+var credentialCheckInterval = 1 * time.Hour
+
+// CredentialCheckReconciler verifies that each credential Secret is able to
+// authenticate with its registry. Changes to the result of the check are
+// recorded as annotations and events on the Secret.
+type CredentialCheckReconciler struct {
+	client.Client
+	Recorder record.EventRecorder
+	Log      logr.Logger
+
+	// VerifyRegistryAuth performs the registry auth handshake. Defaults to
+	// authn.VerifyRegistryAuth.
+	//
+	// +optional
+	VerifyRegistryAuth func(registry name.Registry, auth gauthn.Authenticator) error
+	// Now returns the current time. Defaults to time.Now.
+	//
+	// +optional
+	Now func() time.Time
+}
+
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=core,resources=events,verbs=get;list;watch;create;update;patch;delete
+
+func (r *CredentialCheckReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx := context.Background()
+	log := r.Log.WithValues("secret", req.NamespacedName)
+
+	var originalSecret corev1.Secret
+	if err := r.Get(ctx, req.NamespacedName, &originalSecret); err != nil {
+		if apierrs.IsNotFound(err) {
+			// we'll ignore not-found errors, since they can't be fixed by an immediate
+			// requeue (we'll need to wait for a new notification), and we can get them
+			// on deleted requests.
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "unable to fetch Secret")
+		return ctrl.Result{}, err
+	}
+	if originalSecret.GetDeletionTimestamp() != nil {
+		return ctrl.Result{}, nil
+	}
+	if _, ok := originalSecret.Labels[buildv1alpha1.CredentialLabelKey]; !ok {
+		// ignore other secrets, should never get here
+		return ctrl.Result{}, nil
+	}
+
+	// Don't modify the informers copy
+	secret := originalSecret.DeepCopy()
+
+	return r.reconcile(ctx, log, secret)
+}
+
+func (r *CredentialCheckReconciler) reconcile(ctx context.Context, log logr.Logger, secret *corev1.Secret) (ctrl.Result, error) {
+	checkErr := r.check(secret)
+	registry := secret.Annotations[authn.DockerSecretAnnotation]
+
+	_, checked := secret.Annotations[buildv1alpha1.CredentialVerifiedAnnotationKey]
+	previousErr, failed := secret.Annotations[buildv1alpha1.CredentialErrorAnnotationKey]
+	if checked && failed == (checkErr != nil) && (checkErr == nil || previousErr == checkErr.Error()) {
+		// the result is unchanged
+		return ctrl.Result{RequeueAfter: credentialCheckInterval}, nil
+	}
+
+	original := secret.DeepCopy()
+	if secret.Annotations == nil {
+		secret.Annotations = map[string]string{}
+	}
+	secret.Annotations[buildv1alpha1.CredentialVerifiedAnnotationKey] = r.now().UTC().Format(time.RFC3339)
+	if checkErr != nil {
+		secret.Annotations[buildv1alpha1.CredentialErrorAnnotationKey] = checkErr.Error()
+		r.Recorder.Eventf(secret, corev1.EventTypeWarning, "CredentialInvalid",
+			"Credential failed verification for registry %q: %v", registry, checkErr)
+	} else {
+		delete(secret.Annotations, buildv1alpha1.CredentialErrorAnnotationKey)
+		r.Recorder.Eventf(secret, corev1.EventTypeNormal, "CredentialVerified",
+			"Credential verified for registry %q", registry)
+	}
+
+	log.Info("updating credential verification", "error", secret.Annotations[buildv1alpha1.CredentialErrorAnnotationKey])
+	if err := r.Patch(ctx, secret, client.MergeFrom(original)); err != nil {
+		log.Error(err, "unable to patch Secret")
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{RequeueAfter: credentialCheckInterval}, nil
+}
+
+func (r *CredentialCheckReconciler) check(secret *corev1.Secret) error {
+	server := secret.Annotations[authn.DockerSecretAnnotation]
+	if server == "" {
+		return fmt.Errorf("missing %q annotation", authn.DockerSecretAnnotation)
+	}
+	if secret.Type != corev1.SecretTypeBasicAuth {
+		return fmt.Errorf("unsupported secret type %q, expected %q", secret.Type, corev1.SecretTypeBasicAuth)
+	}
+	registry, err := name.NewRegistry(normalizeRegistry(server), name.WeakValidation)
+	if err != nil {
+		return err
+	}
+	auth, err := authn.NewSecretsKeychain([]corev1.Secret{*secret}).Resolve(registry)
+	if err != nil {
+		return err
+	}
+	verify := r.VerifyRegistryAuth
+	if verify == nil {
+		verify = func(registry name.Registry, auth gauthn.Authenticator) error {
+			return authn.VerifyRegistryAuth(registry, auth, nil)
+		}
+	}
+	return verify(registry, auth)
+}
+
+func (r *CredentialCheckReconciler) now() time.Time {
+	if r.Now == nil {
+		return time.Now()
+	}
+	return r.Now()
+}
+
+func isCredential(obj interface{}) bool {
+	secret, ok := obj.(*corev1.Secret)
+	if !ok {
+		return false
+	}
+	_, ok = secret.Labels[buildv1alpha1.CredentialLabelKey]
+	return ok
+}
+
+func (r *CredentialCheckReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&corev1.Secret{}).
+		WithEventFilter(predicate.Funcs{
+			CreateFunc: func(e event.CreateEvent) bool {
+				// filter secrets to only be credentials
+				return isCredential(e.Object)
+			},
+			UpdateFunc: func(e event.UpdateEvent) bool {
+				if !isCredential(e.ObjectNew) {
+					return false
+				}
+				if !isCredential(e.ObjectOld) {
+					// newly labeled as a credential
+					return true
+				}
+				// ignore updates that only record the verification result
+				oldSecret := e.ObjectOld.(*corev1.Secret)
+				newSecret := e.ObjectNew.(*corev1.Secret)
+				return !equality.Semantic.DeepEqual(oldSecret.Data, newSecret.Data) ||
+					oldSecret.Type != newSecret.Type ||
+					oldSecret.Annotations[authn.DockerSecretAnnotation] != newSecret.Annotations[authn.DockerSecretAnnotation]
+			},
+			DeleteFunc: func(e event.DeleteEvent) bool {
+				// nothing to verify
+				return false
+			},
+		}).
+		Complete(r)
+}
@@ -0,0 +1,254 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package build_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	gauthn "github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	buildv1alpha1 "github.com/projectriff/system/pkg/apis/build/v1alpha1"
+	"github.com/projectriff/system/pkg/controllers/build"
+	rtesting "github.com/projectriff/system/pkg/controllers/testing"
+	"github.com/projectriff/system/pkg/controllers/testing/factories"
+	"github.com/projectriff/system/pkg/tracker"
+)
+
+func TestCredentialCheckReconciler(t *testing.T) {
+	testNamespace := "test-namespace"
+	testName := "my-credential"
+	testKey := types.NamespacedName{Namespace: testNamespace, Name: testName}
+	testRegistry := "registry.example.com"
+	testNow := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = buildv1alpha1.AddToScheme(scheme)
+
+	testCredential := factories.Secret().
+		NamespaceName(testNamespace, testName).
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.AddLabel(buildv1alpha1.CredentialLabelKey, "basic-auth")
+			om.AddAnnotation("build.pivotal.io/docker", testRegistry)
+		}).
+		Type(corev1.SecretTypeBasicAuth).
+		AddData("username", "user").
+		AddData("password", "pass")
+
+	verifyRegistryAuth := func(registry name.Registry, auth gauthn.Authenticator) error {
+		if registry.RegistryStr() != testRegistry {
+			return fmt.Errorf("unexpected registry %q", registry.RegistryStr())
+		}
+		if basic, ok := auth.(*gauthn.Basic); !ok || basic.Username != "user" || basic.Password != "pass" {
+			return fmt.Errorf("UNAUTHORIZED")
+		}
+		return nil
+	}
+
+	table := rtesting.Table{{
+		Name: "secret does not exist",
+		Key:  testKey,
+	}, {
+		Name: "ignore deleted secret",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			testCredential.
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.Deleted(1)
+				}),
+		},
+	}, {
+		Name: "ignore non-credential secrets",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			factories.Secret().
+				NamespaceName(testNamespace, testName),
+		},
+	}, {
+		Name: "error fetching secret",
+		Key:  testKey,
+		WithReactors: []rtesting.ReactionFunc{
+			rtesting.InduceFailure("get", "Secret"),
+		},
+		GivenObjects: []rtesting.Factory{
+			testCredential,
+		},
+		ShouldErr: true,
+	}, {
+		Name: "credential verified",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			testCredential,
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(testCredential, scheme, corev1.EventTypeNormal, "CredentialVerified",
+				`Credential verified for registry "%s"`, testRegistry),
+		},
+		ExpectPatches: []rtesting.PatchRef{
+			{Kind: "Secret", Namespace: testNamespace, Name: testName, PatchType: types.MergePatchType, Patch: `{"metadata":{"annotations":{"build.projectriff.io/credential-verified":"2020-01-02T03:04:05Z"}}}`},
+		},
+		ExpectedResult: controllerruntime.Result{RequeueAfter: 1 * time.Hour},
+	}, {
+		Name: "credential verified, unchanged",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			testCredential.
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.AddAnnotation(buildv1alpha1.CredentialVerifiedAnnotationKey, "2020-01-01T00:00:00Z")
+				}),
+		},
+		ExpectedResult: controllerruntime.Result{RequeueAfter: 1 * time.Hour},
+	}, {
+		Name: "credential verified, clearing previous error",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			testCredential.
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.AddAnnotation(buildv1alpha1.CredentialVerifiedAnnotationKey, "2020-01-01T00:00:00Z")
+					om.AddAnnotation(buildv1alpha1.CredentialErrorAnnotationKey, "UNAUTHORIZED")
+				}),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(testCredential, scheme, corev1.EventTypeNormal, "CredentialVerified",
+				`Credential verified for registry "%s"`, testRegistry),
+		},
+		ExpectPatches: []rtesting.PatchRef{
+			{Kind: "Secret", Namespace: testNamespace, Name: testName, PatchType: types.MergePatchType, Patch: `{"metadata":{"annotations":{"build.projectriff.io/credential-error":null,"build.projectriff.io/credential-verified":"2020-01-02T03:04:05Z"}}}`},
+		},
+		ExpectedResult: controllerruntime.Result{RequeueAfter: 1 * time.Hour},
+	}, {
+		Name: "credential rejected",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			testCredential.
+				AddData("password", "wrong"),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(testCredential, scheme, corev1.EventTypeWarning, "CredentialInvalid",
+				`Credential failed verification for registry "%s": UNAUTHORIZED`, testRegistry),
+		},
+		ExpectPatches: []rtesting.PatchRef{
+			{Kind: "Secret", Namespace: testNamespace, Name: testName, PatchType: types.MergePatchType, Patch: `{"metadata":{"annotations":{"build.projectriff.io/credential-error":"UNAUTHORIZED","build.projectriff.io/credential-verified":"2020-01-02T03:04:05Z"}}}`},
+		},
+		ExpectedResult: controllerruntime.Result{RequeueAfter: 1 * time.Hour},
+	}, {
+		Name: "credential rejected, unchanged",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			testCredential.
+				AddData("password", "wrong").
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.AddAnnotation(buildv1alpha1.CredentialVerifiedAnnotationKey, "2020-01-01T00:00:00Z")
+					om.AddAnnotation(buildv1alpha1.CredentialErrorAnnotationKey, "UNAUTHORIZED")
+				}),
+		},
+		ExpectedResult: controllerruntime.Result{RequeueAfter: 1 * time.Hour},
+	}, {
+		Name: "credential rejected, previously verified",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			testCredential.
+				AddData("password", "wrong").
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.AddAnnotation(buildv1alpha1.CredentialVerifiedAnnotationKey, "2020-01-01T00:00:00Z")
+				}),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(testCredential, scheme, corev1.EventTypeWarning, "CredentialInvalid",
+				`Credential failed verification for registry "%s": UNAUTHORIZED`, testRegistry),
+		},
+		ExpectPatches: []rtesting.PatchRef{
+			{Kind: "Secret", Namespace: testNamespace, Name: testName, PatchType: types.MergePatchType, Patch: `{"metadata":{"annotations":{"build.projectriff.io/credential-error":"UNAUTHORIZED","build.projectriff.io/credential-verified":"2020-01-02T03:04:05Z"}}}`},
+		},
+		ExpectedResult: controllerruntime.Result{RequeueAfter: 1 * time.Hour},
+	}, {
+		Name: "credential missing registry",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			factories.Secret().
+				NamespaceName(testNamespace, testName).
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.AddLabel(buildv1alpha1.CredentialLabelKey, "basic-auth")
+				}).
+				Type(corev1.SecretTypeBasicAuth),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(testCredential, scheme, corev1.EventTypeWarning, "CredentialInvalid",
+				`Credential failed verification for registry "": missing "build.pivotal.io/docker" annotation`),
+		},
+		ExpectPatches: []rtesting.PatchRef{
+			{Kind: "Secret", Namespace: testNamespace, Name: testName, PatchType: types.MergePatchType, Patch: `{"metadata":{"annotations":{"build.projectriff.io/credential-error":"missing \"build.pivotal.io/docker\" annotation","build.projectriff.io/credential-verified":"2020-01-02T03:04:05Z"}}}`},
+		},
+		ExpectedResult: controllerruntime.Result{RequeueAfter: 1 * time.Hour},
+	}, {
+		Name: "credential unsupported type",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			testCredential.
+				Type(corev1.SecretTypeOpaque),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(testCredential, scheme, corev1.EventTypeWarning, "CredentialInvalid",
+				`Credential failed verification for registry "%s": unsupported secret type "Opaque", expected "kubernetes.io/basic-auth"`, testRegistry),
+		},
+		ExpectPatches: []rtesting.PatchRef{
+			{Kind: "Secret", Namespace: testNamespace, Name: testName, PatchType: types.MergePatchType, Patch: `{"metadata":{"annotations":{"build.projectriff.io/credential-error":"unsupported secret type \"Opaque\", expected \"kubernetes.io/basic-auth\"","build.projectriff.io/credential-verified":"2020-01-02T03:04:05Z"}}}`},
+		},
+		ExpectedResult: controllerruntime.Result{RequeueAfter: 1 * time.Hour},
+	}, {
+		Name: "credential verified, patch error",
+		Key:  testKey,
+		WithReactors: []rtesting.ReactionFunc{
+			rtesting.InduceFailure("patch", "Secret"),
+		},
+		GivenObjects: []rtesting.Factory{
+			testCredential,
+		},
+		ShouldErr: true,
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(testCredential, scheme, corev1.EventTypeNormal, "CredentialVerified",
+				`Credential verified for registry "%s"`, testRegistry),
+		},
+		ExpectPatches: []rtesting.PatchRef{
+			{Kind: "Secret", Namespace: testNamespace, Name: testName, PatchType: types.MergePatchType, Patch: `{"metadata":{"annotations":{"build.projectriff.io/credential-verified":"2020-01-02T03:04:05Z"}}}`},
+		},
+	}}
+
+	table.Test(t, scheme, func(t *testing.T, row *rtesting.Testcase, client client.Client, apiReader client.Reader, tracker tracker.Tracker, recorder record.EventRecorder, log logr.Logger) reconcile.Reconciler {
+		return &build.CredentialCheckReconciler{
+			Client:             client,
+			Recorder:           recorder,
+			Log:                log,
+			VerifyRegistryAuth: verifyRegistryAuth,
+			Now: func() time.Time {
+				return testNow
+			},
+		}
+	})
+}
@@ -18,6 +18,7 @@ package build
 
 import (
 	"context"
+	"fmt"
 	"strings"
 
 	"github.com/go-logr/logr"
@@ -41,7 +42,9 @@ import (
 	buildv1alpha1 "github.com/projectriff/system/pkg/apis/build/v1alpha1"
 )
 
-// CredentialReconciler reconciles a Credential object
+// CredentialReconciler binds credentials to the default build service account
+// and to any service account that opts in with the credential selector
+// annotation.
 type CredentialReconciler struct {
 	client.Client
 	Recorder record.EventRecorder
@@ -57,24 +60,25 @@ func (r *CredentialReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error)
 	ctx := context.Background()
 	log := r.Log.WithValues("serviceaccount", req.NamespacedName)
 
-	if req.Name != riffBuildServiceAccount {
-		// ignore other service accounts, should never get here
-		return ctrl.Result{}, nil
-	}
-
-	var originalSerivceAccount corev1.ServiceAccount
-	if err := r.Get(ctx, req.NamespacedName, &originalSerivceAccount); err != nil && !apierrs.IsNotFound(err) {
+	var originalServiceAccount corev1.ServiceAccount
+	if err := r.Get(ctx, req.NamespacedName, &originalServiceAccount); err != nil && !apierrs.IsNotFound(err) {
 		log.Error(err, "unable to fetch ServiceAccount")
 		return ctrl.Result{}, err
 	}
 
-	// Don't modify the informers copy
-	var serviceAccount corev1.ServiceAccount
-	if &originalSerivceAccount != nil {
-		serviceAccount = *(originalSerivceAccount.DeepCopy())
+	if req.Name != riffBuildServiceAccount && !isManagedServiceAccount(&originalServiceAccount) {
+		if _, ok := originalServiceAccount.Annotations[buildv1alpha1.CredentialsAnnotationKey]; ok {
+			// the service account opted out, unbind previously bound credentials
+			return ctrl.Result{}, r.unbindServiceAccount(ctx, log, &originalServiceAccount)
+		}
+		// ignore other service accounts
+		return ctrl.Result{}, nil
 	}
 
-	return r.reconcile(ctx, log, &serviceAccount, req.Namespace)
+	// Don't modify the informers copy
+	serviceAccount := originalServiceAccount.DeepCopy()
+
+	return r.reconcile(ctx, log, serviceAccount, req.Namespace)
 }
 
 func (r *CredentialReconciler) reconcile(ctx context.Context, log logr.Logger, serviceAccount *corev1.ServiceAccount, namespace string) (ctrl.Result, error) {
@@ -82,9 +86,21 @@ func (r *CredentialReconciler) reconcile(ctx context.Context, log logr.Logger, s
 		return ctrl.Result{}, nil
 	}
 
+	selector := buildv1alpha1.CredentialLabelKey
+	if extra := serviceAccount.Annotations[buildv1alpha1.CredentialSelectorAnnotationKey]; extra != "" {
+		if _, err := labels.Parse(extra); err != nil {
+			// the selector can't be fixed by a requeue, wait for the service account to be updated
+			log.Error(err, "invalid credential selector", "selector", extra)
+			r.Recorder.Eventf(serviceAccount, corev1.EventTypeWarning, "InvalidCredentialSelector",
+				"Invalid credential selector %q: %v", extra, err)
+			return ctrl.Result{}, nil
+		}
+		selector = fmt.Sprintf("%s,%s", selector, extra)
+	}
+
 	secretNames := sets.NewString()
 	var secrets corev1.SecretList
-	if err := r.List(ctx, &secrets, client.InNamespace(namespace), MatchingLabels(buildv1alpha1.CredentialLabelKey)); err != nil {
+	if err := r.List(ctx, &secrets, client.InNamespace(namespace), MatchingLabels(selector)); err != nil {
 		log.Error(err, "Failed to get Secrets", "serviceaccount", serviceAccount)
 		return ctrl.Result{Requeue: true}, err
 	}
@@ -145,6 +161,32 @@ func (r *CredentialReconciler) reconcileServiceAccount(ctx context.Context, log
 	return serviceAccount, r.Update(ctx, serviceAccount)
 }
 
+func (r *CredentialReconciler) unbindServiceAccount(ctx context.Context, log logr.Logger, existingServiceAccount *corev1.ServiceAccount) error {
+	if existingServiceAccount.GetDeletionTimestamp() != nil {
+		return nil
+	}
+
+	serviceAccount := existingServiceAccount.DeepCopy()
+	boundSecrets := sets.NewString(strings.Split(serviceAccount.Annotations[buildv1alpha1.CredentialsAnnotationKey], ",")...)
+
+	secrets := []corev1.ObjectReference{}
+	// filter out secrets bound as credentials
+	for _, secret := range serviceAccount.Secrets {
+		if !boundSecrets.Has(secret.Name) {
+			secrets = append(secrets, secret)
+		}
+	}
+	serviceAccount.Secrets = secrets
+	delete(serviceAccount.Annotations, buildv1alpha1.CredentialsAnnotationKey)
+
+	log.Info("unbinding credentials from serviceaccount", "diff", cmp.Diff(existingServiceAccount.Secrets, serviceAccount.Secrets))
+	if err := r.Update(ctx, serviceAccount); err != nil {
+		log.Error(err, "Failed to unbind credentials from ServiceAccount", "serviceaccount", serviceAccount)
+		return err
+	}
+	return nil
+}
+
 func (r *CredentialReconciler) isServiceAccountNeeded(ctx context.Context, secretNames sets.String, namespace string) (bool, error) {
 	if secretNames.Len() != 0 {
 		return true, nil
@@ -193,6 +235,17 @@ func serviceAccountSemanticEquals(desiredServiceAccount, serviceAccount *corev1.
 		equality.Semantic.DeepEqual(desiredServiceAccount.Annotations, serviceAccount.Annotations)
 }
 
+// isManagedServiceAccount returns true if the service account has opted in to
+// having credentials bound.
+func isManagedServiceAccount(obj interface{}) bool {
+	sa, ok := obj.(*corev1.ServiceAccount)
+	if !ok {
+		return false
+	}
+	_, ok = sa.Annotations[buildv1alpha1.CredentialSelectorAnnotationKey]
+	return ok
+}
+
 // MatchingLabels filters the list/delete operation for a given LabelSelctor
 type MatchingLabels string
 
@@ -208,7 +261,7 @@ func (r *CredentialReconciler) SetupWithManager(mgr ctrl.Manager) error {
 				// not all secrets are credentials
 				return []reconcile.Request{}
 			}
-			return []reconcile.Request{
+			requests := []reconcile.Request{
 				{
 					NamespacedName: types.NamespacedName{
 						Namespace: a.Meta.GetNamespace(),
@@ -216,6 +269,24 @@ func (r *CredentialReconciler) SetupWithManager(mgr ctrl.Manager) error {
 					},
 				},
 			}
+			var serviceAccounts corev1.ServiceAccountList
+			if err := r.List(context.Background(), &serviceAccounts, client.InNamespace(a.Meta.GetNamespace())); err != nil {
+				r.Log.Error(err, "unable to list ServiceAccounts", "namespace", a.Meta.GetNamespace())
+				return requests
+			}
+			for i := range serviceAccounts.Items {
+				serviceAccount := &serviceAccounts.Items[i]
+				if serviceAccount.Name == riffBuildServiceAccount || !isManagedServiceAccount(serviceAccount) {
+					continue
+				}
+				requests = append(requests, reconcile.Request{
+					NamespacedName: types.NamespacedName{
+						Namespace: serviceAccount.Namespace,
+						Name:      serviceAccount.Name,
+					},
+				})
+			}
+			return requests
 		}),
 	}
 
@@ -241,8 +312,8 @@ func (r *CredentialReconciler) SetupWithManager(mgr ctrl.Manager) error {
 					// not a serviceaccount, allow
 					return true
 				}
-				// filter services accounts to only be the riff-build sa
-				return sa.Name == riffBuildServiceAccount
+				// filter services accounts to only be the riff-build sa or managed sa
+				return sa.Name == riffBuildServiceAccount || isManagedServiceAccount(sa)
 			},
 			UpdateFunc: func(e event.UpdateEvent) bool {
 				sa, ok := e.ObjectNew.(*corev1.ServiceAccount)
@@ -250,8 +321,9 @@ func (r *CredentialReconciler) SetupWithManager(mgr ctrl.Manager) error {
 					// not a serviceaccount, allow
 					return true
 				}
-				// filter services accounts to only be the riff-build sa
-				return sa.Name == riffBuildServiceAccount
+				// filter services accounts to only be the riff-build sa or managed sa, a
+				// previously managed sa is allowed so credentials are unbound on opt-out
+				return sa.Name == riffBuildServiceAccount || isManagedServiceAccount(sa) || isManagedServiceAccount(e.ObjectOld)
 			},
 		}).
 		// watch for secret mutations to bind to service account
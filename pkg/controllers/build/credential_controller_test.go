@@ -20,6 +20,8 @@ import (
 	"testing"
 
 	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
@@ -51,6 +53,8 @@ func TestCredentialsReconciler(t *testing.T) {
 		}).
 		NamespaceName(testNamespace, "my-credential")
 
+	_, invalidSelectorErr := labels.Parse("team in (a")
+
 	testApplication := factories.Application().
 		NamespaceName(testNamespace, "my-application")
 	testFunction := factories.Function().
@@ -317,6 +321,115 @@ func TestCredentialsReconciler(t *testing.T) {
 				}).
 				Secrets("keep-me"),
 		},
+	}, {
+		Name: "add credentials to managed service account",
+		Key:  types.NamespacedName{Namespace: testNamespace, Name: "my-builds"},
+		GivenObjects: []rtesting.Factory{
+			testServiceAccount.
+				NamespaceName(testNamespace, "my-builds").
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.AddAnnotation("build.projectriff.io/credential-selector", "")
+				}),
+			testCredential,
+		},
+		ExpectUpdates: []rtesting.Factory{
+			testServiceAccount.
+				NamespaceName(testNamespace, "my-builds").
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.AddAnnotation("build.projectriff.io/credential-selector", "")
+					om.AddAnnotation("build.projectriff.io/credentials", testCredential.Create().GetName())
+				}).
+				Secrets(testCredential.Create().GetName()),
+		},
+	}, {
+		Name: "add selected credentials to managed service account",
+		Key:  types.NamespacedName{Namespace: testNamespace, Name: "my-builds"},
+		GivenObjects: []rtesting.Factory{
+			testServiceAccount.
+				NamespaceName(testNamespace, "my-builds").
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.AddAnnotation("build.projectriff.io/credential-selector", "team=a")
+				}),
+			testCredential.
+				NamespaceName(testNamespace, "cred-a").
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.AddLabel("team", "a")
+				}),
+			testCredential.
+				NamespaceName(testNamespace, "cred-b").
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.AddLabel("team", "b")
+				}),
+		},
+		ExpectUpdates: []rtesting.Factory{
+			testServiceAccount.
+				NamespaceName(testNamespace, "my-builds").
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.AddAnnotation("build.projectriff.io/credential-selector", "team=a")
+					om.AddAnnotation("build.projectriff.io/credentials", "cred-a")
+				}).
+				Secrets("cred-a"),
+		},
+	}, {
+		Name: "managed service account, invalid credential selector",
+		Key:  types.NamespacedName{Namespace: testNamespace, Name: "my-builds"},
+		GivenObjects: []rtesting.Factory{
+			testServiceAccount.
+				NamespaceName(testNamespace, "my-builds").
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.AddAnnotation("build.projectriff.io/credential-selector", "team in (a")
+				}),
+			testCredential,
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(testServiceAccount.NamespaceName(testNamespace, "my-builds"), scheme, corev1.EventTypeWarning, "InvalidCredentialSelector",
+				`Invalid credential selector "team in (a": %s`, invalidSelectorErr),
+		},
+	}, {
+		Name: "unbind credentials from opted out service account",
+		Key:  types.NamespacedName{Namespace: testNamespace, Name: "my-builds"},
+		GivenObjects: []rtesting.Factory{
+			testServiceAccount.
+				NamespaceName(testNamespace, "my-builds").
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.AddAnnotation("build.projectriff.io/credentials", testCredential.Create().GetName())
+				}).
+				Secrets(testCredential.Create().GetName(), "keep-me"),
+			testCredential,
+		},
+		ExpectUpdates: []rtesting.Factory{
+			testServiceAccount.
+				NamespaceName(testNamespace, "my-builds").
+				Secrets("keep-me"),
+		},
+	}, {
+		Name: "unbind credentials from opted out service account, update error",
+		Key:  types.NamespacedName{Namespace: testNamespace, Name: "my-builds"},
+		WithReactors: []rtesting.ReactionFunc{
+			rtesting.InduceFailure("update", "ServiceAccount"),
+		},
+		GivenObjects: []rtesting.Factory{
+			testServiceAccount.
+				NamespaceName(testNamespace, "my-builds").
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.AddAnnotation("build.projectriff.io/credentials", testCredential.Create().GetName())
+				}).
+				Secrets(testCredential.Create().GetName()),
+			testCredential,
+		},
+		ShouldErr: true,
+		ExpectUpdates: []rtesting.Factory{
+			testServiceAccount.
+				NamespaceName(testNamespace, "my-builds"),
+		},
+	}, {
+		Name: "ignore unmanaged service account",
+		Key:  types.NamespacedName{Namespace: testNamespace, Name: "my-builds"},
+		GivenObjects: []rtesting.Factory{
+			testServiceAccount.
+				NamespaceName(testNamespace, "my-builds"),
+			testCredential,
+		},
 	}}
 
 	table.Test(t, scheme, func(t *testing.T, row *rtesting.Testcase, client client.Client, apiReader client.Reader, tracker tracker.Tracker, recorder record.EventRecorder, log logr.Logger) reconcile.Reconciler {
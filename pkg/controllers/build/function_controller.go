@@ -19,43 +19,67 @@ package build
 import (
 	"context"
 	"fmt"
+	"io"
 
+	"github.com/google/go-containerregistry/pkg/name"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	buildv1alpha1 "github.com/projectriff/system/pkg/apis/build/v1alpha1"
 	kpackbuildv1alpha1 "github.com/projectriff/system/pkg/apis/thirdparty/kpack/build/v1alpha1"
 	"github.com/projectriff/system/pkg/controllers"
 	"github.com/projectriff/system/pkg/refs"
+	"github.com/projectriff/system/pkg/registries"
+	"github.com/projectriff/system/pkg/tracker"
 )
 
 // +kubebuilder:rbac:groups=build.projectriff.io,resources=functions,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=build.projectriff.io,resources=functions/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=build.pivotal.io,resources=images,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=build.pivotal.io,resources=builders,verbs=get;list;watch
+// +kubebuilder:rbac:groups=build.pivotal.io,resources=builds,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=serviceaccounts,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=persistentvolumeclaims,verbs=get;list;watch;delete
 // +kubebuilder:rbac:groups=core,resources=events,verbs=get;list;watch;create;update;patch;delete
 
-func FunctionReconciler(c controllers.Config) *controllers.ParentReconciler {
+func FunctionReconciler(c controllers.Config, namespace string, random io.Reader) *controllers.ParentReconciler {
 	c.Log = c.Log.WithName("Function")
 
 	return &controllers.ParentReconciler{
 		Type: &buildv1alpha1.Function{},
 		SubReconcilers: []controllers.SubReconciler{
-			FunctionTargetImageReconciler(c),
+			FunctionTargetImageReconciler(c, namespace),
+			FunctionCredentialsReconciler(c),
+			FunctionBuilderReconciler(c, namespace),
+			FunctionBuildEnvReconciler(c, random),
+			FunctionChildBuildEnvReconciler(c),
 			FunctionChildImageReconciler(c),
+			FunctionImageDigestReconciler(c),
+			FunctionBuildMetadataReconciler(c),
+			FunctionBuildHistoryReconciler(c),
+			FunctionBuildCacheReconciler(c),
 		},
 
 		Config: c,
 	}
 }
 
-func FunctionTargetImageReconciler(c controllers.Config) controllers.SubReconciler {
+func FunctionTargetImageReconciler(c controllers.Config, namespace string) controllers.SubReconciler {
 	c.Log = c.Log.WithName("TargetImage")
 
 	return &controllers.SyncReconciler{
 		Sync: func(ctx context.Context, parent *buildv1alpha1.Function) error {
-			targetImage, err := resolveTargetImage(ctx, c.Client, parent)
+			// track registry rewrite rules for a new target image
+			registries.Track(c.Tracker, namespace, parent)
+			targetImage, err := resolveTargetImage(ctx, c.Client, namespace, parent)
 			if err != nil {
 				if err == errMissingDefaultPrefix {
 					parent.Status.MarkImageDefaultPrefixMissing(err.Error())
@@ -73,6 +97,261 @@ func FunctionTargetImageReconciler(c controllers.Config) controllers.SubReconcil
 	}
 }
 
+func FunctionCredentialsReconciler(c controllers.Config) controllers.SubReconciler {
+	c.Log = c.Log.WithName("Credentials")
+
+	return &controllers.SyncReconciler{
+		Sync: func(ctx context.Context, parent *buildv1alpha1.Function) error {
+			health, err := resolveCredentialHealth(ctx, c.Client, parent.Namespace, parent.Spec.ServiceAccountName, parent.Status.TargetImage)
+			if err != nil {
+				return err
+			}
+			// track the service account for credential bindings
+			c.Tracker.Track(
+				tracker.NewKey(schema.GroupVersionKind{Version: "v1", Kind: "ServiceAccount"}, types.NamespacedName{Namespace: parent.Namespace, Name: parent.Spec.ServiceAccountName}),
+				types.NamespacedName{Namespace: parent.Namespace, Name: parent.Name},
+			)
+			for _, secret := range health.Secrets {
+				// track credentials for verification results
+				c.Tracker.Track(
+					tracker.NewKey(schema.GroupVersionKind{Version: "v1", Kind: "Secret"}, types.NamespacedName{Namespace: secret.Namespace, Name: secret.Name}),
+					types.NamespacedName{Namespace: parent.Namespace, Name: parent.Name},
+				)
+			}
+			switch {
+			case !health.Bound():
+				parent.Status.MarkCredentialsNotBound()
+			case !health.Working():
+				parent.Status.MarkCredentialsInvalid(health.Message)
+			default:
+				parent.Status.MarkCredentialsReady()
+			}
+			return nil
+		},
+
+		Config: c,
+		Setup: func(mgr controllers.Manager, bldr *controllers.Builder) error {
+			bldr.Watches(&source.Kind{Type: &corev1.ServiceAccount{}}, controllers.EnqueueTracked(&corev1.ServiceAccount{}, c.Tracker, c.Scheme))
+			bldr.Watches(&source.Kind{Type: &corev1.Secret{}}, controllers.EnqueueTracked(&corev1.Secret{}, c.Tracker, c.Scheme))
+			return nil
+		},
+	}
+}
+
+func FunctionBuilderReconciler(c controllers.Config, namespace string) controllers.SubReconciler {
+	c.Log = c.Log.WithName("Builder")
+
+	return &controllers.SyncReconciler{
+		Sync: func(ctx context.Context, parent *buildv1alpha1.Function) error {
+			if parent.Spec.Source == nil {
+				// builds are not used
+				parent.Status.BuilderRef = nil
+				parent.Status.BuilderImage = ""
+				return nil
+			}
+			builder := buildv1alpha1.BuilderReference{
+				Kind: buildv1alpha1.ClusterBuilderKind,
+				Name: "riff-function",
+			}
+			if parent.Spec.Builder != nil {
+				builder = *parent.Spec.Builder
+			}
+			image, err := resolveBuilderImage(ctx, c, namespace, parent, builder)
+			if err != nil {
+				if _, ok := err.(*builderNotFoundError); ok {
+					parent.Status.MarkBuilderNotFound(err.Error())
+				}
+				return err
+			}
+			parent.Status.BuilderRef = refs.NewTypedLocalObjectReference(builder.Name, schema.GroupKind{Group: kpackbuildv1alpha1.GroupVersion.Group, Kind: builder.Kind})
+			parent.Status.BuilderImage = image
+			return nil
+		},
+
+		Config: c,
+		Setup: func(mgr controllers.Manager, bldr *controllers.Builder) error {
+			bldr.Watches(&source.Kind{Type: &corev1.ConfigMap{}}, controllers.EnqueueTracked(&corev1.ConfigMap{}, c.Tracker, c.Scheme))
+			bldr.Watches(&source.Kind{Type: &kpackbuildv1alpha1.Builder{}}, controllers.EnqueueTracked(&kpackbuildv1alpha1.Builder{}, c.Tracker, c.Scheme))
+			return nil
+		},
+	}
+}
+
+func FunctionBuildHistoryReconciler(c controllers.Config) controllers.SubReconciler {
+	c.Log = c.Log.WithName("BuildHistory")
+
+	return &controllers.SyncReconciler{
+		Sync: func(ctx context.Context, parent *buildv1alpha1.Function) error {
+			if parent.Status.KpackImageRef == nil {
+				parent.Status.Builds = nil
+				parent.Status.MarkUpToDateNotApplicable()
+				return nil
+			}
+			builds, err := resolveBuildHistory(ctx, c.Client, parent.Namespace, parent.Status.KpackImageRef.Name)
+			if err != nil {
+				return err
+			}
+			parent.Status.Builds = builds
+			reflectUpToDate(&parent.Status, builds, parent.Status.BuilderImage)
+			return nil
+		},
+
+		Config: c,
+		Setup: func(mgr controllers.Manager, bldr *controllers.Builder) error {
+			// kpack copies the labels of the Image, including the function label, onto each Build
+			bldr.Watches(&source.Kind{Type: &kpackbuildv1alpha1.Build{}}, enqueueByLabel(buildv1alpha1.FunctionLabelKey))
+			return nil
+		},
+	}
+}
+
+func FunctionBuildCacheReconciler(c controllers.Config) controllers.SubReconciler {
+	c.Log = c.Log.WithName("BuildCache")
+
+	return &controllers.SyncReconciler{
+		Sync: func(ctx context.Context, parent *buildv1alpha1.Function) error {
+			return reconcileBuildCache(ctx, c, parent, parent.Spec.CacheSize, parent.Spec.Cache, &parent.Status.BuildStatus)
+		},
+
+		Config: c,
+		Setup: func(mgr controllers.Manager, bldr *controllers.Builder) error {
+			bldr.Watches(&source.Kind{Type: &corev1.PersistentVolumeClaim{}}, controllers.EnqueueTracked(&corev1.PersistentVolumeClaim{}, c.Tracker, c.Scheme))
+			return nil
+		},
+	}
+}
+
+func FunctionImageDigestReconciler(c controllers.Config) controllers.SubReconciler {
+	c.Log = c.Log.WithName("ImageDigest")
+
+	return &controllers.SyncReconciler{
+		Sync: func(ctx context.Context, parent *buildv1alpha1.Function) (ctrl.Result, error) {
+			if parent.Spec.Source != nil {
+				// the latest image is reflected from the kpack image
+				return ctrl.Result{}, nil
+			}
+			ref, err := name.ParseReference(parent.Status.TargetImage)
+			if err != nil {
+				parent.Status.MarkImageInvalid(err.Error())
+				return ctrl.Result{}, err
+			}
+			latestImage, err := resolveImageDigest(ctx, c.Client, c.Log, parent.Namespace, parent.Spec.ServiceAccountName, ref)
+			if err != nil {
+				parent.Status.MarkImageInvalid(err.Error())
+				return ctrl.Result{}, err
+			}
+			parent.Status.LatestImage = latestImage
+			// poll for the tag to move
+			return ctrl.Result{RequeueAfter: imagePollingInterval}, nil
+		},
+
+		Config: c,
+	}
+}
+
+func FunctionBuildMetadataReconciler(c controllers.Config) controllers.SubReconciler {
+	c.Log = c.Log.WithName("BuildMetadata")
+
+	return &controllers.SyncReconciler{
+		Sync: func(ctx context.Context, parent *buildv1alpha1.Function) error {
+			if parent.Status.LatestImage == "" {
+				parent.Status.BuildMetadata = nil
+				parent.Status.Invoker = ""
+				return nil
+			}
+			if parent.Status.BuildMetadata != nil && parent.Status.BuildMetadata.Image == parent.Status.LatestImage {
+				// images are immutable, the metadata is current
+				return nil
+			}
+			metadata, err := resolveBuildMetadata(ctx, c.Client, c.Log, parent.Namespace, parent.Spec.ServiceAccountName, parent.Status.LatestImage)
+			if err != nil {
+				return err
+			}
+			parent.Status.BuildMetadata = metadata
+			parent.Status.Invoker = detectInvoker(metadata.Buildpacks)
+			return nil
+		},
+
+		Config: c,
+	}
+}
+
+func FunctionBuildEnvReconciler(c controllers.Config, random io.Reader) controllers.SubReconciler {
+	c.Log = c.Log.WithName("BuildEnv")
+
+	return &controllers.SyncReconciler{
+		Sync: func(ctx context.Context, parent *buildv1alpha1.Function) error {
+			if parent.Spec.Source == nil {
+				// kpack builds are not used
+				return nil
+			}
+			values, err := resolveBuildEnv(ctx, c, parent, parent.Spec.Build.Env)
+			if err != nil {
+				if _, ok := err.(*buildEnvError); ok {
+					parent.Status.MarkBuildEnvInvalid(err.Error())
+				}
+				return err
+			}
+			controllers.StashValue(ctx, buildEnvStashKey, values)
+			if len(values) != 0 {
+				digestKey, err := resolveBuildEnvDigestKey(ctx, c.Client, parent, "function", random)
+				if err != nil {
+					return err
+				}
+				controllers.StashValue(ctx, buildEnvDigestKeyStashKey, digestKey)
+			}
+			return nil
+		},
+
+		Config: c,
+	}
+}
+
+func FunctionChildBuildEnvReconciler(c controllers.Config) controllers.SubReconciler {
+	c.Log = c.Log.WithName("ChildBuildEnv")
+
+	return &controllers.ChildReconciler{
+		ParentType:    &buildv1alpha1.Function{},
+		ChildType:     &corev1.Secret{},
+		ChildListType: &corev1.SecretList{},
+
+		DesiredChild: func(ctx context.Context, parent *buildv1alpha1.Function) (*corev1.Secret, error) {
+			values, _ := controllers.RetrieveValue(ctx, buildEnvStashKey).(map[string][]byte)
+			labels := controllers.MergeMaps(parent.Labels, map[string]string{
+				buildv1alpha1.FunctionLabelKey: parent.Name,
+			})
+			digestKey, _ := controllers.RetrieveValue(ctx, buildEnvDigestKeyStashKey).([]byte)
+			return buildEnvSecret(parent, "function", labels, values, digestKey), nil
+		},
+		ReflectChildStatusOnParent: func(parent *buildv1alpha1.Function, child *corev1.Secret, err error) {
+			if err != nil {
+				if apierrs.IsAlreadyExists(err) {
+					name := err.(apierrs.APIStatus).Status().Details.Name
+					parent.Status.MarkBuildEnvInvalid(fmt.Sprintf("build env secret %q already exists", name))
+				}
+			}
+		},
+		MergeBeforeUpdate: func(current, desired *corev1.Secret) {
+			current.Labels = desired.Labels
+			current.Data = desired.Data
+		},
+		SemanticEquals: func(a1, a2 *corev1.Secret) bool {
+			return equality.Semantic.DeepEqual(a1.Data, a2.Data) &&
+				equality.Semantic.DeepEqual(a1.Labels, a2.Labels)
+		},
+
+		Config:     c,
+		IndexField: ".metadata.functionBuildEnvController",
+		Sanitize: func(child *corev1.Secret) interface{} {
+			// never log build env values
+			return child.Name
+		},
+		OurChild: func(parent *buildv1alpha1.Function, child *corev1.Secret) bool {
+			return child.Name == buildEnvSecretName(parent, "function")
+		},
+	}
+}
+
 func FunctionChildImageReconciler(c controllers.Config) controllers.SubReconciler {
 	c.Log = c.Log.WithName("ChildImage")
 
@@ -84,7 +363,7 @@ func FunctionChildImageReconciler(c controllers.Config) controllers.SubReconcile
 		ChildType:     &kpackbuildv1alpha1.Image{},
 		ChildListType: &kpackbuildv1alpha1.ImageList{},
 
-		DesiredChild: func(parent *buildv1alpha1.Function) (*kpackbuildv1alpha1.Image, error) {
+		DesiredChild: func(ctx context.Context, parent *buildv1alpha1.Function) (*kpackbuildv1alpha1.Image, error) {
 			if parent.Spec.Source == nil {
 				return nil, nil
 			}
@@ -94,7 +373,6 @@ func FunctionChildImageReconciler(c controllers.Config) controllers.SubReconcile
 					Labels: controllers.MergeMaps(parent.Labels, map[string]string{
 						buildv1alpha1.FunctionLabelKey: parent.Name,
 					}),
-					Annotations:  make(map[string]string),
 					GenerateName: fmt.Sprintf("%s-function-", parent.Name),
 					Namespace:    parent.Namespace,
 				},
@@ -102,32 +380,41 @@ func FunctionChildImageReconciler(c controllers.Config) controllers.SubReconcile
 					Tag: parent.Status.TargetImage,
 					Builder: kpackbuildv1alpha1.ImageBuilder{
 						TypeMeta: metav1.TypeMeta{
-							Kind: "ClusterBuilder",
+							Kind: parent.Status.BuilderRef.Kind,
 						},
-						Name: "riff-function",
+						Name: parent.Status.BuilderRef.Name,
 					},
-					ServiceAccount:           riffBuildServiceAccount,
+					ServiceAccount:           parent.Spec.ServiceAccountName,
 					Source:                   *parent.Spec.Source,
 					CacheSize:                parent.Spec.CacheSize,
 					FailedBuildHistoryLimit:  parent.Spec.FailedBuildHistoryLimit,
 					SuccessBuildHistoryLimit: parent.Spec.SuccessBuildHistoryLimit,
 					ImageTaggingStrategy:     parent.Spec.ImageTaggingStrategy,
-					Build:                    parent.Spec.Build,
+					Build:                    *parent.Spec.Build.DeepCopy(),
 				},
 			}
+			values, _ := controllers.RetrieveValue(ctx, buildEnvStashKey).(map[string][]byte)
+			if len(values) != 0 {
+				// the condition is reflected by the child build env reconciler
+				if err := checkSecretControlled(ctx, c.Client, parent, buildEnvSecretName(parent, "function")); err != nil {
+					return nil, err
+				}
+			}
+			digestKey, _ := controllers.RetrieveValue(ctx, buildEnvDigestKeyStashKey).([]byte)
+			child.Spec.Build.Env = managedBuildEnv(child.Spec.Build.Env, buildEnvSecretName(parent, "function"), values, digestKey)
 			child.Spec.Build.Env = append(child.Spec.Build.Env,
 				corev1.EnvVar{Name: "RIFF", Value: "true"},
 				corev1.EnvVar{Name: "RIFF_ARTIFACT", Value: parent.Spec.Artifact},
 				corev1.EnvVar{Name: "RIFF_HANDLER", Value: parent.Spec.Handler},
 				corev1.EnvVar{Name: "RIFF_OVERRIDE", Value: parent.Spec.Invoker},
 			)
+			child.Spec.Build.Env = rebuildBuildEnv(child.Spec.Build.Env, parent.Spec.RebuildPolicy, parent.Status.BuilderImage)
 
 			return child, nil
 		},
 		ReflectChildStatusOnParent: func(parent *buildv1alpha1.Function, child *kpackbuildv1alpha1.Image, err error) {
 			if child == nil {
-				// TODO resolve to a digest?
-				parent.Status.LatestImage = parent.Status.TargetImage
+				// the latest image is resolved by the image digest reconciler
 				parent.Status.MarkBuildNotUsed()
 			} else {
 				parent.Status.KpackImageRef = refs.NewTypedLocalObjectReferenceForObject(child, c.Scheme)
@@ -149,3 +436,21 @@ func FunctionChildImageReconciler(c controllers.Config) controllers.SubReconcile
 		},
 	}
 }
+
+// invokerBuildpacks maps riff's invoker buildpacks to the name of the invoker
+var invokerBuildpacks = map[string]string{
+	"io.projectriff.command": "command",
+	"io.projectriff.java":    "java",
+	"io.projectriff.node":    "node",
+}
+
+// detectInvoker returns the name of the invoker whose buildpack contributed to
+// the image, or an empty string when no invoker was used.
+func detectInvoker(buildpacks kpackbuildv1alpha1.BuildpackMetadataList) string {
+	for _, buildpack := range buildpacks {
+		if invoker, ok := invokerBuildpacks[buildpack.ID]; ok {
+			return invoker
+		}
+	}
+	return ""
+}
@@ -17,14 +17,20 @@ limitations under the License.
 package build_test
 
 import (
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/tools/record"
+	controllerruntime "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
@@ -42,10 +48,10 @@ func TestFunctionReconciler(t *testing.T) {
 	testNamespace := "test-namespace"
 	testName := "test-function"
 	testKey := types.NamespacedName{Namespace: testNamespace, Name: testName}
-	testImagePrefix := "example.com/repo"
+	testSystemNamespace := "system-namespace"
+	testBuilderImage := "example.com/builder@sha256:1a2b3c"
 	testGitUrl := "git@example.com:repo.git"
 	testGitRevision := "master"
-	testSha256 := "cf8b4c69d5460f88530e1c80b8856a70801f31c50b191c8413043ba9b160a43e"
 	testConditionReason := "TestReason"
 	testConditionMessage := "meaningful, yet concise"
 	testLabelKey := "test-label-key"
@@ -55,9 +61,35 @@ func TestFunctionReconciler(t *testing.T) {
 	testHandler := "test-fn-handler"
 	testInvoker := "test-fn-invoker"
 
+	functionConditionCredentialsReady := factories.Condition().Type(buildv1alpha1.FunctionConditionCredentialsReady)
 	functionConditionImageResolved := factories.Condition().Type(buildv1alpha1.FunctionConditionImageResolved)
 	functionConditionKpackImageReady := factories.Condition().Type(buildv1alpha1.FunctionConditionKpackImageReady)
 	functionConditionReady := factories.Condition().Type(buildv1alpha1.FunctionConditionReady)
+	functionConditionUpToDate := factories.Condition().Type(buildv1alpha1.FunctionConditionUpToDate)
+
+	testRegistry := rtesting.NewRegistry()
+	defer testRegistry.Close()
+	testLocalImage := testRegistry.Repository("repo/%s", testName)
+	testLocalDigest := testRegistry.PushRandomImage(t, testLocalImage)
+	testImagePrefix := testRegistry.Repository("repo")
+	testBuildEnvDigestKey := "0123456789abcdef0123456789abcdef"
+	testSha256 := strings.TrimPrefix(testRegistry.PushLabeledImage(t, fmt.Sprintf("%s/%s:built", testImagePrefix, testName), map[string]string{
+		"io.buildpacks.stack.id":           "io.buildpacks.stacks.bionic",
+		"io.buildpacks.lifecycle.metadata": `{"buildpacks": [{"key": "io.projectriff.node", "version": "0.2.0"}, {"key": "paketo-buildpacks/node-engine", "version": "0.0.178"}], "runImage": {"reference": "example.com/run@sha256:9f8e7d"}, "stack": {"runImage": {"image": "example.com/run:base"}}}`,
+		"io.buildpacks.build.metadata":     `{"bom": [{"name": "node", "version": "12.16.1", "buildpack": {"id": "paketo-buildpacks/node-engine", "version": "0.0.178"}}]}`,
+	}), "sha256:")
+	testBuildMetadata := &buildv1alpha1.BuildMetadata{
+		Image: fmt.Sprintf("%s/%s@sha256:%s", testImagePrefix, testName, testSha256),
+		Buildpacks: kpackbuildv1alpha1.BuildpackMetadataList{
+			{ID: "io.projectriff.node", Version: "0.2.0"},
+			{ID: "paketo-buildpacks/node-engine", Version: "0.0.178"},
+		},
+		RunImage: "example.com/run@sha256:9f8e7d",
+		StackID:  "io.buildpacks.stacks.bionic",
+		BillOfMaterials: []buildv1alpha1.BillOfMaterialsEntry{
+			{Name: "node", Version: "12.16.1", Buildpack: "paketo-buildpacks/node-engine"},
+		},
+	}
 
 	scheme := runtime.NewScheme()
 	_ = clientgoscheme.AddToScheme(scheme)
@@ -92,9 +124,49 @@ func TestFunctionReconciler(t *testing.T) {
 		}).
 		StatusObservedGeneration(1)
 
+	testNpmSecret := factories.Secret().
+		NamespaceName(testNamespace, "npm").
+		AddData("token", "s3cr3t")
+
+	kpackBuildGiven := factories.KpackBuild().
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.Created(10)
+		})
+	buildCreated := metav1.Unix(10, 0)
+	build1Completed := metav1.Unix(20, 0)
+	build2Completed := metav1.Unix(40, 0)
+
+	testBuildCache := factories.PersistentVolumeClaim().
+		NamespaceName(testNamespace, testBuildCacheName).
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.Created(5)
+		}).
+		Storage("1Gi").
+		StatusCapacity("1Gi")
+	buildCacheCreated := metav1.Unix(5, 0)
+	buildCacheSize := resource.MustParse("1Gi")
+
+	testServiceAccount := factories.ServiceAccount().
+		NamespaceName(testNamespace, "riff-build")
+	testCredential := factories.Secret().
+		NamespaceName(testNamespace, "my-credential").
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.AddLabel(buildv1alpha1.CredentialLabelKey, "basic-auth")
+			om.AddAnnotation("build.pivotal.io/docker", fmt.Sprintf("http://%s", testRegistry.Host))
+		})
+
+	cmBuilders := factories.ConfigMap().
+		NamespaceName(testSystemNamespace, "builders").
+		AddData("riff-function", testBuilderImage)
+
 	cmImagePrefix := factories.ConfigMap().
 		NamespaceName(testNamespace, "riff-build").
 		AddData("default-image-prefix", "")
+	cmClusterImagePrefix := factories.ConfigMap().
+		NamespaceName(testSystemNamespace, "riff-build")
+
+	cmRegistryRewrites := factories.ConfigMap().
+		NamespaceName(testSystemNamespace, "riff-registry-rewrites")
 
 	table := rtesting.Table{{
 		Name: "function does not exist",
@@ -120,6 +192,12 @@ func TestFunctionReconciler(t *testing.T) {
 		Key:  testKey,
 		GivenObjects: []rtesting.Factory{
 			funcValid,
+			cmBuilders,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(cmRegistryRewrites, funcMinimal, scheme),
+			rtesting.NewTrackRequest(testServiceAccount, funcMinimal, scheme),
+			rtesting.NewTrackRequest(cmBuilders, funcMinimal, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
 			rtesting.NewEvent(funcValid, scheme, corev1.EventTypeNormal, "Created",
@@ -138,7 +216,116 @@ func TestFunctionReconciler(t *testing.T) {
 					functionConditionReady.Unknown(),
 				).
 				StatusKpackImageRef("%s-function-001", testName).
-				StatusTargetImage("%s/%s", testImagePrefix, testName),
+				StatusTargetImage("%s/%s", testImagePrefix, testName).
+				StatusBuilder("ClusterBuilder", "riff-function", testBuilderImage),
+		},
+	}, {
+		Name: "create kpack image, build env",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			funcValid.
+				BuildEnv(corev1.EnvVar{
+					Name: "NPM_TOKEN",
+					ValueFrom: &corev1.EnvVarSource{
+						SecretKeyRef: &corev1.SecretKeySelector{
+							LocalObjectReference: corev1.LocalObjectReference{Name: "npm"},
+							Key:                  "token",
+						},
+					},
+				}),
+			cmBuilders,
+			testNpmSecret,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(cmRegistryRewrites, funcMinimal, scheme),
+			rtesting.NewTrackRequest(testServiceAccount, funcMinimal, scheme),
+			rtesting.NewTrackRequest(cmBuilders, funcMinimal, scheme),
+			rtesting.NewTrackRequest(testNpmSecret, funcMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(funcValid, scheme, corev1.EventTypeNormal, "Created",
+				`Created Secret "%s-function-build-env"`, testName),
+			rtesting.NewEvent(funcValid, scheme, corev1.EventTypeNormal, "Created",
+				`Created Image "%s-function-001"`, testName),
+			rtesting.NewEvent(funcValid, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectCreates: []rtesting.Factory{
+			factories.Secret().
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.Namespace(testNamespace).
+						Name("%s-function-build-env", testName).
+						AddLabel(buildv1alpha1.FunctionLabelKey, testName).
+						ControlledBy(funcMinimal, scheme)
+				}).
+				Type(corev1.SecretTypeOpaque).
+				AddData("NPM_TOKEN", "s3cr3t").
+				AddData("RIFF_BUILD_ENV_DIGEST_KEY", testBuildEnvDigestKey),
+			kpackImageCreate.
+				BuildEnv(
+					corev1.EnvVar{
+						Name: "NPM_TOKEN",
+						ValueFrom: &corev1.EnvVarSource{
+							SecretKeyRef: &corev1.SecretKeySelector{
+								LocalObjectReference: corev1.LocalObjectReference{Name: fmt.Sprintf("%s-function-build-env", testName)},
+								Key:                  "NPM_TOKEN",
+							},
+						},
+					},
+					corev1.EnvVar{
+						Name:  "RIFF_BUILD_ENV_DIGEST",
+						Value: "hmac-sha256:5d8f59cfebe54820c9758efd1e3f3e9f97b89dddd2a0f8ca7f89ef606779bb03",
+					},
+				).
+				FunctionBuilder("", "", ""),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			funcMinimal.
+				StatusConditions(
+					functionConditionImageResolved.True(),
+					functionConditionKpackImageReady.Unknown(),
+					functionConditionReady.Unknown(),
+				).
+				StatusKpackImageRef("%s-function-001", testName).
+				StatusTargetImage("%s/%s", testImagePrefix, testName).
+				StatusBuilder("ClusterBuilder", "riff-function", testBuilderImage),
+		},
+	}, {
+		Name: "create kpack image, build env config map not found",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			funcValid.
+				BuildEnv(corev1.EnvVar{
+					Name: "MAVEN_MIRROR",
+					ValueFrom: &corev1.EnvVarSource{
+						ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+							LocalObjectReference: corev1.LocalObjectReference{Name: "maven"},
+							Key:                  "mirror",
+						},
+					},
+				}),
+			cmBuilders,
+		},
+		ShouldErr: true,
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(cmRegistryRewrites, funcMinimal, scheme),
+			rtesting.NewTrackRequest(testServiceAccount, funcMinimal, scheme),
+			rtesting.NewTrackRequest(cmBuilders, funcMinimal, scheme),
+			rtesting.NewTrackRequest(factories.ConfigMap().NamespaceName(testNamespace, "maven"), funcMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(funcValid, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			funcMinimal.
+				StatusConditions(
+					functionConditionImageResolved.True(),
+					functionConditionKpackImageReady.False().Reason("BuildEnvInvalid", `build env "MAVEN_MIRROR" references ConfigMap "maven" which is not found`),
+					functionConditionReady.False().Reason("BuildEnvInvalid", `build env "MAVEN_MIRROR" references ConfigMap "maven" which is not found`),
+				).
+				StatusTargetImage("%s/%s", testImagePrefix, testName).
+				StatusBuilder("ClusterBuilder", "riff-function", testBuilderImage),
 		},
 	}, {
 		Name: "create kpack image, function properties",
@@ -148,6 +335,12 @@ func TestFunctionReconciler(t *testing.T) {
 				Artifact(testArtifact).
 				Handler(testHandler).
 				Invoker(testInvoker),
+			cmBuilders,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(cmRegistryRewrites, funcMinimal, scheme),
+			rtesting.NewTrackRequest(testServiceAccount, funcMinimal, scheme),
+			rtesting.NewTrackRequest(cmBuilders, funcMinimal, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
 			rtesting.NewEvent(funcValid, scheme, corev1.EventTypeNormal, "Created",
@@ -166,103 +359,759 @@ func TestFunctionReconciler(t *testing.T) {
 					functionConditionKpackImageReady.Unknown(),
 					functionConditionReady.Unknown(),
 				).
-				StatusKpackImageRef("%s-function-001", testName).
-				StatusTargetImage("%s/%s", testImagePrefix, testName),
+				StatusKpackImageRef("%s-function-001", testName).
+				StatusTargetImage("%s/%s", testImagePrefix, testName).
+				StatusBuilder("ClusterBuilder", "riff-function", testBuilderImage),
+		},
+	}, {
+		Name: "create kpack image, build cache",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			funcValid.
+				BuildCache("1Gi"),
+			cmBuilders,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(cmRegistryRewrites, funcMinimal, scheme),
+			rtesting.NewTrackRequest(testServiceAccount, funcMinimal, scheme),
+			rtesting.NewTrackRequest(cmBuilders, funcMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(funcValid, scheme, corev1.EventTypeNormal, "Created",
+				`Created Image "%s-function-001"`, testName),
+			rtesting.NewEvent(funcValid, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectCreates: []rtesting.Factory{
+			kpackImageCreate.
+				BuildCache("1Gi"),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			funcMinimal.
+				StatusConditions(
+					functionConditionImageResolved.True(),
+					functionConditionKpackImageReady.Unknown(),
+					functionConditionReady.Unknown(),
+				).
+				StatusKpackImageRef("%s-function-001", testName).
+				StatusTargetImage("%s/%s", testImagePrefix, testName).
+				StatusBuilder("ClusterBuilder", "riff-function", testBuilderImage),
+		},
+	}, {
+		Name: "create kpack image, propagating labels",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			funcValid.
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.AddLabel(testLabelKey, testLabelValue)
+				}),
+			cmBuilders,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(cmRegistryRewrites, funcMinimal, scheme),
+			rtesting.NewTrackRequest(testServiceAccount, funcMinimal, scheme),
+			rtesting.NewTrackRequest(cmBuilders, funcMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(funcValid, scheme, corev1.EventTypeNormal, "Created",
+				`Created Image "%s-function-001"`, testName),
+			rtesting.NewEvent(funcValid, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectCreates: []rtesting.Factory{
+			kpackImageCreate.
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.AddLabel(testLabelKey, testLabelValue)
+				}),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			funcMinimal.
+				StatusConditions(
+					functionConditionImageResolved.True(),
+					functionConditionKpackImageReady.Unknown(),
+					functionConditionReady.Unknown(),
+				).
+				StatusKpackImageRef("%s-function-001", testName).
+				StatusTargetImage("%s/%s", testImagePrefix, testName).
+				StatusBuilder("ClusterBuilder", "riff-function", testBuilderImage),
+		},
+	}, {
+		Name: "create kpack image, custom service account",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			funcValid.
+				ServiceAccountName("my-builds"),
+			cmBuilders,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(cmRegistryRewrites, funcMinimal, scheme),
+			rtesting.NewTrackRequest(testServiceAccount.NamespaceName(testNamespace, "my-builds"), funcMinimal, scheme),
+			rtesting.NewTrackRequest(cmBuilders, funcMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(funcValid, scheme, corev1.EventTypeNormal, "Created",
+				`Created Image "%s-function-001"`, testName),
+			rtesting.NewEvent(funcValid, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectCreates: []rtesting.Factory{
+			kpackImageCreate.
+				ServiceAccount("my-builds"),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			funcMinimal.
+				StatusConditions(
+					functionConditionImageResolved.True(),
+					functionConditionKpackImageReady.Unknown(),
+					functionConditionReady.Unknown(),
+				).
+				StatusKpackImageRef("%s-function-001", testName).
+				StatusTargetImage("%s/%s", testImagePrefix, testName).
+				StatusBuilder("ClusterBuilder", "riff-function", testBuilderImage),
+		},
+	}, {
+		Name: "create kpack image, namespaced builder",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			funcValid.
+				Builder("Builder", "my-builder"),
+			factories.KpackBuilder().
+				NamespaceName(testNamespace, "my-builder").
+				StatusLatestImage("example.com/my-builder@sha256:4d5e6f"),
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(cmRegistryRewrites, funcMinimal, scheme),
+			rtesting.NewTrackRequest(testServiceAccount, funcMinimal, scheme),
+			rtesting.NewTrackRequest(factories.KpackBuilder().NamespaceName(testNamespace, "my-builder"), funcMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(funcValid, scheme, corev1.EventTypeNormal, "Created",
+				`Created Image "%s-function-001"`, testName),
+			rtesting.NewEvent(funcValid, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectCreates: []rtesting.Factory{
+			kpackImageCreate.
+				Builder("Builder", "my-builder"),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			funcMinimal.
+				StatusConditions(
+					functionConditionImageResolved.True(),
+					functionConditionKpackImageReady.Unknown(),
+					functionConditionReady.Unknown(),
+				).
+				StatusKpackImageRef("%s-function-001", testName).
+				StatusTargetImage("%s/%s", testImagePrefix, testName).
+				StatusBuilder("Builder", "my-builder", "example.com/my-builder@sha256:4d5e6f"),
+		},
+	}, {
+		Name: "create kpack image, namespaced builder not found",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			funcValid.
+				Builder("Builder", "my-builder"),
+		},
+		ShouldErr: true,
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(cmRegistryRewrites, funcMinimal, scheme),
+			rtesting.NewTrackRequest(testServiceAccount, funcMinimal, scheme),
+			rtesting.NewTrackRequest(factories.KpackBuilder().NamespaceName(testNamespace, "my-builder"), funcMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(funcValid, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			funcMinimal.
+				StatusConditions(
+					functionConditionImageResolved.True(),
+					functionConditionKpackImageReady.False().Reason("BuilderNotFound", `Builder "my-builder" not found`),
+					functionConditionReady.False().Reason("BuilderNotFound", `Builder "my-builder" not found`),
+				).
+				StatusTargetImage("%s/%s", testImagePrefix, testName),
+		},
+	}, {
+		Name: "create kpack image, cluster builder",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			funcValid.
+				Builder("ClusterBuilder", "my-cluster-builder"),
+			cmBuilders.
+				AddData("my-cluster-builder", "example.com/my-cluster-builder@sha256:7a8b9c"),
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(cmRegistryRewrites, funcMinimal, scheme),
+			rtesting.NewTrackRequest(testServiceAccount, funcMinimal, scheme),
+			rtesting.NewTrackRequest(cmBuilders, funcMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(funcValid, scheme, corev1.EventTypeNormal, "Created",
+				`Created Image "%s-function-001"`, testName),
+			rtesting.NewEvent(funcValid, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectCreates: []rtesting.Factory{
+			kpackImageCreate.
+				Builder("ClusterBuilder", "my-cluster-builder"),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			funcMinimal.
+				StatusConditions(
+					functionConditionImageResolved.True(),
+					functionConditionKpackImageReady.Unknown(),
+					functionConditionReady.Unknown(),
+				).
+				StatusKpackImageRef("%s-function-001", testName).
+				StatusTargetImage("%s/%s", testImagePrefix, testName).
+				StatusBuilder("ClusterBuilder", "my-cluster-builder", "example.com/my-cluster-builder@sha256:7a8b9c"),
+		},
+	}, {
+		Name: "create kpack image, cluster builder not found",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			funcValid.
+				Builder("ClusterBuilder", "my-cluster-builder"),
+			cmBuilders,
+		},
+		ShouldErr: true,
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(cmRegistryRewrites, funcMinimal, scheme),
+			rtesting.NewTrackRequest(testServiceAccount, funcMinimal, scheme),
+			rtesting.NewTrackRequest(cmBuilders, funcMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(funcValid, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			funcMinimal.
+				StatusConditions(
+					functionConditionImageResolved.True(),
+					functionConditionKpackImageReady.False().Reason("BuilderNotFound", `ClusterBuilder "my-cluster-builder" not found`),
+					functionConditionReady.False().Reason("BuilderNotFound", `ClusterBuilder "my-cluster-builder" not found`),
+				).
+				StatusTargetImage("%s/%s", testImagePrefix, testName),
+		},
+	}, {
+		Name: "default image",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			cmImagePrefix.
+				AddData("default-image-prefix", testImagePrefix),
+			funcMinimal.
+				SourceGit(testGitUrl, testGitRevision),
+			cmBuilders,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(cmRegistryRewrites, funcMinimal, scheme),
+			rtesting.NewTrackRequest(testServiceAccount, funcMinimal, scheme),
+			rtesting.NewTrackRequest(cmBuilders, funcMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(funcValid, scheme, corev1.EventTypeNormal, "Created",
+				`Created Image "%s-function-001"`, testName),
+			rtesting.NewEvent(funcValid, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectCreates: []rtesting.Factory{
+			kpackImageCreate,
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			funcMinimal.
+				StatusConditions(
+					functionConditionImageResolved.True(),
+					functionConditionKpackImageReady.Unknown(),
+					functionConditionReady.Unknown(),
+				).
+				StatusKpackImageRef("%s-function-001", testName).
+				StatusTargetImage("%s/%s", testImagePrefix, testName).
+				StatusBuilder("ClusterBuilder", "riff-function", testBuilderImage),
+		},
+	}, {
+		Name: "default image, cluster default",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			cmClusterImagePrefix.
+				AddData("default-image-prefix", testImagePrefix),
+			funcMinimal.
+				SourceGit(testGitUrl, testGitRevision),
+			cmBuilders,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(cmRegistryRewrites, funcMinimal, scheme),
+			rtesting.NewTrackRequest(testServiceAccount, funcMinimal, scheme),
+			rtesting.NewTrackRequest(cmBuilders, funcMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(funcValid, scheme, corev1.EventTypeNormal, "Created",
+				`Created Image "%s-function-001"`, testName),
+			rtesting.NewEvent(funcValid, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectCreates: []rtesting.Factory{
+			kpackImageCreate,
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			funcMinimal.
+				StatusConditions(
+					functionConditionImageResolved.True(),
+					functionConditionKpackImageReady.Unknown(),
+					functionConditionReady.Unknown(),
+				).
+				StatusKpackImageRef("%s-function-001", testName).
+				StatusTargetImage("%s/%s", testImagePrefix, testName).
+				StatusBuilder("ClusterBuilder", "riff-function", testBuilderImage),
+		},
+	}, {
+		Name: "default image, namespace overrides cluster default",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			cmImagePrefix.
+				AddData("default-image-prefix", testImagePrefix),
+			cmClusterImagePrefix.
+				AddData("default-image-prefix", "example.com/cluster"),
+			funcMinimal.
+				SourceGit(testGitUrl, testGitRevision),
+			cmBuilders,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(cmRegistryRewrites, funcMinimal, scheme),
+			rtesting.NewTrackRequest(testServiceAccount, funcMinimal, scheme),
+			rtesting.NewTrackRequest(cmBuilders, funcMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(funcValid, scheme, corev1.EventTypeNormal, "Created",
+				`Created Image "%s-function-001"`, testName),
+			rtesting.NewEvent(funcValid, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectCreates: []rtesting.Factory{
+			kpackImageCreate,
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			funcMinimal.
+				StatusConditions(
+					functionConditionImageResolved.True(),
+					functionConditionKpackImageReady.Unknown(),
+					functionConditionReady.Unknown(),
+				).
+				StatusKpackImageRef("%s-function-001", testName).
+				StatusTargetImage("%s/%s", testImagePrefix, testName).
+				StatusBuilder("ClusterBuilder", "riff-function", testBuilderImage),
+		},
+	}, {
+		Name: "default image, template",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			cmImagePrefix.
+				AddData("default-image-prefix", testImagePrefix),
+			cmClusterImagePrefix.
+				AddData("default-image-template", "{{.Registry}}/{{.Namespace}}/{{.Kind}}-{{.Name}}"),
+			funcMinimal.
+				SourceGit(testGitUrl, testGitRevision),
+			cmBuilders,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(cmRegistryRewrites, funcMinimal, scheme),
+			rtesting.NewTrackRequest(testServiceAccount, funcMinimal, scheme),
+			rtesting.NewTrackRequest(cmBuilders, funcMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(funcValid, scheme, corev1.EventTypeNormal, "Created",
+				`Created Image "%s-function-001"`, testName),
+			rtesting.NewEvent(funcValid, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectCreates: []rtesting.Factory{
+			kpackImageCreate.
+				Tag("%s/%s/function-%s", testImagePrefix, testNamespace, testName),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			funcMinimal.
+				StatusConditions(
+					functionConditionImageResolved.True(),
+					functionConditionKpackImageReady.Unknown(),
+					functionConditionReady.Unknown(),
+				).
+				StatusKpackImageRef("%s-function-001", testName).
+				StatusTargetImage("%s/%s/function-%s", testImagePrefix, testNamespace, testName).
+				StatusBuilder("ClusterBuilder", "riff-function", testBuilderImage),
+		},
+	}, {
+		Name: "default image, missing",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			funcMinimal.
+				SourceGit(testGitUrl, testGitRevision),
+		},
+		ShouldErr: true,
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(cmRegistryRewrites, funcMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(funcValid, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			funcMinimal.
+				StatusConditions(
+					functionConditionImageResolved.False().Reason("DefaultImagePrefixMissing", "missing default image prefix"),
+					functionConditionKpackImageReady.Unknown(),
+					functionConditionReady.False().Reason("DefaultImagePrefixMissing", "missing default image prefix"),
+				),
+		},
+	}, {
+		Name: "default image, undefined",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			cmImagePrefix,
+			funcMinimal.
+				SourceGit(testGitUrl, testGitRevision),
+		},
+		ShouldErr: true,
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(cmRegistryRewrites, funcMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(funcValid, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			funcMinimal.
+				StatusConditions(
+					functionConditionImageResolved.False().Reason("DefaultImagePrefixMissing", "missing default image prefix"),
+					functionConditionKpackImageReady.Unknown(),
+					functionConditionReady.False().Reason("DefaultImagePrefixMissing", "missing default image prefix"),
+				),
+		},
+	}, {
+		Name: "default image, error",
+		Key:  testKey,
+		WithReactors: []rtesting.ReactionFunc{
+			rtesting.InduceFailure("get", "ConfigMap"),
+		},
+		GivenObjects: []rtesting.Factory{
+			cmImagePrefix,
+			funcMinimal.
+				SourceGit(testGitUrl, testGitRevision),
+		},
+		ShouldErr: true,
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(cmRegistryRewrites, funcMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(funcValid, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			funcMinimal.
+				StatusConditions(
+					functionConditionImageResolved.False().Reason("ImageInvalid", "inducing failure for get ConfigMap"),
+					functionConditionKpackImageReady.Unknown(),
+					functionConditionReady.False().Reason("ImageInvalid", "inducing failure for get ConfigMap"),
+				),
+		},
+	}, {
+		Name: "kpack image ready",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			funcValid,
+			testServiceAccount,
+			kpackImageGiven.
+				StatusReady().
+				StatusLatestImage("%s/%s@sha256:%s", testImagePrefix, testName, testSha256),
+			cmBuilders,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(cmRegistryRewrites, funcMinimal, scheme),
+			rtesting.NewTrackRequest(testServiceAccount, funcMinimal, scheme),
+			rtesting.NewTrackRequest(cmBuilders, funcMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(funcValid, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			funcMinimal.
+				StatusConditions(
+					functionConditionImageResolved.True(),
+					functionConditionKpackImageReady.True(),
+					functionConditionReady.True(),
+				).
+				StatusKpackImageRef(kpackImageGiven.Create().GetName()).
+				StatusTargetImage("%s/%s", testImagePrefix, testName).
+				StatusBuilder("ClusterBuilder", "riff-function", testBuilderImage).
+				StatusLatestImage("%s/%s@sha256:%s", testImagePrefix, testName, testSha256).
+				StatusBuildMetadata(testBuildMetadata).
+				StatusInvoker("node"),
+		},
+	}, {
+		Name: "kpack image ready, build history",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			funcValid,
+			testServiceAccount,
+			kpackImageGiven.
+				StatusReady().
+				StatusLatestImage("%s/%s@sha256:%s", testImagePrefix, testName, testSha256),
+			kpackBuildGiven.
+				NamespaceName(testNamespace, "build-1").
+				Image(kpackImageGiven.Create().GetName(), 1, "CONFIG").
+				SourceGit("https://example.com/repo.git", "abc123").
+				BuilderImage(testBuilderImage).
+				StatusSucceeded(20).
+				StatusPodName("build-1-pod"),
+			kpackBuildGiven.
+				NamespaceName(testNamespace, "build-2").
+				Image(kpackImageGiven.Create().GetName(), 2, "COMMIT").
+				SourceGit("https://example.com/repo.git", "def456").
+				BuilderImage(testBuilderImage).
+				StatusFailed(40, "BuildFailed", "build step failed").
+				StatusPodName("build-2-pod"),
+			kpackBuildGiven.
+				NamespaceName(testNamespace, "build-3").
+				Image(kpackImageGiven.Create().GetName(), 3, "STACK").
+				SourceGit("https://example.com/repo.git", "def456").
+				BuilderImage(testBuilderImage).
+				StatusRunning().
+				StatusPodName("build-3-pod"),
+			kpackBuildGiven.
+				NamespaceName(testNamespace, "other-build-1").
+				Image("other-image", 1, "CONFIG"),
+			cmBuilders,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(cmRegistryRewrites, funcMinimal, scheme),
+			rtesting.NewTrackRequest(testServiceAccount, funcMinimal, scheme),
+			rtesting.NewTrackRequest(cmBuilders, funcMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(funcValid, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			funcMinimal.
+				StatusConditions(
+					functionConditionImageResolved.True(),
+					functionConditionKpackImageReady.True(),
+					functionConditionReady.True(),
+					functionConditionUpToDate.True().Info(),
+				).
+				StatusKpackImageRef(kpackImageGiven.Create().GetName()).
+				StatusTargetImage("%s/%s", testImagePrefix, testName).
+				StatusBuilder("ClusterBuilder", "riff-function", testBuilderImage).
+				StatusLatestImage("%s/%s@sha256:%s", testImagePrefix, testName, testSha256).
+				StatusBuildMetadata(testBuildMetadata).
+				StatusInvoker("node").
+				StatusBuilds(
+					buildv1alpha1.BuildSummary{
+						Name:         "build-3",
+						BuildNumber:  3,
+						Revision:     "def456",
+						Reason:       "STACK",
+						StartTime:    &buildCreated,
+						Outcome:      buildv1alpha1.BuildOutcomeRunning,
+						PodName:      "build-3-pod",
+						BuilderImage: testBuilderImage,
+					},
+					buildv1alpha1.BuildSummary{
+						Name:           "build-2",
+						BuildNumber:    2,
+						Revision:       "def456",
+						Reason:         "COMMIT",
+						StartTime:      &buildCreated,
+						CompletionTime: &build2Completed,
+						Outcome:        buildv1alpha1.BuildOutcomeFailed,
+						Message:        "build step failed",
+						PodName:        "build-2-pod",
+						BuilderImage:   testBuilderImage,
+					},
+					buildv1alpha1.BuildSummary{
+						Name:           "build-1",
+						BuildNumber:    1,
+						Revision:       "abc123",
+						Reason:         "CONFIG",
+						StartTime:      &buildCreated,
+						CompletionTime: &build1Completed,
+						Outcome:        buildv1alpha1.BuildOutcomeSucceeded,
+						PodName:        "build-1-pod",
+						BuilderImage:   testBuilderImage,
+					},
+				),
 		},
 	}, {
-		Name: "create kpack image, build cache",
+		Name: "kpack image ready, builder outdated",
 		Key:  testKey,
 		GivenObjects: []rtesting.Factory{
-			funcValid.
-				BuildCache("1Gi"),
+			funcValid,
+			testServiceAccount,
+			kpackImageGiven.
+				StatusReady().
+				StatusLatestImage("%s/%s@sha256:%s", testImagePrefix, testName, testSha256),
+			kpackBuildGiven.
+				NamespaceName(testNamespace, "build-1").
+				Image(kpackImageGiven.Create().GetName(), 1, "CONFIG").
+				BuilderImage("example.com/builder@sha256:0a0b0c").
+				StatusSucceeded(20),
+			cmBuilders,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(cmRegistryRewrites, funcMinimal, scheme),
+			rtesting.NewTrackRequest(testServiceAccount, funcMinimal, scheme),
+			rtesting.NewTrackRequest(cmBuilders, funcMinimal, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
-			rtesting.NewEvent(funcValid, scheme, corev1.EventTypeNormal, "Created",
-				`Created Image "%s-function-001"`, testName),
 			rtesting.NewEvent(funcValid, scheme, corev1.EventTypeNormal, "StatusUpdated",
 				`Updated status`),
 		},
-		ExpectCreates: []rtesting.Factory{
-			kpackImageCreate.
-				BuildCache("1Gi"),
-		},
 		ExpectStatusUpdates: []rtesting.Factory{
 			funcMinimal.
 				StatusConditions(
 					functionConditionImageResolved.True(),
-					functionConditionKpackImageReady.Unknown(),
-					functionConditionReady.Unknown(),
+					functionConditionKpackImageReady.True(),
+					functionConditionReady.True(),
+					functionConditionUpToDate.False().Info().Reason("BuilderOutdated", `latest image was built with builder image "example.com/builder@sha256:0a0b0c", the builder image is now "example.com/builder@sha256:1a2b3c"`),
 				).
-				StatusKpackImageRef("%s-function-001", testName).
-				StatusTargetImage("%s/%s", testImagePrefix, testName),
+				StatusKpackImageRef(kpackImageGiven.Create().GetName()).
+				StatusTargetImage("%s/%s", testImagePrefix, testName).
+				StatusBuilder("ClusterBuilder", "riff-function", testBuilderImage).
+				StatusLatestImage("%s/%s@sha256:%s", testImagePrefix, testName, testSha256).
+				StatusBuildMetadata(testBuildMetadata).
+				StatusInvoker("node").
+				StatusBuilds(
+					buildv1alpha1.BuildSummary{
+						Name:           "build-1",
+						BuildNumber:    1,
+						Reason:         "CONFIG",
+						StartTime:      &buildCreated,
+						CompletionTime: &build1Completed,
+						Outcome:        buildv1alpha1.BuildOutcomeSucceeded,
+						BuilderImage:   "example.com/builder@sha256:0a0b0c",
+					},
+				),
 		},
 	}, {
-		Name: "create kpack image, propagating labels",
+		Name: "kpack image ready, rebuild running",
 		Key:  testKey,
 		GivenObjects: []rtesting.Factory{
-			funcValid.
-				ObjectMeta(func(om factories.ObjectMeta) {
-					om.AddLabel(testLabelKey, testLabelValue)
-				}),
+			funcValid,
+			testServiceAccount,
+			kpackImageGiven.
+				StatusReady().
+				StatusLatestImage("%s/%s@sha256:%s", testImagePrefix, testName, testSha256),
+			kpackBuildGiven.
+				NamespaceName(testNamespace, "build-1").
+				Image(kpackImageGiven.Create().GetName(), 1, "CONFIG").
+				BuilderImage("example.com/builder@sha256:0a0b0c").
+				StatusSucceeded(20),
+			kpackBuildGiven.
+				NamespaceName(testNamespace, "build-2").
+				Image(kpackImageGiven.Create().GetName(), 2, "BUILDPACK").
+				BuilderImage(testBuilderImage).
+				StatusRunning(),
+			cmBuilders,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(cmRegistryRewrites, funcMinimal, scheme),
+			rtesting.NewTrackRequest(testServiceAccount, funcMinimal, scheme),
+			rtesting.NewTrackRequest(cmBuilders, funcMinimal, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
-			rtesting.NewEvent(funcValid, scheme, corev1.EventTypeNormal, "Created",
-				`Created Image "%s-function-001"`, testName),
 			rtesting.NewEvent(funcValid, scheme, corev1.EventTypeNormal, "StatusUpdated",
 				`Updated status`),
 		},
-		ExpectCreates: []rtesting.Factory{
-			kpackImageCreate.
-				ObjectMeta(func(om factories.ObjectMeta) {
-					om.AddLabel(testLabelKey, testLabelValue)
-				}),
-		},
 		ExpectStatusUpdates: []rtesting.Factory{
 			funcMinimal.
 				StatusConditions(
 					functionConditionImageResolved.True(),
-					functionConditionKpackImageReady.Unknown(),
-					functionConditionReady.Unknown(),
+					functionConditionKpackImageReady.True(),
+					functionConditionReady.True(),
+					functionConditionUpToDate.Unknown().Info().Reason("RebuildRunning", `build "build-2" is using builder image "example.com/builder@sha256:1a2b3c"`),
 				).
-				StatusKpackImageRef("%s-function-001", testName).
-				StatusTargetImage("%s/%s", testImagePrefix, testName),
+				StatusKpackImageRef(kpackImageGiven.Create().GetName()).
+				StatusTargetImage("%s/%s", testImagePrefix, testName).
+				StatusBuilder("ClusterBuilder", "riff-function", testBuilderImage).
+				StatusLatestImage("%s/%s@sha256:%s", testImagePrefix, testName, testSha256).
+				StatusBuildMetadata(testBuildMetadata).
+				StatusInvoker("node").
+				StatusBuilds(
+					buildv1alpha1.BuildSummary{
+						Name:         "build-2",
+						BuildNumber:  2,
+						Reason:       "BUILDPACK",
+						StartTime:    &buildCreated,
+						Outcome:      buildv1alpha1.BuildOutcomeRunning,
+						BuilderImage: testBuilderImage,
+					},
+					buildv1alpha1.BuildSummary{
+						Name:           "build-1",
+						BuildNumber:    1,
+						Reason:         "CONFIG",
+						StartTime:      &buildCreated,
+						CompletionTime: &build1Completed,
+						Outcome:        buildv1alpha1.BuildOutcomeSucceeded,
+						BuilderImage:   "example.com/builder@sha256:0a0b0c",
+					},
+				),
 		},
 	}, {
-		Name: "default image",
+		Name: "kpack image ready, build history list error",
 		Key:  testKey,
+		WithReactors: []rtesting.ReactionFunc{
+			rtesting.InduceFailure("list", "BuildList"),
+		},
 		GivenObjects: []rtesting.Factory{
-			cmImagePrefix.
-				AddData("default-image-prefix", testImagePrefix),
-			funcMinimal.
-				SourceGit(testGitUrl, testGitRevision),
+			funcValid,
+			testServiceAccount,
+			kpackImageGiven.
+				StatusReady().
+				StatusLatestImage("%s/%s@sha256:%s", testImagePrefix, testName, testSha256),
+			cmBuilders,
+		},
+		ShouldErr: true,
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(cmRegistryRewrites, funcMinimal, scheme),
+			rtesting.NewTrackRequest(testServiceAccount, funcMinimal, scheme),
+			rtesting.NewTrackRequest(cmBuilders, funcMinimal, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
-			rtesting.NewEvent(funcValid, scheme, corev1.EventTypeNormal, "Created",
-				`Created Image "%s-function-001"`, testName),
 			rtesting.NewEvent(funcValid, scheme, corev1.EventTypeNormal, "StatusUpdated",
 				`Updated status`),
 		},
-		ExpectCreates: []rtesting.Factory{
-			kpackImageCreate,
-		},
 		ExpectStatusUpdates: []rtesting.Factory{
 			funcMinimal.
 				StatusConditions(
 					functionConditionImageResolved.True(),
-					functionConditionKpackImageReady.Unknown(),
-					functionConditionReady.Unknown(),
+					functionConditionKpackImageReady.True(),
+					functionConditionReady.True(),
 				).
-				StatusKpackImageRef("%s-function-001", testName).
-				StatusTargetImage("%s/%s", testImagePrefix, testName),
+				StatusKpackImageRef(kpackImageGiven.Create().GetName()).
+				StatusTargetImage("%s/%s", testImagePrefix, testName).
+				StatusBuilder("ClusterBuilder", "riff-function", testBuilderImage).
+				StatusLatestImage("%s/%s@sha256:%s", testImagePrefix, testName, testSha256).
+				StatusBuildMetadata(testBuildMetadata).
+				StatusInvoker("node"),
 		},
 	}, {
-		Name: "default image, missing",
+		Name: "kpack image ready, build cache",
 		Key:  testKey,
 		GivenObjects: []rtesting.Factory{
-			funcMinimal.
-				SourceGit(testGitUrl, testGitRevision),
+			funcValid,
+			testServiceAccount,
+			kpackImageGiven.
+				StatusReady().
+				StatusBuildCacheName(testBuildCacheName).
+				StatusLatestImage("%s/%s@sha256:%s", testImagePrefix, testName, testSha256),
+			testBuildCache,
+			cmBuilders,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(cmRegistryRewrites, funcMinimal, scheme),
+			rtesting.NewTrackRequest(testServiceAccount, funcMinimal, scheme),
+			rtesting.NewTrackRequest(cmBuilders, funcMinimal, scheme),
+			rtesting.NewTrackRequest(testBuildCache, funcMinimal, scheme),
 		},
-		ShouldErr: true,
 		ExpectEvents: []rtesting.Event{
 			rtesting.NewEvent(funcValid, scheme, corev1.EventTypeNormal, "StatusUpdated",
 				`Updated status`),
@@ -270,44 +1119,91 @@ func TestFunctionReconciler(t *testing.T) {
 		ExpectStatusUpdates: []rtesting.Factory{
 			funcMinimal.
 				StatusConditions(
-					functionConditionImageResolved.False().Reason("DefaultImagePrefixMissing", "missing default image prefix"),
-					functionConditionKpackImageReady.Unknown(),
-					functionConditionReady.False().Reason("DefaultImagePrefixMissing", "missing default image prefix"),
-				),
+					functionConditionImageResolved.True(),
+					functionConditionKpackImageReady.True(),
+					functionConditionReady.True(),
+				).
+				StatusKpackImageRef(kpackImageGiven.Create().GetName()).
+				StatusBuildCacheRef(testBuildCacheName).
+				StatusBuildCache(&buildv1alpha1.BuildCacheStatus{
+					Requested:    &buildCacheSize,
+					Capacity:     &buildCacheSize,
+					CreationTime: &buildCacheCreated,
+				}).
+				StatusTargetImage("%s/%s", testImagePrefix, testName).
+				StatusBuilder("ClusterBuilder", "riff-function", testBuilderImage).
+				StatusLatestImage("%s/%s@sha256:%s", testImagePrefix, testName, testSha256).
+				StatusBuildMetadata(testBuildMetadata).
+				StatusInvoker("node"),
 		},
 	}, {
-		Name: "default image, undefined",
+		Name: "build cache, clear requested",
 		Key:  testKey,
 		GivenObjects: []rtesting.Factory{
-			cmImagePrefix,
-			funcMinimal.
-				SourceGit(testGitUrl, testGitRevision),
+			funcValid.
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.AddAnnotation(buildv1alpha1.ClearBuildCacheAnnotationKey, "1")
+				}),
+			testServiceAccount,
+			kpackImageGiven.
+				StatusReady().
+				StatusBuildCacheName(testBuildCacheName).
+				StatusLatestImage("%s/%s@sha256:%s", testImagePrefix, testName, testSha256),
+			testBuildCache,
+			cmBuilders,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(cmRegistryRewrites, funcMinimal, scheme),
+			rtesting.NewTrackRequest(testServiceAccount, funcMinimal, scheme),
+			rtesting.NewTrackRequest(cmBuilders, funcMinimal, scheme),
+			rtesting.NewTrackRequest(testBuildCache, funcMinimal, scheme),
 		},
-		ShouldErr: true,
 		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(funcValid, scheme, corev1.EventTypeNormal, "Reset",
+				`Reset build cache "%s", reason Requested`, testBuildCacheName),
 			rtesting.NewEvent(funcValid, scheme, corev1.EventTypeNormal, "StatusUpdated",
 				`Updated status`),
 		},
+		ExpectDeletes: []rtesting.DeleteRef{
+			{Kind: "PersistentVolumeClaim", Namespace: testNamespace, Name: testBuildCacheName},
+		},
 		ExpectStatusUpdates: []rtesting.Factory{
 			funcMinimal.
 				StatusConditions(
-					functionConditionImageResolved.False().Reason("DefaultImagePrefixMissing", "missing default image prefix"),
-					functionConditionKpackImageReady.Unknown(),
-					functionConditionReady.False().Reason("DefaultImagePrefixMissing", "missing default image prefix"),
-				),
+					functionConditionImageResolved.True(),
+					functionConditionKpackImageReady.True(),
+					functionConditionReady.True(),
+				).
+				StatusKpackImageRef(kpackImageGiven.Create().GetName()).
+				StatusBuildCacheRef(testBuildCacheName).
+				StatusBuildCache(&buildv1alpha1.BuildCacheStatus{
+					LastResetReason: buildv1alpha1.BuildCacheResetRequested,
+					ClearRequest:    "1",
+				}).
+				StatusTargetImage("%s/%s", testImagePrefix, testName).
+				StatusBuilder("ClusterBuilder", "riff-function", testBuilderImage).
+				StatusLatestImage("%s/%s@sha256:%s", testImagePrefix, testName, testSha256).
+				StatusBuildMetadata(testBuildMetadata).
+				StatusInvoker("node"),
 		},
 	}, {
-		Name: "default image, error",
+		Name: "kpack image ready, build metadata current",
 		Key:  testKey,
-		WithReactors: []rtesting.ReactionFunc{
-			rtesting.InduceFailure("get", "ConfigMap"),
-		},
 		GivenObjects: []rtesting.Factory{
-			cmImagePrefix,
-			funcMinimal.
-				SourceGit(testGitUrl, testGitRevision),
+			funcValid.
+				StatusLatestImage("%s/%s@sha256:%s", testImagePrefix, testName, testSha256).
+				StatusBuildMetadata(testBuildMetadata).
+				StatusInvoker("node"),
+			kpackImageGiven.
+				StatusReady().
+				StatusLatestImage("%s/%s@sha256:%s", testImagePrefix, testName, testSha256),
+			cmBuilders,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(cmRegistryRewrites, funcMinimal, scheme),
+			rtesting.NewTrackRequest(testServiceAccount, funcMinimal, scheme),
+			rtesting.NewTrackRequest(cmBuilders, funcMinimal, scheme),
 		},
-		ShouldErr: true,
 		ExpectEvents: []rtesting.Event{
 			rtesting.NewEvent(funcValid, scheme, corev1.EventTypeNormal, "StatusUpdated",
 				`Updated status`),
@@ -315,19 +1211,38 @@ func TestFunctionReconciler(t *testing.T) {
 		ExpectStatusUpdates: []rtesting.Factory{
 			funcMinimal.
 				StatusConditions(
-					functionConditionImageResolved.False().Reason("ImageInvalid", "inducing failure for get ConfigMap"),
-					functionConditionKpackImageReady.Unknown(),
-					functionConditionReady.False().Reason("ImageInvalid", "inducing failure for get ConfigMap"),
-				),
+					functionConditionImageResolved.True(),
+					functionConditionKpackImageReady.True(),
+					functionConditionReady.True(),
+				).
+				StatusKpackImageRef(kpackImageGiven.Create().GetName()).
+				StatusTargetImage("%s/%s", testImagePrefix, testName).
+				StatusBuilder("ClusterBuilder", "riff-function", testBuilderImage).
+				StatusLatestImage("%s/%s@sha256:%s", testImagePrefix, testName, testSha256).
+				StatusBuildMetadata(testBuildMetadata).
+				StatusInvoker("node"),
 		},
 	}, {
-		Name: "kpack image ready",
+		Name: "credentials bound, not working",
 		Key:  testKey,
 		GivenObjects: []rtesting.Factory{
 			funcValid,
 			kpackImageGiven.
 				StatusReady().
 				StatusLatestImage("%s/%s@sha256:%s", testImagePrefix, testName, testSha256),
+			testCredential.
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.AddAnnotation(buildv1alpha1.CredentialErrorAnnotationKey, "UNAUTHORIZED")
+				}),
+			testServiceAccount.
+				Secrets(testCredential.Create().GetName()),
+			cmBuilders,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(cmRegistryRewrites, funcMinimal, scheme),
+			rtesting.NewTrackRequest(testServiceAccount, funcMinimal, scheme),
+			rtesting.NewTrackRequest(testCredential, funcValid, scheme),
+			rtesting.NewTrackRequest(cmBuilders, funcMinimal, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
 			rtesting.NewEvent(funcValid, scheme, corev1.EventTypeNormal, "StatusUpdated",
@@ -336,23 +1251,41 @@ func TestFunctionReconciler(t *testing.T) {
 		ExpectStatusUpdates: []rtesting.Factory{
 			funcMinimal.
 				StatusConditions(
+					functionConditionCredentialsReady.False().Info().Reason("CredentialsInvalid", fmt.Sprintf(`no working credential for registry "%s"; credential "my-credential": UNAUTHORIZED`, testRegistry.Host)),
 					functionConditionImageResolved.True(),
 					functionConditionKpackImageReady.True(),
 					functionConditionReady.True(),
 				).
 				StatusKpackImageRef(kpackImageGiven.Create().GetName()).
 				StatusTargetImage("%s/%s", testImagePrefix, testName).
-				StatusLatestImage("%s/%s@sha256:%s", testImagePrefix, testName, testSha256),
+				StatusBuilder("ClusterBuilder", "riff-function", testBuilderImage).
+				StatusLatestImage("%s/%s@sha256:%s", testImagePrefix, testName, testSha256).
+				StatusBuildMetadata(testBuildMetadata).
+				StatusInvoker("node"),
 		},
 	}, {
-		Name: "kpack image ready, build cache",
+		Name: "credentials bound, working",
 		Key:  testKey,
 		GivenObjects: []rtesting.Factory{
 			funcValid,
 			kpackImageGiven.
 				StatusReady().
-				StatusBuildCacheName(testBuildCacheName).
 				StatusLatestImage("%s/%s@sha256:%s", testImagePrefix, testName, testSha256),
+			testCredential,
+			testCredential.
+				NamespaceName(testNamespace, "other-registry").
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.AddAnnotation("build.pivotal.io/docker", "https://gcr.io")
+				}),
+			testServiceAccount.
+				Secrets(testCredential.Create().GetName(), "other-registry"),
+			cmBuilders,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(cmRegistryRewrites, funcMinimal, scheme),
+			rtesting.NewTrackRequest(testServiceAccount, funcMinimal, scheme),
+			rtesting.NewTrackRequest(testCredential, funcValid, scheme),
+			rtesting.NewTrackRequest(cmBuilders, funcMinimal, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
 			rtesting.NewEvent(funcValid, scheme, corev1.EventTypeNormal, "StatusUpdated",
@@ -361,25 +1294,35 @@ func TestFunctionReconciler(t *testing.T) {
 		ExpectStatusUpdates: []rtesting.Factory{
 			funcMinimal.
 				StatusConditions(
+					functionConditionCredentialsReady.True().Info(),
 					functionConditionImageResolved.True(),
 					functionConditionKpackImageReady.True(),
 					functionConditionReady.True(),
 				).
 				StatusKpackImageRef(kpackImageGiven.Create().GetName()).
-				StatusBuildCacheRef(testBuildCacheName).
 				StatusTargetImage("%s/%s", testImagePrefix, testName).
-				StatusLatestImage("%s/%s@sha256:%s", testImagePrefix, testName, testSha256),
+				StatusBuilder("ClusterBuilder", "riff-function", testBuilderImage).
+				StatusLatestImage("%s/%s@sha256:%s", testImagePrefix, testName, testSha256).
+				StatusBuildMetadata(testBuildMetadata).
+				StatusInvoker("node"),
 		},
 	}, {
 		Name: "kpack image not-ready",
 		Key:  testKey,
 		GivenObjects: []rtesting.Factory{
 			funcValid,
+			testServiceAccount,
 			kpackImageGiven.
 				StatusConditions(
 					factories.Condition().Type(apis.ConditionReady).False().Reason(testConditionReason, testConditionMessage),
 				).
 				StatusLatestImage("%s/%s@sha256:%s", testImagePrefix, testName, testSha256),
+			cmBuilders,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(cmRegistryRewrites, funcMinimal, scheme),
+			rtesting.NewTrackRequest(testServiceAccount, funcMinimal, scheme),
+			rtesting.NewTrackRequest(cmBuilders, funcMinimal, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
 			rtesting.NewEvent(funcValid, scheme, corev1.EventTypeNormal, "StatusUpdated",
@@ -394,7 +1337,10 @@ func TestFunctionReconciler(t *testing.T) {
 				).
 				StatusKpackImageRef(kpackImageGiven.Create().GetName()).
 				StatusTargetImage("%s/%s", testImagePrefix, testName).
-				StatusLatestImage("%s/%s@sha256:%s", testImagePrefix, testName, testSha256),
+				StatusBuilder("ClusterBuilder", "riff-function", testBuilderImage).
+				StatusLatestImage("%s/%s@sha256:%s", testImagePrefix, testName, testSha256).
+				StatusBuildMetadata(testBuildMetadata).
+				StatusInvoker("node"),
 		},
 	}, {
 		Name: "kpack image create error",
@@ -404,6 +1350,12 @@ func TestFunctionReconciler(t *testing.T) {
 		},
 		GivenObjects: []rtesting.Factory{
 			funcValid,
+			cmBuilders,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(cmRegistryRewrites, funcMinimal, scheme),
+			rtesting.NewTrackRequest(testServiceAccount, funcMinimal, scheme),
+			rtesting.NewTrackRequest(cmBuilders, funcMinimal, scheme),
 		},
 		ShouldErr: true,
 		ExpectEvents: []rtesting.Event{
@@ -422,7 +1374,8 @@ func TestFunctionReconciler(t *testing.T) {
 					functionConditionKpackImageReady.Unknown(),
 					functionConditionReady.Unknown(),
 				).
-				StatusTargetImage("%s/%s", testImagePrefix, testName),
+				StatusTargetImage("%s/%s", testImagePrefix, testName).
+				StatusBuilder("ClusterBuilder", "riff-function", testBuilderImage),
 		},
 	}, {
 		Name: "kpack image update, spec",
@@ -431,6 +1384,12 @@ func TestFunctionReconciler(t *testing.T) {
 			funcValid,
 			kpackImageGiven.
 				SourceGit(testGitUrl, "bogus"),
+			cmBuilders,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(cmRegistryRewrites, funcMinimal, scheme),
+			rtesting.NewTrackRequest(testServiceAccount, funcMinimal, scheme),
+			rtesting.NewTrackRequest(cmBuilders, funcMinimal, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
 			rtesting.NewEvent(funcValid, scheme, corev1.EventTypeNormal, "Updated",
@@ -449,7 +1408,8 @@ func TestFunctionReconciler(t *testing.T) {
 					functionConditionReady.Unknown(),
 				).
 				StatusKpackImageRef(kpackImageGiven.Create().GetName()).
-				StatusTargetImage("%s/%s", testImagePrefix, testName),
+				StatusTargetImage("%s/%s", testImagePrefix, testName).
+				StatusBuilder("ClusterBuilder", "riff-function", testBuilderImage),
 		},
 	}, {
 		Name: "kpack image update, labels",
@@ -460,6 +1420,12 @@ func TestFunctionReconciler(t *testing.T) {
 					om.AddLabel(testLabelKey, testLabelValue)
 				}),
 			kpackImageGiven,
+			cmBuilders,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(cmRegistryRewrites, funcMinimal, scheme),
+			rtesting.NewTrackRequest(testServiceAccount, funcMinimal, scheme),
+			rtesting.NewTrackRequest(cmBuilders, funcMinimal, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
 			rtesting.NewEvent(funcValid, scheme, corev1.EventTypeNormal, "Updated",
@@ -481,7 +1447,45 @@ func TestFunctionReconciler(t *testing.T) {
 					functionConditionReady.Unknown(),
 				).
 				StatusKpackImageRef(kpackImageGiven.Create().GetName()).
-				StatusTargetImage("%s/%s", testImagePrefix, testName),
+				StatusTargetImage("%s/%s", testImagePrefix, testName).
+				StatusBuilder("ClusterBuilder", "riff-function", testBuilderImage),
+		},
+	}, {
+		Name: "kpack image update, rebuild policy",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			funcValid.
+				RebuildPolicy(buildv1alpha1.RebuildPolicyBuilderUpdated),
+			kpackImageGiven.
+				BuildEnv(corev1.EnvVar{Name: "RIFF_BUILDER_IMAGE", Value: "example.com/builder@sha256:0a0b0c"}),
+			cmBuilders,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(cmRegistryRewrites, funcMinimal, scheme),
+			rtesting.NewTrackRequest(testServiceAccount, funcMinimal, scheme),
+			rtesting.NewTrackRequest(cmBuilders, funcMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(funcValid, scheme, corev1.EventTypeNormal, "Updated",
+				`Updated Image "%s"`, kpackImageGiven.Create().GetName()),
+			rtesting.NewEvent(funcValid, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectUpdates: []rtesting.Factory{
+			// kpack rebuilds when the build env changes
+			kpackImageGiven.
+				BuildEnv(corev1.EnvVar{Name: "RIFF_BUILDER_IMAGE", Value: testBuilderImage}),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			funcValid.
+				StatusConditions(
+					functionConditionImageResolved.True(),
+					functionConditionKpackImageReady.Unknown(),
+					functionConditionReady.Unknown(),
+				).
+				StatusKpackImageRef(kpackImageGiven.Create().GetName()).
+				StatusTargetImage("%s/%s", testImagePrefix, testName).
+				StatusBuilder("ClusterBuilder", "riff-function", testBuilderImage),
 		},
 	}, {
 		Name: "kpack image update, fails",
@@ -493,6 +1497,12 @@ func TestFunctionReconciler(t *testing.T) {
 			funcValid,
 			kpackImageGiven.
 				SourceGit(testGitUrl, "bogus"),
+			cmBuilders,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(cmRegistryRewrites, funcMinimal, scheme),
+			rtesting.NewTrackRequest(testServiceAccount, funcMinimal, scheme),
+			rtesting.NewTrackRequest(cmBuilders, funcMinimal, scheme),
 		},
 		ShouldErr: true,
 		ExpectEvents: []rtesting.Event{
@@ -511,7 +1521,8 @@ func TestFunctionReconciler(t *testing.T) {
 					functionConditionKpackImageReady.Unknown(),
 					functionConditionReady.Unknown(),
 				).
-				StatusTargetImage("%s/%s", testImagePrefix, testName),
+				StatusTargetImage("%s/%s", testImagePrefix, testName).
+				StatusBuilder("ClusterBuilder", "riff-function", testBuilderImage),
 		},
 	}, {
 		Name: "kpack image list error",
@@ -521,6 +1532,12 @@ func TestFunctionReconciler(t *testing.T) {
 		},
 		GivenObjects: []rtesting.Factory{
 			funcValid,
+			cmBuilders,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(cmRegistryRewrites, funcMinimal, scheme),
+			rtesting.NewTrackRequest(testServiceAccount, funcMinimal, scheme),
+			rtesting.NewTrackRequest(cmBuilders, funcMinimal, scheme),
 		},
 		ShouldErr: true,
 		ExpectEvents: []rtesting.Event{
@@ -534,7 +1551,8 @@ func TestFunctionReconciler(t *testing.T) {
 					functionConditionKpackImageReady.Unknown(),
 					functionConditionReady.Unknown(),
 				).
-				StatusTargetImage("%s/%s", testImagePrefix, testName),
+				StatusTargetImage("%s/%s", testImagePrefix, testName).
+				StatusBuilder("ClusterBuilder", "riff-function", testBuilderImage),
 		},
 	}, {
 		Name: "function status update error",
@@ -544,6 +1562,12 @@ func TestFunctionReconciler(t *testing.T) {
 		},
 		GivenObjects: []rtesting.Factory{
 			funcValid,
+			cmBuilders,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(cmRegistryRewrites, funcMinimal, scheme),
+			rtesting.NewTrackRequest(testServiceAccount, funcMinimal, scheme),
+			rtesting.NewTrackRequest(cmBuilders, funcMinimal, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
 			rtesting.NewEvent(funcValid, scheme, corev1.EventTypeNormal, "Created",
@@ -562,7 +1586,8 @@ func TestFunctionReconciler(t *testing.T) {
 					functionConditionReady.Unknown(),
 				).
 				StatusKpackImageRef("%s-function-001", testName).
-				StatusTargetImage("%s/%s", testImagePrefix, testName),
+				StatusTargetImage("%s/%s", testImagePrefix, testName).
+				StatusBuilder("ClusterBuilder", "riff-function", testBuilderImage),
 		},
 		ShouldErr: true,
 	}, {
@@ -574,6 +1599,12 @@ func TestFunctionReconciler(t *testing.T) {
 				NamespaceName(testNamespace, "extra1"),
 			kpackImageGiven.
 				NamespaceName(testNamespace, "extra2"),
+			cmBuilders,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(cmRegistryRewrites, funcMinimal, scheme),
+			rtesting.NewTrackRequest(testServiceAccount, funcMinimal, scheme),
+			rtesting.NewTrackRequest(cmBuilders, funcMinimal, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
 			rtesting.NewEvent(funcValid, scheme, corev1.EventTypeNormal, "Deleted",
@@ -600,7 +1631,8 @@ func TestFunctionReconciler(t *testing.T) {
 					functionConditionReady.Unknown(),
 				).
 				StatusKpackImageRef("%s-function-001", testName).
-				StatusTargetImage("%s/%s", testImagePrefix, testName),
+				StatusTargetImage("%s/%s", testImagePrefix, testName).
+				StatusBuilder("ClusterBuilder", "riff-function", testBuilderImage),
 		},
 	}, {
 		Name: "delete extra kpack image, fails",
@@ -614,6 +1646,12 @@ func TestFunctionReconciler(t *testing.T) {
 				NamespaceName(testNamespace, "extra1"),
 			kpackImageGiven.
 				NamespaceName(testNamespace, "extra2"),
+			cmBuilders,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(cmRegistryRewrites, funcMinimal, scheme),
+			rtesting.NewTrackRequest(testServiceAccount, funcMinimal, scheme),
+			rtesting.NewTrackRequest(cmBuilders, funcMinimal, scheme),
 		},
 		ShouldErr: true,
 		ExpectEvents: []rtesting.Event{
@@ -632,14 +1670,20 @@ func TestFunctionReconciler(t *testing.T) {
 					functionConditionKpackImageReady.Unknown(),
 					functionConditionReady.Unknown(),
 				).
-				StatusTargetImage("%s/%s", testImagePrefix, testName),
+				StatusTargetImage("%s/%s", testImagePrefix, testName).
+				StatusBuilder("ClusterBuilder", "riff-function", testBuilderImage),
 		},
 	}, {
 		Name: "local build",
 		Key:  testKey,
 		GivenObjects: []rtesting.Factory{
 			funcMinimal.
-				Image("%s/%s", testImagePrefix, testName),
+				Image(testLocalImage),
+			testServiceAccount,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(cmRegistryRewrites, funcMinimal, scheme),
+			rtesting.NewTrackRequest(testServiceAccount, funcMinimal, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
 			rtesting.NewEvent(funcValid, scheme, corev1.EventTypeNormal, "StatusUpdated",
@@ -652,18 +1696,24 @@ func TestFunctionReconciler(t *testing.T) {
 					functionConditionKpackImageReady.True(),
 					functionConditionReady.True(),
 				).
-				StatusTargetImage("%s/%s", testImagePrefix, testName).
-				// TODO resolve to a digest
-				StatusLatestImage("%s/%s", testImagePrefix, testName),
+				StatusTargetImage(testLocalImage).
+				StatusLatestImage("%s@%s", testLocalImage, testLocalDigest).
+				StatusBuildMetadata(&buildv1alpha1.BuildMetadata{Image: fmt.Sprintf("%s@%s", testLocalImage, testLocalDigest)}),
 		},
+		ExpectedResult: controllerruntime.Result{RequeueAfter: 1 * time.Minute},
 	}, {
 		Name: "local build, removes existing build",
 		Key:  testKey,
 		GivenObjects: []rtesting.Factory{
 			funcMinimal.
-				Image("%s/%s", testImagePrefix, testName),
+				Image(testLocalImage),
+			testServiceAccount,
 			kpackImageGiven,
 		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(cmRegistryRewrites, funcMinimal, scheme),
+			rtesting.NewTrackRequest(testServiceAccount, funcMinimal, scheme),
+		},
 		ExpectEvents: []rtesting.Event{
 			rtesting.NewEvent(funcValid, scheme, corev1.EventTypeNormal, "Deleted",
 				`Deleted Image "%s"`, kpackImageGiven.Create().GetName()),
@@ -680,10 +1730,11 @@ func TestFunctionReconciler(t *testing.T) {
 					functionConditionKpackImageReady.True(),
 					functionConditionReady.True(),
 				).
-				StatusTargetImage("%s/%s", testImagePrefix, testName).
-				// TODO resolve to a digest
-				StatusLatestImage("%s/%s", testImagePrefix, testName),
+				StatusTargetImage(testLocalImage).
+				StatusLatestImage("%s@%s", testLocalImage, testLocalDigest).
+				StatusBuildMetadata(&buildv1alpha1.BuildMetadata{Image: fmt.Sprintf("%s@%s", testLocalImage, testLocalDigest)}),
 		},
+		ExpectedResult: controllerruntime.Result{RequeueAfter: 1 * time.Minute},
 	}, {
 		Name: "local build, removes existing build, error",
 		Key:  testKey,
@@ -692,9 +1743,14 @@ func TestFunctionReconciler(t *testing.T) {
 		},
 		GivenObjects: []rtesting.Factory{
 			funcMinimal.
-				Image("%s/%s", testImagePrefix, testName),
+				Image(testLocalImage),
+			testServiceAccount,
 			kpackImageGiven,
 		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(cmRegistryRewrites, funcMinimal, scheme),
+			rtesting.NewTrackRequest(testServiceAccount, funcMinimal, scheme),
+		},
 		ShouldErr: true,
 		ExpectEvents: []rtesting.Event{
 			rtesting.NewEvent(funcValid, scheme, corev1.EventTypeWarning, "DeleteFailed",
@@ -712,7 +1768,58 @@ func TestFunctionReconciler(t *testing.T) {
 					functionConditionKpackImageReady.Unknown(),
 					functionConditionReady.Unknown(),
 				).
-				StatusTargetImage("%s/%s", testImagePrefix, testName),
+				StatusTargetImage(testLocalImage),
+		},
+	}, {
+		Name: "local build, image not found",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			funcMinimal.
+				Image("%s/missing", testRegistry.Host),
+			testServiceAccount,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(cmRegistryRewrites, funcMinimal, scheme),
+			rtesting.NewTrackRequest(testServiceAccount, funcMinimal, scheme),
+		},
+		ShouldErr: true,
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(funcValid, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			funcMinimal.
+				StatusConditions(
+					functionConditionImageResolved.False().Reason("ImageInvalid", "NAME_UNKNOWN: Unknown name"),
+					functionConditionKpackImageReady.True(),
+					functionConditionReady.False().Reason("ImageInvalid", "NAME_UNKNOWN: Unknown name"),
+				).
+				StatusTargetImage("%s/missing", testRegistry.Host),
+		},
+	}, {
+		Name: "local build, service account missing",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			funcMinimal.
+				Image(testLocalImage),
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(cmRegistryRewrites, funcMinimal, scheme),
+			rtesting.NewTrackRequest(testServiceAccount, funcMinimal, scheme),
+		},
+		ShouldErr: true,
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(funcValid, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			funcMinimal.
+				StatusConditions(
+					functionConditionImageResolved.False().Reason("ImageInvalid", `serviceaccounts "riff-build" not found`),
+					functionConditionKpackImageReady.True(),
+					functionConditionReady.False().Reason("ImageInvalid", `serviceaccounts "riff-build" not found`),
+				).
+				StatusTargetImage(testLocalImage),
 		},
 	}}
 
@@ -723,6 +1830,7 @@ func TestFunctionReconciler(t *testing.T) {
 			Recorder:  recorder,
 			Scheme:    scheme,
 			Log:       log,
-		})
+			Tracker:   tracker,
+		}, testSystemNamespace, strings.NewReader(testBuildEnvDigestKey))
 	})
 }
@@ -0,0 +1,245 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package build
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"github.com/projectriff/system/pkg/apis"
+	buildv1alpha1 "github.com/projectriff/system/pkg/apis/build/v1alpha1"
+	"github.com/projectriff/system/pkg/controllers"
+	"github.com/projectriff/system/pkg/tracker"
+)
+
+// +kubebuilder:rbac:groups=build.projectriff.io,resources=imagepromotions,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=build.projectriff.io,resources=imagepromotions/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=build.projectriff.io,resources=applications;containers;functions,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=serviceaccounts,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=events,verbs=get;list;watch;create;update;patch;delete
+
+func ImagePromotionReconciler(c controllers.Config) *controllers.ParentReconciler {
+	c.Log = c.Log.WithName("ImagePromotion")
+
+	return &controllers.ParentReconciler{
+		Type: &buildv1alpha1.ImagePromotion{},
+		SubReconcilers: []controllers.SubReconciler{
+			ImagePromotionSourceReconciler(c),
+			ImagePromotionCopyReconciler(c),
+		},
+
+		Config: c,
+	}
+}
+
+func ImagePromotionSourceReconciler(c controllers.Config) controllers.SubReconciler {
+	c.Log = c.Log.WithName("Source")
+
+	return &controllers.SyncReconciler{
+		Sync: func(ctx context.Context, parent *buildv1alpha1.ImagePromotion) error {
+			src := parent.Spec.Source
+
+			var build interface {
+				apis.Object
+				GetGroupVersionKind() schema.GroupVersionKind
+			}
+			var buildName string
+			switch {
+			case src.Image != "":
+				if _, err := name.NewDigest(src.Image, name.WeakValidation); err != nil {
+					parent.Status.SourceImage = ""
+					parent.Status.MarkSourceInvalid(err.Error())
+					return nil
+				}
+				parent.Status.SourceImage = src.Image
+				parent.Status.MarkSourceResolved()
+				return nil
+			case src.ApplicationRef != "":
+				build, buildName = &buildv1alpha1.Application{}, src.ApplicationRef
+			case src.ContainerRef != "":
+				build, buildName = &buildv1alpha1.Container{}, src.ContainerRef
+			case src.FunctionRef != "":
+				build, buildName = &buildv1alpha1.Function{}, src.FunctionRef
+			default:
+				return fmt.Errorf("invalid source")
+			}
+
+			key := types.NamespacedName{Namespace: parent.Namespace, Name: buildName}
+			kind := build.GetGroupVersionKind().Kind
+			// track build for new images
+			c.Tracker.Track(
+				tracker.NewKey(build.GetGroupVersionKind(), key),
+				types.NamespacedName{Namespace: parent.Namespace, Name: parent.Name},
+			)
+			if err := c.Get(ctx, key, build); err != nil {
+				if apierrs.IsNotFound(err) {
+					parent.Status.SourceImage = ""
+					parent.Status.MarkSourceNotFound(fmt.Sprintf("%s %q not found", kind, buildName))
+					return nil
+				}
+				return err
+			}
+
+			latestImage := latestImageOf(build)
+			if latestImage == "" {
+				parent.Status.SourceImage = ""
+				parent.Status.MarkSourceNotReady(fmt.Sprintf("%s %q does not have a latest image", kind, buildName))
+				return nil
+			}
+			if _, err := name.NewDigest(latestImage, name.WeakValidation); err != nil {
+				parent.Status.SourceImage = ""
+				parent.Status.MarkSourceInvalid(fmt.Sprintf("%s %q latest image is not a digest: %s", kind, buildName, latestImage))
+				return nil
+			}
+			parent.Status.SourceImage = latestImage
+			parent.Status.MarkSourceResolved()
+			return nil
+		},
+
+		Config: c,
+		Setup: func(mgr controllers.Manager, bldr *controllers.Builder) error {
+			bldr.Watches(&source.Kind{Type: &buildv1alpha1.Application{}}, controllers.EnqueueTracked(&buildv1alpha1.Application{}, c.Tracker, c.Scheme))
+			bldr.Watches(&source.Kind{Type: &buildv1alpha1.Container{}}, controllers.EnqueueTracked(&buildv1alpha1.Container{}, c.Tracker, c.Scheme))
+			bldr.Watches(&source.Kind{Type: &buildv1alpha1.Function{}}, controllers.EnqueueTracked(&buildv1alpha1.Function{}, c.Tracker, c.Scheme))
+			return nil
+		},
+	}
+}
+
+func ImagePromotionCopyReconciler(c controllers.Config) controllers.SubReconciler {
+	c.Log = c.Log.WithName("Copy")
+
+	return &controllers.SyncReconciler{
+		Sync: func(ctx context.Context, parent *buildv1alpha1.ImagePromotion) error {
+			if parent.Status.SourceImage == "" {
+				// nothing to promote
+				return nil
+			}
+			sourceRef, err := name.NewDigest(parent.Status.SourceImage, name.WeakValidation)
+			if err != nil {
+				parent.Status.MarkSourceInvalid(err.Error())
+				return err
+			}
+			destinationRef, err := promotionDestination(parent.Spec.Destination, sourceRef)
+			if err != nil {
+				parent.Status.MarkPromotionFailed(err.Error())
+				return err
+			}
+
+			latestImage := fmt.Sprintf("%s@%s", destinationRef.Context().Name(), sourceRef.DigestStr())
+			if parent.Status.LatestImage == latestImage && parent.Status.GetCondition(buildv1alpha1.ImagePromotionConditionImagePromoted).IsTrue() {
+				// already promoted
+				return nil
+			}
+
+			if err := promoteImage(ctx, c.Client, c.Log, parent.Namespace, parent.Spec.ServiceAccountName, sourceRef, destinationRef); err != nil {
+				parent.Status.MarkPromotionFailed(err.Error())
+				return err
+			}
+			parent.Status.LatestImage = latestImage
+			parent.Status.MarkImagePromoted()
+			return nil
+		},
+
+		Config: c,
+	}
+}
+
+// latestImageOf returns the latest image produced by an application,
+// container or function.
+func latestImageOf(build apis.Object) string {
+	switch b := build.(type) {
+	case *buildv1alpha1.Application:
+		return b.Status.LatestImage
+	case *buildv1alpha1.Container:
+		return b.Status.LatestImage
+	case *buildv1alpha1.Function:
+		return b.Status.LatestImage
+	}
+	return ""
+}
+
+// promotionDestination resolves the reference the promoted image is written
+// to. A destination without a tag is addressed by the source digest.
+func promotionDestination(destination string, source name.Digest) (name.Reference, error) {
+	if repo, err := name.NewRepository(destination, name.WeakValidation); err == nil {
+		return name.NewDigest(fmt.Sprintf("%s@%s", repo.Name(), source.DigestStr()), name.WeakValidation)
+	}
+	return name.NewTag(destination, name.WeakValidation)
+}
+
+// promoteImage copies the source image or image index to the destination,
+// authenticating with the credentials bound to the service account.
+func promoteImage(ctx context.Context, c client.Client, log logr.Logger, namespace, serviceAccountName string, source name.Digest, destination name.Reference) error {
+	keychain, err := constructKeychain(ctx, c, log, namespace, serviceAccountName)
+	if err != nil {
+		return err
+	}
+
+	sourceAuth, err := keychain.Resolve(source.Context().Registry)
+	if err != nil {
+		log.Error(err, "unable to resolve auth for registry", "registry", source.Context().RegistryStr())
+		return err
+	}
+	desc, err := remote.Get(source, remote.WithAuth(sourceAuth))
+	if err != nil {
+		log.Error(err, "failed to read image", "image", source.String())
+		return err
+	}
+
+	destinationAuth, err := keychain.Resolve(destination.Context().Registry)
+	if err != nil {
+		log.Error(err, "unable to resolve auth for registry", "registry", destination.Context().RegistryStr())
+		return err
+	}
+
+	if isImageIndex(desc.MediaType) {
+		// copy the index and every platform image, preserving the digest
+		index, err := desc.ImageIndex()
+		if err != nil {
+			log.Error(err, "failed to read image index", "image", source.String())
+			return err
+		}
+		if err := remote.WriteIndex(destination, index, remote.WithAuth(destinationAuth)); err != nil {
+			log.Error(err, "failed to write image index", "image", destination.String())
+			return err
+		}
+		return nil
+	}
+
+	img, err := desc.Image()
+	if err != nil {
+		log.Error(err, "failed to read image", "image", source.String())
+		return err
+	}
+	if err := remote.Write(destination, img, remote.WithAuth(destinationAuth)); err != nil {
+		log.Error(err, "failed to write image", "image", destination.String())
+		return err
+	}
+
+	return nil
+}
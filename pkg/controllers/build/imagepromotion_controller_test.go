@@ -0,0 +1,486 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package build_test
+
+import (
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	imagetypes "github.com/google/go-containerregistry/pkg/v1/types"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	buildv1alpha1 "github.com/projectriff/system/pkg/apis/build/v1alpha1"
+	"github.com/projectriff/system/pkg/controllers"
+	"github.com/projectriff/system/pkg/controllers/build"
+	rtesting "github.com/projectriff/system/pkg/controllers/testing"
+	"github.com/projectriff/system/pkg/controllers/testing/factories"
+	"github.com/projectriff/system/pkg/tracker"
+)
+
+func TestImagePromotionReconciler(t *testing.T) {
+	testNamespace := "test-namespace"
+	testName := "test-promotion"
+	testKey := types.NamespacedName{Namespace: testNamespace, Name: testName}
+
+	testRegistry := rtesting.NewRegistry()
+	defer testRegistry.Close()
+	testSourceRepository := testRegistry.Repository("dev/my-image")
+	testSourceDigest := testRegistry.PushRandomImage(t, testSourceRepository)
+	testSourceImage := testSourceRepository + "@" + testSourceDigest
+	testSourceIndexDigest, _ := testRegistry.PushImageIndex(t, testSourceRepository+":multi-arch",
+		v1.Platform{OS: "linux", Architecture: "amd64"},
+		v1.Platform{OS: "linux", Architecture: "arm64"},
+	)
+	testSourceIndex := testSourceRepository + "@" + testSourceIndexDigest
+	testDestinationRepository := testRegistry.Repository("prod/my-image")
+
+	verifyPromotedIndex := func(destination string) rtesting.VerifyFunc {
+		return func(t *testing.T, result controllerruntime.Result, err error) {
+			ref, err := name.ParseReference(destination, name.WeakValidation)
+			if err != nil {
+				t.Fatalf("invalid destination %q: %v", destination, err)
+			}
+			desc, err := remote.Get(ref)
+			if err != nil {
+				t.Fatalf("unable to read promoted image %q: %v", destination, err)
+			}
+			if desc.MediaType != imagetypes.OCIImageIndex {
+				t.Errorf("expected promoted image %q to be an image index, got media type %q", destination, desc.MediaType)
+			}
+			if desc.Digest.String() != testSourceIndexDigest {
+				t.Errorf("expected promoted image %q to have digest %q, got %q", destination, testSourceIndexDigest, desc.Digest)
+			}
+		}
+	}
+
+	imagePromotionConditionImagePromoted := factories.Condition().Type(buildv1alpha1.ImagePromotionConditionImagePromoted)
+	imagePromotionConditionReady := factories.Condition().Type(buildv1alpha1.ImagePromotionConditionReady)
+	imagePromotionConditionSourceResolved := factories.Condition().Type(buildv1alpha1.ImagePromotionConditionSourceResolved)
+
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = buildv1alpha1.AddToScheme(scheme)
+
+	promotionMinimal := factories.ImagePromotion().
+		NamespaceName(testNamespace, testName)
+	promotionValid := promotionMinimal.
+		SourceImage(testSourceImage).
+		Destination(testDestinationRepository)
+
+	testServiceAccount := factories.ServiceAccount().
+		NamespaceName(testNamespace, "riff-build")
+	testApplication := factories.Application().
+		NamespaceName(testNamespace, "my-application")
+	testContainer := factories.Container().
+		NamespaceName(testNamespace, "my-container")
+	testFunction := factories.Function().
+		NamespaceName(testNamespace, "my-function")
+
+	table := rtesting.Table{{
+		Name: "image promotion does not exist",
+		Key:  testKey,
+	}, {
+		Name: "ignore deleted image promotion",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			promotionValid.
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.Deleted(1)
+				}),
+		},
+	}, {
+		Name: "image promotion get error",
+		Key:  testKey,
+		WithReactors: []rtesting.ReactionFunc{
+			rtesting.InduceFailure("get", "ImagePromotion"),
+		},
+		ShouldErr: true,
+	}, {
+		Name: "promote image",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			promotionValid,
+			testServiceAccount,
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(promotionValid, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			promotionMinimal.
+				StatusConditions(
+					imagePromotionConditionImagePromoted.True(),
+					imagePromotionConditionReady.True(),
+					imagePromotionConditionSourceResolved.True(),
+				).
+				StatusSourceImage(testSourceImage).
+				StatusLatestImage("%s@%s", testDestinationRepository, testSourceDigest),
+		},
+	}, {
+		Name: "promote image index",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			promotionValid.
+				SourceImage(testSourceIndex),
+			testServiceAccount,
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(promotionValid, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			promotionMinimal.
+				StatusConditions(
+					imagePromotionConditionImagePromoted.True(),
+					imagePromotionConditionReady.True(),
+					imagePromotionConditionSourceResolved.True(),
+				).
+				StatusSourceImage(testSourceIndex).
+				StatusLatestImage("%s@%s", testDestinationRepository, testSourceIndexDigest),
+		},
+		Verify: verifyPromotedIndex(testDestinationRepository + "@" + testSourceIndexDigest),
+	}, {
+		Name: "promote image index, tagged destination",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			promotionValid.
+				SourceImage(testSourceIndex).
+				Destination("%s:multi-arch", testDestinationRepository),
+			testServiceAccount,
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(promotionValid, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			promotionMinimal.
+				StatusConditions(
+					imagePromotionConditionImagePromoted.True(),
+					imagePromotionConditionReady.True(),
+					imagePromotionConditionSourceResolved.True(),
+				).
+				StatusSourceImage(testSourceIndex).
+				StatusLatestImage("%s@%s", testDestinationRepository, testSourceIndexDigest),
+		},
+		Verify: verifyPromotedIndex(testDestinationRepository + ":multi-arch"),
+	}, {
+		Name: "promote image, tagged destination",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			promotionValid.
+				Destination("%s:v1", testDestinationRepository),
+			testServiceAccount,
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(promotionValid, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			promotionMinimal.
+				StatusConditions(
+					imagePromotionConditionImagePromoted.True(),
+					imagePromotionConditionReady.True(),
+					imagePromotionConditionSourceResolved.True(),
+				).
+				StatusSourceImage(testSourceImage).
+				StatusLatestImage("%s@%s", testDestinationRepository, testSourceDigest),
+		},
+	}, {
+		Name: "promote image, already promoted",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			promotionValid.
+				StatusConditions(
+					imagePromotionConditionImagePromoted.True(),
+					imagePromotionConditionReady.True(),
+					imagePromotionConditionSourceResolved.True(),
+				).
+				StatusSourceImage(testSourceImage).
+				StatusLatestImage("%s@%s", testDestinationRepository, testSourceDigest),
+		},
+	}, {
+		Name: "promote image, source image missing",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			promotionValid.
+				SourceImage("%s@sha256:cf8b4c69d5460f88530e1c80b8856a70801f31c50b191c8413043ba9b160a43e", testSourceRepository),
+			testServiceAccount,
+		},
+		ShouldErr: true,
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(promotionValid, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			promotionMinimal.
+				StatusConditions(
+					imagePromotionConditionImagePromoted.False().Reason("PromotionFailed", "MANIFEST_UNKNOWN: Unknown manifest"),
+					imagePromotionConditionReady.False().Reason("PromotionFailed", "MANIFEST_UNKNOWN: Unknown manifest"),
+					imagePromotionConditionSourceResolved.True(),
+				).
+				StatusSourceImage("%s@sha256:cf8b4c69d5460f88530e1c80b8856a70801f31c50b191c8413043ba9b160a43e", testSourceRepository),
+		},
+	}, {
+		Name: "promote image, service account missing",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			promotionValid,
+		},
+		ShouldErr: true,
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(promotionValid, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			promotionMinimal.
+				StatusConditions(
+					imagePromotionConditionImagePromoted.False().Reason("PromotionFailed", `serviceaccounts "riff-build" not found`),
+					imagePromotionConditionReady.False().Reason("PromotionFailed", `serviceaccounts "riff-build" not found`),
+					imagePromotionConditionSourceResolved.True(),
+				).
+				StatusSourceImage(testSourceImage),
+		},
+	}, {
+		Name: "promote application",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			promotionValid.
+				ApplicationRef("my-application"),
+			testApplication.
+				StatusLatestImage(testSourceImage),
+			testServiceAccount,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testApplication, promotionMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(promotionValid, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			promotionMinimal.
+				StatusConditions(
+					imagePromotionConditionImagePromoted.True(),
+					imagePromotionConditionReady.True(),
+					imagePromotionConditionSourceResolved.True(),
+				).
+				StatusSourceImage(testSourceImage).
+				StatusLatestImage("%s@%s", testDestinationRepository, testSourceDigest),
+		},
+	}, {
+		Name: "promote application, not found",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			promotionValid.
+				ApplicationRef("my-application"),
+			testServiceAccount,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testApplication, promotionMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(promotionValid, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			promotionMinimal.
+				StatusConditions(
+					imagePromotionConditionImagePromoted.Unknown(),
+					imagePromotionConditionReady.False().Reason("SourceNotFound", `Application "my-application" not found`),
+					imagePromotionConditionSourceResolved.False().Reason("SourceNotFound", `Application "my-application" not found`),
+				),
+		},
+	}, {
+		Name: "promote application, no latest image",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			promotionValid.
+				ApplicationRef("my-application"),
+			testApplication,
+			testServiceAccount,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testApplication, promotionMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(promotionValid, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			promotionMinimal.
+				StatusConditions(
+					imagePromotionConditionImagePromoted.Unknown(),
+					imagePromotionConditionReady.Unknown().Reason("SourceNotReady", `Application "my-application" does not have a latest image`),
+					imagePromotionConditionSourceResolved.Unknown().Reason("SourceNotReady", `Application "my-application" does not have a latest image`),
+				),
+		},
+	}, {
+		Name: "promote application, latest image is not a digest",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			promotionValid.
+				ApplicationRef("my-application"),
+			testApplication.
+				StatusLatestImage(testSourceRepository),
+			testServiceAccount,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testApplication, promotionMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(promotionValid, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			promotionMinimal.
+				StatusConditions(
+					imagePromotionConditionImagePromoted.Unknown(),
+					imagePromotionConditionReady.False().Reason("SourceInvalid", `Application "my-application" latest image is not a digest: `+testSourceRepository),
+					imagePromotionConditionSourceResolved.False().Reason("SourceInvalid", `Application "my-application" latest image is not a digest: `+testSourceRepository),
+				),
+		},
+	}, {
+		Name: "promote application, get error",
+		Key:  testKey,
+		WithReactors: []rtesting.ReactionFunc{
+			rtesting.InduceFailure("get", "Application"),
+		},
+		GivenObjects: []rtesting.Factory{
+			promotionValid.
+				ApplicationRef("my-application"),
+			testApplication.
+				StatusLatestImage(testSourceImage),
+			testServiceAccount,
+		},
+		ShouldErr: true,
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testApplication, promotionMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(promotionValid, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			promotionMinimal.
+				StatusConditions(
+					imagePromotionConditionImagePromoted.Unknown(),
+					imagePromotionConditionReady.Unknown(),
+					imagePromotionConditionSourceResolved.Unknown(),
+				),
+		},
+	}, {
+		Name: "promote container",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			promotionValid.
+				ContainerRef("my-container"),
+			testContainer.
+				StatusLatestImage(testSourceImage),
+			testServiceAccount,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testContainer, promotionMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(promotionValid, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			promotionMinimal.
+				StatusConditions(
+					imagePromotionConditionImagePromoted.True(),
+					imagePromotionConditionReady.True(),
+					imagePromotionConditionSourceResolved.True(),
+				).
+				StatusSourceImage(testSourceImage).
+				StatusLatestImage("%s@%s", testDestinationRepository, testSourceDigest),
+		},
+	}, {
+		Name: "promote function",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			promotionValid.
+				FunctionRef("my-function"),
+			testFunction.
+				StatusLatestImage(testSourceImage),
+			testServiceAccount,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testFunction, promotionMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(promotionValid, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			promotionMinimal.
+				StatusConditions(
+					imagePromotionConditionImagePromoted.True(),
+					imagePromotionConditionReady.True(),
+					imagePromotionConditionSourceResolved.True(),
+				).
+				StatusSourceImage(testSourceImage).
+				StatusLatestImage("%s@%s", testDestinationRepository, testSourceDigest),
+		},
+	}, {
+		Name: "image promotion status update error",
+		Key:  testKey,
+		WithReactors: []rtesting.ReactionFunc{
+			rtesting.InduceFailure("update", "ImagePromotion"),
+		},
+		GivenObjects: []rtesting.Factory{
+			promotionValid,
+			testServiceAccount,
+		},
+		ShouldErr: true,
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(promotionValid, scheme, corev1.EventTypeWarning, "StatusUpdateFailed",
+				`Failed to update status: inducing failure for update ImagePromotion`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			promotionMinimal.
+				StatusConditions(
+					imagePromotionConditionImagePromoted.True(),
+					imagePromotionConditionReady.True(),
+					imagePromotionConditionSourceResolved.True(),
+				).
+				StatusSourceImage(testSourceImage).
+				StatusLatestImage("%s@%s", testDestinationRepository, testSourceDigest),
+		},
+	}}
+
+	table.Test(t, scheme, func(t *testing.T, row *rtesting.Testcase, client client.Client, apiReader client.Reader, tracker tracker.Tracker, recorder record.EventRecorder, log logr.Logger) reconcile.Reconciler {
+		return build.ImagePromotionReconciler(controllers.Config{
+			Client:    client,
+			APIReader: apiReader,
+			Recorder:  recorder,
+			Scheme:    scheme,
+			Log:       log,
+			Tracker:   tracker,
+		})
+	})
+}
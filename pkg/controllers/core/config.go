@@ -19,9 +19,37 @@ package core
 const (
 	kustomizePrefix = "riff-core" // kustomize adds this prefix to all our resource names
 
-	systemNamespace = "riff-system"
-
 	settingsConfigMapName = kustomizePrefix + "-settings"
 	defaultDomainKey      = "defaultDomain"
 	defaultDomain         = "example.com"
+
+	// hostTemplateKey is a text/template for the host routed to each
+	// deployer, with the fields Name, Namespace and Domain
+	hostTemplateKey     = "hostTemplate"
+	defaultHostTemplate = "{{.Name}}.{{.Namespace}}.{{.Domain}}"
+
+	// defaultTLSSecretKey names a wildcard certificate Secret used for
+	// deployers that do not reference their own certificate. The Secret
+	// must exist in each deployer's namespace.
+	defaultTLSSecretKey = "defaultTLSSecret"
+	// tlsIssuerAnnotationKey is an annotation, in the form key=value, applied
+	// to ingresses so a certificate is issued for them, for example
+	// "cert-manager.io/cluster-issuer=letsencrypt"
+	tlsIssuerAnnotationKey = "tlsIssuerAnnotation"
+
+	// routingBackendKey selects how deployers with an External ingress
+	// policy are exposed, either "Ingress", the default, or "HTTPRoute"
+	routingBackendKey       = "routingBackend"
+	routingBackendHTTPRoute = "HTTPRoute"
+	// gatewayKey is the Gateway, in the form namespace/name, HTTPRoutes are
+	// attached to. A Gateway without a namespace is expected in each
+	// deployer's namespace.
+	gatewayKey = "gateway"
+
+	// ingressNamespaceKey is the namespace of the ingress controller that
+	// NetworkPolicies for deployers with an External ingress policy accept
+	// connections from. When routing with HTTPRoutes, connections are
+	// accepted from the Gateway's namespace instead.
+	ingressNamespaceKey     = "ingressNamespace"
+	defaultIngressNamespace = "ingress-nginx"
 )
@@ -0,0 +1,207 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/discovery"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	corev1alpha1 "github.com/projectriff/system/pkg/apis/core/v1alpha1"
+	streamingv1alpha1 "github.com/projectriff/system/pkg/apis/streaming/v1alpha1"
+	"github.com/projectriff/system/pkg/controllers"
+	"github.com/projectriff/system/pkg/tracker"
+)
+
+const (
+	DeployerBindingsStashKey controllers.StashKey = "deployer-bindings"
+
+	bindingsRootPath = "/var/riff/bindings"
+)
+
+// DeployerResolvedBinding is a binding resolved from the bindable resource
+// referenced by a deployer
+type DeployerResolvedBinding struct {
+	// Name of the directory the binding is mounted into
+	Name string
+	// UID of the bindable resource
+	UID types.UID
+	// MetadataRef is the name of the ConfigMap with the binding metadata
+	MetadataRef string
+	// SecretRef is the name of the Secret with the binding secret
+	SecretRef string
+}
+
+// DiscoverStreamAvailable returns true when the cluster serves
+// streaming.projectriff.io/v1alpha1 Streams
+func DiscoverStreamAvailable(d discovery.DiscoveryInterface) (bool, error) {
+	groups, err := d.ServerGroups()
+	if err != nil {
+		return false, err
+	}
+	for _, group := range groups.Groups {
+		if group.Name != streamingv1alpha1.GroupVersion.Group {
+			continue
+		}
+		for _, version := range group.Versions {
+			if version.Version != streamingv1alpha1.GroupVersion.Version {
+				continue
+			}
+			resources, err := d.ServerResourcesForGroupVersion(version.GroupVersion)
+			if err != nil {
+				return false, err
+			}
+			for _, resource := range resources.APIResources {
+				if resource.Name == "streams" {
+					return true, nil
+				}
+			}
+		}
+	}
+	return false, nil
+}
+
+// DeployerBindingsReconciler resolves the binding metadata and secret of
+// each resource bound to the deployer. Bound resources are tracked, so a
+// rotated binding rolls out the deployer. Only Streams are watched, other
+// bindable resources are resolved the next time the deployer is reconciled.
+func DeployerBindingsReconciler(c controllers.Config, streamAvailable bool) controllers.SubReconciler {
+	c.Log = c.Log.WithName("Bindings")
+
+	return &controllers.SyncReconciler{
+		Sync: func(ctx context.Context, parent *corev1alpha1.Deployer) error {
+			bindings := make([]DeployerResolvedBinding, len(parent.Spec.Bindings))
+			for i, binding := range parent.Spec.Bindings {
+				gv, err := schema.ParseGroupVersion(binding.Ref.APIVersion)
+				if err != nil {
+					return err
+				}
+				bindable := &unstructured.Unstructured{}
+				bindable.SetGroupVersionKind(gv.WithKind(binding.Ref.Kind))
+				key := types.NamespacedName{Namespace: parent.Namespace, Name: binding.Ref.Name}
+				// track bindable resource for rotated bindings
+				c.Tracker.Track(
+					tracker.NewKey(bindable.GroupVersionKind(), key),
+					types.NamespacedName{Namespace: parent.Namespace, Name: parent.Name},
+				)
+				if err := c.Get(ctx, key, bindable); err != nil {
+					c.Log.Error(err, "failed to get bindable resource", "binding", binding.Name, "ref", binding.Ref)
+					return err
+				}
+				metadataRef, _, err := unstructured.NestedString(bindable.Object, "status", "binding", "metadataRef", "name")
+				if err != nil {
+					return err
+				}
+				secretRef, _, err := unstructured.NestedString(bindable.Object, "status", "binding", "secretRef", "name")
+				if err != nil {
+					return err
+				}
+				bindings[i] = DeployerResolvedBinding{
+					Name:        binding.Name,
+					UID:         bindable.GetUID(),
+					MetadataRef: metadataRef,
+					SecretRef:   secretRef,
+				}
+			}
+			controllers.StashValue(ctx, DeployerBindingsStashKey, bindings)
+
+			return nil
+		},
+
+		Config: c,
+		Setup: func(mgr controllers.Manager, bldr *controllers.Builder) error {
+			if streamAvailable {
+				// watching Streams fails when the API is not served
+				bldr.Watches(&source.Kind{Type: &streamingv1alpha1.Stream{}}, controllers.EnqueueTracked(&streamingv1alpha1.Stream{}, c.Tracker, c.Scheme))
+			}
+			return nil
+		},
+	}
+}
+
+// applyDeployerBindings mounts the binding metadata and secret of each
+// binding into the first container of the pod template. Each bindable
+// resource is a single volume, mounted once for each binding referencing it.
+func applyDeployerBindings(template *corev1.PodTemplateSpec, bindings []DeployerResolvedBinding) {
+	if len(bindings) == 0 {
+		return
+	}
+
+	// de-dupe bindable resources and create one volume for each
+	volumes := map[string]corev1.Volume{}
+	volumeMounts := []corev1.VolumeMount{}
+	for _, binding := range bindings {
+		if binding.MetadataRef != "" {
+			name := fmt.Sprintf("binding-%s-metadata", binding.UID)
+			volumes[name] = corev1.Volume{
+				Name: name,
+				VolumeSource: corev1.VolumeSource{
+					ConfigMap: &corev1.ConfigMapVolumeSource{
+						LocalObjectReference: corev1.LocalObjectReference{
+							Name: binding.MetadataRef,
+						},
+					},
+				},
+			}
+			volumeMounts = append(volumeMounts, corev1.VolumeMount{
+				Name:      name,
+				MountPath: fmt.Sprintf("%s/%s/metadata", bindingsRootPath, binding.Name),
+				ReadOnly:  true,
+			})
+		}
+		if binding.SecretRef != "" {
+			name := fmt.Sprintf("binding-%s-secret", binding.UID)
+			volumes[name] = corev1.Volume{
+				Name: name,
+				VolumeSource: corev1.VolumeSource{
+					Secret: &corev1.SecretVolumeSource{
+						SecretName: binding.SecretRef,
+					},
+				},
+			}
+			volumeMounts = append(volumeMounts, corev1.VolumeMount{
+				Name:      name,
+				MountPath: fmt.Sprintf("%s/%s/secret", bindingsRootPath, binding.Name),
+				ReadOnly:  true,
+			})
+		}
+	}
+
+	// sort volumes to avoid update diffs caused by iteration order
+	names := make([]string, 0, len(volumes))
+	for name := range volumes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		template.Spec.Volumes = append(template.Spec.Volumes, volumes[name])
+	}
+
+	container := &template.Spec.Containers[0]
+	container.VolumeMounts = append(container.VolumeMounts, volumeMounts...)
+	container.Env = append(container.Env, corev1.EnvVar{
+		Name:  "CNB_BINDINGS",
+		Value: bindingsRootPath,
+	})
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core_test
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	clientgotesting "k8s.io/client-go/testing"
+
+	corecontrollers "github.com/projectriff/system/pkg/controllers/core"
+)
+
+func TestDiscoverStreamAvailable(t *testing.T) {
+	tests := []struct {
+		name      string
+		resources []*metav1.APIResourceList
+		expected  bool
+	}{
+		{
+			name: "streaming.projectriff.io/v1alpha1",
+			resources: []*metav1.APIResourceList{
+				{
+					GroupVersion: "streaming.projectriff.io/v1alpha1",
+					APIResources: []metav1.APIResource{
+						{Name: "processors", Kind: "Processor"},
+						{Name: "streams", Kind: "Stream"},
+					},
+				},
+			},
+			expected: true,
+		},
+		{
+			name: "streaming.projectriff.io/v1alpha1 without streams",
+			resources: []*metav1.APIResourceList{
+				{
+					GroupVersion: "streaming.projectriff.io/v1alpha1",
+					APIResources: []metav1.APIResource{
+						{Name: "processors", Kind: "Processor"},
+					},
+				},
+			},
+			expected: false,
+		},
+		{
+			name:     "not installed",
+			expected: false,
+		},
+	}
+	for _, c := range tests {
+		t.Run(c.name, func(t *testing.T) {
+			discovery := &fakediscovery.FakeDiscovery{
+				Fake: &clientgotesting.Fake{Resources: c.resources},
+			}
+			actual, err := corecontrollers.DiscoverStreamAvailable(discovery)
+			if err != nil {
+				t.Errorf("DiscoverStreamAvailable() error = %v", err)
+				return
+			}
+			if actual != c.expected {
+				t.Errorf("DiscoverStreamAvailable() = %v, expected %v", actual, c.expected)
+			}
+		})
+	}
+}
@@ -19,16 +19,22 @@ package core
 import (
 	"context"
 	"fmt"
+	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2beta2 "k8s.io/api/autoscaling/v2beta2"
 	corev1 "k8s.io/api/core/v1"
+	k8snetworkingv1 "k8s.io/api/networking/v1"
 	networkingv1beta1 "k8s.io/api/networking/v1beta1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	apierrs "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/client-go/discovery"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	"github.com/projectriff/system/pkg/apis"
@@ -36,105 +42,104 @@ import (
 	corev1alpha1 "github.com/projectriff/system/pkg/apis/core/v1alpha1"
 	"github.com/projectriff/system/pkg/controllers"
 	"github.com/projectriff/system/pkg/refs"
+	"github.com/projectriff/system/pkg/registries"
 	"github.com/projectriff/system/pkg/tracker"
 )
 
 // +kubebuilder:rbac:groups=core.projectriff.io,resources=deployers,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core.projectriff.io,resources=deployers/status,verbs=get;update;patch
-// +kubebuilder:rbac:groups=build.projectriff.io,resources=applications;containers;functions,verbs=get;list;watch
+// +kubebuilder:rbac:groups=build.projectriff.io,resources=applications;containers;functions;imagepromotions,verbs=get;list;watch
 // +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=autoscaling,resources=horizontalpodautoscalers,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=services,verbs=get;list;watch;create;update;patch;delete
-// +kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses;networkpolicies,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=httproutes,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=streaming.projectriff.io,resources=streams,verbs=get;list;watch
 // +kubebuilder:rbac:groups=core,resources=events,verbs=get;list;watch;create;update;patch;delete
 
-func DeployerReconciler(c controllers.Config) *controllers.ParentReconciler {
+func DeployerReconciler(c controllers.Config, namespace string, ingressVersion schema.GroupVersion, httpRouteAvailable, streamAvailable bool, now func() time.Time) *controllers.ParentReconciler {
 	c.Log = c.Log.WithName("Deployer")
 
+	subReconcilers := []controllers.SubReconciler{
+		DeployerBuildRefReconciler(c, namespace, now),
+		DeployerBindingsReconciler(c, streamAvailable),
+		DeployerRolloutReconciler(c, now),
+		DeployerChildDeploymentReconciler(c),
+		DeployerChildCanaryDeploymentReconciler(c),
+		DeployerChildHorizontalPodAutoscalerReconciler(c),
+		DeployerChildPodDisruptionBudgetReconciler(c),
+		DeployerChildNetworkPolicyReconciler(c, namespace),
+		DeployerChildServiceReconciler(c),
+		DeployerChildCanaryServiceReconciler(c),
+		DeployerIngressReconciler(c, namespace, httpRouteAvailable),
+		DeployerChildIngressReconciler(c, ingressVersion),
+		DeployerChildCanaryIngressReconciler(c, ingressVersion),
+	}
+	if httpRouteAvailable {
+		// watching HTTPRoutes fails when the API is not served
+		subReconcilers = append(subReconcilers, DeployerChildHTTPRouteReconciler(c))
+	}
+	subReconcilers = append(subReconcilers, DeployerIngressCertificateReconciler(c, now))
+
 	return &controllers.ParentReconciler{
-		Type: &corev1alpha1.Deployer{},
-		SubReconcilers: []controllers.SubReconciler{
-			DeployerBuildRefReconciler(c),
-			DeployerChildDeploymentReconciler(c),
-			DeployerChildServiceReconciler(c),
-			DeployerChildIngressReconciler(c),
-		},
+		Type:           &corev1alpha1.Deployer{},
+		SubReconcilers: subReconcilers,
 
 		Config: c,
 	}
 }
 
-func DeployerBuildRefReconciler(c controllers.Config) controllers.SubReconciler {
+func DeployerBuildRefReconciler(c controllers.Config, namespace string, now func() time.Time) controllers.SubReconciler {
 	c.Log = c.Log.WithName("BuildRef")
 
 	return &controllers.SyncReconciler{
 		Sync: func(ctx context.Context, parent *corev1alpha1.Deployer) error {
-			build := parent.Spec.Build
-			if build == nil {
-				parent.Status.LatestImage = parent.Spec.Template.Spec.Containers[0].Image
-				return nil
+			// track registry rewrite rules for new images
+			registries.Track(c.Tracker, namespace, parent)
+			rewriter, err := registries.Load(ctx, c, namespace)
+			if err != nil {
+				return err
 			}
 
-			switch {
-			case build.ApplicationRef != "":
-				var application buildv1alpha1.Application
-				key := types.NamespacedName{Namespace: parent.Namespace, Name: build.ApplicationRef}
-				// track application for new images
-				c.Tracker.Track(
-					tracker.NewKey(application.GetGroupVersionKind(), key),
-					types.NamespacedName{Namespace: parent.Namespace, Name: parent.Name},
-				)
-				if err := c.Get(ctx, key, &application); err != nil {
-					if apierrs.IsNotFound(err) {
-						return nil
-					}
-					return err
-				}
-				if application.Status.LatestImage != "" {
-					parent.Status.LatestImage = application.Status.LatestImage
-				}
-				return nil
-
-			case build.ContainerRef != "":
-				var container buildv1alpha1.Container
-				key := types.NamespacedName{Namespace: parent.Namespace, Name: build.ContainerRef}
-				// track container for new images
-				c.Tracker.Track(
-					tracker.NewKey(container.GetGroupVersionKind(), key),
-					types.NamespacedName{Namespace: parent.Namespace, Name: parent.Name},
-				)
-				if err := c.Get(ctx, key, &container); err != nil {
-					if apierrs.IsNotFound(err) {
-						return nil
-					}
-					return err
-				}
-				if container.Status.LatestImage != "" {
-					parent.Status.LatestImage = container.Status.LatestImage
-				}
-				return nil
+			image, buildGeneration, err := resolveDeployerImage(ctx, c, parent)
+			if err != nil {
+				return err
+			}
 
-			case build.FunctionRef != "":
-				var function buildv1alpha1.Function
-				key := types.NamespacedName{Namespace: parent.Namespace, Name: build.FunctionRef}
-				// track function for new images
-				c.Tracker.Track(
-					tracker.NewKey(function.GetGroupVersionKind(), key),
-					types.NamespacedName{Namespace: parent.Namespace, Name: parent.Name},
-				)
-				if err := c.Get(ctx, key, &function); err != nil {
-					if apierrs.IsNotFound(err) {
-						return nil
-					}
-					return err
+			imageSource := corev1alpha1.DeployerImageSourceBuild
+			if parent.Spec.Build == nil {
+				imageSource = corev1alpha1.DeployerImageSourceTemplate
+			}
+			previousSource, previousImage := parent.Status.ImageSource, parent.Status.LatestImage
+			if pinnedImage := parent.Spec.PinnedImage; pinnedImage != "" {
+				parent.Status.LatestImage = rewriter.Rewrite(pinnedImage)
+				parent.Status.ImageSource = corev1alpha1.DeployerImageSourcePinned
+				buildGeneration = 0
+				if previousSource != corev1alpha1.DeployerImageSourcePinned || previousImage != parent.Status.LatestImage {
+					c.Recorder.Eventf(parent, corev1.EventTypeNormal, "Pinned",
+						"Pinned image %q", parent.Status.LatestImage)
 				}
-				if function.Status.LatestImage != "" {
-					parent.Status.LatestImage = function.Status.LatestImage
+			} else if image != "" {
+				parent.Status.LatestImage = rewriter.Rewrite(image)
+				parent.Status.ImageSource = imageSource
+				if previousSource == corev1alpha1.DeployerImageSourcePinned {
+					c.Recorder.Eventf(parent, corev1.EventTypeNormal, "Unpinned",
+						"Unpinned image %q", previousImage)
 				}
-				return nil
+			} else if previousSource == corev1alpha1.DeployerImageSourcePinned {
+				// the pinned image keeps running until the build produces an
+				// image
+				buildGeneration = 0
+			}
 
+			if parent.Status.LatestImage != "" {
+				parent.Status.RecordRevision(parent.Status.LatestImage, buildGeneration, metav1.Time{Time: now()})
 			}
 
-			return fmt.Errorf("invalid build")
+			return nil
 		},
 
 		Config: c,
@@ -142,11 +147,92 @@ func DeployerBuildRefReconciler(c controllers.Config) controllers.SubReconciler
 			bldr.Watches(&source.Kind{Type: &buildv1alpha1.Application{}}, controllers.EnqueueTracked(&buildv1alpha1.Application{}, c.Tracker, c.Scheme))
 			bldr.Watches(&source.Kind{Type: &buildv1alpha1.Container{}}, controllers.EnqueueTracked(&buildv1alpha1.Container{}, c.Tracker, c.Scheme))
 			bldr.Watches(&source.Kind{Type: &buildv1alpha1.Function{}}, controllers.EnqueueTracked(&buildv1alpha1.Function{}, c.Tracker, c.Scheme))
+			bldr.Watches(&source.Kind{Type: &buildv1alpha1.ImagePromotion{}}, controllers.EnqueueTracked(&buildv1alpha1.ImagePromotion{}, c.Tracker, c.Scheme))
+			bldr.Watches(&source.Kind{Type: &corev1.ConfigMap{}}, controllers.EnqueueTracked(&corev1.ConfigMap{}, c.Tracker, c.Scheme))
 			return nil
 		},
 	}
 }
 
+// resolveDeployerImage resolves the image and generation of the deployer's
+// build, or the image of the template when there is no build. An empty image
+// is returned when the build has not produced an image.
+func resolveDeployerImage(ctx context.Context, c controllers.Config, parent *corev1alpha1.Deployer) (string, int64, error) {
+	build := parent.Spec.Build
+	if build == nil {
+		return parent.Spec.Template.Spec.Containers[0].Image, 0, nil
+	}
+
+	switch {
+	case build.ApplicationRef != "":
+		var application buildv1alpha1.Application
+		key := types.NamespacedName{Namespace: parent.Namespace, Name: build.ApplicationRef}
+		// track application for new images
+		c.Tracker.Track(
+			tracker.NewKey(application.GetGroupVersionKind(), key),
+			types.NamespacedName{Namespace: parent.Namespace, Name: parent.Name},
+		)
+		if err := c.Get(ctx, key, &application); err != nil {
+			if apierrs.IsNotFound(err) {
+				return "", 0, nil
+			}
+			return "", 0, err
+		}
+		return application.Status.LatestImage, application.Generation, nil
+
+	case build.ContainerRef != "":
+		var container buildv1alpha1.Container
+		key := types.NamespacedName{Namespace: parent.Namespace, Name: build.ContainerRef}
+		// track container for new images
+		c.Tracker.Track(
+			tracker.NewKey(container.GetGroupVersionKind(), key),
+			types.NamespacedName{Namespace: parent.Namespace, Name: parent.Name},
+		)
+		if err := c.Get(ctx, key, &container); err != nil {
+			if apierrs.IsNotFound(err) {
+				return "", 0, nil
+			}
+			return "", 0, err
+		}
+		return container.Status.LatestImage, container.Generation, nil
+
+	case build.FunctionRef != "":
+		var function buildv1alpha1.Function
+		key := types.NamespacedName{Namespace: parent.Namespace, Name: build.FunctionRef}
+		// track function for new images
+		c.Tracker.Track(
+			tracker.NewKey(function.GetGroupVersionKind(), key),
+			types.NamespacedName{Namespace: parent.Namespace, Name: parent.Name},
+		)
+		if err := c.Get(ctx, key, &function); err != nil {
+			if apierrs.IsNotFound(err) {
+				return "", 0, nil
+			}
+			return "", 0, err
+		}
+		return function.Status.LatestImage, function.Generation, nil
+
+	case build.ImagePromotionRef != "":
+		var imagePromotion buildv1alpha1.ImagePromotion
+		key := types.NamespacedName{Namespace: parent.Namespace, Name: build.ImagePromotionRef}
+		// track image promotion for new images
+		c.Tracker.Track(
+			tracker.NewKey(imagePromotion.GetGroupVersionKind(), key),
+			types.NamespacedName{Namespace: parent.Namespace, Name: parent.Name},
+		)
+		if err := c.Get(ctx, key, &imagePromotion); err != nil {
+			if apierrs.IsNotFound(err) {
+				return "", 0, nil
+			}
+			return "", 0, err
+		}
+		return imagePromotion.Status.LatestImage, imagePromotion.Generation, nil
+
+	}
+
+	return "", 0, fmt.Errorf("invalid build")
+}
+
 func DeployerChildDeploymentReconciler(c controllers.Config) controllers.SubReconciler {
 	c.Log = c.Log.WithName("ChildDeployment")
 
@@ -155,87 +241,513 @@ func DeployerChildDeploymentReconciler(c controllers.Config) controllers.SubReco
 		ChildType:     &appsv1.Deployment{},
 		ChildListType: &appsv1.DeploymentList{},
 
-		DesiredChild: func(parent *corev1alpha1.Deployer) (*appsv1.Deployment, error) {
+		DesiredChild: func(ctx context.Context, parent *corev1alpha1.Deployer) (*appsv1.Deployment, error) {
 			if parent.Status.LatestImage == "" {
 				// no image, skip
 				return nil, nil
 			}
 
-			labels := controllers.MergeMaps(parent.Labels, map[string]string{
-				corev1alpha1.DeployerLabelKey: parent.Name,
-			})
+			image := parent.Status.LatestImage
+			if rollout := parent.Status.Rollout; rollout != nil && rollout.StableImage != "" {
+				// new images are rolled out by a canary
+				image = rollout.StableImage
+			}
 
-			template := *parent.Spec.Template.DeepCopy()
-			template.Labels = controllers.MergeMaps(template.Labels, labels)
-			targetPort := template.Spec.Containers[0].Ports[0]
+			bindings, _ := controllers.RetrieveValue(ctx, DeployerBindingsStashKey).([]DeployerResolvedBinding)
+			child := deployerDeployment(parent, image, bindings, corev1alpha1.DeployerTrackStable)
+
+			if scale := parent.Spec.Scale; scale != nil {
+				var current *int32
+				if parent.Status.DeploymentRef != nil {
+					var deployment appsv1.Deployment
+					key := types.NamespacedName{Namespace: parent.Namespace, Name: parent.Status.DeploymentRef.Name}
+					if err := c.Get(ctx, key, &deployment); err != nil {
+						if !apierrs.IsNotFound(err) {
+							return nil, err
+						}
+					} else {
+						current = deployment.Spec.Replicas
+					}
+				}
+				child.Spec.Replicas = deployerReplicas(scale, current)
+			}
 
-			template.Spec.Containers[0].Env = append(template.Spec.Containers[0].Env, corev1.EnvVar{
-				Name:  "PORT",
-				Value: fmt.Sprintf("%d", targetPort.ContainerPort),
-			})
-			if template.Spec.Containers[0].ReadinessProbe == nil {
-				template.Spec.Containers[0].ReadinessProbe = &corev1.Probe{
-					Handler: corev1.Handler{
-						TCPSocket: &corev1.TCPSocketAction{
-							Port: intstr.FromInt(int(targetPort.ContainerPort)),
-						},
+			return child, nil
+		},
+		ReflectChildStatusOnParent: func(parent *corev1alpha1.Deployer, child *appsv1.Deployment, err error) {
+			if err != nil {
+				return
+			}
+			if child == nil {
+				parent.Status.DeploymentRef = nil
+				parent.Status.Replicas = 0
+				parent.Status.DesiredReplicas = 0
+			} else {
+				parent.Status.DeploymentRef = refs.NewTypedLocalObjectReferenceForObject(child, c.Scheme)
+				parent.Status.Replicas = child.Status.Replicas
+				parent.Status.DesiredReplicas = 0
+				if child.Spec.Replicas != nil {
+					parent.Status.DesiredReplicas = *child.Spec.Replicas
+				}
+				parent.Status.PropagateDeploymentStatus(&child.Status)
+			}
+		},
+		HarmonizeImmutableFields: func(current, desired *appsv1.Deployment) {
+			if desired.Spec.Replicas == nil {
+				// replicas are not managed by the deployer
+				desired.Spec.Replicas = current.Spec.Replicas
+			}
+			if current.Spec.Selector != nil {
+				// the selector is immutable, Deployments created before the
+				// track label keep selecting every pod of the deployer
+				desired.Spec.Selector = current.Spec.Selector
+			}
+		},
+		MergeBeforeUpdate: func(current, desired *appsv1.Deployment) {
+			current.Labels = desired.Labels
+			current.Spec = desired.Spec
+		},
+		SemanticEquals: func(a1, a2 *appsv1.Deployment) bool {
+			return equality.Semantic.DeepEqual(a1.Spec, a2.Spec) &&
+				equality.Semantic.DeepEqual(a1.Labels, a2.Labels)
+		},
+
+		Config:     c,
+		IndexField: ".metadata.deploymentController",
+		Sanitize: func(child *appsv1.Deployment) interface{} {
+			return child.Spec
+		},
+		OurChild: func(parent *corev1alpha1.Deployer, child *appsv1.Deployment) bool {
+			return child.Labels[corev1alpha1.DeployerCanaryLabelKey] == ""
+		},
+	}
+}
+
+// deployerReplicas returns the number of replicas within the bounds of the
+// scale. Autoscaled replicas are preserved while in bounds.
+func deployerReplicas(scale *corev1alpha1.Scale, current *int32) *int32 {
+	min, max := scaleBounds(scale)
+
+	replicas := min
+	if current != nil && *current > min {
+		replicas = *current
+	}
+	if replicas > max {
+		replicas = max
+	}
+	return &replicas
+}
+
+// scaleBounds returns the min and max replicas for the scale, the replicas
+// are autoscaled when max is greater than min
+func scaleBounds(scale *corev1alpha1.Scale) (int32, int32) {
+	min := int32(1)
+	if scale.Min != nil {
+		min = *scale.Min
+	}
+	max := min
+	if scale.Max != nil && *scale.Max > min {
+		max = *scale.Max
+	}
+	return min, max
+}
+
+func DeployerChildHorizontalPodAutoscalerReconciler(c controllers.Config) controllers.SubReconciler {
+	c.Log = c.Log.WithName("ChildHorizontalPodAutoscaler")
+
+	return &controllers.ChildReconciler{
+		ParentType:    &corev1alpha1.Deployer{},
+		ChildType:     &autoscalingv2beta2.HorizontalPodAutoscaler{},
+		ChildListType: &autoscalingv2beta2.HorizontalPodAutoscalerList{},
+
+		DesiredChild: func(parent *corev1alpha1.Deployer) (*autoscalingv2beta2.HorizontalPodAutoscaler, error) {
+			scale := parent.Spec.Scale
+			if scale == nil || parent.Status.DeploymentRef == nil {
+				// no deployment to scale, skip
+				return nil, nil
+			}
+			min, max := scaleBounds(scale)
+			if max <= min {
+				// not autoscaled, skip
+				return nil, nil
+			}
+
+			metrics := []autoscalingv2beta2.MetricSpec{}
+			if scale.TargetCPUUtilization != nil {
+				metrics = append(metrics, resourceUtilizationMetric(corev1.ResourceCPU, *scale.TargetCPUUtilization))
+			}
+			if scale.TargetMemoryUtilization != nil {
+				metrics = append(metrics, resourceUtilizationMetric(corev1.ResourceMemory, *scale.TargetMemoryUtilization))
+			}
+			if len(metrics) == 0 {
+				// the default metric for autoscalers
+				metrics = append(metrics, resourceUtilizationMetric(corev1.ResourceCPU, 80))
+			}
+
+			child := &autoscalingv2beta2.HorizontalPodAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: controllers.MergeMaps(parent.Labels, map[string]string{
+						corev1alpha1.DeployerLabelKey: parent.Name,
+					}),
+					Annotations:  make(map[string]string),
+					GenerateName: fmt.Sprintf("%s-deployer-", parent.Name),
+					Namespace:    parent.Namespace,
+				},
+				Spec: autoscalingv2beta2.HorizontalPodAutoscalerSpec{
+					ScaleTargetRef: autoscalingv2beta2.CrossVersionObjectReference{
+						APIVersion: "apps/v1",
+						Kind:       "Deployment",
+						Name:       parent.Status.DeploymentRef.Name,
 					},
+					MinReplicas: &min,
+					MaxReplicas: max,
+					Metrics:     metrics,
+				},
+			}
+
+			return child, nil
+		},
+		ReflectChildStatusOnParent: func(parent *corev1alpha1.Deployer, child *autoscalingv2beta2.HorizontalPodAutoscaler, err error) {
+			if err != nil {
+				return
+			}
+			if child == nil {
+				parent.Status.HorizontalPodAutoscalerRef = nil
+			} else {
+				parent.Status.HorizontalPodAutoscalerRef = refs.NewTypedLocalObjectReferenceForObject(child, c.Scheme)
+				if child.Status.DesiredReplicas != 0 {
+					parent.Status.DesiredReplicas = child.Status.DesiredReplicas
 				}
 			}
+		},
+		MergeBeforeUpdate: func(current, desired *autoscalingv2beta2.HorizontalPodAutoscaler) {
+			current.Labels = desired.Labels
+			current.Spec = desired.Spec
+		},
+		SemanticEquals: func(a1, a2 *autoscalingv2beta2.HorizontalPodAutoscaler) bool {
+			return equality.Semantic.DeepEqual(a1.Spec, a2.Spec) &&
+				equality.Semantic.DeepEqual(a1.Labels, a2.Labels)
+		},
+
+		Config:     c,
+		IndexField: ".metadata.horizontalPodAutoscalerController",
+		Sanitize: func(child *autoscalingv2beta2.HorizontalPodAutoscaler) interface{} {
+			return child.Spec
+		},
+	}
+}
+
+func resourceUtilizationMetric(name corev1.ResourceName, utilization int32) autoscalingv2beta2.MetricSpec {
+	return autoscalingv2beta2.MetricSpec{
+		Type: autoscalingv2beta2.ResourceMetricSourceType,
+		Resource: &autoscalingv2beta2.ResourceMetricSource{
+			Name: name,
+			Target: autoscalingv2beta2.MetricTarget{
+				Type:               autoscalingv2beta2.UtilizationMetricType,
+				AverageUtilization: &utilization,
+			},
+		},
+	}
+}
 
-			child := &appsv1.Deployment{
+func DeployerChildPodDisruptionBudgetReconciler(c controllers.Config) controllers.SubReconciler {
+	c.Log = c.Log.WithName("ChildPodDisruptionBudget")
+
+	return &controllers.ChildReconciler{
+		ParentType:    &corev1alpha1.Deployer{},
+		ChildType:     &policyv1beta1.PodDisruptionBudget{},
+		ChildListType: &policyv1beta1.PodDisruptionBudgetList{},
+
+		DesiredChild: func(parent *corev1alpha1.Deployer) (*policyv1beta1.PodDisruptionBudget, error) {
+			disruption := parent.Spec.Disruption
+			if disruption == nil {
+				return nil, nil
+			}
+
+			child := &policyv1beta1.PodDisruptionBudget{
 				ObjectMeta: metav1.ObjectMeta{
-					Labels:       labels,
+					Labels: controllers.MergeMaps(parent.Labels, map[string]string{
+						corev1alpha1.DeployerLabelKey: parent.Name,
+					}),
 					Annotations:  make(map[string]string),
 					GenerateName: fmt.Sprintf("%s-deployer-", parent.Name),
 					Namespace:    parent.Namespace,
 				},
-				Spec: appsv1.DeploymentSpec{
+				Spec: policyv1beta1.PodDisruptionBudgetSpec{
+					// includes the canary's pods during a rollout
 					Selector: &metav1.LabelSelector{
 						MatchLabels: map[string]string{
 							corev1alpha1.DeployerLabelKey: parent.Name,
 						},
 					},
-					Template: template,
+					MinAvailable:   disruption.MinAvailable,
+					MaxUnavailable: disruption.MaxUnavailable,
 				},
 			}
-			if child.Spec.Template.Spec.Containers[0].Image == "" {
-				child.Spec.Template.Spec.Containers[0].Image = parent.Status.LatestImage
-			}
 
 			return child, nil
 		},
-		ReflectChildStatusOnParent: func(parent *corev1alpha1.Deployer, child *appsv1.Deployment, err error) {
+		ReflectChildStatusOnParent: func(parent *corev1alpha1.Deployer, child *policyv1beta1.PodDisruptionBudget, err error) {
 			if err != nil {
 				return
 			}
 			if child == nil {
-				parent.Status.DeploymentRef = nil
+				parent.Status.PodDisruptionBudgetRef = nil
 			} else {
-				parent.Status.DeploymentRef = refs.NewTypedLocalObjectReferenceForObject(child, c.Scheme)
-				parent.Status.PropagateDeploymentStatus(&child.Status)
+				parent.Status.PodDisruptionBudgetRef = refs.NewTypedLocalObjectReferenceForObject(child, c.Scheme)
 			}
 		},
-		HarmonizeImmutableFields: func(current, desired *appsv1.Deployment) {
-			desired.Spec.Replicas = current.Spec.Replicas
+		MergeBeforeUpdate: func(current, desired *policyv1beta1.PodDisruptionBudget) {
+			current.Labels = desired.Labels
+			current.Spec = desired.Spec
 		},
-		MergeBeforeUpdate: func(current, desired *appsv1.Deployment) {
+		SemanticEquals: func(a1, a2 *policyv1beta1.PodDisruptionBudget) bool {
+			return equality.Semantic.DeepEqual(a1.Spec, a2.Spec) &&
+				equality.Semantic.DeepEqual(a1.Labels, a2.Labels)
+		},
+
+		Config:     c,
+		IndexField: ".metadata.podDisruptionBudgetController",
+		Sanitize: func(child *policyv1beta1.PodDisruptionBudget) interface{} {
+			return child.Spec
+		},
+	}
+}
+
+// namespaceNameLabelKey is set by Kubernetes 1.21 and later on each
+// namespace to the namespace's name. On older clusters the label must be
+// added to the ingress namespace by hand.
+const namespaceNameLabelKey = "kubernetes.io/metadata.name"
+
+// namespaceNameLabelVersion is the first Kubernetes version to label
+// namespaces with their name
+var namespaceNameLabelVersion = version.MustParseGeneric("1.21.0")
+
+// DiscoverNamespaceNameLabel returns true when the cluster labels each
+// namespace with its name, which NetworkPolicies select the ingress
+// namespace by.
+func DiscoverNamespaceNameLabel(d discovery.ServerVersionInterface) (bool, error) {
+	info, err := d.ServerVersion()
+	if err != nil {
+		return false, err
+	}
+	serverVersion, err := version.ParseGeneric(info.GitVersion)
+	if err != nil {
+		return false, err
+	}
+	return serverVersion.AtLeast(namespaceNameLabelVersion), nil
+}
+
+func DeployerChildNetworkPolicyReconciler(c controllers.Config, namespace string) controllers.SubReconciler {
+	c.Log = c.Log.WithName("ChildNetworkPolicy")
+
+	return &controllers.ChildReconciler{
+		ParentType:    &corev1alpha1.Deployer{},
+		ChildType:     &k8snetworkingv1.NetworkPolicy{},
+		ChildListType: &k8snetworkingv1.NetworkPolicyList{},
+
+		DesiredChild: func(ctx context.Context, parent *corev1alpha1.Deployer) (*k8snetworkingv1.NetworkPolicy, error) {
+			networkPolicy := parent.Spec.NetworkPolicy
+			if networkPolicy == nil {
+				return nil, nil
+			}
+
+			var peers []k8snetworkingv1.NetworkPolicyPeer
+			if parent.Spec.IngressPolicy == corev1alpha1.IngressPolicyExternal {
+				coreSettings := &corev1.ConfigMap{}
+				coreSettingsKey := types.NamespacedName{Namespace: namespace, Name: settingsConfigMapName}
+
+				// track config map
+				c.Tracker.Track(
+					tracker.NewKey(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, coreSettingsKey),
+					types.NamespacedName{Namespace: parent.Namespace, Name: parent.Name},
+				)
+				if err := c.Get(ctx, coreSettingsKey, coreSettings); err != nil {
+					c.Log.Error(err, fmt.Sprintf("unable to fetch resource with reference: %s", coreSettingsKey.String()))
+					return nil, err
+				}
+				ingressNamespace, err := deployerIngressNamespace(parent, coreSettings)
+				if err != nil {
+					return nil, err
+				}
+
+				peers = append(peers, k8snetworkingv1.NetworkPolicyPeer{
+					NamespaceSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							namespaceNameLabelKey: ingressNamespace,
+						},
+					},
+				})
+			} else {
+				// any pod in the deployer's namespace
+				peers = append(peers, k8snetworkingv1.NetworkPolicyPeer{
+					PodSelector: &metav1.LabelSelector{},
+				})
+				if networkPolicy.NamespaceSelector != nil {
+					peers = append(peers, k8snetworkingv1.NetworkPolicyPeer{
+						NamespaceSelector: networkPolicy.NamespaceSelector.DeepCopy(),
+					})
+				}
+			}
+
+			targetPort := parent.Spec.Template.Spec.Containers[0].Ports[0]
+			port := intstr.FromInt(int(targetPort.ContainerPort))
+			child := &k8snetworkingv1.NetworkPolicy{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: controllers.MergeMaps(parent.Labels, map[string]string{
+						corev1alpha1.DeployerLabelKey: parent.Name,
+					}),
+					Annotations:  make(map[string]string),
+					GenerateName: fmt.Sprintf("%s-deployer-", parent.Name),
+					Namespace:    parent.Namespace,
+				},
+				Spec: k8snetworkingv1.NetworkPolicySpec{
+					// includes the canary's pods during a rollout
+					PodSelector: metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							corev1alpha1.DeployerLabelKey: parent.Name,
+						},
+					},
+					PolicyTypes: []k8snetworkingv1.PolicyType{k8snetworkingv1.PolicyTypeIngress},
+					Ingress: []k8snetworkingv1.NetworkPolicyIngressRule{
+						{
+							Ports: []k8snetworkingv1.NetworkPolicyPort{
+								{Protocol: &targetPort.Protocol, Port: &port},
+							},
+							From: peers,
+						},
+					},
+				},
+			}
+
+			return child, nil
+		},
+		ReflectChildStatusOnParent: func(parent *corev1alpha1.Deployer, child *k8snetworkingv1.NetworkPolicy, err error) {
+			if err != nil {
+				return
+			}
+			if child == nil {
+				parent.Status.NetworkPolicyRef = nil
+			} else {
+				parent.Status.NetworkPolicyRef = refs.NewTypedLocalObjectReferenceForObject(child, c.Scheme)
+			}
+		},
+		MergeBeforeUpdate: func(current, desired *k8snetworkingv1.NetworkPolicy) {
 			current.Labels = desired.Labels
 			current.Spec = desired.Spec
 		},
-		SemanticEquals: func(a1, a2 *appsv1.Deployment) bool {
+		SemanticEquals: func(a1, a2 *k8snetworkingv1.NetworkPolicy) bool {
 			return equality.Semantic.DeepEqual(a1.Spec, a2.Spec) &&
 				equality.Semantic.DeepEqual(a1.Labels, a2.Labels)
 		},
 
 		Config:     c,
-		IndexField: ".metadata.deploymentController",
-		Sanitize: func(child *appsv1.Deployment) interface{} {
+		IndexField: ".metadata.networkPolicyController",
+		Sanitize: func(child *k8snetworkingv1.NetworkPolicy) interface{} {
 			return child.Spec
 		},
 	}
 }
 
+// deployerIngressNamespace returns the namespace connections to a deployer
+// with an External ingress policy come from, the Gateway's namespace when
+// routing with HTTPRoutes, otherwise the ingress controller's namespace
+func deployerIngressNamespace(parent *corev1alpha1.Deployer, coreSettings *corev1.ConfigMap) (string, error) {
+	if coreSettings.Data[routingBackendKey] == routingBackendHTTPRoute {
+		gateway, err := deployerGateway(parent, coreSettings.Data[gatewayKey])
+		if err != nil {
+			return "", err
+		}
+		return *gateway.Namespace, nil
+	}
+	if ns := coreSettings.Data[ingressNamespaceKey]; ns != "" {
+		return ns, nil
+	}
+	return defaultIngressNamespace, nil
+}
+
+// deployerDeployment runs the image on the track, pods of the stable and
+// canary tracks are selected by their own Deployment only
+func deployerDeployment(parent *corev1alpha1.Deployer, image string, bindings []DeployerResolvedBinding, track string) *appsv1.Deployment {
+	labels := controllers.MergeMaps(parent.Labels, map[string]string{
+		corev1alpha1.DeployerLabelKey: parent.Name,
+	})
+
+	template := *parent.Spec.Template.DeepCopy()
+	template.Labels = controllers.MergeMaps(template.Labels, labels, map[string]string{
+		corev1alpha1.DeployerTrackLabelKey: track,
+	})
+	targetPort := template.Spec.Containers[0].Ports[0]
+
+	template.Spec.Containers[0].Env = append(template.Spec.Containers[0].Env, corev1.EnvVar{
+		Name:  "PORT",
+		Value: fmt.Sprintf("%d", targetPort.ContainerPort),
+	})
+	if probes := parent.Spec.Probes; probes != nil {
+		if probes.Readiness != nil {
+			template.Spec.Containers[0].ReadinessProbe = deployerProbe(probes.Readiness, targetPort)
+		}
+		if probes.Liveness != nil {
+			template.Spec.Containers[0].LivenessProbe = deployerProbe(probes.Liveness, targetPort)
+		}
+		if probes.Startup != nil {
+			template.Spec.Containers[0].StartupProbe = deployerProbe(probes.Startup, targetPort)
+		}
+	}
+	if template.Spec.Containers[0].ReadinessProbe == nil {
+		template.Spec.Containers[0].ReadinessProbe = deployerProbe(&corev1alpha1.Probe{}, targetPort)
+	}
+	// the image has registry rewrites applied
+	template.Spec.Containers[0].Image = image
+	applyDeployerBindings(&template, bindings)
+
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels:       labels,
+			Annotations:  make(map[string]string),
+			GenerateName: fmt.Sprintf("%s-deployer-", parent.Name),
+			Namespace:    parent.Namespace,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					corev1alpha1.DeployerLabelKey:      parent.Name,
+					corev1alpha1.DeployerTrackLabelKey: track,
+				},
+			},
+			Template:                template,
+			MinReadySeconds:         parent.Spec.MinReadySeconds,
+			ProgressDeadlineSeconds: parent.Spec.ProgressDeadlineSeconds,
+		},
+	}
+}
+
+// deployerProbe checks the container port with an HTTP GET request to the
+// probe's path, or by opening a TCP connection when no path is set
+func deployerProbe(probe *corev1alpha1.Probe, port corev1.ContainerPort) *corev1.Probe {
+	handler := corev1.Handler{
+		TCPSocket: &corev1.TCPSocketAction{
+			Port: intstr.FromInt(int(port.ContainerPort)),
+		},
+	}
+	if probe.Path != "" {
+		handler = corev1.Handler{
+			HTTPGet: &corev1.HTTPGetAction{
+				Path: probe.Path,
+				Port: intstr.FromInt(int(port.ContainerPort)),
+			},
+		}
+	}
+	return &corev1.Probe{
+		Handler:             handler,
+		InitialDelaySeconds: probe.InitialDelaySeconds,
+		PeriodSeconds:       probe.PeriodSeconds,
+		TimeoutSeconds:      probe.TimeoutSeconds,
+		SuccessThreshold:    probe.SuccessThreshold,
+		FailureThreshold:    probe.FailureThreshold,
+	}
+}
+
 func DeployerChildServiceReconciler(c controllers.Config) controllers.SubReconciler {
 	c.Log = c.Log.WithName("ChildService")
 
@@ -270,6 +782,11 @@ func DeployerChildServiceReconciler(c controllers.Config) controllers.SubReconci
 					},
 				},
 			}
+			if rolloutInProgress(parent) != nil && parent.Spec.Rollout.TrafficRouting == corev1alpha1.RolloutTrafficRoutingIngress {
+				// the ingress splits traffic, keep canary pods out of the
+				// stable Service
+				child.Spec.Selector[corev1alpha1.DeployerTrackLabelKey] = corev1alpha1.DeployerTrackStable
+			}
 
 			return child, nil
 		},
@@ -307,76 +824,60 @@ func DeployerChildServiceReconciler(c controllers.Config) controllers.SubReconci
 		Sanitize: func(child *corev1.Service) interface{} {
 			return child.Spec
 		},
+		OurChild: func(parent *corev1alpha1.Deployer, child *corev1.Service) bool {
+			return child.Labels[corev1alpha1.DeployerCanaryLabelKey] == ""
+		},
 	}
 }
 
-func DeployerChildIngressReconciler(c controllers.Config) controllers.SubReconciler {
+func DeployerChildIngressReconciler(c controllers.Config, ingressVersion schema.GroupVersion) controllers.SubReconciler {
 	c.Log = c.Log.WithName("ChildIngress")
 
-	return &controllers.ChildReconciler{
-		ParentType:    &corev1alpha1.Deployer{},
-		ChildType:     &networkingv1beta1.Ingress{},
-		ChildListType: &networkingv1beta1.IngressList{},
-
-		Setup: func(mgr controllers.Manager, bldr *controllers.Builder) error {
-			bldr.Watches(&source.Kind{Type: &corev1.ConfigMap{}}, controllers.EnqueueTracked(&corev1.ConfigMap{}, c.Tracker, c.Scheme))
-			return nil
-		},
-		DesiredChild: func(parent *corev1alpha1.Deployer) (*networkingv1beta1.Ingress, error) {
-			if parent.Status.ServiceRef == nil || parent.Spec.IngressPolicy == corev1alpha1.IngressPolicyClusterLocal {
-				// no service, skip
+	return deployerIngressChildReconciler(c, ingressVersion, ".metadata.ingressController",
+		func(ctx context.Context, parent *corev1alpha1.Deployer) (*networkingv1beta1.Ingress, error) {
+			ingress, ok := controllers.RetrieveValue(ctx, DeployerIngressStashKey).(*DeployerIngress)
+			if !ok {
+				// no ingress, skip
 				return nil, nil
 			}
 
-			coreSettings := &corev1.ConfigMap{}
-			coreSettingsKey := types.NamespacedName{Namespace: systemNamespace, Name: settingsConfigMapName}
-
-			// track config map
-			c.Tracker.Track(
-				tracker.NewKey(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, coreSettingsKey),
-				types.NamespacedName{Namespace: parent.Namespace, Name: parent.Name},
-			)
-			if err := c.Get(context.TODO(), coreSettingsKey, coreSettings); err != nil {
-				c.Log.Error(err, fmt.Sprintf("unable to fetch resource with reference: %s", coreSettingsKey.String()))
-				return nil, err
-			}
-
-			domain := defaultDomain
-			if d := coreSettings.Data[defaultDomainKey]; d != "" {
-				domain = d
-			}
-			host := fmt.Sprintf("%s.%s.%s", parent.Name, parent.Namespace, domain)
-
 			child := &networkingv1beta1.Ingress{
 				ObjectMeta: metav1.ObjectMeta{
 					Labels: controllers.MergeMaps(parent.Labels, map[string]string{
 						corev1alpha1.DeployerLabelKey: parent.Name,
 					}),
-					Annotations:  make(map[string]string),
+					Annotations:  controllers.MergeMaps(ingress.Annotations),
 					GenerateName: fmt.Sprintf("%s-deployer-", parent.Name),
 					Namespace:    parent.Namespace,
 				},
-				Spec: networkingv1beta1.IngressSpec{
-					Rules: []networkingv1beta1.IngressRule{{
-						Host: host,
-						IngressRuleValue: networkingv1beta1.IngressRuleValue{
-							HTTP: &networkingv1beta1.HTTPIngressRuleValue{
-								Paths: []networkingv1beta1.HTTPIngressPath{{
-									Path: "/",
-									Backend: networkingv1beta1.IngressBackend{
-										ServiceName: parent.Status.ServiceRef.Name,
-										ServicePort: intstr.FromInt(80),
-									},
-								}},
-							},
+			}
+			for _, host := range ingress.Hosts {
+				child.Spec.Rules = append(child.Spec.Rules, networkingv1beta1.IngressRule{
+					Host: host,
+					IngressRuleValue: networkingv1beta1.IngressRuleValue{
+						HTTP: &networkingv1beta1.HTTPIngressRuleValue{
+							Paths: []networkingv1beta1.HTTPIngressPath{{
+								Path: ingress.Path,
+								Backend: networkingv1beta1.IngressBackend{
+									ServiceName: parent.Status.ServiceRef.Name,
+									ServicePort: intstr.FromInt(80),
+								},
+							}},
 						},
-					}},
-				},
+					},
+				})
+			}
+
+			if tls, ok := controllers.RetrieveValue(ctx, DeployerIngressTLSStashKey).(*DeployerIngressTLS); ok {
+				child.Spec.TLS = []networkingv1beta1.IngressTLS{{
+					Hosts:      ingress.Hosts,
+					SecretName: tls.SecretName,
+				}}
 			}
 
 			return child, nil
 		},
-		ReflectChildStatusOnParent: func(parent *corev1alpha1.Deployer, child *networkingv1beta1.Ingress, err error) {
+		func(parent *corev1alpha1.Deployer, child *networkingv1beta1.Ingress, err error) {
 			if err != nil {
 				return
 			}
@@ -388,23 +889,19 @@ func DeployerChildIngressReconciler(c controllers.Config) controllers.SubReconci
 				}
 			} else {
 				parent.Status.IngressRef = refs.NewTypedLocalObjectReferenceForObject(child, c.Scheme)
-				parent.Status.URL = fmt.Sprintf("http://%s", child.Spec.Rules[0].Host)
+				scheme := "http"
+				if len(child.Spec.TLS) != 0 {
+					scheme = "https"
+				}
+				parent.Status.URL = fmt.Sprintf("%s://%s", scheme, child.Spec.Rules[0].Host)
+				if path := child.Spec.Rules[0].HTTP.Paths[0].Path; path != "/" {
+					parent.Status.URL += path
+				}
 				parent.Status.PropagateIngressStatus(&child.Status)
 			}
 		},
-		MergeBeforeUpdate: func(current, desired *networkingv1beta1.Ingress) {
-			current.Labels = desired.Labels
-			current.Spec = desired.Spec
+		func(parent *corev1alpha1.Deployer, child *networkingv1beta1.Ingress) bool {
+			return child.Labels[corev1alpha1.DeployerCanaryLabelKey] == ""
 		},
-		SemanticEquals: func(a1, a2 *networkingv1beta1.Ingress) bool {
-			return equality.Semantic.DeepEqual(a1.Spec, a2.Spec) &&
-				equality.Semantic.DeepEqual(a1.Labels, a2.Labels)
-		},
-
-		Config:     c,
-		IndexField: ".metadata.ingressController",
-		Sanitize: func(child *networkingv1beta1.Ingress) interface{} {
-			return child.Spec
-		},
-	}
+	)
 }
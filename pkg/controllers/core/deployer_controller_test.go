@@ -18,23 +18,35 @@ package core_test
 
 import (
 	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
+	k8snetworkingv1 "k8s.io/api/networking/v1"
+	networkingv1beta1 "k8s.io/api/networking/v1beta1"
 	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	clientgotesting "k8s.io/client-go/testing"
 	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	buildv1alpha1 "github.com/projectriff/system/pkg/apis/build/v1alpha1"
 	corev1alpha1 "github.com/projectriff/system/pkg/apis/core/v1alpha1"
+	streamingv1alpha1 "github.com/projectriff/system/pkg/apis/streaming/v1alpha1"
+	gatewayv1 "github.com/projectriff/system/pkg/apis/thirdparty/gateway/v1"
+	networkingv1 "github.com/projectriff/system/pkg/apis/thirdparty/kubernetes/networking/v1"
 	"github.com/projectriff/system/pkg/controllers"
+	"github.com/projectriff/system/pkg/refs"
 
 	corecontrollers "github.com/projectriff/system/pkg/controllers/core"
 	rtesting "github.com/projectriff/system/pkg/controllers/testing"
@@ -44,11 +56,14 @@ import (
 
 func TestDeployerReconciler(t *testing.T) {
 	testNamespace := "test-namespace"
+	testSystemNamespace := "riff-system"
 	testName := "test-deployer"
 	testKey := types.NamespacedName{Namespace: testNamespace, Name: testName}
 	testImagePrefix := "example.com/repo"
 	testSha256 := "cf8b4c69d5460f88530e1c80b8856a70801f31c50b191c8413043ba9b160a43e"
 	testImage := fmt.Sprintf("%s@sha256:%s", testImagePrefix, testSha256)
+	testDeployedTime := metav1.NewTime(time.Date(2020, 2, 3, 4, 5, 6, 0, time.UTC))
+	testRevision := corev1alpha1.DeployerRevision{Image: testImage, DeployedTime: testDeployedTime}
 	testConditionReason := "TestReason"
 	testConditionMessage := "meaningful, yet concise"
 	testDomain := "example.com"
@@ -57,6 +72,16 @@ func TestDeployerReconciler(t *testing.T) {
 	testAddressURL := fmt.Sprintf("http://%s.%s.svc.cluster.local", testName, testNamespace)
 	testLabelKey := "test-label-key"
 	testLabelValue := "test-label-value"
+	testStableImage := fmt.Sprintf("%s@sha256:%s", testImagePrefix, "0ea9b4a5ee4ab2adc77e2e5c9a3e2bf44e61f9f4aff4ad5d6e8b6bac3f02a1f5")
+	testStepStartTime := metav1.NewTime(time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC))
+	testRollout := &corev1alpha1.Rollout{
+		Steps: []corev1alpha1.RolloutStep{
+			{Weight: 25},
+		},
+		TrafficRouting: corev1alpha1.RolloutTrafficRoutingReplicas,
+	}
+	testIngressRollout := testRollout.DeepCopy()
+	testIngressRollout.TrafficRouting = corev1alpha1.RolloutTrafficRoutingIngress
 
 	deployerConditionDeploymentReady := factories.Condition().Type(corev1alpha1.DeployerConditionDeploymentReady)
 	deployerConditionIngressReady := factories.Condition().Type(corev1alpha1.DeployerConditionIngressReady)
@@ -67,14 +92,21 @@ func TestDeployerReconciler(t *testing.T) {
 	_ = clientgoscheme.AddToScheme(scheme)
 	_ = buildv1alpha1.AddToScheme(scheme)
 	_ = corev1alpha1.AddToScheme(scheme)
+	_ = streamingv1alpha1.AddToScheme(scheme)
 
 	deployerMinimal := factories.DeployerCore().
 		NamespaceName(testNamespace, testName)
 	deployerValid := deployerMinimal.
 		Image(testImage).
 		IngressPolicy(corev1alpha1.IngressPolicyClusterLocal)
+	otherDeployer := factories.DeployerCore().
+		NamespaceName(testNamespace, "other-deployer").
+		Image(testImage).
+		IngressPolicy(corev1alpha1.IngressPolicyExternal)
 
-	deploymentCreate := factories.Deployment().
+	// deployments created before the track label select every pod of the
+	// deployer
+	deploymentUntrackedCreate := factories.Deployment().
 		ObjectMeta(func(om factories.ObjectMeta) {
 			om.Namespace(testNamespace)
 			om.GenerateName("%s-deployer-", deployerMinimal.Create().GetName())
@@ -98,12 +130,51 @@ func TestDeployerReconciler(t *testing.T) {
 				},
 			}
 		})
+	deploymentCreate := deploymentUntrackedCreate.
+		AddSelectorLabel(corev1alpha1.DeployerTrackLabelKey, corev1alpha1.DeployerTrackStable)
 	deploymentGiven := deploymentCreate.
 		ObjectMeta(func(om factories.ObjectMeta) {
 			om.Name("%s%s", om.Create().GenerateName, "000")
 			om.Created(1)
 		})
 
+	horizontalPodAutoscalerCreate := factories.HorizontalPodAutoscaler().
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.Namespace(testNamespace)
+			om.GenerateName("%s-deployer-", deployerMinimal.Create().GetName())
+			om.AddLabel(corev1alpha1.DeployerLabelKey, deployerMinimal.Create().GetName())
+			om.ControlledBy(deployerMinimal, scheme)
+		}).
+		ScaleTargetDeployment(deploymentGiven.Create().GetName())
+	horizontalPodAutoscalerGiven := horizontalPodAutoscalerCreate.
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.Name("%s%s", om.Create().GenerateName, "000")
+			om.Created(1)
+		})
+
+	podDisruptionBudgetCreate := factories.PodDisruptionBudget().
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.Namespace(testNamespace)
+			om.GenerateName("%s-deployer-", deployerMinimal.Create().GetName())
+			om.AddLabel(corev1alpha1.DeployerLabelKey, deployerMinimal.Create().GetName())
+			om.ControlledBy(deployerMinimal, scheme)
+		}).
+		AddSelectorLabel(corev1alpha1.DeployerLabelKey, deployerMinimal.Create().GetName())
+	podDisruptionBudgetGiven := podDisruptionBudgetCreate.
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.Name("%s%s", om.Create().GenerateName, "000")
+			om.Created(1)
+		})
+
+	networkPolicyCreate := factories.NetworkPolicy().
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.Namespace(testNamespace)
+			om.GenerateName("%s-deployer-", deployerMinimal.Create().GetName())
+			om.AddLabel(corev1alpha1.DeployerLabelKey, deployerMinimal.Create().GetName())
+			om.ControlledBy(deployerMinimal, scheme)
+		}).
+		AddSelectorLabel(corev1alpha1.DeployerLabelKey, deployerMinimal.Create().GetName())
+
 	serviceCreate := factories.Service().
 		NamespaceName(testNamespace, testName).
 		ObjectMeta(func(om factories.ObjectMeta) {
@@ -124,13 +195,14 @@ func TestDeployerReconciler(t *testing.T) {
 			om.ControlledBy(deployerMinimal, scheme)
 		})
 
-	ingressCreate := factories.Ingress().
+	ingressWithoutRules := factories.Ingress().
 		ObjectMeta(func(om factories.ObjectMeta) {
 			om.Namespace(testNamespace)
 			om.GenerateName("%s-deployer-", deployerMinimal.Create().GetName())
 			om.AddLabel(corev1alpha1.DeployerLabelKey, deployerMinimal.Create().GetName())
 			om.ControlledBy(deployerMinimal, scheme)
-		}).
+		})
+	ingressCreate := ingressWithoutRules.
 		HostToService(testHost, serviceGiven.Create().GetName())
 	ingressGiven := ingressCreate.
 		ObjectMeta(func(om factories.ObjectMeta) {
@@ -138,18 +210,30 @@ func TestDeployerReconciler(t *testing.T) {
 			om.Created(1)
 		})
 
+	testStream := factories.Stream().
+		NamespaceName(testNamespace, "my-stream").
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.UID("my-stream-uid")
+		}).
+		StatusBinding("my-stream-metadata", "my-stream-secret")
+
 	testApplication := factories.Application().
 		NamespaceName(testNamespace, "my-application").
 		StatusLatestImage(testImage)
 	testFunction := factories.Function().
 		NamespaceName(testNamespace, "my-function").
 		StatusLatestImage(testImage)
+	testImagePromotion := factories.ImagePromotion().
+		NamespaceName(testNamespace, "my-image-promotion").
+		StatusLatestImage(testImage)
 	testContainer := factories.Container().
 		NamespaceName(testNamespace, "my-container").
 		StatusLatestImage(testImage)
 
+	testRegistryRewrites := factories.ConfigMap().
+		NamespaceName(testSystemNamespace, "riff-registry-rewrites")
 	testSettings := factories.ConfigMap().
-		NamespaceName("riff-system", "riff-core-settings").
+		NamespaceName(testSystemNamespace, "riff-core-settings").
 		AddData("defaultDomain", "example.com")
 
 	table := rtesting.Table{{
@@ -180,6 +264,7 @@ func TestDeployerReconciler(t *testing.T) {
 			testApplication,
 		},
 		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
 			rtesting.NewTrackRequest(testApplication, deployerMinimal, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
@@ -203,6 +288,8 @@ func TestDeployerReconciler(t *testing.T) {
 					deployerConditionServiceReady.True(),
 				).
 				StatusLatestImage(testImage).
+				StatusImageSource(corev1alpha1.DeployerImageSourceBuild).
+				StatusHistory(testRevision).
 				StatusDeploymentRef("%s-deployer-001", deployerMinimal.Create().GetName()).
 				StatusServiceRef(deployerMinimal.Create().GetName()).
 				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
@@ -219,6 +306,7 @@ func TestDeployerReconciler(t *testing.T) {
 				`Updated status`),
 		},
 		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
 			rtesting.NewTrackRequest(testApplication, deployerMinimal, scheme),
 		},
 		ExpectStatusUpdates: []rtesting.Factory{
@@ -240,6 +328,7 @@ func TestDeployerReconciler(t *testing.T) {
 				StatusLatestImage(""),
 		},
 		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
 			rtesting.NewTrackRequest(testApplication, deployerMinimal, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
@@ -264,6 +353,7 @@ func TestDeployerReconciler(t *testing.T) {
 			testFunction,
 		},
 		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
 			rtesting.NewTrackRequest(testFunction, deployerMinimal, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
@@ -287,6 +377,47 @@ func TestDeployerReconciler(t *testing.T) {
 					deployerConditionServiceReady.True(),
 				).
 				StatusLatestImage(testImage).
+				StatusImageSource(corev1alpha1.DeployerImageSourceBuild).
+				StatusHistory(testRevision).
+				StatusDeploymentRef("%s-deployer-001", deployerMinimal.Create().GetName()).
+				StatusServiceRef(deployerMinimal.Create().GetName()).
+				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
+		},
+	}, {
+		Name: "create resources, from image promotion",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerMinimal.
+				ImagePromotionRef(testImagePromotion.Create().GetName()),
+			testImagePromotion,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testImagePromotion, deployerMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created Deployment "%s-deployer-001"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created Service "%s"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectCreates: []rtesting.Factory{
+			deploymentCreate,
+			serviceCreate,
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusImageSource(corev1alpha1.DeployerImageSourceBuild).
+				StatusHistory(testRevision).
 				StatusDeploymentRef("%s-deployer-001", deployerMinimal.Create().GetName()).
 				StatusServiceRef(deployerMinimal.Create().GetName()).
 				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
@@ -303,6 +434,7 @@ func TestDeployerReconciler(t *testing.T) {
 				`Updated status`),
 		},
 		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
 			rtesting.NewTrackRequest(testFunction, deployerMinimal, scheme),
 		},
 		ExpectStatusUpdates: []rtesting.Factory{
@@ -324,6 +456,7 @@ func TestDeployerReconciler(t *testing.T) {
 				StatusLatestImage(""),
 		},
 		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
 			rtesting.NewTrackRequest(testFunction, deployerMinimal, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
@@ -348,6 +481,7 @@ func TestDeployerReconciler(t *testing.T) {
 			testContainer,
 		},
 		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
 			rtesting.NewTrackRequest(testContainer, deployerMinimal, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
@@ -371,6 +505,8 @@ func TestDeployerReconciler(t *testing.T) {
 					deployerConditionServiceReady.True(),
 				).
 				StatusLatestImage(testImage).
+				StatusImageSource(corev1alpha1.DeployerImageSourceBuild).
+				StatusHistory(testRevision).
 				StatusDeploymentRef("%s-deployer-001", deployerMinimal.Create().GetName()).
 				StatusServiceRef(deployerMinimal.Create().GetName()).
 				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
@@ -383,6 +519,7 @@ func TestDeployerReconciler(t *testing.T) {
 				ContainerRef(testContainer.Create().GetName()),
 		},
 		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
 			rtesting.NewTrackRequest(testContainer, deployerMinimal, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
@@ -408,6 +545,7 @@ func TestDeployerReconciler(t *testing.T) {
 				StatusLatestImage(""),
 		},
 		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
 			rtesting.NewTrackRequest(testContainer, deployerMinimal, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
@@ -430,6 +568,9 @@ func TestDeployerReconciler(t *testing.T) {
 			deployerMinimal.
 				Image(testImage),
 		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
+		},
 		ExpectEvents: []rtesting.Event{
 			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
 				`Created Deployment "%s-deployer-001"`, testName),
@@ -451,115 +592,153 @@ func TestDeployerReconciler(t *testing.T) {
 					deployerConditionServiceReady.True(),
 				).
 				StatusLatestImage(testImage).
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
 				StatusDeploymentRef("%s-deployer-001", deployerMinimal.Create().GetName()).
 				StatusServiceRef(deployerMinimal.Create().GetName()).
 				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
 		},
 	}, {
-		Name: "create deployment, error",
+		Name: "create resources, with fixed scale",
 		Key:  testKey,
-		WithReactors: []rtesting.ReactionFunc{
-			rtesting.InduceFailure("create", "Deployment"),
-		},
 		GivenObjects: []rtesting.Factory{
 			deployerMinimal.
-				Image(testImage),
+				Image(testImage).
+				Scale(&corev1alpha1.Scale{Min: rtesting.Int32Ptr(3), Max: rtesting.Int32Ptr(3)}),
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
 		},
-		ShouldErr: true,
 		ExpectEvents: []rtesting.Event{
-			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeWarning, "CreationFailed",
-				`Failed to create Deployment "": inducing failure for create Deployment`),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created Deployment "%s-deployer-001"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created Service "%s"`, testName),
 			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
 				`Updated status`),
 		},
 		ExpectCreates: []rtesting.Factory{
-			deploymentCreate,
+			deploymentCreate.
+				Replicas(3),
+			serviceCreate,
 		},
 		ExpectStatusUpdates: []rtesting.Factory{
 			deployerMinimal.
 				StatusConditions(
 					deployerConditionDeploymentReady.Unknown(),
-					deployerConditionIngressReady.Unknown(),
+					deployerConditionIngressReady.True(),
 					deployerConditionReady.Unknown(),
-					deployerConditionServiceReady.Unknown(),
+					deployerConditionServiceReady.True(),
 				).
-				StatusLatestImage(testImage),
+				StatusLatestImage(testImage).
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
+				StatusDeploymentRef("%s-deployer-001", deployerMinimal.Create().GetName()).
+				StatusReplicas(0, 3).
+				StatusServiceRef(deployerMinimal.Create().GetName()).
+				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
 		},
 	}, {
-		Name: "create service, error",
+		Name: "create resources, with autoscaling",
 		Key:  testKey,
-		WithReactors: []rtesting.ReactionFunc{
-			rtesting.InduceFailure("create", "Service"),
-		},
 		GivenObjects: []rtesting.Factory{
 			deployerMinimal.
-				Image(testImage),
+				Image(testImage).
+				Scale(&corev1alpha1.Scale{Min: rtesting.Int32Ptr(2), Max: rtesting.Int32Ptr(5)}).
+				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()),
+			deploymentGiven.
+				Replicas(2).
+				StatusReplicas(2),
+			serviceGiven,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
 		},
-		ShouldErr: true,
 		ExpectEvents: []rtesting.Event{
 			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
-				`Created Deployment "%s-deployer-001"`, testName),
-			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeWarning, "CreationFailed",
-				`Failed to create Service "%s": inducing failure for create Service`, testName),
+				`Created HorizontalPodAutoscaler "%s-deployer-001"`, testName),
 			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
 				`Updated status`),
 		},
 		ExpectCreates: []rtesting.Factory{
-			deploymentCreate,
-			serviceCreate,
+			horizontalPodAutoscalerCreate.
+				Replicas(2, 5).
+				AddResourceUtilizationMetric(corev1.ResourceCPU, 80),
 		},
 		ExpectStatusUpdates: []rtesting.Factory{
 			deployerMinimal.
 				StatusConditions(
 					deployerConditionDeploymentReady.Unknown(),
-					deployerConditionIngressReady.Unknown(),
+					deployerConditionIngressReady.True(),
 					deployerConditionReady.Unknown(),
-					deployerConditionServiceReady.Unknown(),
+					deployerConditionServiceReady.True(),
 				).
 				StatusLatestImage(testImage).
-				StatusDeploymentRef("%s-deployer-001", deployerMinimal.Create().GetName()),
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
+				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusReplicas(2, 2).
+				StatusHorizontalPodAutoscalerRef("%s-deployer-001", deployerMinimal.Create().GetName()).
+				StatusServiceRef(deployerMinimal.Create().GetName()).
+				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
 		},
 	}, {
-		Name: "create service, conflicted",
+		Name: "create resources, with autoscaling metrics",
 		Key:  testKey,
-		WithReactors: []rtesting.ReactionFunc{
-			rtesting.InduceFailure("create", "Service", rtesting.InduceFailureOpts{
-				Error: apierrs.NewAlreadyExists(schema.GroupResource{}, testName),
-			}),
-		},
 		GivenObjects: []rtesting.Factory{
 			deployerMinimal.
-				Image(testImage),
+				Image(testImage).
+				Scale(&corev1alpha1.Scale{
+					Min:                     rtesting.Int32Ptr(1),
+					Max:                     rtesting.Int32Ptr(5),
+					TargetCPUUtilization:    rtesting.Int32Ptr(50),
+					TargetMemoryUtilization: rtesting.Int32Ptr(70),
+				}).
+				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()),
+			deploymentGiven.
+				Replicas(1).
+				StatusReplicas(1),
+			serviceGiven,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
 			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
-				`Created Deployment "%s-deployer-001"`, testName),
-			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeWarning, "CreationFailed",
-				`Failed to create Service "%s":  "%s" already exists`, testName, testName),
+				`Created HorizontalPodAutoscaler "%s-deployer-001"`, testName),
 			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
 				`Updated status`),
 		},
 		ExpectCreates: []rtesting.Factory{
-			deploymentCreate,
-			serviceCreate,
+			horizontalPodAutoscalerCreate.
+				Replicas(1, 5).
+				AddResourceUtilizationMetric(corev1.ResourceCPU, 50).
+				AddResourceUtilizationMetric(corev1.ResourceMemory, 70),
 		},
 		ExpectStatusUpdates: []rtesting.Factory{
 			deployerMinimal.
 				StatusConditions(
 					deployerConditionDeploymentReady.Unknown(),
 					deployerConditionIngressReady.True(),
-					deployerConditionReady.False().Reason("NotOwned", `There is an existing Service "test-deployer" that the Deployer does not own.`),
-					deployerConditionServiceReady.False().Reason("NotOwned", `There is an existing Service "test-deployer" that the Deployer does not own.`),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.True(),
 				).
 				StatusLatestImage(testImage).
-				StatusDeploymentRef("%s-deployer-001", deployerMinimal.Create().GetName()),
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
+				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusReplicas(1, 1).
+				StatusHorizontalPodAutoscalerRef("%s-deployer-001", deployerMinimal.Create().GetName()).
+				StatusServiceRef(deployerMinimal.Create().GetName()).
+				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
 		},
 	}, {
-		Name: "update deployment",
+		Name: "autoscaled, preserve replicas",
 		Key:  testKey,
 		GivenObjects: []rtesting.Factory{
 			deployerMinimal.
 				Image(testImage).
+				Scale(&corev1alpha1.Scale{Min: rtesting.Int32Ptr(2), Max: rtesting.Int32Ptr(5)}).
 				StatusConditions(
 					deployerConditionDeploymentReady.Unknown(),
 					deployerConditionIngressReady.True(),
@@ -567,32 +746,32 @@ func TestDeployerReconciler(t *testing.T) {
 					deployerConditionServiceReady.True(),
 				).
 				StatusLatestImage(testImage).
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
 				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusReplicas(3, 4).
+				StatusHorizontalPodAutoscalerRef("%s-deployer-000", deployerMinimal.Create().GetName()).
 				StatusServiceRef(deployerMinimal.Create().GetName()).
 				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
 			deploymentGiven.
-				HandlerContainer(func(container *corev1.Container) {
-					// change to reverse
-					container.Env = nil
-				}),
+				Replicas(4).
+				StatusReplicas(3),
+			horizontalPodAutoscalerGiven.
+				Replicas(2, 5).
+				AddResourceUtilizationMetric(corev1.ResourceCPU, 80).
+				StatusReplicas(3, 4),
 			serviceGiven,
 		},
-		ExpectEvents: []rtesting.Event{
-			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Updated",
-				`Updated Deployment "%s"`, deploymentGiven.Create().GetName()),
-		},
-		ExpectUpdates: []rtesting.Factory{
-			deploymentGiven,
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
 		},
 	}, {
-		Name: "update deployment, update error",
+		Name: "autoscaled, clamp replicas to max",
 		Key:  testKey,
-		WithReactors: []rtesting.ReactionFunc{
-			rtesting.InduceFailure("update", "Deployment"),
-		},
 		GivenObjects: []rtesting.Factory{
 			deployerMinimal.
 				Image(testImage).
+				Scale(&corev1alpha1.Scale{Min: rtesting.Int32Ptr(2), Max: rtesting.Int32Ptr(5)}).
 				StatusConditions(
 					deployerConditionDeploymentReady.Unknown(),
 					deployerConditionIngressReady.True(),
@@ -600,33 +779,37 @@ func TestDeployerReconciler(t *testing.T) {
 					deployerConditionServiceReady.True(),
 				).
 				StatusLatestImage(testImage).
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
 				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusReplicas(8, 8).
+				StatusHorizontalPodAutoscalerRef("%s-deployer-000", deployerMinimal.Create().GetName()).
 				StatusServiceRef(deployerMinimal.Create().GetName()).
 				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
 			deploymentGiven.
-				HandlerContainer(func(container *corev1.Container) {
-					// change to reverse
-					container.Env = nil
-				}),
+				Replicas(8).
+				StatusReplicas(8),
+			horizontalPodAutoscalerGiven.
+				Replicas(2, 5).
+				AddResourceUtilizationMetric(corev1.ResourceCPU, 80),
 			serviceGiven,
 		},
-		ShouldErr: true,
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
+		},
 		ExpectEvents: []rtesting.Event{
-			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeWarning, "UpdateFailed",
-				`Failed to update Deployment "%s": inducing failure for update Deployment`, deploymentGiven.Create().GetName()),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Updated",
+				`Updated Deployment "%s"`, deploymentGiven.Create().GetName()),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
 		},
 		ExpectUpdates: []rtesting.Factory{
-			deploymentGiven,
-		},
-	}, {
-		Name: "update deployment, list deployments failed",
-		Key:  testKey,
-		WithReactors: []rtesting.ReactionFunc{
-			rtesting.InduceFailure("list", "DeploymentList"),
+			deploymentGiven.
+				Replicas(5).
+				StatusReplicas(8),
 		},
-		GivenObjects: []rtesting.Factory{
+		ExpectStatusUpdates: []rtesting.Factory{
 			deployerMinimal.
-				Image(testImage).
 				StatusConditions(
 					deployerConditionDeploymentReady.Unknown(),
 					deployerConditionIngressReady.True(),
@@ -634,23 +817,21 @@ func TestDeployerReconciler(t *testing.T) {
 					deployerConditionServiceReady.True(),
 				).
 				StatusLatestImage(testImage).
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
 				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusReplicas(8, 5).
+				StatusHorizontalPodAutoscalerRef("%s-deployer-000", deployerMinimal.Create().GetName()).
 				StatusServiceRef(deployerMinimal.Create().GetName()).
 				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
-			deploymentGiven.
-				HandlerContainer(func(container *corev1.Container) {
-					// change to reverse
-					container.Env = nil
-				}),
-			serviceGiven,
 		},
-		ShouldErr: true,
 	}, {
-		Name: "update service",
+		Name: "remove autoscaler when no longer autoscaled",
 		Key:  testKey,
 		GivenObjects: []rtesting.Factory{
 			deployerMinimal.
 				Image(testImage).
+				Scale(&corev1alpha1.Scale{Min: rtesting.Int32Ptr(2), Max: rtesting.Int32Ptr(2)}).
 				StatusConditions(
 					deployerConditionDeploymentReady.Unknown(),
 					deployerConditionIngressReady.True(),
@@ -658,30 +839,35 @@ func TestDeployerReconciler(t *testing.T) {
 					deployerConditionServiceReady.True(),
 				).
 				StatusLatestImage(testImage).
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
 				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusReplicas(2, 2).
+				StatusHorizontalPodAutoscalerRef("%s-deployer-000", deployerMinimal.Create().GetName()).
 				StatusServiceRef(deployerMinimal.Create().GetName()).
 				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
-			deploymentGiven,
-			serviceGiven.
-				// change to reverse
-				Ports(),
+			deploymentGiven.
+				Replicas(2).
+				StatusReplicas(2),
+			horizontalPodAutoscalerGiven.
+				Replicas(2, 5).
+				AddResourceUtilizationMetric(corev1.ResourceCPU, 80),
+			serviceGiven,
 		},
-		ExpectEvents: []rtesting.Event{
-			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Updated",
-				`Updated Service "%s"`, serviceGiven.Create().GetName()),
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
 		},
-		ExpectUpdates: []rtesting.Factory{
-			serviceGiven,
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Deleted",
+				`Deleted HorizontalPodAutoscaler "%s"`, horizontalPodAutoscalerGiven.Create().GetName()),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
 		},
-	}, {
-		Name: "update service, update error",
-		Key:  testKey,
-		WithReactors: []rtesting.ReactionFunc{
-			rtesting.InduceFailure("update", "Service"),
+		ExpectDeletes: []rtesting.DeleteRef{
+			{Group: "autoscaling", Kind: "HorizontalPodAutoscaler", Namespace: testNamespace, Name: horizontalPodAutoscalerGiven.Create().GetName()},
 		},
-		GivenObjects: []rtesting.Factory{
+		ExpectStatusUpdates: []rtesting.Factory{
 			deployerMinimal.
-				Image(testImage).
 				StatusConditions(
 					deployerConditionDeploymentReady.Unknown(),
 					deployerConditionIngressReady.True(),
@@ -689,31 +875,38 @@ func TestDeployerReconciler(t *testing.T) {
 					deployerConditionServiceReady.True(),
 				).
 				StatusLatestImage(testImage).
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
 				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusReplicas(2, 2).
 				StatusServiceRef(deployerMinimal.Create().GetName()).
 				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
-			deploymentGiven,
-			serviceGiven.
-				// change to reverse
-				Ports(),
-		},
-		ShouldErr: true,
-		ExpectEvents: []rtesting.Event{
-			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeWarning, "UpdateFailed",
-				`Failed to update Service "%s": inducing failure for update Service`, serviceGiven.Create().GetName()),
-		},
-		ExpectUpdates: []rtesting.Factory{
-			serviceGiven,
 		},
 	}, {
-		Name: "update service, list services failed",
+		Name: "create resources, with disruption budget",
 		Key:  testKey,
-		WithReactors: []rtesting.ReactionFunc{
-			rtesting.InduceFailure("list", "ServiceList"),
-		},
 		GivenObjects: []rtesting.Factory{
 			deployerMinimal.
 				Image(testImage).
+				Disruption(&corev1alpha1.Disruption{MinAvailable: intOrStringPtr(intstr.FromInt(1))}),
+			deploymentGiven,
+			serviceGiven,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created PodDisruptionBudget "%s-deployer-001"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectCreates: []rtesting.Factory{
+			podDisruptionBudgetCreate.
+				MinAvailable(intstr.FromInt(1)),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
 				StatusConditions(
 					deployerConditionDeploymentReady.Unknown(),
 					deployerConditionIngressReady.True(),
@@ -721,21 +914,20 @@ func TestDeployerReconciler(t *testing.T) {
 					deployerConditionServiceReady.True(),
 				).
 				StatusLatestImage(testImage).
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
 				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusPodDisruptionBudgetRef("%s-deployer-001", deployerMinimal.Create().GetName()).
 				StatusServiceRef(deployerMinimal.Create().GetName()).
 				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
-			deploymentGiven,
-			serviceGiven.
-				// change to reverse
-				Ports(),
 		},
-		ShouldErr: true,
 	}, {
-		Name: "cleanup extra deployments",
+		Name: "update disruption budget",
 		Key:  testKey,
 		GivenObjects: []rtesting.Factory{
 			deployerMinimal.
 				Image(testImage).
+				Disruption(&corev1alpha1.Disruption{MaxUnavailable: intOrStringPtr(intstr.FromString("25%"))}).
 				StatusConditions(
 					deployerConditionDeploymentReady.Unknown(),
 					deployerConditionIngressReady.True(),
@@ -743,36 +935,31 @@ func TestDeployerReconciler(t *testing.T) {
 					deployerConditionServiceReady.True(),
 				).
 				StatusLatestImage(testImage).
-				StatusDeploymentRef("%s-deployer-001", deployerMinimal.Create().GetName()).
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
+				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusPodDisruptionBudgetRef("%s-deployer-000", deployerMinimal.Create().GetName()).
 				StatusServiceRef(deployerMinimal.Create().GetName()).
 				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
-			deploymentGiven.
-				NamespaceName(testNamespace, "extra-deployment-1"),
-			deploymentGiven.
-				NamespaceName(testNamespace, "extra-deployment-2"),
+			deploymentGiven,
+			podDisruptionBudgetGiven.
+				MinAvailable(intstr.FromInt(1)),
 			serviceGiven,
 		},
-		ExpectEvents: []rtesting.Event{
-			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Deleted",
-				`Deleted Deployment "%s"`, "extra-deployment-1"),
-			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Deleted",
-				`Deleted Deployment "%s"`, "extra-deployment-2"),
-			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
-				`Created Deployment "%s-deployer-001"`, testName),
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
 		},
-		ExpectDeletes: []rtesting.DeleteRef{
-			{Group: "apps", Kind: "Deployment", Namespace: testNamespace, Name: "extra-deployment-1"},
-			{Group: "apps", Kind: "Deployment", Namespace: testNamespace, Name: "extra-deployment-2"},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Updated",
+				`Updated PodDisruptionBudget "%s"`, podDisruptionBudgetGiven.Create().GetName()),
 		},
-		ExpectCreates: []rtesting.Factory{
-			deploymentCreate,
+		ExpectUpdates: []rtesting.Factory{
+			podDisruptionBudgetGiven.
+				MaxUnavailable(intstr.FromString("25%")),
 		},
 	}, {
-		Name: "cleanup extra deployments, delete deployment failed",
+		Name: "remove disruption budget",
 		Key:  testKey,
-		WithReactors: []rtesting.ReactionFunc{
-			rtesting.InduceFailure("delete", "Deployment"),
-		},
 		GivenObjects: []rtesting.Factory{
 			deployerMinimal.
 				Image(testImage).
@@ -783,29 +970,31 @@ func TestDeployerReconciler(t *testing.T) {
 					deployerConditionServiceReady.True(),
 				).
 				StatusLatestImage(testImage).
-				StatusDeploymentRef("%s-deployer-001", deployerMinimal.Create().GetName()).
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
+				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusPodDisruptionBudgetRef("%s-deployer-000", deployerMinimal.Create().GetName()).
 				StatusServiceRef(deployerMinimal.Create().GetName()).
 				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
-			deploymentGiven.
-				NamespaceName(testNamespace, "extra-deployment-1"),
-			deploymentGiven.
-				NamespaceName(testNamespace, "extra-deployment-2"),
+			deploymentGiven,
+			podDisruptionBudgetGiven.
+				MinAvailable(intstr.FromInt(1)),
 			serviceGiven,
 		},
-		ShouldErr: true,
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
+		},
 		ExpectEvents: []rtesting.Event{
-			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeWarning, "DeleteFailed",
-				`Failed to delete Deployment "%s": inducing failure for delete Deployment`, "extra-deployment-1"),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Deleted",
+				`Deleted PodDisruptionBudget "%s"`, podDisruptionBudgetGiven.Create().GetName()),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
 		},
 		ExpectDeletes: []rtesting.DeleteRef{
-			{Group: "apps", Kind: "Deployment", Namespace: testNamespace, Name: "extra-deployment-1"},
+			{Group: "policy", Kind: "PodDisruptionBudget", Namespace: testNamespace, Name: podDisruptionBudgetGiven.Create().GetName()},
 		},
-	}, {
-		Name: "cleanup extra services",
-		Key:  testKey,
-		GivenObjects: []rtesting.Factory{
+		ExpectStatusUpdates: []rtesting.Factory{
 			deployerMinimal.
-				Image(testImage).
 				StatusConditions(
 					deployerConditionDeploymentReady.Unknown(),
 					deployerConditionIngressReady.True(),
@@ -813,39 +1002,39 @@ func TestDeployerReconciler(t *testing.T) {
 					deployerConditionServiceReady.True(),
 				).
 				StatusLatestImage(testImage).
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
 				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
 				StatusServiceRef(deployerMinimal.Create().GetName()).
 				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
+		},
+	}, {
+		Name: "create resources, with network policy",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerMinimal.
+				Image(testImage).
+				NetworkPolicy(&corev1alpha1.NetworkPolicy{}),
 			deploymentGiven,
-			serviceGiven.
-				NamespaceName(testNamespace, "extra-service-1"),
-			serviceGiven.
-				NamespaceName(testNamespace, "extra-service-2"),
+			serviceGiven,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
-			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Deleted",
-				`Deleted Service "%s"`, "extra-service-1"),
-			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Deleted",
-				`Deleted Service "%s"`, "extra-service-2"),
 			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
-				`Created Service "%s"`, testName),
-		},
-		ExpectDeletes: []rtesting.DeleteRef{
-			{Kind: "Service", Namespace: testNamespace, Name: "extra-service-1"},
-			{Kind: "Service", Namespace: testNamespace, Name: "extra-service-2"},
+				`Created NetworkPolicy "%s-deployer-001"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
 		},
 		ExpectCreates: []rtesting.Factory{
-			serviceCreate,
-		},
-	}, {
-		Name: "cleanup extra services, delete service failed",
-		Key:  testKey,
-		WithReactors: []rtesting.ReactionFunc{
-			rtesting.InduceFailure("delete", "Service"),
+			networkPolicyCreate.
+				AllowIngress(8080, k8snetworkingv1.NetworkPolicyPeer{
+					PodSelector: &metav1.LabelSelector{},
+				}),
 		},
-		GivenObjects: []rtesting.Factory{
+		ExpectStatusUpdates: []rtesting.Factory{
 			deployerMinimal.
-				Image(testImage).
 				StatusConditions(
 					deployerConditionDeploymentReady.Unknown(),
 					deployerConditionIngressReady.True(),
@@ -853,45 +1042,45 @@ func TestDeployerReconciler(t *testing.T) {
 					deployerConditionServiceReady.True(),
 				).
 				StatusLatestImage(testImage).
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
 				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusNetworkPolicyRef("%s-deployer-001", deployerMinimal.Create().GetName()).
 				StatusServiceRef(deployerMinimal.Create().GetName()).
 				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
-			deploymentGiven,
-			serviceGiven.
-				NamespaceName(testNamespace, "extra-service-1"),
-			serviceGiven.
-				NamespaceName(testNamespace, "extra-service-2"),
-		},
-		ShouldErr: true,
-		ExpectEvents: []rtesting.Event{
-			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeWarning, "DeleteFailed",
-				`Failed to delete Service "%s": inducing failure for delete Service`, "extra-service-1"),
-		},
-		ExpectDeletes: []rtesting.DeleteRef{
-			{Kind: "Service", Namespace: testNamespace, Name: "extra-service-1"},
 		},
 	}, {
-		Name: "create ingress",
+		Name: "create resources, with network policy namespace selector",
 		Key:  testKey,
 		GivenObjects: []rtesting.Factory{
 			deployerMinimal.
 				Image(testImage).
-				IngressPolicy(corev1alpha1.IngressPolicyExternal),
+				NetworkPolicy(&corev1alpha1.NetworkPolicy{
+					NamespaceSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{testLabelKey: testLabelValue},
+					},
+				}),
 			deploymentGiven,
 			serviceGiven,
-			testSettings,
 		},
 		ExpectTracks: []rtesting.TrackRequest{
-			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
 			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
-				`Created Ingress "%s-deployer-001"`, testName),
+				`Created NetworkPolicy "%s-deployer-001"`, testName),
 			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
 				`Updated status`),
 		},
 		ExpectCreates: []rtesting.Factory{
-			ingressCreate,
+			networkPolicyCreate.
+				AllowIngress(8080, k8snetworkingv1.NetworkPolicyPeer{
+					PodSelector: &metav1.LabelSelector{},
+				}, k8snetworkingv1.NetworkPolicyPeer{
+					NamespaceSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{testLabelKey: testLabelValue},
+					},
+				}),
 		},
 		ExpectStatusUpdates: []rtesting.Factory{
 			deployerMinimal.
@@ -902,73 +1091,100 @@ func TestDeployerReconciler(t *testing.T) {
 					deployerConditionServiceReady.True(),
 				).
 				StatusLatestImage(testImage).
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
 				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusNetworkPolicyRef("%s-deployer-001", deployerMinimal.Create().GetName()).
 				StatusServiceRef(deployerMinimal.Create().GetName()).
-				StatusIngressRef("%s-deployer-001", testName).
-				StatusAddressURL(testAddressURL).
-				StatusURL(testURL),
+				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
 		},
 	}, {
-		Name: "create ingress, create failed",
+		Name: "create resources, with network policy for external ingress",
 		Key:  testKey,
-		WithReactors: []rtesting.ReactionFunc{
-			rtesting.InduceFailure("create", "Ingress"),
-		},
 		GivenObjects: []rtesting.Factory{
 			deployerMinimal.
 				Image(testImage).
-				IngressPolicy(corev1alpha1.IngressPolicyExternal),
+				IngressPolicy(corev1alpha1.IngressPolicyExternal).
+				NetworkPolicy(&corev1alpha1.NetworkPolicy{}),
 			deploymentGiven,
 			serviceGiven,
-			testSettings,
+			ingressGiven,
+			testSettings.
+				AddData("ingressNamespace", "my-ingress"),
 		},
-		ShouldErr: true,
 		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
 			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
-			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeWarning, "CreationFailed",
-				`Failed to create Ingress "": inducing failure for create Ingress`),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created NetworkPolicy "%s-deployer-001"`, testName),
 			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
 				`Updated status`),
 		},
 		ExpectCreates: []rtesting.Factory{
-			ingressCreate,
+			networkPolicyCreate.
+				AllowIngress(8080, k8snetworkingv1.NetworkPolicyPeer{
+					NamespaceSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{"kubernetes.io/metadata.name": "my-ingress"},
+					},
+				}),
 		},
 		ExpectStatusUpdates: []rtesting.Factory{
 			deployerMinimal.
 				StatusConditions(
 					deployerConditionDeploymentReady.Unknown(),
-					deployerConditionIngressReady.Unknown(),
+					deployerConditionIngressReady.True(),
 					deployerConditionReady.Unknown(),
 					deployerConditionServiceReady.True(),
 				).
 				StatusLatestImage(testImage).
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
 				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusNetworkPolicyRef("%s-deployer-001", deployerMinimal.Create().GetName()).
 				StatusServiceRef(deployerMinimal.Create().GetName()).
-				StatusAddressURL(testAddressURL),
+				StatusIngressRef("%s-deployer-000", testName).
+				StatusAddressURL(testAddressURL).
+				StatusURL(testURL),
 		},
 	}, {
-		Name: "delete ingress",
+		Name: "create resources, with network policy for external ingress, default ingress namespace",
 		Key:  testKey,
 		GivenObjects: []rtesting.Factory{
 			deployerMinimal.
 				Image(testImage).
-				IngressPolicy(corev1alpha1.IngressPolicyClusterLocal),
+				IngressPolicy(corev1alpha1.IngressPolicyExternal).
+				NetworkPolicy(&corev1alpha1.NetworkPolicy{
+					// ignored for external ingress
+					NamespaceSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{testLabelKey: testLabelValue},
+					},
+				}),
 			deploymentGiven,
 			serviceGiven,
 			ingressGiven,
-			testSettings.
-				AddData("defaultDomain", "not.example.com"),
+			testSettings,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
-			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Deleted",
-				`Deleted Ingress "%s"`, ingressGiven.Create().GetName()),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created NetworkPolicy "%s-deployer-001"`, testName),
 			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
 				`Updated status`),
 		},
-		ExpectDeletes: []rtesting.DeleteRef{
-			{Group: "networking.k8s.io", Kind: "Ingress", Namespace: testNamespace, Name: ingressGiven.Create().GetName()},
+		ExpectCreates: []rtesting.Factory{
+			networkPolicyCreate.
+				AllowIngress(8080, k8snetworkingv1.NetworkPolicyPeer{
+					NamespaceSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{"kubernetes.io/metadata.name": "ingress-nginx"},
+					},
+				}),
 		},
 		ExpectStatusUpdates: []rtesting.Factory{
 			deployerMinimal.
@@ -979,74 +1195,112 @@ func TestDeployerReconciler(t *testing.T) {
 					deployerConditionServiceReady.True(),
 				).
 				StatusLatestImage(testImage).
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
 				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusNetworkPolicyRef("%s-deployer-001", deployerMinimal.Create().GetName()).
 				StatusServiceRef(deployerMinimal.Create().GetName()).
-				StatusAddressURL(testAddressURL),
+				StatusIngressRef("%s-deployer-000", testName).
+				StatusAddressURL(testAddressURL).
+				StatusURL(testURL),
 		},
 	}, {
-		Name: "delete ingress, delete failed",
+		Name: "create resources, with bindings",
 		Key:  testKey,
-		WithReactors: []rtesting.ReactionFunc{
-			rtesting.InduceFailure("delete", "Ingress"),
-		},
 		GivenObjects: []rtesting.Factory{
 			deployerMinimal.
 				Image(testImage).
-				IngressPolicy(corev1alpha1.IngressPolicyClusterLocal),
-			deploymentGiven,
-			serviceGiven,
-			ingressGiven,
-			testSettings.
-				AddData("defaultDomain", "not.example.com"),
+				AddBinding("input", corev1alpha1.BindableReference{Name: testStream.Create().GetName()}).
+				AddBinding("output", corev1alpha1.BindableReference{Name: testStream.Create().GetName()}),
+			testStream,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testStream, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testStream, deployerMinimal, scheme),
 		},
-		ShouldErr: true,
 		ExpectEvents: []rtesting.Event{
-			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeWarning, "DeleteFailed",
-				`Failed to delete Ingress "%s": inducing failure for delete Ingress`, ingressGiven.Create().GetName()),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created Deployment "%s-deployer-001"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created Service "%s"`, testName),
 			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
 				`Updated status`),
 		},
-		ExpectDeletes: []rtesting.DeleteRef{
-			{Group: "networking.k8s.io", Kind: "Ingress", Namespace: testNamespace, Name: ingressGiven.Create().GetName()},
+		ExpectCreates: []rtesting.Factory{
+			deploymentCreate.
+				PodTemplateSpec(func(pts factories.PodTemplateSpec) {
+					pts.Volumes(
+						corev1.Volume{
+							Name: "binding-my-stream-uid-metadata",
+							VolumeSource: corev1.VolumeSource{
+								ConfigMap: &corev1.ConfigMapVolumeSource{
+									LocalObjectReference: corev1.LocalObjectReference{Name: "my-stream-metadata"},
+								},
+							},
+						},
+						corev1.Volume{
+							Name: "binding-my-stream-uid-secret",
+							VolumeSource: corev1.VolumeSource{
+								Secret: &corev1.SecretVolumeSource{SecretName: "my-stream-secret"},
+							},
+						},
+					)
+				}).
+				HandlerContainer(func(container *corev1.Container) {
+					container.VolumeMounts = []corev1.VolumeMount{
+						{Name: "binding-my-stream-uid-metadata", MountPath: "/var/riff/bindings/input/metadata", ReadOnly: true},
+						{Name: "binding-my-stream-uid-secret", MountPath: "/var/riff/bindings/input/secret", ReadOnly: true},
+						{Name: "binding-my-stream-uid-metadata", MountPath: "/var/riff/bindings/output/metadata", ReadOnly: true},
+						{Name: "binding-my-stream-uid-secret", MountPath: "/var/riff/bindings/output/secret", ReadOnly: true},
+					}
+					container.Env = append(container.Env, corev1.EnvVar{Name: "CNB_BINDINGS", Value: "/var/riff/bindings"})
+				}),
+			serviceCreate,
 		},
 		ExpectStatusUpdates: []rtesting.Factory{
 			deployerMinimal.
 				StatusConditions(
 					deployerConditionDeploymentReady.Unknown(),
-					deployerConditionIngressReady.Unknown(),
+					deployerConditionIngressReady.True(),
 					deployerConditionReady.Unknown(),
 					deployerConditionServiceReady.True(),
 				).
 				StatusLatestImage(testImage).
-				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
+				StatusDeploymentRef("%s-deployer-001", deployerMinimal.Create().GetName()).
 				StatusServiceRef(deployerMinimal.Create().GetName()).
-				StatusAddressURL(testAddressURL),
+				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
 		},
 	}, {
-		Name: "update ingress",
+		Name: "create resources, with bindings, binding not provisioned",
 		Key:  testKey,
 		GivenObjects: []rtesting.Factory{
 			deployerMinimal.
 				Image(testImage).
-				IngressPolicy(corev1alpha1.IngressPolicyExternal),
-			deploymentGiven,
-			serviceGiven,
-			ingressGiven,
-			testSettings.
-				AddData("defaultDomain", "not.example.com"),
+				AddBinding("input", corev1alpha1.BindableReference{Name: testStream.Create().GetName()}),
+			testStream.
+				StatusBinding("", ""),
 		},
 		ExpectTracks: []rtesting.TrackRequest{
-			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testStream, deployerMinimal, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
-			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Updated",
-				`Updated Ingress "%s"`, ingressGiven.Create().GetName()),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created Deployment "%s-deployer-001"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created Service "%s"`, testName),
 			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
 				`Updated status`),
 		},
-		ExpectUpdates: []rtesting.Factory{
-			ingressGiven.
-				HostToService(fmt.Sprintf("%s.%s.%s", testName, testNamespace, "not.example.com"), serviceGiven.Create().GetName()),
+		ExpectCreates: []rtesting.Factory{
+			deploymentCreate.
+				HandlerContainer(func(container *corev1.Container) {
+					container.Env = append(container.Env, corev1.EnvVar{Name: "CNB_BINDINGS", Value: "/var/riff/bindings"})
+				}),
+			serviceCreate,
 		},
 		ExpectStatusUpdates: []rtesting.Factory{
 			deployerMinimal.
@@ -1057,89 +1311,211 @@ func TestDeployerReconciler(t *testing.T) {
 					deployerConditionServiceReady.True(),
 				).
 				StatusLatestImage(testImage).
-				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
+				StatusDeploymentRef("%s-deployer-001", deployerMinimal.Create().GetName()).
 				StatusServiceRef(deployerMinimal.Create().GetName()).
-				StatusIngressRef("%s-deployer-000", testName).
-				StatusAddressURL(testAddressURL).
-				StatusURL("http://%s.%s.%s", testName, testNamespace, "not.example.com"),
+				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
 		},
 	}, {
-		Name: "update ingress, update failed",
+		Name: "create resources, with bindings, bindable resource not found",
 		Key:  testKey,
-		WithReactors: []rtesting.ReactionFunc{
-			rtesting.InduceFailure("update", "Ingress"),
-		},
 		GivenObjects: []rtesting.Factory{
 			deployerMinimal.
 				Image(testImage).
-				IngressPolicy(corev1alpha1.IngressPolicyExternal),
-			deploymentGiven,
-			serviceGiven,
-			ingressGiven,
-			testSettings.
-				AddData("defaultDomain", "not.example.com"),
+				AddBinding("input", corev1alpha1.BindableReference{Name: testStream.Create().GetName()}),
 		},
 		ShouldErr: true,
 		ExpectTracks: []rtesting.TrackRequest{
-			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testStream, deployerMinimal, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
-			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeWarning, "UpdateFailed",
-				`Failed to update Ingress "%s": inducing failure for update Ingress`, ingressGiven.Create().GetName()),
 			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
 				`Updated status`),
 		},
-		ExpectUpdates: []rtesting.Factory{
-			ingressGiven.
-				HostToService(fmt.Sprintf("%s.%s.%s", testName, testNamespace, "not.example.com"), serviceGiven.Create().GetName()),
-		},
 		ExpectStatusUpdates: []rtesting.Factory{
 			deployerMinimal.
 				StatusConditions(
 					deployerConditionDeploymentReady.Unknown(),
 					deployerConditionIngressReady.Unknown(),
 					deployerConditionReady.Unknown(),
-					deployerConditionServiceReady.True(),
+					deployerConditionServiceReady.Unknown(),
 				).
 				StatusLatestImage(testImage).
-				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
-				StatusServiceRef(deployerMinimal.Create().GetName()).
-				StatusAddressURL(testAddressURL),
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision),
 		},
 	}, {
-		Name: "remove extra ingress",
+		Name: "create resources, rollout in progress",
 		Key:  testKey,
 		GivenObjects: []rtesting.Factory{
 			deployerMinimal.
 				Image(testImage).
-				IngressPolicy(corev1alpha1.IngressPolicyExternal),
-			deploymentGiven,
-			serviceGiven,
-			ingressGiven.
-				NamespaceName(testNamespace, "extra-ingress-1"),
-			ingressGiven.
-				NamespaceName(testNamespace, "extra-ingress-2"),
-			testSettings,
+				Rollout(testRollout).
+				StatusRollout(&corev1alpha1.RolloutStatus{
+					Phase:         corev1alpha1.RolloutPhaseProgressing,
+					StableImage:   testStableImage,
+					CanaryImage:   testImage,
+					Weight:        25,
+					StepStartTime: &testStepStartTime,
+				}),
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
-			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Deleted",
-				`Deleted Ingress "%s"`, "extra-ingress-1"),
-			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Deleted",
-				`Deleted Ingress "%s"`, "extra-ingress-2"),
 			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
-				`Created Ingress "%s-deployer-001"`, testName),
-			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Created Deployment "%s-deployer-001"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created Deployment "%s-deployer-canary-002"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created Service "%s"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
 				`Updated status`),
 		},
+		ExpectCreates: []rtesting.Factory{
+			deploymentCreate.
+				HandlerContainer(func(container *corev1.Container) {
+					container.Image = testStableImage
+				}),
+			factories.Deployment().
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.Namespace(testNamespace)
+					om.GenerateName("%s-deployer-canary-", testName)
+					om.AddLabel(corev1alpha1.DeployerLabelKey, testName)
+					om.AddLabel(corev1alpha1.DeployerCanaryLabelKey, testName)
+					om.ControlledBy(deployerMinimal, scheme)
+				}).
+				AddSelectorLabel(corev1alpha1.DeployerCanaryLabelKey, testName).
+				PodTemplateSpec(func(pts factories.PodTemplateSpec) {
+					pts.AddLabel(corev1alpha1.DeployerLabelKey, testName)
+					pts.AddLabel(corev1alpha1.DeployerTrackLabelKey, corev1alpha1.DeployerTrackCanary)
+				}).
+				HandlerContainer(func(container *corev1.Container) {
+					container.Image = testImage
+					container.Ports = deploymentCreate.Create().Spec.Template.Spec.Containers[0].Ports
+					container.Env = deploymentCreate.Create().Spec.Template.Spec.Containers[0].Env
+					container.ReadinessProbe = deploymentCreate.Create().Spec.Template.Spec.Containers[0].ReadinessProbe
+				}).
+				Replicas(1),
+			serviceCreate,
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
+				StatusDeploymentRef("%s-deployer-001", deployerMinimal.Create().GetName()).
+				StatusServiceRef(deployerMinimal.Create().GetName()).
+				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()).
+				StatusRollout(&corev1alpha1.RolloutStatus{
+					Phase:         corev1alpha1.RolloutPhaseProgressing,
+					StableImage:   testStableImage,
+					CanaryImage:   testImage,
+					Weight:        25,
+					StepStartTime: &testStepStartTime,
+					CanaryDeploymentRef: &refs.TypedLocalObjectReference{
+						APIGroup: rtesting.StringPtr("apps"),
+						Kind:     "Deployment",
+						Name:     fmt.Sprintf("%s-deployer-canary-002", testName),
+					},
+				}),
+		},
+	}, {
+		Name: "create resources, rollout in progress with ingress routing",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerMinimal.
+				Image(testImage).
+				Rollout(testIngressRollout).
+				Disruption(&corev1alpha1.Disruption{MinAvailable: intOrStringPtr(intstr.FromInt(1))}).
+				NetworkPolicy(&corev1alpha1.NetworkPolicy{}).
+				StatusRollout(&corev1alpha1.RolloutStatus{
+					Phase:         corev1alpha1.RolloutPhaseProgressing,
+					StableImage:   testStableImage,
+					CanaryImage:   testImage,
+					Weight:        25,
+					StepStartTime: &testStepStartTime,
+				}),
+		},
 		ExpectTracks: []rtesting.TrackRequest{
-			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
 		},
-		ExpectDeletes: []rtesting.DeleteRef{
-			{Group: "networking.k8s.io", Kind: "Ingress", Namespace: testNamespace, Name: "extra-ingress-1"},
-			{Group: "networking.k8s.io", Kind: "Ingress", Namespace: testNamespace, Name: "extra-ingress-2"},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created Deployment "%s-deployer-001"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created Deployment "%s-deployer-canary-002"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created PodDisruptionBudget "%s-deployer-003"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created NetworkPolicy "%s-deployer-004"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created Service "%s"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created Service "%s-canary"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
 		},
 		ExpectCreates: []rtesting.Factory{
-			ingressCreate,
+			deploymentCreate.
+				HandlerContainer(func(container *corev1.Container) {
+					container.Image = testStableImage
+				}),
+			factories.Deployment().
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.Namespace(testNamespace)
+					om.GenerateName("%s-deployer-canary-", testName)
+					om.AddLabel(corev1alpha1.DeployerLabelKey, testName)
+					om.AddLabel(corev1alpha1.DeployerCanaryLabelKey, testName)
+					om.ControlledBy(deployerMinimal, scheme)
+				}).
+				AddSelectorLabel(corev1alpha1.DeployerCanaryLabelKey, testName).
+				PodTemplateSpec(func(pts factories.PodTemplateSpec) {
+					pts.AddLabel(corev1alpha1.DeployerLabelKey, testName)
+					pts.AddLabel(corev1alpha1.DeployerTrackLabelKey, corev1alpha1.DeployerTrackCanary)
+				}).
+				HandlerContainer(func(container *corev1.Container) {
+					container.Image = testImage
+					container.Ports = deploymentCreate.Create().Spec.Template.Spec.Containers[0].Ports
+					container.Env = deploymentCreate.Create().Spec.Template.Spec.Containers[0].Env
+					container.ReadinessProbe = deploymentCreate.Create().Spec.Template.Spec.Containers[0].ReadinessProbe
+				}).
+				Replicas(1),
+			// the canary's pods are covered by the disruption budget and
+			// network policy
+			podDisruptionBudgetCreate.
+				MinAvailable(intstr.FromInt(1)),
+			networkPolicyCreate.
+				AllowIngress(8080, k8snetworkingv1.NetworkPolicyPeer{
+					PodSelector: &metav1.LabelSelector{},
+				}),
+			// the ingress splits traffic, the stable service only selects
+			// stable pods
+			serviceCreate.
+				AddSelectorLabel(corev1alpha1.DeployerTrackLabelKey, corev1alpha1.DeployerTrackStable),
+			factories.Service().
+				NamespaceName(testNamespace, fmt.Sprintf("%s-canary", testName)).
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.AddLabel(corev1alpha1.DeployerLabelKey, testName)
+					om.AddLabel(corev1alpha1.DeployerCanaryLabelKey, testName)
+					om.ControlledBy(deployerMinimal, scheme)
+				}).
+				AddSelectorLabel(corev1alpha1.DeployerCanaryLabelKey, testName).
+				Ports(
+					corev1.ServicePort{
+						Name:       "http",
+						Port:       80,
+						TargetPort: intstr.FromInt(8080),
+					},
+				),
 		},
 		ExpectStatusUpdates: []rtesting.Factory{
 			deployerMinimal.
@@ -1150,75 +1526,132 @@ func TestDeployerReconciler(t *testing.T) {
 					deployerConditionServiceReady.True(),
 				).
 				StatusLatestImage(testImage).
-				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
+				StatusDeploymentRef("%s-deployer-001", deployerMinimal.Create().GetName()).
+				StatusPodDisruptionBudgetRef("%s-deployer-003", deployerMinimal.Create().GetName()).
+				StatusNetworkPolicyRef("%s-deployer-004", deployerMinimal.Create().GetName()).
 				StatusServiceRef(deployerMinimal.Create().GetName()).
-				StatusIngressRef("%s-deployer-001", testName).
-				StatusAddressURL(testAddressURL).
-				StatusURL(testURL),
+				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()).
+				StatusRollout(&corev1alpha1.RolloutStatus{
+					Phase:         corev1alpha1.RolloutPhaseProgressing,
+					StableImage:   testStableImage,
+					CanaryImage:   testImage,
+					Weight:        25,
+					StepStartTime: &testStepStartTime,
+					CanaryDeploymentRef: &refs.TypedLocalObjectReference{
+						APIGroup: rtesting.StringPtr("apps"),
+						Kind:     "Deployment",
+						Name:     fmt.Sprintf("%s-deployer-canary-002", testName),
+					},
+					CanaryServiceRef: &refs.TypedLocalObjectReference{
+						Kind: "Service",
+						Name: fmt.Sprintf("%s-canary", testName),
+					},
+				}),
 		},
 	}, {
-		Name: "remove extra ingress, listing failed",
+		Name: "create resources, from image, registry rewrite",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerMinimal.
+				Image(testImage),
+			testRegistryRewrites.
+				AddData("rewrites", fmt.Sprintf("%s -> mirror.local/repo", testImagePrefix)),
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created Deployment "%s-deployer-001"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created Service "%s"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectCreates: []rtesting.Factory{
+			deploymentCreate.
+				HandlerContainer(func(container *corev1.Container) {
+					container.Image = fmt.Sprintf("mirror.local/repo@sha256:%s", testSha256)
+				}),
+			serviceCreate,
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage("mirror.local/repo@sha256:%s", testSha256).
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(corev1alpha1.DeployerRevision{Image: fmt.Sprintf("mirror.local/repo@sha256:%s", testSha256), DeployedTime: testDeployedTime}).
+				StatusDeploymentRef("%s-deployer-001", deployerMinimal.Create().GetName()).
+				StatusServiceRef(deployerMinimal.Create().GetName()).
+				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
+		},
+	}, {
+		Name: "create deployment, error",
 		Key:  testKey,
 		WithReactors: []rtesting.ReactionFunc{
-			rtesting.InduceFailure("list", "IngressList"),
+			rtesting.InduceFailure("create", "Deployment"),
 		},
 		GivenObjects: []rtesting.Factory{
 			deployerMinimal.
-				Image(testImage).
-				IngressPolicy(corev1alpha1.IngressPolicyExternal),
-			deploymentGiven,
-			serviceGiven,
-			ingressGiven.
-				NamespaceName(testNamespace, "extra-ingress-1"),
-			ingressGiven.
-				NamespaceName(testNamespace, "extra-ingress-2"),
-			testSettings,
+				Image(testImage),
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
 		},
 		ShouldErr: true,
 		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeWarning, "CreationFailed",
+				`Failed to create Deployment "": inducing failure for create Deployment`),
 			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
 				`Updated status`),
 		},
+		ExpectCreates: []rtesting.Factory{
+			deploymentCreate,
+		},
 		ExpectStatusUpdates: []rtesting.Factory{
 			deployerMinimal.
 				StatusConditions(
 					deployerConditionDeploymentReady.Unknown(),
 					deployerConditionIngressReady.Unknown(),
 					deployerConditionReady.Unknown(),
-					deployerConditionServiceReady.True(),
+					deployerConditionServiceReady.Unknown(),
 				).
 				StatusLatestImage(testImage).
-				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
-				StatusServiceRef(deployerMinimal.Create().GetName()).
-				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision),
 		},
 	}, {
-		Name: "remove extra ingress, delete failed",
+		Name: "create service, error",
 		Key:  testKey,
 		WithReactors: []rtesting.ReactionFunc{
-			rtesting.InduceFailure("delete", "Ingress"),
+			rtesting.InduceFailure("create", "Service"),
 		},
 		GivenObjects: []rtesting.Factory{
 			deployerMinimal.
-				Image(testImage).
-				IngressPolicy(corev1alpha1.IngressPolicyExternal),
-			deploymentGiven,
-			serviceGiven,
-			ingressGiven.
-				NamespaceName(testNamespace, "extra-ingress-1"),
-			ingressGiven.
-				NamespaceName(testNamespace, "extra-ingress-2"),
-			testSettings,
+				Image(testImage),
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
 		},
 		ShouldErr: true,
 		ExpectEvents: []rtesting.Event{
-			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeWarning, "DeleteFailed",
-				`Failed to delete Ingress "%s": inducing failure for delete Ingress`, "extra-ingress-1"),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created Deployment "%s-deployer-001"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeWarning, "CreationFailed",
+				`Failed to create Service "%s": inducing failure for create Service`, testName),
 			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
 				`Updated status`),
 		},
-		ExpectDeletes: []rtesting.DeleteRef{
-			{Group: "networking.k8s.io", Kind: "Ingress", Namespace: testNamespace, Name: "extra-ingress-1"},
+		ExpectCreates: []rtesting.Factory{
+			deploymentCreate,
+			serviceCreate,
 		},
 		ExpectStatusUpdates: []rtesting.Factory{
 			deployerMinimal.
@@ -1226,22 +1659,93 @@ func TestDeployerReconciler(t *testing.T) {
 					deployerConditionDeploymentReady.Unknown(),
 					deployerConditionIngressReady.Unknown(),
 					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.Unknown(),
+				).
+				StatusLatestImage(testImage).
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
+				StatusDeploymentRef("%s-deployer-001", deployerMinimal.Create().GetName()),
+		},
+	}, {
+		Name: "create service, conflicted",
+		Key:  testKey,
+		WithReactors: []rtesting.ReactionFunc{
+			rtesting.InduceFailure("create", "Service", rtesting.InduceFailureOpts{
+				Error: apierrs.NewAlreadyExists(schema.GroupResource{}, testName),
+			}),
+		},
+		GivenObjects: []rtesting.Factory{
+			deployerMinimal.
+				Image(testImage),
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created Deployment "%s-deployer-001"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeWarning, "CreationFailed",
+				`Failed to create Service "%s":  "%s" already exists`, testName, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectCreates: []rtesting.Factory{
+			deploymentCreate,
+			serviceCreate,
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.False().Reason("NotOwned", `There is an existing Service "test-deployer" that the Deployer does not own.`),
+					deployerConditionServiceReady.False().Reason("NotOwned", `There is an existing Service "test-deployer" that the Deployer does not own.`),
+				).
+				StatusLatestImage(testImage).
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
+				StatusDeploymentRef("%s-deployer-001", deployerMinimal.Create().GetName()),
+		},
+	}, {
+		Name: "update deployment",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerMinimal.
+				Image(testImage).
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.Unknown(),
 					deployerConditionServiceReady.True(),
 				).
 				StatusLatestImage(testImage).
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
 				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
 				StatusServiceRef(deployerMinimal.Create().GetName()).
 				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
+			deploymentGiven.
+				HandlerContainer(func(container *corev1.Container) {
+					// change to reverse
+					container.Env = nil
+				}),
+			serviceGiven,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Updated",
+				`Updated Deployment "%s"`, deploymentGiven.Create().GetName()),
+		},
+		ExpectUpdates: []rtesting.Factory{
+			deploymentGiven,
 		},
 	}, {
-		Name: "propagate labels",
+		Name: "update deployment, keep the selector of an untracked deployment",
 		Key:  testKey,
 		GivenObjects: []rtesting.Factory{
 			deployerMinimal.
-				ObjectMeta(func(om factories.ObjectMeta) {
-					om.AddLabel(testLabelKey, testLabelValue)
-				}).
-				IngressPolicy(corev1alpha1.IngressPolicyExternal).
 				Image(testImage).
 				StatusConditions(
 					deployerConditionDeploymentReady.Unknown(),
@@ -1250,203 +1754,2309 @@ func TestDeployerReconciler(t *testing.T) {
 					deployerConditionServiceReady.True(),
 				).
 				StatusLatestImage(testImage).
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
 				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
 				StatusServiceRef(deployerMinimal.Create().GetName()).
-				StatusIngressRef(ingressGiven.Create().GetName()).
-				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()).
-				StatusURL(testURL),
-			deploymentGiven,
+				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
+			deploymentUntrackedCreate.
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.Name("%s%s", om.Create().GenerateName, "000")
+					om.Created(1)
+				}),
 			serviceGiven,
-			ingressGiven,
-			testSettings,
 		},
 		ExpectTracks: []rtesting.TrackRequest{
-			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
 			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Updated",
 				`Updated Deployment "%s"`, deploymentGiven.Create().GetName()),
-			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Updated",
-				`Updated Service "%s"`, serviceGiven.Create().GetName()),
-			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Updated",
-				`Updated Ingress "%s"`, ingressGiven.Create().GetName()),
 		},
 		ExpectUpdates: []rtesting.Factory{
-			deploymentGiven.
+			deploymentUntrackedCreate.
 				ObjectMeta(func(om factories.ObjectMeta) {
-					om.AddLabel(testLabelKey, testLabelValue)
+					om.Name("%s%s", om.Create().GenerateName, "000")
+					om.Created(1)
 				}).
 				PodTemplateSpec(func(pts factories.PodTemplateSpec) {
-					pts.AddLabel(testLabelKey, testLabelValue)
-				}),
-			serviceGiven.
-				ObjectMeta(func(om factories.ObjectMeta) {
-					om.AddLabel(testLabelKey, testLabelValue)
+					pts.AddLabel(corev1alpha1.DeployerTrackLabelKey, corev1alpha1.DeployerTrackStable)
 				}),
-			ingressGiven.
+		},
+	}, {
+		Name: "update deployment, update error",
+		Key:  testKey,
+		WithReactors: []rtesting.ReactionFunc{
+			rtesting.InduceFailure("update", "Deployment"),
+		},
+		GivenObjects: []rtesting.Factory{
+			deployerMinimal.
+				Image(testImage).
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
+				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusServiceRef(deployerMinimal.Create().GetName()).
+				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
+			deploymentGiven.
+				HandlerContainer(func(container *corev1.Container) {
+					// change to reverse
+					container.Env = nil
+				}),
+			serviceGiven,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
+		},
+		ShouldErr: true,
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeWarning, "UpdateFailed",
+				`Failed to update Deployment "%s": inducing failure for update Deployment`, deploymentGiven.Create().GetName()),
+		},
+		ExpectUpdates: []rtesting.Factory{
+			deploymentGiven,
+		},
+	}, {
+		Name: "update deployment, list deployments failed",
+		Key:  testKey,
+		WithReactors: []rtesting.ReactionFunc{
+			rtesting.InduceFailure("list", "DeploymentList"),
+		},
+		GivenObjects: []rtesting.Factory{
+			deployerMinimal.
+				Image(testImage).
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
+				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusServiceRef(deployerMinimal.Create().GetName()).
+				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
+			deploymentGiven.
+				HandlerContainer(func(container *corev1.Container) {
+					// change to reverse
+					container.Env = nil
+				}),
+			serviceGiven,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
+		},
+		ShouldErr: true,
+	}, {
+		Name: "update service",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerMinimal.
+				Image(testImage).
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
+				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusServiceRef(deployerMinimal.Create().GetName()).
+				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
+			deploymentGiven,
+			serviceGiven.
+				// change to reverse
+				Ports(),
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Updated",
+				`Updated Service "%s"`, serviceGiven.Create().GetName()),
+		},
+		ExpectUpdates: []rtesting.Factory{
+			serviceGiven,
+		},
+	}, {
+		Name: "update service, update error",
+		Key:  testKey,
+		WithReactors: []rtesting.ReactionFunc{
+			rtesting.InduceFailure("update", "Service"),
+		},
+		GivenObjects: []rtesting.Factory{
+			deployerMinimal.
+				Image(testImage).
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
+				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusServiceRef(deployerMinimal.Create().GetName()).
+				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
+			deploymentGiven,
+			serviceGiven.
+				// change to reverse
+				Ports(),
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
+		},
+		ShouldErr: true,
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeWarning, "UpdateFailed",
+				`Failed to update Service "%s": inducing failure for update Service`, serviceGiven.Create().GetName()),
+		},
+		ExpectUpdates: []rtesting.Factory{
+			serviceGiven,
+		},
+	}, {
+		Name: "update service, list services failed",
+		Key:  testKey,
+		WithReactors: []rtesting.ReactionFunc{
+			rtesting.InduceFailure("list", "ServiceList"),
+		},
+		GivenObjects: []rtesting.Factory{
+			deployerMinimal.
+				Image(testImage).
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
+				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusServiceRef(deployerMinimal.Create().GetName()).
+				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
+			deploymentGiven,
+			serviceGiven.
+				// change to reverse
+				Ports(),
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
+		},
+		ShouldErr: true,
+	}, {
+		Name: "cleanup extra deployments",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerMinimal.
+				Image(testImage).
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
+				StatusDeploymentRef("%s-deployer-001", deployerMinimal.Create().GetName()).
+				StatusServiceRef(deployerMinimal.Create().GetName()).
+				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
+			deploymentGiven.
+				NamespaceName(testNamespace, "extra-deployment-1"),
+			deploymentGiven.
+				NamespaceName(testNamespace, "extra-deployment-2"),
+			serviceGiven,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Deleted",
+				`Deleted Deployment "%s"`, "extra-deployment-1"),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Deleted",
+				`Deleted Deployment "%s"`, "extra-deployment-2"),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created Deployment "%s-deployer-001"`, testName),
+		},
+		ExpectDeletes: []rtesting.DeleteRef{
+			{Group: "apps", Kind: "Deployment", Namespace: testNamespace, Name: "extra-deployment-1"},
+			{Group: "apps", Kind: "Deployment", Namespace: testNamespace, Name: "extra-deployment-2"},
+		},
+		ExpectCreates: []rtesting.Factory{
+			deploymentCreate,
+		},
+	}, {
+		Name: "cleanup extra deployments, delete deployment failed",
+		Key:  testKey,
+		WithReactors: []rtesting.ReactionFunc{
+			rtesting.InduceFailure("delete", "Deployment"),
+		},
+		GivenObjects: []rtesting.Factory{
+			deployerMinimal.
+				Image(testImage).
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
+				StatusDeploymentRef("%s-deployer-001", deployerMinimal.Create().GetName()).
+				StatusServiceRef(deployerMinimal.Create().GetName()).
+				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
+			deploymentGiven.
+				NamespaceName(testNamespace, "extra-deployment-1"),
+			deploymentGiven.
+				NamespaceName(testNamespace, "extra-deployment-2"),
+			serviceGiven,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
+		},
+		ShouldErr: true,
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeWarning, "DeleteFailed",
+				`Failed to delete Deployment "%s": inducing failure for delete Deployment`, "extra-deployment-1"),
+		},
+		ExpectDeletes: []rtesting.DeleteRef{
+			{Group: "apps", Kind: "Deployment", Namespace: testNamespace, Name: "extra-deployment-1"},
+		},
+	}, {
+		Name: "cleanup extra services",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerMinimal.
+				Image(testImage).
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
+				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusServiceRef(deployerMinimal.Create().GetName()).
+				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
+			deploymentGiven,
+			serviceGiven.
+				NamespaceName(testNamespace, "extra-service-1"),
+			serviceGiven.
+				NamespaceName(testNamespace, "extra-service-2"),
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Deleted",
+				`Deleted Service "%s"`, "extra-service-1"),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Deleted",
+				`Deleted Service "%s"`, "extra-service-2"),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created Service "%s"`, testName),
+		},
+		ExpectDeletes: []rtesting.DeleteRef{
+			{Kind: "Service", Namespace: testNamespace, Name: "extra-service-1"},
+			{Kind: "Service", Namespace: testNamespace, Name: "extra-service-2"},
+		},
+		ExpectCreates: []rtesting.Factory{
+			serviceCreate,
+		},
+	}, {
+		Name: "cleanup extra services, delete service failed",
+		Key:  testKey,
+		WithReactors: []rtesting.ReactionFunc{
+			rtesting.InduceFailure("delete", "Service"),
+		},
+		GivenObjects: []rtesting.Factory{
+			deployerMinimal.
+				Image(testImage).
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
+				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusServiceRef(deployerMinimal.Create().GetName()).
+				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
+			deploymentGiven,
+			serviceGiven.
+				NamespaceName(testNamespace, "extra-service-1"),
+			serviceGiven.
+				NamespaceName(testNamespace, "extra-service-2"),
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
+		},
+		ShouldErr: true,
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeWarning, "DeleteFailed",
+				`Failed to delete Service "%s": inducing failure for delete Service`, "extra-service-1"),
+		},
+		ExpectDeletes: []rtesting.DeleteRef{
+			{Kind: "Service", Namespace: testNamespace, Name: "extra-service-1"},
+		},
+	}, {
+		Name: "create ingress",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerMinimal.
+				Image(testImage).
+				IngressPolicy(corev1alpha1.IngressPolicyExternal),
+			deploymentGiven,
+			serviceGiven,
+			testSettings,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created Ingress "%s-deployer-001"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectCreates: []rtesting.Factory{
+			ingressCreate,
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
+				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusServiceRef(deployerMinimal.Create().GetName()).
+				StatusIngressRef("%s-deployer-001", testName).
+				StatusAddressURL(testAddressURL).
+				StatusURL(testURL),
+		},
+	}, {
+		Name: "create ingress, with tls",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerMinimal.
+				Image(testImage).
+				IngressPolicy(corev1alpha1.IngressPolicyExternal).
+				TLS("my-certificate"),
+			deploymentGiven,
+			serviceGiven,
+			testSettings,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(factories.Secret().NamespaceName(testNamespace, "my-certificate"), deployerMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created Ingress "%s-deployer-001"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectCreates: []rtesting.Factory{
+			ingressCreate.
+				TLS("my-certificate", testHost),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.False().Reason("CertificateMissing", `The certificate Secret "my-certificate" was not found.`),
+					deployerConditionReady.False().Reason("CertificateMissing", `The certificate Secret "my-certificate" was not found.`),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
+				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusServiceRef(deployerMinimal.Create().GetName()).
+				StatusIngressRef("%s-deployer-001", testName).
+				StatusAddressURL(testAddressURL).
+				StatusURL("https://%s", testHost),
+		},
+	}, {
+		Name: "create ingress, with default tls secret",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerMinimal.
+				Image(testImage).
+				IngressPolicy(corev1alpha1.IngressPolicyExternal),
+			deploymentGiven,
+			serviceGiven,
+			testSettings.
+				AddData("defaultTLSSecret", "wildcard-certificate"),
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(factories.Secret().NamespaceName(testNamespace, "wildcard-certificate"), deployerMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created Ingress "%s-deployer-001"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectCreates: []rtesting.Factory{
+			ingressCreate.
+				TLS("wildcard-certificate", testHost),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.False().Reason("CertificateMissing", `The certificate Secret "wildcard-certificate" was not found.`),
+					deployerConditionReady.False().Reason("CertificateMissing", `The certificate Secret "wildcard-certificate" was not found.`),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
+				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusServiceRef(deployerMinimal.Create().GetName()).
+				StatusIngressRef("%s-deployer-001", testName).
+				StatusAddressURL(testAddressURL).
+				StatusURL("https://%s", testHost),
+		},
+	}, {
+		Name: "create ingress, with tls issuer",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerMinimal.
+				Image(testImage).
+				IngressPolicy(corev1alpha1.IngressPolicyExternal),
+			deploymentGiven,
+			serviceGiven,
+			testSettings.
+				AddData("tlsIssuerAnnotation", "cert-manager.io/cluster-issuer=letsencrypt"),
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(factories.Secret().NamespaceName(testNamespace, fmt.Sprintf("%s-deployer-tls", testName)), deployerMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created Ingress "%s-deployer-001"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectCreates: []rtesting.Factory{
+			ingressCreate.
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.AddAnnotation("cert-manager.io/cluster-issuer", "letsencrypt")
+				}).
+				TLS(fmt.Sprintf("%s-deployer-tls", testName), testHost),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.Unknown().Reason("CertificatePending", `Waiting for a certificate to be issued into Secret "test-deployer-deployer-tls".`),
+					deployerConditionReady.Unknown().Reason("CertificatePending", `Waiting for a certificate to be issued into Secret "test-deployer-deployer-tls".`),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
+				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusServiceRef(deployerMinimal.Create().GetName()).
+				StatusIngressRef("%s-deployer-001", testName).
+				StatusAddressURL(testAddressURL).
+				StatusURL("https://%s", testHost),
+		},
+	}, {
+		Name: "create ingress, with hosts, path, class and annotations",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerMinimal.
+				Image(testImage).
+				IngressPolicy(corev1alpha1.IngressPolicyExternal).
+				Ingress(&corev1alpha1.Ingress{
+					Hosts:     []string{"www.example.com", testHost},
+					Path:      "/api",
+					ClassName: "nginx",
+					Annotations: map[string]string{
+						"nginx.ingress.kubernetes.io/proxy-body-size": "8m",
+					},
+				}),
+			deploymentGiven,
+			serviceGiven,
+			testSettings,
+			// the same host on a different path is not a conflict, even
+			// when one path is nested in the other
+			otherDeployer.
+				Ingress(&corev1alpha1.Ingress{
+					Hosts: []string{"www.example.com"},
+				}),
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(otherDeployer, deployerMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created Ingress "%s-deployer-001"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectCreates: []rtesting.Factory{
+			ingressWithoutRules.
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.AddAnnotation("kubernetes.io/ingress.class", "nginx")
+					om.AddAnnotation("nginx.ingress.kubernetes.io/proxy-body-size", "8m")
+				}).
+				AddRule(testHost, "/api", serviceGiven.Create().GetName()).
+				AddRule("www.example.com", "/api", serviceGiven.Create().GetName()),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
+				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusServiceRef(deployerMinimal.Create().GetName()).
+				StatusIngressRef("%s-deployer-001", testName).
+				StatusAddressURL(testAddressURL).
+				StatusURL("%s/api", testURL),
+		},
+	}, {
+		Name: "create ingress, with host template",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerMinimal.
+				Image(testImage).
+				IngressPolicy(corev1alpha1.IngressPolicyExternal),
+			deploymentGiven,
+			serviceGiven,
+			testSettings.
+				AddData("hostTemplate", "{{.Name}}-{{.Namespace}}.{{.Domain}}"),
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created Ingress "%s-deployer-001"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectCreates: []rtesting.Factory{
+			ingressCreate.
+				HostToService(fmt.Sprintf("%s-%s.%s", testName, testNamespace, testDomain), serviceGiven.Create().GetName()),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
+				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusServiceRef(deployerMinimal.Create().GetName()).
+				StatusIngressRef("%s-deployer-001", testName).
+				StatusAddressURL(testAddressURL).
+				StatusURL("http://%s-%s.%s", testName, testNamespace, testDomain),
+		},
+	}, {
+		Name: "create ingress, invalid host template",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerMinimal.
+				Image(testImage).
+				IngressPolicy(corev1alpha1.IngressPolicyExternal),
+			deploymentGiven,
+			serviceGiven,
+			testSettings.
+				AddData("hostTemplate", "{{.Bogus}}"),
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+		},
+		ShouldErr: true,
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.Unknown(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
+				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusServiceRef(deployerMinimal.Create().GetName()).
+				StatusAddressURL(testAddressURL),
+		},
+	}, {
+		Name: "create ingress, host conflict",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerMinimal.
+				Image(testImage).
+				IngressPolicy(corev1alpha1.IngressPolicyExternal).
+				Ingress(&corev1alpha1.Ingress{
+					Hosts: []string{"www.example.com"},
+					Path:  "/",
+				}),
+			deploymentGiven,
+			serviceGiven,
+			testSettings,
+			// created at the same time, the deployer named first is older
+			otherDeployer.
+				Ingress(&corev1alpha1.Ingress{
+					Hosts: []string{"www.example.com"},
+				}),
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(otherDeployer, deployerMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.False().Reason("HostConflict", `The host "www.example.com" is already routed to Deployer "other-deployer".`),
+					deployerConditionReady.False().Reason("HostConflict", `The host "www.example.com" is already routed to Deployer "other-deployer".`),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
+				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusServiceRef(deployerMinimal.Create().GetName()).
+				StatusAddressURL(testAddressURL),
+		},
+	}, {
+		Name: "create ingress, same path conflict",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerMinimal.
+				Image(testImage).
+				IngressPolicy(corev1alpha1.IngressPolicyExternal).
+				Ingress(&corev1alpha1.Ingress{
+					Hosts: []string{"www.example.com"},
+					Path:  "/api",
+				}),
+			deploymentGiven,
+			serviceGiven,
+			testSettings,
+			// a trailing slash routes the same path
+			otherDeployer.
+				Ingress(&corev1alpha1.Ingress{
+					Hosts: []string{"www.example.com"},
+					Path:  "/api/",
+				}),
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(otherDeployer, deployerMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.False().Reason("HostConflict", `The host "www.example.com" is already routed to Deployer "other-deployer".`),
+					deployerConditionReady.False().Reason("HostConflict", `The host "www.example.com" is already routed to Deployer "other-deployer".`),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
+				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusServiceRef(deployerMinimal.Create().GetName()).
+				StatusAddressURL(testAddressURL),
+		},
+	}, {
+		Name: "create ingress, host routed by a newer deployer",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerMinimal.
+				Image(testImage).
+				IngressPolicy(corev1alpha1.IngressPolicyExternal).
+				Ingress(&corev1alpha1.Ingress{
+					Hosts: []string{"www.example.com"},
+				}),
+			deploymentGiven,
+			serviceGiven,
+			testSettings,
+			// the older deployer owns the host
+			otherDeployer.
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.Created(1)
+				}).
+				Ingress(&corev1alpha1.Ingress{
+					Hosts: []string{"www.example.com"},
+				}),
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created Ingress "%s-deployer-001"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectCreates: []rtesting.Factory{
+			ingressWithoutRules.
+				AddRule(testHost, "/", serviceGiven.Create().GetName()).
+				AddRule("www.example.com", "/", serviceGiven.Create().GetName()),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
+				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusServiceRef(deployerMinimal.Create().GetName()).
+				StatusIngressRef("%s-deployer-001", testName).
+				StatusAddressURL(testAddressURL).
+				StatusURL(testURL),
+		},
+	}, {
+		Name: "create ingress, httproute not available",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerMinimal.
+				Image(testImage).
+				IngressPolicy(corev1alpha1.IngressPolicyExternal),
+			deploymentGiven,
+			serviceGiven,
+			testSettings.
+				AddData("routingBackend", "HTTPRoute").
+				AddData("gateway", "gateway-system/external"),
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.False().Reason("HTTPRouteNotAvailable", "The HTTPRoute API is not served by the cluster."),
+					deployerConditionReady.False().Reason("HTTPRouteNotAvailable", "The HTTPRoute API is not served by the cluster."),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
+				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusServiceRef(deployerMinimal.Create().GetName()).
+				StatusAddressURL(testAddressURL),
+		},
+	}, {
+		Name: "create ingress, create failed",
+		Key:  testKey,
+		WithReactors: []rtesting.ReactionFunc{
+			rtesting.InduceFailure("create", "Ingress"),
+		},
+		GivenObjects: []rtesting.Factory{
+			deployerMinimal.
+				Image(testImage).
+				IngressPolicy(corev1alpha1.IngressPolicyExternal),
+			deploymentGiven,
+			serviceGiven,
+			testSettings,
+		},
+		ShouldErr: true,
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeWarning, "CreationFailed",
+				`Failed to create Ingress "": inducing failure for create Ingress`),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectCreates: []rtesting.Factory{
+			ingressCreate,
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.Unknown(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
+				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusServiceRef(deployerMinimal.Create().GetName()).
+				StatusAddressURL(testAddressURL),
+		},
+	}, {
+		Name: "delete ingress",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerMinimal.
+				Image(testImage).
+				IngressPolicy(corev1alpha1.IngressPolicyClusterLocal),
+			deploymentGiven,
+			serviceGiven,
+			ingressGiven,
+			testSettings.
+				AddData("defaultDomain", "not.example.com"),
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Deleted",
+				`Deleted Ingress "%s"`, ingressGiven.Create().GetName()),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectDeletes: []rtesting.DeleteRef{
+			{Group: "networking.k8s.io", Kind: "Ingress", Namespace: testNamespace, Name: ingressGiven.Create().GetName()},
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
+				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusServiceRef(deployerMinimal.Create().GetName()).
+				StatusAddressURL(testAddressURL),
+		},
+	}, {
+		Name: "delete ingress, delete failed",
+		Key:  testKey,
+		WithReactors: []rtesting.ReactionFunc{
+			rtesting.InduceFailure("delete", "Ingress"),
+		},
+		GivenObjects: []rtesting.Factory{
+			deployerMinimal.
+				Image(testImage).
+				IngressPolicy(corev1alpha1.IngressPolicyClusterLocal),
+			deploymentGiven,
+			serviceGiven,
+			ingressGiven,
+			testSettings.
+				AddData("defaultDomain", "not.example.com"),
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
+		},
+		ShouldErr: true,
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeWarning, "DeleteFailed",
+				`Failed to delete Ingress "%s": inducing failure for delete Ingress`, ingressGiven.Create().GetName()),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectDeletes: []rtesting.DeleteRef{
+			{Group: "networking.k8s.io", Kind: "Ingress", Namespace: testNamespace, Name: ingressGiven.Create().GetName()},
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.Unknown(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
+				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusServiceRef(deployerMinimal.Create().GetName()).
+				StatusAddressURL(testAddressURL),
+		},
+	}, {
+		Name: "update ingress",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerMinimal.
+				Image(testImage).
+				IngressPolicy(corev1alpha1.IngressPolicyExternal),
+			deploymentGiven,
+			serviceGiven,
+			ingressGiven,
+			testSettings.
+				AddData("defaultDomain", "not.example.com"),
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Updated",
+				`Updated Ingress "%s"`, ingressGiven.Create().GetName()),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectUpdates: []rtesting.Factory{
+			ingressGiven.
+				HostToService(fmt.Sprintf("%s.%s.%s", testName, testNamespace, "not.example.com"), serviceGiven.Create().GetName()),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
+				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusServiceRef(deployerMinimal.Create().GetName()).
+				StatusIngressRef("%s-deployer-000", testName).
+				StatusAddressURL(testAddressURL).
+				StatusURL("http://%s.%s.%s", testName, testNamespace, "not.example.com"),
+		},
+	}, {
+		Name: "update ingress, update failed",
+		Key:  testKey,
+		WithReactors: []rtesting.ReactionFunc{
+			rtesting.InduceFailure("update", "Ingress"),
+		},
+		GivenObjects: []rtesting.Factory{
+			deployerMinimal.
+				Image(testImage).
+				IngressPolicy(corev1alpha1.IngressPolicyExternal),
+			deploymentGiven,
+			serviceGiven,
+			ingressGiven,
+			testSettings.
+				AddData("defaultDomain", "not.example.com"),
+		},
+		ShouldErr: true,
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeWarning, "UpdateFailed",
+				`Failed to update Ingress "%s": inducing failure for update Ingress`, ingressGiven.Create().GetName()),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectUpdates: []rtesting.Factory{
+			ingressGiven.
+				HostToService(fmt.Sprintf("%s.%s.%s", testName, testNamespace, "not.example.com"), serviceGiven.Create().GetName()),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.Unknown(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
+				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusServiceRef(deployerMinimal.Create().GetName()).
+				StatusAddressURL(testAddressURL),
+		},
+	}, {
+		Name: "remove extra ingress",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerMinimal.
+				Image(testImage).
+				IngressPolicy(corev1alpha1.IngressPolicyExternal),
+			deploymentGiven,
+			serviceGiven,
+			ingressGiven.
+				NamespaceName(testNamespace, "extra-ingress-1"),
+			ingressGiven.
+				NamespaceName(testNamespace, "extra-ingress-2"),
+			testSettings,
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Deleted",
+				`Deleted Ingress "%s"`, "extra-ingress-1"),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Deleted",
+				`Deleted Ingress "%s"`, "extra-ingress-2"),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created Ingress "%s-deployer-001"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+		},
+		ExpectDeletes: []rtesting.DeleteRef{
+			{Group: "networking.k8s.io", Kind: "Ingress", Namespace: testNamespace, Name: "extra-ingress-1"},
+			{Group: "networking.k8s.io", Kind: "Ingress", Namespace: testNamespace, Name: "extra-ingress-2"},
+		},
+		ExpectCreates: []rtesting.Factory{
+			ingressCreate,
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
+				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusServiceRef(deployerMinimal.Create().GetName()).
+				StatusIngressRef("%s-deployer-001", testName).
+				StatusAddressURL(testAddressURL).
+				StatusURL(testURL),
+		},
+	}, {
+		Name: "remove extra ingress, listing failed",
+		Key:  testKey,
+		WithReactors: []rtesting.ReactionFunc{
+			rtesting.InduceFailure("list", "IngressList"),
+		},
+		GivenObjects: []rtesting.Factory{
+			deployerMinimal.
+				Image(testImage).
+				IngressPolicy(corev1alpha1.IngressPolicyExternal),
+			deploymentGiven,
+			serviceGiven,
+			ingressGiven.
+				NamespaceName(testNamespace, "extra-ingress-1"),
+			ingressGiven.
+				NamespaceName(testNamespace, "extra-ingress-2"),
+			testSettings,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+		},
+		ShouldErr: true,
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.Unknown(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
+				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusServiceRef(deployerMinimal.Create().GetName()).
+				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
+		},
+	}, {
+		Name: "remove extra ingress, delete failed",
+		Key:  testKey,
+		WithReactors: []rtesting.ReactionFunc{
+			rtesting.InduceFailure("delete", "Ingress"),
+		},
+		GivenObjects: []rtesting.Factory{
+			deployerMinimal.
+				Image(testImage).
+				IngressPolicy(corev1alpha1.IngressPolicyExternal),
+			deploymentGiven,
+			serviceGiven,
+			ingressGiven.
+				NamespaceName(testNamespace, "extra-ingress-1"),
+			ingressGiven.
+				NamespaceName(testNamespace, "extra-ingress-2"),
+			testSettings,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+		},
+		ShouldErr: true,
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeWarning, "DeleteFailed",
+				`Failed to delete Ingress "%s": inducing failure for delete Ingress`, "extra-ingress-1"),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectDeletes: []rtesting.DeleteRef{
+			{Group: "networking.k8s.io", Kind: "Ingress", Namespace: testNamespace, Name: "extra-ingress-1"},
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.Unknown(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
+				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusServiceRef(deployerMinimal.Create().GetName()).
+				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
+		},
+	}, {
+		Name: "propagate labels",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerMinimal.
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.AddLabel(testLabelKey, testLabelValue)
+				}).
+				IngressPolicy(corev1alpha1.IngressPolicyExternal).
+				Image(testImage).
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
+				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusServiceRef(deployerMinimal.Create().GetName()).
+				StatusIngressRef(ingressGiven.Create().GetName()).
+				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()).
+				StatusURL(testURL),
+			deploymentGiven,
+			serviceGiven,
+			ingressGiven,
+			testSettings,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Updated",
+				`Updated Deployment "%s"`, deploymentGiven.Create().GetName()),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Updated",
+				`Updated Service "%s"`, serviceGiven.Create().GetName()),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Updated",
+				`Updated Ingress "%s"`, ingressGiven.Create().GetName()),
+		},
+		ExpectUpdates: []rtesting.Factory{
+			deploymentGiven.
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.AddLabel(testLabelKey, testLabelValue)
+				}).
+				PodTemplateSpec(func(pts factories.PodTemplateSpec) {
+					pts.AddLabel(testLabelKey, testLabelValue)
+				}),
+			serviceGiven.
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.AddLabel(testLabelKey, testLabelValue)
+				}),
+			ingressGiven.
+				ObjectMeta(func(om factories.ObjectMeta) {
+					om.AddLabel(testLabelKey, testLabelValue)
+				}),
+		},
+	}, {
+		Name: "create resources, with probes",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerValid.
+				Probes(&corev1alpha1.Probes{
+					Readiness: &corev1alpha1.Probe{Path: "/ready", PeriodSeconds: 5},
+					Liveness:  &corev1alpha1.Probe{Path: "/healthz", FailureThreshold: 5},
+					Startup:   &corev1alpha1.Probe{InitialDelaySeconds: 10, FailureThreshold: 30},
+				}).
+				MinReadySeconds(10).
+				ProgressDeadlineSeconds(120),
+			serviceGiven,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created Deployment "%s-deployer-001"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectCreates: []rtesting.Factory{
+			deploymentCreate.
+				HandlerContainer(func(container *corev1.Container) {
+					container.ReadinessProbe = &corev1.Probe{
+						Handler: corev1.Handler{
+							HTTPGet: &corev1.HTTPGetAction{
+								Path: "/ready",
+								Port: intstr.FromInt(8080),
+							},
+						},
+						PeriodSeconds: 5,
+					}
+					container.LivenessProbe = &corev1.Probe{
+						Handler: corev1.Handler{
+							HTTPGet: &corev1.HTTPGetAction{
+								Path: "/healthz",
+								Port: intstr.FromInt(8080),
+							},
+						},
+						FailureThreshold: 5,
+					}
+					container.StartupProbe = &corev1.Probe{
+						Handler: corev1.Handler{
+							TCPSocket: &corev1.TCPSocketAction{
+								Port: intstr.FromInt(8080),
+							},
+						},
+						InitialDelaySeconds: 10,
+						FailureThreshold:    30,
+					}
+				}).
+				MinReadySeconds(10).
+				ProgressDeadlineSeconds(120),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
+				StatusDeploymentRef("%s-deployer-001", deployerMinimal.Create().GetName()).
+				StatusServiceRef(deployerMinimal.Create().GetName()).
+				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
+		},
+	}, {
+		Name: "ready",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerValid,
+			deploymentGiven.
+				StatusConditions(
+					factories.Condition().Type("Available").True(),
+					factories.Condition().Type("Progressing").Unknown(),
+				),
+			serviceGiven,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
+				StatusConditions(
+					deployerConditionDeploymentReady.True(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.True(),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
+				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusServiceRef(deployerMinimal.Create().GetName()).
+				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
+		},
+	}, {
+		Name: "ready, with ingress",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerValid.
+				IngressPolicy(corev1alpha1.IngressPolicyExternal),
+			deploymentGiven.
+				StatusConditions(
+					factories.Condition().Type("Available").True(),
+					factories.Condition().Type("Progressing").Unknown(),
+				),
+			serviceGiven,
+			ingressGiven.
+				StatusLoadBalancer(
+					corev1.LoadBalancerIngress{
+						Hostname: testHost,
+					},
+				),
+			testSettings,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
+				StatusConditions(
+					deployerConditionDeploymentReady.True(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.True(),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
+				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusServiceRef(deployerMinimal.Create().GetName()).
+				StatusIngressRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusAddressURL(testAddressURL).
+				StatusURL(testURL),
+		},
+	}, {
+		Name: "not ready",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerValid,
+			deploymentGiven.
+				StatusConditions(
+					factories.Condition().Type("Available").False().Reason(testConditionReason, testConditionMessage),
+					factories.Condition().Type("Progressing").Unknown(),
+				),
+			serviceGiven,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
+				StatusConditions(
+					deployerConditionDeploymentReady.False().Reason(testConditionReason, testConditionMessage),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.False().Reason(testConditionReason, testConditionMessage),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
+				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusServiceRef(deployerMinimal.Create().GetName()).
+				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
+		},
+	}, {
+		Name: "not ready, progress deadline exceeded",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerValid,
+			deploymentGiven.
+				StatusConditions(
+					factories.Condition().Type("Available").True(),
+					factories.Condition().Type("Progressing").False().Reason("ProgressDeadlineExceeded", testConditionMessage),
+				),
+			serviceGiven,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
+				StatusConditions(
+					deployerConditionDeploymentReady.False().Reason("ProgressDeadlineExceeded", testConditionMessage),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.False().Reason("ProgressDeadlineExceeded", testConditionMessage),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
+				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusServiceRef(deployerMinimal.Create().GetName()).
+				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
+		},
+	}, {
+		Name: "update status error",
+		Key:  testKey,
+		WithReactors: []rtesting.ReactionFunc{
+			rtesting.InduceFailure("update", "Deployer"),
+		},
+		GivenObjects: []rtesting.Factory{
+			deployerValid,
+			deploymentGiven,
+			serviceGiven,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
+		},
+		ShouldErr: true,
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeWarning, "StatusUpdateFailed",
+				`Failed to update status: inducing failure for update Deployer`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
+				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusServiceRef(deployerMinimal.Create().GetName()).
+				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
+		},
+	}, {
+		Name: "settings not found",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerValid.
+				IngressPolicy(corev1alpha1.IngressPolicyExternal),
+			deploymentGiven,
+			serviceGiven,
+			ingressGiven,
+		},
+		ShouldErr: true,
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.Unknown(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
+				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
+				StatusServiceRef(deployerMinimal.Create().GetName()).
+				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
+		},
+	}}
+
+	table.Test(t, scheme, func(t *testing.T, row *rtesting.Testcase, client client.Client, apiReader client.Reader, tracker tracker.Tracker, recorder record.EventRecorder, log logr.Logger) reconcile.Reconciler {
+		return corecontrollers.DeployerReconciler(
+			controllers.Config{
+				Client:    client,
+				APIReader: apiReader,
+				Recorder:  recorder,
+				Scheme:    scheme,
+				Log:       log,
+				Tracker:   tracker,
+			},
+			testSystemNamespace,
+			networkingv1beta1.SchemeGroupVersion,
+			false,
+			false,
+			func() time.Time {
+				return testDeployedTime.Time
+			},
+		)
+	})
+}
+
+func TestDeployerReconciler_IngressV1(t *testing.T) {
+	testNamespace := "test-namespace"
+	testSystemNamespace := "riff-system"
+	testName := "test-deployer"
+	testKey := types.NamespacedName{Namespace: testNamespace, Name: testName}
+	testImagePrefix := "example.com/repo"
+	testSha256 := "cf8b4c69d5460f88530e1c80b8856a70801f31c50b191c8413043ba9b160a43e"
+	testImage := fmt.Sprintf("%s@sha256:%s", testImagePrefix, testSha256)
+	testDeployedTime := metav1.NewTime(time.Date(2020, 2, 3, 4, 5, 6, 0, time.UTC))
+	testRevision := corev1alpha1.DeployerRevision{Image: testImage, DeployedTime: testDeployedTime}
+	testDomain := "example.com"
+	testHost := fmt.Sprintf("%s.%s.%s", testName, testNamespace, testDomain)
+	testURL := fmt.Sprintf("http://%s", testHost)
+	testAddressURL := fmt.Sprintf("http://%s.%s.svc.cluster.local", testName, testNamespace)
+
+	deployerConditionDeploymentReady := factories.Condition().Type(corev1alpha1.DeployerConditionDeploymentReady)
+	deployerConditionIngressReady := factories.Condition().Type(corev1alpha1.DeployerConditionIngressReady)
+	deployerConditionReady := factories.Condition().Type(corev1alpha1.DeployerConditionReady)
+	deployerConditionServiceReady := factories.Condition().Type(corev1alpha1.DeployerConditionServiceReady)
+
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = buildv1alpha1.AddToScheme(scheme)
+	_ = corev1alpha1.AddToScheme(scheme)
+	_ = networkingv1.AddToScheme(scheme)
+
+	deployerMinimal := factories.DeployerCore().
+		NamespaceName(testNamespace, testName)
+	deployerValid := deployerMinimal.
+		Image(testImage).
+		IngressPolicy(corev1alpha1.IngressPolicyExternal)
+	otherDeployer := factories.DeployerCore().
+		NamespaceName(testNamespace, "other-deployer").
+		Image(testImage).
+		IngressPolicy(corev1alpha1.IngressPolicyExternal)
+
+	deploymentGiven := factories.Deployment().
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.Namespace(testNamespace)
+			om.Name("%s-deployer-000", testName)
+			om.AddLabel(corev1alpha1.DeployerLabelKey, testName)
+			om.ControlledBy(deployerMinimal, scheme)
+			om.Created(1)
+		}).
+		AddSelectorLabel(corev1alpha1.DeployerLabelKey, testName).
+		AddSelectorLabel(corev1alpha1.DeployerTrackLabelKey, corev1alpha1.DeployerTrackStable).
+		HandlerContainer(func(container *corev1.Container) {
+			container.Image = testImage
+			container.Ports = []corev1.ContainerPort{
+				{Name: "http", ContainerPort: 8080, Protocol: corev1.ProtocolTCP},
+			}
+			container.Env = []corev1.EnvVar{
+				{Name: "PORT", Value: "8080"},
+			}
+			container.ReadinessProbe = &corev1.Probe{
+				Handler: corev1.Handler{
+					TCPSocket: &corev1.TCPSocketAction{
+						Port: intstr.FromInt(8080),
+					},
+				},
+			}
+		})
+
+	serviceGiven := factories.Service().
+		NamespaceName(testNamespace, testName).
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.AddLabel(corev1alpha1.DeployerLabelKey, testName)
+			om.ControlledBy(deployerMinimal, scheme)
+			om.Created(1)
+		}).
+		AddSelectorLabel(corev1alpha1.DeployerLabelKey, testName).
+		Ports(
+			corev1.ServicePort{
+				Name:       "http",
+				Port:       80,
+				TargetPort: intstr.FromInt(8080),
+			},
+		)
+
+	ingressWithoutRules := factories.IngressV1().
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.Namespace(testNamespace)
+			om.GenerateName("%s-deployer-", testName)
+			om.AddLabel(corev1alpha1.DeployerLabelKey, testName)
+			om.ControlledBy(deployerMinimal, scheme)
+		})
+	ingressCreate := ingressWithoutRules.
+		AddRule(testHost, "/", serviceGiven.Create().GetName())
+	ingressGivenWithoutRules := ingressWithoutRules.
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.Name("%s%s", om.Create().GenerateName, "000")
+			om.Created(1)
+		})
+	ingressGiven := ingressGivenWithoutRules.
+		AddRule(testHost, "/", serviceGiven.Create().GetName())
+
+	testRegistryRewrites := factories.ConfigMap().
+		NamespaceName(testSystemNamespace, "riff-registry-rewrites")
+	testSettings := factories.ConfigMap().
+		NamespaceName(testSystemNamespace, "riff-core-settings").
+		AddData("defaultDomain", "example.com")
+
+	table := rtesting.Table{{
+		Name: "create ingress",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerValid,
+			deploymentGiven,
+			serviceGiven,
+			testSettings,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created Ingress "%s-deployer-001"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectCreates: []rtesting.Factory{
+			ingressCreate,
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
+				StatusDeploymentRef("%s-deployer-000", testName).
+				StatusServiceRef(testName).
+				StatusIngressRef("%s-deployer-001", testName).
+				StatusAddressURL(testAddressURL).
+				StatusURL(testURL),
+		},
+	}, {
+		Name: "create ingress, with hosts, path, class and annotations",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerValid.
+				Ingress(&corev1alpha1.Ingress{
+					Hosts:     []string{"www.example.com"},
+					Path:      "/api",
+					ClassName: "nginx",
+					Annotations: map[string]string{
+						"nginx.ingress.kubernetes.io/proxy-body-size": "8m",
+					},
+				}),
+			deploymentGiven,
+			serviceGiven,
+			testSettings,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created Ingress "%s-deployer-001"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectCreates: []rtesting.Factory{
+			ingressWithoutRules.
 				ObjectMeta(func(om factories.ObjectMeta) {
-					om.AddLabel(testLabelKey, testLabelValue)
+					om.AddAnnotation("nginx.ingress.kubernetes.io/proxy-body-size", "8m")
+				}).
+				ClassName("nginx").
+				AddRule(testHost, "/api", serviceGiven.Create().GetName()).
+				AddRule("www.example.com", "/api", serviceGiven.Create().GetName()),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
+				StatusDeploymentRef("%s-deployer-000", testName).
+				StatusServiceRef(testName).
+				StatusIngressRef("%s-deployer-001", testName).
+				StatusAddressURL(testAddressURL).
+				StatusURL("%s/api", testURL),
+		},
+	}, {
+		Name: "create ingress, host conflict",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerValid.
+				Ingress(&corev1alpha1.Ingress{
+					Hosts: []string{"www.example.com"},
+					Path:  "/",
+				}),
+			deploymentGiven,
+			serviceGiven,
+			testSettings,
+			// created at the same time, the deployer named first is older
+			otherDeployer.
+				Ingress(&corev1alpha1.Ingress{
+					Hosts: []string{"www.example.com"},
 				}),
 		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(otherDeployer, deployerMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.False().Reason("HostConflict", `The host "www.example.com" is already routed to Deployer "other-deployer".`),
+					deployerConditionReady.False().Reason("HostConflict", `The host "www.example.com" is already routed to Deployer "other-deployer".`),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
+				StatusDeploymentRef("%s-deployer-000", testName).
+				StatusServiceRef(testName).
+				StatusAddressURL(testAddressURL),
+		},
 	}, {
-		Name: "ready",
+		Name: "update ingress",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerValid,
+			deploymentGiven,
+			serviceGiven,
+			ingressGiven,
+			testSettings.
+				AddData("defaultDomain", "not.example.com"),
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Updated",
+				`Updated Ingress "%s"`, ingressGiven.Create().GetName()),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectUpdates: []rtesting.Factory{
+			ingressGivenWithoutRules.
+				AddRule(fmt.Sprintf("%s.%s.%s", testName, testNamespace, "not.example.com"), "/", serviceGiven.Create().GetName()),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
+				StatusConditions(
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.Unknown(),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
+				StatusDeploymentRef("%s-deployer-000", testName).
+				StatusServiceRef(testName).
+				StatusIngressRef("%s-deployer-000", testName).
+				StatusAddressURL(testAddressURL).
+				StatusURL("http://%s.%s.%s", testName, testNamespace, "not.example.com"),
+		},
+	}, {
+		Name: "ready, with ingress",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerValid,
+			deploymentGiven.
+				StatusConditions(
+					factories.Condition().Type("Available").True(),
+					factories.Condition().Type("Progressing").Unknown(),
+				),
+			serviceGiven,
+			ingressGiven.
+				StatusLoadBalancer(
+					networkingv1.IngressLoadBalancerIngress{
+						Hostname: testHost,
+					},
+				),
+			testSettings,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
+				StatusConditions(
+					deployerConditionDeploymentReady.True(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.True(),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
+				StatusDeploymentRef("%s-deployer-000", testName).
+				StatusServiceRef(testName).
+				StatusIngressRef("%s-deployer-000", testName).
+				StatusAddressURL(testAddressURL).
+				StatusURL(testURL),
+		},
+	}}
+
+	table.Test(t, scheme, func(t *testing.T, row *rtesting.Testcase, client client.Client, apiReader client.Reader, tracker tracker.Tracker, recorder record.EventRecorder, log logr.Logger) reconcile.Reconciler {
+		return corecontrollers.DeployerReconciler(
+			controllers.Config{
+				Client:    client,
+				APIReader: apiReader,
+				Recorder:  recorder,
+				Scheme:    scheme,
+				Log:       log,
+				Tracker:   tracker,
+			},
+			testSystemNamespace,
+			networkingv1.GroupVersion,
+			false,
+			false,
+			func() time.Time {
+				return testDeployedTime.Time
+			},
+		)
+	})
+}
+
+func TestDeployerReconciler_HTTPRoute(t *testing.T) {
+	testNamespace := "test-namespace"
+	testSystemNamespace := "riff-system"
+	testName := "test-deployer"
+	testKey := types.NamespacedName{Namespace: testNamespace, Name: testName}
+	testImagePrefix := "example.com/repo"
+	testSha256 := "cf8b4c69d5460f88530e1c80b8856a70801f31c50b191c8413043ba9b160a43e"
+	testImage := fmt.Sprintf("%s@sha256:%s", testImagePrefix, testSha256)
+	testDeployedTime := metav1.NewTime(time.Date(2020, 2, 3, 4, 5, 6, 0, time.UTC))
+	testRevision := corev1alpha1.DeployerRevision{Image: testImage, DeployedTime: testDeployedTime}
+	testDomain := "example.com"
+	testHost := fmt.Sprintf("%s.%s.%s", testName, testNamespace, testDomain)
+	testURL := fmt.Sprintf("http://%s", testHost)
+	testAddressURL := fmt.Sprintf("http://%s.%s.svc.cluster.local", testName, testNamespace)
+	testGatewayNamespace := "gateway-system"
+	testGatewayName := "external"
+
+	deployerConditionDeploymentReady := factories.Condition().Type(corev1alpha1.DeployerConditionDeploymentReady)
+	deployerConditionIngressReady := factories.Condition().Type(corev1alpha1.DeployerConditionIngressReady)
+	deployerConditionReady := factories.Condition().Type(corev1alpha1.DeployerConditionReady)
+	deployerConditionServiceReady := factories.Condition().Type(corev1alpha1.DeployerConditionServiceReady)
+
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = buildv1alpha1.AddToScheme(scheme)
+	_ = corev1alpha1.AddToScheme(scheme)
+	_ = gatewayv1.AddToScheme(scheme)
+
+	deployerMinimal := factories.DeployerCore().
+		NamespaceName(testNamespace, testName)
+	deployerValid := deployerMinimal.
+		Image(testImage).
+		IngressPolicy(corev1alpha1.IngressPolicyExternal)
+
+	deploymentGiven := factories.Deployment().
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.Namespace(testNamespace)
+			om.Name("%s-deployer-000", testName)
+			om.AddLabel(corev1alpha1.DeployerLabelKey, testName)
+			om.ControlledBy(deployerMinimal, scheme)
+			om.Created(1)
+		}).
+		AddSelectorLabel(corev1alpha1.DeployerLabelKey, testName).
+		AddSelectorLabel(corev1alpha1.DeployerTrackLabelKey, corev1alpha1.DeployerTrackStable).
+		HandlerContainer(func(container *corev1.Container) {
+			container.Image = testImage
+			container.Ports = []corev1.ContainerPort{
+				{Name: "http", ContainerPort: 8080, Protocol: corev1.ProtocolTCP},
+			}
+			container.Env = []corev1.EnvVar{
+				{Name: "PORT", Value: "8080"},
+			}
+			container.ReadinessProbe = &corev1.Probe{
+				Handler: corev1.Handler{
+					TCPSocket: &corev1.TCPSocketAction{
+						Port: intstr.FromInt(8080),
+					},
+				},
+			}
+		})
+
+	serviceGiven := factories.Service().
+		NamespaceName(testNamespace, testName).
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.AddLabel(corev1alpha1.DeployerLabelKey, testName)
+			om.ControlledBy(deployerMinimal, scheme)
+			om.Created(1)
+		}).
+		AddSelectorLabel(corev1alpha1.DeployerLabelKey, testName).
+		Ports(
+			corev1.ServicePort{
+				Name:       "http",
+				Port:       80,
+				TargetPort: intstr.FromInt(8080),
+			},
+		)
+
+	ingressGiven := factories.Ingress().
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.Namespace(testNamespace)
+			om.Name("%s-deployer-000", testName)
+			om.AddLabel(corev1alpha1.DeployerLabelKey, testName)
+			om.ControlledBy(deployerMinimal, scheme)
+			om.Created(1)
+		}).
+		HostToService(testHost, testName)
+
+	networkPolicyCreate := factories.NetworkPolicy().
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.Namespace(testNamespace)
+			om.GenerateName("%s-deployer-", testName)
+			om.AddLabel(corev1alpha1.DeployerLabelKey, testName)
+			om.ControlledBy(deployerMinimal, scheme)
+		}).
+		AddSelectorLabel(corev1alpha1.DeployerLabelKey, testName)
+
+	routeCreate := factories.HTTPRoute().
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.Namespace(testNamespace)
+			om.GenerateName("%s-deployer-", testName)
+			om.AddLabel(corev1alpha1.DeployerLabelKey, testName)
+			om.ControlledBy(deployerMinimal, scheme)
+		}).
+		Gateway(testGatewayNamespace, testGatewayName).
+		Hostnames(testHost).
+		PathPrefix("/").
+		AddBackend(testName, 80)
+	routeGiven := routeCreate.
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.Name("%s%s", om.Create().GenerateName, "000")
+			om.Created(1)
+		})
+
+	testRegistryRewrites := factories.ConfigMap().
+		NamespaceName(testSystemNamespace, "riff-registry-rewrites")
+	testSettings := factories.ConfigMap().
+		NamespaceName(testSystemNamespace, "riff-core-settings").
+		AddData("defaultDomain", "example.com").
+		AddData("routingBackend", "HTTPRoute").
+		AddData("gateway", fmt.Sprintf("%s/%s", testGatewayNamespace, testGatewayName))
+
+	table := rtesting.Table{{
+		Name: "create route",
 		Key:  testKey,
 		GivenObjects: []rtesting.Factory{
 			deployerValid,
-			deploymentGiven.
-				StatusConditions(
-					factories.Condition().Type("Available").True(),
-					factories.Condition().Type("Progressing").Unknown(),
-				),
+			deploymentGiven,
 			serviceGiven,
+			testSettings,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created HTTPRoute "%s-deployer-001"`, testName),
 			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
 				`Updated status`),
 		},
+		ExpectCreates: []rtesting.Factory{
+			routeCreate,
+		},
 		ExpectStatusUpdates: []rtesting.Factory{
 			deployerMinimal.
 				StatusConditions(
-					deployerConditionDeploymentReady.True(),
-					deployerConditionIngressReady.True(),
-					deployerConditionReady.True(),
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.Unknown().Reason("GatewayPending", `Waiting for Gateway "external" to accept the route.`),
+					deployerConditionReady.Unknown().Reason("GatewayPending", `Waiting for Gateway "external" to accept the route.`),
 					deployerConditionServiceReady.True(),
 				).
 				StatusLatestImage(testImage).
-				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
-				StatusServiceRef(deployerMinimal.Create().GetName()).
-				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
+				StatusDeploymentRef("%s-deployer-000", testName).
+				StatusServiceRef(testName).
+				StatusHTTPRouteRef("%s-deployer-001", testName).
+				StatusAddressURL(testAddressURL),
 		},
 	}, {
-		Name: "ready, with ingress",
+		Name: "create route, with network policy",
 		Key:  testKey,
 		GivenObjects: []rtesting.Factory{
 			deployerValid.
-				IngressPolicy(corev1alpha1.IngressPolicyExternal),
-			deploymentGiven.
-				StatusConditions(
-					factories.Condition().Type("Available").True(),
-					factories.Condition().Type("Progressing").Unknown(),
-				),
+				NetworkPolicy(&corev1alpha1.NetworkPolicy{}),
+			deploymentGiven,
 			serviceGiven,
-			ingressGiven.
-				StatusLoadBalancer(
-					corev1.LoadBalancerIngress{
-						Hostname: testHost,
-					},
-				),
-			testSettings,
+			// the gateway's namespace is allowed instead
+			testSettings.
+				AddData("ingressNamespace", "my-ingress"),
 		},
 		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
 			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created NetworkPolicy "%s-deployer-001"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created HTTPRoute "%s-deployer-002"`, testName),
 			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
 				`Updated status`),
 		},
+		ExpectCreates: []rtesting.Factory{
+			networkPolicyCreate.
+				AllowIngress(8080, k8snetworkingv1.NetworkPolicyPeer{
+					NamespaceSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{"kubernetes.io/metadata.name": testGatewayNamespace},
+					},
+				}),
+			routeCreate,
+		},
 		ExpectStatusUpdates: []rtesting.Factory{
 			deployerMinimal.
 				StatusConditions(
-					deployerConditionDeploymentReady.True(),
-					deployerConditionIngressReady.True(),
-					deployerConditionReady.True(),
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.Unknown().Reason("GatewayPending", `Waiting for Gateway "external" to accept the route.`),
+					deployerConditionReady.Unknown().Reason("GatewayPending", `Waiting for Gateway "external" to accept the route.`),
 					deployerConditionServiceReady.True(),
 				).
 				StatusLatestImage(testImage).
-				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
-				StatusServiceRef(deployerMinimal.Create().GetName()).
-				StatusIngressRef("%s-deployer-000", deployerMinimal.Create().GetName()).
-				StatusAddressURL(testAddressURL).
-				StatusURL(testURL),
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
+				StatusDeploymentRef("%s-deployer-000", testName).
+				StatusNetworkPolicyRef("%s-deployer-001", testName).
+				StatusServiceRef(testName).
+				StatusHTTPRouteRef("%s-deployer-002", testName).
+				StatusAddressURL(testAddressURL),
 		},
 	}, {
-		Name: "not ready",
+		Name: "create route, gateway in deployer namespace",
 		Key:  testKey,
 		GivenObjects: []rtesting.Factory{
 			deployerValid,
-			deploymentGiven.
-				StatusConditions(
-					factories.Condition().Type("Available").False().Reason(testConditionReason, testConditionMessage),
-					factories.Condition().Type("Progressing").Unknown(),
-				),
+			deploymentGiven,
 			serviceGiven,
+			testSettings.
+				AddData("gateway", testGatewayName),
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created HTTPRoute "%s-deployer-001"`, testName),
 			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
 				`Updated status`),
 		},
+		ExpectCreates: []rtesting.Factory{
+			routeCreate.
+				Gateway(testNamespace, testGatewayName),
+		},
 		ExpectStatusUpdates: []rtesting.Factory{
 			deployerMinimal.
 				StatusConditions(
-					deployerConditionDeploymentReady.False().Reason(testConditionReason, testConditionMessage),
-					deployerConditionIngressReady.True(),
-					deployerConditionReady.False().Reason(testConditionReason, testConditionMessage),
+					deployerConditionDeploymentReady.Unknown(),
+					deployerConditionIngressReady.Unknown().Reason("GatewayPending", `Waiting for Gateway "external" to accept the route.`),
+					deployerConditionReady.Unknown().Reason("GatewayPending", `Waiting for Gateway "external" to accept the route.`),
 					deployerConditionServiceReady.True(),
 				).
 				StatusLatestImage(testImage).
-				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
-				StatusServiceRef(deployerMinimal.Create().GetName()).
-				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
+				StatusDeploymentRef("%s-deployer-000", testName).
+				StatusServiceRef(testName).
+				StatusHTTPRouteRef("%s-deployer-001", testName).
+				StatusAddressURL(testAddressURL),
 		},
 	}, {
-		Name: "update status error",
+		Name: "create route, invalid gateway",
 		Key:  testKey,
-		WithReactors: []rtesting.ReactionFunc{
-			rtesting.InduceFailure("update", "Deployer"),
-		},
 		GivenObjects: []rtesting.Factory{
 			deployerValid,
 			deploymentGiven,
 			serviceGiven,
+			testSettings.
+				AddData("gateway", "gateway-system/external/http"),
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
 		},
-		ShouldErr: true,
 		ExpectEvents: []rtesting.Event{
-			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeWarning, "StatusUpdateFailed",
-				`Failed to update status: inducing failure for update Deployer`),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
 		},
 		ExpectStatusUpdates: []rtesting.Factory{
 			deployerMinimal.
 				StatusConditions(
 					deployerConditionDeploymentReady.Unknown(),
-					deployerConditionIngressReady.True(),
+					deployerConditionIngressReady.Unknown(),
 					deployerConditionReady.Unknown(),
 					deployerConditionServiceReady.True(),
 				).
 				StatusLatestImage(testImage).
-				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
-				StatusServiceRef(deployerMinimal.Create().GetName()).
-				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
+				StatusDeploymentRef("%s-deployer-000", testName).
+				StatusServiceRef(testName).
+				StatusAddressURL(testAddressURL),
 		},
+		ShouldErr: true,
 	}, {
-		Name: "settings not found",
+		Name: "replace ingress with route",
 		Key:  testKey,
 		GivenObjects: []rtesting.Factory{
 			deployerValid.
-				IngressPolicy(corev1alpha1.IngressPolicyExternal),
+				StatusIngressRef("%s-deployer-000", testName).
+				StatusURL(testURL),
 			deploymentGiven,
 			serviceGiven,
 			ingressGiven,
+			testSettings,
 		},
-		ShouldErr: true,
 		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
 			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Deleted",
+				`Deleted Ingress "%s-deployer-000"`, testName),
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "Created",
+				`Created HTTPRoute "%s-deployer-001"`, testName),
 			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
 				`Updated status`),
 		},
+		ExpectDeletes: []rtesting.DeleteRef{
+			{Group: "networking.k8s.io", Kind: "Ingress", Namespace: testNamespace, Name: fmt.Sprintf("%s-deployer-000", testName)},
+		},
+		ExpectCreates: []rtesting.Factory{
+			routeCreate,
+		},
 		ExpectStatusUpdates: []rtesting.Factory{
 			deployerMinimal.
 				StatusConditions(
 					deployerConditionDeploymentReady.Unknown(),
-					deployerConditionIngressReady.Unknown(),
-					deployerConditionReady.Unknown(),
+					deployerConditionIngressReady.Unknown().Reason("GatewayPending", `Waiting for Gateway "external" to accept the route.`),
+					deployerConditionReady.Unknown().Reason("GatewayPending", `Waiting for Gateway "external" to accept the route.`),
 					deployerConditionServiceReady.True(),
 				).
 				StatusLatestImage(testImage).
-				StatusDeploymentRef("%s-deployer-000", deployerMinimal.Create().GetName()).
-				StatusServiceRef(deployerMinimal.Create().GetName()).
-				StatusAddressURL("http://%s.%s.svc.cluster.local", serviceCreate.Create().GetName(), serviceCreate.Create().GetNamespace()),
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
+				StatusDeploymentRef("%s-deployer-000", testName).
+				StatusServiceRef(testName).
+				StatusHTTPRouteRef("%s-deployer-001", testName).
+				StatusAddressURL(testAddressURL),
+		},
+	}, {
+		Name: "ready, with route",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			deployerValid,
+			deploymentGiven.
+				StatusConditions(
+					factories.Condition().Type("Available").True(),
+					factories.Condition().Type("Progressing").Unknown(),
+				),
+			serviceGiven,
+			routeGiven.
+				StatusParent(testGatewayNamespace, testGatewayName,
+					gatewayv1.Condition{
+						Type:   gatewayv1.RouteConditionAccepted,
+						Status: metav1.ConditionTrue,
+						Reason: "Accepted",
+					},
+				),
+			testSettings,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployerMinimal, scheme),
+			rtesting.NewTrackRequest(testSettings, deployerMinimal, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployerMinimal, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			deployerMinimal.
+				StatusConditions(
+					deployerConditionDeploymentReady.True(),
+					deployerConditionIngressReady.True(),
+					deployerConditionReady.True(),
+					deployerConditionServiceReady.True(),
+				).
+				StatusLatestImage(testImage).
+				StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
+				StatusDeploymentRef("%s-deployer-000", testName).
+				StatusServiceRef(testName).
+				StatusHTTPRouteRef("%s-deployer-000", testName).
+				StatusAddressURL(testAddressURL).
+				StatusURL(testURL),
 		},
 	}}
 
@@ -1460,6 +4070,312 @@ func TestDeployerReconciler(t *testing.T) {
 				Log:       log,
 				Tracker:   tracker,
 			},
+			testSystemNamespace,
+			networkingv1beta1.SchemeGroupVersion,
+			true,
+			false,
+			func() time.Time {
+				return testDeployedTime.Time
+			},
+		)
+	})
+}
+
+func TestDeployerBuildRefReconciler(t *testing.T) {
+	testNamespace := "test-namespace"
+	testSystemNamespace := "riff-system"
+	testName := "test-deployer"
+	testImagePrefix := "example.com/repo"
+	testImage := fmt.Sprintf("%s@sha256:%s", testImagePrefix, "cf8b4c69d5460f88530e1c80b8856a70801f31c50b191c8413043ba9b160a43e")
+	testPreviousImage := fmt.Sprintf("%s@sha256:%s", testImagePrefix, "0ea9b4a5ee4ab2adc77e2e5c9a3e2bf44e61f9f4aff4ad5d6e8b6bac3f02a1f5")
+	testNow := time.Date(2020, 2, 3, 4, 5, 6, 0, time.UTC)
+	testPreviousTime := metav1.NewTime(time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC))
+	testPreviousRevision := corev1alpha1.DeployerRevision{Image: testPreviousImage, DeployedTime: testPreviousTime, BuildGeneration: 1}
+
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = buildv1alpha1.AddToScheme(scheme)
+	_ = corev1alpha1.AddToScheme(scheme)
+
+	testRegistryRewrites := factories.ConfigMap().
+		NamespaceName(testSystemNamespace, "riff-registry-rewrites")
+	testApplication := factories.Application().
+		NamespaceName(testNamespace, "my-application").
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.Generation(2)
+		}).
+		StatusLatestImage(testImage)
+
+	deployer := factories.DeployerCore().
+		NamespaceName(testNamespace, testName).
+		ApplicationRef(testApplication.Create().GetName())
+	deployerTemplate := factories.DeployerCore().
+		NamespaceName(testNamespace, testName).
+		Image(testImage)
+	deployerDeployed := deployer.
+		StatusLatestImage(testImage).
+		StatusImageSource(corev1alpha1.DeployerImageSourceBuild).
+		StatusHistory(
+			corev1alpha1.DeployerRevision{Image: testImage, DeployedTime: testPreviousTime, BuildGeneration: 2},
+			testPreviousRevision,
+		)
+	deployerPinned := deployer.
+		PinnedImage(testPreviousImage).
+		StatusLatestImage(testPreviousImage).
+		StatusImageSource(corev1alpha1.DeployerImageSourcePinned).
+		StatusHistory(
+			corev1alpha1.DeployerRevision{Image: testPreviousImage, DeployedTime: testPreviousTime, BuildGeneration: 1},
+			corev1alpha1.DeployerRevision{Image: testImage, DeployedTime: testPreviousTime, BuildGeneration: 2},
+		)
+
+	table := rtesting.SubTable{{
+		Name: "record build image",
+		Parent: deployer.
+			StatusLatestImage(testPreviousImage).
+			StatusImageSource(corev1alpha1.DeployerImageSourceBuild).
+			StatusHistory(testPreviousRevision),
+		GivenObjects: []rtesting.Factory{
+			testApplication,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployer, scheme),
+			rtesting.NewTrackRequest(testApplication, deployer, scheme),
+		},
+		ExpectParent: deployer.
+			StatusLatestImage(testImage).
+			StatusImageSource(corev1alpha1.DeployerImageSourceBuild).
+			StatusHistory(
+				corev1alpha1.DeployerRevision{Image: testImage, DeployedTime: metav1.NewTime(testNow), BuildGeneration: 2},
+				testPreviousRevision,
+			),
+	}, {
+		Name:   "image already recorded",
+		Parent: deployerDeployed,
+		GivenObjects: []rtesting.Factory{
+			testApplication,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployer, scheme),
+			rtesting.NewTrackRequest(testApplication, deployer, scheme),
+		},
+		ExpectParent: deployerDeployed,
+	}, {
+		Name:   "record template image",
+		Parent: deployerTemplate,
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployer, scheme),
+		},
+		ExpectParent: deployerTemplate.
+			StatusLatestImage(testImage).
+			StatusImageSource(corev1alpha1.DeployerImageSourceTemplate).
+			StatusHistory(
+				corev1alpha1.DeployerRevision{Image: testImage, DeployedTime: metav1.NewTime(testNow)},
+			),
+	}, {
+		Name: "history is bounded",
+		Parent: deployer.
+			StatusLatestImage(testPreviousImage).
+			StatusImageSource(corev1alpha1.DeployerImageSourceBuild).
+			StatusHistory(func() []corev1alpha1.DeployerRevision {
+				history := []corev1alpha1.DeployerRevision{}
+				for i := 0; i < corev1alpha1.DeployerHistoryLimit; i++ {
+					history = append(history, corev1alpha1.DeployerRevision{Image: fmt.Sprintf("%s:%d", testImagePrefix, i), DeployedTime: testPreviousTime})
+				}
+				return history
+			}()...),
+		GivenObjects: []rtesting.Factory{
+			testApplication,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployer, scheme),
+			rtesting.NewTrackRequest(testApplication, deployer, scheme),
+		},
+		ExpectParent: deployer.
+			StatusLatestImage(testImage).
+			StatusImageSource(corev1alpha1.DeployerImageSourceBuild).
+			StatusHistory(func() []corev1alpha1.DeployerRevision {
+				history := []corev1alpha1.DeployerRevision{
+					{Image: testImage, DeployedTime: metav1.NewTime(testNow), BuildGeneration: 2},
+				}
+				for i := 0; i < corev1alpha1.DeployerHistoryLimit-1; i++ {
+					history = append(history, corev1alpha1.DeployerRevision{Image: fmt.Sprintf("%s:%d", testImagePrefix, i), DeployedTime: testPreviousTime})
+				}
+				return history
+			}()...),
+	}, {
+		Name: "pin image",
+		Parent: deployerDeployed.
+			PinnedImage(testPreviousImage),
+		GivenObjects: []rtesting.Factory{
+			testApplication,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployer, scheme),
+			rtesting.NewTrackRequest(testApplication, deployer, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployer, scheme, corev1.EventTypeNormal, "Pinned",
+				`Pinned image "%s"`, testPreviousImage),
+		},
+		ExpectParent: deployerDeployed.
+			PinnedImage(testPreviousImage).
+			StatusLatestImage(testPreviousImage).
+			StatusImageSource(corev1alpha1.DeployerImageSourcePinned).
+			StatusHistory(
+				corev1alpha1.DeployerRevision{Image: testPreviousImage, DeployedTime: metav1.NewTime(testNow), BuildGeneration: 1},
+				corev1alpha1.DeployerRevision{Image: testImage, DeployedTime: testPreviousTime, BuildGeneration: 2},
+			),
+	}, {
+		Name:   "pinned image unchanged",
+		Parent: deployerPinned,
+		GivenObjects: []rtesting.Factory{
+			testApplication,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployer, scheme),
+			rtesting.NewTrackRequest(testApplication, deployer, scheme),
+		},
+		ExpectParent: deployerPinned,
+	}, {
+		Name:   "pinned image, build not found",
+		Parent: deployerPinned,
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployer, scheme),
+			rtesting.NewTrackRequest(testApplication, deployer, scheme),
+		},
+		ExpectParent: deployerPinned,
+	}, {
+		Name: "unpin image",
+		Parent: deployerPinned.
+			PinnedImage(""),
+		GivenObjects: []rtesting.Factory{
+			testApplication,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployer, scheme),
+			rtesting.NewTrackRequest(testApplication, deployer, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployer, scheme, corev1.EventTypeNormal, "Unpinned",
+				`Unpinned image "%s"`, testPreviousImage),
+		},
+		ExpectParent: deployer.
+			StatusLatestImage(testImage).
+			StatusImageSource(corev1alpha1.DeployerImageSourceBuild).
+			StatusHistory(
+				corev1alpha1.DeployerRevision{Image: testImage, DeployedTime: metav1.NewTime(testNow), BuildGeneration: 2},
+				corev1alpha1.DeployerRevision{Image: testPreviousImage, DeployedTime: testPreviousTime, BuildGeneration: 1},
+			),
+	}, {
+		Name: "unpin image, build has no image",
+		Parent: deployerPinned.
+			PinnedImage(""),
+		GivenObjects: []rtesting.Factory{
+			testApplication.
+				StatusLatestImage(""),
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployer, scheme),
+			rtesting.NewTrackRequest(testApplication, deployer, scheme),
+		},
+		// the pinned image keeps running until the build produces an image
+		ExpectParent: deployerPinned.
+			PinnedImage(""),
+	}, {
+		Name: "unpin image, build not found",
+		Parent: deployerPinned.
+			PinnedImage(""),
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployer, scheme),
+			rtesting.NewTrackRequest(testApplication, deployer, scheme),
+		},
+		ExpectParent: deployerPinned.
+			PinnedImage(""),
+	}, {
+		Name: "pin image, registry rewrite",
+		Parent: deployerDeployed.
+			PinnedImage(testPreviousImage),
+		GivenObjects: []rtesting.Factory{
+			testApplication,
+			testRegistryRewrites.
+				AddData("rewrites", fmt.Sprintf("%s -> mirror.local/repo", testImagePrefix)),
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployer, scheme),
+			rtesting.NewTrackRequest(testApplication, deployer, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployer, scheme, corev1.EventTypeNormal, "Pinned",
+				`Pinned image "%s"`, strings.Replace(testPreviousImage, testImagePrefix, "mirror.local/repo", 1)),
+		},
+		ExpectParent: deployerDeployed.
+			PinnedImage(testPreviousImage).
+			StatusLatestImage(strings.Replace(testPreviousImage, testImagePrefix, "mirror.local/repo", 1)).
+			StatusImageSource(corev1alpha1.DeployerImageSourcePinned).
+			StatusHistory(
+				corev1alpha1.DeployerRevision{Image: strings.Replace(testPreviousImage, testImagePrefix, "mirror.local/repo", 1), DeployedTime: metav1.NewTime(testNow)},
+				corev1alpha1.DeployerRevision{Image: testImage, DeployedTime: testPreviousTime, BuildGeneration: 2},
+				testPreviousRevision,
+			),
+	}}
+
+	table.Test(t, scheme, func(t *testing.T, row *rtesting.SubTestcase, client client.Client, tracker tracker.Tracker, recorder record.EventRecorder, log logr.Logger) controllers.SubReconciler {
+		return corecontrollers.DeployerBuildRefReconciler(
+			controllers.Config{
+				Client:   client,
+				Recorder: recorder,
+				Scheme:   scheme,
+				Log:      log,
+				Tracker:  tracker,
+			},
+			testSystemNamespace,
+			func() time.Time {
+				return testNow
+			},
 		)
 	})
 }
+
+func intOrStringPtr(i intstr.IntOrString) *intstr.IntOrString {
+	return &i
+}
+
+func TestDiscoverNamespaceNameLabel(t *testing.T) {
+	tests := []struct {
+		name       string
+		gitVersion string
+		expected   bool
+	}{
+		{
+			name:       "1.20",
+			gitVersion: "v1.20.15",
+			expected:   false,
+		},
+		{
+			name:       "1.21",
+			gitVersion: "v1.21.0",
+			expected:   true,
+		},
+		{
+			name:       "vendor suffix",
+			gitVersion: "v1.24.3-gke.200",
+			expected:   true,
+		},
+	}
+	for _, c := range tests {
+		t.Run(c.name, func(t *testing.T) {
+			discovery := &fakediscovery.FakeDiscovery{
+				Fake:               &clientgotesting.Fake{},
+				FakedServerVersion: &version.Info{GitVersion: c.gitVersion},
+			}
+			actual, err := corecontrollers.DiscoverNamespaceNameLabel(discovery)
+			if err != nil {
+				t.Errorf("DiscoverNamespaceNameLabel() error = %v", err)
+				return
+			}
+			if actual != c.expected {
+				t.Errorf("DiscoverNamespaceNameLabel() = %v, expected %v", actual, c.expected)
+			}
+		})
+	}
+}
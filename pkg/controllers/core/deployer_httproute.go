@@ -0,0 +1,200 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/discovery"
+
+	corev1alpha1 "github.com/projectriff/system/pkg/apis/core/v1alpha1"
+	gatewayv1 "github.com/projectriff/system/pkg/apis/thirdparty/gateway/v1"
+	"github.com/projectriff/system/pkg/controllers"
+	"github.com/projectriff/system/pkg/refs"
+)
+
+const (
+	DeployerHTTPRouteStashKey controllers.StashKey = "deployer-httproute"
+)
+
+// DeployerHTTPRoute is the routing resolved for a deployer exposed by an
+// HTTPRoute
+type DeployerHTTPRoute struct {
+	// Hosts routed to the deployer, the first host is used for the
+	// deployer's URL
+	Hosts []string
+	// Path prefix routed on each host
+	Path string
+	// Gateway the route is attached to
+	Gateway gatewayv1.ParentReference
+}
+
+// DiscoverHTTPRouteAvailable returns true when the cluster serves
+// gateway.networking.k8s.io/v1 HTTPRoutes
+func DiscoverHTTPRouteAvailable(d discovery.DiscoveryInterface) (bool, error) {
+	groups, err := d.ServerGroups()
+	if err != nil {
+		return false, err
+	}
+	for _, group := range groups.Groups {
+		if group.Name != gatewayv1.GroupVersion.Group {
+			continue
+		}
+		for _, version := range group.Versions {
+			if version.Version != gatewayv1.GroupVersion.Version {
+				continue
+			}
+			resources, err := d.ServerResourcesForGroupVersion(version.GroupVersion)
+			if err != nil {
+				return false, err
+			}
+			for _, resource := range resources.APIResources {
+				if resource.Name == "httproutes" {
+					return true, nil
+				}
+			}
+		}
+	}
+	return false, nil
+}
+
+// deployerGateway parses the Gateway HTTPRoutes are attached to, in the
+// form namespace/name or name
+func deployerGateway(parent *corev1alpha1.Deployer, gateway string) (gatewayv1.ParentReference, error) {
+	namespace, name := parent.Namespace, gateway
+	if parts := strings.Split(gateway, "/"); len(parts) == 2 {
+		namespace, name = parts[0], parts[1]
+	}
+	if namespace == "" || name == "" || strings.Contains(name, "/") {
+		return gatewayv1.ParentReference{}, fmt.Errorf("invalid %s %q: expected namespace/name", gatewayKey, gateway)
+	}
+	group := gatewayv1.GroupVersion.Group
+	kind := "Gateway"
+	return gatewayv1.ParentReference{
+		Group:     &group,
+		Kind:      &kind,
+		Namespace: &namespace,
+		Name:      name,
+	}, nil
+}
+
+// DeployerChildHTTPRouteReconciler exposes the deployer through an
+// HTTPRoute attached to the cluster's Gateway. The URL is reported once the
+// Gateway accepts the route.
+func DeployerChildHTTPRouteReconciler(c controllers.Config) controllers.SubReconciler {
+	c.Log = c.Log.WithName("ChildHTTPRoute")
+
+	return &controllers.ChildReconciler{
+		ParentType:    &corev1alpha1.Deployer{},
+		ChildType:     &gatewayv1.HTTPRoute{},
+		ChildListType: &gatewayv1.HTTPRouteList{},
+
+		DesiredChild: func(ctx context.Context, parent *corev1alpha1.Deployer) (*gatewayv1.HTTPRoute, error) {
+			route, ok := controllers.RetrieveValue(ctx, DeployerHTTPRouteStashKey).(*DeployerHTTPRoute)
+			if !ok {
+				// no route, skip
+				return nil, nil
+			}
+
+			pathType := gatewayv1.PathMatchPathPrefix
+			path := route.Path
+			backends := []gatewayv1.HTTPBackendRef{
+				deployerHTTPBackendRef(parent.Status.ServiceRef.Name),
+			}
+			if rollout := rolloutInProgress(parent); rollout != nil && rollout.CanaryServiceRef != nil && parent.Spec.Rollout.TrafficRouting == corev1alpha1.RolloutTrafficRoutingIngress {
+				stableWeight := 100 - rollout.Weight
+				canaryWeight := rollout.Weight
+				backends[0].Weight = &stableWeight
+				backends = append(backends, deployerHTTPBackendRef(rollout.CanaryServiceRef.Name))
+				backends[1].Weight = &canaryWeight
+			}
+
+			child := &gatewayv1.HTTPRoute{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: controllers.MergeMaps(parent.Labels, map[string]string{
+						corev1alpha1.DeployerLabelKey: parent.Name,
+					}),
+					GenerateName: fmt.Sprintf("%s-deployer-", parent.Name),
+					Namespace:    parent.Namespace,
+				},
+				Spec: gatewayv1.HTTPRouteSpec{
+					ParentRefs: []gatewayv1.ParentReference{route.Gateway},
+					Hostnames:  route.Hosts,
+					Rules: []gatewayv1.HTTPRouteRule{{
+						Matches: []gatewayv1.HTTPRouteMatch{{
+							Path: &gatewayv1.HTTPPathMatch{
+								Type:  &pathType,
+								Value: &path,
+							},
+						}},
+						BackendRefs: backends,
+					}},
+				},
+			}
+
+			return child, nil
+		},
+		ReflectChildStatusOnParent: func(parent *corev1alpha1.Deployer, child *gatewayv1.HTTPRoute, err error) {
+			if err != nil {
+				return
+			}
+			if child == nil {
+				parent.Status.HTTPRouteRef = nil
+				return
+			}
+			parent.Status.HTTPRouteRef = refs.NewTypedLocalObjectReferenceForObject(child, c.Scheme)
+			parent.Status.PropagateHTTPRouteStatus(&child.Status, child.Spec.ParentRefs[0])
+			parent.Status.URL = ""
+			if parent.Status.GetCondition(corev1alpha1.DeployerConditionIngressReady).IsTrue() {
+				parent.Status.URL = fmt.Sprintf("http://%s", child.Spec.Hostnames[0])
+				if path := *child.Spec.Rules[0].Matches[0].Path.Value; path != "/" {
+					parent.Status.URL += path
+				}
+			}
+		},
+		MergeBeforeUpdate: func(current, desired *gatewayv1.HTTPRoute) {
+			current.Labels = desired.Labels
+			current.Spec = desired.Spec
+		},
+		SemanticEquals: func(a1, a2 *gatewayv1.HTTPRoute) bool {
+			return equality.Semantic.DeepEqual(a1.Spec, a2.Spec) &&
+				equality.Semantic.DeepEqual(a1.Labels, a2.Labels)
+		},
+
+		Config:     c,
+		IndexField: ".metadata.httpRouteController",
+		Sanitize: func(child *gatewayv1.HTTPRoute) interface{} {
+			return child.Spec
+		},
+	}
+}
+
+func deployerHTTPBackendRef(serviceName string) gatewayv1.HTTPBackendRef {
+	port := int32(80)
+	return gatewayv1.HTTPBackendRef{
+		BackendRef: gatewayv1.BackendRef{
+			BackendObjectReference: gatewayv1.BackendObjectReference{
+				Name: serviceName,
+				Port: &port,
+			},
+		},
+	}
+}
@@ -0,0 +1,384 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	clientgotesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1alpha1 "github.com/projectriff/system/pkg/apis/core/v1alpha1"
+	gatewayv1 "github.com/projectriff/system/pkg/apis/thirdparty/gateway/v1"
+	"github.com/projectriff/system/pkg/controllers"
+	corecontrollers "github.com/projectriff/system/pkg/controllers/core"
+	rtesting "github.com/projectriff/system/pkg/controllers/testing"
+	"github.com/projectriff/system/pkg/controllers/testing/factories"
+	"github.com/projectriff/system/pkg/refs"
+	"github.com/projectriff/system/pkg/tracker"
+)
+
+func TestDeployerChildHTTPRouteReconciler(t *testing.T) {
+	testNamespace := "test-namespace"
+	testName := "test-deployer"
+	testHost := fmt.Sprintf("%s.%s.example.com", testName, testNamespace)
+	testGatewayNamespace := "gateway-system"
+	testGatewayName := "external"
+	testNow := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	deployerConditionDeploymentReady := factories.Condition().Type(corev1alpha1.DeployerConditionDeploymentReady)
+	deployerConditionIngressReady := factories.Condition().Type(corev1alpha1.DeployerConditionIngressReady)
+	deployerConditionReady := factories.Condition().Type(corev1alpha1.DeployerConditionReady)
+	deployerConditionServiceReady := factories.Condition().Type(corev1alpha1.DeployerConditionServiceReady)
+
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = corev1alpha1.AddToScheme(scheme)
+	_ = gatewayv1.AddToScheme(scheme)
+
+	testGateway := func() gatewayv1.ParentReference {
+		group := gatewayv1.GroupVersion.Group
+		kind := "Gateway"
+		namespace := testGatewayNamespace
+		return gatewayv1.ParentReference{
+			Group:     &group,
+			Kind:      &kind,
+			Namespace: &namespace,
+			Name:      testGatewayName,
+		}
+	}
+	routeCondition := func(conditionType string, status metav1.ConditionStatus, reason, message string) gatewayv1.Condition {
+		return gatewayv1.Condition{
+			Type:               conditionType,
+			Status:             status,
+			LastTransitionTime: metav1.NewTime(testNow),
+			Reason:             reason,
+			Message:            message,
+		}
+	}
+
+	deployer := factories.DeployerCore().
+		NamespaceName(testNamespace, testName).
+		IngressPolicy(corev1alpha1.IngressPolicyExternal).
+		StatusServiceRef(testName).
+		StatusConditions(
+			deployerConditionDeploymentReady.True(),
+			deployerConditionIngressReady.Unknown(),
+			deployerConditionReady.Unknown(),
+			deployerConditionServiceReady.True(),
+		)
+	deployerPending := deployer.
+		StatusConditions(
+			deployerConditionDeploymentReady.True(),
+			deployerConditionIngressReady.Unknown().Reason("GatewayPending", `Waiting for Gateway "external" to accept the route.`),
+			deployerConditionReady.Unknown().Reason("GatewayPending", `Waiting for Gateway "external" to accept the route.`),
+			deployerConditionServiceReady.True(),
+		)
+	deployerReady := deployer.
+		StatusConditions(
+			deployerConditionDeploymentReady.True(),
+			deployerConditionIngressReady.True(),
+			deployerConditionReady.True(),
+			deployerConditionServiceReady.True(),
+		)
+
+	routeWithoutBackends := factories.HTTPRoute().
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.Namespace(testNamespace)
+			om.GenerateName("%s-deployer-", testName)
+			om.AddLabel(corev1alpha1.DeployerLabelKey, testName)
+			om.ControlledBy(deployer, scheme)
+		}).
+		Gateway(testGatewayNamespace, testGatewayName).
+		Hostnames(testHost).
+		PathPrefix("/")
+	routeCreate := routeWithoutBackends.
+		AddBackend(testName, 80)
+	routeGiven := routeCreate.
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.Name("%s%s", om.Create().GenerateName, "000")
+			om.Created(1)
+		})
+	routeAccepted := routeGiven.
+		StatusParent(testGatewayNamespace, testGatewayName,
+			routeCondition(gatewayv1.RouteConditionAccepted, metav1.ConditionTrue, "Accepted", ""),
+			routeCondition(gatewayv1.RouteConditionResolvedRefs, metav1.ConditionTrue, "ResolvedRefs", ""),
+		)
+
+	canaryDeployer := deployer.
+		Rollout(&corev1alpha1.Rollout{
+			Steps: []corev1alpha1.RolloutStep{
+				{Weight: 25},
+			},
+			TrafficRouting: corev1alpha1.RolloutTrafficRoutingIngress,
+		}).
+		StatusRollout(&corev1alpha1.RolloutStatus{
+			Phase:       corev1alpha1.RolloutPhaseProgressing,
+			CanaryImage: "example.com/repo@sha256:0ea9b4a5ee4ab2adc77e2e5c9a3e2bf44e61f9f4aff4ad5d6e8b6bac3f02a1f5",
+			Weight:      25,
+			CanaryServiceRef: &refs.TypedLocalObjectReference{
+				Kind: "Service",
+				Name: fmt.Sprintf("%s-canary", testName),
+			},
+		})
+
+	stashedRoute := map[controllers.StashKey]interface{}{
+		corecontrollers.DeployerHTTPRouteStashKey: &corecontrollers.DeployerHTTPRoute{
+			Hosts:   []string{testHost},
+			Path:    "/",
+			Gateway: testGateway(),
+		},
+	}
+
+	table := rtesting.SubTable{{
+		Name:         "no route",
+		Parent:       deployer,
+		ExpectParent: deployer,
+	}, {
+		Name:               "create route",
+		Parent:             deployer,
+		GivenStashedValues: stashedRoute,
+		ExpectParent: deployerPending.
+			StatusHTTPRouteRef("%s-deployer-001", testName),
+		ExpectCreates: []rtesting.Factory{
+			routeCreate,
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployer, scheme, corev1.EventTypeNormal, "Created",
+				`Created HTTPRoute "%s-deployer-001"`, testName),
+		},
+	}, {
+		Name:               "create route, with canary",
+		Parent:             canaryDeployer,
+		GivenStashedValues: stashedRoute,
+		ExpectParent: canaryDeployer.
+			StatusConditions(
+				deployerConditionDeploymentReady.True(),
+				deployerConditionIngressReady.Unknown().Reason("GatewayPending", `Waiting for Gateway "external" to accept the route.`),
+				deployerConditionReady.Unknown().Reason("GatewayPending", `Waiting for Gateway "external" to accept the route.`),
+				deployerConditionServiceReady.True(),
+			).
+			StatusHTTPRouteRef("%s-deployer-001", testName),
+		ExpectCreates: []rtesting.Factory{
+			routeWithoutBackends.
+				AddWeightedBackend(testName, 80, 75).
+				AddWeightedBackend(fmt.Sprintf("%s-canary", testName), 80, 25),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployer, scheme, corev1.EventTypeNormal, "Created",
+				`Created HTTPRoute "%s-deployer-001"`, testName),
+		},
+	}, {
+		Name:               "route accepted",
+		Parent:             deployer,
+		GivenStashedValues: stashedRoute,
+		GivenObjects: []rtesting.Factory{
+			routeAccepted,
+		},
+		ExpectParent: deployerReady.
+			StatusHTTPRouteRef("%s-deployer-000", testName).
+			StatusURL("http://%s", testHost),
+	}, {
+		Name:   "route accepted, with path",
+		Parent: deployer,
+		GivenStashedValues: map[controllers.StashKey]interface{}{
+			corecontrollers.DeployerHTTPRouteStashKey: &corecontrollers.DeployerHTTPRoute{
+				Hosts:   []string{testHost, "www.example.com"},
+				Path:    "/api",
+				Gateway: testGateway(),
+			},
+		},
+		GivenObjects: []rtesting.Factory{
+			routeAccepted.
+				Hostnames(testHost, "www.example.com").
+				PathPrefix("/api"),
+		},
+		ExpectParent: deployerReady.
+			StatusHTTPRouteRef("%s-deployer-000", testName).
+			StatusURL("http://%s/api", testHost),
+	}, {
+		Name:               "route not accepted",
+		Parent:             deployer,
+		GivenStashedValues: stashedRoute,
+		GivenObjects: []rtesting.Factory{
+			routeGiven.
+				StatusParent(testGatewayNamespace, testGatewayName,
+					routeCondition(gatewayv1.RouteConditionAccepted, metav1.ConditionFalse, "NotAllowedByListeners", "no listener allows routes from the namespace"),
+				),
+		},
+		ExpectParent: deployer.
+			StatusConditions(
+				deployerConditionDeploymentReady.True(),
+				deployerConditionIngressReady.False().Reason("NotAllowedByListeners", "no listener allows routes from the namespace"),
+				deployerConditionReady.False().Reason("NotAllowedByListeners", "no listener allows routes from the namespace"),
+				deployerConditionServiceReady.True(),
+			).
+			StatusHTTPRouteRef("%s-deployer-000", testName),
+	}, {
+		Name:               "route backends not resolved",
+		Parent:             deployer,
+		GivenStashedValues: stashedRoute,
+		GivenObjects: []rtesting.Factory{
+			routeGiven.
+				StatusParent(testGatewayNamespace, testGatewayName,
+					routeCondition(gatewayv1.RouteConditionAccepted, metav1.ConditionTrue, "Accepted", ""),
+					routeCondition(gatewayv1.RouteConditionResolvedRefs, metav1.ConditionFalse, "BackendNotFound", "service not found"),
+				),
+		},
+		ExpectParent: deployer.
+			StatusConditions(
+				deployerConditionDeploymentReady.True(),
+				deployerConditionIngressReady.False().Reason("BackendNotFound", "service not found"),
+				deployerConditionReady.False().Reason("BackendNotFound", "service not found"),
+				deployerConditionServiceReady.True(),
+			).
+			StatusHTTPRouteRef("%s-deployer-000", testName),
+	}, {
+		Name:               "route accepted by another gateway",
+		Parent:             deployer,
+		GivenStashedValues: stashedRoute,
+		GivenObjects: []rtesting.Factory{
+			routeGiven.
+				StatusParent(testGatewayNamespace, "internal",
+					routeCondition(gatewayv1.RouteConditionAccepted, metav1.ConditionTrue, "Accepted", ""),
+				),
+		},
+		ExpectParent: deployerPending.
+			StatusHTTPRouteRef("%s-deployer-000", testName),
+	}, {
+		Name:               "update route",
+		Parent:             deployer,
+		GivenStashedValues: stashedRoute,
+		GivenObjects: []rtesting.Factory{
+			routeAccepted.
+				Hostnames("www.example.com"),
+		},
+		ExpectParent: deployerReady.
+			StatusHTTPRouteRef("%s-deployer-000", testName).
+			StatusURL("http://%s", testHost),
+		ExpectUpdates: []rtesting.Factory{
+			routeAccepted,
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployer, scheme, corev1.EventTypeNormal, "Updated",
+				`Updated HTTPRoute "%s-deployer-000"`, testName),
+		},
+	}, {
+		Name: "delete route",
+		Parent: deployer.
+			StatusHTTPRouteRef("%s-deployer-000", testName).
+			StatusURL("http://%s", testHost),
+		GivenObjects: []rtesting.Factory{
+			routeAccepted,
+		},
+		ExpectParent: deployer.
+			StatusURL("http://%s", testHost),
+		ExpectDeletes: []rtesting.DeleteRef{
+			{Group: "gateway.networking.k8s.io", Kind: "HTTPRoute", Namespace: testNamespace, Name: fmt.Sprintf("%s-deployer-000", testName)},
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployer, scheme, corev1.EventTypeNormal, "Deleted",
+				`Deleted HTTPRoute "%s-deployer-000"`, testName),
+		},
+	}}
+
+	table.Test(t, scheme, func(t *testing.T, row *rtesting.SubTestcase, client client.Client, tracker tracker.Tracker, recorder record.EventRecorder, log logr.Logger) controllers.SubReconciler {
+		return corecontrollers.DeployerChildHTTPRouteReconciler(
+			controllers.Config{
+				Client:   client,
+				Recorder: recorder,
+				Scheme:   scheme,
+				Log:      log,
+				Tracker:  tracker,
+			},
+		)
+	})
+}
+
+func TestDiscoverHTTPRouteAvailable(t *testing.T) {
+	tests := []struct {
+		name      string
+		resources []*metav1.APIResourceList
+		expected  bool
+	}{
+		{
+			name: "gateway.networking.k8s.io/v1",
+			resources: []*metav1.APIResourceList{
+				{
+					GroupVersion: "gateway.networking.k8s.io/v1",
+					APIResources: []metav1.APIResource{
+						{Name: "gateways", Kind: "Gateway"},
+						{Name: "httproutes", Kind: "HTTPRoute"},
+					},
+				},
+			},
+			expected: true,
+		},
+		{
+			name: "gateway.networking.k8s.io/v1 without httproutes",
+			resources: []*metav1.APIResourceList{
+				{
+					GroupVersion: "gateway.networking.k8s.io/v1",
+					APIResources: []metav1.APIResource{
+						{Name: "gateways", Kind: "Gateway"},
+					},
+				},
+			},
+			expected: false,
+		},
+		{
+			name: "gateway.networking.k8s.io/v1beta1",
+			resources: []*metav1.APIResourceList{
+				{
+					GroupVersion: "gateway.networking.k8s.io/v1beta1",
+					APIResources: []metav1.APIResource{
+						{Name: "httproutes", Kind: "HTTPRoute"},
+					},
+				},
+			},
+			expected: false,
+		},
+		{
+			name:     "not installed",
+			expected: false,
+		},
+	}
+	for _, c := range tests {
+		t.Run(c.name, func(t *testing.T) {
+			discovery := &fakediscovery.FakeDiscovery{
+				Fake: &clientgotesting.Fake{Resources: c.resources},
+			}
+			actual, err := corecontrollers.DiscoverHTTPRouteAvailable(discovery)
+			if err != nil {
+				t.Errorf("DiscoverHTTPRouteAvailable() error = %v", err)
+				return
+			}
+			if actual != c.expected {
+				t.Errorf("DiscoverHTTPRouteAvailable() = %v, expected %v", actual, c.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,274 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	corev1alpha1 "github.com/projectriff/system/pkg/apis/core/v1alpha1"
+	"github.com/projectriff/system/pkg/controllers"
+	"github.com/projectriff/system/pkg/tracker"
+)
+
+const (
+	DeployerIngressStashKey controllers.StashKey = "deployer-ingress"
+
+	ingressClassAnnotationKey = "kubernetes.io/ingress.class"
+)
+
+// DeployerIngress is the routing resolved for a deployer's ingress
+type DeployerIngress struct {
+	// Hosts routed to the deployer, the first host is used for the
+	// deployer's URL
+	Hosts []string
+	// Path prefix routed on each host
+	Path string
+	// Annotations for the Ingress
+	Annotations map[string]string
+}
+
+// DeployerIngressReconciler resolves the hosts, path and annotations for the
+// deployer's ingress. A deployer created earlier in the namespace owns its
+// hosts and path, routing the same path on the same host is a conflict and
+// the ingress is not created. Conflicts are rejected on admission by the
+// DeployerIngressValidator, the conflict is only reported here for deployers
+// admitted concurrently. When the cluster's settings select HTTPRoutes, the
+// hosts and path are resolved for an HTTPRoute instead.
+func DeployerIngressReconciler(c controllers.Config, namespace string, httpRouteAvailable bool) controllers.SubReconciler {
+	c.Log = c.Log.WithName("Ingress")
+
+	return &controllers.SyncReconciler{
+		Setup: func(mgr controllers.Manager, bldr *controllers.Builder) error {
+			bldr.Watches(&source.Kind{Type: &corev1.ConfigMap{}}, controllers.EnqueueTracked(&corev1.ConfigMap{}, c.Tracker, c.Scheme))
+			bldr.Watches(&source.Kind{Type: &corev1alpha1.Deployer{}}, controllers.EnqueueTracked(&corev1alpha1.Deployer{}, c.Tracker, c.Scheme))
+			return nil
+		},
+		Sync: func(ctx context.Context, parent *corev1alpha1.Deployer) error {
+			if parent.Status.ServiceRef == nil || parent.Spec.IngressPolicy == corev1alpha1.IngressPolicyClusterLocal {
+				// no service, skip
+				return nil
+			}
+
+			coreSettings := &corev1.ConfigMap{}
+			coreSettingsKey := types.NamespacedName{Namespace: namespace, Name: settingsConfigMapName}
+
+			// track config map
+			c.Tracker.Track(
+				tracker.NewKey(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, coreSettingsKey),
+				types.NamespacedName{Namespace: parent.Namespace, Name: parent.Name},
+			)
+			if err := c.Get(ctx, coreSettingsKey, coreSettings); err != nil {
+				c.Log.Error(err, fmt.Sprintf("unable to fetch resource with reference: %s", coreSettingsKey.String()))
+				return err
+			}
+
+			ingress, err := deployerIngress(parent, coreSettings)
+			if err != nil {
+				return err
+			}
+
+			if coreSettings.Data[routingBackendKey] == routingBackendHTTPRoute {
+				if !httpRouteAvailable {
+					parent.Status.MarkHTTPRouteNotAvailable()
+					return nil
+				}
+				gateway, err := deployerGateway(parent, coreSettings.Data[gatewayKey])
+				if err != nil {
+					return err
+				}
+				// conflicting routes are resolved by the gateway
+				controllers.StashValue(ctx, DeployerHTTPRouteStashKey, &DeployerHTTPRoute{
+					Hosts:   ingress.Hosts,
+					Path:    ingress.Path,
+					Gateway: gateway,
+				})
+				return nil
+			}
+
+			if host, deployer, err := deployerIngressConflict(ctx, c, parent, ingress, coreSettings); err != nil {
+				return err
+			} else if host != "" {
+				parent.Status.MarkIngressHostConflict(host, deployer)
+				return nil
+			}
+
+			if tls := resolveDeployerIngressTLS(parent, coreSettings); tls != nil {
+				controllers.StashValue(ctx, DeployerIngressTLSStashKey, tls)
+				ingress.Annotations = controllers.MergeMaps(ingress.Annotations, tls.Annotations)
+			}
+			controllers.StashValue(ctx, DeployerIngressStashKey, ingress)
+
+			return nil
+		},
+
+		Config: c,
+	}
+}
+
+// deployerIngress resolves the hosts, path and annotations requested by the
+// deployer
+func deployerIngress(parent *corev1alpha1.Deployer, coreSettings *corev1.ConfigMap) (*DeployerIngress, error) {
+	host, err := deployerHost(parent, coreSettings)
+	if err != nil {
+		return nil, err
+	}
+	ingress := &DeployerIngress{
+		Hosts:       []string{host},
+		Path:        "/",
+		Annotations: map[string]string{},
+	}
+	if spec := parent.Spec.Ingress; spec != nil {
+		for _, h := range spec.Hosts {
+			if h != host {
+				ingress.Hosts = append(ingress.Hosts, h)
+			}
+		}
+		if spec.Path != "" {
+			ingress.Path = spec.Path
+		}
+		ingress.Annotations = controllers.MergeMaps(ingress.Annotations, spec.Annotations)
+		if spec.ClassName != "" {
+			ingress.Annotations[ingressClassAnnotationKey] = spec.ClassName
+		}
+	}
+	return ingress, nil
+}
+
+// deployerHost renders the cluster's host template for the deployer
+func deployerHost(parent *corev1alpha1.Deployer, coreSettings *corev1.ConfigMap) (string, error) {
+	domain := defaultDomain
+	if d := coreSettings.Data[defaultDomainKey]; d != "" {
+		domain = d
+	}
+	hostTemplate := defaultHostTemplate
+	if t := coreSettings.Data[hostTemplateKey]; t != "" {
+		hostTemplate = t
+	}
+
+	tmpl, err := template.New(hostTemplateKey).Option("missingkey=error").Parse(hostTemplate)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s %q: %w", hostTemplateKey, hostTemplate, err)
+	}
+	var host bytes.Buffer
+	err = tmpl.Execute(&host, struct {
+		Name      string
+		Namespace string
+		Domain    string
+	}{
+		Name:      parent.Name,
+		Namespace: parent.Namespace,
+		Domain:    domain,
+	})
+	if err != nil {
+		return "", fmt.Errorf("invalid %s %q: %w", hostTemplateKey, hostTemplate, err)
+	}
+	if host.Len() == 0 {
+		return "", fmt.Errorf("invalid %s %q: empty host", hostTemplateKey, hostTemplate)
+	}
+	return host.String(), nil
+}
+
+// deployerIngressConflict returns the first host and the deployer already
+// routing it, if any. The oldest deployer in the namespace routing a host
+// and path owns them, regardless of the order deployers are reconciled.
+// Older deployers are tracked so the conflict is resolved again as they
+// change.
+func deployerIngressConflict(ctx context.Context, c controllers.Config, parent *corev1alpha1.Deployer, ingress *DeployerIngress, coreSettings *corev1.ConfigMap) (string, string, error) {
+	var deployers corev1alpha1.DeployerList
+	if err := c.List(ctx, &deployers, client.InNamespace(parent.Namespace)); err != nil {
+		return "", "", err
+	}
+	others := []corev1alpha1.Deployer{}
+	for _, other := range deployers.Items {
+		if other.Name != parent.Name && deployerOlder(&other, parent) {
+			others = append(others, other)
+		}
+	}
+	sort.Slice(others, func(i, j int) bool {
+		return deployerOlder(&others[i], &others[j])
+	})
+
+	for i := range others {
+		other := &others[i]
+		c.Tracker.Track(
+			tracker.NewKey(corev1alpha1.GroupVersion.WithKind("Deployer"), types.NamespacedName{Namespace: other.Namespace, Name: other.Name}),
+			types.NamespacedName{Namespace: parent.Namespace, Name: parent.Name},
+		)
+		if host := deployerIngressSharedHost(ingress, other, coreSettings); host != "" {
+			return host, other.Name, nil
+		}
+	}
+	return "", "", nil
+}
+
+// deployerIngressSharedHost returns the first host the other deployer
+// routes on the same path as the ingress, if any
+func deployerIngressSharedHost(ingress *DeployerIngress, other *corev1alpha1.Deployer, coreSettings *corev1.ConfigMap) string {
+	if other.Spec.IngressPolicy == corev1alpha1.IngressPolicyClusterLocal || other.DeletionTimestamp != nil {
+		return ""
+	}
+	routed, err := deployerIngress(other, coreSettings)
+	if err != nil {
+		// the other deployer reports the error
+		return ""
+	}
+	if !ingressPathsConflict(ingress.Path, routed.Path) {
+		return ""
+	}
+	for _, host := range ingress.Hosts {
+		for _, h := range routed.Hosts {
+			if host == h {
+				return host
+			}
+		}
+	}
+	return ""
+}
+
+// deployerOlder is true when a was created before b, deployers created at the
+// same time are ordered by name
+func deployerOlder(a, b *corev1alpha1.Deployer) bool {
+	if !a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+		return a.CreationTimestamp.Before(&b.CreationTimestamp)
+	}
+	return a.Name < b.Name
+}
+
+// ingressPathsConflict is true when both paths are the same, ignoring a
+// trailing slash. Nested paths do not conflict, the ingress controller routes
+// each request to the longest matching path.
+func ingressPathsConflict(a, b string) bool {
+	return ingressPath(a) == ingressPath(b)
+}
+
+func ingressPath(path string) string {
+	if trimmed := strings.TrimSuffix(path, "/"); trimmed != "" {
+		return trimmed
+	}
+	return "/"
+}
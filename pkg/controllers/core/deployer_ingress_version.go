@@ -0,0 +1,278 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1beta1 "k8s.io/api/networking/v1beta1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/discovery"
+
+	corev1alpha1 "github.com/projectriff/system/pkg/apis/core/v1alpha1"
+	networkingv1 "github.com/projectriff/system/pkg/apis/thirdparty/kubernetes/networking/v1"
+	"github.com/projectriff/system/pkg/controllers"
+)
+
+// DiscoverIngressVersion returns the newest Ingress API served by the
+// cluster. networking.k8s.io/v1 is preferred, falling back to
+// networking.k8s.io/v1beta1 for clusters that do not serve it.
+func DiscoverIngressVersion(d discovery.DiscoveryInterface) (schema.GroupVersion, error) {
+	groups, err := d.ServerGroups()
+	if err != nil {
+		return schema.GroupVersion{}, err
+	}
+	for _, group := range groups.Groups {
+		if group.Name != networkingv1.GroupVersion.Group {
+			continue
+		}
+		for _, version := range group.Versions {
+			if version.Version != networkingv1.GroupVersion.Version {
+				continue
+			}
+			resources, err := d.ServerResourcesForGroupVersion(version.GroupVersion)
+			if err != nil {
+				return schema.GroupVersion{}, err
+			}
+			for _, resource := range resources.APIResources {
+				if resource.Name == "ingresses" {
+					return networkingv1.GroupVersion, nil
+				}
+			}
+		}
+	}
+	return networkingv1beta1.SchemeGroupVersion, nil
+}
+
+// deployerIngressChildReconciler creates a ChildReconciler for Ingresses in
+// the requested API version. The desired child and the reflected status are
+// always expressed as networking.k8s.io/v1beta1 Ingresses, and converted to
+// and from networking.k8s.io/v1 as needed.
+func deployerIngressChildReconciler(
+	c controllers.Config,
+	version schema.GroupVersion,
+	indexField string,
+	desiredChild func(ctx context.Context, parent *corev1alpha1.Deployer) (*networkingv1beta1.Ingress, error),
+	reflectChildStatusOnParent func(parent *corev1alpha1.Deployer, child *networkingv1beta1.Ingress, err error),
+	ourChild func(parent *corev1alpha1.Deployer, child *networkingv1beta1.Ingress) bool,
+) controllers.SubReconciler {
+	if version != networkingv1.GroupVersion {
+		return &controllers.ChildReconciler{
+			ParentType:    &corev1alpha1.Deployer{},
+			ChildType:     &networkingv1beta1.Ingress{},
+			ChildListType: &networkingv1beta1.IngressList{},
+
+			DesiredChild:               desiredChild,
+			ReflectChildStatusOnParent: reflectChildStatusOnParent,
+			MergeBeforeUpdate: func(current, desired *networkingv1beta1.Ingress) {
+				current.Labels = desired.Labels
+				current.Annotations = desired.Annotations
+				current.Spec = desired.Spec
+			},
+			SemanticEquals: func(a1, a2 *networkingv1beta1.Ingress) bool {
+				return equality.Semantic.DeepEqual(a1.Spec, a2.Spec) &&
+					equality.Semantic.DeepEqual(a1.Labels, a2.Labels) &&
+					equality.Semantic.DeepEqual(a1.Annotations, a2.Annotations)
+			},
+
+			Config:     c,
+			IndexField: indexField,
+			Sanitize: func(child *networkingv1beta1.Ingress) interface{} {
+				return child.Spec
+			},
+			OurChild: ourChild,
+		}
+	}
+
+	return &controllers.ChildReconciler{
+		ParentType:    &corev1alpha1.Deployer{},
+		ChildType:     &networkingv1.Ingress{},
+		ChildListType: &networkingv1.IngressList{},
+
+		DesiredChild: func(ctx context.Context, parent *corev1alpha1.Deployer) (*networkingv1.Ingress, error) {
+			child, err := desiredChild(ctx, parent)
+			if err != nil || child == nil {
+				return nil, err
+			}
+			return convertIngressToV1(child), nil
+		},
+		ReflectChildStatusOnParent: func(parent *corev1alpha1.Deployer, child *networkingv1.Ingress, err error) {
+			if child == nil {
+				reflectChildStatusOnParent(parent, nil, err)
+				return
+			}
+			reflectChildStatusOnParent(parent, convertIngressToV1beta1(child), err)
+		},
+		MergeBeforeUpdate: func(current, desired *networkingv1.Ingress) {
+			current.Labels = desired.Labels
+			current.Annotations = desired.Annotations
+			current.Spec = desired.Spec
+		},
+		SemanticEquals: func(a1, a2 *networkingv1.Ingress) bool {
+			return equality.Semantic.DeepEqual(a1.Spec, a2.Spec) &&
+				equality.Semantic.DeepEqual(a1.Labels, a2.Labels) &&
+				equality.Semantic.DeepEqual(a1.Annotations, a2.Annotations)
+		},
+
+		Config:     c,
+		IndexField: indexField,
+		Sanitize: func(child *networkingv1.Ingress) interface{} {
+			return child.Spec
+		},
+		OurChild: func(parent *corev1alpha1.Deployer, child *networkingv1.Ingress) bool {
+			return ourChild(parent, convertIngressToV1beta1(child))
+		},
+	}
+}
+
+// getIngress fetches an Ingress in the requested API version, returning it
+// as a networking.k8s.io/v1beta1 Ingress
+func getIngress(ctx context.Context, c controllers.Config, version schema.GroupVersion, key types.NamespacedName) (*networkingv1beta1.Ingress, error) {
+	if version != networkingv1.GroupVersion {
+		ingress := &networkingv1beta1.Ingress{}
+		if err := c.Get(ctx, key, ingress); err != nil {
+			return nil, err
+		}
+		return ingress, nil
+	}
+	ingress := &networkingv1.Ingress{}
+	if err := c.Get(ctx, key, ingress); err != nil {
+		return nil, err
+	}
+	return convertIngressToV1beta1(ingress), nil
+}
+
+// convertIngressToV1 converts a networking.k8s.io/v1beta1 Ingress to
+// networking.k8s.io/v1. Paths are matched by prefix, and the ingress class
+// annotation is moved to the ingressClassName field.
+func convertIngressToV1(in *networkingv1beta1.Ingress) *networkingv1.Ingress {
+	out := &networkingv1.Ingress{
+		ObjectMeta: *in.ObjectMeta.DeepCopy(),
+	}
+	if className, ok := out.Annotations[ingressClassAnnotationKey]; ok {
+		delete(out.Annotations, ingressClassAnnotationKey)
+		out.Spec.IngressClassName = &className
+	}
+	if in.Spec.Backend != nil {
+		backend := convertIngressBackendToV1(*in.Spec.Backend)
+		out.Spec.DefaultBackend = &backend
+	}
+	for _, tls := range in.Spec.TLS {
+		out.Spec.TLS = append(out.Spec.TLS, networkingv1.IngressTLS{
+			Hosts:      append([]string(nil), tls.Hosts...),
+			SecretName: tls.SecretName,
+		})
+	}
+	for _, rule := range in.Spec.Rules {
+		r := networkingv1.IngressRule{Host: rule.Host}
+		if rule.HTTP != nil {
+			r.HTTP = &networkingv1.HTTPIngressRuleValue{}
+			for _, path := range rule.HTTP.Paths {
+				pathType := networkingv1.PathTypePrefix
+				r.HTTP.Paths = append(r.HTTP.Paths, networkingv1.HTTPIngressPath{
+					Path:     path.Path,
+					PathType: &pathType,
+					Backend:  convertIngressBackendToV1(path.Backend),
+				})
+			}
+		}
+		out.Spec.Rules = append(out.Spec.Rules, r)
+	}
+	for _, lb := range in.Status.LoadBalancer.Ingress {
+		out.Status.LoadBalancer.Ingress = append(out.Status.LoadBalancer.Ingress, networkingv1.IngressLoadBalancerIngress{
+			IP:       lb.IP,
+			Hostname: lb.Hostname,
+		})
+	}
+	return out
+}
+
+func convertIngressBackendToV1(in networkingv1beta1.IngressBackend) networkingv1.IngressBackend {
+	out := networkingv1.IngressBackend{
+		Service: &networkingv1.IngressServiceBackend{
+			Name: in.ServiceName,
+		},
+	}
+	if in.ServicePort.Type == intstr.String {
+		out.Service.Port.Name = in.ServicePort.StrVal
+	} else {
+		out.Service.Port.Number = in.ServicePort.IntVal
+	}
+	return out
+}
+
+// convertIngressToV1beta1 converts a networking.k8s.io/v1 Ingress to
+// networking.k8s.io/v1beta1. Path types and resource backends are not
+// represented in networking.k8s.io/v1beta1 and are dropped.
+func convertIngressToV1beta1(in *networkingv1.Ingress) *networkingv1beta1.Ingress {
+	out := &networkingv1beta1.Ingress{
+		ObjectMeta: *in.ObjectMeta.DeepCopy(),
+	}
+	if in.Spec.IngressClassName != nil {
+		out.Annotations = controllers.MergeMaps(out.Annotations, map[string]string{
+			ingressClassAnnotationKey: *in.Spec.IngressClassName,
+		})
+	}
+	if in.Spec.DefaultBackend != nil {
+		backend := convertIngressBackendToV1beta1(*in.Spec.DefaultBackend)
+		out.Spec.Backend = &backend
+	}
+	for _, tls := range in.Spec.TLS {
+		out.Spec.TLS = append(out.Spec.TLS, networkingv1beta1.IngressTLS{
+			Hosts:      append([]string(nil), tls.Hosts...),
+			SecretName: tls.SecretName,
+		})
+	}
+	for _, rule := range in.Spec.Rules {
+		r := networkingv1beta1.IngressRule{Host: rule.Host}
+		if rule.HTTP != nil {
+			r.HTTP = &networkingv1beta1.HTTPIngressRuleValue{}
+			for _, path := range rule.HTTP.Paths {
+				r.HTTP.Paths = append(r.HTTP.Paths, networkingv1beta1.HTTPIngressPath{
+					Path:    path.Path,
+					Backend: convertIngressBackendToV1beta1(path.Backend),
+				})
+			}
+		}
+		out.Spec.Rules = append(out.Spec.Rules, r)
+	}
+	for _, lb := range in.Status.LoadBalancer.Ingress {
+		out.Status.LoadBalancer.Ingress = append(out.Status.LoadBalancer.Ingress, corev1.LoadBalancerIngress{
+			IP:       lb.IP,
+			Hostname: lb.Hostname,
+		})
+	}
+	return out
+}
+
+func convertIngressBackendToV1beta1(in networkingv1.IngressBackend) networkingv1beta1.IngressBackend {
+	out := networkingv1beta1.IngressBackend{}
+	if in.Service == nil {
+		return out
+	}
+	out.ServiceName = in.Service.Name
+	if in.Service.Port.Name != "" {
+		out.ServicePort = intstr.FromString(in.Service.Port.Name)
+	} else {
+		out.ServicePort = intstr.FromInt(int(in.Service.Port.Number))
+	}
+	return out
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core_test
+
+import (
+	"testing"
+
+	networkingv1beta1 "k8s.io/api/networking/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	clientgotesting "k8s.io/client-go/testing"
+
+	networkingv1 "github.com/projectriff/system/pkg/apis/thirdparty/kubernetes/networking/v1"
+	corecontrollers "github.com/projectriff/system/pkg/controllers/core"
+)
+
+func TestDiscoverIngressVersion(t *testing.T) {
+	tests := []struct {
+		name      string
+		resources []*metav1.APIResourceList
+		expected  schema.GroupVersion
+	}{
+		{
+			name: "networking.k8s.io/v1",
+			resources: []*metav1.APIResourceList{
+				{
+					GroupVersion: "networking.k8s.io/v1",
+					APIResources: []metav1.APIResource{
+						{Name: "networkpolicies", Kind: "NetworkPolicy"},
+						{Name: "ingresses", Kind: "Ingress"},
+					},
+				},
+				{
+					GroupVersion: "networking.k8s.io/v1beta1",
+					APIResources: []metav1.APIResource{
+						{Name: "ingresses", Kind: "Ingress"},
+					},
+				},
+			},
+			expected: networkingv1.GroupVersion,
+		},
+		{
+			name: "networking.k8s.io/v1 without ingresses",
+			resources: []*metav1.APIResourceList{
+				{
+					GroupVersion: "networking.k8s.io/v1",
+					APIResources: []metav1.APIResource{
+						{Name: "networkpolicies", Kind: "NetworkPolicy"},
+					},
+				},
+				{
+					GroupVersion: "networking.k8s.io/v1beta1",
+					APIResources: []metav1.APIResource{
+						{Name: "ingresses", Kind: "Ingress"},
+					},
+				},
+			},
+			expected: networkingv1beta1.SchemeGroupVersion,
+		},
+		{
+			name: "networking.k8s.io/v1beta1",
+			resources: []*metav1.APIResourceList{
+				{
+					GroupVersion: "networking.k8s.io/v1beta1",
+					APIResources: []metav1.APIResource{
+						{Name: "ingresses", Kind: "Ingress"},
+					},
+				},
+			},
+			expected: networkingv1beta1.SchemeGroupVersion,
+		},
+	}
+	for _, c := range tests {
+		t.Run(c.name, func(t *testing.T) {
+			discovery := &fakediscovery.FakeDiscovery{
+				Fake: &clientgotesting.Fake{Resources: c.resources},
+			}
+			actual, err := corecontrollers.DiscoverIngressVersion(discovery)
+			if err != nil {
+				t.Errorf("DiscoverIngressVersion() error = %v", err)
+				return
+			}
+			if actual != c.expected {
+				t.Errorf("DiscoverIngressVersion() = %v, expected %v", actual, c.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	corev1alpha1 "github.com/projectriff/system/pkg/apis/core/v1alpha1"
+)
+
+const DeployerIngressValidatorPath = "/validate-core-projectriff-io-v1alpha1-deployer-ingress"
+
+// +kubebuilder:webhook:path=/validate-core-projectriff-io-v1alpha1-deployer-ingress,mutating=false,failurePolicy=fail,groups=core.projectriff.io,resources=deployers,verbs=create;update,versions=v1alpha1,name=deployer-ingress.core.projectriff.io
+
+// DeployerIngressValidator rejects a deployer routing the same path on a
+// host as another deployer in the namespace. Deployers admitted concurrently
+// may still collide, the DeployerIngressReconciler reports those conflicts.
+type DeployerIngressValidator struct {
+	Client client.Client
+	// Namespace holding the cluster's settings
+	Namespace string
+
+	decoder *admission.Decoder
+}
+
+var _ admission.Handler = &DeployerIngressValidator{}
+var _ admission.DecoderInjector = &DeployerIngressValidator{}
+
+func (v *DeployerIngressValidator) InjectDecoder(d *admission.Decoder) error {
+	v.decoder = d
+	return nil
+}
+
+func (v *DeployerIngressValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	parent := &corev1alpha1.Deployer{}
+	if err := v.decoder.Decode(req, parent); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	// the namespace is defaulted from the request
+	parent.Namespace = req.Namespace
+	if parent.Spec.IngressPolicy != corev1alpha1.IngressPolicyExternal || parent.DeletionTimestamp != nil {
+		return admission.Allowed("")
+	}
+
+	coreSettings := &corev1.ConfigMap{}
+	coreSettingsKey := types.NamespacedName{Namespace: v.Namespace, Name: settingsConfigMapName}
+	if err := v.Client.Get(ctx, coreSettingsKey, coreSettings); err != nil {
+		if apierrs.IsNotFound(err) {
+			// the reconciler reports the missing settings
+			return admission.Allowed("")
+		}
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	if coreSettings.Data[routingBackendKey] == routingBackendHTTPRoute {
+		// conflicting routes are resolved by the gateway
+		return admission.Allowed("")
+	}
+	ingress, err := deployerIngress(parent, coreSettings)
+	if err != nil {
+		// the reconciler reports the error
+		return admission.Allowed("")
+	}
+
+	var previous *DeployerIngress
+	if req.Operation == admissionv1beta1.Update {
+		old := &corev1alpha1.Deployer{}
+		if err := v.decoder.DecodeRaw(req.OldObject, old); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		if old.Spec.IngressPolicy == corev1alpha1.IngressPolicyExternal {
+			previous, _ = deployerIngress(old, coreSettings)
+		}
+	}
+
+	var deployers corev1alpha1.DeployerList
+	if err := v.Client.List(ctx, &deployers, client.InNamespace(req.Namespace)); err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	for i := range deployers.Items {
+		other := &deployers.Items[i]
+		if other.Name == parent.Name {
+			continue
+		}
+		host := deployerIngressSharedHost(ingress, other, coreSettings)
+		if host == "" {
+			continue
+		}
+		if previous != nil && deployerIngressSharedHost(previous, other, coreSettings) == host {
+			// the collision predates this update and is reported on the
+			// deployer's status, don't block unrelated changes
+			continue
+		}
+		return admission.Denied(fmt.Sprintf("host %q and path %q are already routed to Deployer %q", host, ingress.Path, other.Name))
+	}
+	return admission.Allowed("")
+}
@@ -0,0 +1,291 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	corev1alpha1 "github.com/projectriff/system/pkg/apis/core/v1alpha1"
+	corecontrollers "github.com/projectriff/system/pkg/controllers/core"
+	rtesting "github.com/projectriff/system/pkg/controllers/testing"
+	"github.com/projectriff/system/pkg/controllers/testing/factories"
+)
+
+func TestDeployerIngressValidator(t *testing.T) {
+	testNamespace := "test-namespace"
+	testSystemNamespace := "riff-system"
+	testName := "test-deployer"
+	testImage := "registry.example.com/repo@sha256:cf8b4c69d5460f88530e1c80b8856a70801f31c50b191c8413043ba9b160a43e"
+
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = corev1alpha1.AddToScheme(scheme)
+
+	deployer := factories.DeployerCore().
+		NamespaceName(testNamespace, testName).
+		Image(testImage).
+		IngressPolicy(corev1alpha1.IngressPolicyExternal)
+	otherDeployer := factories.DeployerCore().
+		NamespaceName(testNamespace, "other-deployer").
+		Image(testImage).
+		IngressPolicy(corev1alpha1.IngressPolicyExternal)
+
+	testSettings := factories.ConfigMap().
+		NamespaceName(testSystemNamespace, "riff-core-settings").
+		AddData("defaultDomain", "example.com")
+
+	tests := []struct {
+		name         string
+		operation    admissionv1beta1.Operation
+		given        rtesting.Factory
+		old          rtesting.Factory
+		givenObjects []rtesting.Factory
+		allowed      bool
+		reason       string
+	}{{
+		name:      "no other deployers",
+		operation: admissionv1beta1.Create,
+		given: deployer.
+			Ingress(&corev1alpha1.Ingress{
+				Hosts: []string{"www.example.com"},
+			}),
+		givenObjects: []rtesting.Factory{
+			testSettings,
+		},
+		allowed: true,
+	}, {
+		name:      "same host and path",
+		operation: admissionv1beta1.Create,
+		given: deployer.
+			Ingress(&corev1alpha1.Ingress{
+				Hosts: []string{"www.example.com"},
+				Path:  "/api",
+			}),
+		givenObjects: []rtesting.Factory{
+			testSettings,
+			otherDeployer.
+				Ingress(&corev1alpha1.Ingress{
+					Hosts: []string{"www.example.com"},
+					Path:  "/api/",
+				}),
+		},
+		allowed: false,
+		reason:  `host "www.example.com" and path "/api" are already routed to Deployer "other-deployer"`,
+	}, {
+		name:      "same host, nested path",
+		operation: admissionv1beta1.Create,
+		given: deployer.
+			Ingress(&corev1alpha1.Ingress{
+				Hosts: []string{"www.example.com"},
+				Path:  "/api",
+			}),
+		givenObjects: []rtesting.Factory{
+			testSettings,
+			otherDeployer.
+				Ingress(&corev1alpha1.Ingress{
+					Hosts: []string{"www.example.com"},
+				}),
+		},
+		allowed: true,
+	}, {
+		name:      "same path, different host",
+		operation: admissionv1beta1.Create,
+		given: deployer.
+			Ingress(&corev1alpha1.Ingress{
+				Hosts: []string{"www.example.com"},
+			}),
+		givenObjects: []rtesting.Factory{
+			testSettings,
+			otherDeployer.
+				Ingress(&corev1alpha1.Ingress{
+					Hosts: []string{"api.example.com"},
+				}),
+		},
+		allowed: true,
+	}, {
+		name:      "other deployer is cluster local",
+		operation: admissionv1beta1.Create,
+		given: deployer.
+			Ingress(&corev1alpha1.Ingress{
+				Hosts: []string{"www.example.com"},
+			}),
+		givenObjects: []rtesting.Factory{
+			testSettings,
+			otherDeployer.
+				IngressPolicy(corev1alpha1.IngressPolicyClusterLocal).
+				Ingress(&corev1alpha1.Ingress{
+					Hosts: []string{"www.example.com"},
+				}),
+		},
+		allowed: true,
+	}, {
+		name:      "cluster local deployer",
+		operation: admissionv1beta1.Create,
+		given: deployer.
+			IngressPolicy(corev1alpha1.IngressPolicyClusterLocal).
+			Ingress(&corev1alpha1.Ingress{
+				Hosts: []string{"www.example.com"},
+			}),
+		givenObjects: []rtesting.Factory{
+			testSettings,
+			otherDeployer.
+				Ingress(&corev1alpha1.Ingress{
+					Hosts: []string{"www.example.com"},
+				}),
+		},
+		allowed: true,
+	}, {
+		name:      "routes resolved by the gateway",
+		operation: admissionv1beta1.Create,
+		given: deployer.
+			Ingress(&corev1alpha1.Ingress{
+				Hosts: []string{"www.example.com"},
+			}),
+		givenObjects: []rtesting.Factory{
+			testSettings.
+				AddData("routingBackend", "HTTPRoute"),
+			otherDeployer.
+				Ingress(&corev1alpha1.Ingress{
+					Hosts: []string{"www.example.com"},
+				}),
+		},
+		allowed: true,
+	}, {
+		name:      "settings not found",
+		operation: admissionv1beta1.Create,
+		given: deployer.
+			Ingress(&corev1alpha1.Ingress{
+				Hosts: []string{"www.example.com"},
+			}),
+		givenObjects: []rtesting.Factory{
+			otherDeployer.
+				Ingress(&corev1alpha1.Ingress{
+					Hosts: []string{"www.example.com"},
+				}),
+		},
+		allowed: true,
+	}, {
+		name:      "update to a routed host",
+		operation: admissionv1beta1.Update,
+		given: deployer.
+			Ingress(&corev1alpha1.Ingress{
+				Hosts: []string{"www.example.com"},
+			}),
+		old: deployer.
+			Ingress(&corev1alpha1.Ingress{
+				Hosts: []string{"api.example.com"},
+			}),
+		givenObjects: []rtesting.Factory{
+			testSettings,
+			deployer.
+				Ingress(&corev1alpha1.Ingress{
+					Hosts: []string{"api.example.com"},
+				}),
+			otherDeployer.
+				Ingress(&corev1alpha1.Ingress{
+					Hosts: []string{"www.example.com"},
+				}),
+		},
+		allowed: false,
+		reason:  `host "www.example.com" and path "/" are already routed to Deployer "other-deployer"`,
+	}, {
+		name:      "update with an existing collision",
+		operation: admissionv1beta1.Update,
+		given: deployer.
+			Image("registry.example.com/repo:next").
+			Ingress(&corev1alpha1.Ingress{
+				Hosts: []string{"www.example.com"},
+			}),
+		old: deployer.
+			Ingress(&corev1alpha1.Ingress{
+				Hosts: []string{"www.example.com"},
+			}),
+		givenObjects: []rtesting.Factory{
+			testSettings,
+			deployer.
+				Ingress(&corev1alpha1.Ingress{
+					Hosts: []string{"www.example.com"},
+				}),
+			otherDeployer.
+				Ingress(&corev1alpha1.Ingress{
+					Hosts: []string{"www.example.com"},
+				}),
+		},
+		allowed: true,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			givenObjects := make([]runtime.Object, len(test.givenObjects))
+			for i := range test.givenObjects {
+				givenObjects[i] = test.givenObjects[i].CreateObject()
+			}
+			decoder, err := admission.NewDecoder(scheme)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			validator := &corecontrollers.DeployerIngressValidator{
+				Client:    fake.NewFakeClientWithScheme(scheme, givenObjects...),
+				Namespace: testSystemNamespace,
+			}
+			if err := validator.InjectDecoder(decoder); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			req := admission.Request{
+				AdmissionRequest: admissionv1beta1.AdmissionRequest{
+					Operation: test.operation,
+					Namespace: testNamespace,
+					Name:      testName,
+					Object:    rawDeployer(t, test.given),
+				},
+			}
+			if test.old != nil {
+				req.OldObject = rawDeployer(t, test.old)
+			}
+			resp := validator.Handle(context.TODO(), req)
+
+			if expected, actual := test.allowed, resp.Allowed; expected != actual {
+				t.Errorf("expected allowed %v, actually %v: %v", expected, actual, resp.Result)
+			}
+			if test.reason != "" {
+				if resp.Result == nil {
+					t.Fatalf("expected reason %q, actually no result", test.reason)
+				}
+				if expected, actual := test.reason, string(resp.Result.Reason); expected != actual {
+					t.Errorf("expected reason %q, actually %q", expected, actual)
+				}
+			}
+		})
+	}
+}
+
+func rawDeployer(t *testing.T, f rtesting.Factory) runtime.RawExtension {
+	raw, err := json.Marshal(f.CreateObject())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return runtime.RawExtension{Raw: raw}
+}
@@ -0,0 +1,485 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1beta1 "k8s.io/api/networking/v1beta1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	corev1alpha1 "github.com/projectriff/system/pkg/apis/core/v1alpha1"
+	"github.com/projectriff/system/pkg/controllers"
+	"github.com/projectriff/system/pkg/refs"
+)
+
+const (
+	// nginx style canary annotations, also supported by other ingress
+	// controllers
+	ingressCanaryAnnotationKey       = "nginx.ingress.kubernetes.io/canary"
+	ingressCanaryWeightAnnotationKey = "nginx.ingress.kubernetes.io/canary-weight"
+)
+
+// DeployerRolloutReconciler steps new images through the rollout defined on
+// the deployer. The first image, and every image for deployers without a
+// rollout, is used directly by the stable Deployment.
+func DeployerRolloutReconciler(c controllers.Config, now func() time.Time) controllers.SubReconciler {
+	c.Log = c.Log.WithName("Rollout")
+
+	return &controllers.SyncReconciler{
+		Sync: func(ctx context.Context, parent *corev1alpha1.Deployer) (ctrl.Result, error) {
+			rollout := parent.Spec.Rollout
+			if rollout == nil {
+				parent.Status.Rollout = nil
+				return ctrl.Result{}, nil
+			}
+			if parent.Status.LatestImage == "" {
+				// no image, skip
+				return ctrl.Result{}, nil
+			}
+
+			status := parent.Status.Rollout
+			if status == nil || status.StableImage == "" {
+				// the first image is not rolled out
+				parent.Status.Rollout = &corev1alpha1.RolloutStatus{
+					StableImage: parent.Status.LatestImage,
+				}
+				return ctrl.Result{}, nil
+			}
+
+			switch {
+			case status.StableImage == parent.Status.LatestImage:
+				if status.Phase == corev1alpha1.RolloutPhaseProgressing {
+					resetRolloutStep(status)
+					status.Phase = corev1alpha1.RolloutPhaseAborted
+					status.Message = fmt.Sprintf("Rollout of image %q was superseded by the stable image", status.CanaryImage)
+					status.CanaryImage = ""
+					c.Recorder.Event(parent, corev1.EventTypeNormal, "RolloutAborted", status.Message)
+				}
+				if status.Phase == corev1alpha1.RolloutPhasePromoted && status.CanaryImage != "" {
+					// the canary keeps serving until the stable Deployment
+					// runs the promoted image
+					updated, err := stableDeploymentUpdated(ctx, c, parent, status.StableImage)
+					if err != nil {
+						return ctrl.Result{}, err
+					}
+					if updated {
+						resetRolloutStep(status)
+						status.CanaryImage = ""
+						status.Message = fmt.Sprintf("Promoted image %q", status.StableImage)
+					}
+				}
+				return ctrl.Result{}, nil
+
+			case status.CanaryImage != parent.Status.LatestImage:
+				resetRolloutStep(status)
+				status.Phase = corev1alpha1.RolloutPhaseProgressing
+				status.CanaryImage = parent.Status.LatestImage
+				status.Weight = rollout.Steps[0].Weight
+				status.StepStartTime = &metav1.Time{Time: now()}
+				status.Message = rolloutStepMessage(rollout, status)
+				c.Recorder.Eventf(parent, corev1.EventTypeNormal, "RolloutStarted",
+					"Started rollout of image %q", status.CanaryImage)
+				return ctrl.Result{}, nil
+
+			case status.Phase != corev1alpha1.RolloutPhaseProgressing:
+				// the canary image was aborted
+				return ctrl.Result{}, nil
+			}
+
+			// the steps may have changed since the rollout started
+			if int(status.Step) >= len(rollout.Steps) {
+				status.Step = int32(len(rollout.Steps) - 1)
+			}
+			step := rollout.Steps[status.Step]
+			status.Weight = step.Weight
+
+			if status.CanaryDeploymentRef == nil {
+				// no canary, yet
+				return ctrl.Result{}, nil
+			}
+			var canary appsv1.Deployment
+			key := types.NamespacedName{Namespace: parent.Namespace, Name: status.CanaryDeploymentRef.Name}
+			if err := c.Get(ctx, key, &canary); err != nil {
+				if apierrs.IsNotFound(err) {
+					return ctrl.Result{}, nil
+				}
+				return ctrl.Result{}, err
+			}
+
+			if failed, message := deploymentFailed(&canary); failed {
+				resetRolloutStep(status)
+				status.Phase = corev1alpha1.RolloutPhaseAborted
+				status.Message = fmt.Sprintf("Rollout of image %q aborted: %s", status.CanaryImage, message)
+				c.Recorder.Event(parent, corev1.EventTypeWarning, "RolloutAborted", status.Message)
+				return ctrl.Result{}, nil
+			}
+			if !deploymentReady(&canary) {
+				status.Message = fmt.Sprintf("%s, waiting for canary to be ready", rolloutStepMessage(rollout, status))
+				return ctrl.Result{}, nil
+			}
+			status.Message = rolloutStepMessage(rollout, status)
+
+			if step.Pause != nil && status.StepStartTime != nil {
+				if remaining := status.StepStartTime.Add(step.Pause.Duration).Sub(now()); remaining > 0 {
+					return ctrl.Result{RequeueAfter: remaining}, nil
+				}
+			}
+
+			if int(status.Step)+1 < len(rollout.Steps) {
+				status.Step++
+				status.Weight = rollout.Steps[status.Step].Weight
+				status.StepStartTime = &metav1.Time{Time: now()}
+				status.Message = rolloutStepMessage(rollout, status)
+				// the next step may not change the canary, check back
+				return ctrl.Result{Requeue: true}, nil
+			}
+
+			// the canary is removed once the stable Deployment is updated
+			status.Phase = corev1alpha1.RolloutPhasePromoted
+			status.StableImage = status.CanaryImage
+			status.StepStartTime = nil
+			status.Message = fmt.Sprintf("Promoted image %q, waiting for stable deployment to be ready", status.StableImage)
+			c.Recorder.Eventf(parent, corev1.EventTypeNormal, "RolloutPromoted", "Promoted image %q", status.StableImage)
+			return ctrl.Result{}, nil
+		},
+
+		Config: c,
+	}
+}
+
+func resetRolloutStep(status *corev1alpha1.RolloutStatus) {
+	status.Step = 0
+	status.Weight = 0
+	status.StepStartTime = nil
+}
+
+func rolloutStepMessage(rollout *corev1alpha1.Rollout, status *corev1alpha1.RolloutStatus) string {
+	return fmt.Sprintf("Step %d of %d, %d%% of traffic to canary", status.Step+1, len(rollout.Steps), status.Weight)
+}
+
+// deploymentReady is true when every replica of the most recent spec is
+// available
+func deploymentReady(deployment *appsv1.Deployment) bool {
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+	return deployment.Status.ObservedGeneration >= deployment.Generation &&
+		deployment.Status.UpdatedReplicas >= replicas &&
+		deployment.Status.AvailableReplicas >= replicas
+}
+
+// stableDeploymentUpdated is true when every replica of the stable
+// Deployment runs the image and is available
+func stableDeploymentUpdated(ctx context.Context, c controllers.Config, parent *corev1alpha1.Deployer, image string) (bool, error) {
+	if parent.Status.DeploymentRef == nil {
+		return false, nil
+	}
+	var stable appsv1.Deployment
+	key := types.NamespacedName{Namespace: parent.Namespace, Name: parent.Status.DeploymentRef.Name}
+	if err := c.Get(ctx, key, &stable); err != nil {
+		if apierrs.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	if containers := stable.Spec.Template.Spec.Containers; len(containers) == 0 || containers[0].Image != image {
+		return false, nil
+	}
+	return deploymentReady(&stable) && stable.Status.UpdatedReplicas == stable.Status.Replicas, nil
+}
+
+// deploymentFailed is true when the deployment exceeded its progress deadline
+func deploymentFailed(deployment *appsv1.Deployment) (bool, string) {
+	for _, cond := range deployment.Status.Conditions {
+		if cond.Type == appsv1.DeploymentProgressing && cond.Status == corev1.ConditionFalse && cond.Reason == "ProgressDeadlineExceeded" {
+			return true, cond.Message
+		}
+	}
+	return false, ""
+}
+
+// rolloutInProgress returns the rollout status when a canary is desired. A
+// promoted canary is kept until the stable Deployment runs its image.
+func rolloutInProgress(parent *corev1alpha1.Deployer) *corev1alpha1.RolloutStatus {
+	if parent.Spec.Rollout == nil {
+		return nil
+	}
+	status := parent.Status.Rollout
+	if status == nil || status.CanaryImage == "" {
+		return nil
+	}
+	if status.Phase != corev1alpha1.RolloutPhaseProgressing && status.Phase != corev1alpha1.RolloutPhasePromoted {
+		return nil
+	}
+	return status
+}
+
+func deployerCanaryServiceName(parent *corev1alpha1.Deployer) string {
+	return fmt.Sprintf("%s-canary", parent.Name)
+}
+
+// canaryReplicas approximates the weight by the ratio of canary replicas to
+// all replicas behind the Service
+func canaryReplicas(stable, weight int32) int32 {
+	if weight >= 100 {
+		return stable
+	}
+	replicas := (stable*weight + (100 - weight) - 1) / (100 - weight)
+	if replicas < 1 {
+		return 1
+	}
+	return replicas
+}
+
+func DeployerChildCanaryDeploymentReconciler(c controllers.Config) controllers.SubReconciler {
+	c.Log = c.Log.WithName("ChildCanaryDeployment")
+
+	return &controllers.ChildReconciler{
+		ParentType:    &corev1alpha1.Deployer{},
+		ChildType:     &appsv1.Deployment{},
+		ChildListType: &appsv1.DeploymentList{},
+
+		DesiredChild: func(ctx context.Context, parent *corev1alpha1.Deployer) (*appsv1.Deployment, error) {
+			rollout := rolloutInProgress(parent)
+			if rollout == nil {
+				// no rollout, skip
+				return nil, nil
+			}
+
+			stableReplicas := int32(1)
+			if parent.Status.DeploymentRef != nil {
+				var stable appsv1.Deployment
+				key := types.NamespacedName{Namespace: parent.Namespace, Name: parent.Status.DeploymentRef.Name}
+				if err := c.Get(ctx, key, &stable); err != nil {
+					if !apierrs.IsNotFound(err) {
+						return nil, err
+					}
+				} else if stable.Spec.Replicas != nil {
+					stableReplicas = *stable.Spec.Replicas
+				}
+			}
+
+			bindings, _ := controllers.RetrieveValue(ctx, DeployerBindingsStashKey).([]DeployerResolvedBinding)
+			child := deployerDeployment(parent, rollout.CanaryImage, bindings, corev1alpha1.DeployerTrackCanary)
+			child.GenerateName = fmt.Sprintf("%s-deployer-canary-", parent.Name)
+			child.Labels = controllers.MergeMaps(child.Labels, map[string]string{
+				corev1alpha1.DeployerCanaryLabelKey: parent.Name,
+			})
+			child.Spec.Selector = &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					corev1alpha1.DeployerCanaryLabelKey: parent.Name,
+				},
+			}
+			child.Spec.Template.Labels = controllers.MergeMaps(child.Spec.Template.Labels, map[string]string{
+				corev1alpha1.DeployerCanaryLabelKey: parent.Name,
+			})
+			replicas := stableReplicas
+			if parent.Spec.Rollout.TrafficRouting != corev1alpha1.RolloutTrafficRoutingIngress {
+				// the stable Service splits traffic by replicas
+				replicas = canaryReplicas(stableReplicas, rollout.Weight)
+			}
+			child.Spec.Replicas = &replicas
+
+			return child, nil
+		},
+		ReflectChildStatusOnParent: func(parent *corev1alpha1.Deployer, child *appsv1.Deployment, err error) {
+			if err != nil || parent.Status.Rollout == nil {
+				return
+			}
+			if child == nil {
+				parent.Status.Rollout.CanaryDeploymentRef = nil
+			} else {
+				parent.Status.Rollout.CanaryDeploymentRef = refs.NewTypedLocalObjectReferenceForObject(child, c.Scheme)
+			}
+		},
+		MergeBeforeUpdate: func(current, desired *appsv1.Deployment) {
+			current.Labels = desired.Labels
+			current.Spec = desired.Spec
+		},
+		SemanticEquals: func(a1, a2 *appsv1.Deployment) bool {
+			return equality.Semantic.DeepEqual(a1.Spec, a2.Spec) &&
+				equality.Semantic.DeepEqual(a1.Labels, a2.Labels)
+		},
+
+		Config:     c,
+		IndexField: ".metadata.canaryDeploymentController",
+		Sanitize: func(child *appsv1.Deployment) interface{} {
+			return child.Spec
+		},
+		OurChild: func(parent *corev1alpha1.Deployer, child *appsv1.Deployment) bool {
+			return child.Labels[corev1alpha1.DeployerCanaryLabelKey] != ""
+		},
+	}
+}
+
+func DeployerChildCanaryServiceReconciler(c controllers.Config) controllers.SubReconciler {
+	c.Log = c.Log.WithName("ChildCanaryService")
+
+	return &controllers.ChildReconciler{
+		ParentType:    &corev1alpha1.Deployer{},
+		ChildType:     &corev1.Service{},
+		ChildListType: &corev1.ServiceList{},
+
+		DesiredChild: func(parent *corev1alpha1.Deployer) (*corev1.Service, error) {
+			rollout := rolloutInProgress(parent)
+			if rollout == nil || rollout.CanaryDeploymentRef == nil || parent.Spec.Rollout.TrafficRouting != corev1alpha1.RolloutTrafficRoutingIngress {
+				// no canary routed by ingress, skip
+				return nil, nil
+			}
+
+			targetPort := parent.Spec.Template.Spec.Containers[0].Ports[0]
+
+			child := &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: controllers.MergeMaps(parent.Labels, map[string]string{
+						corev1alpha1.DeployerLabelKey:       parent.Name,
+						corev1alpha1.DeployerCanaryLabelKey: parent.Name,
+					}),
+					Annotations: make(map[string]string),
+					Namespace:   parent.Namespace,
+					Name:        deployerCanaryServiceName(parent),
+				},
+				Spec: corev1.ServiceSpec{
+					Ports: []corev1.ServicePort{
+						{Name: targetPort.Name, Port: 80, TargetPort: intstr.FromInt(int(targetPort.ContainerPort))},
+					},
+					Selector: map[string]string{
+						corev1alpha1.DeployerCanaryLabelKey: parent.Name,
+					},
+				},
+			}
+
+			return child, nil
+		},
+		ReflectChildStatusOnParent: func(parent *corev1alpha1.Deployer, child *corev1.Service, err error) {
+			if err != nil || parent.Status.Rollout == nil {
+				return
+			}
+			if child == nil {
+				parent.Status.Rollout.CanaryServiceRef = nil
+			} else {
+				parent.Status.Rollout.CanaryServiceRef = refs.NewTypedLocalObjectReferenceForObject(child, c.Scheme)
+			}
+		},
+		HarmonizeImmutableFields: func(current, desired *corev1.Service) {
+			desired.Spec.ClusterIP = current.Spec.ClusterIP
+		},
+		MergeBeforeUpdate: func(current, desired *corev1.Service) {
+			current.Labels = desired.Labels
+			current.Spec = desired.Spec
+		},
+		SemanticEquals: func(a1, a2 *corev1.Service) bool {
+			return equality.Semantic.DeepEqual(a1.Spec, a2.Spec) &&
+				equality.Semantic.DeepEqual(a1.Labels, a2.Labels)
+		},
+
+		Config:     c,
+		IndexField: ".metadata.canaryServiceController",
+		Sanitize: func(child *corev1.Service) interface{} {
+			return child.Spec
+		},
+		OurChild: func(parent *corev1alpha1.Deployer, child *corev1.Service) bool {
+			return child.Labels[corev1alpha1.DeployerCanaryLabelKey] != ""
+		},
+	}
+}
+
+func DeployerChildCanaryIngressReconciler(c controllers.Config, ingressVersion schema.GroupVersion) controllers.SubReconciler {
+	c.Log = c.Log.WithName("ChildCanaryIngress")
+
+	return deployerIngressChildReconciler(c, ingressVersion, ".metadata.canaryIngressController",
+		func(ctx context.Context, parent *corev1alpha1.Deployer) (*networkingv1beta1.Ingress, error) {
+			rollout := rolloutInProgress(parent)
+			if rollout == nil || rollout.CanaryServiceRef == nil || parent.Status.IngressRef == nil {
+				// no canary service or stable ingress, skip
+				return nil, nil
+			}
+
+			key := types.NamespacedName{Namespace: parent.Namespace, Name: parent.Status.IngressRef.Name}
+			stable, err := getIngress(ctx, c, ingressVersion, key)
+			if err != nil {
+				if apierrs.IsNotFound(err) {
+					return nil, nil
+				}
+				return nil, err
+			}
+
+			// route the same hosts and paths as the stable ingress to the canary
+			spec := *stable.Spec.DeepCopy()
+			for i := range spec.Rules {
+				if spec.Rules[i].HTTP == nil {
+					continue
+				}
+				for j := range spec.Rules[i].HTTP.Paths {
+					spec.Rules[i].HTTP.Paths[j].Backend.ServiceName = rollout.CanaryServiceRef.Name
+				}
+			}
+			if spec.Backend != nil {
+				spec.Backend.ServiceName = rollout.CanaryServiceRef.Name
+			}
+
+			child := &networkingv1beta1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: controllers.MergeMaps(parent.Labels, map[string]string{
+						corev1alpha1.DeployerLabelKey:       parent.Name,
+						corev1alpha1.DeployerCanaryLabelKey: parent.Name,
+					}),
+					Annotations: controllers.MergeMaps(stable.Annotations, map[string]string{
+						ingressCanaryAnnotationKey:       "true",
+						ingressCanaryWeightAnnotationKey: fmt.Sprintf("%d", rollout.Weight),
+					}),
+					GenerateName: fmt.Sprintf("%s-deployer-canary-", parent.Name),
+					Namespace:    parent.Namespace,
+				},
+				Spec: spec,
+			}
+			if tls, ok := controllers.RetrieveValue(ctx, DeployerIngressTLSStashKey).(*DeployerIngressTLS); ok {
+				// the stable ingress requests the certificate
+				for key := range tls.Annotations {
+					delete(child.Annotations, key)
+				}
+			}
+
+			return child, nil
+		},
+		func(parent *corev1alpha1.Deployer, child *networkingv1beta1.Ingress, err error) {
+			if err != nil || parent.Status.Rollout == nil {
+				return
+			}
+			if child == nil {
+				parent.Status.Rollout.CanaryIngressRef = nil
+			} else {
+				parent.Status.Rollout.CanaryIngressRef = refs.NewTypedLocalObjectReferenceForObject(child, c.Scheme)
+			}
+		},
+		func(parent *corev1alpha1.Deployer, child *networkingv1beta1.Ingress) bool {
+			return child.Labels[corev1alpha1.DeployerCanaryLabelKey] != ""
+		},
+	)
+}
@@ -0,0 +1,673 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1beta1 "k8s.io/api/networking/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/projectriff/system/pkg/apis"
+	corev1alpha1 "github.com/projectriff/system/pkg/apis/core/v1alpha1"
+	"github.com/projectriff/system/pkg/controllers"
+	corecontrollers "github.com/projectriff/system/pkg/controllers/core"
+	rtesting "github.com/projectriff/system/pkg/controllers/testing"
+	"github.com/projectriff/system/pkg/controllers/testing/factories"
+	"github.com/projectriff/system/pkg/refs"
+	"github.com/projectriff/system/pkg/tracker"
+)
+
+func TestDeployerRollout(t *testing.T) {
+	testNamespace := "test-namespace"
+	testName := "test-deployer"
+	testImagePrefix := "example.com/repo"
+	testStableImage := fmt.Sprintf("%s@sha256:%s", testImagePrefix, "cf8b4c69d5460f88530e1c80b8856a70801f31c50b191c8413043ba9b160a43e")
+	testCanaryImage := fmt.Sprintf("%s@sha256:%s", testImagePrefix, "0ea9b4a5ee4ab2adc77e2e5c9a3e2bf44e61f9f4aff4ad5d6e8b6bac3f02a1f5")
+	testHost := fmt.Sprintf("%s.%s.example.com", testName, testNamespace)
+	testNow := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = corev1alpha1.AddToScheme(scheme)
+
+	rollout := &corev1alpha1.Rollout{
+		Steps: []corev1alpha1.RolloutStep{
+			{Weight: 25, Pause: &metav1.Duration{Duration: 5 * time.Minute}},
+			{Weight: 50},
+		},
+		TrafficRouting: corev1alpha1.RolloutTrafficRoutingReplicas,
+	}
+	ingressRollout := rollout.DeepCopy()
+	ingressRollout.TrafficRouting = corev1alpha1.RolloutTrafficRoutingIngress
+
+	deployer := factories.DeployerCore().
+		NamespaceName(testNamespace, testName).
+		Image(testCanaryImage).
+		HandlerContainer(func(container *corev1.Container) {
+			container.Ports = []corev1.ContainerPort{
+				{Name: "http", ContainerPort: 8080, Protocol: corev1.ProtocolTCP},
+			}
+		}).
+		Rollout(rollout).
+		StatusLatestImage(testCanaryImage)
+
+	canaryDeploymentRef := &refs.TypedLocalObjectReference{
+		APIGroup: rtesting.StringPtr("apps"),
+		Kind:     "Deployment",
+		Name:     fmt.Sprintf("%s-deployer-canary-000", testName),
+	}
+	canaryServiceRef := &refs.TypedLocalObjectReference{
+		Kind: "Service",
+		Name: fmt.Sprintf("%s-canary", testName),
+	}
+	canaryIngressRef := &refs.TypedLocalObjectReference{
+		APIGroup: rtesting.StringPtr("networking.k8s.io"),
+		Kind:     "Ingress",
+		Name:     fmt.Sprintf("%s-deployer-canary-001", testName),
+	}
+	progressing := func(step, weight int32, startTime time.Time) *corev1alpha1.RolloutStatus {
+		return &corev1alpha1.RolloutStatus{
+			Phase:               corev1alpha1.RolloutPhaseProgressing,
+			Message:             fmt.Sprintf("Step %d of 2, %d%% of traffic to canary", step+1, weight),
+			StableImage:         testStableImage,
+			CanaryImage:         testCanaryImage,
+			Step:                step,
+			Weight:              weight,
+			StepStartTime:       &metav1.Time{Time: startTime},
+			CanaryDeploymentRef: canaryDeploymentRef.DeepCopy(),
+		}
+	}
+
+	promoting := func() *corev1alpha1.RolloutStatus {
+		return &corev1alpha1.RolloutStatus{
+			Phase:               corev1alpha1.RolloutPhasePromoted,
+			Message:             fmt.Sprintf("Promoted image %q, waiting for stable deployment to be ready", testCanaryImage),
+			StableImage:         testCanaryImage,
+			CanaryImage:         testCanaryImage,
+			Step:                1,
+			Weight:              50,
+			CanaryDeploymentRef: canaryDeploymentRef.DeepCopy(),
+		}
+	}
+
+	handlerContainer := func(image string) func(container *corev1.Container) {
+		return func(container *corev1.Container) {
+			container.Image = image
+			container.Ports = []corev1.ContainerPort{
+				{Name: "http", ContainerPort: 8080, Protocol: corev1.ProtocolTCP},
+			}
+			container.Env = []corev1.EnvVar{
+				{Name: "PORT", Value: "8080"},
+			}
+			container.ReadinessProbe = &corev1.Probe{
+				Handler: corev1.Handler{
+					TCPSocket: &corev1.TCPSocketAction{
+						Port: intstr.FromInt(8080),
+					},
+				},
+			}
+		}
+	}
+
+	stableDeployment := factories.Deployment().
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.Namespace(testNamespace)
+			om.Name("%s-deployer-000", testName)
+			om.AddLabel(corev1alpha1.DeployerLabelKey, testName)
+			om.ControlledBy(deployer, scheme)
+			om.Created(1)
+		}).
+		AddSelectorLabel(corev1alpha1.DeployerLabelKey, testName).
+		AddSelectorLabel(corev1alpha1.DeployerTrackLabelKey, corev1alpha1.DeployerTrackStable).
+		HandlerContainer(handlerContainer(testStableImage)).
+		Replicas(3)
+	canaryDeploymentCreate := factories.Deployment().
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.Namespace(testNamespace)
+			om.GenerateName("%s-deployer-canary-", testName)
+			om.AddLabel(corev1alpha1.DeployerLabelKey, testName)
+			om.AddLabel(corev1alpha1.DeployerCanaryLabelKey, testName)
+			om.ControlledBy(deployer, scheme)
+		}).
+		AddSelectorLabel(corev1alpha1.DeployerCanaryLabelKey, testName).
+		PodTemplateSpec(func(pts factories.PodTemplateSpec) {
+			pts.AddLabel(corev1alpha1.DeployerLabelKey, testName)
+			pts.AddLabel(corev1alpha1.DeployerTrackLabelKey, corev1alpha1.DeployerTrackCanary)
+		}).
+		HandlerContainer(handlerContainer(testCanaryImage))
+	canaryDeploymentGiven := canaryDeploymentCreate.
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.Name("%s-deployer-canary-000", testName)
+			om.Created(1)
+		}).
+		Replicas(1)
+
+	stableIngress := factories.Ingress().
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.Namespace(testNamespace)
+			om.Name("%s-deployer-000", testName)
+			om.AddLabel(corev1alpha1.DeployerLabelKey, testName)
+			om.ControlledBy(deployer, scheme)
+			om.Created(1)
+		}).
+		HostToService(testHost, testName)
+
+	t.Run("DeployerRolloutReconciler", func(t *testing.T) {
+		table := rtesting.SubTable{{
+			Name: "no rollout",
+			Parent: deployer.
+				Rollout(nil).
+				StatusRollout(&corev1alpha1.RolloutStatus{StableImage: testStableImage}),
+			ExpectParent: deployer.
+				Rollout(nil),
+		}, {
+			Name: "no image",
+			Parent: deployer.
+				StatusLatestImage(""),
+		}, {
+			Name:   "first image is stable",
+			Parent: deployer,
+			ExpectParent: deployer.
+				StatusRollout(&corev1alpha1.RolloutStatus{StableImage: testCanaryImage}),
+		}, {
+			Name: "stable image unchanged",
+			Parent: deployer.
+				StatusLatestImage(testStableImage).
+				StatusRollout(&corev1alpha1.RolloutStatus{StableImage: testStableImage}),
+		}, {
+			Name: "start rollout",
+			Parent: deployer.
+				StatusRollout(&corev1alpha1.RolloutStatus{
+					Phase:       corev1alpha1.RolloutPhasePromoted,
+					StableImage: testStableImage,
+				}),
+			ExpectParent: deployer.
+				StatusRollout(&corev1alpha1.RolloutStatus{
+					Phase:         corev1alpha1.RolloutPhaseProgressing,
+					Message:       "Step 1 of 2, 25% of traffic to canary",
+					StableImage:   testStableImage,
+					CanaryImage:   testCanaryImage,
+					Weight:        25,
+					StepStartTime: &metav1.Time{Time: testNow},
+				}),
+			ExpectEvents: []rtesting.Event{
+				rtesting.NewEvent(deployer, scheme, corev1.EventTypeNormal, "RolloutStarted",
+					`Started rollout of image "%s"`, testCanaryImage),
+			},
+		}, {
+			Name: "wait for canary",
+			Parent: deployer.
+				StatusRollout(func() *corev1alpha1.RolloutStatus {
+					status := progressing(0, 25, testNow)
+					status.CanaryDeploymentRef = nil
+					return status
+				}()),
+		}, {
+			Name: "canary not ready",
+			Parent: deployer.
+				StatusRollout(progressing(0, 25, testNow.Add(-10*time.Minute))),
+			GivenObjects: []rtesting.Factory{
+				canaryDeploymentGiven,
+			},
+			ExpectParent: deployer.
+				StatusRollout(func() *corev1alpha1.RolloutStatus {
+					status := progressing(0, 25, testNow.Add(-10*time.Minute))
+					status.Message = "Step 1 of 2, 25% of traffic to canary, waiting for canary to be ready"
+					return status
+				}()),
+		}, {
+			Name: "canary ready, paused",
+			Parent: deployer.
+				StatusRollout(progressing(0, 25, testNow.Add(-1*time.Minute))),
+			GivenObjects: []rtesting.Factory{
+				canaryDeploymentGiven.
+					StatusReplicas(1),
+			},
+			ExpectedResult: controllerruntime.Result{RequeueAfter: 4 * time.Minute},
+		}, {
+			Name: "canary ready, next step",
+			Parent: deployer.
+				StatusRollout(progressing(0, 25, testNow.Add(-10*time.Minute))),
+			GivenObjects: []rtesting.Factory{
+				canaryDeploymentGiven.
+					StatusReplicas(1),
+			},
+			ExpectParent: deployer.
+				StatusRollout(progressing(1, 50, testNow)),
+			ExpectedResult: controllerruntime.Result{Requeue: true},
+		}, {
+			Name: "canary ready, promote",
+			Parent: deployer.
+				StatusRollout(progressing(1, 50, testNow)),
+			GivenObjects: []rtesting.Factory{
+				canaryDeploymentGiven.
+					StatusReplicas(1),
+			},
+			ExpectParent: deployer.
+				StatusRollout(promoting()),
+			ExpectEvents: []rtesting.Event{
+				rtesting.NewEvent(deployer, scheme, corev1.EventTypeNormal, "RolloutPromoted",
+					`Promoted image "%s"`, testCanaryImage),
+			},
+		}, {
+			Name: "promoted, stable deployment not updated",
+			Parent: deployer.
+				StatusDeploymentRef("%s-deployer-000", testName).
+				StatusRollout(promoting()),
+			GivenObjects: []rtesting.Factory{
+				stableDeployment.
+					StatusReplicas(3),
+			},
+		}, {
+			Name: "promoted, stable deployment rolling out",
+			Parent: deployer.
+				StatusDeploymentRef("%s-deployer-000", testName).
+				StatusRollout(promoting()),
+			GivenObjects: []rtesting.Factory{
+				stableDeployment.
+					HandlerContainer(handlerContainer(testCanaryImage)).
+					StatusReplicas(3).
+					StatusUpdatedReplicas(1),
+			},
+		}, {
+			Name: "promoted, stable deployment updated",
+			Parent: deployer.
+				StatusDeploymentRef("%s-deployer-000", testName).
+				StatusRollout(promoting()),
+			GivenObjects: []rtesting.Factory{
+				stableDeployment.
+					HandlerContainer(handlerContainer(testCanaryImage)).
+					StatusReplicas(3),
+			},
+			ExpectParent: deployer.
+				StatusDeploymentRef("%s-deployer-000", testName).
+				StatusRollout(&corev1alpha1.RolloutStatus{
+					Phase:               corev1alpha1.RolloutPhasePromoted,
+					Message:             fmt.Sprintf("Promoted image %q", testCanaryImage),
+					StableImage:         testCanaryImage,
+					CanaryDeploymentRef: canaryDeploymentRef,
+				}),
+		}, {
+			Name: "promoted, stable deployment get error",
+			Parent: deployer.
+				StatusDeploymentRef("%s-deployer-000", testName).
+				StatusRollout(promoting()),
+			GivenObjects: []rtesting.Factory{
+				stableDeployment,
+			},
+			WithReactors: []rtesting.ReactionFunc{
+				rtesting.InduceFailure("get", "Deployment"),
+			},
+			ShouldErr: true,
+		}, {
+			Name: "steps removed during rollout",
+			Parent: deployer.
+				Rollout(&corev1alpha1.Rollout{
+					Steps: []corev1alpha1.RolloutStep{{Weight: 40}},
+				}).
+				StatusRollout(progressing(1, 50, testNow)),
+			GivenObjects: []rtesting.Factory{
+				canaryDeploymentGiven,
+			},
+			ExpectParent: deployer.
+				Rollout(&corev1alpha1.Rollout{
+					Steps: []corev1alpha1.RolloutStep{{Weight: 40}},
+				}).
+				StatusRollout(func() *corev1alpha1.RolloutStatus {
+					status := progressing(0, 40, testNow)
+					status.Message = "Step 1 of 1, 40% of traffic to canary, waiting for canary to be ready"
+					return status
+				}()),
+		}, {
+			Name: "canary failed, abort",
+			Parent: deployer.
+				StatusRollout(progressing(0, 25, testNow)),
+			GivenObjects: []rtesting.Factory{
+				canaryDeploymentGiven.
+					StatusConditions(
+						factories.Condition().Type(apis.ConditionType(appsv1.DeploymentProgressing)).False().Reason("ProgressDeadlineExceeded", "too slow"),
+					),
+			},
+			ExpectParent: deployer.
+				StatusRollout(&corev1alpha1.RolloutStatus{
+					Phase:               corev1alpha1.RolloutPhaseAborted,
+					Message:             fmt.Sprintf("Rollout of image %q aborted: too slow", testCanaryImage),
+					StableImage:         testStableImage,
+					CanaryImage:         testCanaryImage,
+					CanaryDeploymentRef: canaryDeploymentRef,
+				}),
+			ExpectEvents: []rtesting.Event{
+				rtesting.NewEvent(deployer, scheme, corev1.EventTypeWarning, "RolloutAborted",
+					`Rollout of image "%s" aborted: too slow`, testCanaryImage),
+			},
+		}, {
+			Name: "aborted image is not retried",
+			Parent: deployer.
+				StatusRollout(&corev1alpha1.RolloutStatus{
+					Phase:       corev1alpha1.RolloutPhaseAborted,
+					StableImage: testStableImage,
+					CanaryImage: testCanaryImage,
+				}),
+		}, {
+			Name: "superseded by stable image",
+			Parent: deployer.
+				StatusLatestImage(testStableImage).
+				StatusRollout(progressing(0, 25, testNow)),
+			ExpectParent: deployer.
+				StatusLatestImage(testStableImage).
+				StatusRollout(&corev1alpha1.RolloutStatus{
+					Phase:               corev1alpha1.RolloutPhaseAborted,
+					Message:             fmt.Sprintf("Rollout of image %q was superseded by the stable image", testCanaryImage),
+					StableImage:         testStableImage,
+					CanaryDeploymentRef: canaryDeploymentRef,
+				}),
+			ExpectEvents: []rtesting.Event{
+				rtesting.NewEvent(deployer, scheme, corev1.EventTypeNormal, "RolloutAborted",
+					`Rollout of image "%s" was superseded by the stable image`, testCanaryImage),
+			},
+		}, {
+			Name: "get canary failed",
+			Parent: deployer.
+				StatusRollout(progressing(0, 25, testNow)),
+			GivenObjects: []rtesting.Factory{
+				canaryDeploymentGiven,
+			},
+			WithReactors: []rtesting.ReactionFunc{
+				rtesting.InduceFailure("get", "Deployment"),
+			},
+			ShouldErr: true,
+		}}
+
+		table.Test(t, scheme, func(t *testing.T, row *rtesting.SubTestcase, client client.Client, tracker tracker.Tracker, recorder record.EventRecorder, log logr.Logger) controllers.SubReconciler {
+			return corecontrollers.DeployerRolloutReconciler(
+				controllers.Config{
+					Client:   client,
+					Recorder: recorder,
+					Scheme:   scheme,
+					Log:      log,
+					Tracker:  tracker,
+				},
+				func() time.Time {
+					return testNow
+				},
+			)
+		})
+	})
+
+	t.Run("DeployerChildCanaryDeploymentReconciler", func(t *testing.T) {
+		table := rtesting.SubTable{{
+			Name: "no rollout",
+			Parent: deployer.
+				StatusRollout(&corev1alpha1.RolloutStatus{StableImage: testStableImage}),
+			GivenObjects: []rtesting.Factory{
+				stableDeployment,
+			},
+		}, {
+			Name: "create canary",
+			Parent: deployer.
+				StatusDeploymentRef("%s-deployer-000", testName).
+				StatusRollout(func() *corev1alpha1.RolloutStatus {
+					status := progressing(0, 25, testNow)
+					status.CanaryDeploymentRef = nil
+					return status
+				}()),
+			GivenObjects: []rtesting.Factory{
+				stableDeployment,
+			},
+			ExpectParent: deployer.
+				StatusDeploymentRef("%s-deployer-000", testName).
+				StatusRollout(func() *corev1alpha1.RolloutStatus {
+					status := progressing(0, 25, testNow)
+					status.CanaryDeploymentRef.Name = fmt.Sprintf("%s-deployer-canary-001", testName)
+					return status
+				}()),
+			ExpectCreates: []rtesting.Factory{
+				canaryDeploymentCreate.
+					Replicas(1),
+			},
+			ExpectEvents: []rtesting.Event{
+				rtesting.NewEvent(deployer, scheme, corev1.EventTypeNormal, "Created",
+					`Created Deployment "%s-deployer-canary-001"`, testName),
+			},
+		}, {
+			Name: "scale canary with weight",
+			Parent: deployer.
+				StatusDeploymentRef("%s-deployer-000", testName).
+				StatusRollout(progressing(1, 50, testNow)),
+			GivenObjects: []rtesting.Factory{
+				stableDeployment,
+				canaryDeploymentGiven,
+			},
+			ExpectParent: deployer.
+				StatusDeploymentRef("%s-deployer-000", testName).
+				StatusRollout(progressing(1, 50, testNow)),
+			ExpectUpdates: []rtesting.Factory{
+				canaryDeploymentGiven.
+					Replicas(3),
+			},
+			ExpectEvents: []rtesting.Event{
+				rtesting.NewEvent(deployer, scheme, corev1.EventTypeNormal, "Updated",
+					`Updated Deployment "%s-deployer-canary-000"`, testName),
+			},
+		}, {
+			Name: "ingress routing, canary scaled like the stable deployment",
+			Parent: deployer.
+				IngressPolicy(corev1alpha1.IngressPolicyExternal).
+				Rollout(ingressRollout).
+				StatusDeploymentRef("%s-deployer-000", testName).
+				StatusRollout(progressing(0, 25, testNow)),
+			GivenObjects: []rtesting.Factory{
+				stableDeployment,
+				canaryDeploymentGiven,
+			},
+			ExpectParent: deployer.
+				IngressPolicy(corev1alpha1.IngressPolicyExternal).
+				Rollout(ingressRollout).
+				StatusDeploymentRef("%s-deployer-000", testName).
+				StatusRollout(progressing(0, 25, testNow)),
+			ExpectUpdates: []rtesting.Factory{
+				canaryDeploymentGiven.
+					Replicas(3),
+			},
+			ExpectEvents: []rtesting.Event{
+				rtesting.NewEvent(deployer, scheme, corev1.EventTypeNormal, "Updated",
+					`Updated Deployment "%s-deployer-canary-000"`, testName),
+			},
+		}, {
+			Name: "delete canary once promoted",
+			Parent: deployer.
+				StatusDeploymentRef("%s-deployer-000", testName).
+				StatusRollout(&corev1alpha1.RolloutStatus{
+					Phase:               corev1alpha1.RolloutPhasePromoted,
+					StableImage:         testCanaryImage,
+					CanaryDeploymentRef: canaryDeploymentRef,
+				}),
+			GivenObjects: []rtesting.Factory{
+				stableDeployment,
+				canaryDeploymentGiven,
+			},
+			ExpectParent: deployer.
+				StatusDeploymentRef("%s-deployer-000", testName).
+				StatusRollout(&corev1alpha1.RolloutStatus{
+					Phase:       corev1alpha1.RolloutPhasePromoted,
+					StableImage: testCanaryImage,
+				}),
+			ExpectDeletes: []rtesting.DeleteRef{
+				{Group: "apps", Kind: "Deployment", Namespace: testNamespace, Name: canaryDeploymentRef.Name},
+			},
+			ExpectEvents: []rtesting.Event{
+				rtesting.NewEvent(deployer, scheme, corev1.EventTypeNormal, "Deleted",
+					`Deleted Deployment "%s-deployer-canary-000"`, testName),
+			},
+		}, {
+			Name: "keep canary until the stable deployment is updated",
+			Parent: deployer.
+				StatusDeploymentRef("%s-deployer-000", testName).
+				StatusRollout(func() *corev1alpha1.RolloutStatus {
+					status := promoting()
+					status.Step = 0
+					status.Weight = 25
+					return status
+				}()),
+			GivenObjects: []rtesting.Factory{
+				stableDeployment,
+				canaryDeploymentGiven,
+			},
+		}}
+
+		table.Test(t, scheme, func(t *testing.T, row *rtesting.SubTestcase, client client.Client, tracker tracker.Tracker, recorder record.EventRecorder, log logr.Logger) controllers.SubReconciler {
+			return corecontrollers.DeployerChildCanaryDeploymentReconciler(
+				controllers.Config{
+					Client:   client,
+					Recorder: recorder,
+					Scheme:   scheme,
+					Log:      log,
+					Tracker:  tracker,
+				},
+			)
+		})
+	})
+
+	canaryServiceCreate := factories.Service().
+		NamespaceName(testNamespace, canaryServiceRef.Name).
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.AddLabel(corev1alpha1.DeployerLabelKey, testName)
+			om.AddLabel(corev1alpha1.DeployerCanaryLabelKey, testName)
+			om.ControlledBy(deployer, scheme)
+		}).
+		AddSelectorLabel(corev1alpha1.DeployerCanaryLabelKey, testName).
+		Ports(
+			corev1.ServicePort{
+				Name:       "http",
+				Port:       80,
+				TargetPort: intstr.FromInt(8080),
+			},
+		)
+	ingressDeployer := deployer.
+		IngressPolicy(corev1alpha1.IngressPolicyExternal).
+		Rollout(ingressRollout).
+		StatusIngressRef("%s-deployer-000", testName)
+
+	t.Run("DeployerChildCanaryServiceReconciler", func(t *testing.T) {
+		table := rtesting.SubTable{{
+			Name: "replicas routing",
+			Parent: deployer.
+				StatusRollout(progressing(0, 25, testNow)),
+		}, {
+			Name: "create canary service",
+			Parent: ingressDeployer.
+				StatusRollout(progressing(0, 25, testNow)),
+			ExpectParent: ingressDeployer.
+				StatusRollout(func() *corev1alpha1.RolloutStatus {
+					status := progressing(0, 25, testNow)
+					status.CanaryServiceRef = canaryServiceRef
+					return status
+				}()),
+			ExpectCreates: []rtesting.Factory{
+				canaryServiceCreate,
+			},
+			ExpectEvents: []rtesting.Event{
+				rtesting.NewEvent(deployer, scheme, corev1.EventTypeNormal, "Created",
+					`Created Service "%s"`, canaryServiceRef.Name),
+			},
+		}}
+
+		table.Test(t, scheme, func(t *testing.T, row *rtesting.SubTestcase, client client.Client, tracker tracker.Tracker, recorder record.EventRecorder, log logr.Logger) controllers.SubReconciler {
+			return corecontrollers.DeployerChildCanaryServiceReconciler(
+				controllers.Config{
+					Client:   client,
+					Recorder: recorder,
+					Scheme:   scheme,
+					Log:      log,
+					Tracker:  tracker,
+				},
+			)
+		})
+	})
+
+	t.Run("DeployerChildCanaryIngressReconciler", func(t *testing.T) {
+		withCanaryService := func(weight int32) *corev1alpha1.RolloutStatus {
+			status := progressing(0, weight, testNow)
+			status.CanaryServiceRef = canaryServiceRef
+			return status
+		}
+
+		table := rtesting.SubTable{{
+			Name: "no canary service",
+			Parent: ingressDeployer.
+				StatusRollout(progressing(0, 25, testNow)),
+			GivenObjects: []rtesting.Factory{
+				stableIngress,
+			},
+		}, {
+			Name: "create canary ingress",
+			Parent: ingressDeployer.
+				StatusRollout(withCanaryService(25)),
+			GivenObjects: []rtesting.Factory{
+				stableIngress,
+			},
+			ExpectParent: ingressDeployer.
+				StatusRollout(func() *corev1alpha1.RolloutStatus {
+					status := withCanaryService(25)
+					status.CanaryIngressRef = canaryIngressRef
+					return status
+				}()),
+			ExpectCreates: []rtesting.Factory{
+				factories.Ingress().
+					ObjectMeta(func(om factories.ObjectMeta) {
+						om.Namespace(testNamespace)
+						om.GenerateName("%s-deployer-canary-", testName)
+						om.AddLabel(corev1alpha1.DeployerLabelKey, testName)
+						om.AddLabel(corev1alpha1.DeployerCanaryLabelKey, testName)
+						om.AddAnnotation("nginx.ingress.kubernetes.io/canary", "true")
+						om.AddAnnotation("nginx.ingress.kubernetes.io/canary-weight", "25")
+						om.ControlledBy(deployer, scheme)
+					}).
+					HostToService(testHost, canaryServiceRef.Name),
+			},
+			ExpectEvents: []rtesting.Event{
+				rtesting.NewEvent(deployer, scheme, corev1.EventTypeNormal, "Created",
+					`Created Ingress "%s"`, canaryIngressRef.Name),
+			},
+		}}
+
+		table.Test(t, scheme, func(t *testing.T, row *rtesting.SubTestcase, client client.Client, tracker tracker.Tracker, recorder record.EventRecorder, log logr.Logger) controllers.SubReconciler {
+			return corecontrollers.DeployerChildCanaryIngressReconciler(
+				controllers.Config{
+					Client:   client,
+					Recorder: recorder,
+					Scheme:   scheme,
+					Log:      log,
+					Tracker:  tracker,
+				},
+				networkingv1beta1.SchemeGroupVersion,
+			)
+		})
+	})
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	corev1alpha1 "github.com/projectriff/system/pkg/apis/core/v1alpha1"
+	"github.com/projectriff/system/pkg/controllers"
+	"github.com/projectriff/system/pkg/tracker"
+)
+
+const (
+	DeployerIngressTLSStashKey controllers.StashKey = "deployer-ingress-tls"
+)
+
+// DeployerIngressTLS is the TLS configuration resolved for a deployer's
+// ingress
+type DeployerIngressTLS struct {
+	// SecretName is the Secret holding the certificate for the ingress
+	SecretName string
+	// Annotations request that a certificate is issued into the Secret
+	Annotations map[string]string
+}
+
+// resolveDeployerIngressTLS returns the TLS configuration for the
+// deployer's ingress, or nil if the ingress is not secured. A certificate
+// referenced by the deployer is preferred over the cluster-wide settings.
+func resolveDeployerIngressTLS(parent *corev1alpha1.Deployer, coreSettings *corev1.ConfigMap) *DeployerIngressTLS {
+	if parent.Spec.TLS != nil {
+		return &DeployerIngressTLS{
+			SecretName: parent.Spec.TLS.SecretRef,
+		}
+	}
+	if secretName := coreSettings.Data[defaultTLSSecretKey]; secretName != "" {
+		return &DeployerIngressTLS{
+			SecretName: secretName,
+		}
+	}
+	if annotation := coreSettings.Data[tlsIssuerAnnotationKey]; annotation != "" {
+		parts := strings.SplitN(annotation, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil
+		}
+		return &DeployerIngressTLS{
+			SecretName: fmt.Sprintf("%s-deployer-tls", parent.Name),
+			Annotations: map[string]string{
+				parts[0]: parts[1],
+			},
+		}
+	}
+	return nil
+}
+
+// DeployerIngressCertificateReconciler reports a missing, invalid or expired
+// certificate for the deployer's ingress. Reconciliation is requeued for
+// when the certificate expires.
+func DeployerIngressCertificateReconciler(c controllers.Config, now func() time.Time) controllers.SubReconciler {
+	c.Log = c.Log.WithName("IngressCertificate")
+
+	return &controllers.SyncReconciler{
+		Setup: func(mgr controllers.Manager, bldr *controllers.Builder) error {
+			bldr.Watches(&source.Kind{Type: &corev1.Secret{}}, controllers.EnqueueTracked(&corev1.Secret{}, c.Tracker, c.Scheme))
+			return nil
+		},
+		Sync: func(ctx context.Context, parent *corev1alpha1.Deployer) (ctrl.Result, error) {
+			tls, ok := controllers.RetrieveValue(ctx, DeployerIngressTLSStashKey).(*DeployerIngressTLS)
+			if !ok || parent.Status.IngressRef == nil {
+				// no secured ingress, skip
+				return ctrl.Result{}, nil
+			}
+
+			secret := &corev1.Secret{}
+			secretKey := types.NamespacedName{Namespace: parent.Namespace, Name: tls.SecretName}
+
+			// track secret
+			c.Tracker.Track(
+				tracker.NewKey(schema.GroupVersionKind{Version: "v1", Kind: "Secret"}, secretKey),
+				types.NamespacedName{Namespace: parent.Namespace, Name: parent.Name},
+			)
+			if err := c.Get(ctx, secretKey, secret); err != nil {
+				if !apierrs.IsNotFound(err) {
+					return ctrl.Result{}, err
+				}
+				if len(tls.Annotations) != 0 {
+					parent.Status.MarkIngressCertificatePending(tls.SecretName)
+				} else {
+					parent.Status.MarkIngressCertificateMissing(tls.SecretName)
+				}
+				return ctrl.Result{}, nil
+			}
+
+			certificate, err := parseCertificate(secret.Data[corev1.TLSCertKey])
+			if err != nil {
+				parent.Status.MarkIngressCertificateInvalid(tls.SecretName, err.Error())
+				return ctrl.Result{}, nil
+			}
+			remaining := certificate.NotAfter.Sub(now())
+			if remaining <= 0 {
+				parent.Status.MarkIngressCertificateExpired(tls.SecretName, certificate.NotAfter)
+				return ctrl.Result{}, nil
+			}
+
+			return ctrl.Result{RequeueAfter: remaining}, nil
+		},
+
+		Config: c,
+	}
+}
+
+// parseCertificate returns the first certificate in the PEM encoded data,
+// which is the leaf certificate for a kubernetes.io/tls Secret
+func parseCertificate(data []byte) (*x509.Certificate, error) {
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("no PEM encoded certificate found in %q", corev1.TLSCertKey)
+		}
+		if block.Type == "CERTIFICATE" {
+			return x509.ParseCertificate(block.Bytes)
+		}
+	}
+}
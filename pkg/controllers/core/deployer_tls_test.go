@@ -0,0 +1,246 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1alpha1 "github.com/projectriff/system/pkg/apis/core/v1alpha1"
+	"github.com/projectriff/system/pkg/controllers"
+	corecontrollers "github.com/projectriff/system/pkg/controllers/core"
+	rtesting "github.com/projectriff/system/pkg/controllers/testing"
+	"github.com/projectriff/system/pkg/controllers/testing/factories"
+	"github.com/projectriff/system/pkg/tracker"
+)
+
+func TestDeployerIngressCertificateReconciler(t *testing.T) {
+	testNamespace := "test-namespace"
+	testName := "test-deployer"
+	testSecretName := "my-certificate"
+	testNow := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	testNotAfter := testNow.Add(72 * time.Hour)
+
+	deployerConditionDeploymentReady := factories.Condition().Type(corev1alpha1.DeployerConditionDeploymentReady)
+	deployerConditionIngressReady := factories.Condition().Type(corev1alpha1.DeployerConditionIngressReady)
+	deployerConditionReady := factories.Condition().Type(corev1alpha1.DeployerConditionReady)
+	deployerConditionServiceReady := factories.Condition().Type(corev1alpha1.DeployerConditionServiceReady)
+
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = corev1alpha1.AddToScheme(scheme)
+
+	deployerWithoutIngress := factories.DeployerCore().
+		NamespaceName(testNamespace, testName).
+		IngressPolicy(corev1alpha1.IngressPolicyExternal).
+		StatusConditions(
+			deployerConditionDeploymentReady.True(),
+			deployerConditionIngressReady.True(),
+			deployerConditionReady.True(),
+			deployerConditionServiceReady.True(),
+		)
+	deployer := deployerWithoutIngress.
+		StatusIngressRef("%s-deployer-000", testName)
+
+	certificate := factories.Secret().
+		NamespaceName(testNamespace, testSecretName).
+		Type(corev1.SecretTypeTLS).
+		AddData(corev1.TLSCertKey, generateCertificate(t, testNotAfter))
+
+	table := rtesting.SubTable{{
+		Name:   "no tls",
+		Parent: deployer,
+	}, {
+		Name:   "no ingress",
+		Parent: deployerWithoutIngress,
+		GivenStashedValues: map[controllers.StashKey]interface{}{
+			corecontrollers.DeployerIngressTLSStashKey: &corecontrollers.DeployerIngressTLS{SecretName: testSecretName},
+		},
+		ExpectParent: deployerWithoutIngress,
+	}, {
+		Name:   "valid certificate",
+		Parent: deployer,
+		GivenStashedValues: map[controllers.StashKey]interface{}{
+			corecontrollers.DeployerIngressTLSStashKey: &corecontrollers.DeployerIngressTLS{SecretName: testSecretName},
+		},
+		GivenObjects: []rtesting.Factory{
+			certificate,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(certificate, deployer, scheme),
+		},
+		ExpectParent:   deployer,
+		ExpectedResult: controllerruntime.Result{RequeueAfter: 72 * time.Hour},
+	}, {
+		Name:   "valid certificate chain",
+		Parent: deployer,
+		GivenStashedValues: map[controllers.StashKey]interface{}{
+			corecontrollers.DeployerIngressTLSStashKey: &corecontrollers.DeployerIngressTLS{SecretName: testSecretName},
+		},
+		GivenObjects: []rtesting.Factory{
+			certificate.
+				AddData(corev1.TLSCertKey, generateCertificate(t, testNotAfter)+generateCertificate(t, testNotAfter.Add(time.Hour))),
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(certificate, deployer, scheme),
+		},
+		ExpectParent:   deployer,
+		ExpectedResult: controllerruntime.Result{RequeueAfter: 72 * time.Hour},
+	}, {
+		Name:   "expired certificate",
+		Parent: deployer,
+		GivenStashedValues: map[controllers.StashKey]interface{}{
+			corecontrollers.DeployerIngressTLSStashKey: &corecontrollers.DeployerIngressTLS{SecretName: testSecretName},
+		},
+		GivenObjects: []rtesting.Factory{
+			certificate.
+				AddData(corev1.TLSCertKey, generateCertificate(t, testNow.Add(-time.Hour))),
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(certificate, deployer, scheme),
+		},
+		ExpectParent: deployer.
+			StatusConditions(
+				deployerConditionDeploymentReady.True(),
+				deployerConditionIngressReady.False().Reason("CertificateExpired", `The certificate in Secret "my-certificate" expired at 2020-01-02T02:04:05Z.`),
+				deployerConditionReady.False().Reason("CertificateExpired", `The certificate in Secret "my-certificate" expired at 2020-01-02T02:04:05Z.`),
+				deployerConditionServiceReady.True(),
+			),
+	}, {
+		Name:   "invalid certificate",
+		Parent: deployer,
+		GivenStashedValues: map[controllers.StashKey]interface{}{
+			corecontrollers.DeployerIngressTLSStashKey: &corecontrollers.DeployerIngressTLS{SecretName: testSecretName},
+		},
+		GivenObjects: []rtesting.Factory{
+			certificate.
+				AddData(corev1.TLSCertKey, "not a certificate"),
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(certificate, deployer, scheme),
+		},
+		ExpectParent: deployer.
+			StatusConditions(
+				deployerConditionDeploymentReady.True(),
+				deployerConditionIngressReady.False().Reason("CertificateInvalid", `The certificate in Secret "my-certificate" is invalid: no PEM encoded certificate found in "tls.crt"`),
+				deployerConditionReady.False().Reason("CertificateInvalid", `The certificate in Secret "my-certificate" is invalid: no PEM encoded certificate found in "tls.crt"`),
+				deployerConditionServiceReady.True(),
+			),
+	}, {
+		Name:   "missing certificate",
+		Parent: deployer,
+		GivenStashedValues: map[controllers.StashKey]interface{}{
+			corecontrollers.DeployerIngressTLSStashKey: &corecontrollers.DeployerIngressTLS{SecretName: testSecretName},
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(certificate, deployer, scheme),
+		},
+		ExpectParent: deployer.
+			StatusConditions(
+				deployerConditionDeploymentReady.True(),
+				deployerConditionIngressReady.False().Reason("CertificateMissing", `The certificate Secret "my-certificate" was not found.`),
+				deployerConditionReady.False().Reason("CertificateMissing", `The certificate Secret "my-certificate" was not found.`),
+				deployerConditionServiceReady.True(),
+			),
+	}, {
+		Name:   "pending certificate",
+		Parent: deployer,
+		GivenStashedValues: map[controllers.StashKey]interface{}{
+			corecontrollers.DeployerIngressTLSStashKey: &corecontrollers.DeployerIngressTLS{
+				SecretName: testSecretName,
+				Annotations: map[string]string{
+					"cert-manager.io/cluster-issuer": "letsencrypt",
+				},
+			},
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(certificate, deployer, scheme),
+		},
+		ExpectParent: deployer.
+			StatusConditions(
+				deployerConditionDeploymentReady.True(),
+				deployerConditionIngressReady.Unknown().Reason("CertificatePending", `Waiting for a certificate to be issued into Secret "my-certificate".`),
+				deployerConditionReady.Unknown().Reason("CertificatePending", `Waiting for a certificate to be issued into Secret "my-certificate".`),
+				deployerConditionServiceReady.True(),
+			),
+	}, {
+		Name:   "get secret failed",
+		Parent: deployer,
+		GivenStashedValues: map[controllers.StashKey]interface{}{
+			corecontrollers.DeployerIngressTLSStashKey: &corecontrollers.DeployerIngressTLS{SecretName: testSecretName},
+		},
+		GivenObjects: []rtesting.Factory{
+			certificate,
+		},
+		WithReactors: []rtesting.ReactionFunc{
+			rtesting.InduceFailure("get", "Secret"),
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(certificate, deployer, scheme),
+		},
+		ShouldErr: true,
+	}}
+
+	table.Test(t, scheme, func(t *testing.T, row *rtesting.SubTestcase, client client.Client, tracker tracker.Tracker, recorder record.EventRecorder, log logr.Logger) controllers.SubReconciler {
+		return corecontrollers.DeployerIngressCertificateReconciler(
+			controllers.Config{
+				Client:   client,
+				Recorder: recorder,
+				Scheme:   scheme,
+				Log:      log,
+				Tracker:  tracker,
+			},
+			func() time.Time {
+				return testNow
+			},
+		)
+	})
+}
+
+// generateCertificate returns a PEM encoded self-signed certificate
+func generateCertificate(t *testing.T, notAfter time.Time) string {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unable to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "test-deployer.test-namespace.example.com"},
+		NotBefore:    notAfter.Add(-365 * 24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("unable to create certificate: %v", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
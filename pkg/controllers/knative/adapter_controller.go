@@ -21,6 +21,7 @@ import (
 	"fmt"
 
 	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	apierrs "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
@@ -30,22 +31,24 @@ import (
 	knativev1alpha1 "github.com/projectriff/system/pkg/apis/knative/v1alpha1"
 	servingv1 "github.com/projectriff/system/pkg/apis/thirdparty/knative/serving/v1"
 	"github.com/projectriff/system/pkg/controllers"
+	"github.com/projectriff/system/pkg/registries"
 	"github.com/projectriff/system/pkg/tracker"
 )
 
 // +kubebuilder:rbac:groups=knative.projectriff.io,resources=adapters,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=knative.projectriff.io,resources=adapters/status,verbs=get;update;patch
-// +kubebuilder:rbac:groups=build.projectriff.io,resources=applications;containers;functions,verbs=get;list;watch
+// +kubebuilder:rbac:groups=build.projectriff.io,resources=applications;containers;functions;imagepromotions,verbs=get;list;watch
 // +kubebuilder:rbac:groups=serving.knative.dev,resources=configurations;services,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch
 // +kubebuilder:rbac:groups=core,resources=events,verbs=get;list;watch;create;update;patch;delete
 
-func AdapterReconciler(c controllers.Config) *controllers.ParentReconciler {
+func AdapterReconciler(c controllers.Config, namespace string) *controllers.ParentReconciler {
 	c.Log = c.Log.WithName("Adapter")
 
 	return &controllers.ParentReconciler{
 		Type: &knativev1alpha1.Adapter{},
 		SubReconcilers: []controllers.SubReconciler{
-			AdapterBuildRefReconciler(c),
+			AdapterBuildRefReconciler(c, namespace),
 			AdapterTargetRefReconciler(c),
 		},
 
@@ -53,11 +56,18 @@ func AdapterReconciler(c controllers.Config) *controllers.ParentReconciler {
 	}
 }
 
-func AdapterBuildRefReconciler(c controllers.Config) controllers.SubReconciler {
+func AdapterBuildRefReconciler(c controllers.Config, namespace string) controllers.SubReconciler {
 	c.Log = c.Log.WithName("BuildRef")
 
 	return &controllers.SyncReconciler{
 		Sync: func(ctx context.Context, parent *knativev1alpha1.Adapter) error {
+			// track registry rewrite rules for new images
+			registries.Track(c.Tracker, namespace, parent)
+			rewriter, err := registries.Load(ctx, c, namespace)
+			if err != nil {
+				return err
+			}
+
 			build := parent.Spec.Build
 
 			switch {
@@ -77,7 +87,7 @@ func AdapterBuildRefReconciler(c controllers.Config) controllers.SubReconciler {
 					return err
 				}
 				if application.Status.LatestImage != "" {
-					parent.Status.LatestImage = application.Status.LatestImage
+					parent.Status.LatestImage = rewriter.Rewrite(application.Status.LatestImage)
 					parent.Status.MarkBuildReady()
 				}
 				return nil
@@ -98,7 +108,7 @@ func AdapterBuildRefReconciler(c controllers.Config) controllers.SubReconciler {
 					return err
 				}
 				if container.Status.LatestImage != "" {
-					parent.Status.LatestImage = container.Status.LatestImage
+					parent.Status.LatestImage = rewriter.Rewrite(container.Status.LatestImage)
 					parent.Status.MarkBuildReady()
 				}
 				return nil
@@ -119,7 +129,28 @@ func AdapterBuildRefReconciler(c controllers.Config) controllers.SubReconciler {
 					return err
 				}
 				if function.Status.LatestImage != "" {
-					parent.Status.LatestImage = function.Status.LatestImage
+					parent.Status.LatestImage = rewriter.Rewrite(function.Status.LatestImage)
+					parent.Status.MarkBuildReady()
+				}
+				return nil
+
+			case build.ImagePromotionRef != "":
+				var imagePromotion buildv1alpha1.ImagePromotion
+				key := types.NamespacedName{Namespace: parent.Namespace, Name: build.ImagePromotionRef}
+				// track image promotion for new images
+				c.Tracker.Track(
+					tracker.NewKey(imagePromotion.GetGroupVersionKind(), key),
+					types.NamespacedName{Namespace: parent.Namespace, Name: parent.Name},
+				)
+				if err := c.Get(ctx, key, &imagePromotion); err != nil {
+					if apierrs.IsNotFound(err) {
+						parent.Status.MarkBuildNotFound("image promotion", build.ImagePromotionRef)
+						return nil
+					}
+					return err
+				}
+				if imagePromotion.Status.LatestImage != "" {
+					parent.Status.LatestImage = rewriter.Rewrite(imagePromotion.Status.LatestImage)
 					parent.Status.MarkBuildReady()
 				}
 				return nil
@@ -133,6 +164,8 @@ func AdapterBuildRefReconciler(c controllers.Config) controllers.SubReconciler {
 			bldr.Watches(&source.Kind{Type: &buildv1alpha1.Application{}}, controllers.EnqueueTracked(&buildv1alpha1.Application{}, c.Tracker, c.Scheme))
 			bldr.Watches(&source.Kind{Type: &buildv1alpha1.Container{}}, controllers.EnqueueTracked(&buildv1alpha1.Container{}, c.Tracker, c.Scheme))
 			bldr.Watches(&source.Kind{Type: &buildv1alpha1.Function{}}, controllers.EnqueueTracked(&buildv1alpha1.Function{}, c.Tracker, c.Scheme))
+			bldr.Watches(&source.Kind{Type: &buildv1alpha1.ImagePromotion{}}, controllers.EnqueueTracked(&buildv1alpha1.ImagePromotion{}, c.Tracker, c.Scheme))
+			bldr.Watches(&source.Kind{Type: &corev1.ConfigMap{}}, controllers.EnqueueTracked(&corev1.ConfigMap{}, c.Tracker, c.Scheme))
 			return nil
 		},
 	}
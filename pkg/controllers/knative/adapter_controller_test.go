@@ -41,11 +41,13 @@ import (
 
 func TestAdapterReconciler(t *testing.T) {
 	testNamespace := "test-namespace"
+	testSystemNamespace := "riff-system"
 	testName := "test-adapter"
 	testKey := types.NamespacedName{Namespace: testNamespace, Name: testName}
 	testImagePrefix := "example.com/repo"
 	testSha256 := "cf8b4c69d5460f88530e1c80b8856a70801f31c50b191c8413043ba9b160a43e"
 	testImage := fmt.Sprintf("%s/%s@sha256:%s", testImagePrefix, testName, testSha256)
+	testMirrorImage := fmt.Sprintf("%s/%s@sha256:%s", "mirror.local/repo", testName, testSha256)
 
 	adapterConditionBuildReady := factories.Condition().Type(knativev1alpha1.AdapterConditionBuildReady)
 	adapterConditionReady := factories.Condition().Type(knativev1alpha1.AdapterConditionReady)
@@ -57,6 +59,9 @@ func TestAdapterReconciler(t *testing.T) {
 	_ = knativev1alpha1.AddToScheme(scheme)
 	_ = knativeservingv1.AddToScheme(scheme)
 
+	testRegistryRewrites := factories.ConfigMap().
+		NamespaceName(testSystemNamespace, "riff-registry-rewrites")
+
 	testAdapter := factories.AdapterKnative().
 		NamespaceName(testNamespace, testName)
 
@@ -113,6 +118,7 @@ func TestAdapterReconciler(t *testing.T) {
 		},
 		ShouldErr: true,
 		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, testAdapter, scheme),
 			rtesting.NewTrackRequest(testApplication, testAdapter, scheme),
 			rtesting.NewTrackRequest(testService, testAdapter, scheme),
 		},
@@ -148,6 +154,7 @@ func TestAdapterReconciler(t *testing.T) {
 			testService,
 		},
 		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, testAdapter, scheme),
 			rtesting.NewTrackRequest(testApplication, testAdapter, scheme),
 			rtesting.NewTrackRequest(testService, testAdapter, scheme),
 		},
@@ -170,6 +177,44 @@ func TestAdapterReconciler(t *testing.T) {
 				).
 				StatusLatestImage(testImage),
 		},
+	}, {
+		Name: "adapt application to service, registry rewrite",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			testRegistryRewrites.
+				AddData("rewrites", fmt.Sprintf("%s -> mirror.local/repo", testImagePrefix)),
+			testAdapter.
+				ApplicationRef(testApplication.Create().GetName()).
+				ServiceRef(testService.Create().GetName()),
+			testApplication.
+				StatusLatestImage(testImage).
+				StatusReady(),
+			testService,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, testAdapter, scheme),
+			rtesting.NewTrackRequest(testApplication, testAdapter, scheme),
+			rtesting.NewTrackRequest(testService, testAdapter, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(testAdapter, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectUpdates: []rtesting.Factory{
+			testService.
+				UserContainer(func(uc *corev1.Container) {
+					uc.Image = testMirrorImage
+				}),
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			testAdapter.
+				StatusConditions(
+					adapterConditionBuildReady.True(),
+					adapterConditionReady.True(),
+					adapterConditionTargetFound.True(),
+				).
+				StatusLatestImage(testMirrorImage),
+		},
 	}, {
 		Name: "adapt application to service, application not ready",
 		Key:  testKey,
@@ -181,6 +226,7 @@ func TestAdapterReconciler(t *testing.T) {
 			testService,
 		},
 		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, testAdapter, scheme),
 			rtesting.NewTrackRequest(testApplication, testAdapter, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
@@ -205,6 +251,7 @@ func TestAdapterReconciler(t *testing.T) {
 			testService,
 		},
 		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, testAdapter, scheme),
 			rtesting.NewTrackRequest(testApplication, testAdapter, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
@@ -236,6 +283,7 @@ func TestAdapterReconciler(t *testing.T) {
 		},
 		ShouldErr: true,
 		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, testAdapter, scheme),
 			rtesting.NewTrackRequest(testApplication, testAdapter, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
@@ -263,6 +311,7 @@ func TestAdapterReconciler(t *testing.T) {
 			testService,
 		},
 		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, testAdapter, scheme),
 			rtesting.NewTrackRequest(testFunction, testAdapter, scheme),
 			rtesting.NewTrackRequest(testService, testAdapter, scheme),
 		},
@@ -296,6 +345,7 @@ func TestAdapterReconciler(t *testing.T) {
 			testService,
 		},
 		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, testAdapter, scheme),
 			rtesting.NewTrackRequest(testFunction, testAdapter, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
@@ -320,6 +370,7 @@ func TestAdapterReconciler(t *testing.T) {
 			testService,
 		},
 		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, testAdapter, scheme),
 			rtesting.NewTrackRequest(testFunction, testAdapter, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
@@ -351,6 +402,7 @@ func TestAdapterReconciler(t *testing.T) {
 		},
 		ShouldErr: true,
 		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, testAdapter, scheme),
 			rtesting.NewTrackRequest(testFunction, testAdapter, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
@@ -378,6 +430,7 @@ func TestAdapterReconciler(t *testing.T) {
 			testService,
 		},
 		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, testAdapter, scheme),
 			rtesting.NewTrackRequest(testContainer, testAdapter, scheme),
 			rtesting.NewTrackRequest(testService, testAdapter, scheme),
 		},
@@ -411,6 +464,7 @@ func TestAdapterReconciler(t *testing.T) {
 			testService,
 		},
 		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, testAdapter, scheme),
 			rtesting.NewTrackRequest(testContainer, testAdapter, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
@@ -435,6 +489,7 @@ func TestAdapterReconciler(t *testing.T) {
 			testService,
 		},
 		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, testAdapter, scheme),
 			rtesting.NewTrackRequest(testContainer, testAdapter, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
@@ -466,6 +521,7 @@ func TestAdapterReconciler(t *testing.T) {
 		},
 		ShouldErr: true,
 		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, testAdapter, scheme),
 			rtesting.NewTrackRequest(testContainer, testAdapter, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
@@ -492,6 +548,7 @@ func TestAdapterReconciler(t *testing.T) {
 				StatusReady(),
 		},
 		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, testAdapter, scheme),
 			rtesting.NewTrackRequest(testContainer, testAdapter, scheme),
 			rtesting.NewTrackRequest(testService, testAdapter, scheme),
 		},
@@ -525,6 +582,7 @@ func TestAdapterReconciler(t *testing.T) {
 		},
 		ShouldErr: true,
 		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, testAdapter, scheme),
 			rtesting.NewTrackRequest(testContainer, testAdapter, scheme),
 			rtesting.NewTrackRequest(testService, testAdapter, scheme),
 		},
@@ -563,6 +621,7 @@ func TestAdapterReconciler(t *testing.T) {
 				}),
 		},
 		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, testAdapter, scheme),
 			rtesting.NewTrackRequest(testContainer, testAdapter, scheme),
 			rtesting.NewTrackRequest(testService, testAdapter, scheme),
 		},
@@ -583,6 +642,7 @@ func TestAdapterReconciler(t *testing.T) {
 		},
 		ShouldErr: true,
 		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, testAdapter, scheme),
 			rtesting.NewTrackRequest(testContainer, testAdapter, scheme),
 			rtesting.NewTrackRequest(testService, testAdapter, scheme),
 		},
@@ -619,6 +679,7 @@ func TestAdapterReconciler(t *testing.T) {
 			testConfiguration,
 		},
 		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, testAdapter, scheme),
 			rtesting.NewTrackRequest(testContainer, testAdapter, scheme),
 			rtesting.NewTrackRequest(testConfiguration, testAdapter, scheme),
 		},
@@ -653,6 +714,7 @@ func TestAdapterReconciler(t *testing.T) {
 				StatusReady(),
 		},
 		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, testAdapter, scheme),
 			rtesting.NewTrackRequest(testContainer, testAdapter, scheme),
 			rtesting.NewTrackRequest(testConfiguration, testAdapter, scheme),
 		},
@@ -686,6 +748,7 @@ func TestAdapterReconciler(t *testing.T) {
 		},
 		ShouldErr: true,
 		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, testAdapter, scheme),
 			rtesting.NewTrackRequest(testContainer, testAdapter, scheme),
 			rtesting.NewTrackRequest(testConfiguration, testAdapter, scheme),
 		},
@@ -724,6 +787,7 @@ func TestAdapterReconciler(t *testing.T) {
 				}),
 		},
 		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, testAdapter, scheme),
 			rtesting.NewTrackRequest(testContainer, testAdapter, scheme),
 			rtesting.NewTrackRequest(testConfiguration, testAdapter, scheme),
 		},
@@ -744,6 +808,7 @@ func TestAdapterReconciler(t *testing.T) {
 		},
 		ShouldErr: true,
 		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, testAdapter, scheme),
 			rtesting.NewTrackRequest(testContainer, testAdapter, scheme),
 			rtesting.NewTrackRequest(testConfiguration, testAdapter, scheme),
 		},
@@ -779,6 +844,7 @@ func TestAdapterReconciler(t *testing.T) {
 				Scheme:    scheme,
 				Tracker:   tracker,
 			},
+			testSystemNamespace,
 		)
 	})
 }
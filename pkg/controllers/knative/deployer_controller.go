@@ -19,7 +19,9 @@ package knative
 import (
 	"context"
 	"fmt"
+	"time"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	apierrs "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -31,22 +33,24 @@ import (
 	servingv1 "github.com/projectriff/system/pkg/apis/thirdparty/knative/serving/v1"
 	"github.com/projectriff/system/pkg/controllers"
 	"github.com/projectriff/system/pkg/refs"
+	"github.com/projectriff/system/pkg/registries"
 	"github.com/projectriff/system/pkg/tracker"
 )
 
 // +kubebuilder:rbac:groups=knative.projectriff.io,resources=deployers,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=knative.projectriff.io,resources=deployers/status,verbs=get;update;patch
-// +kubebuilder:rbac:groups=build.projectriff.io,resources=applications;containers;functions,verbs=get;list;watch
+// +kubebuilder:rbac:groups=build.projectriff.io,resources=applications;containers;functions;imagepromotions,verbs=get;list;watch
 // +kubebuilder:rbac:groups=serving.knative.dev,resources=configurations;routes,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch
 // +kubebuilder:rbac:groups=core,resources=events,verbs=get;list;watch;create;update;patch;delete
 
-func DeployerReconciler(c controllers.Config) *controllers.ParentReconciler {
+func DeployerReconciler(c controllers.Config, namespace string, now func() time.Time) *controllers.ParentReconciler {
 	c.Log = c.Log.WithName("Deployer")
 
 	return &controllers.ParentReconciler{
 		Type: &knativev1alpha1.Deployer{},
 		SubReconcilers: []controllers.SubReconciler{
-			DeployerBuildRefReconciler(c),
+			DeployerBuildRefReconciler(c, namespace, now),
 			DeployerChildConfigurationReconciler(c),
 			DeployerChildRouteReconciler(c),
 		},
@@ -55,78 +59,54 @@ func DeployerReconciler(c controllers.Config) *controllers.ParentReconciler {
 	}
 }
 
-func DeployerBuildRefReconciler(c controllers.Config) controllers.SubReconciler {
+func DeployerBuildRefReconciler(c controllers.Config, namespace string, now func() time.Time) controllers.SubReconciler {
 	c.Log = c.Log.WithName("BuildRef")
 
 	return &controllers.SyncReconciler{
 		Sync: func(ctx context.Context, parent *knativev1alpha1.Deployer) error {
-			build := parent.Spec.Build
-			if build == nil {
-				parent.Status.LatestImage = parent.Spec.Template.Spec.Containers[0].Image
-				return nil
+			// track registry rewrite rules for new images
+			registries.Track(c.Tracker, namespace, parent)
+			rewriter, err := registries.Load(ctx, c, namespace)
+			if err != nil {
+				return err
 			}
 
-			switch {
-			case build.ApplicationRef != "":
-				var application buildv1alpha1.Application
-				key := types.NamespacedName{Namespace: parent.Namespace, Name: build.ApplicationRef}
-				// track application for new images
-				c.Tracker.Track(
-					tracker.NewKey(application.GetGroupVersionKind(), key),
-					types.NamespacedName{Namespace: parent.Namespace, Name: parent.Name},
-				)
-				if err := c.Get(ctx, key, &application); err != nil {
-					if apierrs.IsNotFound(err) {
-						return nil
-					}
-					return err
-				}
-				if application.Status.LatestImage != "" {
-					parent.Status.LatestImage = application.Status.LatestImage
-				}
-				return nil
-
-			case build.ContainerRef != "":
-				var container buildv1alpha1.Container
-				key := types.NamespacedName{Namespace: parent.Namespace, Name: build.ContainerRef}
-				// track container for new images
-				c.Tracker.Track(
-					tracker.NewKey(container.GetGroupVersionKind(), key),
-					types.NamespacedName{Namespace: parent.Namespace, Name: parent.Name},
-				)
-				if err := c.Get(ctx, key, &container); err != nil {
-					if apierrs.IsNotFound(err) {
-						return nil
-					}
-					return err
-				}
-				if container.Status.LatestImage != "" {
-					parent.Status.LatestImage = container.Status.LatestImage
-				}
-				return nil
-
-			case build.FunctionRef != "":
-				var function buildv1alpha1.Function
-				key := types.NamespacedName{Namespace: parent.Namespace, Name: build.FunctionRef}
-				// track function for new images
-				c.Tracker.Track(
-					tracker.NewKey(function.GetGroupVersionKind(), key),
-					types.NamespacedName{Namespace: parent.Namespace, Name: parent.Name},
-				)
-				if err := c.Get(ctx, key, &function); err != nil {
-					if apierrs.IsNotFound(err) {
-						return nil
-					}
-					return err
+			image, buildGeneration, err := resolveDeployerImage(ctx, c, parent)
+			if err != nil {
+				return err
+			}
+
+			imageSource := knativev1alpha1.DeployerImageSourceBuild
+			if parent.Spec.Build == nil {
+				imageSource = knativev1alpha1.DeployerImageSourceTemplate
+			}
+			previousSource, previousImage := parent.Status.ImageSource, parent.Status.LatestImage
+			if pinnedImage := parent.Spec.PinnedImage; pinnedImage != "" {
+				parent.Status.LatestImage = rewriter.Rewrite(pinnedImage)
+				parent.Status.ImageSource = knativev1alpha1.DeployerImageSourcePinned
+				buildGeneration = 0
+				if previousSource != knativev1alpha1.DeployerImageSourcePinned || previousImage != parent.Status.LatestImage {
+					c.Recorder.Eventf(parent, corev1.EventTypeNormal, "Pinned",
+						"Pinned image %q", parent.Status.LatestImage)
 				}
-				if function.Status.LatestImage != "" {
-					parent.Status.LatestImage = function.Status.LatestImage
+			} else if image != "" {
+				parent.Status.LatestImage = rewriter.Rewrite(image)
+				parent.Status.ImageSource = imageSource
+				if previousSource == knativev1alpha1.DeployerImageSourcePinned {
+					c.Recorder.Eventf(parent, corev1.EventTypeNormal, "Unpinned",
+						"Unpinned image %q", previousImage)
 				}
-				return nil
+			} else if previousSource == knativev1alpha1.DeployerImageSourcePinned {
+				// the pinned image keeps running until the build produces an
+				// image
+				buildGeneration = 0
+			}
 
+			if parent.Status.LatestImage != "" {
+				parent.Status.RecordRevision(parent.Status.LatestImage, buildGeneration, metav1.Time{Time: now()})
 			}
 
-			return fmt.Errorf("invalid build")
+			return nil
 		},
 
 		Config: c,
@@ -134,11 +114,92 @@ func DeployerBuildRefReconciler(c controllers.Config) controllers.SubReconciler
 			bldr.Watches(&source.Kind{Type: &buildv1alpha1.Application{}}, controllers.EnqueueTracked(&buildv1alpha1.Application{}, c.Tracker, c.Scheme))
 			bldr.Watches(&source.Kind{Type: &buildv1alpha1.Container{}}, controllers.EnqueueTracked(&buildv1alpha1.Container{}, c.Tracker, c.Scheme))
 			bldr.Watches(&source.Kind{Type: &buildv1alpha1.Function{}}, controllers.EnqueueTracked(&buildv1alpha1.Function{}, c.Tracker, c.Scheme))
+			bldr.Watches(&source.Kind{Type: &buildv1alpha1.ImagePromotion{}}, controllers.EnqueueTracked(&buildv1alpha1.ImagePromotion{}, c.Tracker, c.Scheme))
+			bldr.Watches(&source.Kind{Type: &corev1.ConfigMap{}}, controllers.EnqueueTracked(&corev1.ConfigMap{}, c.Tracker, c.Scheme))
 			return nil
 		},
 	}
 }
 
+// resolveDeployerImage resolves the image and generation of the deployer's
+// build, or the image of the template when there is no build. An empty image
+// is returned when the build has not produced an image.
+func resolveDeployerImage(ctx context.Context, c controllers.Config, parent *knativev1alpha1.Deployer) (string, int64, error) {
+	build := parent.Spec.Build
+	if build == nil {
+		return parent.Spec.Template.Spec.Containers[0].Image, 0, nil
+	}
+
+	switch {
+	case build.ApplicationRef != "":
+		var application buildv1alpha1.Application
+		key := types.NamespacedName{Namespace: parent.Namespace, Name: build.ApplicationRef}
+		// track application for new images
+		c.Tracker.Track(
+			tracker.NewKey(application.GetGroupVersionKind(), key),
+			types.NamespacedName{Namespace: parent.Namespace, Name: parent.Name},
+		)
+		if err := c.Get(ctx, key, &application); err != nil {
+			if apierrs.IsNotFound(err) {
+				return "", 0, nil
+			}
+			return "", 0, err
+		}
+		return application.Status.LatestImage, application.Generation, nil
+
+	case build.ContainerRef != "":
+		var container buildv1alpha1.Container
+		key := types.NamespacedName{Namespace: parent.Namespace, Name: build.ContainerRef}
+		// track container for new images
+		c.Tracker.Track(
+			tracker.NewKey(container.GetGroupVersionKind(), key),
+			types.NamespacedName{Namespace: parent.Namespace, Name: parent.Name},
+		)
+		if err := c.Get(ctx, key, &container); err != nil {
+			if apierrs.IsNotFound(err) {
+				return "", 0, nil
+			}
+			return "", 0, err
+		}
+		return container.Status.LatestImage, container.Generation, nil
+
+	case build.FunctionRef != "":
+		var function buildv1alpha1.Function
+		key := types.NamespacedName{Namespace: parent.Namespace, Name: build.FunctionRef}
+		// track function for new images
+		c.Tracker.Track(
+			tracker.NewKey(function.GetGroupVersionKind(), key),
+			types.NamespacedName{Namespace: parent.Namespace, Name: parent.Name},
+		)
+		if err := c.Get(ctx, key, &function); err != nil {
+			if apierrs.IsNotFound(err) {
+				return "", 0, nil
+			}
+			return "", 0, err
+		}
+		return function.Status.LatestImage, function.Generation, nil
+
+	case build.ImagePromotionRef != "":
+		var imagePromotion buildv1alpha1.ImagePromotion
+		key := types.NamespacedName{Namespace: parent.Namespace, Name: build.ImagePromotionRef}
+		// track image promotion for new images
+		c.Tracker.Track(
+			tracker.NewKey(imagePromotion.GetGroupVersionKind(), key),
+			types.NamespacedName{Namespace: parent.Namespace, Name: parent.Name},
+		)
+		if err := c.Get(ctx, key, &imagePromotion); err != nil {
+			if apierrs.IsNotFound(err) {
+				return "", 0, nil
+			}
+			return "", 0, err
+		}
+		return imagePromotion.Status.LatestImage, imagePromotion.Generation, nil
+
+	}
+
+	return "", 0, fmt.Errorf("invalid build")
+}
+
 func DeployerChildConfigurationReconciler(c controllers.Config) controllers.SubReconciler {
 	c.Log = c.Log.WithName("ChildConfiguration")
 
@@ -19,10 +19,12 @@ package knative_test
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
 	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
@@ -43,11 +45,14 @@ import (
 
 func TestDeployerReconciler(t *testing.T) {
 	testNamespace := "test-namespace"
+	testSystemNamespace := "riff-system"
 	testName := "test-deployer"
 	testKey := types.NamespacedName{Namespace: testNamespace, Name: testName}
 	testImagePrefix := "example.com/repo"
 	testSha256 := "cf8b4c69d5460f88530e1c80b8856a70801f31c50b191c8413043ba9b160a43e"
 	testImage := fmt.Sprintf("%s/%s@sha256:%s", testImagePrefix, testName, testSha256)
+	testDeployedTime := metav1.NewTime(time.Date(2020, 2, 3, 4, 5, 6, 0, time.UTC))
+	testRevision := knativev1alpha1.DeployerRevision{Image: testImage, DeployedTime: testDeployedTime}
 	testAddressURL := "http://internal.local"
 	testURL := "http://example.com"
 
@@ -61,6 +66,9 @@ func TestDeployerReconciler(t *testing.T) {
 	_ = knativev1alpha1.AddToScheme(scheme)
 	_ = knativeservingv1.AddToScheme(scheme)
 
+	testRegistryRewrites := factories.ConfigMap().
+		NamespaceName(testSystemNamespace, "riff-registry-rewrites")
+
 	testDeployer := factories.DeployerKnative().
 		NamespaceName(testNamespace, testName)
 
@@ -70,6 +78,9 @@ func TestDeployerReconciler(t *testing.T) {
 	testFunction := factories.Function().
 		NamespaceName(testNamespace, "my-function").
 		StatusLatestImage(testImage)
+	testImagePromotion := factories.ImagePromotion().
+		NamespaceName(testNamespace, "my-image-promotion").
+		StatusLatestImage(testImage)
 	testContainer := factories.Container().
 		NamespaceName(testNamespace, "my-container").
 		StatusLatestImage(testImage)
@@ -150,6 +161,7 @@ func TestDeployerReconciler(t *testing.T) {
 			testApplication,
 		},
 		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, testDeployer, scheme),
 			rtesting.NewTrackRequest(testApplication, testDeployer, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
@@ -172,6 +184,8 @@ func TestDeployerReconciler(t *testing.T) {
 					deployerConditionRouteReady.Unknown(),
 				).
 				StatusLatestImage(testImage).
+				StatusImageSource(knativev1alpha1.DeployerImageSourceBuild).
+				StatusHistory(testRevision).
 				StatusConfigurationRef(testConfigurationGiven.Create().GetName()).
 				StatusRouteRef(testRouteGiven.Create().GetName()),
 		},
@@ -183,6 +197,7 @@ func TestDeployerReconciler(t *testing.T) {
 				ApplicationRef(testApplication.Create().GetName()),
 		},
 		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, testDeployer, scheme),
 			rtesting.NewTrackRequest(testApplication, testDeployer, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
@@ -209,6 +224,7 @@ func TestDeployerReconciler(t *testing.T) {
 		},
 		ShouldErr: true,
 		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, testDeployer, scheme),
 			rtesting.NewTrackRequest(testApplication, testDeployer, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
@@ -233,6 +249,7 @@ func TestDeployerReconciler(t *testing.T) {
 				StatusLatestImage(""),
 		},
 		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, testDeployer, scheme),
 			rtesting.NewTrackRequest(testApplication, testDeployer, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
@@ -256,6 +273,7 @@ func TestDeployerReconciler(t *testing.T) {
 			testFunction,
 		},
 		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, testDeployer, scheme),
 			rtesting.NewTrackRequest(testFunction, testDeployer, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
@@ -278,6 +296,45 @@ func TestDeployerReconciler(t *testing.T) {
 					deployerConditionRouteReady.Unknown(),
 				).
 				StatusLatestImage(testImage).
+				StatusImageSource(knativev1alpha1.DeployerImageSourceBuild).
+				StatusHistory(testRevision).
+				StatusConfigurationRef(testConfigurationGiven.Create().GetName()).
+				StatusRouteRef(testRouteGiven.Create().GetName()),
+		},
+	}, {
+		Name: "create knative resources, from image promotion",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			testDeployer.
+				ImagePromotionRef(testImagePromotion.Create().GetName()),
+			testImagePromotion,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, testDeployer, scheme),
+			rtesting.NewTrackRequest(testImagePromotion, testDeployer, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(testDeployer, scheme, corev1.EventTypeNormal, "Created",
+				`Created Configuration "%s-deployer-001"`, testName),
+			rtesting.NewEvent(testDeployer, scheme, corev1.EventTypeNormal, "Created",
+				`Created Route "%s"`, testName),
+			rtesting.NewEvent(testDeployer, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectCreates: []rtesting.Factory{
+			testConfigurationCreate,
+			testRouteCreate,
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			testDeployer.
+				StatusConditions(
+					deployerConditionConfigurationReady.Unknown(),
+					deployerConditionReady.Unknown(),
+					deployerConditionRouteReady.Unknown(),
+				).
+				StatusLatestImage(testImage).
+				StatusImageSource(knativev1alpha1.DeployerImageSourceBuild).
+				StatusHistory(testRevision).
 				StatusConfigurationRef(testConfigurationGiven.Create().GetName()).
 				StatusRouteRef(testRouteGiven.Create().GetName()),
 		},
@@ -289,6 +346,7 @@ func TestDeployerReconciler(t *testing.T) {
 				FunctionRef(testFunction.Create().GetName()),
 		},
 		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, testDeployer, scheme),
 			rtesting.NewTrackRequest(testFunction, testDeployer, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
@@ -315,6 +373,7 @@ func TestDeployerReconciler(t *testing.T) {
 		},
 		ShouldErr: true,
 		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, testDeployer, scheme),
 			rtesting.NewTrackRequest(testFunction, testDeployer, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
@@ -339,6 +398,7 @@ func TestDeployerReconciler(t *testing.T) {
 				StatusLatestImage(""),
 		},
 		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, testDeployer, scheme),
 			rtesting.NewTrackRequest(testFunction, testDeployer, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
@@ -362,6 +422,7 @@ func TestDeployerReconciler(t *testing.T) {
 			testContainer,
 		},
 		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, testDeployer, scheme),
 			rtesting.NewTrackRequest(testContainer, testDeployer, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
@@ -384,6 +445,8 @@ func TestDeployerReconciler(t *testing.T) {
 					deployerConditionRouteReady.Unknown(),
 				).
 				StatusLatestImage(testImage).
+				StatusImageSource(knativev1alpha1.DeployerImageSourceBuild).
+				StatusHistory(testRevision).
 				StatusConfigurationRef(testConfigurationGiven.Create().GetName()).
 				StatusRouteRef(testRouteGiven.Create().GetName()),
 		},
@@ -395,6 +458,7 @@ func TestDeployerReconciler(t *testing.T) {
 				ContainerRef(testContainer.Create().GetName()),
 		},
 		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, testDeployer, scheme),
 			rtesting.NewTrackRequest(testContainer, testDeployer, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
@@ -421,6 +485,7 @@ func TestDeployerReconciler(t *testing.T) {
 		},
 		ShouldErr: true,
 		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, testDeployer, scheme),
 			rtesting.NewTrackRequest(testContainer, testDeployer, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
@@ -445,6 +510,7 @@ func TestDeployerReconciler(t *testing.T) {
 				StatusLatestImage(""),
 		},
 		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, testDeployer, scheme),
 			rtesting.NewTrackRequest(testContainer, testDeployer, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
@@ -466,6 +532,9 @@ func TestDeployerReconciler(t *testing.T) {
 			testDeployer.
 				Image(testImage),
 		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, testDeployer, scheme),
+		},
 		ExpectEvents: []rtesting.Event{
 			rtesting.NewEvent(testDeployer, scheme, corev1.EventTypeNormal, "Created",
 				`Created Configuration "%s-deployer-001"`, testName),
@@ -486,6 +555,8 @@ func TestDeployerReconciler(t *testing.T) {
 					deployerConditionRouteReady.Unknown(),
 				).
 				StatusLatestImage(testImage).
+				StatusImageSource(knativev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
 				StatusConfigurationRef(testConfigurationGiven.Create().GetName()).
 				StatusRouteRef(testRouteGiven.Create().GetName()),
 		},
@@ -499,6 +570,9 @@ func TestDeployerReconciler(t *testing.T) {
 			testDeployer.
 				Image(testImage),
 		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, testDeployer, scheme),
+		},
 		ShouldErr: true,
 		ExpectEvents: []rtesting.Event{
 			rtesting.NewEvent(testDeployer, scheme, corev1.EventTypeWarning, "CreationFailed",
@@ -516,7 +590,9 @@ func TestDeployerReconciler(t *testing.T) {
 					deployerConditionReady.Unknown(),
 					deployerConditionRouteReady.Unknown(),
 				).
-				StatusLatestImage(testImage),
+				StatusLatestImage(testImage).
+				StatusImageSource(knativev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision),
 		},
 	}, {
 		Name: "create knative resources, create route failed",
@@ -528,6 +604,9 @@ func TestDeployerReconciler(t *testing.T) {
 			testDeployer.
 				Image(testImage),
 		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, testDeployer, scheme),
+		},
 		ShouldErr: true,
 		ExpectEvents: []rtesting.Event{
 			rtesting.NewEvent(testDeployer, scheme, corev1.EventTypeNormal, "Created",
@@ -549,6 +628,8 @@ func TestDeployerReconciler(t *testing.T) {
 					deployerConditionRouteReady.Unknown(),
 				).
 				StatusLatestImage(testImage).
+				StatusImageSource(knativev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
 				StatusConfigurationRef(testConfigurationGiven.Create().GetName()),
 		},
 	}, {
@@ -563,6 +644,9 @@ func TestDeployerReconciler(t *testing.T) {
 			testDeployer.
 				Image(testImage),
 		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, testDeployer, scheme),
+		},
 		ExpectEvents: []rtesting.Event{
 			rtesting.NewEvent(testDeployer, scheme, corev1.EventTypeNormal, "Created",
 				`Created Configuration "%s-deployer-001"`, testName),
@@ -583,6 +667,8 @@ func TestDeployerReconciler(t *testing.T) {
 					deployerConditionRouteReady.False().Reason("NotOwned", `There is an existing Route "test-deployer" that the Deployer does not own.`),
 				).
 				StatusLatestImage(testImage).
+				StatusImageSource(knativev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
 				StatusConfigurationRef(testConfigurationGiven.Create().GetName()),
 		},
 	}, {
@@ -596,6 +682,9 @@ func TestDeployerReconciler(t *testing.T) {
 			testConfigurationGiven.
 				NamespaceName(testNamespace, "extra-configuration-2"),
 		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, testDeployer, scheme),
+		},
 		ExpectEvents: []rtesting.Event{
 			rtesting.NewEvent(testDeployer, scheme, corev1.EventTypeNormal, "Deleted",
 				`Deleted Configuration "%s"`, "extra-configuration-1"),
@@ -624,6 +713,8 @@ func TestDeployerReconciler(t *testing.T) {
 					deployerConditionRouteReady.Unknown(),
 				).
 				StatusLatestImage(testImage).
+				StatusImageSource(knativev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
 				StatusConfigurationRef(testConfigurationGiven.Create().GetName()).
 				StatusRouteRef(testRouteGiven.Create().GetName()),
 		},
@@ -641,6 +732,9 @@ func TestDeployerReconciler(t *testing.T) {
 			testConfigurationGiven.
 				NamespaceName(testNamespace, "extra-configuration-2"),
 		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, testDeployer, scheme),
+		},
 		ShouldErr: true,
 		ExpectEvents: []rtesting.Event{
 			rtesting.NewEvent(testDeployer, scheme, corev1.EventTypeWarning, "DeleteFailed",
@@ -658,7 +752,9 @@ func TestDeployerReconciler(t *testing.T) {
 					deployerConditionReady.Unknown(),
 					deployerConditionRouteReady.Unknown(),
 				).
-				StatusLatestImage(testImage),
+				StatusLatestImage(testImage).
+				StatusImageSource(knativev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision),
 		},
 	}, {
 		Name: "create knative resources, delete extra routes",
@@ -671,6 +767,9 @@ func TestDeployerReconciler(t *testing.T) {
 			testRouteGiven.
 				NamespaceName(testNamespace, "extra-route-2"),
 		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, testDeployer, scheme),
+		},
 		ExpectEvents: []rtesting.Event{
 			rtesting.NewEvent(testDeployer, scheme, corev1.EventTypeNormal, "Created",
 				`Created Configuration "%s-deployer-001"`, testName),
@@ -699,6 +798,8 @@ func TestDeployerReconciler(t *testing.T) {
 					deployerConditionRouteReady.Unknown(),
 				).
 				StatusLatestImage(testImage).
+				StatusImageSource(knativev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
 				StatusConfigurationRef(testConfigurationGiven.Create().GetName()).
 				StatusRouteRef(testRouteGiven.Create().GetName()),
 		},
@@ -716,6 +817,9 @@ func TestDeployerReconciler(t *testing.T) {
 			testRouteGiven.
 				NamespaceName(testNamespace, "extra-route-2"),
 		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, testDeployer, scheme),
+		},
 		ShouldErr: true,
 		ExpectEvents: []rtesting.Event{
 			rtesting.NewEvent(testDeployer, scheme, corev1.EventTypeNormal, "Created",
@@ -739,6 +843,8 @@ func TestDeployerReconciler(t *testing.T) {
 					deployerConditionRouteReady.Unknown(),
 				).
 				StatusLatestImage(testImage).
+				StatusImageSource(knativev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
 				StatusConfigurationRef(testConfigurationGiven.Create().GetName()),
 		},
 	}, {
@@ -753,6 +859,9 @@ func TestDeployerReconciler(t *testing.T) {
 				}),
 			testRouteGiven,
 		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, testDeployer, scheme),
+		},
 		ExpectEvents: []rtesting.Event{
 			rtesting.NewEvent(testDeployer, scheme, corev1.EventTypeNormal, "Updated",
 				`Updated Configuration "%s"`, testConfigurationGiven.Create().GetName()),
@@ -770,6 +879,8 @@ func TestDeployerReconciler(t *testing.T) {
 					deployerConditionRouteReady.Unknown(),
 				).
 				StatusLatestImage(testImage).
+				StatusImageSource(knativev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
 				StatusConfigurationRef(testConfigurationGiven.Create().GetName()).
 				StatusRouteRef(testRouteGiven.Create().GetName()),
 		},
@@ -788,6 +899,9 @@ func TestDeployerReconciler(t *testing.T) {
 				}),
 			testRouteGiven,
 		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, testDeployer, scheme),
+		},
 		ShouldErr: true,
 		ExpectEvents: []rtesting.Event{
 			rtesting.NewEvent(testDeployer, scheme, corev1.EventTypeNormal, "StatusUpdated",
@@ -800,7 +914,9 @@ func TestDeployerReconciler(t *testing.T) {
 					deployerConditionReady.Unknown(),
 					deployerConditionRouteReady.Unknown(),
 				).
-				StatusLatestImage(testImage),
+				StatusLatestImage(testImage).
+				StatusImageSource(knativev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision),
 		},
 	}, {
 		Name: "update configuration, update failed",
@@ -817,6 +933,9 @@ func TestDeployerReconciler(t *testing.T) {
 				}),
 			testRouteGiven,
 		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, testDeployer, scheme),
+		},
 		ShouldErr: true,
 		ExpectEvents: []rtesting.Event{
 			rtesting.NewEvent(testDeployer, scheme, corev1.EventTypeWarning, "UpdateFailed",
@@ -834,7 +953,9 @@ func TestDeployerReconciler(t *testing.T) {
 					deployerConditionReady.Unknown(),
 					deployerConditionRouteReady.Unknown(),
 				).
-				StatusLatestImage(testImage),
+				StatusLatestImage(testImage).
+				StatusImageSource(knativev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision),
 		},
 	}, {
 		Name: "update route",
@@ -846,6 +967,9 @@ func TestDeployerReconciler(t *testing.T) {
 			testRouteGiven.
 				Traffic(),
 		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, testDeployer, scheme),
+		},
 		ExpectEvents: []rtesting.Event{
 			rtesting.NewEvent(testDeployer, scheme, corev1.EventTypeNormal, "Updated",
 				`Updated Route "%s"`, testRouteGiven.Create().GetName()),
@@ -863,6 +987,8 @@ func TestDeployerReconciler(t *testing.T) {
 					deployerConditionRouteReady.Unknown(),
 				).
 				StatusLatestImage(testImage).
+				StatusImageSource(knativev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
 				StatusConfigurationRef(testConfigurationGiven.Create().GetName()).
 				StatusRouteRef(testRouteGiven.Create().GetName()),
 		},
@@ -879,6 +1005,9 @@ func TestDeployerReconciler(t *testing.T) {
 			testRouteGiven.
 				Traffic(),
 		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, testDeployer, scheme),
+		},
 		ShouldErr: true,
 		ExpectEvents: []rtesting.Event{
 			rtesting.NewEvent(testDeployer, scheme, corev1.EventTypeNormal, "StatusUpdated",
@@ -892,6 +1021,8 @@ func TestDeployerReconciler(t *testing.T) {
 					deployerConditionRouteReady.Unknown(),
 				).
 				StatusLatestImage(testImage).
+				StatusImageSource(knativev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
 				StatusConfigurationRef(testConfigurationGiven.Create().GetName()),
 		},
 	}, {
@@ -907,6 +1038,9 @@ func TestDeployerReconciler(t *testing.T) {
 			testRouteGiven.
 				Traffic(),
 		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, testDeployer, scheme),
+		},
 		ShouldErr: true,
 		ExpectEvents: []rtesting.Event{
 			rtesting.NewEvent(testDeployer, scheme, corev1.EventTypeWarning, "UpdateFailed",
@@ -925,6 +1059,8 @@ func TestDeployerReconciler(t *testing.T) {
 					deployerConditionRouteReady.Unknown(),
 				).
 				StatusLatestImage(testImage).
+				StatusImageSource(knativev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
 				StatusConfigurationRef(testConfigurationGiven.Create().GetName()),
 		},
 	}, {
@@ -939,6 +1075,9 @@ func TestDeployerReconciler(t *testing.T) {
 			testConfigurationGiven,
 			testRouteGiven,
 		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, testDeployer, scheme),
+		},
 		ShouldErr: true,
 		ExpectEvents: []rtesting.Event{
 			rtesting.NewEvent(testDeployer, scheme, corev1.EventTypeWarning, "StatusUpdateFailed",
@@ -952,6 +1091,8 @@ func TestDeployerReconciler(t *testing.T) {
 					deployerConditionRouteReady.Unknown(),
 				).
 				StatusLatestImage(testImage).
+				StatusImageSource(knativev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
 				StatusConfigurationRef(testConfigurationGiven.Create().GetName()).
 				StatusRouteRef(testRouteGiven.Create().GetName()),
 		},
@@ -970,6 +1111,9 @@ func TestDeployerReconciler(t *testing.T) {
 			testConfigurationGiven,
 			testRouteGiven,
 		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, testDeployer, scheme),
+		},
 		ExpectEvents: []rtesting.Event{
 			rtesting.NewEvent(testDeployer, scheme, corev1.EventTypeNormal, "Updated",
 				`Updated Configuration "%s"`, testConfigurationGiven.Create().GetName()),
@@ -999,6 +1143,8 @@ func TestDeployerReconciler(t *testing.T) {
 					deployerConditionRouteReady.Unknown(),
 				).
 				StatusLatestImage(testImage).
+				StatusImageSource(knativev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
 				StatusConfigurationRef(testConfigurationGiven.Create().GetName()).
 				StatusRouteRef(testRouteGiven.Create().GetName()),
 		},
@@ -1012,6 +1158,9 @@ func TestDeployerReconciler(t *testing.T) {
 			testConfigurationGiven,
 			testRouteGiven,
 		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, testDeployer, scheme),
+		},
 		ExpectEvents: []rtesting.Event{
 			rtesting.NewEvent(testDeployer, scheme, corev1.EventTypeNormal, "Updated",
 				`Updated Configuration "%s"`, testConfigurationGiven.Create().GetName()),
@@ -1030,6 +1179,8 @@ func TestDeployerReconciler(t *testing.T) {
 					deployerConditionRouteReady.Unknown(),
 				).
 				StatusLatestImage(testImage).
+				StatusImageSource(knativev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
 				StatusConfigurationRef(testConfigurationGiven.Create().GetName()).
 				StatusRouteRef(testRouteGiven.Create().GetName()),
 		},
@@ -1044,6 +1195,9 @@ func TestDeployerReconciler(t *testing.T) {
 			testConfigurationGiven,
 			testRouteGiven,
 		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, testDeployer, scheme),
+		},
 		ExpectEvents: []rtesting.Event{
 			rtesting.NewEvent(testDeployer, scheme, corev1.EventTypeNormal, "Updated",
 				`Updated Configuration "%s"`, testConfigurationGiven.Create().GetName()),
@@ -1070,6 +1224,8 @@ func TestDeployerReconciler(t *testing.T) {
 					deployerConditionRouteReady.Unknown(),
 				).
 				StatusLatestImage(testImage).
+				StatusImageSource(knativev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
 				StatusConfigurationRef(testConfigurationGiven.Create().GetName()).
 				StatusRouteRef(testRouteGiven.Create().GetName()),
 		},
@@ -1090,6 +1246,9 @@ func TestDeployerReconciler(t *testing.T) {
 				StatusAddressURL(testAddressURL).
 				StatusURL(testURL),
 		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, testDeployer, scheme),
+		},
 		ExpectEvents: []rtesting.Event{
 			rtesting.NewEvent(testDeployer, scheme, corev1.EventTypeNormal, "StatusUpdated",
 				`Updated status`),
@@ -1102,6 +1261,8 @@ func TestDeployerReconciler(t *testing.T) {
 					deployerConditionRouteReady.True(),
 				).
 				StatusLatestImage(testImage).
+				StatusImageSource(knativev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
 				StatusConfigurationRef(testConfigurationGiven.Create().GetName()).
 				StatusRouteRef(testRouteGiven.Create().GetName()).
 				StatusAddressURL(testAddressURL).
@@ -1122,6 +1283,9 @@ func TestDeployerReconciler(t *testing.T) {
 				StatusAddressURL(testAddressURL).
 				StatusURL(testURL),
 		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, testDeployer, scheme),
+		},
 		ExpectEvents: []rtesting.Event{
 			rtesting.NewEvent(testDeployer, scheme, corev1.EventTypeNormal, "StatusUpdated",
 				`Updated status`),
@@ -1134,6 +1298,8 @@ func TestDeployerReconciler(t *testing.T) {
 					deployerConditionRouteReady.True(),
 				).
 				StatusLatestImage(testImage).
+				StatusImageSource(knativev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
 				StatusConfigurationRef(testConfigurationGiven.Create().GetName()).
 				StatusRouteRef(testRouteGiven.Create().GetName()).
 				StatusAddressURL(testAddressURL).
@@ -1154,6 +1320,9 @@ func TestDeployerReconciler(t *testing.T) {
 				StatusAddressURL(testAddressURL).
 				StatusURL(testURL),
 		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, testDeployer, scheme),
+		},
 		ExpectEvents: []rtesting.Event{
 			rtesting.NewEvent(testDeployer, scheme, corev1.EventTypeNormal, "StatusUpdated",
 				`Updated status`),
@@ -1166,6 +1335,8 @@ func TestDeployerReconciler(t *testing.T) {
 					deployerConditionRouteReady.False().Reason("TestReason", "a human readable message"),
 				).
 				StatusLatestImage(testImage).
+				StatusImageSource(knativev1alpha1.DeployerImageSourceTemplate).
+				StatusHistory(testRevision).
 				StatusConfigurationRef(testConfigurationGiven.Create().GetName()).
 				StatusRouteRef(testRouteGiven.Create().GetName()).
 				StatusAddressURL(testAddressURL).
@@ -1183,6 +1354,267 @@ func TestDeployerReconciler(t *testing.T) {
 				Scheme:    scheme,
 				Tracker:   tracker,
 			},
+			testSystemNamespace,
+			func() time.Time {
+				return testDeployedTime.Time
+			},
+		)
+	})
+}
+
+func TestDeployerBuildRefReconciler(t *testing.T) {
+	testNamespace := "test-namespace"
+	testSystemNamespace := "riff-system"
+	testName := "test-deployer"
+	testImagePrefix := "example.com/repo"
+	testImage := fmt.Sprintf("%s@sha256:%s", testImagePrefix, "cf8b4c69d5460f88530e1c80b8856a70801f31c50b191c8413043ba9b160a43e")
+	testPreviousImage := fmt.Sprintf("%s@sha256:%s", testImagePrefix, "0ea9b4a5ee4ab2adc77e2e5c9a3e2bf44e61f9f4aff4ad5d6e8b6bac3f02a1f5")
+	testMirrorImage := fmt.Sprintf("%s@sha256:%s", "mirror.local/repo", "cf8b4c69d5460f88530e1c80b8856a70801f31c50b191c8413043ba9b160a43e")
+	testMirrorPreviousImage := fmt.Sprintf("%s@sha256:%s", "mirror.local/repo", "0ea9b4a5ee4ab2adc77e2e5c9a3e2bf44e61f9f4aff4ad5d6e8b6bac3f02a1f5")
+	testNow := time.Date(2020, 2, 3, 4, 5, 6, 0, time.UTC)
+	testPreviousTime := metav1.NewTime(time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC))
+	testPreviousRevision := knativev1alpha1.DeployerRevision{Image: testPreviousImage, DeployedTime: testPreviousTime, BuildGeneration: 1}
+
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = buildv1alpha1.AddToScheme(scheme)
+	_ = knativev1alpha1.AddToScheme(scheme)
+
+	testRegistryRewrites := factories.ConfigMap().
+		NamespaceName(testSystemNamespace, "riff-registry-rewrites")
+
+	testFunction := factories.Function().
+		NamespaceName(testNamespace, "my-function").
+		ObjectMeta(func(om factories.ObjectMeta) {
+			om.Generation(2)
+		}).
+		StatusLatestImage(testImage)
+
+	deployer := factories.DeployerKnative().
+		NamespaceName(testNamespace, testName).
+		FunctionRef(testFunction.Create().GetName())
+	deployerDeployed := deployer.
+		StatusLatestImage(testImage).
+		StatusImageSource(knativev1alpha1.DeployerImageSourceBuild).
+		StatusHistory(
+			knativev1alpha1.DeployerRevision{Image: testImage, DeployedTime: testPreviousTime, BuildGeneration: 2},
+			testPreviousRevision,
+		)
+	deployerPinned := deployer.
+		PinnedImage(testPreviousImage).
+		StatusLatestImage(testPreviousImage).
+		StatusImageSource(knativev1alpha1.DeployerImageSourcePinned).
+		StatusHistory(
+			knativev1alpha1.DeployerRevision{Image: testPreviousImage, DeployedTime: testPreviousTime, BuildGeneration: 1},
+			knativev1alpha1.DeployerRevision{Image: testImage, DeployedTime: testPreviousTime, BuildGeneration: 2},
+		)
+
+	table := rtesting.SubTable{{
+		Name: "record build image",
+		Parent: deployer.
+			StatusLatestImage(testPreviousImage).
+			StatusImageSource(knativev1alpha1.DeployerImageSourceBuild).
+			StatusHistory(testPreviousRevision),
+		GivenObjects: []rtesting.Factory{
+			testFunction,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployer, scheme),
+			rtesting.NewTrackRequest(testFunction, deployer, scheme),
+		},
+		ExpectParent: deployer.
+			StatusLatestImage(testImage).
+			StatusImageSource(knativev1alpha1.DeployerImageSourceBuild).
+			StatusHistory(
+				knativev1alpha1.DeployerRevision{Image: testImage, DeployedTime: metav1.NewTime(testNow), BuildGeneration: 2},
+				testPreviousRevision,
+			),
+	}, {
+		Name: "record build image, registry rewrite",
+		Parent: deployer.
+			StatusLatestImage(testPreviousImage).
+			StatusImageSource(knativev1alpha1.DeployerImageSourceBuild).
+			StatusHistory(testPreviousRevision),
+		GivenObjects: []rtesting.Factory{
+			testRegistryRewrites.
+				AddData("rewrites", fmt.Sprintf("%s -> mirror.local/repo", testImagePrefix)),
+			testFunction,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployer, scheme),
+			rtesting.NewTrackRequest(testFunction, deployer, scheme),
+		},
+		ExpectParent: deployer.
+			StatusLatestImage(testMirrorImage).
+			StatusImageSource(knativev1alpha1.DeployerImageSourceBuild).
+			StatusHistory(
+				knativev1alpha1.DeployerRevision{Image: testMirrorImage, DeployedTime: metav1.NewTime(testNow), BuildGeneration: 2},
+				testPreviousRevision,
+			),
+	}, {
+		Name: "record build image, invalid registry rewrites",
+		Parent: deployer.
+			StatusLatestImage(testPreviousImage).
+			StatusImageSource(knativev1alpha1.DeployerImageSourceBuild).
+			StatusHistory(testPreviousRevision),
+		GivenObjects: []rtesting.Factory{
+			testRegistryRewrites.
+				AddData("rewrites", "not a rule"),
+			testFunction,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployer, scheme),
+		},
+		ShouldErr: true,
+	}, {
+		Name: "record build image, registry rewrites get error",
+		Parent: deployer.
+			StatusLatestImage(testPreviousImage).
+			StatusImageSource(knativev1alpha1.DeployerImageSourceBuild).
+			StatusHistory(testPreviousRevision),
+		WithReactors: []rtesting.ReactionFunc{
+			rtesting.InduceFailure("get", "ConfigMap"),
+		},
+		GivenObjects: []rtesting.Factory{
+			testFunction,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployer, scheme),
+		},
+		ShouldErr: true,
+	}, {
+		Name:   "image already recorded",
+		Parent: deployerDeployed,
+		GivenObjects: []rtesting.Factory{
+			testFunction,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployer, scheme),
+			rtesting.NewTrackRequest(testFunction, deployer, scheme),
+		},
+		ExpectParent: deployerDeployed,
+	}, {
+		Name: "pin image",
+		Parent: deployerDeployed.
+			PinnedImage(testPreviousImage),
+		GivenObjects: []rtesting.Factory{
+			testFunction,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployer, scheme),
+			rtesting.NewTrackRequest(testFunction, deployer, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployer, scheme, corev1.EventTypeNormal, "Pinned",
+				`Pinned image "%s"`, testPreviousImage),
+		},
+		ExpectParent: deployerDeployed.
+			PinnedImage(testPreviousImage).
+			StatusLatestImage(testPreviousImage).
+			StatusImageSource(knativev1alpha1.DeployerImageSourcePinned).
+			StatusHistory(
+				knativev1alpha1.DeployerRevision{Image: testPreviousImage, DeployedTime: metav1.NewTime(testNow), BuildGeneration: 1},
+				knativev1alpha1.DeployerRevision{Image: testImage, DeployedTime: testPreviousTime, BuildGeneration: 2},
+			),
+	}, {
+		Name: "pin image, registry rewrite",
+		Parent: deployerDeployed.
+			PinnedImage(testPreviousImage),
+		GivenObjects: []rtesting.Factory{
+			testRegistryRewrites.
+				AddData("rewrites", fmt.Sprintf("%s -> mirror.local/repo", testImagePrefix)),
+			testFunction,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployer, scheme),
+			rtesting.NewTrackRequest(testFunction, deployer, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployer, scheme, corev1.EventTypeNormal, "Pinned",
+				`Pinned image "%s"`, testMirrorPreviousImage),
+		},
+		ExpectParent: deployerDeployed.
+			PinnedImage(testPreviousImage).
+			StatusLatestImage(testMirrorPreviousImage).
+			StatusImageSource(knativev1alpha1.DeployerImageSourcePinned).
+			StatusHistory(
+				knativev1alpha1.DeployerRevision{Image: testMirrorPreviousImage, DeployedTime: metav1.NewTime(testNow)},
+				knativev1alpha1.DeployerRevision{Image: testImage, DeployedTime: testPreviousTime, BuildGeneration: 2},
+				testPreviousRevision,
+			),
+	}, {
+		Name:   "pinned image unchanged",
+		Parent: deployerPinned,
+		GivenObjects: []rtesting.Factory{
+			testFunction,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployer, scheme),
+			rtesting.NewTrackRequest(testFunction, deployer, scheme),
+		},
+		ExpectParent: deployerPinned,
+	}, {
+		Name: "unpin image",
+		Parent: deployerPinned.
+			PinnedImage(""),
+		GivenObjects: []rtesting.Factory{
+			testFunction,
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployer, scheme),
+			rtesting.NewTrackRequest(testFunction, deployer, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(deployer, scheme, corev1.EventTypeNormal, "Unpinned",
+				`Unpinned image "%s"`, testPreviousImage),
+		},
+		ExpectParent: deployer.
+			StatusLatestImage(testImage).
+			StatusImageSource(knativev1alpha1.DeployerImageSourceBuild).
+			StatusHistory(
+				knativev1alpha1.DeployerRevision{Image: testImage, DeployedTime: metav1.NewTime(testNow), BuildGeneration: 2},
+				knativev1alpha1.DeployerRevision{Image: testPreviousImage, DeployedTime: testPreviousTime, BuildGeneration: 1},
+			),
+	}, {
+		Name: "unpin image, build has no image",
+		Parent: deployerPinned.
+			PinnedImage(""),
+		GivenObjects: []rtesting.Factory{
+			testFunction.
+				StatusLatestImage(""),
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployer, scheme),
+			rtesting.NewTrackRequest(testFunction, deployer, scheme),
+		},
+		// the pinned image keeps running until the build produces an image
+		ExpectParent: deployerPinned.
+			PinnedImage(""),
+	}, {
+		Name: "unpin image, build not found",
+		Parent: deployerPinned.
+			PinnedImage(""),
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(testRegistryRewrites, deployer, scheme),
+			rtesting.NewTrackRequest(testFunction, deployer, scheme),
+		},
+		ExpectParent: deployerPinned.
+			PinnedImage(""),
+	}}
+
+	table.Test(t, scheme, func(t *testing.T, row *rtesting.SubTestcase, client client.Client, tracker tracker.Tracker, recorder record.EventRecorder, log logr.Logger) controllers.SubReconciler {
+		return knative.DeployerBuildRefReconciler(
+			controllers.Config{
+				Client:   client,
+				Recorder: recorder,
+				Scheme:   scheme,
+				Log:      log,
+				Tracker:  tracker,
+			},
+			testSystemNamespace,
+			func() time.Time {
+				return testNow
+			},
 		)
 	})
 }
@@ -133,15 +133,37 @@ func (r *ParentReconciler) reconcile(ctx context.Context, parent apis.Object) (c
 		return ctrl.Result{}, nil
 	}
 
+	var aggregateResult ctrl.Result
 	for _, reconciler := range r.SubReconcilers {
-		if _, err := reconciler.Reconcile(ctx, parent); err != nil {
+		result, err := reconciler.Reconcile(ctx, parent)
+		if err != nil {
 			return ctrl.Result{}, err
 		}
+		aggregateResult = aggregateResults(aggregateResult, result)
 	}
 
 	r.copyGeneration(parent)
 
-	return ctrl.Result{}, nil
+	return aggregateResult, nil
+}
+
+// aggregateResults combines the results of multiple sub reconcilers, the
+// soonest requeue wins.
+func aggregateResults(r1, r2 ctrl.Result) ctrl.Result {
+	result := ctrl.Result{
+		Requeue: r1.Requeue || r2.Requeue,
+	}
+	switch {
+	case r1.RequeueAfter == 0:
+		result.RequeueAfter = r2.RequeueAfter
+	case r2.RequeueAfter == 0:
+		result.RequeueAfter = r1.RequeueAfter
+	case r1.RequeueAfter < r2.RequeueAfter:
+		result.RequeueAfter = r1.RequeueAfter
+	default:
+		result.RequeueAfter = r2.RequeueAfter
+	}
+	return result
 }
 
 func (r *ParentReconciler) copyGeneration(obj apis.Object) {
@@ -181,6 +203,7 @@ type SyncReconciler struct {
 	//
 	// Expected function signature:
 	//     func(ctx context.Context, parent apis.Object) error
+	//     func(ctx context.Context, parent apis.Object) (ctrl.Result, error)
 	Sync interface{}
 
 	Config
@@ -194,26 +217,30 @@ func (r *SyncReconciler) SetupWithManager(mgr ctrl.Manager, bldr *builder.Builde
 }
 
 func (r *SyncReconciler) Reconcile(ctx context.Context, parent apis.Object) (ctrl.Result, error) {
-	err := r.sync(ctx, parent)
+	result, err := r.sync(ctx, parent)
 	if err != nil {
 		r.Log.Error(err, "unable to sync", typeName(parent), parent)
 		return ctrl.Result{}, err
 	}
 
-	return ctrl.Result{}, nil
+	return result, nil
 }
 
-func (r *SyncReconciler) sync(ctx context.Context, parent apis.Object) error {
+func (r *SyncReconciler) sync(ctx context.Context, parent apis.Object) (ctrl.Result, error) {
 	fn := reflect.ValueOf(r.Sync)
 	out := fn.Call([]reflect.Value{
 		reflect.ValueOf(ctx),
 		reflect.ValueOf(parent),
 	})
+	var result ctrl.Result
+	if len(out) == 2 {
+		result = out[0].Interface().(ctrl.Result)
+	}
 	var err error
-	if !out[0].IsNil() {
-		err = out[0].Interface().(error)
+	if errVal := out[len(out)-1]; !errVal.IsNil() {
+		err = errVal.Interface().(error)
 	}
-	return err
+	return result, err
 }
 
 // ChildReconciler is a sub reconciler that manages a single child resource for
@@ -302,6 +329,16 @@ type ChildReconciler struct {
 	// +optional
 	Sanitize interface{}
 
+	// OurChild is used when there are multiple children of the same type
+	// controlled by the parent, selecting the child managed by this
+	// reconciler. Children that are not ours are never updated or deleted.
+	//
+	// Expected function signature:
+	//     func(parent, child apis.Object) bool
+	//
+	// +optional
+	OurChild interface{}
+
 	Config
 
 	// IndexField is used to index objects of the child's type based on their
@@ -355,7 +392,7 @@ func (r *ChildReconciler) reconcile(ctx context.Context, parent apis.Object) (ap
 		return nil, err
 	}
 	// TODO do we need to remove resources pending deletion?
-	items := r.items(children)
+	items := r.items(children, parent)
 	if len(items) == 1 {
 		actual = items[0]
 	} else if len(items) > 1 {
@@ -521,12 +558,33 @@ func (r *ChildReconciler) sanitize(child apis.Object) interface{} {
 	return sanitized
 }
 
-func (r *ChildReconciler) items(children runtime.Object) []apis.Object {
+func (r *ChildReconciler) ourChild(parent, child apis.Object) bool {
+	if r.OurChild == nil {
+		return true
+	}
+	fn := reflect.ValueOf(r.OurChild)
+	out := fn.Call([]reflect.Value{
+		reflect.ValueOf(parent),
+		reflect.ValueOf(child),
+	})
+	return out[0].Bool()
+}
+
+func (r *ChildReconciler) items(children runtime.Object, parent apis.Object) []apis.Object {
 	childrenValue := reflect.ValueOf(children).Elem()
 	itemsValue := childrenValue.FieldByName("Items")
-	items := make([]apis.Object, itemsValue.Len())
-	for i := range items {
-		items[i] = itemsValue.Index(i).Addr().Interface().(apis.Object)
+	items := []apis.Object{}
+	for i := 0; i < itemsValue.Len(); i++ {
+		item := itemsValue.Index(i).Addr().Interface().(apis.Object)
+		// the index selects children of the parent, but never touch objects
+		// the parent does not control
+		if !metav1.IsControlledBy(item, parent) {
+			continue
+		}
+		if !r.ourChild(parent, item) {
+			continue
+		}
+		items = append(items, item)
 	}
 	return items
 }
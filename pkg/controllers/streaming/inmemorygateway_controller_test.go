@@ -63,6 +63,8 @@ func TestInMemoryGatewayReconciler(t *testing.T) {
 	_ = clientgoscheme.AddToScheme(scheme)
 	_ = streamingv1alpha1.AddToScheme(scheme)
 
+	registryRewritesConfigMap := factories.ConfigMap().
+		NamespaceName(testSystemNamespace, "riff-registry-rewrites")
 	inMemoryGatewayImagesConfigMap := factories.ConfigMap().
 		NamespaceName(testSystemNamespace, inmemoryGatewayImages).
 		AddData(gatewayImageKey, testGatewayImage).
@@ -152,6 +154,7 @@ func TestInMemoryGatewayReconciler(t *testing.T) {
 		},
 		ExpectTracks: []rtesting.TrackRequest{
 			rtesting.NewTrackRequest(inMemoryGatewayImagesConfigMap, inMemoryGateway, scheme),
+			rtesting.NewTrackRequest(registryRewritesConfigMap, inMemoryGateway, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
 			rtesting.NewEvent(inMemoryGateway, scheme, corev1.EventTypeNormal, "Created",
@@ -180,6 +183,7 @@ func TestInMemoryGatewayReconciler(t *testing.T) {
 		},
 		ExpectTracks: []rtesting.TrackRequest{
 			rtesting.NewTrackRequest(inMemoryGatewayImagesConfigMap, inMemoryGateway, scheme),
+			rtesting.NewTrackRequest(registryRewritesConfigMap, inMemoryGateway, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
 			rtesting.NewEvent(inMemoryGateway, scheme, corev1.EventTypeNormal, "StatusUpdated",
@@ -205,6 +209,7 @@ func TestInMemoryGatewayReconciler(t *testing.T) {
 		},
 		ExpectTracks: []rtesting.TrackRequest{
 			rtesting.NewTrackRequest(inMemoryGatewayImagesConfigMap, inMemoryGateway, scheme),
+			rtesting.NewTrackRequest(registryRewritesConfigMap, inMemoryGateway, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
 			rtesting.NewEvent(inMemoryGateway, scheme, corev1.EventTypeNormal, "Updated",
@@ -236,6 +241,7 @@ func TestInMemoryGatewayReconciler(t *testing.T) {
 		},
 		ExpectTracks: []rtesting.TrackRequest{
 			rtesting.NewTrackRequest(inMemoryGatewayImagesConfigMap, inMemoryGateway, scheme),
+			rtesting.NewTrackRequest(registryRewritesConfigMap, inMemoryGateway, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
 			rtesting.NewEvent(inMemoryGateway, scheme, corev1.EventTypeNormal, "StatusUpdated",
@@ -264,6 +270,7 @@ func TestInMemoryGatewayReconciler(t *testing.T) {
 		},
 		ExpectTracks: []rtesting.TrackRequest{
 			rtesting.NewTrackRequest(inMemoryGatewayImagesConfigMap, inMemoryGateway, scheme),
+			rtesting.NewTrackRequest(registryRewritesConfigMap, inMemoryGateway, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
 			rtesting.NewEvent(inMemoryGateway, scheme, corev1.EventTypeNormal, "StatusUpdated",
@@ -287,6 +294,7 @@ func TestInMemoryGatewayReconciler(t *testing.T) {
 		ShouldErr: true,
 		ExpectTracks: []rtesting.TrackRequest{
 			rtesting.NewTrackRequest(inMemoryGatewayImagesConfigMap, inMemoryGateway, scheme),
+			rtesting.NewTrackRequest(registryRewritesConfigMap, inMemoryGateway, scheme),
 		},
 	}, {
 		Name: "invalid address",
@@ -301,6 +309,7 @@ func TestInMemoryGatewayReconciler(t *testing.T) {
 		ShouldErr: true,
 		ExpectTracks: []rtesting.TrackRequest{
 			rtesting.NewTrackRequest(inMemoryGatewayImagesConfigMap, inMemoryGateway, scheme),
+			rtesting.NewTrackRequest(registryRewritesConfigMap, inMemoryGateway, scheme),
 		},
 	}, {
 		Name: "conflicting gateway",
@@ -316,6 +325,7 @@ func TestInMemoryGatewayReconciler(t *testing.T) {
 		},
 		ExpectTracks: []rtesting.TrackRequest{
 			rtesting.NewTrackRequest(inMemoryGatewayImagesConfigMap, inMemoryGateway, scheme),
+			rtesting.NewTrackRequest(registryRewritesConfigMap, inMemoryGateway, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
 			rtesting.NewEvent(inMemoryGateway, scheme, corev1.EventTypeWarning, "CreationFailed",
@@ -354,6 +364,7 @@ func TestInMemoryGatewayReconciler(t *testing.T) {
 		ShouldErr: true,
 		ExpectTracks: []rtesting.TrackRequest{
 			rtesting.NewTrackRequest(inMemoryGatewayImagesConfigMap, inMemoryGateway, scheme),
+			rtesting.NewTrackRequest(registryRewritesConfigMap, inMemoryGateway, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
 			rtesting.NewEvent(inMemoryGateway, scheme, corev1.EventTypeWarning, "CreationFailed",
@@ -64,6 +64,8 @@ func TestKafkaGatewayReconciler(t *testing.T) {
 	_ = clientgoscheme.AddToScheme(scheme)
 	_ = streamingv1alpha1.AddToScheme(scheme)
 
+	registryRewritesConfigMap := factories.ConfigMap().
+		NamespaceName(testSystemNamespace, "riff-registry-rewrites")
 	kafkaGatewayImagesConfigMap := factories.ConfigMap().
 		NamespaceName(testSystemNamespace, kafkaGatewayImages).
 		AddData(gatewayImageKey, testGatewayImage).
@@ -156,6 +158,7 @@ func TestKafkaGatewayReconciler(t *testing.T) {
 		},
 		ExpectTracks: []rtesting.TrackRequest{
 			rtesting.NewTrackRequest(kafkaGatewayImagesConfigMap, kafkaGateway, scheme),
+			rtesting.NewTrackRequest(registryRewritesConfigMap, kafkaGateway, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
 			rtesting.NewEvent(kafkaGateway, scheme, corev1.EventTypeNormal, "Created",
@@ -174,6 +177,38 @@ func TestKafkaGatewayReconciler(t *testing.T) {
 					kafkaGatewayConditionReady.Unknown(),
 				),
 		},
+	}, {
+		Name: "creates gateway, registry rewrite",
+		Key:  testKey,
+		GivenObjects: []rtesting.Factory{
+			kafkaGatewayMinimal,
+			kafkaGatewayImagesConfigMap,
+			registryRewritesConfigMap.
+				AddData("rewrites", fmt.Sprintf("%s -> mirror.local/repo", testImagePrefix)),
+		},
+		ExpectTracks: []rtesting.TrackRequest{
+			rtesting.NewTrackRequest(kafkaGatewayImagesConfigMap, kafkaGateway, scheme),
+			rtesting.NewTrackRequest(registryRewritesConfigMap, kafkaGateway, scheme),
+		},
+		ExpectEvents: []rtesting.Event{
+			rtesting.NewEvent(kafkaGateway, scheme, corev1.EventTypeNormal, "Created",
+				`Created Gateway "%s"`, testName),
+			rtesting.NewEvent(kafkaGateway, scheme, corev1.EventTypeNormal, "StatusUpdated",
+				`Updated status`),
+		},
+		ExpectCreates: []rtesting.Factory{
+			gatewayCreate,
+		},
+		ExpectStatusUpdates: []rtesting.Factory{
+			kafkaGateway.
+				StatusGatewayImage("mirror.local/repo/gateway").
+				StatusProvisionerImage("mirror.local/repo/provisioner").
+				StatusObservedGeneration(1).
+				StatusConditions(
+					kafkaGatewayConditionGatewayReady.Unknown(),
+					kafkaGatewayConditionReady.Unknown(),
+				),
+		},
 	}, {
 		Name: "propagate address",
 		Key:  testKey,
@@ -184,6 +219,7 @@ func TestKafkaGatewayReconciler(t *testing.T) {
 		},
 		ExpectTracks: []rtesting.TrackRequest{
 			rtesting.NewTrackRequest(kafkaGatewayImagesConfigMap, kafkaGateway, scheme),
+			rtesting.NewTrackRequest(registryRewritesConfigMap, kafkaGateway, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
 			rtesting.NewEvent(kafkaGateway, scheme, corev1.EventTypeNormal, "StatusUpdated",
@@ -209,6 +245,7 @@ func TestKafkaGatewayReconciler(t *testing.T) {
 		},
 		ExpectTracks: []rtesting.TrackRequest{
 			rtesting.NewTrackRequest(kafkaGatewayImagesConfigMap, kafkaGateway, scheme),
+			rtesting.NewTrackRequest(registryRewritesConfigMap, kafkaGateway, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
 			rtesting.NewEvent(kafkaGateway, scheme, corev1.EventTypeNormal, "Updated",
@@ -240,6 +277,7 @@ func TestKafkaGatewayReconciler(t *testing.T) {
 		},
 		ExpectTracks: []rtesting.TrackRequest{
 			rtesting.NewTrackRequest(kafkaGatewayImagesConfigMap, kafkaGateway, scheme),
+			rtesting.NewTrackRequest(registryRewritesConfigMap, kafkaGateway, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
 			rtesting.NewEvent(kafkaGateway, scheme, corev1.EventTypeNormal, "StatusUpdated",
@@ -268,6 +306,7 @@ func TestKafkaGatewayReconciler(t *testing.T) {
 		},
 		ExpectTracks: []rtesting.TrackRequest{
 			rtesting.NewTrackRequest(kafkaGatewayImagesConfigMap, kafkaGateway, scheme),
+			rtesting.NewTrackRequest(registryRewritesConfigMap, kafkaGateway, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
 			rtesting.NewEvent(kafkaGateway, scheme, corev1.EventTypeNormal, "StatusUpdated",
@@ -291,6 +330,7 @@ func TestKafkaGatewayReconciler(t *testing.T) {
 		ShouldErr: true,
 		ExpectTracks: []rtesting.TrackRequest{
 			rtesting.NewTrackRequest(kafkaGatewayImagesConfigMap, kafkaGateway, scheme),
+			rtesting.NewTrackRequest(registryRewritesConfigMap, kafkaGateway, scheme),
 		},
 	}, {
 		Name: "invalid address",
@@ -305,6 +345,7 @@ func TestKafkaGatewayReconciler(t *testing.T) {
 		ShouldErr: true,
 		ExpectTracks: []rtesting.TrackRequest{
 			rtesting.NewTrackRequest(kafkaGatewayImagesConfigMap, kafkaGateway, scheme),
+			rtesting.NewTrackRequest(registryRewritesConfigMap, kafkaGateway, scheme),
 		},
 	}, {
 		Name: "conflicting gateway",
@@ -320,6 +361,7 @@ func TestKafkaGatewayReconciler(t *testing.T) {
 		},
 		ExpectTracks: []rtesting.TrackRequest{
 			rtesting.NewTrackRequest(kafkaGatewayImagesConfigMap, kafkaGateway, scheme),
+			rtesting.NewTrackRequest(registryRewritesConfigMap, kafkaGateway, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
 			rtesting.NewEvent(kafkaGateway, scheme, corev1.EventTypeWarning, "CreationFailed",
@@ -358,6 +400,7 @@ func TestKafkaGatewayReconciler(t *testing.T) {
 		ShouldErr: true,
 		ExpectTracks: []rtesting.TrackRequest{
 			rtesting.NewTrackRequest(kafkaGatewayImagesConfigMap, kafkaGateway, scheme),
+			rtesting.NewTrackRequest(registryRewritesConfigMap, kafkaGateway, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
 			rtesting.NewEvent(kafkaGateway, scheme, corev1.EventTypeWarning, "CreationFailed",
@@ -38,6 +38,7 @@ import (
 	kedav1alpha1 "github.com/projectriff/system/pkg/apis/thirdparty/keda/v1alpha1"
 	"github.com/projectriff/system/pkg/controllers"
 	"github.com/projectriff/system/pkg/refs"
+	"github.com/projectriff/system/pkg/registries"
 	"github.com/projectriff/system/pkg/tracker"
 )
 
@@ -90,10 +91,20 @@ func ProcessorSyncProcessorImages(c controllers.Config, namespace string) contro
 				tracker.NewKey(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, key),
 				types.NamespacedName{Namespace: processor.Namespace, Name: processor.Name},
 			)
+			// track registry rewrite rules for new images
+			registries.Track(c.Tracker, namespace, processor)
 			if err := c.Get(ctx, key, &config); err != nil {
 				return err
 			}
-			controllers.StashValue(ctx, ProcessorImagesStashKey, config.Data)
+			rewriter, err := registries.Load(ctx, c, namespace)
+			if err != nil {
+				return err
+			}
+			images := make(map[string]string, len(config.Data))
+			for k, v := range config.Data {
+				images[k] = rewriter.Rewrite(v)
+			}
+			controllers.StashValue(ctx, ProcessorImagesStashKey, images)
 			return nil
 		},
 
@@ -59,6 +59,8 @@ func TestProcessorReconciler(t *testing.T) {
 	processorImages := "riff-streaming-processor"
 	processorImageKey := "processorImage"
 
+	registryRewritesConfigMap := factories.ConfigMap().
+		NamespaceName(testSystemNamespace, "riff-registry-rewrites")
 	processorImagesConfigMap := factories.ConfigMap().
 		NamespaceName(testSystemNamespace, processorImages).
 		AddData(processorImageKey, testProcessorImage)
@@ -210,6 +212,7 @@ func TestProcessorReconciler(t *testing.T) {
 			},
 			ExpectTracks: []rtesting.TrackRequest{
 				rtesting.NewTrackRequest(processorImagesConfigMap, processor, scheme),
+				rtesting.NewTrackRequest(registryRewritesConfigMap, processor, scheme),
 			},
 			ExpectEvents: []rtesting.Event{
 				rtesting.NewEvent(processor, scheme, corev1.EventTypeNormal, "Created",
@@ -254,6 +257,7 @@ func TestProcessorReconciler(t *testing.T) {
 			},
 			ExpectTracks: []rtesting.TrackRequest{
 				rtesting.NewTrackRequest(processorImagesConfigMap, processor, scheme),
+				rtesting.NewTrackRequest(registryRewritesConfigMap, processor, scheme),
 			},
 			ExpectEvents: []rtesting.Event{
 				rtesting.NewEvent(processor, scheme, corev1.EventTypeNormal, "StatusUpdated",
@@ -296,6 +300,7 @@ func TestProcessorReconciler(t *testing.T) {
 				Parent: processor,
 				ExpectTracks: []rtesting.TrackRequest{
 					rtesting.NewTrackRequest(processorImagesConfigMap, processor, scheme),
+					rtesting.NewTrackRequest(registryRewritesConfigMap, processor, scheme),
 				},
 				ShouldErr: true,
 			},
@@ -307,6 +312,7 @@ func TestProcessorReconciler(t *testing.T) {
 				},
 				ExpectTracks: []rtesting.TrackRequest{
 					rtesting.NewTrackRequest(processorImagesConfigMap, processor, scheme),
+					rtesting.NewTrackRequest(registryRewritesConfigMap, processor, scheme),
 				},
 				ExpectStashedValues: map[controllers.StashKey]interface{}{
 					streaming.ProcessorImagesStashKey: processorImagesConfigMap.Create().Data,
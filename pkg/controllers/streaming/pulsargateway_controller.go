@@ -32,6 +32,7 @@ import (
 	streamingv1alpha1 "github.com/projectriff/system/pkg/apis/streaming/v1alpha1"
 	"github.com/projectriff/system/pkg/controllers"
 	"github.com/projectriff/system/pkg/refs"
+	"github.com/projectriff/system/pkg/registries"
 	"github.com/projectriff/system/pkg/tracker"
 )
 
@@ -67,11 +68,17 @@ func PulsarGatewaySyncConfigReconciler(c controllers.Config, namespace string) c
 				tracker.NewKey(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, key),
 				types.NamespacedName{Namespace: parent.Namespace, Name: parent.Name},
 			)
+			// track registry rewrite rules for new images
+			registries.Track(c.Tracker, namespace, parent)
 			if err := c.Get(ctx, key, &config); err != nil {
 				return err
 			}
-			parent.Status.GatewayImage = config.Data[gatewayImageKey]
-			parent.Status.ProvisionerImage = config.Data[provisionerImageKey]
+			rewriter, err := registries.Load(ctx, c, namespace)
+			if err != nil {
+				return err
+			}
+			parent.Status.GatewayImage = rewriter.Rewrite(config.Data[gatewayImageKey])
+			parent.Status.ProvisionerImage = rewriter.Rewrite(config.Data[provisionerImageKey])
 			return nil
 		},
 
@@ -64,6 +64,8 @@ func TestPulsarGatewayReconciler(t *testing.T) {
 	_ = clientgoscheme.AddToScheme(scheme)
 	_ = streamingv1alpha1.AddToScheme(scheme)
 
+	registryRewritesConfigMap := factories.ConfigMap().
+		NamespaceName(testSystemNamespace, "riff-registry-rewrites")
 	pulsarGatewayImagesConfigMap := factories.ConfigMap().
 		NamespaceName(testSystemNamespace, pulsarGatewayImages).
 		AddData(gatewayImageKey, testGatewayImage).
@@ -158,6 +160,7 @@ func TestPulsarGatewayReconciler(t *testing.T) {
 		},
 		ExpectTracks: []rtesting.TrackRequest{
 			rtesting.NewTrackRequest(pulsarGatewayImagesConfigMap, pulsarGateway, scheme),
+			rtesting.NewTrackRequest(registryRewritesConfigMap, pulsarGateway, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
 			rtesting.NewEvent(pulsarGateway, scheme, corev1.EventTypeNormal, "Created",
@@ -186,6 +189,7 @@ func TestPulsarGatewayReconciler(t *testing.T) {
 		},
 		ExpectTracks: []rtesting.TrackRequest{
 			rtesting.NewTrackRequest(pulsarGatewayImagesConfigMap, pulsarGateway, scheme),
+			rtesting.NewTrackRequest(registryRewritesConfigMap, pulsarGateway, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
 			rtesting.NewEvent(pulsarGateway, scheme, corev1.EventTypeNormal, "StatusUpdated",
@@ -211,6 +215,7 @@ func TestPulsarGatewayReconciler(t *testing.T) {
 		},
 		ExpectTracks: []rtesting.TrackRequest{
 			rtesting.NewTrackRequest(pulsarGatewayImagesConfigMap, pulsarGateway, scheme),
+			rtesting.NewTrackRequest(registryRewritesConfigMap, pulsarGateway, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
 			rtesting.NewEvent(pulsarGateway, scheme, corev1.EventTypeNormal, "Updated",
@@ -242,6 +247,7 @@ func TestPulsarGatewayReconciler(t *testing.T) {
 		},
 		ExpectTracks: []rtesting.TrackRequest{
 			rtesting.NewTrackRequest(pulsarGatewayImagesConfigMap, pulsarGateway, scheme),
+			rtesting.NewTrackRequest(registryRewritesConfigMap, pulsarGateway, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
 			rtesting.NewEvent(pulsarGateway, scheme, corev1.EventTypeNormal, "StatusUpdated",
@@ -270,6 +276,7 @@ func TestPulsarGatewayReconciler(t *testing.T) {
 		},
 		ExpectTracks: []rtesting.TrackRequest{
 			rtesting.NewTrackRequest(pulsarGatewayImagesConfigMap, pulsarGateway, scheme),
+			rtesting.NewTrackRequest(registryRewritesConfigMap, pulsarGateway, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
 			rtesting.NewEvent(pulsarGateway, scheme, corev1.EventTypeNormal, "StatusUpdated",
@@ -293,6 +300,7 @@ func TestPulsarGatewayReconciler(t *testing.T) {
 		ShouldErr: true,
 		ExpectTracks: []rtesting.TrackRequest{
 			rtesting.NewTrackRequest(pulsarGatewayImagesConfigMap, pulsarGateway, scheme),
+			rtesting.NewTrackRequest(registryRewritesConfigMap, pulsarGateway, scheme),
 		},
 	}, {
 		Name: "invalid address",
@@ -307,6 +315,7 @@ func TestPulsarGatewayReconciler(t *testing.T) {
 		ShouldErr: true,
 		ExpectTracks: []rtesting.TrackRequest{
 			rtesting.NewTrackRequest(pulsarGatewayImagesConfigMap, pulsarGateway, scheme),
+			rtesting.NewTrackRequest(registryRewritesConfigMap, pulsarGateway, scheme),
 		},
 	}, {
 		Name: "conflicting gateway",
@@ -322,6 +331,7 @@ func TestPulsarGatewayReconciler(t *testing.T) {
 		},
 		ExpectTracks: []rtesting.TrackRequest{
 			rtesting.NewTrackRequest(pulsarGatewayImagesConfigMap, pulsarGateway, scheme),
+			rtesting.NewTrackRequest(registryRewritesConfigMap, pulsarGateway, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
 			rtesting.NewEvent(pulsarGateway, scheme, corev1.EventTypeWarning, "CreationFailed",
@@ -360,6 +370,7 @@ func TestPulsarGatewayReconciler(t *testing.T) {
 		ShouldErr: true,
 		ExpectTracks: []rtesting.TrackRequest{
 			rtesting.NewTrackRequest(pulsarGatewayImagesConfigMap, pulsarGateway, scheme),
+			rtesting.NewTrackRequest(registryRewritesConfigMap, pulsarGateway, scheme),
 		},
 		ExpectEvents: []rtesting.Event{
 			rtesting.NewEvent(pulsarGateway, scheme, corev1.EventTypeWarning, "CreationFailed",
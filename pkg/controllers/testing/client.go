@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -36,6 +37,7 @@ type clientWrapper struct {
 	createActions       []objectAction
 	updateActions       []objectAction
 	deleteActions       []DeleteAction
+	patchActions        []PatchAction
 	statusUpdateActions []objectAction
 	genCount            int
 	reactionChain       []Reactor
@@ -50,6 +52,7 @@ func newClientWrapperWithScheme(scheme *runtime.Scheme, objs ...runtime.Object)
 		createActions:       []objectAction{},
 		updateActions:       []objectAction{},
 		deleteActions:       []DeleteAction{},
+		patchActions:        []PatchAction{},
 		statusUpdateActions: []objectAction{},
 		genCount:            0,
 		reactionChain:       []Reactor{},
@@ -94,8 +97,8 @@ func (w *clientWrapper) objmeta(obj runtime.Object) (schema.GroupVersionResource
 		Resource: gvk.Kind,
 	}
 
-	if objmeta, ok := obj.(metav1.ObjectMetaAccessor); ok {
-		return gvr, objmeta.GetObjectMeta().GetNamespace(), objmeta.GetObjectMeta().GetName(), nil
+	if objmeta, err := meta.Accessor(obj); err == nil {
+		return gvr, objmeta.GetNamespace(), objmeta.GetName(), nil
 	}
 	if _, ok := obj.(metav1.ListMetaAccessor); ok {
 		return gvr, "", "", nil
@@ -210,8 +213,27 @@ func (w *clientWrapper) Update(ctx context.Context, obj runtime.Object, opts ...
 
 	return w.client.Update(ctx, obj, opts...)
 }
+
 func (w *clientWrapper) Patch(ctx context.Context, obj runtime.Object, patch client.Patch, opts ...client.PatchOption) error {
-	panic(fmt.Errorf("Patch() is not implemented"))
+	gvr, namespace, name, err := w.objmeta(obj)
+	if err != nil {
+		return err
+	}
+	data, err := patch.Data(obj)
+	if err != nil {
+		return err
+	}
+
+	// capture action
+	w.patchActions = append(w.patchActions, clientgotesting.NewPatchAction(gvr, namespace, name, patch.Type(), data))
+
+	// call reactor chain
+	err = w.react(clientgotesting.NewPatchAction(gvr, namespace, name, patch.Type(), data))
+	if err != nil {
+		return err
+	}
+
+	return w.client.Patch(ctx, obj, patch, opts...)
 }
 
 func (w *clientWrapper) DeleteAllOf(ctx context.Context, obj runtime.Object, opts ...client.DeleteAllOfOption) error {
@@ -104,6 +104,14 @@ func (f *adapterKnative) FunctionRef(format string, a ...interface{}) *adapterKn
 	})
 }
 
+func (f *adapterKnative) ImagePromotionRef(format string, a ...interface{}) *adapterKnative {
+	return f.mutation(func(adapter *knativev1alpha1.Adapter) {
+		adapter.Spec.Build = knativev1alpha1.Build{
+			ImagePromotionRef: fmt.Sprintf(format, a...),
+		}
+	})
+}
+
 func (f *adapterKnative) ConfigurationRef(format string, a ...interface{}) *adapterKnative {
 	return f.mutation(func(adapter *knativev1alpha1.Adapter) {
 		adapter.Spec.Target = knativev1alpha1.AdapterTarget{
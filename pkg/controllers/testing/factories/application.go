@@ -19,7 +19,9 @@ package factories
 import (
 	"fmt"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 
 	"github.com/projectriff/system/pkg/apis"
 	buildv1alpha1 "github.com/projectriff/system/pkg/apis/build/v1alpha1"
@@ -123,6 +125,57 @@ func (f *application) BuildCache(quantity string) *application {
 	})
 }
 
+func (f *application) BuildCachePolicy(policy *buildv1alpha1.BuildCachePolicy) *application {
+	return f.mutation(func(app *buildv1alpha1.Application) {
+		app.Spec.Cache = policy
+	})
+}
+
+func (f *application) Builder(kind, name string) *application {
+	return f.mutation(func(app *buildv1alpha1.Application) {
+		app.Spec.Builder = &buildv1alpha1.BuilderReference{
+			Kind: kind,
+			Name: name,
+		}
+	})
+}
+
+func (f *application) RebuildPolicy(policy buildv1alpha1.RebuildPolicy) *application {
+	return f.mutation(func(app *buildv1alpha1.Application) {
+		app.Spec.RebuildPolicy = policy
+	})
+}
+
+func (f *application) SourceBlob(url string) *application {
+	return f.mutation(func(app *buildv1alpha1.Application) {
+		app.Spec.Source = &buildv1alpha1.Source{
+			Blob: &buildv1alpha1.Blob{
+				URL: url,
+			},
+		}
+	})
+}
+
+func (f *application) Dockerfile(path string) *application {
+	return f.mutation(func(app *buildv1alpha1.Application) {
+		app.Spec.Dockerfile = &buildv1alpha1.DockerfileBuild{
+			Path: path,
+		}
+	})
+}
+
+func (f *application) ServiceAccountName(name string) *application {
+	return f.mutation(func(app *buildv1alpha1.Application) {
+		app.Spec.ServiceAccountName = name
+	})
+}
+
+func (f *application) BuildEnv(env ...corev1.EnvVar) *application {
+	return f.mutation(func(app *buildv1alpha1.Application) {
+		app.Spec.Build.Env = append(app.Spec.Build.Env, env...)
+	})
+}
+
 func (f *application) StatusConditions(conditions ...*condition) *application {
 	return f.mutation(func(app *buildv1alpha1.Application) {
 		c := make([]apis.Condition, len(conditions))
@@ -164,6 +217,12 @@ func (f *application) StatusBuildCacheRef(format string, a ...interface{}) *appl
 	})
 }
 
+func (f *application) StatusBuildCache(cache *buildv1alpha1.BuildCacheStatus) *application {
+	return f.mutation(func(app *buildv1alpha1.Application) {
+		app.Status.BuildCache = cache
+	})
+}
+
 func (f *application) StatusTargetImage(format string, a ...interface{}) *application {
 	return f.mutation(func(app *buildv1alpha1.Application) {
 		app.Status.TargetImage = fmt.Sprintf(format, a...)
@@ -175,3 +234,49 @@ func (f *application) StatusLatestImage(format string, a ...interface{}) *applic
 		app.Status.LatestImage = fmt.Sprintf(format, a...)
 	})
 }
+
+func (f *application) StatusBuilder(kind, name, image string) *application {
+	return f.mutation(func(app *buildv1alpha1.Application) {
+		app.Status.BuilderRef = refs.NewTypedLocalObjectReference(name, schema.GroupKind{Group: "build.pivotal.io", Kind: kind})
+		app.Status.BuilderImage = image
+	})
+}
+
+func (f *application) StatusBuilderImage(format string, a ...interface{}) *application {
+	return f.mutation(func(app *buildv1alpha1.Application) {
+		app.Status.BuilderImage = fmt.Sprintf(format, a...)
+	})
+}
+
+func (f *application) StatusDockerfileJobRef(format string, a ...interface{}) *application {
+	return f.mutation(func(app *buildv1alpha1.Application) {
+		app.Status.DockerfileJobRef = &refs.TypedLocalObjectReference{
+			APIGroup: rtesting.StringPtr("batch"),
+			Kind:     "Job",
+			Name:     fmt.Sprintf(format, a...),
+		}
+	})
+}
+
+func (f *application) StatusDockerfileSourceResolver(revision string, format string, a ...interface{}) *application {
+	return f.mutation(func(app *buildv1alpha1.Application) {
+		app.Status.DockerfileSourceResolverRef = &refs.TypedLocalObjectReference{
+			APIGroup: rtesting.StringPtr("build.pivotal.io"),
+			Kind:     "SourceResolver",
+			Name:     fmt.Sprintf(format, a...),
+		}
+		app.Status.DockerfileSourceRevision = revision
+	})
+}
+
+func (f *application) StatusBuilds(builds ...buildv1alpha1.BuildSummary) *application {
+	return f.mutation(func(app *buildv1alpha1.Application) {
+		app.Status.Builds = builds
+	})
+}
+
+func (f *application) StatusBuildMetadata(metadata *buildv1alpha1.BuildMetadata) *application {
+	return f.mutation(func(app *buildv1alpha1.Application) {
+		app.Status.BuildMetadata = metadata
+	})
+}
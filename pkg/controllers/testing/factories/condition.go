@@ -20,6 +20,7 @@ import (
 	"fmt"
 
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/projectriff/system/pkg/apis"
 )
@@ -90,6 +91,12 @@ func (f *condition) Reason(reason, message string) *condition {
 	})
 }
 
+func (f *condition) LastTransitionTime(sec int64) *condition {
+	return f.mutation(func(c *apis.Condition) {
+		c.LastTransitionTime = apis.VolatileTime{Inner: metav1.Unix(sec, 0)}
+	})
+}
+
 func (f *condition) Info() *condition {
 	return f.mutation(func(c *apis.Condition) {
 		c.Severity = apis.ConditionSeverityInfo
@@ -86,6 +86,22 @@ func (f *container) Image(format string, a ...interface{}) *container {
 	})
 }
 
+func (f *container) ServiceAccountName(name string) *container {
+	return f.mutation(func(con *buildv1alpha1.Container) {
+		con.Spec.ServiceAccountName = name
+	})
+}
+
+func (f *container) Platform(os, architecture, variant string) *container {
+	return f.mutation(func(con *buildv1alpha1.Container) {
+		con.Spec.Platform = &buildv1alpha1.ImagePlatform{
+			OS:           os,
+			Architecture: architecture,
+			Variant:      variant,
+		}
+	})
+}
+
 func (f *container) StatusConditions(conditions ...*condition) *container {
 	return f.mutation(func(con *buildv1alpha1.Container) {
 		c := make([]apis.Condition, len(conditions))
@@ -119,3 +135,9 @@ func (f *container) StatusLatestImage(format string, a ...interface{}) *containe
 		con.Status.LatestImage = fmt.Sprintf(format, a...)
 	})
 }
+
+func (f *container) StatusPlatforms(platforms ...buildv1alpha1.PlatformImage) *container {
+	return f.mutation(func(con *buildv1alpha1.Container) {
+		con.Status.Platforms = platforms
+	})
+}
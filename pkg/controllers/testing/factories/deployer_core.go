@@ -125,18 +125,97 @@ func (f *deployerCore) FunctionRef(format string, a ...interface{}) *deployerCor
 	})
 }
 
+func (f *deployerCore) ImagePromotionRef(format string, a ...interface{}) *deployerCore {
+	return f.mutation(func(deployer *corev1alpha1.Deployer) {
+		deployer.Spec.Build = &corev1alpha1.Build{
+			ImagePromotionRef: fmt.Sprintf(format, a...),
+		}
+	})
+}
+
 func (f *deployerCore) Image(format string, a ...interface{}) *deployerCore {
 	return f.HandlerContainer(func(container *corev1.Container) {
 		container.Image = fmt.Sprintf(format, a...)
 	})
 }
 
+func (f *deployerCore) Probes(probes *corev1alpha1.Probes) *deployerCore {
+	return f.mutation(func(deployer *corev1alpha1.Deployer) {
+		deployer.Spec.Probes = probes
+	})
+}
+
+func (f *deployerCore) MinReadySeconds(seconds int32) *deployerCore {
+	return f.mutation(func(deployer *corev1alpha1.Deployer) {
+		deployer.Spec.MinReadySeconds = seconds
+	})
+}
+
+func (f *deployerCore) ProgressDeadlineSeconds(seconds int32) *deployerCore {
+	return f.mutation(func(deployer *corev1alpha1.Deployer) {
+		deployer.Spec.ProgressDeadlineSeconds = rtesting.Int32Ptr(seconds)
+	})
+}
+
+func (f *deployerCore) Disruption(disruption *corev1alpha1.Disruption) *deployerCore {
+	return f.mutation(func(deployer *corev1alpha1.Deployer) {
+		deployer.Spec.Disruption = disruption
+	})
+}
+
+func (f *deployerCore) NetworkPolicy(networkPolicy *corev1alpha1.NetworkPolicy) *deployerCore {
+	return f.mutation(func(deployer *corev1alpha1.Deployer) {
+		deployer.Spec.NetworkPolicy = networkPolicy
+	})
+}
+
+func (f *deployerCore) AddBinding(name string, ref corev1alpha1.BindableReference) *deployerCore {
+	return f.mutation(func(deployer *corev1alpha1.Deployer) {
+		deployer.Spec.Bindings = append(deployer.Spec.Bindings, corev1alpha1.DeployerBinding{
+			Name: name,
+			Ref:  ref,
+		})
+	})
+}
+
+func (f *deployerCore) PinnedImage(format string, a ...interface{}) *deployerCore {
+	return f.mutation(func(deployer *corev1alpha1.Deployer) {
+		deployer.Spec.PinnedImage = fmt.Sprintf(format, a...)
+	})
+}
+
 func (f *deployerCore) IngressPolicy(policy corev1alpha1.IngressPolicy) *deployerCore {
 	return f.mutation(func(deployer *corev1alpha1.Deployer) {
 		deployer.Spec.IngressPolicy = policy
 	})
 }
 
+func (f *deployerCore) Ingress(ingress *corev1alpha1.Ingress) *deployerCore {
+	return f.mutation(func(deployer *corev1alpha1.Deployer) {
+		deployer.Spec.Ingress = ingress
+	})
+}
+
+func (f *deployerCore) TLS(secretRef string) *deployerCore {
+	return f.mutation(func(deployer *corev1alpha1.Deployer) {
+		deployer.Spec.TLS = &corev1alpha1.TLS{
+			SecretRef: secretRef,
+		}
+	})
+}
+
+func (f *deployerCore) Rollout(rollout *corev1alpha1.Rollout) *deployerCore {
+	return f.mutation(func(deployer *corev1alpha1.Deployer) {
+		deployer.Spec.Rollout = rollout
+	})
+}
+
+func (f *deployerCore) Scale(scale *corev1alpha1.Scale) *deployerCore {
+	return f.mutation(func(deployer *corev1alpha1.Deployer) {
+		deployer.Spec.Scale = scale
+	})
+}
+
 func (f *deployerCore) StatusConditions(conditions ...*condition) *deployerCore {
 	return f.mutation(func(deployer *corev1alpha1.Deployer) {
 		c := make([]apis.Condition, len(conditions))
@@ -159,6 +238,18 @@ func (f *deployerCore) StatusLatestImage(format string, a ...interface{}) *deplo
 	})
 }
 
+func (f *deployerCore) StatusImageSource(source corev1alpha1.DeployerImageSource) *deployerCore {
+	return f.mutation(func(deployer *corev1alpha1.Deployer) {
+		deployer.Status.ImageSource = source
+	})
+}
+
+func (f *deployerCore) StatusHistory(revisions ...corev1alpha1.DeployerRevision) *deployerCore {
+	return f.mutation(func(deployer *corev1alpha1.Deployer) {
+		deployer.Status.History = revisions
+	})
+}
+
 func (f *deployerCore) StatusDeploymentRef(format string, a ...interface{}) *deployerCore {
 	return f.mutation(func(deployer *corev1alpha1.Deployer) {
 		deployer.Status.DeploymentRef = &refs.TypedLocalObjectReference{
@@ -189,6 +280,16 @@ func (f *deployerCore) StatusIngressRef(format string, a ...interface{}) *deploy
 	})
 }
 
+func (f *deployerCore) StatusHTTPRouteRef(format string, a ...interface{}) *deployerCore {
+	return f.mutation(func(deployer *corev1alpha1.Deployer) {
+		deployer.Status.HTTPRouteRef = &refs.TypedLocalObjectReference{
+			APIGroup: rtesting.StringPtr("gateway.networking.k8s.io"),
+			Kind:     "HTTPRoute",
+			Name:     fmt.Sprintf(format, a...),
+		}
+	})
+}
+
 func (f *deployerCore) StatusAddressURL(format string, a ...interface{}) *deployerCore {
 	return f.mutation(func(deployer *corev1alpha1.Deployer) {
 		deployer.Status.Address = &apis.Addressable{
@@ -202,3 +303,46 @@ func (f *deployerCore) StatusURL(format string, a ...interface{}) *deployerCore
 		deployer.Status.URL = fmt.Sprintf(format, a...)
 	})
 }
+
+func (f *deployerCore) StatusRollout(rollout *corev1alpha1.RolloutStatus) *deployerCore {
+	return f.mutation(func(deployer *corev1alpha1.Deployer) {
+		deployer.Status.Rollout = rollout
+	})
+}
+
+func (f *deployerCore) StatusHorizontalPodAutoscalerRef(format string, a ...interface{}) *deployerCore {
+	return f.mutation(func(deployer *corev1alpha1.Deployer) {
+		deployer.Status.HorizontalPodAutoscalerRef = &refs.TypedLocalObjectReference{
+			APIGroup: rtesting.StringPtr("autoscaling"),
+			Kind:     "HorizontalPodAutoscaler",
+			Name:     fmt.Sprintf(format, a...),
+		}
+	})
+}
+
+func (f *deployerCore) StatusPodDisruptionBudgetRef(format string, a ...interface{}) *deployerCore {
+	return f.mutation(func(deployer *corev1alpha1.Deployer) {
+		deployer.Status.PodDisruptionBudgetRef = &refs.TypedLocalObjectReference{
+			APIGroup: rtesting.StringPtr("policy"),
+			Kind:     "PodDisruptionBudget",
+			Name:     fmt.Sprintf(format, a...),
+		}
+	})
+}
+
+func (f *deployerCore) StatusNetworkPolicyRef(format string, a ...interface{}) *deployerCore {
+	return f.mutation(func(deployer *corev1alpha1.Deployer) {
+		deployer.Status.NetworkPolicyRef = &refs.TypedLocalObjectReference{
+			APIGroup: rtesting.StringPtr("networking.k8s.io"),
+			Kind:     "NetworkPolicy",
+			Name:     fmt.Sprintf(format, a...),
+		}
+	})
+}
+
+func (f *deployerCore) StatusReplicas(current, desired int32) *deployerCore {
+	return f.mutation(func(deployer *corev1alpha1.Deployer) {
+		deployer.Status.Replicas = current
+		deployer.Status.DesiredReplicas = desired
+	})
+}
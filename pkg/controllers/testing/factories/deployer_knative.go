@@ -119,6 +119,14 @@ func (f *deployerKnative) FunctionRef(format string, a ...interface{}) *deployer
 	})
 }
 
+func (f *deployerKnative) ImagePromotionRef(format string, a ...interface{}) *deployerKnative {
+	return f.mutation(func(deployer *knativev1alpha1.Deployer) {
+		deployer.Spec.Build = &knativev1alpha1.Build{
+			ImagePromotionRef: fmt.Sprintf(format, a...),
+		}
+	})
+}
+
 func (f *deployerKnative) Image(format string, a ...interface{}) *deployerKnative {
 	return f.PodTemplateSpec(func(ptsf PodTemplateSpec) {
 		ptsf.ContainerNamed("user-container", func(container *corev1.Container) {
@@ -127,6 +135,12 @@ func (f *deployerKnative) Image(format string, a ...interface{}) *deployerKnativ
 	})
 }
 
+func (f *deployerKnative) PinnedImage(format string, a ...interface{}) *deployerKnative {
+	return f.mutation(func(deployer *knativev1alpha1.Deployer) {
+		deployer.Spec.PinnedImage = fmt.Sprintf(format, a...)
+	})
+}
+
 func (f *deployerKnative) IngressPolicy(policy knativev1alpha1.IngressPolicy) *deployerKnative {
 	return f.mutation(func(deployer *knativev1alpha1.Deployer) {
 		deployer.Spec.IngressPolicy = policy
@@ -173,6 +187,18 @@ func (f *deployerKnative) StatusLatestImage(format string, a ...interface{}) *de
 	})
 }
 
+func (f *deployerKnative) StatusImageSource(source knativev1alpha1.DeployerImageSource) *deployerKnative {
+	return f.mutation(func(deployer *knativev1alpha1.Deployer) {
+		deployer.Status.ImageSource = source
+	})
+}
+
+func (f *deployerKnative) StatusHistory(revisions ...knativev1alpha1.DeployerRevision) *deployerKnative {
+	return f.mutation(func(deployer *knativev1alpha1.Deployer) {
+		deployer.Status.History = revisions
+	})
+}
+
 func (f *deployerKnative) StatusConfigurationRef(format string, a ...interface{}) *deployerKnative {
 	return f.mutation(func(deployer *knativev1alpha1.Deployer) {
 		deployer.Status.ConfigurationRef = &refs.TypedLocalObjectReference{
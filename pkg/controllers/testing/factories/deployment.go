@@ -103,6 +103,18 @@ func (f *deployment) Replicas(replicas int32) *deployment {
 	})
 }
 
+func (f *deployment) MinReadySeconds(seconds int32) *deployment {
+	return f.mutation(func(deployment *appsv1.Deployment) {
+		deployment.Spec.MinReadySeconds = seconds
+	})
+}
+
+func (f *deployment) ProgressDeadlineSeconds(seconds int32) *deployment {
+	return f.mutation(func(deployment *appsv1.Deployment) {
+		deployment.Spec.ProgressDeadlineSeconds = rtesting.Int32Ptr(seconds)
+	})
+}
+
 func (f *deployment) AddSelectorLabel(key, value string) *deployment {
 	return f.mutation(func(deployment *appsv1.Deployment) {
 		if deployment.Spec.Selector == nil {
@@ -113,6 +125,21 @@ func (f *deployment) AddSelectorLabel(key, value string) *deployment {
 	})
 }
 
+func (f *deployment) StatusReplicas(replicas int32) *deployment {
+	return f.mutation(func(deployment *appsv1.Deployment) {
+		deployment.Status.Replicas = replicas
+		deployment.Status.UpdatedReplicas = replicas
+		deployment.Status.ReadyReplicas = replicas
+		deployment.Status.AvailableReplicas = replicas
+	})
+}
+
+func (f *deployment) StatusUpdatedReplicas(replicas int32) *deployment {
+	return f.mutation(func(deployment *appsv1.Deployment) {
+		deployment.Status.UpdatedReplicas = replicas
+	})
+}
+
 func (f *deployment) StatusConditions(conditions ...*condition) *deployment {
 	return f.mutation(func(deployment *appsv1.Deployment) {
 		c := make([]appsv1.DeploymentCondition, len(conditions))
@@ -19,7 +19,9 @@ package factories
 import (
 	"fmt"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 
 	"github.com/projectriff/system/pkg/apis"
 	buildv1alpha1 "github.com/projectriff/system/pkg/apis/build/v1alpha1"
@@ -122,6 +124,16 @@ func (f *function) SourceGit(url string, revision string) *function {
 	})
 }
 
+func (f *function) SourceBlob(url string) *function {
+	return f.mutation(func(fn *buildv1alpha1.Function) {
+		fn.Spec.Source = &buildv1alpha1.Source{
+			Blob: &buildv1alpha1.Blob{
+				URL: url,
+			},
+		}
+	})
+}
+
 func (f *function) SourceSubPath(subpath string) *function {
 	return f.mutation(func(fn *buildv1alpha1.Function) {
 		if fn.Spec.Source == nil {
@@ -141,6 +153,39 @@ func (f *function) BuildCache(quantity string) *function {
 	})
 }
 
+func (f *function) BuildCachePolicy(policy *buildv1alpha1.BuildCachePolicy) *function {
+	return f.mutation(func(fn *buildv1alpha1.Function) {
+		fn.Spec.Cache = policy
+	})
+}
+
+func (f *function) Builder(kind, name string) *function {
+	return f.mutation(func(fn *buildv1alpha1.Function) {
+		fn.Spec.Builder = &buildv1alpha1.BuilderReference{
+			Kind: kind,
+			Name: name,
+		}
+	})
+}
+
+func (f *function) RebuildPolicy(policy buildv1alpha1.RebuildPolicy) *function {
+	return f.mutation(func(fn *buildv1alpha1.Function) {
+		fn.Spec.RebuildPolicy = policy
+	})
+}
+
+func (f *function) ServiceAccountName(name string) *function {
+	return f.mutation(func(fn *buildv1alpha1.Function) {
+		fn.Spec.ServiceAccountName = name
+	})
+}
+
+func (f *function) BuildEnv(env ...corev1.EnvVar) *function {
+	return f.mutation(func(fn *buildv1alpha1.Function) {
+		fn.Spec.Build.Env = append(fn.Spec.Build.Env, env...)
+	})
+}
+
 func (f *function) StatusConditions(conditions ...*condition) *function {
 	return f.mutation(func(fn *buildv1alpha1.Function) {
 		c := make([]apis.Condition, len(conditions))
@@ -182,6 +227,12 @@ func (f *function) StatusBuildCacheRef(format string, a ...interface{}) *functio
 	})
 }
 
+func (f *function) StatusBuildCache(cache *buildv1alpha1.BuildCacheStatus) *function {
+	return f.mutation(func(fn *buildv1alpha1.Function) {
+		fn.Status.BuildCache = cache
+	})
+}
+
 func (f *function) StatusTargetImage(format string, a ...interface{}) *function {
 	return f.mutation(func(fn *buildv1alpha1.Function) {
 		fn.Status.TargetImage = fmt.Sprintf(format, a...)
@@ -193,3 +244,28 @@ func (f *function) StatusLatestImage(format string, a ...interface{}) *function
 		fn.Status.LatestImage = fmt.Sprintf(format, a...)
 	})
 }
+
+func (f *function) StatusBuilder(kind, name, image string) *function {
+	return f.mutation(func(fn *buildv1alpha1.Function) {
+		fn.Status.BuilderRef = refs.NewTypedLocalObjectReference(name, schema.GroupKind{Group: "build.pivotal.io", Kind: kind})
+		fn.Status.BuilderImage = image
+	})
+}
+
+func (f *function) StatusBuilds(builds ...buildv1alpha1.BuildSummary) *function {
+	return f.mutation(func(fn *buildv1alpha1.Function) {
+		fn.Status.Builds = builds
+	})
+}
+
+func (f *function) StatusBuildMetadata(metadata *buildv1alpha1.BuildMetadata) *function {
+	return f.mutation(func(fn *buildv1alpha1.Function) {
+		fn.Status.BuildMetadata = metadata
+	})
+}
+
+func (f *function) StatusInvoker(invoker string) *function {
+	return f.mutation(func(fn *buildv1alpha1.Function) {
+		fn.Status.Invoker = invoker
+	})
+}
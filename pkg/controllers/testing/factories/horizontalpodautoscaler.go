@@ -0,0 +1,122 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package factories
+
+import (
+	"fmt"
+
+	autoscalingv2beta2 "k8s.io/api/autoscaling/v2beta2"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/projectriff/system/pkg/apis"
+	rtesting "github.com/projectriff/system/pkg/controllers/testing"
+)
+
+type horizontalPodAutoscaler struct {
+	target *autoscalingv2beta2.HorizontalPodAutoscaler
+}
+
+var (
+	_ rtesting.Factory = (*horizontalPodAutoscaler)(nil)
+)
+
+func HorizontalPodAutoscaler(seed ...*autoscalingv2beta2.HorizontalPodAutoscaler) *horizontalPodAutoscaler {
+	var target *autoscalingv2beta2.HorizontalPodAutoscaler
+	switch len(seed) {
+	case 0:
+		target = &autoscalingv2beta2.HorizontalPodAutoscaler{}
+	case 1:
+		target = seed[0]
+	default:
+		panic(fmt.Errorf("expected exactly zero or one seed, got %v", seed))
+	}
+	return &horizontalPodAutoscaler{
+		target: target,
+	}
+}
+
+func (f *horizontalPodAutoscaler) deepCopy() *horizontalPodAutoscaler {
+	return HorizontalPodAutoscaler(f.target.DeepCopy())
+}
+
+func (f *horizontalPodAutoscaler) Create() *autoscalingv2beta2.HorizontalPodAutoscaler {
+	return f.deepCopy().target
+}
+
+func (f *horizontalPodAutoscaler) CreateObject() apis.Object {
+	return f.Create()
+}
+
+func (f *horizontalPodAutoscaler) mutation(m func(*autoscalingv2beta2.HorizontalPodAutoscaler)) *horizontalPodAutoscaler {
+	f = f.deepCopy()
+	m(f.target)
+	return f
+}
+
+func (f *horizontalPodAutoscaler) NamespaceName(namespace, name string) *horizontalPodAutoscaler {
+	return f.mutation(func(hpa *autoscalingv2beta2.HorizontalPodAutoscaler) {
+		hpa.ObjectMeta.Namespace = namespace
+		hpa.ObjectMeta.Name = name
+	})
+}
+
+func (f *horizontalPodAutoscaler) ObjectMeta(nf func(ObjectMeta)) *horizontalPodAutoscaler {
+	return f.mutation(func(hpa *autoscalingv2beta2.HorizontalPodAutoscaler) {
+		omf := objectMeta(hpa.ObjectMeta)
+		nf(omf)
+		hpa.ObjectMeta = omf.Create()
+	})
+}
+
+func (f *horizontalPodAutoscaler) ScaleTargetDeployment(name string) *horizontalPodAutoscaler {
+	return f.mutation(func(hpa *autoscalingv2beta2.HorizontalPodAutoscaler) {
+		hpa.Spec.ScaleTargetRef = autoscalingv2beta2.CrossVersionObjectReference{
+			APIVersion: "apps/v1",
+			Kind:       "Deployment",
+			Name:       name,
+		}
+	})
+}
+
+func (f *horizontalPodAutoscaler) Replicas(min, max int32) *horizontalPodAutoscaler {
+	return f.mutation(func(hpa *autoscalingv2beta2.HorizontalPodAutoscaler) {
+		hpa.Spec.MinReplicas = rtesting.Int32Ptr(min)
+		hpa.Spec.MaxReplicas = max
+	})
+}
+
+func (f *horizontalPodAutoscaler) AddResourceUtilizationMetric(name corev1.ResourceName, utilization int32) *horizontalPodAutoscaler {
+	return f.mutation(func(hpa *autoscalingv2beta2.HorizontalPodAutoscaler) {
+		hpa.Spec.Metrics = append(hpa.Spec.Metrics, autoscalingv2beta2.MetricSpec{
+			Type: autoscalingv2beta2.ResourceMetricSourceType,
+			Resource: &autoscalingv2beta2.ResourceMetricSource{
+				Name: name,
+				Target: autoscalingv2beta2.MetricTarget{
+					Type:               autoscalingv2beta2.UtilizationMetricType,
+					AverageUtilization: rtesting.Int32Ptr(utilization),
+				},
+			},
+		})
+	})
+}
+
+func (f *horizontalPodAutoscaler) StatusReplicas(current, desired int32) *horizontalPodAutoscaler {
+	return f.mutation(func(hpa *autoscalingv2beta2.HorizontalPodAutoscaler) {
+		hpa.Status.CurrentReplicas = current
+		hpa.Status.DesiredReplicas = desired
+	})
+}
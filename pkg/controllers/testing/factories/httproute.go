@@ -0,0 +1,158 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package factories
+
+import (
+	"fmt"
+
+	"github.com/projectriff/system/pkg/apis"
+	gatewayv1 "github.com/projectriff/system/pkg/apis/thirdparty/gateway/v1"
+	rtesting "github.com/projectriff/system/pkg/controllers/testing"
+)
+
+type httpRoute struct {
+	target *gatewayv1.HTTPRoute
+}
+
+var (
+	_ rtesting.Factory = (*httpRoute)(nil)
+)
+
+func HTTPRoute(seed ...*gatewayv1.HTTPRoute) *httpRoute {
+	var target *gatewayv1.HTTPRoute
+	switch len(seed) {
+	case 0:
+		target = &gatewayv1.HTTPRoute{}
+	case 1:
+		target = seed[0]
+	default:
+		panic(fmt.Errorf("expected exactly zero or one seed, got %v", seed))
+	}
+	return &httpRoute{
+		target: target,
+	}
+}
+
+func (f *httpRoute) deepCopy() *httpRoute {
+	return HTTPRoute(f.target.DeepCopy())
+}
+
+func (f *httpRoute) Create() *gatewayv1.HTTPRoute {
+	return f.deepCopy().target
+}
+
+func (f *httpRoute) CreateObject() apis.Object {
+	return f.Create()
+}
+
+func (f *httpRoute) mutation(m func(*gatewayv1.HTTPRoute)) *httpRoute {
+	f = f.deepCopy()
+	m(f.target)
+	return f
+}
+
+func (f *httpRoute) NamespaceName(namespace, name string) *httpRoute {
+	return f.mutation(func(r *gatewayv1.HTTPRoute) {
+		r.ObjectMeta.Namespace = namespace
+		r.ObjectMeta.Name = name
+	})
+}
+
+func (f *httpRoute) ObjectMeta(nf func(ObjectMeta)) *httpRoute {
+	return f.mutation(func(r *gatewayv1.HTTPRoute) {
+		omf := objectMeta(r.ObjectMeta)
+		nf(omf)
+		r.ObjectMeta = omf.Create()
+	})
+}
+
+func (f *httpRoute) Gateway(namespace, name string) *httpRoute {
+	return f.mutation(func(r *gatewayv1.HTTPRoute) {
+		r.Spec.ParentRefs = []gatewayv1.ParentReference{
+			gatewayParentReference(namespace, name),
+		}
+	})
+}
+
+func (f *httpRoute) Hostnames(hostnames ...string) *httpRoute {
+	return f.mutation(func(r *gatewayv1.HTTPRoute) {
+		r.Spec.Hostnames = hostnames
+	})
+}
+
+func (f *httpRoute) PathPrefix(path string) *httpRoute {
+	return f.mutation(func(r *gatewayv1.HTTPRoute) {
+		pathType := gatewayv1.PathMatchPathPrefix
+		rule := f.rule(r)
+		rule.Matches = []gatewayv1.HTTPRouteMatch{{
+			Path: &gatewayv1.HTTPPathMatch{
+				Type:  &pathType,
+				Value: &path,
+			},
+		}}
+	})
+}
+
+func (f *httpRoute) AddBackend(serviceName string, port int32) *httpRoute {
+	return f.mutation(func(r *gatewayv1.HTTPRoute) {
+		rule := f.rule(r)
+		rule.BackendRefs = append(rule.BackendRefs, gatewayv1.HTTPBackendRef{
+			BackendRef: gatewayv1.BackendRef{
+				BackendObjectReference: gatewayv1.BackendObjectReference{
+					Name: serviceName,
+					Port: &port,
+				},
+			},
+		})
+	})
+}
+
+func (f *httpRoute) AddWeightedBackend(serviceName string, port, weight int32) *httpRoute {
+	return f.AddBackend(serviceName, port).mutation(func(r *gatewayv1.HTTPRoute) {
+		rule := f.rule(r)
+		rule.BackendRefs[len(rule.BackendRefs)-1].Weight = &weight
+	})
+}
+
+func (f *httpRoute) StatusParent(namespace, name string, conditions ...gatewayv1.Condition) *httpRoute {
+	return f.mutation(func(r *gatewayv1.HTTPRoute) {
+		r.Status.Parents = append(r.Status.Parents, gatewayv1.RouteParentStatus{
+			ParentRef:      gatewayParentReference(namespace, name),
+			ControllerName: "example.com/gateway-controller",
+			Conditions:     conditions,
+		})
+	})
+}
+
+// rule returns the single rule of the route, creating it if needed
+func (f *httpRoute) rule(r *gatewayv1.HTTPRoute) *gatewayv1.HTTPRouteRule {
+	if len(r.Spec.Rules) == 0 {
+		r.Spec.Rules = []gatewayv1.HTTPRouteRule{{}}
+	}
+	return &r.Spec.Rules[0]
+}
+
+func gatewayParentReference(namespace, name string) gatewayv1.ParentReference {
+	group := gatewayv1.GroupVersion.Group
+	kind := "Gateway"
+	return gatewayv1.ParentReference{
+		Group:     &group,
+		Kind:      &kind,
+		Namespace: &namespace,
+		Name:      name,
+	}
+}
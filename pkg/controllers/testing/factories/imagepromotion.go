@@ -0,0 +1,159 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package factories
+
+import (
+	"fmt"
+
+	"github.com/projectriff/system/pkg/apis"
+	buildv1alpha1 "github.com/projectriff/system/pkg/apis/build/v1alpha1"
+	rtesting "github.com/projectriff/system/pkg/controllers/testing"
+)
+
+type imagePromotion struct {
+	target *buildv1alpha1.ImagePromotion
+}
+
+var (
+	_ rtesting.Factory = (*imagePromotion)(nil)
+)
+
+func ImagePromotion(seed ...*buildv1alpha1.ImagePromotion) *imagePromotion {
+	var target *buildv1alpha1.ImagePromotion
+	switch len(seed) {
+	case 0:
+		target = &buildv1alpha1.ImagePromotion{}
+	case 1:
+		target = seed[0]
+	default:
+		panic(fmt.Errorf("expected exactly zero or one seed, got %v", seed))
+	}
+	return &imagePromotion{
+		target: target,
+	}
+}
+
+func (f *imagePromotion) deepCopy() *imagePromotion {
+	return ImagePromotion(f.target.DeepCopy())
+}
+
+func (f *imagePromotion) Create() *buildv1alpha1.ImagePromotion {
+	return f.deepCopy().target
+}
+
+func (f *imagePromotion) CreateObject() apis.Object {
+	return f.Create()
+}
+
+func (f *imagePromotion) mutation(m func(*buildv1alpha1.ImagePromotion)) *imagePromotion {
+	f = f.deepCopy()
+	m(f.target)
+	return f
+}
+
+func (f *imagePromotion) NamespaceName(namespace, name string) *imagePromotion {
+	return f.mutation(func(ip *buildv1alpha1.ImagePromotion) {
+		ip.ObjectMeta.Namespace = namespace
+		ip.ObjectMeta.Name = name
+	})
+}
+
+func (f *imagePromotion) ObjectMeta(nf func(ObjectMeta)) *imagePromotion {
+	return f.mutation(func(ip *buildv1alpha1.ImagePromotion) {
+		omf := objectMeta(ip.ObjectMeta)
+		nf(omf)
+		ip.ObjectMeta = omf.Create()
+	})
+}
+
+func (f *imagePromotion) ApplicationRef(format string, a ...interface{}) *imagePromotion {
+	return f.mutation(func(ip *buildv1alpha1.ImagePromotion) {
+		ip.Spec.Source = buildv1alpha1.ImagePromotionSource{
+			ApplicationRef: fmt.Sprintf(format, a...),
+		}
+	})
+}
+
+func (f *imagePromotion) ContainerRef(format string, a ...interface{}) *imagePromotion {
+	return f.mutation(func(ip *buildv1alpha1.ImagePromotion) {
+		ip.Spec.Source = buildv1alpha1.ImagePromotionSource{
+			ContainerRef: fmt.Sprintf(format, a...),
+		}
+	})
+}
+
+func (f *imagePromotion) FunctionRef(format string, a ...interface{}) *imagePromotion {
+	return f.mutation(func(ip *buildv1alpha1.ImagePromotion) {
+		ip.Spec.Source = buildv1alpha1.ImagePromotionSource{
+			FunctionRef: fmt.Sprintf(format, a...),
+		}
+	})
+}
+
+func (f *imagePromotion) SourceImage(format string, a ...interface{}) *imagePromotion {
+	return f.mutation(func(ip *buildv1alpha1.ImagePromotion) {
+		ip.Spec.Source = buildv1alpha1.ImagePromotionSource{
+			Image: fmt.Sprintf(format, a...),
+		}
+	})
+}
+
+func (f *imagePromotion) Destination(format string, a ...interface{}) *imagePromotion {
+	return f.mutation(func(ip *buildv1alpha1.ImagePromotion) {
+		ip.Spec.Destination = fmt.Sprintf(format, a...)
+	})
+}
+
+func (f *imagePromotion) ServiceAccountName(name string) *imagePromotion {
+	return f.mutation(func(ip *buildv1alpha1.ImagePromotion) {
+		ip.Spec.ServiceAccountName = name
+	})
+}
+
+func (f *imagePromotion) StatusConditions(conditions ...*condition) *imagePromotion {
+	return f.mutation(func(ip *buildv1alpha1.ImagePromotion) {
+		c := make([]apis.Condition, len(conditions))
+		for i, cg := range conditions {
+			c[i] = cg.Create()
+		}
+		ip.Status.Conditions = c
+	})
+}
+
+func (f *imagePromotion) StatusReady() *imagePromotion {
+	return f.StatusConditions(
+		Condition().Type(buildv1alpha1.ImagePromotionConditionReady).True(),
+	)
+}
+
+func (f *imagePromotion) StatusObservedGeneration(generation int64) *imagePromotion {
+	return f.mutation(func(ip *buildv1alpha1.ImagePromotion) {
+		ip.Status.ObservedGeneration = generation
+	})
+}
+
+func (f *imagePromotion) StatusSourceImage(format string, a ...interface{}) *imagePromotion {
+	return f.mutation(func(ip *buildv1alpha1.ImagePromotion) {
+		ip.Status.SourceImage = fmt.Sprintf(format, a...)
+	})
+}
+
+func (f *imagePromotion) StatusLatestImage(format string, a ...interface{}) *imagePromotion {
+	return f.mutation(func(ip *buildv1alpha1.ImagePromotion) {
+		ip.Status.LatestImage = fmt.Sprintf(format, a...)
+	})
+}
@@ -104,6 +104,34 @@ func (f *ingress) HostToService(host, serviceName string) *ingress {
 	})
 }
 
+func (f *ingress) AddRule(host, path, serviceName string) *ingress {
+	return f.mutation(func(i *networkingv1beta1.Ingress) {
+		i.Spec.Rules = append(i.Spec.Rules, networkingv1beta1.IngressRule{
+			Host: host,
+			IngressRuleValue: networkingv1beta1.IngressRuleValue{
+				HTTP: &networkingv1beta1.HTTPIngressRuleValue{
+					Paths: []networkingv1beta1.HTTPIngressPath{{
+						Path: path,
+						Backend: networkingv1beta1.IngressBackend{
+							ServiceName: serviceName,
+							ServicePort: intstr.FromInt(80),
+						},
+					}},
+				},
+			},
+		})
+	})
+}
+
+func (f *ingress) TLS(secretName string, hosts ...string) *ingress {
+	return f.mutation(func(i *networkingv1beta1.Ingress) {
+		i.Spec.TLS = append(i.Spec.TLS, networkingv1beta1.IngressTLS{
+			Hosts:      hosts,
+			SecretName: secretName,
+		})
+	})
+}
+
 func (f *ingress) StatusLoadBalancer(ingress ...corev1.LoadBalancerIngress) *ingress {
 	return f.mutation(func(i *networkingv1beta1.Ingress) {
 		i.Status.LoadBalancer.Ingress = ingress
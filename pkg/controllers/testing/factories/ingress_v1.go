@@ -0,0 +1,127 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package factories
+
+import (
+	"fmt"
+
+	"github.com/projectriff/system/pkg/apis"
+	networkingv1 "github.com/projectriff/system/pkg/apis/thirdparty/kubernetes/networking/v1"
+	rtesting "github.com/projectriff/system/pkg/controllers/testing"
+)
+
+type ingressV1 struct {
+	target *networkingv1.Ingress
+}
+
+var (
+	_ rtesting.Factory = (*ingressV1)(nil)
+)
+
+func IngressV1(seed ...*networkingv1.Ingress) *ingressV1 {
+	var target *networkingv1.Ingress
+	switch len(seed) {
+	case 0:
+		target = &networkingv1.Ingress{}
+	case 1:
+		target = seed[0]
+	default:
+		panic(fmt.Errorf("expected exactly zero or one seed, got %v", seed))
+	}
+	return &ingressV1{
+		target: target,
+	}
+}
+
+func (f *ingressV1) deepCopy() *ingressV1 {
+	return IngressV1(f.target.DeepCopy())
+}
+
+func (f *ingressV1) Create() *networkingv1.Ingress {
+	return f.deepCopy().target
+}
+
+func (f *ingressV1) CreateObject() apis.Object {
+	return f.Create()
+}
+
+func (f *ingressV1) mutation(m func(*networkingv1.Ingress)) *ingressV1 {
+	f = f.deepCopy()
+	m(f.target)
+	return f
+}
+
+func (f *ingressV1) NamespaceName(namespace, name string) *ingressV1 {
+	return f.mutation(func(sa *networkingv1.Ingress) {
+		sa.ObjectMeta.Namespace = namespace
+		sa.ObjectMeta.Name = name
+	})
+}
+
+func (f *ingressV1) ObjectMeta(nf func(ObjectMeta)) *ingressV1 {
+	return f.mutation(func(sa *networkingv1.Ingress) {
+		omf := objectMeta(sa.ObjectMeta)
+		nf(omf)
+		sa.ObjectMeta = omf.Create()
+	})
+}
+
+func (f *ingressV1) ClassName(className string) *ingressV1 {
+	return f.mutation(func(i *networkingv1.Ingress) {
+		i.Spec.IngressClassName = &className
+	})
+}
+
+func (f *ingressV1) AddRule(host, path, serviceName string) *ingressV1 {
+	return f.mutation(func(i *networkingv1.Ingress) {
+		pathType := networkingv1.PathTypePrefix
+		i.Spec.Rules = append(i.Spec.Rules, networkingv1.IngressRule{
+			Host: host,
+			IngressRuleValue: networkingv1.IngressRuleValue{
+				HTTP: &networkingv1.HTTPIngressRuleValue{
+					Paths: []networkingv1.HTTPIngressPath{{
+						Path:     path,
+						PathType: &pathType,
+						Backend: networkingv1.IngressBackend{
+							Service: &networkingv1.IngressServiceBackend{
+								Name: serviceName,
+								Port: networkingv1.ServiceBackendPort{
+									Number: 80,
+								},
+							},
+						},
+					}},
+				},
+			},
+		})
+	})
+}
+
+func (f *ingressV1) TLS(secretName string, hosts ...string) *ingressV1 {
+	return f.mutation(func(i *networkingv1.Ingress) {
+		i.Spec.TLS = append(i.Spec.TLS, networkingv1.IngressTLS{
+			Hosts:      hosts,
+			SecretName: secretName,
+		})
+	})
+}
+
+func (f *ingressV1) StatusLoadBalancer(ingress ...networkingv1.IngressLoadBalancerIngress) *ingressV1 {
+	return f.mutation(func(i *networkingv1.Ingress) {
+		i.Status.LoadBalancer.Ingress = ingress
+	})
+}
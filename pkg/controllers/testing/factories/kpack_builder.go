@@ -0,0 +1,87 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package factories
+
+import (
+	"fmt"
+
+	"github.com/projectriff/system/pkg/apis"
+	kpackbuildv1alpha1 "github.com/projectriff/system/pkg/apis/thirdparty/kpack/build/v1alpha1"
+	rtesting "github.com/projectriff/system/pkg/controllers/testing"
+)
+
+type kpackBuilder struct {
+	target *kpackbuildv1alpha1.Builder
+}
+
+var (
+	_ rtesting.Factory = (*kpackBuilder)(nil)
+)
+
+func KpackBuilder(seed ...*kpackbuildv1alpha1.Builder) *kpackBuilder {
+	var target *kpackbuildv1alpha1.Builder
+	switch len(seed) {
+	case 0:
+		target = &kpackbuildv1alpha1.Builder{}
+	case 1:
+		target = seed[0]
+	default:
+		panic(fmt.Errorf("expected exactly zero or one seed, got %v", seed))
+	}
+	return &kpackBuilder{
+		target: target,
+	}
+}
+
+func (f *kpackBuilder) deepCopy() *kpackBuilder {
+	return KpackBuilder(f.target.DeepCopy())
+}
+
+func (f *kpackBuilder) Create() *kpackbuildv1alpha1.Builder {
+	return f.deepCopy().target
+}
+
+func (f *kpackBuilder) CreateObject() apis.Object {
+	return f.Create()
+}
+
+func (f *kpackBuilder) mutation(m func(*kpackbuildv1alpha1.Builder)) *kpackBuilder {
+	f = f.deepCopy()
+	m(f.target)
+	return f
+}
+
+func (f *kpackBuilder) NamespaceName(namespace, name string) *kpackBuilder {
+	return f.mutation(func(builder *kpackbuildv1alpha1.Builder) {
+		builder.ObjectMeta.Namespace = namespace
+		builder.ObjectMeta.Name = name
+	})
+}
+
+func (f *kpackBuilder) ObjectMeta(nf func(ObjectMeta)) *kpackBuilder {
+	return f.mutation(func(builder *kpackbuildv1alpha1.Builder) {
+		omf := objectMeta(builder.ObjectMeta)
+		nf(omf)
+		builder.ObjectMeta = omf.Create()
+	})
+}
+
+func (f *kpackBuilder) StatusLatestImage(image string) *kpackBuilder {
+	return f.mutation(func(builder *kpackbuildv1alpha1.Builder) {
+		builder.Status.LatestImage = image
+	})
+}
@@ -168,6 +168,29 @@ func (f *kpackImage) BuildCache(quantity string) *kpackImage {
 	})
 }
 
+func (f *kpackImage) Builder(kind, name string) *kpackImage {
+	return f.mutation(func(image *kpackbuildv1alpha1.Image) {
+		image.Spec.Builder = kpackbuildv1alpha1.ImageBuilder{
+			TypeMeta: metav1.TypeMeta{
+				Kind: kind,
+			},
+			Name: name,
+		}
+	})
+}
+
+func (f *kpackImage) ServiceAccount(name string) *kpackImage {
+	return f.mutation(func(image *kpackbuildv1alpha1.Image) {
+		image.Spec.ServiceAccount = name
+	})
+}
+
+func (f *kpackImage) BuildEnv(env ...corev1.EnvVar) *kpackImage {
+	return f.mutation(func(image *kpackbuildv1alpha1.Image) {
+		image.Spec.Build.Env = append(image.Spec.Build.Env, env...)
+	})
+}
+
 func (f *kpackImage) StatusConditions(conditions ...*condition) *kpackImage {
 	return f.mutation(func(image *kpackbuildv1alpha1.Image) {
 		c := make([]apis.Condition, len(conditions))
@@ -0,0 +1,133 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package factories
+
+import (
+	"fmt"
+
+	"github.com/projectriff/system/pkg/apis"
+	kpackbuildv1alpha1 "github.com/projectriff/system/pkg/apis/thirdparty/kpack/build/v1alpha1"
+	rtesting "github.com/projectriff/system/pkg/controllers/testing"
+)
+
+type kpackSourceResolver struct {
+	target *kpackbuildv1alpha1.SourceResolver
+}
+
+var (
+	_ rtesting.Factory = (*kpackSourceResolver)(nil)
+)
+
+func KpackSourceResolver(seed ...*kpackbuildv1alpha1.SourceResolver) *kpackSourceResolver {
+	var target *kpackbuildv1alpha1.SourceResolver
+	switch len(seed) {
+	case 0:
+		target = &kpackbuildv1alpha1.SourceResolver{}
+	case 1:
+		target = seed[0]
+	default:
+		panic(fmt.Errorf("expected exactly zero or one seed, got %v", seed))
+	}
+	return &kpackSourceResolver{
+		target: target,
+	}
+}
+
+func (f *kpackSourceResolver) deepCopy() *kpackSourceResolver {
+	return KpackSourceResolver(f.target.DeepCopy())
+}
+
+func (f *kpackSourceResolver) Create() *kpackbuildv1alpha1.SourceResolver {
+	return f.deepCopy().target
+}
+
+func (f *kpackSourceResolver) CreateObject() apis.Object {
+	return f.Create()
+}
+
+func (f *kpackSourceResolver) mutation(m func(*kpackbuildv1alpha1.SourceResolver)) *kpackSourceResolver {
+	f = f.deepCopy()
+	m(f.target)
+	return f
+}
+
+func (f *kpackSourceResolver) NamespaceName(namespace, name string) *kpackSourceResolver {
+	return f.mutation(func(resolver *kpackbuildv1alpha1.SourceResolver) {
+		resolver.ObjectMeta.Namespace = namespace
+		resolver.ObjectMeta.Name = name
+	})
+}
+
+func (f *kpackSourceResolver) ObjectMeta(nf func(ObjectMeta)) *kpackSourceResolver {
+	return f.mutation(func(resolver *kpackbuildv1alpha1.SourceResolver) {
+		omf := objectMeta(resolver.ObjectMeta)
+		nf(omf)
+		resolver.ObjectMeta = omf.Create()
+	})
+}
+
+func (f *kpackSourceResolver) ServiceAccount(name string) *kpackSourceResolver {
+	return f.mutation(func(resolver *kpackbuildv1alpha1.SourceResolver) {
+		resolver.Spec.ServiceAccount = name
+	})
+}
+
+func (f *kpackSourceResolver) SourceGit(url string, revision string) *kpackSourceResolver {
+	return f.mutation(func(resolver *kpackbuildv1alpha1.SourceResolver) {
+		resolver.Spec.Source = kpackbuildv1alpha1.SourceConfig{
+			Git: &kpackbuildv1alpha1.Git{
+				URL:      url,
+				Revision: revision,
+			},
+			SubPath: resolver.Spec.Source.SubPath,
+		}
+	})
+}
+
+func (f *kpackSourceResolver) StatusConditions(conditions ...*condition) *kpackSourceResolver {
+	return f.mutation(func(resolver *kpackbuildv1alpha1.SourceResolver) {
+		c := make([]apis.Condition, len(conditions))
+		for i, cg := range conditions {
+			c[i] = cg.Create()
+		}
+		resolver.Status.Conditions = c
+	})
+}
+
+func (f *kpackSourceResolver) StatusReady() *kpackSourceResolver {
+	return f.StatusConditions(
+		Condition().Type(apis.ConditionReady).True(),
+	)
+}
+
+func (f *kpackSourceResolver) StatusObservedGeneration(generation int64) *kpackSourceResolver {
+	return f.mutation(func(resolver *kpackbuildv1alpha1.SourceResolver) {
+		resolver.Status.ObservedGeneration = generation
+	})
+}
+
+func (f *kpackSourceResolver) StatusResolvedGit(url, commit string) *kpackSourceResolver {
+	return f.mutation(func(resolver *kpackbuildv1alpha1.SourceResolver) {
+		resolver.Status.Source = kpackbuildv1alpha1.ResolvedSourceConfig{
+			Git: &kpackbuildv1alpha1.ResolvedGitSource{
+				URL:      url,
+				Revision: commit,
+				Type:     kpackbuildv1alpha1.Branch,
+			},
+		}
+	})
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package factories
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/projectriff/system/pkg/apis"
+	rtesting "github.com/projectriff/system/pkg/controllers/testing"
+)
+
+type networkPolicy struct {
+	target *networkingv1.NetworkPolicy
+}
+
+var (
+	_ rtesting.Factory = (*networkPolicy)(nil)
+)
+
+func NetworkPolicy(seed ...*networkingv1.NetworkPolicy) *networkPolicy {
+	var target *networkingv1.NetworkPolicy
+	switch len(seed) {
+	case 0:
+		target = &networkingv1.NetworkPolicy{}
+	case 1:
+		target = seed[0]
+	default:
+		panic(fmt.Errorf("expected exactly zero or one seed, got %v", seed))
+	}
+	return &networkPolicy{
+		target: target,
+	}
+}
+
+func (f *networkPolicy) deepCopy() *networkPolicy {
+	return NetworkPolicy(f.target.DeepCopy())
+}
+
+func (f *networkPolicy) Create() *networkingv1.NetworkPolicy {
+	return f.deepCopy().target
+}
+
+func (f *networkPolicy) CreateObject() apis.Object {
+	return f.Create()
+}
+
+func (f *networkPolicy) mutation(m func(*networkingv1.NetworkPolicy)) *networkPolicy {
+	f = f.deepCopy()
+	m(f.target)
+	return f
+}
+
+func (f *networkPolicy) NamespaceName(namespace, name string) *networkPolicy {
+	return f.mutation(func(np *networkingv1.NetworkPolicy) {
+		np.ObjectMeta.Namespace = namespace
+		np.ObjectMeta.Name = name
+	})
+}
+
+func (f *networkPolicy) ObjectMeta(nf func(ObjectMeta)) *networkPolicy {
+	return f.mutation(func(np *networkingv1.NetworkPolicy) {
+		omf := objectMeta(np.ObjectMeta)
+		nf(omf)
+		np.ObjectMeta = omf.Create()
+	})
+}
+
+func (f *networkPolicy) AddSelectorLabel(key, value string) *networkPolicy {
+	return f.mutation(func(np *networkingv1.NetworkPolicy) {
+		metav1.AddLabelToSelector(&np.Spec.PodSelector, key, value)
+		np.Spec.PolicyTypes = []networkingv1.PolicyType{networkingv1.PolicyTypeIngress}
+	})
+}
+
+// AllowIngress adds an ingress rule accepting TCP connections to the port
+// from the peers
+func (f *networkPolicy) AllowIngress(port int, peers ...networkingv1.NetworkPolicyPeer) *networkPolicy {
+	return f.mutation(func(np *networkingv1.NetworkPolicy) {
+		protocol := corev1.ProtocolTCP
+		p := intstr.FromInt(port)
+		np.Spec.Ingress = append(np.Spec.Ingress, networkingv1.NetworkPolicyIngressRule{
+			Ports: []networkingv1.NetworkPolicyPort{
+				{Protocol: &protocol, Port: &p},
+			},
+			From: peers,
+		})
+	})
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package factories
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/projectriff/system/pkg/apis"
+	rtesting "github.com/projectriff/system/pkg/controllers/testing"
+)
+
+type persistentVolumeClaim struct {
+	target *corev1.PersistentVolumeClaim
+}
+
+var (
+	_ rtesting.Factory = (*persistentVolumeClaim)(nil)
+)
+
+func PersistentVolumeClaim(seed ...*corev1.PersistentVolumeClaim) *persistentVolumeClaim {
+	var target *corev1.PersistentVolumeClaim
+	switch len(seed) {
+	case 0:
+		target = &corev1.PersistentVolumeClaim{}
+	case 1:
+		target = seed[0]
+	default:
+		panic(fmt.Errorf("expected exactly zero or one seed, got %v", seed))
+	}
+	return &persistentVolumeClaim{
+		target: target,
+	}
+}
+
+func (f *persistentVolumeClaim) deepCopy() *persistentVolumeClaim {
+	return PersistentVolumeClaim(f.target.DeepCopy())
+}
+
+func (f *persistentVolumeClaim) Create() *corev1.PersistentVolumeClaim {
+	return f.deepCopy().target
+}
+
+func (f *persistentVolumeClaim) CreateObject() apis.Object {
+	return f.Create()
+}
+
+func (f *persistentVolumeClaim) mutation(m func(*corev1.PersistentVolumeClaim)) *persistentVolumeClaim {
+	f = f.deepCopy()
+	m(f.target)
+	return f
+}
+
+func (f *persistentVolumeClaim) NamespaceName(namespace, name string) *persistentVolumeClaim {
+	return f.mutation(func(pvc *corev1.PersistentVolumeClaim) {
+		pvc.ObjectMeta.Namespace = namespace
+		pvc.ObjectMeta.Name = name
+	})
+}
+
+func (f *persistentVolumeClaim) ObjectMeta(nf func(ObjectMeta)) *persistentVolumeClaim {
+	return f.mutation(func(pvc *corev1.PersistentVolumeClaim) {
+		omf := objectMeta(pvc.ObjectMeta)
+		nf(omf)
+		pvc.ObjectMeta = omf.Create()
+	})
+}
+
+func (f *persistentVolumeClaim) Storage(quantity string) *persistentVolumeClaim {
+	return f.mutation(func(pvc *corev1.PersistentVolumeClaim) {
+		pvc.Spec.Resources.Requests = corev1.ResourceList{
+			corev1.ResourceStorage: resource.MustParse(quantity),
+		}
+	})
+}
+
+func (f *persistentVolumeClaim) StatusCapacity(quantity string) *persistentVolumeClaim {
+	return f.mutation(func(pvc *corev1.PersistentVolumeClaim) {
+		pvc.Status.Capacity = corev1.ResourceList{
+			corev1.ResourceStorage: resource.MustParse(quantity),
+		}
+	})
+}
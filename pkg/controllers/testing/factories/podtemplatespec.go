@@ -26,7 +26,10 @@ type PodTemplateSpec interface {
 	AddLabel(key, value string) PodTemplateSpec
 	AddAnnotation(key, value string) PodTemplateSpec
 	ContainerNamed(name string, cb func(*corev1.Container)) PodTemplateSpec
+	InitContainerNamed(name string, cb func(*corev1.Container)) PodTemplateSpec
 	Volumes(volumes ...corev1.Volume) PodTemplateSpec
+	ServiceAccountName(name string) PodTemplateSpec
+	RestartPolicy(policy corev1.RestartPolicy) PodTemplateSpec
 }
 
 type podTemplateSpecImpl struct {
@@ -93,8 +96,47 @@ func (f *podTemplateSpecImpl) ContainerNamed(name string, cb func(*corev1.Contai
 	})
 }
 
+func (f *podTemplateSpecImpl) InitContainerNamed(name string, cb func(*corev1.Container)) PodTemplateSpec {
+	return f.mutate(func(pts *corev1.PodTemplateSpec) {
+		found := false
+		// check for existing init container
+		for i, container := range pts.Spec.InitContainers {
+			if container.Name == name {
+				found = true
+				if cb != nil {
+					// container mutations
+					cb(&container)
+					pts.Spec.InitContainers[i] = container
+				}
+				break
+			}
+		}
+		if !found {
+			// not found, create new init container
+			container := corev1.Container{Name: name}
+			if cb != nil {
+				// container mutations
+				cb(&container)
+			}
+			pts.Spec.InitContainers = append(pts.Spec.InitContainers, container)
+		}
+	})
+}
+
 func (f *podTemplateSpecImpl) Volumes(volumes ...corev1.Volume) PodTemplateSpec {
 	return f.mutate(func(pts *corev1.PodTemplateSpec) {
 		pts.Spec.Volumes = volumes
 	})
 }
+
+func (f *podTemplateSpecImpl) ServiceAccountName(name string) PodTemplateSpec {
+	return f.mutate(func(pts *corev1.PodTemplateSpec) {
+		pts.Spec.ServiceAccountName = name
+	})
+}
+
+func (f *podTemplateSpecImpl) RestartPolicy(policy corev1.RestartPolicy) PodTemplateSpec {
+	return f.mutate(func(pts *corev1.PodTemplateSpec) {
+		pts.Spec.RestartPolicy = policy
+	})
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testing
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// Registry is an in-process container registry. Images are held in memory
+// for the life of the registry.
+type Registry struct {
+	server *httptest.Server
+	// Host is the host and port the registry is serving from
+	Host string
+}
+
+// NewRegistry starts a new in-process registry. The registry must be closed
+// once it is no longer needed.
+func NewRegistry() *Registry {
+	server := httptest.NewServer(registry.New(registry.Logger(log.New(ioutil.Discard, "", 0))))
+	return &Registry{
+		server: server,
+		Host:   strings.TrimPrefix(server.URL, "http://"),
+	}
+}
+
+// Repository returns the name of a repository within the registry.
+func (r *Registry) Repository(format string, a ...interface{}) string {
+	return fmt.Sprintf("%s/%s", r.Host, fmt.Sprintf(format, a...))
+}
+
+// PushRandomImage writes a random image to the tag and returns the image's
+// digest.
+func (r *Registry) PushRandomImage(t *testing.T, tag string) string {
+	t.Helper()
+	return r.PushLabeledImage(t, tag, nil)
+}
+
+// PushLabeledImage writes a random image with the config labels to the tag
+// and returns the image's digest.
+func (r *Registry) PushLabeledImage(t *testing.T, tag string, labels map[string]string) string {
+	t.Helper()
+	ref, err := name.ParseReference(tag)
+	if err != nil {
+		t.Fatalf("invalid tag %q: %v", tag, err)
+	}
+	img := randomImage(t, labels)
+	if err := remote.Write(ref, img); err != nil {
+		t.Fatalf("unable to push image %q: %v", tag, err)
+	}
+	digest, err := img.Digest()
+	if err != nil {
+		t.Fatalf("unable to compute image digest: %v", err)
+	}
+	return digest.String()
+}
+
+// PushImageIndex writes an image index to the tag with a random image for
+// each platform. The digest of the index is returned, followed by the digest
+// of each platform's image.
+func (r *Registry) PushImageIndex(t *testing.T, tag string, platforms ...v1.Platform) (string, []string) {
+	t.Helper()
+	return r.PushLabeledImageIndex(t, tag, nil, platforms...)
+}
+
+// PushLabeledImageIndex writes an image index to the tag with a random image
+// with the config labels for each platform. The digest of the index is
+// returned, followed by the digest of each platform's image.
+func (r *Registry) PushLabeledImageIndex(t *testing.T, tag string, labels map[string]string, platforms ...v1.Platform) (string, []string) {
+	t.Helper()
+	ref, err := name.ParseReference(tag)
+	if err != nil {
+		t.Fatalf("invalid tag %q: %v", tag, err)
+	}
+	adds := make([]mutate.IndexAddendum, len(platforms))
+	digests := make([]string, len(platforms))
+	for i := range platforms {
+		img := randomImage(t, labels)
+		digest, err := img.Digest()
+		if err != nil {
+			t.Fatalf("unable to compute image digest: %v", err)
+		}
+		adds[i] = mutate.IndexAddendum{
+			Add: img,
+			Descriptor: v1.Descriptor{
+				Platform: &platforms[i],
+			},
+		}
+		digests[i] = digest.String()
+	}
+	index := mutate.AppendManifests(empty.Index, adds...)
+	if err := remote.WriteIndex(ref, index); err != nil {
+		t.Fatalf("unable to push image index %q: %v", tag, err)
+	}
+	digest, err := index.Digest()
+	if err != nil {
+		t.Fatalf("unable to compute image index digest: %v", err)
+	}
+	return digest.String(), digests
+}
+
+// randomImage creates a random image with the config labels.
+func randomImage(t *testing.T, labels map[string]string) v1.Image {
+	t.Helper()
+	img, err := random.Image(256, 1)
+	if err != nil {
+		t.Fatalf("unable to create random image: %v", err)
+	}
+	if labels == nil {
+		return img
+	}
+	cfg, err := img.ConfigFile()
+	if err != nil {
+		t.Fatalf("unable to read image config: %v", err)
+	}
+	cfg = cfg.DeepCopy()
+	cfg.Config.Labels = labels
+	img, err = mutate.ConfigFile(img, cfg)
+	if err != nil {
+		t.Fatalf("unable to label image: %v", err)
+	}
+	return img
+}
+
+// Close stops the registry.
+func (r *Registry) Close() {
+	r.server.Close()
+}
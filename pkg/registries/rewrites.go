@@ -0,0 +1,205 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registries
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	corev1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/projectriff/system/pkg/apis"
+	"github.com/projectriff/system/pkg/tracker"
+)
+
+const (
+	// ConfigMapName is the ConfigMap in the system namespace holding the
+	// cluster-wide registry rewrite rules
+	ConfigMapName = "riff-registry-rewrites"
+	// RewritesKey in the ConfigMap holds one rule per line, in the form
+	// `<from> -> <to>`. Blank lines and lines starting with `#` are ignored.
+	RewritesKey = "rewrites"
+)
+
+// Rule rewrites image references starting with From to start with To.
+type Rule struct {
+	From string
+	To   string
+}
+
+// Rewriter rewrites image references to a mirror. A nil Rewriter leaves
+// references unchanged.
+type Rewriter struct {
+	// rules ordered by the longest prefix first
+	rules []Rule
+}
+
+// NewRewriter creates a Rewriter for the rules. The From prefix of each rule
+// is qualified with its registry, so `library/nginx`, `docker.io/library/nginx`
+// and `index.docker.io/library/nginx` are equivalent. A bare name without a
+// slash, like `nginx`, is a Docker Hub official image, while a name ending with
+// a slash, like `projectriff/`, or qualified with a registry is a namespace.
+// When multiple rules match an image, the rule with the
+// longest From prefix is used.
+func NewRewriter(rules ...Rule) *Rewriter {
+	sorted := make([]Rule, len(rules))
+	for i, rule := range rules {
+		sorted[i] = Rule{
+			From: normalizePrefix(rule.From),
+			To:   strings.TrimSuffix(rule.To, "/"),
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return len(sorted[i].From) > len(sorted[j].From)
+	})
+	return &Rewriter{rules: sorted}
+}
+
+// Parse reads the rules from the value of the RewritesKey.
+func Parse(config string) (*Rewriter, error) {
+	rules := []Rule{}
+	scanner := bufio.NewScanner(strings.NewReader(config))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		parts := strings.Split(text, "->")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid rewrite rule on line %d: expected `<from> -> <to>`", line)
+		}
+		rule := Rule{
+			From: strings.TrimSpace(parts[0]),
+			To:   strings.TrimSpace(parts[1]),
+		}
+		if strings.Trim(rule.From, "/") == "" || strings.Trim(rule.To, "/") == "" {
+			return nil, fmt.Errorf("invalid rewrite rule on line %d: expected `<from> -> <to>`", line)
+		}
+		rules = append(rules, rule)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return NewRewriter(rules...), nil
+}
+
+// Rewrite returns the image with the prefix of the longest matching rule
+// replaced. The image's repository is normalized to its fully qualified name
+// before matching, and a prefix matches whole path segments of the
+// repository. The tag or digest of the image is preserved. Images without a
+// matching rule are returned unchanged.
+func (r *Rewriter) Rewrite(image string) string {
+	if r == nil || image == "" {
+		return image
+	}
+	ref, err := name.ParseReference(image, name.WeakValidation)
+	if err != nil {
+		return image
+	}
+	suffix := ""
+	switch ref := ref.(type) {
+	case name.Tag:
+		if strings.HasSuffix(image, ":"+ref.TagStr()) {
+			suffix = ":" + ref.TagStr()
+		}
+	case name.Digest:
+		suffix = "@" + ref.DigestStr()
+	}
+	repository := ref.Context().Name()
+	for _, rule := range r.rules {
+		if repository != rule.From && !strings.HasPrefix(repository, rule.From+"/") {
+			continue
+		}
+		return rule.To + repository[len(rule.From):] + suffix
+	}
+	return image
+}
+
+// normalizePrefix qualifies the From prefix of a rule with its registry, the
+// same way the repository of an image is qualified.
+func normalizePrefix(prefix string) string {
+	namespace := strings.HasSuffix(prefix, "/")
+	prefix = strings.TrimSuffix(prefix, "/")
+	if !strings.Contains(prefix, "/") {
+		if strings.ContainsAny(prefix, ".:") || prefix == "localhost" {
+			registry, err := name.NewRegistry(prefix, name.WeakValidation)
+			if err != nil {
+				return prefix
+			}
+			return registry.Name()
+		}
+		if !namespace && prefix != "library" {
+			// an official image on Docker Hub
+			ref, err := name.ParseReference(prefix, name.WeakValidation)
+			if err != nil {
+				return prefix
+			}
+			return ref.Context().Name()
+		}
+	}
+	// qualify a repository within the namespace, so the namespace itself is
+	// not expanded as an official image on Docker Hub
+	repository, err := name.NewRepository(prefix+"/_", name.WeakValidation)
+	if err != nil {
+		return prefix
+	}
+	return strings.TrimSuffix(repository.Name(), "/_")
+}
+
+// Load reads the rewrite rules from the ConfigMap in the system namespace. A
+// missing ConfigMap has no rules.
+func Load(ctx context.Context, c client.Reader, systemNamespace string) (*Rewriter, error) {
+	if systemNamespace == "" {
+		return NewRewriter(), nil
+	}
+	var config corev1.ConfigMap
+	if err := c.Get(ctx, Key(systemNamespace), &config); err != nil {
+		if apierrs.IsNotFound(err) {
+			return NewRewriter(), nil
+		}
+		return nil, err
+	}
+	rewriter, err := Parse(config.Data[RewritesKey])
+	if err != nil {
+		return nil, fmt.Errorf("%s ConfigMap: %v", ConfigMapName, err)
+	}
+	return rewriter, nil
+}
+
+// Track registers the parent to be enqueued when the rewrite rules change.
+func Track(t tracker.Tracker, systemNamespace string, parent apis.Object) {
+	if systemNamespace == "" {
+		return
+	}
+	t.Track(
+		tracker.NewKey(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, Key(systemNamespace)),
+		types.NamespacedName{Namespace: parent.GetNamespace(), Name: parent.GetName()},
+	)
+}
+
+// Key is the name of the ConfigMap holding the rewrite rules.
+func Key(systemNamespace string) types.NamespacedName {
+	return types.NamespacedName{Namespace: systemNamespace, Name: ConfigMapName}
+}
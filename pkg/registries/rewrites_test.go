@@ -0,0 +1,222 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registries
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   string
+		expected []Rule
+		err      string
+	}{{
+		name:     "empty",
+		config:   "",
+		expected: []Rule{},
+	}, {
+		name: "rules",
+		config: `
+# mirror of the public registries
+gcr.io -> mirror.local/gcr
+gcr.io/projectriff/ -> mirror.local/riff/
+  index.docker.io->mirror.local/dockerhub
+`,
+		expected: []Rule{
+			{From: "gcr.io/projectriff", To: "mirror.local/riff"},
+			{From: "index.docker.io", To: "mirror.local/dockerhub"},
+			{From: "gcr.io", To: "mirror.local/gcr"},
+		},
+	}, {
+		name:   "missing arrow",
+		config: "gcr.io mirror.local/gcr",
+		err:    "invalid rewrite rule on line 1: expected `<from> -> <to>`",
+	}, {
+		name:   "missing target",
+		config: "gcr.io -> mirror.local/gcr\ndocker.io ->",
+		err:    "invalid rewrite rule on line 2: expected `<from> -> <to>`",
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rewriter, err := Parse(test.config)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Fatalf("Parse() error = %v, expected %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse() unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(test.expected, rewriter.rules); diff != "" {
+				t.Errorf("Parse() (-expected, +actual) = %v", diff)
+			}
+		})
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	rewriter := NewRewriter(
+		Rule{From: "gcr.io", To: "mirror.local/gcr"},
+		Rule{From: "gcr.io/projectriff", To: "mirror.local/riff"},
+		Rule{From: "index.docker.io/library", To: "mirror.local/library"},
+		Rule{From: "localhost", To: "mirror.local/localhost"},
+	)
+	tests := []struct {
+		name     string
+		rewriter *Rewriter
+		image    string
+		expected string
+	}{{
+		name:     "nil rewriter",
+		image:    "gcr.io/foo/bar",
+		expected: "gcr.io/foo/bar",
+	}, {
+		name:     "empty",
+		rewriter: rewriter,
+		image:    "",
+		expected: "",
+	}, {
+		name:     "registry",
+		rewriter: rewriter,
+		image:    "gcr.io/foo/bar:latest",
+		expected: "mirror.local/gcr/foo/bar:latest",
+	}, {
+		name:     "longest prefix",
+		rewriter: rewriter,
+		image:    "gcr.io/projectriff/processor@sha256:ab6f1d7b2d2c2fb0a2e2e8ab6d9c9b1c5f1e2a3b4c5d6e7f8091a2b3c4d5e6f7",
+		expected: "mirror.local/riff/processor@sha256:ab6f1d7b2d2c2fb0a2e2e8ab6d9c9b1c5f1e2a3b4c5d6e7f8091a2b3c4d5e6f7",
+	}, {
+		name:     "repository",
+		rewriter: rewriter,
+		image:    "gcr.io/projectriff:v1",
+		expected: "mirror.local/riff:v1",
+	}, {
+		name:     "partial path segment",
+		rewriter: rewriter,
+		image:    "gcr.io/projectriff-samples/hello",
+		expected: "mirror.local/gcr/projectriff-samples/hello",
+	}, {
+		name:     "registry port",
+		rewriter: rewriter,
+		image:    "localhost:5000/hello",
+		expected: "localhost:5000/hello",
+	}, {
+		name:     "docker hub",
+		rewriter: rewriter,
+		image:    "nginx:1.17",
+		expected: "mirror.local/library/nginx:1.17",
+	}, {
+		name:     "docker hub, fully qualified",
+		rewriter: rewriter,
+		image:    "index.docker.io/library/nginx",
+		expected: "mirror.local/library/nginx",
+	}, {
+		name:     "docker hub, user repository",
+		rewriter: rewriter,
+		image:    "projectriff/builder:0.5.0",
+		expected: "projectriff/builder:0.5.0",
+	}, {
+		name:     "no match",
+		rewriter: rewriter,
+		image:    "example.com/hello",
+		expected: "example.com/hello",
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if actual := test.rewriter.Rewrite(test.image); actual != test.expected {
+				t.Errorf("Rewrite(%q) = %q, expected %q", test.image, actual, test.expected)
+			}
+		})
+	}
+}
+
+func TestRewrite_DockerHub(t *testing.T) {
+	tests := []struct {
+		name     string
+		from     string
+		image    string
+		expected string
+	}{{
+		name:     "short name rule, short name image",
+		from:     "nginx",
+		image:    "nginx:1.17",
+		expected: "mirror.local:1.17",
+	}, {
+		name:     "short name rule, qualified image",
+		from:     "nginx",
+		image:    "docker.io/library/nginx",
+		expected: "mirror.local",
+	}, {
+		name:     "qualified rule, short name image",
+		from:     "docker.io/library/nginx",
+		image:    "nginx",
+		expected: "mirror.local",
+	}, {
+		name:     "short name rule, other image",
+		from:     "nginx",
+		image:    "nginx-ingress",
+		expected: "nginx-ingress",
+	}, {
+		name:     "docker.io registry rule",
+		from:     "docker.io/",
+		image:    "nginx:1.17",
+		expected: "mirror.local/library/nginx:1.17",
+	}, {
+		name:     "docker.io registry rule, user repository",
+		from:     "docker.io",
+		image:    "projectriff/builder",
+		expected: "mirror.local/projectriff/builder",
+	}, {
+		name:     "library namespace rule",
+		from:     "library/",
+		image:    "nginx@sha256:ab6f1d7b2d2c2fb0a2e2e8ab6d9c9b1c5f1e2a3b4c5d6e7f8091a2b3c4d5e6f7",
+		expected: "mirror.local/nginx@sha256:ab6f1d7b2d2c2fb0a2e2e8ab6d9c9b1c5f1e2a3b4c5d6e7f8091a2b3c4d5e6f7",
+	}, {
+		name:     "library namespace rule without slash",
+		from:     "docker.io/library",
+		image:    "docker.io/library/nginx:1.17",
+		expected: "mirror.local/nginx:1.17",
+	}, {
+		name:     "library namespace rule, user repository",
+		from:     "library/",
+		image:    "projectriff/builder",
+		expected: "projectriff/builder",
+	}, {
+		name:     "user namespace rule",
+		from:     "projectriff/",
+		image:    "index.docker.io/projectriff/builder:0.5.0",
+		expected: "mirror.local/builder:0.5.0",
+	}, {
+		name:     "qualified user namespace rule",
+		from:     "index.docker.io/projectriff",
+		image:    "index.docker.io/projectriff/builder:0.5.0",
+		expected: "mirror.local/builder:0.5.0",
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rewriter := NewRewriter(Rule{From: test.from, To: "mirror.local"})
+			if actual := rewriter.Rewrite(test.image); actual != test.expected {
+				t.Errorf("Rewrite(%q) = %q, expected %q", test.image, actual, test.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,651 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sourceupload
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/projectriff/system/pkg/apis"
+	buildv1alpha1 "github.com/projectriff/system/pkg/apis/build/v1alpha1"
+)
+
+// +kubebuilder:rbac:groups=build.projectriff.io,resources=applications;functions,verbs=get;list;watch;update
+// +kubebuilder:rbac:groups=authentication.k8s.io,resources=tokenreviews,verbs=create
+// +kubebuilder:rbac:groups=authorization.k8s.io,resources=subjectaccessreviews,verbs=create
+
+const (
+	// defaultMaxSize of an uploaded source archive in bytes
+	defaultMaxSize = 1 << 30
+	// blobsPath serves uploaded archives by their digest
+	blobsPath = "/blobs/sha256/"
+	// blobExtension is appended to the digest of an archive
+	blobExtension = ".tar.gz"
+	// uploadPrefix starts the name of an archive being uploaded
+	uploadPrefix = ".upload-"
+	// defaultTTL of an unreferenced archive
+	defaultTTL = 24 * time.Hour
+	// defaultGCInterval between collecting unreferenced archives
+	defaultGCInterval = time.Hour
+)
+
+var (
+	// uploadPath matches `/namespaces/<namespace>/<resource>/<name>/source`
+	uploadPath = regexp.MustCompile(`^/namespaces/([^/]+)/(applications|functions)/([^/]+)/source$`)
+	// blobPath matches the hex encoded sha256 digest of an archive
+	blobPath = regexp.MustCompile(`^` + blobsPath + `([0-9a-f]{64})` + regexp.QuoteMeta(blobExtension) + `$`)
+)
+
+// Server accepts source archives for Applications and Functions. Each
+// archive is stored by its digest and served over HTTP, the blob source of
+// the resource is updated to the served archive. Archives no longer
+// referenced by an Application or Function are removed once their TTL
+// expires.
+//
+// Uploads are a PUT of a gzipped tarball to
+// `/namespaces/<namespace>/<applications|functions>/<name>/source` with the
+// caller's bearer token, over TLS. The caller must be allowed to update the
+// resource. A resource with a source other than an uploaded archive is only
+// replaced when the request has the `replace=true` query parameter.
+//
+// Archives are stored on a single volume, the manager must run as a single
+// replica.
+type Server struct {
+	Client client.Client
+	Log    logr.Logger
+
+	// Addr the archives are served on over HTTP.
+	Addr string
+	// UploadAddr uploads are accepted on over TLS.
+	UploadAddr string
+	// CertDir contains the tls.crt and tls.key files of the upload server.
+	CertDir string
+	// Dir the archives are stored in.
+	Dir string
+	// URL the archives are served from, must be reachable by builds.
+	URL string
+
+	// MaxSize of an archive in bytes. Defaults to 1GiB.
+	//
+	// +optional
+	MaxSize int64
+	// TTL of an archive that is not referenced by a resource, measured from
+	// when it was last uploaded. Defaults to 24 hours.
+	//
+	// +optional
+	TTL time.Duration
+	// GCInterval between removing expired archives. Defaults to 1 hour.
+	//
+	// +optional
+	GCInterval time.Duration
+	// Authorizer checks that the caller may update the resource. Defaults to
+	// a ReviewAuthorizer.
+	//
+	// +optional
+	Authorizer Authorizer
+}
+
+// UploadResult describes an uploaded archive
+type UploadResult struct {
+	// Digest of the archive.
+	Digest string `json:"digest"`
+	// URL the archive is served from.
+	URL string `json:"url"`
+}
+
+// Start serves archives and uploads, and collects expired archives, until
+// the stop channel is closed.
+func (s *Server) Start(stop <-chan struct{}) error {
+	if err := os.MkdirAll(s.Dir, 0755); err != nil {
+		return err
+	}
+	certificate := &keyPair{
+		certFile: filepath.Join(s.CertDir, "tls.crt"),
+		keyFile:  filepath.Join(s.CertDir, "tls.key"),
+	}
+	if _, err := certificate.GetCertificate(nil); err != nil {
+		return err
+	}
+	blobServer := &http.Server{Addr: s.Addr, Handler: s.BlobHandler()}
+	uploadServer := &http.Server{
+		Addr:      s.UploadAddr,
+		Handler:   s.UploadHandler(),
+		TLSConfig: &tls.Config{GetCertificate: certificate.GetCertificate},
+	}
+	errs := make(chan error, 2)
+	go func() {
+		s.Log.Info("serving source archives", "addr", s.Addr)
+		errs <- blobServer.ListenAndServe()
+	}()
+	go func() {
+		s.Log.Info("serving source uploads", "addr", s.UploadAddr)
+		errs <- uploadServer.ListenAndServeTLS("", "")
+	}()
+	ticker := time.NewTicker(s.gcInterval())
+	defer ticker.Stop()
+	for {
+		select {
+		case err := <-errs:
+			return err
+		case <-ticker.C:
+			if err := s.CollectGarbage(context.Background()); err != nil {
+				s.Log.Error(err, "unable to collect expired archives")
+			}
+		case <-stop:
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			if err := uploadServer.Shutdown(ctx); err != nil {
+				return err
+			}
+			return blobServer.Shutdown(ctx)
+		}
+	}
+}
+
+// NeedLeaderElection is true, only the leader serves and collects the
+// archives on the volume.
+func (s *Server) NeedLeaderElection() bool {
+	return true
+}
+
+// CollectGarbage removes archives, and abandoned partial uploads, that are
+// older than the TTL and not referenced by an Application or Function.
+func (s *Server) CollectGarbage(ctx context.Context) error {
+	referenced, err := s.referencedDigests(ctx)
+	if err != nil {
+		return err
+	}
+	files, err := ioutil.ReadDir(s.Dir)
+	if err != nil {
+		return err
+	}
+	expired := time.Now().Add(-s.ttl())
+	for _, file := range files {
+		if file.IsDir() || file.ModTime().After(expired) {
+			continue
+		}
+		name := file.Name()
+		if strings.HasSuffix(name, blobExtension) && referenced[strings.TrimSuffix(name, blobExtension)] {
+			continue
+		}
+		if !strings.HasSuffix(name, blobExtension) && !strings.HasPrefix(name, uploadPrefix) {
+			// not ours
+			continue
+		}
+		s.Log.Info("removing expired archive", "file", name)
+		if err := os.Remove(filepath.Join(s.Dir, name)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// referencedDigests returns the digests of archives referenced by the blob
+// source of an Application or Function.
+func (s *Server) referencedDigests(ctx context.Context) (map[string]bool, error) {
+	prefix := strings.TrimSuffix(s.URL, "/") + blobsPath
+	referenced := map[string]bool{}
+	reference := func(source *buildv1alpha1.Source) {
+		if source == nil || source.Blob == nil || !strings.HasPrefix(source.Blob.URL, prefix) {
+			return
+		}
+		referenced[strings.TrimSuffix(strings.TrimPrefix(source.Blob.URL, prefix), blobExtension)] = true
+	}
+
+	var applications buildv1alpha1.ApplicationList
+	if err := s.Client.List(ctx, &applications); err != nil {
+		return nil, err
+	}
+	for _, application := range applications.Items {
+		reference(application.Spec.Source)
+	}
+	var functions buildv1alpha1.FunctionList
+	if err := s.Client.List(ctx, &functions); err != nil {
+		return nil, err
+	}
+	for _, function := range functions.Items {
+		reference(function.Spec.Source)
+	}
+	return referenced, nil
+}
+
+// BlobHandler serves the stored archives by their digest.
+func (s *Server) BlobHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if match := blobPath.FindStringSubmatch(r.URL.Path); match != nil {
+			s.serveBlob(w, r, match[1])
+			return
+		}
+		http.NotFound(w, r)
+	})
+}
+
+// UploadHandler accepts archives for Applications and Functions. The handler
+// carries bearer tokens and must only be served over TLS.
+func (s *Server) UploadHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if match := uploadPath.FindStringSubmatch(r.URL.Path); match != nil {
+			s.upload(w, r, types.NamespacedName{Namespace: match[1], Name: match[3]}, match[2])
+			return
+		}
+		http.NotFound(w, r)
+	})
+}
+
+func (s *Server) serveBlob(w http.ResponseWriter, r *http.Request, digest string) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	file, err := os.Open(s.blobFile(digest))
+	if err != nil {
+		if os.IsNotExist(err) {
+			http.NotFound(w, r)
+			return
+		}
+		s.Log.Error(err, "unable to open blob", "digest", digest)
+		http.Error(w, "unable to read blob", http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		s.Log.Error(err, "unable to stat blob", "digest", digest)
+		http.Error(w, "unable to read blob", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/gzip")
+	// blobs are content addressed and never change
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	http.ServeContent(w, r, "", info.ModTime(), file)
+}
+
+func (s *Server) upload(w http.ResponseWriter, r *http.Request, key types.NamespacedName, resource string) {
+	ctx := r.Context()
+	log := s.Log.WithValues("resource", resource, "namespace", key.Namespace, "name", key.Name)
+
+	if r.Method != http.MethodPut && r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	token := bearerToken(r)
+	if token == "" {
+		http.Error(w, "missing bearer token", http.StatusUnauthorized)
+		return
+	}
+	allowed, reason, err := s.authorizer().Authorize(ctx, token, authorizationv1.ResourceAttributes{
+		Namespace: key.Namespace,
+		Verb:      "update",
+		Group:     buildv1alpha1.GroupVersion.Group,
+		Resource:  resource,
+		Name:      key.Name,
+	})
+	if err != nil {
+		if err == errUnauthenticated {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		log.Error(err, "unable to authorize upload")
+		http.Error(w, "unable to authorize upload", http.StatusInternalServerError)
+		return
+	}
+	if !allowed {
+		message := fmt.Sprintf("not allowed to update %s %q in namespace %q", resource, key.Name, key.Namespace)
+		if reason != "" {
+			message = fmt.Sprintf("%s: %s", message, reason)
+		}
+		http.Error(w, message, http.StatusForbidden)
+		return
+	}
+
+	target, err := newResource(resource)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err := s.Client.Get(ctx, key, target); err != nil {
+		if apierrs.IsNotFound(err) {
+			http.Error(w, fmt.Sprintf("%s %q not found in namespace %q", resource, key.Name, key.Namespace), http.StatusNotFound)
+			return
+		}
+		log.Error(err, "unable to get resource")
+		http.Error(w, "unable to get resource", http.StatusInternalServerError)
+		return
+	}
+	replace := r.URL.Query().Get("replace") == "true"
+	if err := s.checkReplace(target, replace); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	digest, err := s.store(r.Body)
+	if err != nil {
+		if invalid, ok := err.(*invalidArchiveError); ok {
+			http.Error(w, invalid.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Error(err, "unable to store source")
+		http.Error(w, "unable to store source", http.StatusInternalServerError)
+		return
+	}
+	result := UploadResult{
+		Digest: "sha256:" + digest,
+		URL:    strings.TrimSuffix(s.URL, "/") + blobsPath + digest + blobExtension,
+	}
+
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if err := s.Client.Get(ctx, key, target); err != nil {
+			return err
+		}
+		if err := s.checkReplace(target, replace); err != nil {
+			return err
+		}
+		setBlobSource(target, result.URL)
+		annotations := target.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[buildv1alpha1.SourceUploadAnnotationKey] = result.Digest
+		target.SetAnnotations(annotations)
+		return s.Client.Update(ctx, target)
+	})
+	if err != nil {
+		if conflict, ok := err.(*sourceConflictError); ok {
+			http.Error(w, conflict.Error(), http.StatusConflict)
+			return
+		}
+		log.Error(err, "unable to update source")
+		http.Error(w, "unable to update source", http.StatusInternalServerError)
+		return
+	}
+	log.Info("uploaded source", "digest", result.Digest)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// checkReplace returns an error when the upload would replace a source of the
+// resource that is not an uploaded archive, unless replace is requested.
+func (s *Server) checkReplace(target apis.Object, replace bool) error {
+	source := getSource(target)
+	if replace || source == nil {
+		return nil
+	}
+	if source.Blob != nil && strings.HasPrefix(source.Blob.URL, strings.TrimSuffix(s.URL, "/")+blobsPath) {
+		return nil
+	}
+	kind := "blob"
+	switch {
+	case source.Git != nil:
+		kind = "git"
+	case source.Registry != nil:
+		kind = "registry"
+	}
+	return &sourceConflictError{
+		message: fmt.Sprintf("%q has a %s source, upload with `replace=true` to replace it", target.GetName(), kind),
+	}
+}
+
+// store writes the archive to the blob directory, returning the hex encoded
+// sha256 digest of the archive.
+func (s *Server) store(body io.Reader) (string, error) {
+	temp, err := ioutil.TempFile(s.Dir, uploadPrefix)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(temp.Name())
+	defer temp.Close()
+
+	hash := sha256.New()
+	// read one byte past the limit to detect archives that are too large
+	written, err := io.Copy(io.MultiWriter(temp, hash), io.LimitReader(body, s.maxSize()+1))
+	if err != nil {
+		return "", err
+	}
+	if written > s.maxSize() {
+		return "", &invalidArchiveError{message: fmt.Sprintf("archive is larger than %d bytes", s.maxSize())}
+	}
+	if _, err := temp.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	if err := checkArchive(temp); err != nil {
+		return "", err
+	}
+	if err := temp.Close(); err != nil {
+		return "", err
+	}
+
+	digest := hex.EncodeToString(hash.Sum(nil))
+	if err := os.Rename(temp.Name(), s.blobFile(digest)); err != nil {
+		return "", err
+	}
+	return digest, nil
+}
+
+func (s *Server) blobFile(digest string) string {
+	return filepath.Join(s.Dir, digest+blobExtension)
+}
+
+func (s *Server) maxSize() int64 {
+	if s.MaxSize == 0 {
+		return defaultMaxSize
+	}
+	return s.MaxSize
+}
+
+func (s *Server) ttl() time.Duration {
+	if s.TTL == 0 {
+		return defaultTTL
+	}
+	return s.TTL
+}
+
+func (s *Server) gcInterval() time.Duration {
+	if s.GCInterval == 0 {
+		return defaultGCInterval
+	}
+	return s.GCInterval
+}
+
+func (s *Server) authorizer() Authorizer {
+	if s.Authorizer == nil {
+		return &ReviewAuthorizer{Client: s.Client}
+	}
+	return s.Authorizer
+}
+
+type sourceConflictError struct {
+	message string
+}
+
+func (e *sourceConflictError) Error() string {
+	return e.message
+}
+
+type invalidArchiveError struct {
+	message string
+}
+
+func (e *invalidArchiveError) Error() string {
+	return e.message
+}
+
+// checkArchive reads each entry of a gzipped tarball.
+func checkArchive(archive io.Reader) error {
+	gz, err := gzip.NewReader(archive)
+	if err != nil {
+		return &invalidArchiveError{message: fmt.Sprintf("expected a gzipped tarball: %v", err)}
+	}
+	tr := tar.NewReader(gz)
+	for {
+		if _, err := tr.Next(); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return &invalidArchiveError{message: fmt.Sprintf("expected a gzipped tarball: %v", err)}
+		}
+	}
+}
+
+func newResource(resource string) (apis.Object, error) {
+	switch resource {
+	case "applications":
+		return &buildv1alpha1.Application{}, nil
+	case "functions":
+		return &buildv1alpha1.Function{}, nil
+	}
+	return nil, fmt.Errorf("unsupported resource %q", resource)
+}
+
+func sourceField(target apis.Object) **buildv1alpha1.Source {
+	switch t := target.(type) {
+	case *buildv1alpha1.Application:
+		return &t.Spec.Source
+	case *buildv1alpha1.Function:
+		return &t.Spec.Source
+	}
+	return nil
+}
+
+func getSource(target apis.Object) *buildv1alpha1.Source {
+	return *sourceField(target)
+}
+
+// setBlobSource replaces the source of the resource with the blob, keeping
+// the sub path.
+func setBlobSource(target apis.Object, url string) {
+	source := sourceField(target)
+	subPath := ""
+	if *source != nil {
+		subPath = (*source).SubPath
+	}
+	*source = &buildv1alpha1.Source{
+		Blob:    &buildv1alpha1.Blob{URL: url},
+		SubPath: subPath,
+	}
+}
+
+// keyPair loads the serving certificate, reloading it when the certificate
+// file changes.
+type keyPair struct {
+	certFile string
+	keyFile  string
+
+	m           sync.Mutex
+	modTime     time.Time
+	certificate *tls.Certificate
+}
+
+func (k *keyPair) GetCertificate(_ *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	k.m.Lock()
+	defer k.m.Unlock()
+
+	info, err := os.Stat(k.certFile)
+	if err != nil {
+		return nil, err
+	}
+	if k.certificate != nil && info.ModTime().Equal(k.modTime) {
+		return k.certificate, nil
+	}
+	certificate, err := tls.LoadX509KeyPair(k.certFile, k.keyFile)
+	if err != nil {
+		return nil, err
+	}
+	k.modTime = info.ModTime()
+	k.certificate = &certificate
+	return k.certificate, nil
+}
+
+func bearerToken(r *http.Request) string {
+	auth := strings.TrimSpace(r.Header.Get("Authorization"))
+	parts := strings.SplitN(auth, " ", 2)
+	if len(parts) != 2 || !strings.EqualFold(parts[0], "bearer") {
+		return ""
+	}
+	return strings.TrimSpace(parts[1])
+}
+
+var errUnauthenticated = fmt.Errorf("invalid bearer token")
+
+// Authorizer decides whether the caller with the bearer token is allowed the
+// action on a resource.
+type Authorizer interface {
+	// Authorize returns whether the action is allowed with an optional reason.
+	Authorize(ctx context.Context, token string, attributes authorizationv1.ResourceAttributes) (bool, string, error)
+}
+
+// ReviewAuthorizer authenticates the bearer token with a TokenReview and
+// checks the RBAC of the authenticated user with a SubjectAccessReview.
+type ReviewAuthorizer struct {
+	Client client.Client
+}
+
+func (a *ReviewAuthorizer) Authorize(ctx context.Context, token string, attributes authorizationv1.ResourceAttributes) (bool, string, error) {
+	tokenReview := &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{
+			Token: token,
+		},
+	}
+	if err := a.Client.Create(ctx, tokenReview); err != nil {
+		return false, "", err
+	}
+	if !tokenReview.Status.Authenticated {
+		return false, "", errUnauthenticated
+	}
+
+	user := tokenReview.Status.User
+	extra := make(map[string]authorizationv1.ExtraValue, len(user.Extra))
+	for k, v := range user.Extra {
+		extra[k] = authorizationv1.ExtraValue(v)
+	}
+	accessReview := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			ResourceAttributes: &attributes,
+			User:               user.Username,
+			Groups:             user.Groups,
+			UID:                user.UID,
+			Extra:              extra,
+		},
+	}
+	if err := a.Client.Create(ctx, accessReview); err != nil {
+		return false, "", err
+	}
+	return accessReview.Status.Allowed, accessReview.Status.Reason, nil
+}
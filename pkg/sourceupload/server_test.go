@@ -0,0 +1,535 @@
+/*
+Copyright 2020 the original author or authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sourceupload_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	buildv1alpha1 "github.com/projectriff/system/pkg/apis/build/v1alpha1"
+	"github.com/projectriff/system/pkg/controllers/testing/factories"
+	"github.com/projectriff/system/pkg/sourceupload"
+)
+
+func TestServer(t *testing.T) {
+	testNamespace := "test-namespace"
+	testName := "test-name"
+	testToken := "test-token"
+	testURL := "http://source.riff-system.svc.cluster.local"
+
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = buildv1alpha1.AddToScheme(scheme)
+
+	testArchive := archive(t, map[string]string{"hello.txt": "hello"})
+	testDigest := fmt.Sprintf("%x", sha256.Sum256(testArchive))
+	testBlobURL := fmt.Sprintf("%s/blobs/sha256/%s.tar.gz", testURL, testDigest)
+
+	application := factories.Application().
+		NamespaceName(testNamespace, testName).
+		Image("example.com/repo").
+		SourceGit("https://example.com/repo.git", "master").
+		SourceSubPath("app")
+	uploadedApplication := factories.Application().
+		NamespaceName(testNamespace, testName).
+		Image("example.com/repo").
+		SourceBlob(fmt.Sprintf("%s/blobs/sha256/%s.tar.gz", testURL, strings.Repeat("a", 64)))
+	function := factories.Function().
+		NamespaceName(testNamespace, testName).
+		Image("example.com/repo")
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		token          string
+		body           []byte
+		blob           bool
+		allowed        bool
+		givenObjects   []runtime.Object
+		expectedStatus int
+		expectedResult *sourceupload.UploadResult
+		expectedSource *buildv1alpha1.Source
+	}{{
+		name:           "upload application source",
+		method:         http.MethodPut,
+		path:           fmt.Sprintf("/namespaces/%s/applications/%s/source", testNamespace, testName),
+		token:          testToken,
+		body:           testArchive,
+		allowed:        true,
+		givenObjects:   []runtime.Object{uploadedApplication.Create()},
+		expectedStatus: http.StatusOK,
+		expectedResult: &sourceupload.UploadResult{
+			Digest: "sha256:" + testDigest,
+			URL:    testBlobURL,
+		},
+		expectedSource: &buildv1alpha1.Source{
+			Blob: &buildv1alpha1.Blob{URL: testBlobURL},
+		},
+	}, {
+		name:           "replace application git source",
+		method:         http.MethodPut,
+		path:           fmt.Sprintf("/namespaces/%s/applications/%s/source?replace=true", testNamespace, testName),
+		token:          testToken,
+		body:           testArchive,
+		allowed:        true,
+		givenObjects:   []runtime.Object{application.Create()},
+		expectedStatus: http.StatusOK,
+		expectedResult: &sourceupload.UploadResult{
+			Digest: "sha256:" + testDigest,
+			URL:    testBlobURL,
+		},
+		expectedSource: &buildv1alpha1.Source{
+			Blob:    &buildv1alpha1.Blob{URL: testBlobURL},
+			SubPath: "app",
+		},
+	}, {
+		name:           "git source not replaced",
+		method:         http.MethodPut,
+		path:           fmt.Sprintf("/namespaces/%s/applications/%s/source", testNamespace, testName),
+		token:          testToken,
+		body:           testArchive,
+		allowed:        true,
+		givenObjects:   []runtime.Object{application.Create()},
+		expectedStatus: http.StatusConflict,
+	}, {
+		name:    "external blob source not replaced",
+		method:  http.MethodPut,
+		path:    fmt.Sprintf("/namespaces/%s/applications/%s/source", testNamespace, testName),
+		token:   testToken,
+		body:    testArchive,
+		allowed: true,
+		givenObjects: []runtime.Object{
+			application.SourceBlob("https://example.com/source.tar.gz").Create(),
+		},
+		expectedStatus: http.StatusConflict,
+	}, {
+		name:           "upload function source",
+		method:         http.MethodPut,
+		path:           fmt.Sprintf("/namespaces/%s/functions/%s/source", testNamespace, testName),
+		token:          testToken,
+		body:           testArchive,
+		allowed:        true,
+		givenObjects:   []runtime.Object{function.Create()},
+		expectedStatus: http.StatusOK,
+		expectedResult: &sourceupload.UploadResult{
+			Digest: "sha256:" + testDigest,
+			URL:    testBlobURL,
+		},
+		expectedSource: &buildv1alpha1.Source{
+			Blob: &buildv1alpha1.Blob{URL: testBlobURL},
+		},
+	}, {
+		name:           "missing token",
+		method:         http.MethodPut,
+		path:           fmt.Sprintf("/namespaces/%s/applications/%s/source", testNamespace, testName),
+		body:           testArchive,
+		allowed:        true,
+		givenObjects:   []runtime.Object{application.Create()},
+		expectedStatus: http.StatusUnauthorized,
+	}, {
+		name:           "not allowed",
+		method:         http.MethodPut,
+		path:           fmt.Sprintf("/namespaces/%s/applications/%s/source", testNamespace, testName),
+		token:          testToken,
+		body:           testArchive,
+		allowed:        false,
+		givenObjects:   []runtime.Object{uploadedApplication.Create()},
+		expectedStatus: http.StatusForbidden,
+	}, {
+		name:           "resource not found",
+		method:         http.MethodPut,
+		path:           fmt.Sprintf("/namespaces/%s/applications/%s/source", testNamespace, testName),
+		token:          testToken,
+		body:           testArchive,
+		allowed:        true,
+		expectedStatus: http.StatusNotFound,
+	}, {
+		name:           "not a tarball",
+		method:         http.MethodPut,
+		path:           fmt.Sprintf("/namespaces/%s/applications/%s/source", testNamespace, testName),
+		token:          testToken,
+		body:           []byte("hello"),
+		allowed:        true,
+		givenObjects:   []runtime.Object{uploadedApplication.Create()},
+		expectedStatus: http.StatusBadRequest,
+	}, {
+		name:           "too large",
+		method:         http.MethodPut,
+		path:           fmt.Sprintf("/namespaces/%s/applications/%s/source", testNamespace, testName),
+		token:          testToken,
+		body:           bytes.Repeat([]byte{0}, 2048),
+		allowed:        true,
+		givenObjects:   []runtime.Object{uploadedApplication.Create()},
+		expectedStatus: http.StatusBadRequest,
+	}, {
+		name:           "unsupported method",
+		method:         http.MethodGet,
+		path:           fmt.Sprintf("/namespaces/%s/applications/%s/source", testNamespace, testName),
+		token:          testToken,
+		allowed:        true,
+		givenObjects:   []runtime.Object{application.Create()},
+		expectedStatus: http.StatusMethodNotAllowed,
+	}, {
+		name:           "unsupported resource",
+		method:         http.MethodPut,
+		path:           fmt.Sprintf("/namespaces/%s/containers/%s/source", testNamespace, testName),
+		token:          testToken,
+		body:           testArchive,
+		allowed:        true,
+		expectedStatus: http.StatusNotFound,
+	}, {
+		name:           "blob upload path not found",
+		method:         http.MethodGet,
+		path:           fmt.Sprintf("/namespaces/%s/applications/%s/source", testNamespace, testName),
+		blob:           true,
+		expectedStatus: http.StatusNotFound,
+	}, {
+		name:           "blob not found",
+		method:         http.MethodGet,
+		path:           fmt.Sprintf("/blobs/sha256/%s.tar.gz", testDigest),
+		blob:           true,
+		expectedStatus: http.StatusNotFound,
+	}, {
+		name:           "invalid blob digest",
+		method:         http.MethodGet,
+		path:           "/blobs/sha256/../secret.tar.gz",
+		blob:           true,
+		expectedStatus: http.StatusNotFound,
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "sourceupload")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+
+			c := fake.NewFakeClientWithScheme(scheme, test.givenObjects...)
+			server := &sourceupload.Server{
+				Client:  c,
+				Log:     logf.NullLogger{},
+				Dir:     dir,
+				URL:     testURL,
+				MaxSize: 1024,
+				Authorizer: &fakeAuthorizer{
+					t:       t,
+					token:   testToken,
+					allowed: test.allowed,
+				},
+			}
+
+			request := httptest.NewRequest(test.method, test.path, bytes.NewReader(test.body))
+			if test.token != "" {
+				request.Header.Set("Authorization", "Bearer "+test.token)
+			}
+			handler := server.UploadHandler()
+			if test.blob {
+				handler = server.BlobHandler()
+			}
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, request)
+
+			if actual, expected := response.Code, test.expectedStatus; actual != expected {
+				t.Fatalf("ServeHTTP() status = %d, expected %d: %s", actual, expected, response.Body.String())
+			}
+			if test.expectedResult == nil {
+				return
+			}
+
+			actual := &sourceupload.UploadResult{}
+			if err := json.Unmarshal(response.Body.Bytes(), actual); err != nil {
+				t.Fatalf("unable to read result: %v", err)
+			}
+			if diff := cmp.Diff(test.expectedResult, actual); diff != "" {
+				t.Errorf("ServeHTTP() result (-expected, +actual): %s", diff)
+			}
+
+			var source *buildv1alpha1.Source
+			var annotations map[string]string
+			key := types.NamespacedName{Namespace: testNamespace, Name: testName}
+			if _, ok := test.givenObjects[0].(*buildv1alpha1.Application); ok {
+				var app buildv1alpha1.Application
+				if err := c.Get(context.TODO(), key, &app); err != nil {
+					t.Fatal(err)
+				}
+				source = app.Spec.Source
+				annotations = app.Annotations
+			} else {
+				var fn buildv1alpha1.Function
+				if err := c.Get(context.TODO(), key, &fn); err != nil {
+					t.Fatal(err)
+				}
+				source = fn.Spec.Source
+				annotations = fn.Annotations
+			}
+			if diff := cmp.Diff(test.expectedSource, source); diff != "" {
+				t.Errorf("ServeHTTP() source (-expected, +actual): %s", diff)
+			}
+			if actual, expected := annotations[buildv1alpha1.SourceUploadAnnotationKey], test.expectedResult.Digest; actual != expected {
+				t.Errorf("ServeHTTP() source upload annotation = %q, expected %q", actual, expected)
+			}
+
+			// the uploaded archive is served
+			blob := httptest.NewRecorder()
+			server.BlobHandler().ServeHTTP(blob, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/blobs/sha256/%s.tar.gz", testDigest), nil))
+			if blob.Code != http.StatusOK {
+				t.Fatalf("blob status = %d, expected %d", blob.Code, http.StatusOK)
+			}
+			if !bytes.Equal(blob.Body.Bytes(), test.body) {
+				t.Errorf("blob content does not match the uploaded archive")
+			}
+		})
+	}
+}
+
+func TestServer_CollectGarbage(t *testing.T) {
+	testNamespace := "test-namespace"
+	testURL := "http://source.riff-system.svc.cluster.local"
+	testReferencedDigest := strings.Repeat("a", 64)
+	testFunctionDigest := strings.Repeat("b", 64)
+	testExpiredDigest := strings.Repeat("c", 64)
+	testFreshDigest := strings.Repeat("d", 64)
+
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = buildv1alpha1.AddToScheme(scheme)
+
+	application := factories.Application().
+		NamespaceName(testNamespace, "my-application").
+		Image("example.com/repo").
+		SourceBlob(fmt.Sprintf("%s/blobs/sha256/%s.tar.gz", testURL, testReferencedDigest))
+	function := factories.Function().
+		NamespaceName(testNamespace, "my-function").
+		Image("example.com/repo").
+		SourceBlob(fmt.Sprintf("%s/blobs/sha256/%s.tar.gz", testURL, testFunctionDigest))
+
+	dir, err := ioutil.TempDir("", "sourceupload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	expired := time.Now().Add(-2 * time.Hour)
+	files := map[string]bool{
+		// file name: expected to be kept
+		testReferencedDigest + ".tar.gz": true,
+		testFunctionDigest + ".tar.gz":   true,
+		testExpiredDigest + ".tar.gz":    false,
+		testFreshDigest + ".tar.gz":      true,
+		".upload-123":                    false,
+		"lost+found.txt":                 true,
+	}
+	for name := range files {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte{}, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if name == testFreshDigest+".tar.gz" {
+			continue
+		}
+		if err := os.Chtimes(path, expired, expired); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	server := &sourceupload.Server{
+		Client: fake.NewFakeClientWithScheme(scheme, application.Create(), function.Create()),
+		Log:    logf.NullLogger{},
+		Dir:    dir,
+		URL:    testURL,
+		TTL:    time.Hour,
+	}
+	if err := server.CollectGarbage(context.TODO()); err != nil {
+		t.Fatalf("CollectGarbage() error = %v", err)
+	}
+
+	for name, expected := range files {
+		_, err := os.Stat(filepath.Join(dir, name))
+		if actual := err == nil; actual != expected {
+			t.Errorf("CollectGarbage() kept %q = %v, expected %v", name, actual, expected)
+		}
+	}
+}
+
+func TestServer_NeedLeaderElection(t *testing.T) {
+	server := &sourceupload.Server{}
+	if !server.NeedLeaderElection() {
+		t.Errorf("NeedLeaderElection() = false, expected only the leader to serve uploads")
+	}
+}
+
+func TestServer_StartWithoutCertificate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sourceupload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	server := &sourceupload.Server{
+		Log:        logf.NullLogger{},
+		Addr:       "127.0.0.1:0",
+		UploadAddr: "127.0.0.1:0",
+		CertDir:    filepath.Join(dir, "certs"),
+		Dir:        filepath.Join(dir, "source"),
+	}
+	stop := make(chan struct{})
+	defer close(stop)
+	if err := server.Start(stop); err == nil {
+		t.Errorf("Start() expected an error without a serving certificate")
+	}
+}
+
+func TestReviewAuthorizer(t *testing.T) {
+	testToken := "test-token"
+	attributes := authorizationv1.ResourceAttributes{
+		Namespace: "test-namespace",
+		Verb:      "update",
+		Group:     "build.projectriff.io",
+		Resource:  "applications",
+		Name:      "test-name",
+	}
+
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+
+	tests := []struct {
+		name          string
+		authenticated bool
+		allowed       bool
+		shouldErr     bool
+	}{{
+		name:          "allowed",
+		authenticated: true,
+		allowed:       true,
+	}, {
+		name:          "denied",
+		authenticated: true,
+		allowed:       false,
+	}, {
+		name:          "unauthenticated",
+		authenticated: false,
+		shouldErr:     true,
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := &reviewClient{
+				Client: fake.NewFakeClientWithScheme(scheme),
+				review: func(obj runtime.Object) {
+					switch review := obj.(type) {
+					case *authenticationv1.TokenReview:
+						if review.Spec.Token != testToken {
+							t.Errorf("unexpected token %q", review.Spec.Token)
+						}
+						review.Status.Authenticated = test.authenticated
+						review.Status.User = authenticationv1.UserInfo{
+							Username: "jane",
+							Groups:   []string{"developers"},
+						}
+					case *authorizationv1.SubjectAccessReview:
+						if diff := cmp.Diff(&attributes, review.Spec.ResourceAttributes); diff != "" {
+							t.Errorf("unexpected resource attributes (-expected, +actual): %s", diff)
+						}
+						if review.Spec.User != "jane" {
+							t.Errorf("unexpected user %q", review.Spec.User)
+						}
+						review.Status.Allowed = test.allowed
+					}
+				},
+			}
+			authorizer := &sourceupload.ReviewAuthorizer{Client: c}
+			allowed, _, err := authorizer.Authorize(context.TODO(), testToken, attributes)
+			if (err != nil) != test.shouldErr {
+				t.Fatalf("Authorize() error = %v, expected error %v", err, test.shouldErr)
+			}
+			if allowed != test.allowed {
+				t.Errorf("Authorize() = %v, expected %v", allowed, test.allowed)
+			}
+		})
+	}
+}
+
+type fakeAuthorizer struct {
+	t       *testing.T
+	token   string
+	allowed bool
+}
+
+func (a *fakeAuthorizer) Authorize(ctx context.Context, token string, attributes authorizationv1.ResourceAttributes) (bool, string, error) {
+	if token != a.token {
+		a.t.Errorf("unexpected token %q", token)
+	}
+	if attributes.Verb != "update" || attributes.Group != buildv1alpha1.GroupVersion.Group {
+		a.t.Errorf("unexpected attributes %+v", attributes)
+	}
+	return a.allowed, "", nil
+}
+
+// reviewClient fills in the status of created reviews
+type reviewClient struct {
+	client.Client
+	review func(obj runtime.Object)
+}
+
+func (c *reviewClient) Create(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+	c.review(obj)
+	return nil
+}
+
+func archive(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}